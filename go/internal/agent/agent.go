@@ -0,0 +1,343 @@
+// Package agent keeps unlocked vault keys in memory between lockr commands,
+// like ssh-agent keeps SSH keys. The agent listens on a unix socket that
+// only its user can reach and hands a vault's raw SQLCipher key to lockr
+// commands, so they skip the password prompt and key derivation.
+//
+// A key is forgotten once it goes unused for the agent's timeout, when it is
+// locked, and when the agent stops. Keys never touch the disk.
+//
+// The agent also holds grants: a single secret value handed out to whoever
+// presents the grant's token before it expires, once or until it expires.
+package agent
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Operations understood by the agent
+const (
+	OpGet    = "get"
+	OpAdd    = "add"
+	OpLock   = "lock"
+	OpStatus = "status"
+	OpStop   = "stop"
+	OpGrant  = "grant"
+	OpRedeem = "redeem"
+)
+
+// tokenLength is the number of random bytes in a grant token
+const tokenLength = 24
+
+// ErrLocked is returned when the agent does not hold the vault's key
+var ErrLocked = errors.New("agent does not hold the key of this vault")
+
+// ErrNotRunning is returned when no agent listens on the socket
+var ErrNotRunning = errors.New("agent is not running")
+
+// ErrGrantInvalid is returned for a grant token that is unknown, was
+// already redeemed or has expired
+var ErrGrantInvalid = errors.New("grant is unknown, already used or expired")
+
+// Request is one line sent to the agent
+type Request struct {
+	Op    string `json:"op"`
+	Vault string `json:"vault,omitempty"`
+	Label string `json:"label,omitempty"`
+	Key   []byte `json:"key,omitempty"`
+
+	// Grants
+	Value []byte        `json:"value,omitempty"`
+	TTL   time.Duration `json:"ttl,omitempty"`
+	Once  bool          `json:"once,omitempty"`
+	Token string        `json:"token,omitempty"`
+}
+
+// Response is the agent's one-line answer
+type Response struct {
+	Error  string        `json:"error,omitempty"`
+	Key    []byte        `json:"key,omitempty"`
+	PID    int           `json:"pid,omitempty"`
+	Vaults []VaultStatus `json:"vaults,omitempty"`
+	Grants int           `json:"grants,omitempty"`
+	Token  string        `json:"token,omitempty"`
+	Value  []byte        `json:"value,omitempty"`
+}
+
+// VaultStatus describes a key held by the agent
+type VaultStatus struct {
+	Vault     string    `json:"vault"`
+	Label     string    `json:"label"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// entry is a key held in memory
+type entry struct {
+	key     []byte
+	label   string
+	expires time.Time
+}
+
+// grant is a secret value waiting to be redeemed
+type grant struct {
+	value   []byte
+	once    bool
+	expires time.Time
+}
+
+// Server holds vault keys and answers requests on a socket
+type Server struct {
+	timeout time.Duration
+	now     func() time.Time
+
+	mu       sync.Mutex
+	entries  map[string]*entry
+	grants   map[string]*grant
+	listener net.Listener
+}
+
+// NewServer creates an agent that forgets a key once it has not been used
+// for timeout
+func NewServer(timeout time.Duration) *Server {
+	return &Server{
+		timeout: timeout,
+		now:     time.Now,
+		entries: make(map[string]*entry),
+		grants:  make(map[string]*grant),
+	}
+}
+
+// Listen creates the socket at path, in a directory only the current user
+// can enter. A socket left behind by an agent that died is replaced; one an
+// agent still answers on is an error.
+func Listen(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if NewClient(path).Running() {
+		return nil, fmt.Errorf("an agent is already running on %s", path)
+	}
+	os.Remove(path)
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Serve answers requests on l until a stop request arrives or l is closed.
+// Every key is wiped from memory before it returns.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	s.listener = l
+	s.mu.Unlock()
+	defer s.lockAll()
+
+	// Expired keys are wiped even if nobody asks for them again
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.expire()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle answers the single request on conn
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	var req Request
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err == nil {
+		err = json.Unmarshal(line, &req)
+	}
+	var resp Response
+	if err != nil {
+		resp.Error = fmt.Sprintf("invalid request: %v", err)
+	} else {
+		resp = s.Handle(req)
+	}
+	json.NewEncoder(conn).Encode(resp)
+
+	if req.Op == OpStop && resp.Error == "" {
+		s.mu.Lock()
+		if s.listener != nil {
+			s.listener.Close()
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Handle answers one request
+func (s *Server) Handle(req Request) Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLocked()
+
+	switch req.Op {
+	case OpGet:
+		e, ok := s.entries[req.Vault]
+		if !ok {
+			return Response{Error: ErrLocked.Error()}
+		}
+		// Like a session, every use extends the timeout
+		e.expires = s.now().Add(s.timeout)
+		return Response{Key: append([]byte(nil), e.key...)}
+	case OpAdd:
+		if req.Vault == "" || len(req.Key) == 0 {
+			return Response{Error: "add needs a vault and a key"}
+		}
+		s.forgetLocked(req.Vault)
+		s.entries[req.Vault] = &entry{key: req.Key, label: req.Label, expires: s.now().Add(s.timeout)}
+		return Response{}
+	case OpLock:
+		if req.Vault == "" {
+			s.forgetAllLocked()
+		} else {
+			s.forgetLocked(req.Vault)
+		}
+		return Response{}
+	case OpStatus, OpStop:
+		resp := Response{PID: os.Getpid()}
+		for vault, e := range s.entries {
+			resp.Vaults = append(resp.Vaults, VaultStatus{Vault: vault, Label: e.label, ExpiresAt: e.expires})
+		}
+		sort.Slice(resp.Vaults, func(i, j int) bool { return resp.Vaults[i].Label < resp.Vaults[j].Label })
+		resp.Grants = len(s.grants)
+		return resp
+	case OpGrant:
+		if len(req.Value) == 0 || req.TTL <= 0 {
+			return Response{Error: "grant needs a value and a positive TTL"}
+		}
+		token, err := newToken()
+		if err != nil {
+			return Response{Error: fmt.Sprintf("failed to create grant: %v", err)}
+		}
+		s.grants[token] = &grant{value: req.Value, once: req.Once, expires: s.now().Add(req.TTL)}
+		return Response{Token: token}
+	case OpRedeem:
+		g, ok := s.grants[req.Token]
+		if !ok {
+			return Response{Error: ErrGrantInvalid.Error()}
+		}
+		value := append([]byte(nil), g.value...)
+		if g.once {
+			s.revokeLocked(req.Token)
+		}
+		return Response{Value: value}
+	default:
+		return Response{Error: fmt.Sprintf("unknown operation %q", req.Op)}
+	}
+}
+
+// expire wipes the keys whose timeout has passed
+func (s *Server) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLocked()
+}
+
+// expireLocked wipes expired keys and grants; the caller must hold s.mu
+func (s *Server) expireLocked() {
+	now := s.now()
+	for vault, e := range s.entries {
+		if !now.Before(e.expires) {
+			s.forgetLocked(vault)
+		}
+	}
+	for token, g := range s.grants {
+		if !now.Before(g.expires) {
+			s.revokeLocked(token)
+		}
+	}
+}
+
+// lockAll wipes every key and grant
+func (s *Server) lockAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.forgetAllLocked()
+}
+
+// forgetAllLocked wipes every key and grant; the caller must hold s.mu
+func (s *Server) forgetAllLocked() {
+	for vault := range s.entries {
+		s.forgetLocked(vault)
+	}
+	for token := range s.grants {
+		s.revokeLocked(token)
+	}
+}
+
+// forgetLocked overwrites and drops the key of vault; the caller must hold
+// s.mu
+func (s *Server) forgetLocked(vault string) {
+	e, ok := s.entries[vault]
+	if !ok {
+		return
+	}
+	wipe(e.key)
+	delete(s.entries, vault)
+}
+
+// revokeLocked overwrites and drops the grant with token; the caller must
+// hold s.mu
+func (s *Server) revokeLocked(token string) {
+	g, ok := s.grants[token]
+	if !ok {
+		return
+	}
+	wipe(g.value)
+	delete(s.grants, token)
+}
+
+// wipe overwrites b with zeros
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// newToken returns a random grant token
+func newToken() (string, error) {
+	b := make([]byte, tokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
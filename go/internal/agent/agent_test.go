@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Handle(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := NewServer(15 * time.Minute)
+	s.now = func() time.Time { return now }
+
+	assert.Equal(t, ErrLocked.Error(), s.Handle(Request{Op: OpGet, Vault: "v1"}).Error)
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	require.Empty(t, s.Handle(Request{Op: OpAdd, Vault: "v1", Label: "/vault", Key: append([]byte(nil), key...)}).Error)
+	assert.Equal(t, key, s.Handle(Request{Op: OpGet, Vault: "v1"}).Key)
+
+	// Each use restarts the timeout
+	now = now.Add(10 * time.Minute)
+	assert.Equal(t, key, s.Handle(Request{Op: OpGet, Vault: "v1"}).Key)
+	now = now.Add(10 * time.Minute)
+	assert.Equal(t, key, s.Handle(Request{Op: OpGet, Vault: "v1"}).Key)
+
+	status := s.Handle(Request{Op: OpStatus})
+	require.Len(t, status.Vaults, 1)
+	assert.Equal(t, "/vault", status.Vaults[0].Label)
+	assert.Equal(t, now.Add(15*time.Minute), status.Vaults[0].ExpiresAt)
+
+	// An unused key is forgotten and wiped
+	held := s.entries["v1"].key
+	now = now.Add(15 * time.Minute)
+	assert.Equal(t, ErrLocked.Error(), s.Handle(Request{Op: OpGet, Vault: "v1"}).Error)
+	assert.Equal(t, make([]byte, len(key)), held)
+
+	require.Empty(t, s.Handle(Request{Op: OpAdd, Vault: "v1", Key: []byte("k1")}).Error)
+	require.Empty(t, s.Handle(Request{Op: OpAdd, Vault: "v2", Key: []byte("k2")}).Error)
+	require.Empty(t, s.Handle(Request{Op: OpLock, Vault: "v1"}).Error)
+	assert.Len(t, s.Handle(Request{Op: OpStatus}).Vaults, 1)
+	require.Empty(t, s.Handle(Request{Op: OpLock}).Error)
+	assert.Empty(t, s.Handle(Request{Op: OpStatus}).Vaults)
+
+	assert.NotEmpty(t, s.Handle(Request{Op: OpAdd, Vault: "v1"}).Error)
+	assert.NotEmpty(t, s.Handle(Request{Op: "dump"}).Error)
+}
+
+func TestServer_Grant(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := NewServer(15 * time.Minute)
+	s.now = func() time.Time { return now }
+
+	assert.NotEmpty(t, s.Handle(Request{Op: OpGrant, Value: []byte("v"), Once: true}).Error)
+	assert.Equal(t, ErrGrantInvalid.Error(), s.Handle(Request{Op: OpRedeem, Token: "nope"}).Error)
+
+	// A one-time grant is redeemed once, then wiped
+	once := s.Handle(Request{Op: OpGrant, Value: []byte("secret"), TTL: 5 * time.Minute, Once: true})
+	require.Empty(t, once.Error)
+	require.Len(t, once.Token, 2*tokenLength)
+	held := s.grants[once.Token].value
+	assert.Equal(t, 1, s.Handle(Request{Op: OpStatus}).Grants)
+	assert.Equal(t, []byte("secret"), s.Handle(Request{Op: OpRedeem, Token: once.Token}).Value)
+	assert.Equal(t, make([]byte, len("secret")), held)
+	assert.Equal(t, ErrGrantInvalid.Error(), s.Handle(Request{Op: OpRedeem, Token: once.Token}).Error)
+
+	// Others last until their TTL, which using them does not extend
+	many := s.Handle(Request{Op: OpGrant, Value: []byte("secret"), TTL: 5 * time.Minute})
+	now = now.Add(4 * time.Minute)
+	assert.Equal(t, []byte("secret"), s.Handle(Request{Op: OpRedeem, Token: many.Token}).Value)
+	assert.Equal(t, []byte("secret"), s.Handle(Request{Op: OpRedeem, Token: many.Token}).Value)
+	now = now.Add(time.Minute)
+	assert.Equal(t, ErrGrantInvalid.Error(), s.Handle(Request{Op: OpRedeem, Token: many.Token}).Error)
+
+	// Locking the agent drops grants along with keys
+	pending := s.Handle(Request{Op: OpGrant, Value: []byte("secret"), TTL: time.Minute, Once: true})
+	require.Empty(t, s.Handle(Request{Op: OpLock}).Error)
+	assert.Equal(t, ErrGrantInvalid.Error(), s.Handle(Request{Op: OpRedeem, Token: pending.Token}).Error)
+}
+
+func TestClient(t *testing.T) {
+	// Unix socket paths are short, so avoid the long test temp directory
+	dir, err := os.MkdirTemp("", "lockr-agent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sub", "agent.sock")
+
+	client := NewClient(path)
+	assert.False(t, client.Running())
+	_, err = client.Key("v1")
+	assert.ErrorIs(t, err, ErrNotRunning)
+
+	l, err := Listen(path)
+	require.NoError(t, err)
+	info, err := os.Stat(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	served := make(chan error, 1)
+	go func() { served <- NewServer(time.Minute).Serve(l) }()
+
+	assert.True(t, client.Running())
+	_, err = Listen(path)
+	assert.Error(t, err, "a second agent refuses to start")
+
+	_, err = client.Key("v1")
+	assert.ErrorIs(t, err, ErrLocked)
+	require.NoError(t, client.Add("v1", "/vault", []byte("secret key")))
+	key, err := client.Key("v1")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret key"), key)
+
+	status, err := client.Status()
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), status.PID)
+	assert.Len(t, status.Vaults, 1)
+
+	token, err := client.Grant([]byte("value"), time.Minute, true)
+	require.NoError(t, err)
+	value, err := client.Redeem(token)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+	_, err = client.Redeem(token)
+	assert.ErrorIs(t, err, ErrGrantInvalid)
+
+	_, err = client.Stop()
+	require.NoError(t, err)
+	require.NoError(t, <-served)
+	assert.False(t, client.Running())
+
+	// The socket it left behind does not stop a new agent
+	l, err = Listen(path)
+	require.NoError(t, err)
+	l.Close()
+}
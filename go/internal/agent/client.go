@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// dialTimeout bounds how long a command waits for the agent, so a wedged
+// agent cannot hang lockr
+const dialTimeout = 2 * time.Second
+
+// Client talks to the agent listening on a socket
+type Client struct {
+	path string
+}
+
+// NewClient returns a client for the agent on the socket at path
+func NewClient(path string) *Client {
+	return &Client{path: path}
+}
+
+// Path returns the socket the client talks to
+func (c *Client) Path() string {
+	return c.path
+}
+
+// Running reports whether an agent answers on the socket
+func (c *Client) Running() bool {
+	_, err := c.call(Request{Op: OpStatus})
+	return err == nil
+}
+
+// Key returns the raw key of vault and restarts its timeout, or ErrLocked
+func (c *Client) Key(vault string) ([]byte, error) {
+	resp, err := c.call(Request{Op: OpGet, Vault: vault})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Key, nil
+}
+
+// Add hands the raw key of vault to the agent, replacing any it held.
+// label names the vault in status output.
+func (c *Client) Add(vault, label string, key []byte) error {
+	_, err := c.call(Request{Op: OpAdd, Vault: vault, Label: label, Key: key})
+	return err
+}
+
+// Lock makes the agent forget the key of vault, or every key if vault is ""
+func (c *Client) Lock(vault string) error {
+	_, err := c.call(Request{Op: OpLock, Vault: vault})
+	return err
+}
+
+// Grant hands value to the agent and returns a token that retrieves it
+// until ttl passes; with once, only the first retrieval succeeds
+func (c *Client) Grant(value []byte, ttl time.Duration, once bool) (string, error) {
+	resp, err := c.call(Request{Op: OpGrant, Value: value, TTL: ttl, Once: once})
+	if err != nil {
+		return "", err
+	}
+	return resp.Token, nil
+}
+
+// Redeem returns the value granted under token, or ErrGrantInvalid
+func (c *Client) Redeem(token string) ([]byte, error) {
+	resp, err := c.call(Request{Op: OpRedeem, Token: token})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Value, nil
+}
+
+// Info describes a running agent
+type Info struct {
+	PID    int
+	Vaults []VaultStatus
+	Grants int
+}
+
+// Status returns the agent's process ID and what it holds
+func (c *Client) Status() (Info, error) {
+	resp, err := c.call(Request{Op: OpStatus})
+	if err != nil {
+		return Info{}, err
+	}
+	return Info{PID: resp.PID, Vaults: resp.Vaults, Grants: resp.Grants}, nil
+}
+
+// Stop makes the agent forget every key and exit, returning its process ID
+func (c *Client) Stop() (int, error) {
+	resp, err := c.call(Request{Op: OpStop})
+	if err != nil {
+		return 0, err
+	}
+	return resp.PID, nil
+}
+
+// call sends req on a new connection and reads the response
+func (c *Client) call(req Request) (Response, error) {
+	conn, err := net.DialTimeout("unix", c.path, dialTimeout)
+	if err != nil {
+		return Response{}, fmt.Errorf("%w: %v", ErrNotRunning, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return Response{}, fmt.Errorf("failed to talk to agent: %w", err)
+	}
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to talk to agent: %w", err)
+	}
+
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return Response{}, fmt.Errorf("invalid agent response: %w", err)
+	}
+	if resp.Error != "" {
+		for _, known := range []error{ErrLocked, ErrGrantInvalid} {
+			if resp.Error == known.Error() {
+				return resp, known
+			}
+		}
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
@@ -0,0 +1,273 @@
+// Package api serves the vault to local programs (scripts, editors, GUI
+// front-ends) as a small JSON REST API, meant to listen on a unix socket
+// only its user can reach.
+//
+// Every request carries a bearer token issued to a named client. The
+// Backend decides what that client may do, checks that the vault's session
+// is still valid and records what it reads and writes.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/lockr/go/internal/authz"
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/policy"
+	"github.com/lockr/go/internal/security"
+)
+
+// MaxBodySize caps request bodies; values are at most this large
+const MaxBodySize = 4 << 20
+
+// ErrUnauthorized is returned for a missing or unknown token
+var ErrUnauthorized = errors.New("missing or unknown API token")
+
+// ErrLocked is returned by a Backend when the vault is locked and cannot be
+// unlocked without a person at the terminal
+var ErrLocked = errors.New("vault is locked")
+
+// Entry describes a secret without its value
+type Entry struct {
+	Key       string    `json:"key"`
+	Tags      []string  `json:"tags,omitempty"`
+	Revision  int64     `json:"revision"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Secret is a secret's value as returned by a get
+type Secret struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Revision int64  `json:"revision"`
+}
+
+// Backend answers the requests that touch the vault on behalf of client,
+// the identity a token was issued to
+type Backend interface {
+	// Authenticate returns the client a token was issued to, or
+	// ErrUnauthorized
+	Authenticate(token string) (string, error)
+	// List returns the secrets matching a glob pattern ("" for all) that
+	// client may list
+	List(client, pattern string) ([]Entry, error)
+	// Get returns the value of key
+	Get(client, key string) (Secret, error)
+	// Set stores value under key, creating it if needed, and reports
+	// whether it was created
+	Set(client, key, value string) (bool, error)
+	// Delete deletes key
+	Delete(client, key string) error
+}
+
+// setRequest is the body of a PUT
+type setRequest struct {
+	Value *string `json:"value"`
+}
+
+// setResponse is the body of a successful PUT
+type setResponse struct {
+	Key string `json:"key"`
+}
+
+// listResponse is the body of a successful list
+type listResponse struct {
+	Secrets []Entry `json:"secrets"`
+}
+
+// errorResponse is the body of every failed request
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewHandler returns the API's routes, served from backend. Failed token
+// checks are rate limited.
+func NewHandler(backend Backend) http.Handler {
+	h := &handler{backend: backend, limiter: security.NewDefaultLimiter()}
+	mux := http.NewServeMux()
+	for _, rt := range routes {
+		serve := rt.serve
+		mux.HandleFunc(rt.method+" "+rt.path, h.authenticated(func(w http.ResponseWriter, r *http.Request, client string) {
+			serve(h, w, r, client)
+		}))
+	}
+	return mux
+}
+
+// handler holds the state shared by the routes
+type handler struct {
+	backend Backend
+	limiter *security.Limiter
+}
+
+// peerKey is the context key under which ConnContext records the caller
+type peerKey struct{}
+
+// connections numbers connections whose peer process cannot be identified
+var connections atomic.Uint64
+
+// ConnContext records which process is at the other end of c, so that one
+// caller's wrong tokens do not lock out the others. Set it as the
+// http.Server's ConnContext. Where the platform cannot name the process,
+// each connection counts as its own caller.
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	peer := peerID(c)
+	if peer == "" {
+		peer = fmt.Sprintf("conn:%d", connections.Add(1))
+	}
+	return context.WithValue(ctx, peerKey{}, peer)
+}
+
+// limiterKey is the limiter entry for failed token checks made by the
+// caller of r: its process if ConnContext recorded it, else its address
+func limiterKey(r *http.Request) string {
+	if peer, ok := r.Context().Value(peerKey{}).(string); ok {
+		return "rest/" + peer
+	}
+	if r.RemoteAddr != "" {
+		return "rest/" + r.RemoteAddr
+	}
+	return "rest"
+}
+
+// authenticated wraps a route with the token check, passing the client on
+func (h *handler) authenticated(route func(w http.ResponseWriter, r *http.Request, client string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limiterKey := limiterKey(r)
+		if err := h.limiter.Check(limiterKey); err != nil {
+			writeError(w, err)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			writeError(w, ErrUnauthorized)
+			return
+		}
+		client, err := h.backend.Authenticate(token)
+		if err != nil {
+			if errors.Is(err, ErrUnauthorized) {
+				h.limiter.Failure(limiterKey)
+			}
+			writeError(w, err)
+			return
+		}
+		h.limiter.Success(limiterKey)
+		route(w, r, client)
+	}
+}
+
+func (h *handler) list(w http.ResponseWriter, r *http.Request, client string) {
+	entries, err := h.backend.List(client, r.URL.Query().Get("pattern"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if entries == nil {
+		entries = []Entry{}
+	}
+	writeJSON(w, http.StatusOK, listResponse{entries})
+}
+
+func (h *handler) get(w http.ResponseWriter, r *http.Request, client string) {
+	secret, err := h.backend.Get(client, r.PathValue("key"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, secret)
+}
+
+func (h *handler) set(w http.ResponseWriter, r *http.Request, client string) {
+	var req setRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodySize)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{fmt.Sprintf("invalid body: %v", err)})
+		return
+	}
+	if req.Value == nil || *req.Value == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{`body needs a non-empty "value"`})
+		return
+	}
+
+	key := r.PathValue("key")
+	created, err := h.backend.Set(client, key, *req.Value)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, setResponse{key})
+}
+
+func (h *handler) delete(w http.ResponseWriter, r *http.Request, client string) {
+	if err := h.backend.Delete(client, r.PathValue("key")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeError answers with the status matching err
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var limited *security.RateLimitError
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		status = http.StatusUnauthorized
+	case errors.As(err, &limited):
+		w.Header().Set("Retry-After", fmt.Sprint(int(limited.RetryAfter.Seconds()+0.5)))
+		status = http.StatusTooManyRequests
+	case errors.Is(err, authz.ErrDenied), errors.Is(err, policy.ErrClipboardOnly):
+		status = http.StatusForbidden
+	case errors.Is(err, database.ErrKeyNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, database.ErrInvalidKey), errors.Is(err, database.ErrInvalidPattern):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrLocked):
+		status = http.StatusLocked
+	}
+	writeJSON(w, status, errorResponse{err.Error()})
+}
+
+// writeJSON answers with status and v as JSON
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Listen creates the socket at path, in a directory only the current user
+// can enter, readable and writable by the user alone. A socket left behind
+// by a server that died is replaced; one a server still answers on is an
+// error.
+func Listen(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("an API server is already listening on %s", path)
+	}
+	os.Remove(path)
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lockr/go/internal/authz"
+	"github.com/lockr/go/internal/database"
+)
+
+// fakeBackend keeps secrets in a map and lets rest:ci do anything under ci/
+type fakeBackend struct {
+	tokens  Tokens
+	secrets map[string]string
+	locked  bool
+}
+
+func (b *fakeBackend) Authenticate(token string) (string, error) {
+	t, ok := b.tokens.Match(token)
+	if !ok {
+		return "", ErrUnauthorized
+	}
+	return t.Client(), nil
+}
+
+func (b *fakeBackend) check(client, key string) error {
+	if b.locked {
+		return ErrLocked
+	}
+	if client != "rest:ci" || !strings.HasPrefix(key, "ci/") {
+		return authz.ErrDenied
+	}
+	return nil
+}
+
+func (b *fakeBackend) List(client, pattern string) ([]Entry, error) {
+	var entries []Entry
+	for key := range b.secrets {
+		if b.check(client, key) == nil {
+			entries = append(entries, Entry{Key: key})
+		}
+	}
+	return entries, nil
+}
+
+func (b *fakeBackend) Get(client, key string) (Secret, error) {
+	if err := b.check(client, key); err != nil {
+		return Secret{}, err
+	}
+	value, ok := b.secrets[key]
+	if !ok {
+		return Secret{}, database.ErrKeyNotFound
+	}
+	return Secret{Key: key, Value: value, Revision: 1}, nil
+}
+
+func (b *fakeBackend) Set(client, key, value string) (bool, error) {
+	if err := b.check(client, key); err != nil {
+		return false, err
+	}
+	_, exists := b.secrets[key]
+	b.secrets[key] = value
+	return !exists, nil
+}
+
+func (b *fakeBackend) Delete(client, key string) error {
+	if err := b.check(client, key); err != nil {
+		return err
+	}
+	if _, ok := b.secrets[key]; !ok {
+		return database.ErrKeyNotFound
+	}
+	delete(b.secrets, key)
+	return nil
+}
+
+func newTestServer(t *testing.T) (*fakeBackend, http.Handler, string) {
+	t.Helper()
+	token, secret, err := NewToken("ci")
+	require.NoError(t, err)
+	backend := &fakeBackend{tokens: Tokens{token}, secrets: map[string]string{"ci/db": "s3cret", "prod/db": "other"}}
+	return backend, NewHandler(backend), secret
+}
+
+func do(h http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	return doFrom(h, "pid:1", method, path, token, body)
+}
+
+// doFrom makes a request as the process named peer
+func doFrom(h http.Handler, peer, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), peerKey{}, peer))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler_Secrets(t *testing.T) {
+	backend, h, token := newTestServer(t)
+
+	rec := do(h, "GET", "/v1/secrets/ci/db", token, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var secret Secret
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &secret))
+	assert.Equal(t, "s3cret", secret.Value)
+
+	rec = do(h, "GET", "/v1/secrets", token, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"secrets": [{"key": "ci/db", "revision": 0, "created_at": "0001-01-01T00:00:00Z"}]}`, rec.Body.String())
+
+	assert.Equal(t, http.StatusCreated, do(h, "PUT", "/v1/secrets/ci/new", token, `{"value": "v1"}`).Code)
+	assert.Equal(t, http.StatusOK, do(h, "PUT", "/v1/secrets/ci/new", token, `{"value": "v2"}`).Code)
+	assert.Equal(t, "v2", backend.secrets["ci/new"])
+	assert.Equal(t, http.StatusBadRequest, do(h, "PUT", "/v1/secrets/ci/new", token, `{"value": ""}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(h, "PUT", "/v1/secrets/ci/new", token, `nope`).Code)
+
+	assert.Equal(t, http.StatusNoContent, do(h, "DELETE", "/v1/secrets/ci/new", token, "").Code)
+	assert.Equal(t, http.StatusNotFound, do(h, "DELETE", "/v1/secrets/ci/new", token, "").Code)
+	assert.Equal(t, http.StatusForbidden, do(h, "GET", "/v1/secrets/prod/db", token, "").Code)
+
+	backend.locked = true
+	assert.Equal(t, http.StatusLocked, do(h, "GET", "/v1/secrets/ci/db", token, "").Code)
+}
+
+func TestHandler_Tokens(t *testing.T) {
+	_, h, token := newTestServer(t)
+
+	assert.Equal(t, http.StatusUnauthorized, do(h, "GET", "/v1/secrets/ci/db", "", "").Code)
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusUnauthorized, do(h, "GET", "/v1/secrets/ci/db", "lockr_api_wrong", "").Code)
+	}
+
+	// Too many wrong tokens lock out that caller for a while
+	rec := do(h, "GET", "/v1/secrets/ci/db", token, "")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+
+	// but not the other processes using the socket
+	assert.Equal(t, http.StatusOK, doFrom(h, "pid:2", "GET", "/v1/secrets/ci/db", token, "").Code)
+}
+
+func TestConnContext(t *testing.T) {
+	dir, err := os.MkdirTemp("", "lockr-api")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l, err := net.Listen("unix", filepath.Join(dir, "api.sock"))
+	require.NoError(t, err)
+	defer l.Close()
+
+	client, err := net.Dial("unix", l.Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	server, err := l.Accept()
+	require.NoError(t, err)
+	defer server.Close()
+
+	peer, ok := ConnContext(context.Background(), server).Value(peerKey{}).(string)
+	require.True(t, ok)
+	assert.NotEmpty(t, peer)
+	if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
+		assert.Equal(t, fmt.Sprintf("pid:%d", os.Getpid()), peer)
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	data, err := OpenAPI("1.2.3")
+	require.NoError(t, err)
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Version string `json:"version"`
+		} `json:"info"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Required []string `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Equal(t, "1.2.3", doc.Info.Version)
+
+	// Every served route is described
+	require.Len(t, doc.Paths, 2)
+	assert.Contains(t, doc.Paths["/v1/secrets"], "get")
+	for _, method := range []string{"get", "put", "delete"} {
+		assert.Contains(t, doc.Paths["/v1/secrets/{key}"], method)
+	}
+	assert.Contains(t, string(doc.Paths["/v1/secrets/{key}"]["put"]), `"201"`)
+
+	assert.Equal(t, []string{"key", "revision", "value"}, doc.Components.Schemas["Secret"].Required)
+	assert.Equal(t, []string{"value"}, doc.Components.Schemas["SetRequest"].Required)
+	assert.Contains(t, doc.Components.Schemas, "Entry")
+}
+
+func TestTokens(t *testing.T) {
+	ci, secret, err := NewToken("ci")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, "lockr_api_"))
+	assert.NotContains(t, ci.Hash, secret)
+	_, _, err = NewToken("rest *")
+	assert.Error(t, err)
+
+	other, _, err := NewToken("raycast")
+	require.NoError(t, err)
+	tokens := Tokens{ci, other}
+
+	found, ok := tokens.Match(secret)
+	require.True(t, ok)
+	assert.Equal(t, "rest:ci", found.Client())
+	_, ok = tokens.Match(secret + "x")
+	assert.False(t, ok)
+
+	data, err := EncodeTokens(tokens.Without("raycast"))
+	require.NoError(t, err)
+	decoded, err := DecodeTokens(data)
+	require.NoError(t, err)
+	assert.Len(t, decoded, 1)
+	_, ok = decoded.Find("ci")
+	assert.True(t, ok)
+}
@@ -0,0 +1,253 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// route is one endpoint. NewHandler serves the routes and OpenAPI describes
+// them, so the description cannot drift from what is served.
+type route struct {
+	method  string
+	path    string // http.ServeMux pattern
+	id      string
+	summary string
+	serve   func(h *handler, w http.ResponseWriter, r *http.Request, client string)
+	query   map[string]string // query parameters and their descriptions
+	body    any               // request body, nil for none
+	replies []reply
+}
+
+// reply is one documented response of a route
+type reply struct {
+	status      int
+	description string
+	body        any // nil for an empty body
+}
+
+// routes are the API's endpoints
+var routes = []route{
+	{
+		method:  http.MethodGet,
+		path:    "/v1/secrets",
+		id:      "listSecrets",
+		summary: "List the keys, tags and revisions of the secrets the client may list; never values",
+		serve:   (*handler).list,
+		query:   map[string]string{"pattern": "Glob the keys must match, such as ci/*; all keys when empty"},
+		replies: []reply{
+			{http.StatusOK, "The matching secrets", listResponse{}},
+			{http.StatusBadRequest, "Invalid pattern", errorResponse{}},
+		},
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/v1/secrets/{key...}",
+		id:      "getSecret",
+		summary: "Get the value of a secret, with its references resolved",
+		serve:   (*handler).get,
+		replies: []reply{
+			{http.StatusOK, "The secret", Secret{}},
+			{http.StatusForbidden, "Denied by access rules, or the secret (or one it references) is clipboard-only", errorResponse{}},
+			{http.StatusNotFound, "No such secret", errorResponse{}},
+		},
+	},
+	{
+		method:  http.MethodPut,
+		path:    "/v1/secrets/{key...}",
+		id:      "setSecret",
+		summary: "Store a value under a key, creating the secret if needed",
+		serve:   (*handler).set,
+		body:    setRequest{},
+		replies: []reply{
+			{http.StatusOK, "The secret was updated", setResponse{}},
+			{http.StatusCreated, "The secret was created", setResponse{}},
+			{http.StatusBadRequest, "Invalid key or body", errorResponse{}},
+			{http.StatusForbidden, "Denied by access rules", errorResponse{}},
+		},
+	},
+	{
+		method:  http.MethodDelete,
+		path:    "/v1/secrets/{key...}",
+		id:      "deleteSecret",
+		summary: "Delete a secret",
+		serve:   (*handler).delete,
+		replies: []reply{
+			{http.StatusNoContent, "The secret was deleted", nil},
+			{http.StatusForbidden, "Denied by access rules", errorResponse{}},
+			{http.StatusNotFound, "No such secret", errorResponse{}},
+		},
+	},
+}
+
+// commonReplies can be the answer to any request
+var commonReplies = []reply{
+	{http.StatusUnauthorized, "Missing or unknown API token", errorResponse{}},
+	{http.StatusLocked, "The vault is locked and cannot be unlocked without a person at the terminal", errorResponse{}},
+	{http.StatusTooManyRequests, "Too many wrong tokens; retry after the number of seconds in Retry-After", errorResponse{}},
+	{http.StatusInternalServerError, "The vault could not answer", errorResponse{}},
+}
+
+// OpenAPI returns an OpenAPI 3 description of the API as indented JSON, for
+// clients that generate typed bindings. version is lockr's version.
+func OpenAPI(version string) ([]byte, error) {
+	schemas := map[string]any{}
+	paths := map[string]map[string]any{}
+
+	for _, rt := range routes {
+		path, params := openAPIPath(rt.path)
+		for name, description := range rt.query {
+			params = append(params, map[string]any{
+				"name":        name,
+				"in":          "query",
+				"description": description,
+				"schema":      map[string]any{"type": "string"},
+			})
+		}
+
+		responses := map[string]any{}
+		for _, r := range append(append([]reply{}, rt.replies...), commonReplies...) {
+			response := map[string]any{"description": r.description}
+			if r.body != nil {
+				response["content"] = jsonContent(schemaRef(reflect.TypeOf(r.body), schemas))
+			}
+			if r.status == http.StatusTooManyRequests {
+				response["headers"] = map[string]any{
+					"Retry-After": map[string]any{"schema": map[string]any{"type": "integer"}},
+				}
+			}
+			responses[strconv.Itoa(r.status)] = response
+		}
+
+		operation := map[string]any{
+			"operationId": rt.id,
+			"summary":     rt.summary,
+			"responses":   responses,
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if rt.body != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(schemaRef(reflect.TypeOf(rt.body), schemas)),
+			}
+		}
+
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(rt.method)] = operation
+	}
+
+	doc := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "lockr local API",
+			"version":     version,
+			"description": "Served by 'lockr serve' on a unix socket only its user can reach. Tokens come from 'lockr serve token create'; access rules ('lockr acl') decide what each may do.",
+		},
+		"servers": []any{
+			map[string]any{"url": "http://lockr", "description": "Any host name; connect to the unix socket, by default api.sock in lockr's runtime directory"},
+		},
+		"security": []any{map[string]any{"bearer": []string{}}},
+		"paths":    paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"bearer": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// openAPIPath converts a ServeMux pattern to an OpenAPI path and its path
+// parameters. A {name...} wildcard matches the rest of the path, slashes
+// included, as keys such as ci/db need.
+func openAPIPath(pattern string) (string, []any) {
+	var params []any
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		name, ok := strings.CutPrefix(segment, "{")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSuffix(name, "}")
+		param := map[string]any{"in": "path", "required": true, "schema": map[string]any{"type": "string"}}
+		if rest, ok := strings.CutSuffix(name, "..."); ok {
+			name = rest
+			param["description"] = "The rest of the path, slashes included and not escaped, such as ci/db"
+		}
+		param["name"] = name
+		segments[i] = "{" + name + "}"
+		params = append(params, param)
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// jsonContent is a content map holding schema as application/json
+func jsonContent(schema any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+// schemaRef adds the schema of struct type t to schemas and returns a
+// reference to it
+func schemaRef(t reflect.Type, schemas map[string]any) map[string]any {
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	if _, ok := schemas[name]; !ok {
+		schemas[name] = objectSchema(t, schemas)
+	}
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// schemaOf returns the JSON schema of t as encoding/json serializes it.
+// Structs are referenced from schemas.
+func schemaOf(t reflect.Type, schemas map[string]any) map[string]any {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaOf(t.Elem(), schemas)
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem(), schemas)}
+	case reflect.Struct:
+		return schemaRef(t, schemas)
+	}
+	return map[string]any{}
+}
+
+// objectSchema returns the JSON schema of struct type t. Fields without
+// omitempty are required.
+func objectSchema(t reflect.Type, schemas map[string]any) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		properties[name] = schemaOf(field.Type, schemas)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// peerID names the process at the other end of a unix socket connection,
+// from the credentials the kernel recorded when it connected
+func peerID(c net.Conn) string {
+	uc, ok := c.(*net.UnixConn)
+	if !ok {
+		return ""
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return ""
+	}
+	var pid int
+	raw.Control(func(fd uintptr) {
+		pid, err = unix.GetsockoptInt(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERPID)
+	})
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("pid:%d", pid)
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package api
+
+import "net"
+
+// peerID cannot identify the peer of a connection on this platform
+func peerID(c net.Conn) string {
+	return ""
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lockr/go/internal/security"
+)
+
+// ClientPrefix starts the client identity of every API token, as matched by
+// access rules: a token named ci acts as rest:ci
+const ClientPrefix = "rest:"
+
+// tokenPrefix marks API tokens so they are recognizable in scripts and
+// secret scanners
+const tokenPrefix = "lockr_api_"
+
+// tokenLength is the number of random bytes in a token
+const tokenLength = 32
+
+// Token is an issued API token. Only a hash of the token is kept.
+type Token struct {
+	Name      string    `json:"name"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Client is the identity the token acts as
+func (t Token) Client() string {
+	return ClientPrefix + t.Name
+}
+
+// Tokens are the tokens issued for a vault
+type Tokens []Token
+
+// NewToken issues a token named name and returns it along with the token
+// itself, which is shown once and never stored
+func NewToken(name string) (Token, string, error) {
+	if strings.TrimSpace(name) == "" || strings.ContainsAny(name, " \t\n*?") {
+		return Token{}, "", fmt.Errorf("invalid token name %q: use a word such as ci or raycast", name)
+	}
+	b := make([]byte, tokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return Token{}, "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	secret := tokenPrefix + hex.EncodeToString(b)
+	return Token{Name: name, Hash: hashToken(secret), CreatedAt: time.Now().UTC()}, secret, nil
+}
+
+// Find returns the token named name
+func (ts Tokens) Find(name string) (Token, bool) {
+	for _, t := range ts {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Token{}, false
+}
+
+// Match returns the token secret was issued as. Every stored hash is
+// compared in constant time.
+func (ts Tokens) Match(secret string) (Token, bool) {
+	hash := hashToken(secret)
+	var found Token
+	ok := false
+	for _, t := range ts {
+		if security.Equal(hash, t.Hash) {
+			found, ok = t, true
+		}
+	}
+	return found, ok
+}
+
+// Without returns the tokens except the one named name
+func (ts Tokens) Without(name string) Tokens {
+	var rest Tokens
+	for _, t := range ts {
+		if t.Name != name {
+			rest = append(rest, t)
+		}
+	}
+	return rest
+}
+
+// DecodeTokens parses tokens stored with EncodeTokens
+func DecodeTokens(data string) (Tokens, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var ts Tokens
+	if err := json.Unmarshal([]byte(data), &ts); err != nil {
+		return nil, fmt.Errorf("invalid API tokens: %w", err)
+	}
+	return ts, nil
+}
+
+// EncodeTokens serialises tokens for storage
+func EncodeTokens(ts Tokens) (string, error) {
+	data, err := json.Marshal(ts)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// hashToken returns the stored form of a token
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
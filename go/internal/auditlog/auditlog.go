@@ -0,0 +1,167 @@
+// Package auditlog exports the vault's audit trail as a self-describing
+// document that can be signed with gpg or minisign and kept outside the
+// vault, e.g. for compliance records
+package auditlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/update"
+)
+
+// Version is the version of the export format written by Marshal
+const Version = 1
+
+// Signers that can sign an export
+const (
+	SignerGPG      = "gpg"
+	SignerMinisign = "minisign"
+)
+
+// Export is the audit trail of one vault at the time it was exported
+type Export struct {
+	Version      int                    `json:"version"`
+	Vault        string                 `json:"vault"`
+	ExportedAt   time.Time              `json:"exported_at"`
+	Events       []database.AuditEvent  `json:"events"`
+	AuthAttempts []database.AuthAttempt `json:"auth_attempts"`
+}
+
+// New returns an export of the given events and attempts, which the vault
+// lists newest first, in chronological order
+func New(vault string, events []database.AuditEvent, attempts []database.AuthAttempt) *Export {
+	e := &Export{
+		Version:      Version,
+		Vault:        vault,
+		ExportedAt:   time.Now().UTC(),
+		Events:       slices.Clone(events),
+		AuthAttempts: slices.Clone(attempts),
+	}
+	slices.Reverse(e.Events)
+	slices.Reverse(e.AuthAttempts)
+	if e.Events == nil {
+		e.Events = []database.AuditEvent{}
+	}
+	if e.AuthAttempts == nil {
+		e.AuthAttempts = []database.AuthAttempt{}
+	}
+	return e
+}
+
+// Marshal encodes the export as indented JSON, the bytes that get signed
+func (e *Export) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Parse decodes an export written by Marshal
+func Parse(data []byte) (*Export, error) {
+	var e Export
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("not an audit log export: %w", err)
+	}
+	if e.Version != Version {
+		return nil, fmt.Errorf("unsupported audit log export version %d", e.Version)
+	}
+	return &e, nil
+}
+
+// Span returns the times of the oldest and newest entries, or zero times
+// for an empty export
+func (e *Export) Span() (oldest, newest time.Time) {
+	for _, ev := range e.Events {
+		oldest, newest = widen(oldest, newest, ev.Timestamp)
+	}
+	for _, a := range e.AuthAttempts {
+		oldest, newest = widen(oldest, newest, a.Timestamp)
+	}
+	return oldest, newest
+}
+
+func widen(oldest, newest, t time.Time) (time.Time, time.Time) {
+	if oldest.IsZero() || t.Before(oldest) {
+		oldest = t
+	}
+	if t.After(newest) {
+		newest = t
+	}
+	return oldest, newest
+}
+
+// SignatureFile returns where the signature of the export at path is kept
+// for signer: path.asc for gpg and path.minisig for minisign
+func SignatureFile(path, signer string) string {
+	if signer == SignerMinisign {
+		return path + ".minisig"
+	}
+	return path + ".asc"
+}
+
+// Sign signs the export at path with signer, writing a detached signature
+// next to it, and returns the signature's path. key selects the gpg key
+// (--local-user) or minisign secret key file; "" uses the signer's default.
+// The signer asks for its passphrase on the terminal.
+func Sign(path, signer, key, comment string) (string, error) {
+	sigPath := SignatureFile(path, signer)
+	var cmd *exec.Cmd
+	switch signer {
+	case SignerGPG:
+		args := []string{"--armor", "--detach-sign", "--yes", "--output", sigPath}
+		if key != "" {
+			args = append(args, "--local-user", key)
+		}
+		cmd = exec.Command("gpg", append(args, path)...)
+	case SignerMinisign:
+		args := []string{"-S", "-m", path, "-x", sigPath, "-t", comment}
+		if key != "" {
+			args = append(args, "-s", key)
+		}
+		cmd = exec.Command("minisign", args...)
+	default:
+		return "", fmt.Errorf("unknown signer %q (want gpg or minisign)", signer)
+	}
+
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stderr, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s failed: %w", signer, err)
+	}
+	return sigPath, nil
+}
+
+// VerifyGPG checks the detached gpg signature at sigPath against the export
+// at path and returns what gpg reports about the signer
+func VerifyGPG(path, sigPath string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("gpg", "--verify", sigPath, path)
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	report := strings.TrimSpace(stderr.String())
+	if err != nil {
+		if report != "" {
+			return "", fmt.Errorf("bad gpg signature: %s", report)
+		}
+		return "", fmt.Errorf("gpg failed: %w", err)
+	}
+	return report, nil
+}
+
+// VerifyMinisign checks a minisign signature of the export data against
+// publicKey, a minisign .pub file's contents or its key line
+func VerifyMinisign(data, signature []byte, publicKey string) error {
+	key, err := update.ParsePublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+	return key.Verify(data, signature)
+}
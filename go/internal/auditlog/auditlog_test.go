@@ -0,0 +1,77 @@
+package auditlog
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/lockr/go/internal/database"
+)
+
+func TestExport(t *testing.T) {
+	t1 := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	key := "db/prod"
+	events := []database.AuditEvent{
+		{ID: 2, Timestamp: t2, Event: database.AuditEventGet, Key: &key},
+		{ID: 1, Timestamp: t1, Event: database.AuditEventGet, Key: &key},
+	}
+	attempts := []database.AuthAttempt{{ID: 1, Timestamp: t1.Add(-time.Minute), Success: true}}
+
+	export := New("/vault", events, attempts)
+	assert.Equal(t, int64(1), export.Events[0].ID, "oldest first")
+	assert.Equal(t, int64(2), events[0].ID, "the caller's slice is left alone")
+
+	data, err := export.Marshal()
+	require.NoError(t, err)
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, "/vault", parsed.Vault)
+	assert.Len(t, parsed.Events, 2)
+	oldest, newest := parsed.Span()
+	assert.Equal(t, t1.Add(-time.Minute), oldest)
+	assert.Equal(t, t2, newest)
+
+	empty, err := New("/vault", nil, nil).Marshal()
+	require.NoError(t, err)
+	assert.Contains(t, string(empty), `"events": []`)
+
+	_, err = Parse([]byte(`{"version": 9}`))
+	assert.Error(t, err)
+	_, err = Parse([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestVerifyMinisign(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keyID := []byte("12345678")
+	publicKey := "untrusted comment: minisign public key\n" +
+		base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), pub...)) + "\n"
+
+	data, err := New("/vault", nil, nil).Marshal()
+	require.NoError(t, err)
+	digest := blake2b.Sum512(data)
+	sig := ed25519.Sign(priv, digest[:])
+	comment := "lockr audit log"
+	global := ed25519.Sign(priv, append(append([]byte{}, sig...), comment...))
+	signature := []byte(fmt.Sprintf("untrusted comment: signature\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(append(append([]byte("ED"), keyID...), sig...)),
+		comment, base64.StdEncoding.EncodeToString(global)))
+
+	assert.NoError(t, VerifyMinisign(data, signature, publicKey))
+	assert.Error(t, VerifyMinisign(append(data, ' '), signature, publicKey))
+	assert.Error(t, VerifyMinisign(data, signature, "not a key"))
+}
+
+func TestSignatureFile(t *testing.T) {
+	assert.Equal(t, "audit.json.asc", SignatureFile("audit.json", SignerGPG))
+	assert.Equal(t, "audit.json.minisig", SignatureFile("audit.json", SignerMinisign))
+}
@@ -0,0 +1,179 @@
+// Package authz decides which keys and operations each local client may use
+// when the vault is served to other programs. Access is denied unless a
+// rule allows it.
+package authz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Operations a rule can allow
+const (
+	OpGet    = "get"
+	OpList   = "list"
+	OpSet    = "set"
+	OpDelete = "delete"
+	OpAll    = "*"
+)
+
+// ValidOps lists the operations accepted in rules
+var ValidOps = []string{OpGet, OpList, OpSet, OpDelete, OpAll}
+
+// ErrDenied is returned for requests no rule allows
+var ErrDenied = errors.New("access denied")
+
+// Rule allows a client identity to perform some operations on keys matching
+// some patterns. Client and key patterns are case-insensitive globs where *
+// matches any run of characters, including '/', and ? matches one.
+type Rule struct {
+	Client string   `json:"client"`
+	Keys   []string `json:"keys"`
+	Ops    []string `json:"ops"`
+}
+
+// Validate reports whether the rule is complete and well formed
+func (r Rule) Validate() error {
+	if strings.TrimSpace(r.Client) == "" {
+		return errors.New("rule needs a client identity")
+	}
+	if len(r.Keys) == 0 {
+		return errors.New("rule needs at least one key pattern")
+	}
+	if len(r.Ops) == 0 {
+		return errors.New("rule needs at least one operation")
+	}
+	for _, op := range r.Ops {
+		if !validOp(op) {
+			return fmt.Errorf("unknown operation %q (use %s)", op, strings.Join(ValidOps, ", "))
+		}
+	}
+	return nil
+}
+
+// Allows reports whether the rule permits client to perform op on key
+func (r Rule) Allows(client, op, key string) bool {
+	if !matchGlob(r.Client, client) {
+		return false
+	}
+	opAllowed := false
+	for _, allowed := range r.Ops {
+		if allowed == OpAll || allowed == op {
+			opAllowed = true
+			break
+		}
+	}
+	if !opAllowed {
+		return false
+	}
+	for _, pattern := range r.Keys {
+		if matchGlob(pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// String describes the rule for display
+func (r Rule) String() string {
+	return fmt.Sprintf("%s may %s %s", r.Client, strings.Join(r.Ops, ","), strings.Join(r.Keys, " "))
+}
+
+// DenyFunc is told about every denied request, for auditing
+type DenyFunc func(client, op, key string)
+
+// Authorizer evaluates requests against a set of rules
+type Authorizer struct {
+	rules  []Rule
+	onDeny DenyFunc
+}
+
+// NewAuthorizer creates an Authorizer; onDeny may be nil
+func NewAuthorizer(rules []Rule, onDeny DenyFunc) *Authorizer {
+	return &Authorizer{rules: rules, onDeny: onDeny}
+}
+
+// Allowed reports whether any rule permits the request, without auditing
+func (a *Authorizer) Allowed(client, op, key string) bool {
+	for _, r := range a.rules {
+		if r.Allows(client, op, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns nil if the request is allowed and an error wrapping
+// ErrDenied otherwise, reporting the denial to the DenyFunc
+func (a *Authorizer) Check(client, op, key string) error {
+	if a.Allowed(client, op, key) {
+		return nil
+	}
+	if a.onDeny != nil {
+		a.onDeny(client, op, key)
+	}
+	return fmt.Errorf("%w: client %q may not %s '%s'", ErrDenied, client, op, key)
+}
+
+// Filter returns the keys client may perform op on. Keys filtered out are
+// not reported as denials, so listing does not flood the audit log.
+func (a *Authorizer) Filter(client, op string, keys []string) []string {
+	var allowed []string
+	for _, key := range keys {
+		if a.Allowed(client, op, key) {
+			allowed = append(allowed, key)
+		}
+	}
+	return allowed
+}
+
+// Decode parses rules stored with Encode
+func Decode(data string) ([]Rule, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var rules []Rule
+	if err := json.Unmarshal([]byte(data), &rules); err != nil {
+		return nil, fmt.Errorf("invalid access rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Encode serialises rules for storage
+func Encode(rules []Rule) (string, error) {
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func validOp(op string) bool {
+	for _, valid := range ValidOps {
+		if op == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches s against a case-insensitive glob pattern
+func matchGlob(pattern, s string) bool {
+	var b strings.Builder
+	b.WriteString("(?is)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String()).MatchString(s)
+}
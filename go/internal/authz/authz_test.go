@@ -0,0 +1,68 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorizer_Check(t *testing.T) {
+	var denials []string
+	a := NewAuthorizer([]Rule{
+		{Client: "plugin:vscode", Keys: []string{"dev/*"}, Ops: []string{OpGet, OpList}},
+		{Client: "rest:*", Keys: []string{"ci/*", "shared/token"}, Ops: []string{OpAll}},
+	}, func(client, op, key string) {
+		denials = append(denials, client+" "+op+" "+key)
+	})
+
+	assert.NoError(t, a.Check("plugin:vscode", OpGet, "dev/db/password"))
+	assert.NoError(t, a.Check("Plugin:VSCode", OpGet, "DEV/api"))
+	assert.NoError(t, a.Check("rest:deploy", OpDelete, "ci/token"))
+	assert.NoError(t, a.Check("rest:deploy", OpSet, "shared/token"))
+
+	// Deny by default
+	assert.ErrorIs(t, a.Check("plugin:vscode", OpSet, "dev/api"), ErrDenied)
+	assert.ErrorIs(t, a.Check("plugin:vscode", OpGet, "prod/api"), ErrDenied)
+	assert.ErrorIs(t, a.Check("browser", OpGet, "dev/api"), ErrDenied)
+	assert.ErrorIs(t, NewAuthorizer(nil, nil).Check("plugin:vscode", OpGet, "dev/api"), ErrDenied)
+
+	assert.Equal(t, []string{
+		"plugin:vscode set dev/api",
+		"plugin:vscode get prod/api",
+		"browser get dev/api",
+	}, denials)
+}
+
+func TestAuthorizer_Filter(t *testing.T) {
+	a := NewAuthorizer([]Rule{{Client: "plugin:*", Keys: []string{"dev/*"}, Ops: []string{OpList}}}, func(string, string, string) {
+		t.Fatal("filtering must not report denials")
+	})
+	assert.Equal(t, []string{"dev/a", "dev/b"}, a.Filter("plugin:x", OpList, []string{"dev/a", "prod/a", "dev/b"}))
+	assert.Empty(t, a.Filter("plugin:x", OpGet, []string{"dev/a"}))
+}
+
+func TestRule_Validate(t *testing.T) {
+	assert.NoError(t, Rule{Client: "c", Keys: []string{"*"}, Ops: []string{OpGet}}.Validate())
+	assert.Error(t, Rule{Keys: []string{"*"}, Ops: []string{OpGet}}.Validate())
+	assert.Error(t, Rule{Client: "c", Ops: []string{OpGet}}.Validate())
+	assert.Error(t, Rule{Client: "c", Keys: []string{"*"}}.Validate())
+	assert.Error(t, Rule{Client: "c", Keys: []string{"*"}, Ops: []string{"read"}}.Validate())
+}
+
+func TestEncodeDecode(t *testing.T) {
+	rules := []Rule{{Client: "rest:ci", Keys: []string{"ci/*"}, Ops: []string{OpGet}}}
+	data, err := Encode(rules)
+	require.NoError(t, err)
+	decoded, err := Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, rules, decoded)
+}
+
+func TestMatchGlob(t *testing.T) {
+	assert.True(t, matchGlob("db/*", "db/prod/password"))
+	assert.True(t, matchGlob("db/?", "db/1"))
+	assert.False(t, matchGlob("db/?", "db/12"))
+	assert.True(t, matchGlob("a.b", "A.B"))
+	assert.False(t, matchGlob("a.b", "axb"))
+}
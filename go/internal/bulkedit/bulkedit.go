@@ -0,0 +1,187 @@
+// Package bulkedit renders secrets as an editable text file and turns the
+// edited file back into a batch of changes. Values are masked unless the
+// user asks to see them, so the file can be opened in an editor without
+// putting every value on screen.
+package bulkedit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lockr/go/internal/database"
+)
+
+const (
+	// Mask stands for a value that is kept as it is
+	Mask = "********"
+	// RevealMarker asks for the current value to be shown in the next round
+	RevealMarker = "?reveal"
+)
+
+// Entry is a secret as it was when the file was written
+type Entry struct {
+	Key      string
+	Value    string
+	Revision int64
+}
+
+// Item is one line of the file. Masked items show Mask instead of Value.
+type Item struct {
+	Key    string
+	Value  string
+	Masked bool
+}
+
+// Line is one parsed line of an edited file
+type Line struct {
+	Number int
+	Key    string
+	Value  string
+	Keep   bool
+	Reveal bool
+}
+
+// Result is what an edited file asks for
+type Result struct {
+	Changes []database.SecretChange
+	// Reveal lists keys whose values should be shown before applying
+	Reveal []string
+	// Items is the file as edited, for showing it again
+	Items []Item
+}
+
+// Masked returns one masked item per entry
+func Masked(entries []Entry) []Item {
+	items := make([]Item, len(entries))
+	for i, e := range entries {
+		items[i] = Item{Key: e.Key, Masked: true}
+	}
+	return items
+}
+
+// Render writes items as the editable file, below a help header
+func Render(w io.Writer, title string, items []Item) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# lockr edit: %s\n", title)
+	b.WriteString(`#
+# One secret per line: KEY = VALUE
+#   ` + Mask + `    keeps the current value
+#   ` + RevealMarker + `     shows the current value (the file opens again after saving)
+#   "text"      sets the value; \n, \t, \" and \\ work as in Go strings
+#   text        sets the value, without leading and trailing spaces
+# Delete a line to delete that secret and add one to create a secret.
+# Nothing is changed until you save and confirm; an unchanged file cancels.
+
+`)
+	for _, item := range items {
+		value := Mask
+		if !item.Masked {
+			value = strconv.Quote(item.Value)
+		}
+		fmt.Fprintf(&b, "%s = %s\n", item.Key, value)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Parse reads an edited file. Blank lines and lines starting with # are
+// ignored.
+func Parse(r io.Reader) ([]Line, error) {
+	var lines []Line
+	seen := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	number := 0
+	for scanner.Scan() {
+		number++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		// Keys cannot contain spaces or '=', so the first of either ends the key
+		end := strings.IndexFunc(text, func(r rune) bool { return r == '=' || unicode.IsSpace(r) })
+		if end <= 0 {
+			return nil, fmt.Errorf("line %d: expected KEY = VALUE", number)
+		}
+		key, rest := text[:end], strings.TrimSpace(text[end:])
+		if !strings.HasPrefix(rest, "=") {
+			return nil, fmt.Errorf("line %d: expected '=' after %s", number, key)
+		}
+		raw := strings.TrimSpace(rest[1:])
+
+		if first, dup := seen[strings.ToLower(key)]; dup {
+			return nil, fmt.Errorf("line %d: %s is already on line %d", number, key, first)
+		}
+		seen[strings.ToLower(key)] = number
+
+		line := Line{Number: number, Key: key}
+		switch {
+		case raw == Mask:
+			line.Keep = true
+		case raw == RevealMarker:
+			line.Reveal = true
+		case raw == "":
+			return nil, fmt.Errorf("line %d: %s has no value (write \"\" for an empty one)", number, key)
+		case strings.HasPrefix(raw, `"`):
+			value, err := strconv.Unquote(raw)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid quoted value for %s", number, key)
+			}
+			line.Value = value
+		default:
+			line.Value = raw
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// Compare works out what the edited lines change about entries. Keys
+// compare case-insensitively. Lines that asked for a reveal produce no
+// changes until they are edited.
+func Compare(entries []Entry, lines []Line) (*Result, error) {
+	byKey := make(map[string]Entry, len(entries))
+	for _, e := range entries {
+		byKey[strings.ToLower(e.Key)] = e
+	}
+
+	res := &Result{}
+	present := make(map[string]bool)
+	for _, l := range lines {
+		e, exists := byKey[strings.ToLower(l.Key)]
+		present[strings.ToLower(l.Key)] = true
+
+		switch {
+		case !exists && (l.Keep || l.Reveal):
+			return nil, fmt.Errorf("line %d: %s is new and needs a value", l.Number, l.Key)
+		case !exists:
+			res.Changes = append(res.Changes, database.SecretChange{Op: database.ChangeCreate, Key: l.Key, Value: l.Value})
+			res.Items = append(res.Items, Item{Key: l.Key, Value: l.Value})
+		case l.Keep:
+			res.Items = append(res.Items, Item{Key: e.Key, Masked: true})
+		case l.Reveal:
+			res.Reveal = append(res.Reveal, e.Key)
+			res.Items = append(res.Items, Item{Key: e.Key, Value: e.Value})
+		default:
+			if l.Value != e.Value {
+				res.Changes = append(res.Changes, database.SecretChange{Op: database.ChangeUpdate, Key: e.Key, Value: l.Value, Revision: e.Revision})
+			}
+			res.Items = append(res.Items, Item{Key: e.Key, Value: l.Value})
+		}
+	}
+
+	for _, e := range entries {
+		if !present[strings.ToLower(e.Key)] {
+			res.Changes = append(res.Changes, database.SecretChange{Op: database.ChangeDelete, Key: e.Key, Revision: e.Revision})
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,89 @@
+package bulkedit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lockr/go/internal/database"
+)
+
+var entries = []Entry{
+	{Key: "app/db", Value: "pw", Revision: 3},
+	{Key: "app/token", Value: "tok", Revision: 1},
+	{Key: "app/old", Value: "x", Revision: 2},
+}
+
+func TestRenderParse_RoundTrip(t *testing.T) {
+	items := Masked(entries)
+	items[1] = Item{Key: "app/token", Value: "line\nbreak \"quoted\""}
+
+	var buf bytes.Buffer
+	require.NoError(t, Render(&buf, "app/*", items))
+	assert.NotContains(t, buf.String(), "pw")
+	assert.Contains(t, buf.String(), "app/db = "+Mask)
+
+	lines, err := Parse(&buf)
+	require.NoError(t, err)
+	require.Len(t, lines, 3)
+	assert.True(t, lines[0].Keep)
+	assert.Equal(t, "line\nbreak \"quoted\"", lines[1].Value)
+}
+
+func TestParse(t *testing.T) {
+	lines, err := Parse(strings.NewReader(`
+# comment
+a = plain text with = signs  
+b=` + RevealMarker + `
+c = ""
+`))
+	require.NoError(t, err)
+	require.Len(t, lines, 3)
+	assert.Equal(t, "plain text with = signs", lines[0].Value)
+	assert.Equal(t, "b", lines[1].Key)
+	assert.True(t, lines[1].Reveal)
+	assert.Equal(t, "", lines[2].Value)
+
+	for _, bad := range []string{"novalue", "a b", "a =", `a = "unterminated`, "a = 1\nA = 2"} {
+		_, err := Parse(strings.NewReader(bad))
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	lines, err := Parse(strings.NewReader(`
+app/db = new password
+APP/token = ` + Mask + `
+app/new = "fresh"
+`))
+	require.NoError(t, err)
+
+	res, err := Compare(entries, lines)
+	require.NoError(t, err)
+	assert.Empty(t, res.Reveal)
+	assert.Equal(t, []database.SecretChange{
+		{Op: database.ChangeUpdate, Key: "app/db", Value: "new password", Revision: 3},
+		{Op: database.ChangeCreate, Key: "app/new", Value: "fresh"},
+		{Op: database.ChangeDelete, Key: "app/old", Revision: 2},
+	}, res.Changes)
+}
+
+func TestCompare_RevealAndUnchanged(t *testing.T) {
+	lines, err := Parse(strings.NewReader("app/db = pw\napp/token = " + RevealMarker + "\napp/old = " + Mask))
+	require.NoError(t, err)
+
+	res, err := Compare(entries, lines)
+	require.NoError(t, err)
+	assert.Empty(t, res.Changes)
+	assert.Equal(t, []string{"app/token"}, res.Reveal)
+	assert.Equal(t, Item{Key: "app/token", Value: "tok"}, res.Items[1])
+	assert.True(t, res.Items[2].Masked)
+
+	lines, err = Parse(strings.NewReader("brand/new = " + Mask))
+	require.NoError(t, err)
+	_, err = Compare(nil, lines)
+	assert.Error(t, err)
+}
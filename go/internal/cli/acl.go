@@ -0,0 +1,256 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/authz"
+	"github.com/lockr/go/internal/database"
+)
+
+// aclRulesSetting is the vault setting holding the access rules
+const aclRulesSetting = "acl.rules"
+
+var aclCmd = &cobra.Command{
+	Use:   "acl",
+	Short: "Control which keys local clients may use",
+	Long: `Access rules decide what each client identity may do when the vault is
+served to other programs (plugins, REST tokens, browser extensions). A rule
+names a client, the keys it covers and the operations it allows. Anything no
+rule allows is denied, and every denial is recorded in the audit log
+('lockr auth-log --events'). Secrets a client retrieves are recorded under
+its identity too; review them with 'lockr acl usage' before revoking a rule.
+
+Client and key patterns are case-insensitive globs: * matches anything,
+including '/', and ? matches one character. Operations are get, list, set,
+delete, or * for all of them.
+
+Rules are stored encrypted in the vault. They do not restrict the lockr
+command line itself, which always has full access after unlocking.
+
+Examples:
+  lockr acl add --client plugin:vscode --keys 'dev/*' --ops get,list
+  lockr acl add --client 'rest:*' --keys 'ci/*' --keys shared/token --ops '*'
+  lockr acl list
+  lockr acl check plugin:vscode get prod/db    # Would this be allowed?
+  lockr acl usage plugin:vscode                # What did it read?
+  lockr acl remove 1`,
+}
+
+var aclAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add an access rule",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		var rule authz.Rule
+		rule.Client, _ = cmd.Flags().GetString("client")
+		rule.Keys, _ = cmd.Flags().GetStringArray("keys")
+		rule.Ops, _ = cmd.Flags().GetStringSlice("ops")
+		for i, op := range rule.Ops {
+			rule.Ops[i] = strings.ToLower(strings.TrimSpace(op))
+		}
+
+		if err := rule.Validate(); err != nil {
+			handleError(err, "Invalid rule")
+			return
+		}
+
+		rules, err := loadACLRules()
+		if err != nil {
+			handleError(err, "Failed to read access rules")
+			return
+		}
+		rules = append(rules, rule)
+		if err := saveACLRules(rules); err != nil {
+			handleError(err, "Failed to save access rules")
+			return
+		}
+
+		printInfo("Added rule %d: %s", len(rules), rule)
+	},
+}
+
+var aclListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List access rules",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		rules, err := loadACLRules()
+		if err != nil {
+			handleError(err, "Failed to read access rules")
+			return
+		}
+		if len(rules) == 0 {
+			fmt.Println("No access rules; every client request is denied")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "#\tCLIENT\tOPERATIONS\tKEYS")
+		for i, rule := range rules {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", i+1, rule.Client, strings.Join(rule.Ops, ","), strings.Join(rule.Keys, " "))
+		}
+		w.Flush()
+	},
+}
+
+var aclRemoveCmd = &cobra.Command{
+	Use:   "remove <number>",
+	Short: "Remove an access rule",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		rules, err := loadACLRules()
+		if err != nil {
+			handleError(err, "Failed to read access rules")
+			return
+		}
+
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > len(rules) {
+			handleError(fmt.Errorf("no rule %s; see 'lockr acl list'", args[0]), "")
+			return
+		}
+
+		removed := rules[n-1]
+		rules = append(rules[:n-1], rules[n:]...)
+		if err := saveACLRules(rules); err != nil {
+			handleError(err, "Failed to save access rules")
+			return
+		}
+
+		printInfo("Removed rule %d: %s", n, removed)
+	},
+}
+
+var aclCheckCmd = &cobra.Command{
+	Use:   "check <client> <operation> <key>",
+	Short: "Show whether a client request would be allowed",
+	Args:  cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		rules, err := loadACLRules()
+		if err != nil {
+			handleError(err, "Failed to read access rules")
+			return
+		}
+
+		client, op, key := args[0], strings.ToLower(args[1]), args[2]
+		for i, rule := range rules {
+			if rule.Allows(client, op, key) {
+				fmt.Printf("allowed by rule %d: %s\n", i+1, rule)
+				return
+			}
+		}
+		fmt.Println("denied: no rule allows it")
+		os.Exit(1)
+	},
+}
+
+var aclUsageCmd = &cobra.Command{
+	Use:   "usage <client>",
+	Short: "Show which secrets a client retrieved",
+	Long: `List the audit events recorded for a client identity, newest first: the
+secrets it retrieved and the requests access rules denied. Use it to scope
+the damage when a plugin or automation credential may have leaked, then
+remove the rules that let it in.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		limit, _ := cmd.Flags().GetInt("limit")
+		printIdentityUsage(args[0], limit)
+	},
+}
+
+// printIdentityUsage prints the newest limit audit events recorded for a
+// client identity and how many distinct secrets it retrieved
+func printIdentityUsage(identity string, limit int) {
+	events, err := vaultDB.ListAuditEventsByIdentity(identity, limit)
+	if err != nil {
+		handleError(err, "Failed to read audit log")
+		return
+	}
+	if len(events) == 0 {
+		fmt.Printf("No audit events recorded for '%s'\n", identity)
+		return
+	}
+
+	keys := make(map[string]bool)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tEVENT\tKEY\tHOST\tDETAILS")
+	for _, e := range events {
+		if e.Event == database.AuditEventGet && e.Key != nil {
+			keys[strings.ToLower(*e.Key)] = true
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			e.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			e.Event,
+			valueOrDash(e.Key),
+			orDash(e.Client.Hostname),
+			valueOrDash(e.Details))
+	}
+	w.Flush()
+	printInfo("\n%d events, %d distinct secrets retrieved", len(events), len(keys))
+}
+
+// loadACLRules reads the vault's access rules
+func loadACLRules() ([]authz.Rule, error) {
+	data, _, err := vaultDB.GetSetting(aclRulesSetting)
+	if err != nil {
+		return nil, err
+	}
+	return authz.Decode(data)
+}
+
+// saveACLRules replaces the vault's access rules
+func saveACLRules(rules []authz.Rule) error {
+	if len(rules) == 0 {
+		return vaultDB.DeleteSetting(aclRulesSetting)
+	}
+	data, err := authz.Encode(rules)
+	if err != nil {
+		return err
+	}
+	return vaultDB.SetSetting(aclRulesSetting, data)
+}
+
+func init() {
+	aclAddCmd.Flags().String("client", "", "Client identity or glob, e.g. plugin:vscode or 'rest:*'")
+	aclAddCmd.Flags().StringArray("keys", nil, "Key glob the rule covers (repeatable)")
+	aclAddCmd.Flags().StringSlice("ops", nil, "Allowed operations: get, list, set, delete or *")
+
+	aclUsageCmd.Flags().Int("limit", 100, "Maximum number of events to show (0 for all)")
+
+	aclCmd.AddCommand(aclAddCmd)
+	aclCmd.AddCommand(aclListCmd)
+	aclCmd.AddCommand(aclRemoveCmd)
+	aclCmd.AddCommand(aclCheckCmd)
+	aclCmd.AddCommand(aclUsageCmd)
+}
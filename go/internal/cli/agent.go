@@ -0,0 +1,313 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/agent"
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/keyring"
+	"github.com/lockr/go/internal/session"
+)
+
+// agentSocketEnv overrides where the agent listens, like SSH_AUTH_SOCK
+const agentSocketEnv = "LOCKR_AGENT_SOCK"
+
+// agentStartWait is how long 'agent start' waits for the agent to listen
+const agentStartWait = 3 * time.Second
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Keep the vault unlocked between commands",
+	Long: `Run a background agent that holds unlocked vault keys in memory, like
+ssh-agent, so commands skip the password prompt and key derivation.
+
+While the agent runs, every command asks it for the vault's key first. A
+vault unlocked any other way (password, keyring or cached key) is handed to
+the agent, so the next command finds it there. The agent forgets a key once
+it goes unused for --timeout (session_timeout from the config, else 15
+minutes), when it is locked and
+when it stops. Keys never touch the disk.
+
+The agent listens on a socket in $XDG_RUNTIME_DIR/lockr (or the state
+directory) that only you can reach; set LOCKR_AGENT_SOCK to use another.
+A vault with a label in config.yml (a profile, see 'lockr use') has an agent
+of its own, which these commands manage while that vault is in use.
+
+Examples:
+  lockr agent start                 # Start and unlock the vault
+  lockr agent start --timeout 1h
+  lockr agent status                # Which vaults are unlocked, and until when
+  lockr agent lock                  # Forget every key, keep running
+  lockr agent stop`,
+}
+
+var agentStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the agent and unlock the vault into it",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		if !cmd.Flags().Changed("timeout") && sessionTimeout > 0 {
+			timeout = sessionTimeout
+		}
+		if timeout <= 0 {
+			handleError(errors.New("--timeout must be positive"), "")
+			return
+		}
+		if foreground, _ := cmd.Flags().GetBool("foreground"); foreground {
+			if err := runAgent(timeout); err != nil {
+				handleError(err, "Agent failed")
+			}
+			return
+		}
+
+		if _, err := os.Stat(vaultPath); os.IsNotExist(err) {
+			handleError(fmt.Errorf("no vault at %s; run 'lockr init' first", vaultPath), "")
+			return
+		}
+
+		client := agentClient()
+		if client.Running() {
+			printInfo("Agent already running on %s", client.Path())
+		} else {
+			pid, err := spawnAgent(client, timeout)
+			if err != nil {
+				handleError(err, "Failed to start agent")
+				return
+			}
+			printInfo("✓ Agent started (pid %d) on %s", pid, client.Path())
+		}
+
+		// Unlocking hands the key to the agent
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+		printInfo("✓ Vault unlocked in the agent; it locks after %s unused", timeout)
+	},
+}
+
+var agentStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the agent, wiping every key it holds",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		pid, err := agentClient().Stop()
+		if errors.Is(err, agent.ErrNotRunning) {
+			printInfo("Agent is not running")
+			return
+		}
+		if err != nil {
+			handleError(err, "Failed to stop agent")
+			return
+		}
+		printInfo("✓ Agent stopped (pid %d)", pid)
+	},
+}
+
+var agentStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the agent runs and which vaults it holds",
+	Long: `Show whether the agent is running and which vaults it holds unlocked.
+Exits with status 1 when no agent is running.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		client := agentClient()
+		info, err := client.Status()
+		if errors.Is(err, agent.ErrNotRunning) {
+			fmt.Println("Agent is not running")
+			os.Exit(1)
+		}
+		if err != nil {
+			handleError(err, "Failed to query agent")
+			return
+		}
+
+		fmt.Printf("Agent running (pid %d) on %s\n", info.PID, client.Path())
+		if len(info.Vaults) == 0 {
+			fmt.Println("  No vaults unlocked")
+		}
+		for _, v := range info.Vaults {
+			fmt.Printf("  %s  locks in %s unless used\n", v.Label, time.Until(v.ExpiresAt).Round(time.Second))
+		}
+		if info.Grants > 0 {
+			fmt.Printf("  %d pending grants (see 'lockr grant')\n", info.Grants)
+		}
+	},
+}
+
+var agentLockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Make the agent forget every key and grant without stopping it",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Values cached by get --max-age are forgotten along with the keys
+		clearValueCache(true)
+		err := agentClient().Lock("")
+		if errors.Is(err, agent.ErrNotRunning) {
+			printInfo("Agent is not running")
+			return
+		}
+		if err != nil {
+			handleError(err, "Failed to lock agent")
+			return
+		}
+		printInfo("✓ Agent forgot every vault key and grant")
+	},
+}
+
+// agentClient returns a client for the agent socket in use. The vault of a
+// profile has an agent of its own.
+func agentClient() *agent.Client {
+	if path := os.Getenv(agentSocketEnv); path != "" {
+		return agent.NewClient(path)
+	}
+	if profile := vaultProfile(); profile != "" {
+		return agent.NewClient(dirs.ProfileAgentSocket(profile))
+	}
+	return agent.NewClient(dirs.AgentSocket())
+}
+
+// allAgentClients returns clients for the shared agent and the agent of
+// every profile
+func allAgentClients() []*agent.Client {
+	if path := os.Getenv(agentSocketEnv); path != "" {
+		return []*agent.Client{agent.NewClient(path)}
+	}
+	clients := []*agent.Client{agent.NewClient(dirs.AgentSocket())}
+	for profile := range vaultThemes.Profiles() {
+		clients = append(clients, agent.NewClient(dirs.ProfileAgentSocket(profile)))
+	}
+	return clients
+}
+
+// spawnAgent starts 'lockr agent start --foreground' in the background and
+// waits until it listens, returning its process ID
+func spawnAgent(client *agent.Client, timeout time.Duration) (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+
+	// The child reads the same config, which defines the profile it serves
+	child := exec.Command(exe, "--config", configPath, "agent", "start", "--foreground", "--timeout", timeout.String())
+	child.Env = append(os.Environ(), agentSocketEnv+"="+client.Path())
+	child.Dir = "/"
+	detach(child)
+	if err := child.Start(); err != nil {
+		return 0, err
+	}
+	pid := child.Process.Pid
+	child.Process.Release()
+
+	for deadline := time.Now().Add(agentStartWait); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if client.Running() {
+			return pid, nil
+		}
+	}
+	return 0, fmt.Errorf("agent did not start listening on %s", client.Path())
+}
+
+// runAgent serves the agent socket until stopped or interrupted
+func runAgent(timeout time.Duration) error {
+	client := agentClient()
+	l, err := agent.Listen(client.Path())
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	return agent.NewServer(timeout).Serve(l)
+}
+
+// unlockWithAgent unlocks the vault with a key held by a running agent and
+// reports whether it did
+func unlockWithAgent() bool {
+	client := agentClient()
+	vaultID := keyring.VaultID(vaultPath)
+	key, err := client.Key(vaultID)
+	if err != nil {
+		if !errors.Is(err, agent.ErrNotRunning) {
+			printVerbose("Agent: %v", err)
+		}
+		return false
+	}
+
+	stopUnlock := timePhase("unlock")
+	err = sessionMgr.AuthenticateWithKey(key)
+	stopUnlock()
+	if err == database.ErrAuthenticationFailed {
+		// The vault was rekeyed since the agent got the key
+		client.Lock(vaultID)
+	}
+	return err == nil
+}
+
+// shareWithAgent hands the key of the vault just unlocked to a running
+// agent. password is the one it was unlocked with, or "" when the keyring
+// or a cached derived key unlocked it.
+func shareWithAgent(password string) {
+	client := agentClient()
+	if !client.Running() {
+		return
+	}
+
+	key, err := vaultKey(password)
+	if err == nil {
+		label, _ := filepath.Abs(vaultPath)
+		err = client.Add(keyring.VaultID(vaultPath), label, key)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to hand the vault key to the agent: %v\n", err)
+		return
+	}
+	printVerbose("Vault key handed to the agent")
+}
+
+// vaultKey returns the raw key of the vault, derived from password or,
+// when it is "", taken from the keyring
+func vaultKey(password string) ([]byte, error) {
+	km := sessionMgr.GetKeyringManager()
+	if password == "" {
+		if key, err := km.GetDerivedKey(keyring.VaultID(vaultPath)); err == nil {
+			return key, nil
+		}
+		stored, err := km.GetPassword()
+		if err != nil {
+			return nil, err
+		}
+		password = stored
+	}
+
+	keyed, ok := vaultDB.(database.KeyedStore)
+	if !ok {
+		return nil, session.ErrKeyCacheUnsupported
+	}
+	return keyed.DeriveKey(password)
+}
+
+func init() {
+	agentStartCmd.Flags().Duration("timeout", session.SessionTimeout, "Forget a vault's key once it goes unused this long")
+	agentStartCmd.Flags().Bool("foreground", false, "Run the agent in the foreground instead of unlocking the vault into a background agent")
+
+	agentCmd.AddCommand(agentStartCmd)
+	agentCmd.AddCommand(agentStopCmd)
+	agentCmd.AddCommand(agentStatusCmd)
+	agentCmd.AddCommand(agentLockCmd)
+}
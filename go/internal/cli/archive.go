@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// archiveCmd moves secrets out of the working set without deleting them
+var archiveCmd = &cobra.Command{
+	Use:   "archive <key>...",
+	Short: "Archive secrets without deleting them",
+	Long: `Mark secrets as archived. Archived secrets keep their values but no longer
+appear in get, list, search or the interactive picker, which keeps the working
+set small without destroying old credentials. 'lockr unarchive' restores them.
+
+Examples:
+  lockr archive aws/old-access-key
+  lockr list --archived             # Show archived secrets
+  lockr unarchive aws/old-access-key`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setArchived(args, vaultDB.ArchiveSecret, "archived")
+	},
+}
+
+// unarchiveCmd returns archived secrets to the working set
+var unarchiveCmd = &cobra.Command{
+	Use:   "unarchive <key>...",
+	Short: "Restore archived secrets",
+	Long: `Return archived secrets to the working set, so they appear in get, list and
+search again.
+
+Examples:
+  lockr unarchive aws/old-access-key`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setArchived(args, vaultDB.UnarchiveSecret, "restored")
+	},
+}
+
+// setArchived applies change to every key, reporting each one, and exits
+// with the not-found status if any key could not be changed
+func setArchived(keys []string, change func(key string) error, verb string) {
+	if err := ensureAuthenticated(); err != nil {
+		handleError(err, "Authentication failed")
+		return
+	}
+
+	var failed error
+	for _, arg := range keys {
+		key := activeWorkspace.QualifyKey(arg)
+		if err := change(key); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to update '%s': %v\n", key, err)
+			failed = err
+			continue
+		}
+		invalidateCachedSecret(key)
+		printInfo("Secret '%s' %s", key, verb)
+	}
+
+	if failed != nil {
+		handleError(failed, "Not every secret was "+verb)
+	}
+}
+
+// listArchived prints the archived secrets, most recently archived first
+func listArchived(raw bool) {
+	secrets, err := vaultDB.ArchivedSecrets()
+	if err != nil {
+		handleError(err, "Failed to list archived secrets")
+		return
+	}
+
+	if raw {
+		printRawKeys(secrets)
+		return
+	}
+	if len(secrets) == 0 {
+		fmt.Println("No archived secrets")
+		return
+	}
+
+	for _, secret := range secrets {
+		fmt.Printf("%-30s (archived %s, created %s)\n",
+			secret.Key,
+			formatAge(*secret.ArchivedAt),
+			secret.CreatedAt.Format("2006-01-02"))
+	}
+	fmt.Printf("\nTotal: %d archived secrets\n", len(secrets))
+}
+
+// archivedHint explains that key was not found because it is archived
+func archivedHint(key string) string {
+	secret, err := vaultDB.PeekSecret(key)
+	if err != nil || secret.ArchivedAt == nil {
+		return ""
+	}
+	return fmt.Sprintf("Secret '%s' is archived; restore it with 'lockr unarchive %s'", key, key)
+}
@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/auditlog"
+)
+
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Export the audit log, signed, and verify exports",
+	Long: `Export the vault's audit log (secret retrievals, grants, access denials and
+authentication attempts) as a JSON document to keep outside the vault, e.g.
+for compliance or personal records. With --sign, the export gets a detached
+gpg or minisign signature, so later changes to it are detected by
+'lockr log verify'.
+
+Examples:
+  lockr log export -o audit-2026-10.json --sign               # gpg, default key
+  lockr log export -o audit.json --sign --key ops@example.com
+  lockr log export -o audit.json --sign=minisign --key ~/.minisign/lockr.key
+  lockr log verify audit.json                                 # uses audit.json.asc
+  lockr log verify audit.json --pubkey ~/.minisign/lockr.pub  # uses audit.json.minisig`,
+}
+
+var logExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write the audit log as JSON, optionally signed",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		output, _ := cmd.Flags().GetString("output")
+		signer, _ := cmd.Flags().GetString("sign")
+		key, _ := cmd.Flags().GetString("key")
+		if signer != "" && signer != auditlog.SignerGPG && signer != auditlog.SignerMinisign {
+			handleError(fmt.Errorf("unknown signer %q (want gpg or minisign)", signer), "")
+			return
+		}
+		if signer != "" && output == "" {
+			handleError(errors.New("--sign needs --output, to keep the signature next to the export"), "")
+			return
+		}
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		events, err := vaultDB.ListAuditEvents(0)
+		if err != nil {
+			handleError(err, "Failed to read audit log")
+			return
+		}
+		attempts, err := vaultDB.ListAuthAttempts(0)
+		if err != nil {
+			handleError(err, "Failed to read authentication log")
+			return
+		}
+		vault, _ := filepath.Abs(vaultPath)
+		export := auditlog.New(vault, events, attempts)
+		data, err := export.Marshal()
+		if err != nil {
+			handleError(err, "Failed to encode audit log")
+			return
+		}
+
+		if output == "" {
+			os.Stdout.Write(data)
+			return
+		}
+		if err := os.WriteFile(output, data, 0600); err != nil {
+			handleError(err, "Failed to write export")
+			return
+		}
+		printInfo("✓ Exported %d audit events and %d authentication attempts to %s",
+			len(export.Events), len(export.AuthAttempts), output)
+
+		if signer == "" {
+			return
+		}
+		comment := fmt.Sprintf("lockr audit log of %s exported %s", vault, export.ExportedAt.Format(time.RFC3339))
+		sigPath, err := auditlog.Sign(output, signer, key, comment)
+		if err != nil {
+			handleError(err, "Failed to sign export")
+			return
+		}
+		printInfo("✓ Signed with %s: %s", signer, sigPath)
+	},
+}
+
+var logVerifyCmd = &cobra.Command{
+	Use:   "verify <export>",
+	Short: "Check an export's signature and summarize it",
+	Long: `Check the detached signature of an audit log export and summarize what it
+holds. The signature is looked for next to the export (export.asc for gpg,
+export.minisig for minisign) unless --signature names it. minisign
+signatures need the signer's public key (--pubkey); gpg ones are checked
+against your keyring. Exits with status 1 when the signature does not verify.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := args[0]
+		sigPath, _ := cmd.Flags().GetString("signature")
+		pubkey, _ := cmd.Flags().GetString("pubkey")
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			handleError(err, "Failed to read export")
+			return
+		}
+		export, err := auditlog.Parse(data)
+		if err != nil {
+			handleError(err, "")
+			return
+		}
+
+		if sigPath == "" {
+			sigPath = findSignature(path)
+			if sigPath == "" {
+				handleError(fmt.Errorf("no signature next to %s; pass --signature", path), "")
+				return
+			}
+		}
+
+		if filepath.Ext(sigPath) == ".minisig" {
+			if pubkey == "" {
+				handleError(errors.New("minisign signatures need the signer's public key (--pubkey)"), "")
+				return
+			}
+			keyText, err := os.ReadFile(pubkey)
+			if err != nil {
+				handleError(err, "Failed to read public key")
+				return
+			}
+			signature, err := os.ReadFile(sigPath)
+			if err != nil {
+				handleError(err, "Failed to read signature")
+				return
+			}
+			if err := auditlog.VerifyMinisign(data, signature, string(keyText)); err != nil {
+				handleError(err, "Signature check failed")
+				return
+			}
+			printInfo("✓ Good minisign signature (%s)", sigPath)
+		} else {
+			report, err := auditlog.VerifyGPG(path, sigPath)
+			if err != nil {
+				handleError(err, "Signature check failed")
+				return
+			}
+			printVerbose("%s", report)
+			printInfo("✓ Good gpg signature (%s)", sigPath)
+		}
+
+		fmt.Printf("Vault:       %s\n", export.Vault)
+		fmt.Printf("Exported:    %s\n", export.ExportedAt.Local().Format("2006-01-02 15:04:05"))
+		fmt.Printf("Events:      %d audit events, %d authentication attempts\n", len(export.Events), len(export.AuthAttempts))
+		if oldest, newest := export.Span(); !oldest.IsZero() {
+			fmt.Printf("Covers:      %s to %s\n", oldest.Local().Format("2006-01-02 15:04:05"), newest.Local().Format("2006-01-02 15:04:05"))
+		}
+	},
+}
+
+// findSignature returns the gpg or minisign signature kept next to the
+// export at path, or "" if there is none
+func findSignature(path string) string {
+	for _, signer := range []string{auditlog.SignerGPG, auditlog.SignerMinisign} {
+		candidate := auditlog.SignatureFile(path, signer)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func init() {
+	logExportCmd.Flags().StringP("output", "o", "", "Write the export to this file instead of stdout")
+	logExportCmd.Flags().String("sign", "", "Sign the export with gpg (the default) or minisign")
+	logExportCmd.Flags().Lookup("sign").NoOptDefVal = auditlog.SignerGPG
+	logExportCmd.Flags().String("key", "", "gpg key (--local-user) or minisign secret key file to sign with")
+	logVerifyCmd.Flags().String("signature", "", "Signature file (default: the export's .asc or .minisig)")
+	logVerifyCmd.Flags().String("pubkey", "", "minisign public key file of the signer")
+
+	logCmd.AddCommand(logExportCmd)
+	logCmd.AddCommand(logVerifyCmd)
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var authLogCmd = &cobra.Command{
+	Use:   "auth-log",
+	Short: "Show recent authentication attempts",
+	Long: `Display recent authentication attempts recorded in the vault, including
+the terminal, hostname and client (cli, agent, rest) each one came from.
+With --events, show audited secret retrievals instead.
+
+Examples:
+  lockr auth-log                  # Last 20 authentication attempts
+  lockr auth-log --limit 100      # Last 100 attempts
+  lockr auth-log --events         # Recent secret retrievals`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		limit, _ := cmd.Flags().GetInt("limit")
+		events, _ := cmd.Flags().GetBool("events")
+
+		if events {
+			printAuditEvents(limit)
+			return
+		}
+
+		attempts, err := vaultDB.ListAuthAttempts(limit)
+		if err != nil {
+			handleError(err, "Failed to read authentication log")
+			return
+		}
+
+		if len(attempts) == 0 {
+			fmt.Println("No authentication attempts recorded")
+			return
+		}
+
+		fmt.Printf("%-20s %-8s %-12s %-10s %-20s %-6s\n", "TIME", "RESULT", "USER", "TERMINAL", "HOST", "CLIENT")
+		for _, a := range attempts {
+			result := "ok"
+			if !a.Success {
+				result = "FAILED"
+			}
+			fmt.Printf("%-20s %-8s %-12s %-10s %-20s %-6s\n",
+				a.Timestamp.Local().Format("2006-01-02 15:04:05"),
+				result,
+				truncateString(a.Username, 12),
+				truncateString(valueOrDash(a.Terminal), 10),
+				truncateString(valueOrDash(a.Hostname), 20),
+				valueOrDash(a.Client))
+		}
+	},
+}
+
+// printAuditEvents prints the most recent audit events
+func printAuditEvents(limit int) {
+	events, err := vaultDB.ListAuditEvents(limit)
+	if err != nil {
+		handleError(err, "Failed to read audit log")
+		return
+	}
+
+	if len(events) == 0 {
+		fmt.Println("No audit events recorded")
+		return
+	}
+
+	fmt.Printf("%-20s %-12s %-30s %-12s %-10s %-20s %-6s %s\n", "TIME", "EVENT", "KEY", "USER", "TERMINAL", "HOST", "CLIENT", "DETAILS")
+	for _, e := range events {
+		client := orDash(e.Client.Client)
+		if e.Client.Identity != "" {
+			client += " " + e.Client.Identity
+		}
+		fmt.Printf("%-20s %-12s %-30s %-12s %-10s %-20s %-6s %s\n",
+			e.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			e.Event,
+			truncateString(valueOrDash(e.Key), 30),
+			truncateString(orDash(e.Client.Username), 12),
+			truncateString(orDash(e.Client.Terminal), 10),
+			truncateString(orDash(e.Client.Hostname), 20),
+			client,
+			valueOrDash(e.Details))
+	}
+}
+
+// valueOrDash dereferences an optional column, using "-" for NULL
+func valueOrDash(s *string) string {
+	if s == nil {
+		return "-"
+	}
+	return orDash(*s)
+}
+
+// orDash returns "-" for empty strings
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func init() {
+	authLogCmd.Flags().IntP("limit", "n", 20, "Number of entries to show (0 for all)")
+	authLogCmd.Flags().Bool("events", false, "Show audited secret retrievals instead of auth attempts")
+}
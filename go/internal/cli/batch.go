@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/lockr/go/internal/policy"
+	"github.com/lockr/go/internal/refs"
+	"github.com/lockr/go/internal/transform"
+)
+
+// batchResult is the outcome of one key in 'lockr get --batch'
+type batchResult struct {
+	Value *string `json:"value,omitempty"`
+	Error string  `json:"error,omitempty"`
+}
+
+// readKeyList reads one key per line, skipping blank lines, # comments and
+// repeated keys
+func readKeyList(r io.Reader) ([]string, error) {
+	var keys []string
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key := strings.TrimSpace(scanner.Text())
+		if key == "" || strings.HasPrefix(key, "#") || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys, scanner.Err()
+}
+
+// batchGet retrieves every key listed on r over the open vault connection
+// and writes the values, or the error for each key, in format. Values are
+// encoded with the named transform, if any. It returns the number of keys
+// that failed.
+func batchGet(r io.Reader, w io.Writer, format string, noResolve bool, transformName string) (int, error) {
+	if format != "json" && format != "lines" {
+		return 0, fmt.Errorf("unknown output %q (use json or lines)", format)
+	}
+
+	keys, err := readKeyList(r)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read keys: %w", err)
+	}
+
+	results := make(map[string]batchResult, len(keys))
+	failed := 0
+	for _, key := range keys {
+		value, err := batchValue(activeWorkspace.QualifyKey(key), noResolve)
+		if err == nil {
+			value, err = transform.Apply(transformName, value)
+		}
+		if err != nil {
+			results[key] = batchResult{Error: err.Error()}
+			failed++
+			continue
+		}
+		results[key] = batchResult{Value: &value}
+	}
+
+	if format == "lines" {
+		// Values only, in input order; nothing is printed unless all resolved
+		if failed > 0 {
+			for _, key := range keys {
+				if results[key].Error != "" {
+					fmt.Fprintf(os.Stderr, "%s: %s\n", key, results[key].Error)
+				}
+			}
+			return failed, nil
+		}
+		for _, key := range keys {
+			fmt.Fprintln(w, *results[key].Value)
+		}
+		return 0, nil
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return failed, encoder.Encode(results)
+}
+
+// batchValue retrieves one secret for batchGet, applying the same access
+// tracking, auditing and policy checks as a single get
+func batchValue(key string, noResolve bool) (string, error) {
+	secret, err := vaultDB.GetSecret(key)
+	if err != nil {
+		return "", err
+	}
+	auditSecretAccess(key)
+
+	if resolvePolicy(key, secret.Tags).ClipboardOnly {
+		return "", policy.ErrClipboardOnly
+	}
+	if noResolve {
+		return secret.Value, nil
+	}
+	return refs.Resolve(secret.Key, secret.Value, checkedLookup(nil, ""))
+}
@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/security"
+)
+
+// cloneCmd copies the vault (or a subset of it) into a new vault file
+var cloneCmd = &cobra.Command{
+	Use:   "clone",
+	Short: "Copy the vault to a new, independent vault file",
+	Long: `Create a new vault file containing a copy of this vault's secrets.
+The copy shares nothing with the original: it has its own sessions and
+access history, and can be encrypted with a different password.
+
+Use --pattern to copy only matching keys, e.g. to build a travel vault.
+
+Examples:
+  lockr clone --to backup.lockr                         # Full copy, same password
+  lockr clone --to travel.lockr --pattern 'personal/*'  # Subset of keys
+  lockr clone --to shared.lockr --new-password          # Re-encrypt with a new password`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		target, _ := cmd.Flags().GetString("to")
+		pattern, _ := cmd.Flags().GetString("pattern")
+		newPassword, _ := cmd.Flags().GetBool("new-password")
+
+		if target == "" {
+			handleError(errors.New("--to is required"), "")
+			return
+		}
+
+		absTarget, err := filepath.Abs(target)
+		if err != nil {
+			handleError(err, "Invalid target path")
+			return
+		}
+		if absVault, err := filepath.Abs(vaultPath); err == nil && absVault == absTarget {
+			handleError(errors.New("target is the current vault"), "")
+			return
+		}
+
+		if _, err := os.Stat(target); err == nil {
+			if !force {
+				fmt.Printf("Vault already exists at %s\nUse --force to overwrite\n", target)
+				return
+			}
+			if err := os.Remove(target); err != nil {
+				handleError(err, "Failed to delete existing vault")
+				return
+			}
+		}
+
+		// Determine the clone's password. Without --new-password the copy
+		// reuses the password that unlocks this vault.
+		var password string
+		if newPassword {
+			if err := ensureAuthenticated(); err != nil {
+				handleError(err, "Authentication failed")
+				return
+			}
+			password, err = promptNewPassword("Enter password for the cloned vault: ")
+		} else {
+			password, err = authenticateWithPassword()
+		}
+		if err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		secrets, err := vaultDB.ExportSecrets(pattern)
+		if err != nil {
+			handleError(err, "Failed to read secrets")
+			return
+		}
+
+		count, err := cloneVault(target, password, secrets)
+		if err != nil {
+			handleError(err, "Clone failed")
+			return
+		}
+
+		fmt.Printf("Cloned %d secrets to %s\n", count, target)
+	},
+}
+
+// cloneVault creates a vault at path encrypted with password and imports
+// secrets into it. The new file is removed if anything fails.
+func cloneVault(path, password string, secrets []database.Secret) (int, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return 0, fmt.Errorf("failed to create vault directory: %w", err)
+	}
+
+	store, err := database.OpenStore(vaultEngine(path), path)
+	if err != nil {
+		return 0, err
+	}
+	if durable, ok := store.(database.DurableStore); ok {
+		durable.SetDurability(vaultDurability)
+	}
+
+	if err := store.Connect(password); err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+
+	count, err := store.ImportSecrets(secrets)
+	if closeErr := store.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// authenticateWithPassword authenticates like ensureAuthenticated but returns
+// the password that unlocked the vault, for commands that need to reuse it
+func authenticateWithPassword() (string, error) {
+	km := sessionMgr.GetKeyringManager()
+	if km.IsEnabled() && km.HasPassword() {
+		if password, err := km.GetPassword(); err == nil {
+			if err := sessionMgr.Authenticate(password); err == nil {
+				printVerbose("Authenticated using keyring")
+				afterAuthentication()
+				return password, nil
+			}
+		}
+	}
+
+	password, err := promptPassword("Enter vault password: ")
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+
+	if err := sessionMgr.Authenticate(password); err != nil {
+		return "", fmt.Errorf("authentication failed: %w", err)
+	}
+
+	afterAuthentication()
+	return password, nil
+}
+
+// promptNewPassword prompts for a new password twice and checks both entries match
+func promptNewPassword(prompt string) (string, error) {
+	password, err := promptPassword(prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	if password == "" {
+		return "", errors.New("password cannot be empty")
+	}
+
+	confirm, err := promptPassword("Confirm password: ")
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	if !security.Equal(password, confirm) {
+		return "", errors.New("passwords do not match")
+	}
+
+	return password, nil
+}
+
+func init() {
+	cloneCmd.Flags().String("to", "", "Path of the new vault file")
+	cloneCmd.Flags().String("pattern", "", "Only copy keys matching this glob pattern")
+	cloneCmd.Flags().Bool("new-password", false, "Encrypt the clone with a different password")
+}
@@ -1,17 +1,32 @@
 package cli
 
 import (
-	"crypto/rand"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
 
+	"github.com/lockr/go/internal/authz"
 	"github.com/lockr/go/internal/clipboard"
+	"github.com/lockr/go/internal/config"
 	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/diceware"
+	"github.com/lockr/go/internal/dsn"
+	"github.com/lockr/go/internal/generator"
+	"github.com/lockr/go/internal/keymap"
+	"github.com/lockr/go/internal/keyring"
+	"github.com/lockr/go/internal/policy"
+	"github.com/lockr/go/internal/refs"
 	"github.com/lockr/go/internal/search"
+	"github.com/lockr/go/internal/strength"
+	"github.com/lockr/go/internal/transform"
+	"github.com/lockr/go/internal/vaultio"
 )
 
 // getCmd represents the get command for retrieving secrets
@@ -24,20 +39,155 @@ opens an interactive fuzzy search interface.
 Examples:
   lockr get mykey          # Get secret for 'mykey'
   lockr get                # Interactive search
-  lockr get --no-copy     # Get secret without copying to clipboard`,
+  lockr get --no-copy     # Get secret without copying to clipboard
+  lockr get --show mykey   # Print the secret (required with --no-clipboard)
+  lockr get --no-resolve db/url  # Show ${ref:...} references unexpanded
+  lockr get --max-age 300 db/url # Reuse a value fetched in the last 5 minutes
+  lockr get --density detailed   # Picker rows also show namespace and tags
+  lockr get --picker fzf         # Pick with fzf; only key names are sent to it
+  lockr get --show-notes db/prod # Also print the notes stored with the secret
+  lockr get --to-file tls.pem tls/chain  # Write a large value to a file
+  lockr get --transform base64 --show k8s/db-password  # For a Secret manifest
+  lockr get db/prod --as dsn --driver postgres          # Connection string
+  cat keys.txt | lockr get --batch --output json
+
+--picker fzf or --picker sk (or picker.program in the config file) hands the
+key names to fzf or skim instead of the built-in picker, so your own
+FZF_DEFAULT_OPTS and bindings apply. Values are never sent to the program.
+
+Copying a value of 256 KB or more asks first, because some clipboard
+managers freeze on values that large; --to-file writes it to a file instead.
+Set clipboard.confirm_size in the config file to change the threshold.
+
+--transform encodes the value for its destination before it is copied,
+printed or written: base64 (Kubernetes Secret data, basic-auth headers), hex,
+urlencode (percent-encoding safe anywhere in a URL) or json-escape (the
+contents of a JSON string, without quotes).
+
+--as dsn assembles a connection string from a structured secret: either a
+JSON object stored under the key ({"host": ..., "port": ..., "user": ...,
+"password": ..., "database": ...}), or one secret per field under it
+(db/prod/host, db/prod/password, ...). --driver picks the format (postgres,
+mysql, sqlserver, mongodb or redis) unless a "driver" field names it. Fields
+such as sslmode are passed on as options where the driver knows them.
+
+A value may embed other secrets with ${ref:key}, for example
+"postgres://app:${ref:db/password}@db/app"; references are expanded when the
+secret is retrieved. Write $${ref:key} for a literal ${ref:key}.
+
+With --max-age, a value read within that many seconds (or a duration such as
+5m) is served from an encrypted local cache, so scripts fetching the same
+secret at boot skip reading and resolving it again. The vault is still
+unlocked (cheaply, with the agent or a cached key) to check that the secret
+is neither hidden nor archived and still at the cached revision, to apply its
+current policy and to audit the read. Cached reads are not counted. Travel
+mode, changing policy rules, 'lockr agent lock' and revoking sessions clear
+the cache.
+
+With --batch, keys are read from stdin (one per line; blank lines and # comments
+are skipped) and fetched over a single unlocked connection, so the key
+derivation is paid once. --output json prints an object mapping each key to
+{"value": ...} or {"error": ...}; --output lines prints only the values, in
+input order, and nothing at all if any key fails. The exit status is 1 if any
+key failed. Clipboard-only secrets are reported as errors. Since stdin carries
+the keys, the vault must unlock without a prompt (keyring or cached key).`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		noResolve, _ := cmd.Flags().GetBool("no-resolve")
+		noCopy, _ := cmd.Flags().GetBool("no-copy")
+		if show, _ := cmd.Flags().GetBool("show"); show {
+			noCopy = true
+		}
+		toFile, _ := cmd.Flags().GetString("to-file")
+		transformName, _ := cmd.Flags().GetString("transform")
+		if err := transform.Check(transformName); err != nil {
+			handleError(err, "")
+			return
+		}
+		asFormat, _ := cmd.Flags().GetString("as")
+		driver, _ := cmd.Flags().GetString("driver")
+		if asFormat != "" && asFormat != asDSN {
+			handleError(fmt.Errorf("unknown --as format %q (use %s)", asFormat, asDSN), "")
+			return
+		}
+		if driver != "" && asFormat != asDSN {
+			handleError(errors.New("--driver needs --as dsn"), "")
+			return
+		}
+		deliver := func(value string, defaults policy.Defaults) error {
+			value, err := transform.Apply(transformName, value)
+			if err != nil {
+				return err
+			}
+			if toFile != "" {
+				return writeSecretFile(toFile, value, defaults)
+			}
+			return deliverSecret(value, noCopy, defaults)
+		}
+		if batch, _ := cmd.Flags().GetBool("batch"); batch {
+			if len(args) > 0 {
+				handleError(fmt.Errorf("--batch reads keys from stdin and takes no arguments"), "")
+				return
+			}
+			if asFormat != "" {
+				handleError(errors.New("--as does not work with --batch"), "")
+				return
+			}
+			if err := ensureAuthenticated(); err != nil {
+				handleError(err, "Authentication failed")
+				return
+			}
+			output, _ := cmd.Flags().GetString("output")
+			failed, err := batchGet(os.Stdin, os.Stdout, output, noResolve, transformName)
+			if err != nil {
+				handleError(err, "Batch get failed")
+				return
+			}
+			if failed > 0 {
+				os.Exit(1)
+			}
+			return
+		}
+
+		maxAgeFlag, _ := cmd.Flags().GetString("max-age")
+		maxAge, err := parseMaxAge(maxAgeFlag)
+		if err != nil {
+			handleError(err, "Invalid --max-age")
+			return
+		}
+		useCache := maxAge > 0 && !noResolve && asFormat == "" && len(args) == 1
+
+		if useCache {
+			key := activeWorkspace.QualifyKey(args[0])
+			if entry, ok := cachedSecret(key, maxAge); ok {
+				if defaults, ok := servableFromCache(key, entry); ok {
+					applyClipboardPolicy(defaults)
+					if err := deliver(entry.Value, defaults); err != nil {
+						handleError(err, fmt.Sprintf("Cannot deliver secret '%s'", key))
+						return
+					}
+					printVerbose("Served '%s' from the value cache (revision %d, fetched %s ago)",
+						key, entry.Revision, time.Since(entry.FetchedAt).Round(time.Second))
+					return
+				}
+			}
+		}
+
 		if err := ensureAuthenticated(); err != nil {
 			handleError(err, "Authentication failed")
 			return
 		}
 
 		var key string
-		var err error
 
 		if len(args) == 0 {
 			// Interactive mode
-			key, err = interactiveGet()
+			options, err := pickerOptions(cmd)
+			if err != nil {
+				handleError(err, "")
+				return
+			}
+			key, err = interactiveGet(options)
 			if err != nil {
 				handleError(err, "Interactive search failed")
 				return
@@ -46,29 +196,71 @@ Examples:
 				fmt.Println("No selection made")
 				return
 			}
+			// The picker may have stayed open past the session timeout
+			if err := ensureAuthenticated(); err != nil {
+				handleError(err, "Authentication failed")
+				return
+			}
 		} else {
-			key = args[0]
+			key = activeWorkspace.QualifyKey(args[0])
+		}
+
+		if asFormat == asDSN {
+			value, defaults, err := connectionString(key, driver)
+			if err != nil {
+				handleError(err, fmt.Sprintf("Failed to get secret '%s'", key))
+				return
+			}
+			applyClipboardPolicy(defaults)
+			if err := deliver(value, defaults); err != nil {
+				handleError(err, fmt.Sprintf("Cannot deliver secret '%s'", key))
+			}
+			return
 		}
 
 		// Retrieve the secret
 		secret, err := vaultDB.GetSecret(key)
+		if err == database.ErrKeyNotFound {
+			if hint := archivedHint(key); hint != "" {
+				fmt.Fprintln(os.Stderr, hint)
+			}
+		}
 		if err != nil {
 			handleError(err, fmt.Sprintf("Failed to get secret '%s'", key))
 			return
 		}
 
-		// Handle clipboard operations
-		noCopy, _ := cmd.Flags().GetBool("no-copy")
-		if !noCopy && clipboardMgr != nil {
-			if err := clipboardMgr.CopySecretWithNotification(secret.Value); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to copy to clipboard: %v\n", err)
-				fmt.Printf("Secret: %s\n", secret.Value)
+		auditSecretAccess(key)
+		defaults := resolvePolicy(key, secret.Tags)
+
+		value := secret.Value
+		if !noResolve {
+			value, err = refs.Resolve(secret.Key, secret.Value, policyLookup(&defaults))
+			if err != nil {
+				handleError(err, fmt.Sprintf("Failed to resolve references in '%s'", key))
+				return
 			}
-		} else {
-			fmt.Printf("Secret: %s\n", secret.Value)
+		}
+		applyClipboardPolicy(defaults)
+
+		if useCache {
+			cacheSecret(secret, value, defaults)
+		}
+
+		// Handle clipboard operations
+		if err := deliver(value, defaults); err != nil {
+			handleError(err, fmt.Sprintf("Cannot deliver secret '%s'", key))
+			return
 		}
 
+		if showNotes, _ := cmd.Flags().GetBool("show-notes"); showNotes {
+			printNotes(secret)
+		}
+		warnExpired(secret)
 		printVerbose("Retrieved secret for key '%s' (accessed %d times)", key, secret.AccessCount)
+		if secret.URL != nil {
+			printVerbose("URL: %s", *secret.URL)
+		}
 	},
 }
 
@@ -83,7 +275,31 @@ Examples:
   lockr set mykey                   # Prompt for secret value (hidden input)
   lockr set -g mykey                # Auto-generate a random secret
   lockr set -g -l 32 mykey          # Generate 32-character secret
-  lockr set -f -g mykey             # Force update with generated secret`,
+  lockr set --words 6 mykey         # Generate a six-word diceware passphrase
+  lockr set --generator mykey       # Tune length and characters while previewing
+  lockr set -f -g mykey             # Force update with generated secret
+  lockr set --upsert -g mykey       # Create or update without asking
+  lockr set --diff mykey            # Compare with the current value before overwriting
+  lockr set -f --if-revision 3 key  # Update only if nobody changed it since revision 3
+  lockr set --if-revision 0 key     # Create only; fail if the key already exists
+  lockr set --tag api --tag prod k  # Store and tag the secret
+  lockr set db/prod --tag db --note 'prod RDS' --expires 90d --url https://console.aws.amazon.com/rds/
+
+--note (or --notes), --url and --expires annotate the secret as it is stored; on an existing
+secret they replace the current values (--expires never removes the expiry).
+--expires takes a duration from now (90d, 12h) or a date (2025-01-31); get
+warns when it returns an expired secret.
+
+When the key already exists, set asks before overwriting it. Automation should
+state its intent instead: --upsert creates or updates without asking, 'lockr
+update' only updates and fails if the key does not exist, and --if-revision 0
+only creates.
+
+Policy rules ('lockr policy') can change the generator and clipboard defaults
+for a key based on its tags or namespace; explicit flags always win.
+
+Revisions start at 1 and increase with every update ('lockr list --format json'
+shows them). A conditional write that loses a race exits with code 6.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := ensureAuthenticated(); err != nil {
@@ -91,50 +307,56 @@ Examples:
 			return
 		}
 
-		key := args[0]
-		var value string
+		key := activeWorkspace.QualifyKey(args[0])
 
-		generate, _ := cmd.Flags().GetBool("generate")
+		showDiff, _ := cmd.Flags().GetBool("diff")
+		upsert, _ := cmd.Flags().GetBool("upsert")
+		ifRevision, _ := cmd.Flags().GetInt64("if-revision")
+		conditional := cmd.Flags().Changed("if-revision")
+		if conditional && ifRevision < 0 {
+			handleError(fmt.Errorf("--if-revision must be 0 or greater"), "")
+			return
+		}
+		if upsert && conditional && ifRevision == 0 {
+			handleError(fmt.Errorf("--upsert cannot be combined with --if-revision 0"), "")
+			return
+		}
+		tags, err := tagFlag(cmd)
+		if err != nil {
+			handleError(err, "")
+			return
+		}
+		meta, err := metadataFlags(cmd)
+		if err != nil {
+			handleError(err, "")
+			return
+		}
 
-		if generate {
-			// Auto-generate a random secret
-			length, _ := cmd.Flags().GetInt("length")
-			var err error
-			value, err = generateSecret(length)
-			if err != nil {
-				handleError(err, "Failed to generate secret")
-				return
-			}
-			// Copy to clipboard without displaying
-			if clipboardMgr != nil {
-				if err := clipboardMgr.CopySecretWithNotification(value); err != nil {
-					handleError(err, "Failed to copy generated secret to clipboard")
-					return
-				}
-			} else {
-				handleError(fmt.Errorf("clipboard not available"), "Cannot generate secret without clipboard support")
-				return
-			}
-		} else {
-			// Read value securely with hidden input
-			var err error
-			value, err = promptPassword("Enter secret value: ")
-			if err != nil {
-				handleError(err, "Failed to read secret value")
+		value, entropyBits, source, ok := readSecretValue(cmd, key)
+		if !ok {
+			return
+		}
+
+		// A positive --if-revision only ever updates an existing secret
+		err = database.ErrDuplicateKey
+		if !conditional || ifRevision == 0 {
+			// Try to create the secret first
+			err = vaultDB.CreateSecret(key, value)
+		}
+
+		if err == database.ErrDuplicateKey {
+			if conditional && ifRevision == 0 {
+				handleError(database.ErrRevisionMismatch, fmt.Sprintf("Secret '%s' already exists", key))
 				return
 			}
-			if value == "" {
-				handleError(fmt.Errorf("secret value cannot be empty"), "")
-				return
+
+			if showDiff {
+				printValueDiff(key, value, entropyBits)
 			}
-		}
 
-		// Try to create the secret first
-		err := vaultDB.CreateSecret(key, value)
-		if err == database.ErrDuplicateKey {
 			// Key exists, ask for update confirmation
-			if !force {
-				fmt.Printf("Secret '%s' already exists. Update it? (y/N): ", key)
+			if !force && !upsert {
+				fmt.Print(vaultPrompt(fmt.Sprintf("Secret '%s' already exists. Update it? (y/N): ", key)))
 				var response string
 				fmt.Scanln(&response)
 				if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
@@ -143,28 +365,277 @@ Examples:
 				}
 			}
 
-			// Update existing secret
-			if err := vaultDB.UpdateSecret(key, value); err != nil {
-				handleError(err, fmt.Sprintf("Failed to update secret '%s'", key))
+			if !updateSecretValue(key, value, conditional, ifRevision) {
 				return
 			}
-			fmt.Printf("Secret '%s' updated successfully\n", key)
-			printVerbose("Updated secret with key '%s'", key)
 		} else if err != nil {
 			handleError(err, fmt.Sprintf("Failed to store secret '%s'", key))
 			return
 		} else {
-			fmt.Printf("Secret '%s' stored successfully\n", key)
+			printInfo("Secret '%s' stored successfully", key)
 			printVerbose("Stored new secret with key '%s'", key)
 		}
+
+		if err := annotateSecret(key, tags, meta); err != nil {
+			handleError(err, fmt.Sprintf("Secret '%s' stored, but annotating it failed", key))
+			return
+		}
+
+		invalidateCachedSecret(key)
+		recordStrength(key, entropyBits, source)
+		warnLimits()
+	},
+}
+
+// updateCmd changes the value of an existing secret and never creates one
+var updateCmd = &cobra.Command{
+	Use:   "update <key>",
+	Short: "Change the value of an existing secret",
+	Long: `Replace the value of a secret that already exists, without asking. Unlike
+set, update never creates a secret: a missing key fails with exit code 3, so
+a typo in automation cannot leave a stray new key behind.
+
+The value is read like set reads it: prompted with hidden input, or generated
+with -g, --words or --generator.
+
+Examples:
+  lockr update db/password             # Prompt for the new value
+  lockr update -g -l 40 api/token      # Rotate to a generated value
+  lockr update --if-revision 3 db/url  # Only if nobody changed it since revision 3`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		key := activeWorkspace.QualifyKey(args[0])
+
+		ifRevision, _ := cmd.Flags().GetInt64("if-revision")
+		conditional := cmd.Flags().Changed("if-revision")
+		if conditional && ifRevision < 1 {
+			handleError(fmt.Errorf("--if-revision must be 1 or greater"), "")
+			return
+		}
+
+		// Fail before prompting or generating when there is nothing to update
+		if _, err := vaultDB.PeekSecret(key); err != nil {
+			handleError(err, fmt.Sprintf("Cannot update secret '%s'", key))
+			return
+		}
+
+		value, entropyBits, source, ok := readSecretValue(cmd, key)
+		if !ok {
+			return
+		}
+
+		if showDiff, _ := cmd.Flags().GetBool("diff"); showDiff {
+			printValueDiff(key, value, entropyBits)
+		}
+
+		if !updateSecretValue(key, value, conditional, ifRevision) {
+			return
+		}
+
+		invalidateCachedSecret(key)
+		recordStrength(key, entropyBits, source)
 	},
 }
 
+// readSecretValue obtains the value for set or update: generated when the
+// flags ask for it (copying it to the clipboard without displaying it),
+// otherwise prompted for with hidden input. It returns the value's estimated
+// strength and origin, and false after reporting an error or cancellation.
+func readSecretValue(cmd *cobra.Command, key string) (string, float64, string, bool) {
+	defaults := secretDefaults(key)
+	applyClipboardPolicy(defaults)
+
+	generate, _ := cmd.Flags().GetBool("generate")
+	passphrase := cmd.Flags().Changed("words")
+	if generate && !passphrase && !cmd.Flags().Changed("length") && defaults.Words > 0 {
+		passphrase = true
+	}
+	useGenerator, _ := cmd.Flags().GetBool("generator")
+
+	if !generate && !passphrase && !useGenerator {
+		// Read value securely with hidden input
+		value, err := promptPassword(vaultPrompt("Enter secret value: "))
+		if err != nil {
+			handleError(err, "Failed to read secret value")
+			return "", 0, "", false
+		}
+		if value == "" {
+			handleError(fmt.Errorf("secret value cannot be empty"), "")
+			return "", 0, "", false
+		}
+		checkReferences(key, value)
+		return value, strength.Estimate(value), database.SourceManual, true
+	}
+
+	var value string
+	var entropyBits float64
+	var err error
+	if useGenerator {
+		// Let the user tune the options while previewing values
+		value, entropyBits, err = interactiveGenerate(cmd, defaults)
+		if err == generator.ErrAborted {
+			fmt.Println("Cancelled")
+			return "", 0, "", false
+		}
+	} else if passphrase {
+		// Diceware passphrase from the embedded word list
+		words, _ := cmd.Flags().GetInt("words")
+		if !cmd.Flags().Changed("words") && defaults.Words > 0 {
+			words = defaults.Words
+		}
+		separator, _ := cmd.Flags().GetString("separator")
+		value, err = diceware.Generate(words, separator)
+		entropyBits = strength.ForGenerated(words, diceware.WordCount)
+	} else {
+		// Auto-generate a random secret
+		length, _ := cmd.Flags().GetInt("length")
+		if !cmd.Flags().Changed("length") && defaults.Length > 0 {
+			length = defaults.Length
+		}
+		charset := secretCharset
+		noSymbols, _ := cmd.Flags().GetBool("no-symbols")
+		if !cmd.Flags().Changed("no-symbols") && defaults.Symbols != nil {
+			noSymbols = !*defaults.Symbols
+		}
+		if noSymbols {
+			charset = alphanumericCharset
+		}
+		value, err = generateSecret(length, charset)
+		entropyBits = strength.ForGenerated(length, len(charset))
+	}
+	if err != nil {
+		handleError(err, "Failed to generate secret")
+		return "", 0, "", false
+	}
+
+	// Copy to clipboard without displaying
+	if clipboardMgr == nil {
+		handleError(fmt.Errorf("clipboard not available"), "Cannot generate secret without clipboard support")
+		return "", 0, "", false
+	}
+	if err := clipboardMgr.CopySecretWithNotification(value); err != nil {
+		handleError(err, "Failed to copy generated secret to clipboard")
+		return "", 0, "", false
+	}
+	return value, entropyBits, database.SourceGenerated, true
+}
+
+// updateSecretValue overwrites the value of an existing secret, optionally
+// only if it is still at ifRevision, and reports the outcome. It returns
+// false after reporting an error.
+func updateSecretValue(key, value string, conditional bool, ifRevision int64) bool {
+	var err error
+	if conditional {
+		err = vaultDB.UpdateSecretIfRevision(key, value, ifRevision)
+	} else {
+		err = vaultDB.UpdateSecret(key, value)
+	}
+	if err == database.ErrRevisionMismatch {
+		handleError(err, revisionConflictMessage(key, ifRevision))
+		return false
+	}
+	if err != nil {
+		handleError(err, fmt.Sprintf("Failed to update secret '%s'", key))
+		return false
+	}
+	printInfo("Secret '%s' updated successfully", key)
+	printVerbose("Updated secret with key '%s'", key)
+	return true
+}
+
+// recordStrength stores the entropy estimate for a newly set value and warns
+// about weak manually entered values
+func recordStrength(key string, entropyBits float64, source string) {
+	if err := vaultDB.SetSecretStrength(key, entropyBits, source); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record secret strength: %v\n", err)
+		return
+	}
+
+	rating := strength.Rate(entropyBits)
+	printVerbose("Estimated strength of '%s': %.0f bits (%s, %s)", key, entropyBits, rating, source)
+	if source == database.SourceManual && rating == strength.Weak {
+		fmt.Fprintf(os.Stderr, "Warning: this value looks weak (~%.0f bits); consider 'lockr set -g %s'\n", entropyBits, key)
+	}
+}
+
+// revisionConflictMessage explains a failed conditional update of key
+func revisionConflictMessage(key string, expected int64) string {
+	current, err := vaultDB.PeekSecret(key)
+	if err != nil {
+		return fmt.Sprintf("Secret '%s' changed since revision %d", key, expected)
+	}
+	return fmt.Sprintf("Secret '%s' changed since revision %d (now at revision %d)", key, expected, current.Revision)
+}
+
+// printValueDiff prints a masked comparison between the stored value of key
+// and newValue, so an overwrite of a value changed elsewhere can be spotted
+// without revealing either value
+func printValueDiff(key, newValue string, newBits float64) {
+	current, err := vaultDB.PeekSecret(key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cannot compare with current value: %v\n", err)
+		return
+	}
+	old := current.Value
+
+	fmt.Printf("Current value of '%s':\n", key)
+	if current.UpdatedAt != nil {
+		fmt.Printf("  Last modified:   %s (%s)\n", current.UpdatedAt.Local().Format("2006-01-02 15:04:05"), formatAge(*current.UpdatedAt))
+	}
+	if old == newValue {
+		fmt.Println("  New value is identical to the current value")
+		return
+	}
+
+	oldLen, newLen := len([]rune(old)), len([]rune(newValue))
+	fmt.Printf("  Length:          %d -> %d (%+d)\n", oldLen, newLen, newLen-oldLen)
+	fmt.Printf("  First character: %s\n", sameOrDiffers(firstRune(old) == firstRune(newValue)))
+	fmt.Printf("  Last character:  %s\n", sameOrDiffers(lastRune(old) == lastRune(newValue)))
+
+	oldBits := strength.Estimate(old)
+	if current.EntropyBits != nil {
+		oldBits = *current.EntropyBits
+	}
+	fmt.Printf("  Strength:        %.0f -> %.0f bits (%s -> %s)\n", oldBits, newBits, strength.Rate(oldBits), strength.Rate(newBits))
+}
+
+// sameOrDiffers describes the result of a masked comparison
+func sameOrDiffers(same bool) string {
+	if same {
+		return "same"
+	}
+	return "differs"
+}
+
+// firstRune returns the first rune of s, or 0 if s is empty
+func firstRune(s string) rune {
+	for _, r := range s {
+		return r
+	}
+	return 0
+}
+
+// lastRune returns the last rune of s, or 0 if s is empty
+func lastRune(s string) rune {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return 0
+	}
+	return runes[len(runes)-1]
+}
+
 // deleteCmd represents the delete command for removing secrets
 var deleteCmd = &cobra.Command{
 	Use:   "delete <key>",
 	Short: "Delete a secret from the vault",
-	Long: `Permanently delete a secret from the vault by its key.
+	Long: `Delete a secret from the vault by its key. Its last value is kept in
+'lockr history', so 'lockr restore' can bring it back; run 'lockr history
+--clear' on the key to forget it for good.
 
 Examples:
   lockr delete mykey
@@ -176,11 +647,11 @@ Examples:
 			return
 		}
 
-		key := args[0]
+		key := activeWorkspace.QualifyKey(args[0])
 
 		// Confirmation check
 		if !force {
-			fmt.Printf("Are you sure you want to delete secret '%s'? (y/N): ", key)
+			fmt.Print(vaultPrompt(fmt.Sprintf("Are you sure you want to delete secret '%s'? (y/N): ", key)))
 			var response string
 			fmt.Scanln(&response)
 			if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
@@ -195,7 +666,8 @@ Examples:
 			return
 		}
 
-		fmt.Printf("Secret '%s' deleted successfully\n", key)
+		invalidateCachedSecret(key)
+		printInfo("Secret '%s' deleted successfully", key)
 		printVerbose("Deleted secret with key '%s'", key)
 	},
 }
@@ -210,7 +682,20 @@ Examples:
   lockr list                     # List all secrets
   lockr list api                 # Search for keys matching "api"
   lockr list --format table      # List in table format
-  lockr list --limit 10 user     # Search and limit to 10 results`,
+  lockr list --raw               # Keys only, one per line (for scripts)
+  lockr list --limit 10 user     # Search and limit to 10 results
+  lockr list --page 2 api        # Show the second page of matches
+  lockr list --offset 40 --limit 20
+  lockr list --match glob 'prod/*/db'
+  lockr list --match regex '^aws_.*_key$'
+  lockr list --archived          # Secrets put away with 'lockr archive'
+  lockr list --tag api           # Secrets tagged api
+  lockr list 'db tag:prod count:>10'
+  lockr list --explain dbpw       # Show why each match ranked where it did
+
+Fuzzy patterns and the interactive picker accept qualifiers alongside the
+text: tag:NAME, ns:PREFIX/, created:>YYYY-MM-DD, accessed:<YYYY-MM-DD and
+count:>N (comparisons >, >=, <, <= and =).`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := ensureAuthenticated(); err != nil {
@@ -218,43 +703,139 @@ Examples:
 			return
 		}
 
-		// Get all secrets
-		secrets, err := vaultDB.ListSecrets()
+		limit, offset, err := resolvePagination(cmd)
 		if err != nil {
-			handleError(err, "Failed to list secrets")
+			handleError(err, "")
 			return
 		}
 
-		if len(secrets) == 0 {
-			fmt.Println("No secrets stored in vault")
+		raw, _ := cmd.Flags().GetBool("raw")
+		if archived, _ := cmd.Flags().GetBool("archived"); archived {
+			listArchived(raw)
+			return
+		}
+
+		matchFlag, _ := cmd.Flags().GetString("match")
+		matchMode, err := search.ParseMatchMode(matchFlag)
+		if err != nil {
+			handleError(err, "")
+			return
+		}
+
+		tags, err := tagFlag(cmd)
+		if err != nil {
+			handleError(err, "")
+			return
+		}
+		if len(tags) > 0 && len(args) > 0 && matchMode != search.MatchFuzzy {
+			handleError(fmt.Errorf("--tag only combines with fuzzy patterns"), "")
 			return
 		}
 
 		// If pattern provided, perform search
 		if len(args) > 0 {
 			pattern := args[0]
-			limit, _ := cmd.Flags().GetInt("limit")
-			if limit == 0 {
-				limit = 100 // Default limit for search
+
+			if matchMode != search.MatchFuzzy {
+				listMatching(matchMode, pattern, limit, offset, raw)
+				return
+			}
+
+			secrets, err := vaultDB.ListSecrets()
+			if err != nil {
+				handleError(err, "Failed to list secrets")
+				return
+			}
+
+			if len(secrets) == 0 && !raw {
+				fmt.Println("No secrets stored in vault")
+				return
 			}
 
-			// Perform fuzzy search
+			// Qualifiers such as tag:prod narrow the list before fuzzy ranking
+			query, err := search.ParseQuery(pattern)
+			if err != nil {
+				handleError(err, "Invalid search query")
+				return
+			}
 			engine := search.NewEngine()
-			matches := engine.SearchInteractive(pattern, secrets, limit)
+			stopSearch := timePhase("search")
+			candidates := filterTagged(query.Filter(secrets), tags)
+			matches, total := engine.SearchPage(query.Text, candidates, offset, limit)
+			stopSearch()
+
+			if raw {
+				for _, match := range matches {
+					fmt.Println(match.Result.Key)
+				}
+				return
+			}
 
-			if len(matches) == 0 {
+			if total == 0 {
 				fmt.Printf("No matches found for pattern '%s'\n", pattern)
 				return
 			}
 
-			fmt.Printf("Found %d matches for pattern '%s':\n\n", len(matches), pattern)
+			fmt.Printf("Showing %d of %d matches for pattern '%s'%s:\n\n",
+				len(matches), total, pattern, formatOffset(offset))
+			explain, _ := cmd.Flags().GetBool("explain")
 			for i, match := range matches {
 				fmt.Printf("%d. %s (score: %.1f, accessed: %d times)\n",
-					i+1, match.Result.Key, match.Score, match.Result.AccessCount)
+					offset+i+1, match.Result.Key, match.Score, match.Result.AccessCount)
+				if explain {
+					printExplanation(engine.Explain(query.Text, match.Result.Key))
+				}
+			}
+			if explain {
+				fmt.Println("\nEqual scores rank keys starting with the pattern first, then alphabetically.")
+				fmt.Println("Access counts and recency do not affect the order.")
 			}
 			return
 		}
 
+		// Only paginate the full listing when explicitly requested
+		paginate := cmd.Flags().Changed("limit") || cmd.Flags().Changed("offset") || cmd.Flags().Changed("page")
+
+		var secrets []database.SearchResult
+		switch {
+		case len(tags) > 0:
+			secrets, err = listTagged(tags)
+		case paginate:
+			secrets, err = vaultDB.ListSecretsPage(limit, offset)
+		default:
+			secrets, err = vaultDB.ListSecrets()
+		}
+		if err != nil {
+			handleError(err, "Failed to list secrets")
+			return
+		}
+
+		total := len(secrets)
+		if len(tags) > 0 {
+			if paginate {
+				secrets = pageOf(secrets, limit, offset)
+			}
+		} else if paginate {
+			if total, err = vaultDB.CountSecrets(); err != nil {
+				handleError(err, "Failed to count secrets")
+				return
+			}
+		}
+
+		if raw {
+			printRawKeys(secrets)
+			return
+		}
+
+		if total == 0 {
+			if len(tags) > 0 {
+				fmt.Printf("No secrets tagged %s\n", strings.Join(tags, ", "))
+				return
+			}
+			fmt.Println("No secrets stored in vault")
+			return
+		}
+
 		// List all secrets
 		format, _ := cmd.Flags().GetString("format")
 		switch format {
@@ -266,7 +847,11 @@ Examples:
 			printSecretsList(secrets)
 		}
 
-		fmt.Printf("\nTotal: %d secrets\n", len(secrets))
+		if paginate {
+			fmt.Printf("\nShowing %d of %d secrets%s\n", len(secrets), total, formatOffset(offset))
+		} else {
+			fmt.Printf("\nTotal: %d secrets\n", total)
+		}
 	},
 }
 
@@ -280,6 +865,12 @@ and system capabilities.`,
 		// Check if vault file exists
 		fmt.Printf("Vault Status:\n")
 		fmt.Printf("  Path: %s\n", vaultPath)
+		if theme := activeTheme(); theme.Label != "" {
+			fmt.Printf("  Label: %s\n", colorize(theme.Label, theme))
+		}
+		if activeProfile != "" {
+			fmt.Printf("  Profile: %s (from $%s)\n", activeProfile, profileEnv)
+		}
 
 		if _, err := os.Stat(vaultPath); os.IsNotExist(err) {
 			fmt.Printf("  Status: Not initialized\n")
@@ -296,8 +887,22 @@ and system capabilities.`,
 				if secrets, err := vaultDB.ListSecrets(); err == nil {
 					fmt.Printf("  Secrets count: %d\n", len(secrets))
 				}
+				for _, w := range limitWarnings() {
+					fmt.Printf("  Warning: %s\n", w)
+				}
+
+				// Show sessions recorded by all clients
+				if sessions, err := sessionMgr.ListSessions(); err == nil {
+					fmt.Printf("  Active sessions: %d\n", len(sessions))
+				}
+
+				if enabled, err := travelEnabled(); err == nil && enabled {
+					fmt.Printf("  Travel mode: on\n")
+				}
+				printDurability()
 			} else {
 				fmt.Printf("  Connected: No\n")
+				fmt.Printf("  Durability: %s\n", vaultDurability)
 			}
 		}
 
@@ -307,20 +912,53 @@ and system capabilities.`,
 			status := clipboardMgr.GetStatus()
 			fmt.Printf("  Supported: %v\n", status["supported"])
 			fmt.Printf("  Platform: %v\n", status["platform"])
+			fmt.Printf("  Backend: %v\n", status["backend"])
+			if session, ok := status["session"].(string); ok && session != "" {
+				fmt.Printf("  Session: %s\n", session)
+			}
+			if tools, ok := status["commands"].([]string); ok && len(tools) > 1 {
+				fmt.Printf("  Available: %s\n", strings.Join(tools, ", "))
+			}
 			fmt.Printf("  Auto-clear: %v\n", status["auto_clear"])
 			fmt.Printf("  Clear delay: %v\n", status["clear_delay"])
 		} else {
 			fmt.Printf("  Supported: %v\n", clipboard.IsSupported())
-			fmt.Printf("  Enabled: No (--no-clipboard flag used)\n")
+			if noClipboard {
+				fmt.Printf("  Enabled: No (--no-clipboard flag used)\n")
+			} else {
+				fmt.Printf("  Enabled: No\n")
+			}
 		}
 
 		// System info
 		fmt.Printf("\nSystem Info:\n")
 		fmt.Printf("  Verbose mode: %v\n", verbose)
 		fmt.Printf("  Config path: %s\n", configPath)
+
+		fmt.Printf("\nDirectories:\n")
+		fmt.Printf("  Config: %s\n", dirs.Config)
+		fmt.Printf("  Data: %s\n", dirs.Data)
+		fmt.Printf("  State: %s\n", dirs.State)
+		fmt.Printf("  Cache: %s\n", dirs.Cache)
 	},
 }
 
+// printDurability shows the durability mode along with the storage
+// settings it put in effect
+func printDurability() {
+	durable, ok := vaultDB.(database.DurableStore)
+	if !ok {
+		fmt.Printf("  Durability: %s\n", vaultDurability)
+		return
+	}
+	settings, err := durable.Durability()
+	if err != nil {
+		fmt.Printf("  Durability: %s\n", vaultDurability)
+		return
+	}
+	fmt.Printf("  Durability: %s (synchronous=%s, journal_mode=%s)\n", settings.Mode, settings.Synchronous, settings.JournalMode)
+}
+
 // versionCmd represents the version command
 var versionCmd = &cobra.Command{
 	Use:   "version",
@@ -334,6 +972,9 @@ var versionCmd = &cobra.Command{
 		if getBuildTime() != "unknown" {
 			fmt.Printf("built: %s\n", getBuildTime())
 		}
+		if !search.InteractiveAvailable {
+			fmt.Println("build: minimal (no interactive search or clipboard)")
+		}
 	},
 }
 
@@ -343,10 +984,24 @@ var initCmd = &cobra.Command{
 	Short: "Initialize a new vault",
 	Long: `Create a new encrypted vault database with the specified password.
 
+With --from or --from-bitwarden the new vault is seeded from an export in a
+single transaction. If anything fails, nothing is imported and the new vault
+is removed.
+
 Examples:
-  lockr init                # Initialize with password prompt
-  lockr init --force        # Overwrite existing vault`,
+  lockr init                              # Initialize with password prompt
+  lockr init --force                      # Overwrite existing vault
+  lockr init --from export.lockrx         # Create and import a lockr export
+  lockr init --from-bitwarden bw.json     # Create and import a Bitwarden export`,
 	Run: func(cmd *cobra.Command, args []string) {
+		// Load seed data before touching the existing vault so a bad file
+		// leaves everything as it was
+		seed, err := loadSeedData(cmd)
+		if err != nil {
+			handleError(err, "Failed to read seed data")
+			return
+		}
+
 		// Check if vault already exists
 		vaultExists := false
 		if _, err := os.Stat(vaultPath); err == nil {
@@ -403,18 +1058,34 @@ Examples:
 			handleError(err, "Failed to initialize vault")
 			return
 		}
-
-		fmt.Printf("Vault initialized successfully at %s\n", vaultPath)
 		printVerbose("Created new vault database")
+
+		if seed != nil {
+			imported, err := vaultDB.ImportSecrets(vaultio.ToSecrets(seed))
+			if err != nil {
+				// Don't leave a half-initialized vault behind
+				vaultDB.Close()
+				if removeErr := os.Remove(vaultPath); removeErr != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to remove new vault: %v\n", removeErr)
+				}
+				handleError(err, "Import failed, vault was not created")
+				return
+			}
+			fmt.Printf("Imported %d secrets\n", imported)
+		}
+
+		printInfo("Vault initialized successfully at %s", vaultPath)
 	},
 }
 
 // rekeyCmd represents the rekey command for changing vault password
 var rekeyCmd = &cobra.Command{
-	Use:   "rekey",
-	Short: "Change the vault master password",
+	Use:     "rekey",
+	Aliases: []string{"passwd"},
+	Short:   "Change the vault master password",
 	Long: `Change the encryption password for the vault. This re-encrypts the entire
-database with a new password. All stored secrets remain intact.
+database with a new password. All stored secrets remain intact, and are read
+back with the new password before the command reports success.
 
 This is useful for:
 - Regular password rotation
@@ -423,7 +1094,8 @@ This is useful for:
 
 Examples:
   lockr rekey               # Change password with prompts
-  lockr rekey --auto-update # Update keyring automatically`,
+  lockr rekey --auto-update # Update keyring automatically
+  lockr passwd              # Same as rekey`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Check if vault exists
 		if _, err := os.Stat(vaultPath); os.IsNotExist(err) {
@@ -439,33 +1111,50 @@ Examples:
 			return
 		}
 
-		// Prompt for new password
-		newPassword, err := promptPassword("Enter new vault password: ")
+		// Prompt for and confirm the new password
+		newPassword, err := promptNewPassword("Enter new vault password: ")
 		if err != nil {
 			handleError(err, "Failed to read new password")
 			return
 		}
 
-		// Confirm new password
-		confirmPassword, err := promptPassword("Confirm new vault password: ")
-		if err != nil {
-			handleError(err, "Failed to read password confirmation")
+		// Perform rekey operation
+		rep := statusReporter("rekey")
+		rep.Start(1)
+		printInfo("Re-encrypting vault with new password...")
+		if err := vaultDB.Rekey(oldPassword, newPassword); err != nil {
+			rep.Fail(err)
+			handleError(err, "Failed to rekey vault")
 			return
 		}
+		rep.Step(1, "re-encrypted vault")
 
-		if newPassword != confirmPassword {
-			fmt.Println("Error: Passwords do not match")
-			os.Exit(1)
+		// Rekey reopened the vault with the new password; make sure every
+		// secret still reads before the old password is forgotten anywhere
+		secrets, err := vaultDB.ExportSecrets("")
+		if err != nil {
+			rep.Fail(err)
+			handleError(err, "The re-encrypted vault does not read cleanly; keep the old password and a backup at hand")
+			return
 		}
+		rep.Done("")
 
-		// Perform rekey operation
-		fmt.Println("Re-encrypting vault with new password...")
-		if err := vaultDB.Rekey(oldPassword, newPassword); err != nil {
-			handleError(err, "Failed to rekey vault")
-			return
+		printInfo("✓ Vault password changed successfully")
+		printVerbose("Read back %d secrets with the new password", len(secrets))
+
+		// A cached derived key no longer matches; replace it with the new one
+		vaultID := keyring.VaultID(vaultPath)
+		if sessionMgr.GetKeyringManager().HasDerivedKey(vaultID) {
+			if err := sessionMgr.CacheDerivedKey(vaultID, newPassword); err != nil {
+				sessionMgr.GetKeyringManager().DeleteDerivedKey(vaultID)
+				fmt.Fprintf(os.Stderr, "Warning: failed to update cached derived key, removed it: %v\n", err)
+			} else {
+				printInfo("✓ Cached derived key updated")
+			}
 		}
 
-		fmt.Println("✓ Vault password changed successfully")
+		// A running agent still holds the old key
+		shareWithAgent(newPassword)
 
 		// Update keyring if auto-update flag is set or prompt user
 		autoUpdate, _ := cmd.Flags().GetBool("auto-update")
@@ -475,7 +1164,7 @@ Examples:
 				if err := km.UpdatePassword(newPassword); err != nil {
 					fmt.Fprintf(os.Stderr, "Warning: failed to update keyring: %v\n", err)
 				} else {
-					fmt.Println("✓ Keyring updated with new password")
+					printInfo("✓ Keyring updated with new password")
 				}
 			}
 		} else {
@@ -489,7 +1178,7 @@ Examples:
 					if err := km.UpdatePassword(newPassword); err != nil {
 						fmt.Fprintf(os.Stderr, "Warning: failed to update keyring: %v\n", err)
 					} else {
-						fmt.Println("✓ Keyring updated with new password")
+						printInfo("✓ Keyring updated with new password")
 					}
 				} else {
 					fmt.Println("Note: Old password remains in keyring. Run 'lockr keyring set' to update it.")
@@ -505,22 +1194,119 @@ Examples:
 func init() {
 	// get command flags
 	getCmd.Flags().Bool("no-copy", false, "Don't copy secret to clipboard")
-
-	// set command flags
-	setCmd.Flags().BoolP("generate", "g", false, "Auto-generate a random secret")
-	setCmd.Flags().IntP("length", "l", 24, "Length of generated secret")
+	getCmd.Flags().Bool("show", false, "Print the secret instead of copying it (same as --no-copy)")
+	getCmd.Flags().String("to-file", "", "Write the value to this file (mode 0600) instead of copying it, e.g. for certificates")
+	getCmd.Flags().String("transform", "", "Encode the value first: "+strings.Join(transform.Names, ", "))
+	getCmd.Flags().String("as", "", "Assemble the value from a structured secret's fields: dsn (a connection string)")
+	getCmd.Flags().String("driver", "", "Connection string format for --as dsn: "+strings.Join(dsn.Drivers(), ", "))
+	getCmd.Flags().Bool("no-resolve", false, "Return the stored value without expanding ${ref:key} references")
+	getCmd.Flags().String("max-age", "", "Serve a cached value fetched at most this long ago (seconds or duration)")
+	getCmd.Flags().Int("results", 0, "Results shown by the interactive picker (0 fits the terminal height)")
+	getCmd.Flags().String("density", "compact", "Interactive picker rows: compact, or detailed with namespace and tags")
+	getCmd.Flags().String("picker", search.PickerBuiltin, "Interactive picker: builtin, or fzf or sk to pick from key names with your own setup")
+	getCmd.Flags().Bool("show-notes", false, "Print the secret's notes on stderr")
+	getCmd.Flags().Bool("batch", false, "Read keys from stdin, one per line, and print all their values")
+	getCmd.Flags().String("output", "json", "Output of --batch: json (values or errors by key) or lines (values in input order)")
+
+	// set and update command flags
+	for _, c := range []*cobra.Command{setCmd, updateCmd} {
+		c.Flags().BoolP("generate", "g", false, "Auto-generate a random secret")
+		c.Flags().IntP("length", "l", defaultSecretLength, "Length of generated secret")
+		c.Flags().Bool("no-symbols", false, "Generate letters and digits only")
+		c.Flags().Bool("generator", false, "Choose a generated secret in an interactive panel")
+		c.Flags().Int("words", diceware.DefaultWords, "Generate a diceware passphrase with this many words")
+		c.Flags().String("separator", "-", "Separator between passphrase words")
+		c.Flags().Bool("diff", false, "Show a masked comparison with the current value before overwriting")
+	}
+	setCmd.Flags().Int64("if-revision", 0, "Only write if the secret is still at this revision (0 = must not exist)")
+	setCmd.Flags().Bool("upsert", false, "Create the secret or update it without asking")
+	setCmd.Flags().StringSlice("tag", nil, "Tag the secret (repeatable, or comma-separated)")
+	setCmd.Flags().String("note", "", "Store a note with the secret (also --notes)")
+	setCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "notes" {
+			name = "note"
+		}
+		return pflag.NormalizedName(name)
+	})
+	setCmd.Flags().String("url", "", "Store the URL where the secret is used")
+	setCmd.Flags().String("expires", "", "Mark the secret as expiring after a duration (90d) or on a date (2025-01-31)")
+	updateCmd.Flags().Int64("if-revision", 0, "Only write if the secret is still at this revision")
 
 	// list command flags (merged with search)
 	listCmd.Flags().String("format", "list", "Output format: list, table, json")
+	listCmd.Flags().Bool("raw", false, "Print only keys, one per line, with no headers or totals")
 	listCmd.Flags().String("sort", "accessed", "Sort by: key, created, accessed")
-	listCmd.Flags().Int("limit", 20, "Maximum number of search results to show")
+	listCmd.Flags().Int("limit", 20, "Maximum number of results to show (0 for no limit)")
+	listCmd.Flags().String("match", "fuzzy", "Pattern matching mode: fuzzy, glob, regex")
+	listCmd.Flags().Int("offset", 0, "Number of results to skip")
+	listCmd.Flags().Int("page", 0, "Page number to show (1-based, uses --limit as page size)")
+	listCmd.Flags().Bool("archived", false, "List archived secrets instead of the working set")
+	listCmd.Flags().Bool("explain", false, "Show how each fuzzy match was scored")
+	listCmd.Flags().StringSlice("tag", nil, "Only list secrets with this tag (repeatable; all must match)")
+
+	// init command flags
+	initCmd.Flags().String("from", "", "Seed the new vault from a lockrx export")
+	initCmd.Flags().String("from-bitwarden", "", "Seed the new vault from an unencrypted Bitwarden JSON export")
+	initCmd.MarkFlagsMutuallyExclusive("from", "from-bitwarden")
 
 	// rekey command flags
 	rekeyCmd.Flags().Bool("auto-update", false, "Automatically update keyring without prompting")
 }
 
+// pickerSettings are the interactive picker options from the config file
+var pickerSettings config.Picker
+
+// keymaps are the key bindings of the interactive screens, with the config
+// file's remappings applied
+var keymaps = keymap.Defaults()
+
+// pickerOptions combines the --results, --density and --picker flags with
+// the config file; flags win
+func pickerOptions(cmd *cobra.Command) (search.DisplayOptions, error) {
+	results := pickerSettings.Results
+	if cmd.Flags().Changed("results") {
+		results, _ = cmd.Flags().GetInt("results")
+	}
+	if results < 0 {
+		return search.DisplayOptions{}, fmt.Errorf("--results must be 0 or greater")
+	}
+
+	density := pickerSettings.Density
+	if cmd.Flags().Changed("density") {
+		density, _ = cmd.Flags().GetString("density")
+	}
+	d, err := search.ParseDensity(density)
+	if err != nil {
+		return search.DisplayOptions{}, err
+	}
+
+	picker := pickerSettings.Program
+	if cmd.Flags().Changed("picker") {
+		picker, _ = cmd.Flags().GetString("picker")
+	}
+	program, err := search.ParsePicker(picker)
+	if err != nil {
+		return search.DisplayOptions{}, err
+	}
+
+	theme := activeTheme()
+	// Settings has already validated the color
+	color, _ := theme.ColorCode()
+	return search.DisplayOptions{
+		Results: results,
+		Density: d,
+		Keys:    keymaps[keymap.ScreenPicker],
+		Program: program,
+		Label:   theme.Label,
+		Color:   color,
+
+		Reveal:    revealSecret,
+		RevealFor: pickerSettings.RevealFor,
+	}, nil
+}
+
 // interactiveGet runs the interactive search interface
-func interactiveGet() (string, error) {
+func interactiveGet(options search.DisplayOptions) (string, error) {
 	// Get all secrets for search
 	secrets, err := vaultDB.ListSecrets()
 	if err != nil {
@@ -533,16 +1319,364 @@ func interactiveGet() (string, error) {
 	}
 
 	// Run interactive search
-	return search.RunInteractiveSearch(secrets)
+	if options.Program != "" {
+		return search.RunExternalPicker(options.Program, secrets)
+	}
+	return search.RunInteractiveSearch(secrets, options)
+}
+
+// revealSecret returns the value of key for the picker's reveal key. Values
+// of clipboard-only secrets are never shown, and sensitive ones only once
+// password unlocks the vault.
+func revealSecret(key string, password []byte) ([]byte, error) {
+	if sessionMgr.Expired() {
+		return nil, errors.New("session expired; select the secret to unlock the vault again")
+	}
+	peeked, err := vaultDB.PeekSecret(key)
+	if err != nil {
+		return nil, err
+	}
+	defaults := resolvePolicy(key, peeked.Tags)
+	if defaults.ClipboardOnly {
+		return nil, policy.ErrClipboardOnly
+	}
+	if defaults.Sensitive {
+		if password == nil {
+			return nil, search.ErrPasswordRequired
+		}
+		if err := verifyPassword(string(password)); err != nil {
+			return nil, err
+		}
+	}
+
+	secret, err := vaultDB.GetSecret(key)
+	if err != nil {
+		return nil, err
+	}
+	auditSecretAccess(key)
+	value, err := refs.Resolve(secret.Key, secret.Value, checkedLookup(nil, ""))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(value), nil
+}
+
+// verifyPassword checks password unlocks the vault, on a connection of its
+// own so the current session is left alone
+func verifyPassword(password string) error {
+	store, err := database.OpenStore(vaultEngine(vaultPath), vaultPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	if err := store.Connect(password); err != nil {
+		if err == database.ErrAuthenticationFailed {
+			return errors.New("wrong vault password")
+		}
+		return err
+	}
+	return nil
+}
+
+// printExplanation prints the rules behind a match score, one per line
+func printExplanation(e search.Explanation) {
+	fmt.Printf("     %s match\n", e.Quality)
+	for _, f := range e.Factors {
+		fmt.Printf("     %+7.1f  %-12s %s\n", f.Value, f.Name, f.Detail)
+	}
+	for _, note := range e.Notes {
+		fmt.Printf("              %s\n", note)
+	}
+}
+
+// listMatching lists secrets matching a glob or regex pattern evaluated in SQL
+func listMatching(mode search.MatchMode, pattern string, limit, offset int, raw bool) {
+	var results []database.SearchResult
+	var total int
+	var err error
+
+	switch mode {
+	case search.MatchGlob:
+		results, total, err = vaultDB.SearchSecretsGlob(pattern, limit, offset)
+	case search.MatchRegex:
+		results, total, err = vaultDB.SearchSecretsRegex(pattern, limit, offset)
+	}
+	if err != nil {
+		handleError(err, "Search failed")
+		return
+	}
+
+	if raw {
+		printRawKeys(results)
+		return
+	}
+
+	if total == 0 {
+		fmt.Printf("No matches found for %s pattern '%s'\n", mode, pattern)
+		return
+	}
+
+	fmt.Printf("Showing %d of %d matches for %s pattern '%s'%s:\n\n",
+		len(results), total, mode, pattern, formatOffset(offset))
+	for i, result := range results {
+		fmt.Printf("%d. %s (accessed: %d times)\n", offset+i+1, result.Key, result.AccessCount)
+	}
+}
+
+// resolvePagination reads the --limit, --offset and --page flags and returns
+// the effective limit and offset
+func resolvePagination(cmd *cobra.Command) (int, int, error) {
+	limit, _ := cmd.Flags().GetInt("limit")
+	offset, _ := cmd.Flags().GetInt("offset")
+	page, _ := cmd.Flags().GetInt("page")
+
+	if limit < 0 {
+		return 0, 0, fmt.Errorf("--limit must not be negative")
+	}
+	if offset < 0 {
+		return 0, 0, fmt.Errorf("--offset must not be negative")
+	}
+
+	if cmd.Flags().Changed("page") {
+		if cmd.Flags().Changed("offset") {
+			return 0, 0, fmt.Errorf("--page and --offset cannot be used together")
+		}
+		if page < 1 {
+			return 0, 0, fmt.Errorf("--page must be 1 or greater")
+		}
+		if limit == 0 {
+			return 0, 0, fmt.Errorf("--page requires a non-zero --limit")
+		}
+		offset = (page - 1) * limit
+	}
+
+	return limit, offset, nil
+}
+
+// formatOffset returns a short suffix describing a non-zero result offset
+func formatOffset(offset int) string {
+	if offset == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (starting at %d)", offset+1)
+}
+
+// loadSeedData reads the records requested by init's --from flags, returning
+// nil when no seed file was given
+func loadSeedData(cmd *cobra.Command) ([]vaultio.Record, error) {
+	sources := []struct {
+		flag   string
+		format string
+	}{
+		{"from", vaultio.FormatLockrx},
+		{"from-bitwarden", vaultio.FormatBitwarden},
+	}
+
+	for _, source := range sources {
+		path, _ := cmd.Flags().GetString(source.flag)
+		if path == "" {
+			continue
+		}
+
+		records, err := vaultio.ReadFile(path, source.format)
+		if err != nil {
+			return nil, err
+		}
+		printVerbose("Read %d records from %s", len(records), path)
+		return records, nil
+	}
+
+	return nil, nil
+}
+
+// deliverSecret copies a secret value to the clipboard, falling back to
+// printing it when the clipboard is unavailable or copying is disabled.
+// Clipboard-only secrets are never printed, whatever the flags. With
+// --no-clipboard a value is only printed when that was asked for.
+func deliverSecret(value string, noCopy bool, defaults policy.Defaults) error {
+	if noClipboard && !noCopy && !defaults.ClipboardOnly {
+		return fmt.Errorf("the clipboard is disabled by --no-clipboard; pass --show to print the value")
+	}
+	if defaults.ClipboardOnly {
+		if noCopy {
+			return fmt.Errorf("%w; --no-copy is not allowed", policy.ErrClipboardOnly)
+		}
+		if clipboardMgr == nil {
+			return fmt.Errorf("%w and no clipboard is available", policy.ErrClipboardOnly)
+		}
+		if err := confirmLargeCopy(value); err != nil {
+			return err
+		}
+		return copySecret(value)
+	}
+
+	if !noCopy && clipboardMgr != nil {
+		if err := confirmLargeCopy(value); err != nil {
+			return err
+		}
+		if err := copySecret(value); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to copy to clipboard: %v\n", err)
+			printSecret(value)
+		}
+	} else {
+		printSecret(value)
+	}
+	return nil
+}
+
+// confirmCopySize is the value size from which copying to the clipboard
+// asks first (0 never asks), from the config file's clipboard.confirm_size
+var confirmCopySize int64 = config.DefaultConfirmCopySize
+
+// confirmLargeCopy asks before a value of confirmCopySize bytes or more is
+// copied, since some clipboard managers freeze on them. It returns an error
+// when the value should not be copied. --force skips the question.
+func confirmLargeCopy(value string) error {
+	if confirmCopySize <= 0 || int64(len(value)) < confirmCopySize || force {
+		return nil
+	}
+
+	size := config.FormatSize(int64(len(value)))
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("the value is %s, enough to freeze some clipboard managers; pass --force to copy it anyway or write it to a file with 'lockr get --to-file'", size)
+	}
+	fmt.Printf("The value is %s, enough to freeze some clipboard managers.\n", size)
+	fmt.Print("Copy it anyway? 'lockr get --to-file PATH' writes it to a file instead. (y/N): ")
+	var response string
+	fmt.Scanln(&response)
+	if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
+		return errors.New("not copied")
+	}
+	return nil
+}
+
+// writeSecretFile writes a value to path, readable only by the current
+// user. Clipboard-only secrets are never written out.
+func writeSecretFile(path, value string, defaults policy.Defaults) error {
+	if defaults.ClipboardOnly {
+		return fmt.Errorf("%w; --to-file is not allowed", policy.ErrClipboardOnly)
+	}
+	if err := writePrivateFile(path, []byte(value)); err != nil {
+		return err
+	}
+	printInfo("✓ Value written to %s (mode 0600)", path)
+	return nil
+}
+
+// copySecret copies a value to the clipboard, timing it for --timings
+func copySecret(value string) error {
+	defer timePhase("clipboard")()
+	return clipboardMgr.CopySecretWithNotification(value)
+}
+
+// printSecret prints a secret value, bare in quiet mode so scripts can use
+// the output as is
+func printSecret(value string) {
+	if quiet {
+		fmt.Println(value)
+		return
+	}
+	fmt.Printf("Secret: %s\n", value)
+}
+
+// secretLookup adapts a secret reader for reference resolution. Referenced
+// secrets are read without access tracking.
+func secretLookup(peek func(key string) (*database.Secret, error)) refs.Lookup {
+	return func(key string) (string, error) {
+		secret, err := peek(key)
+		if err != nil {
+			return "", err
+		}
+		return secret.Value, nil
+	}
+}
+
+// policyLookup is secretLookup for values delivered under policy defaults d.
+// d is made as strict as the policy of every secret referenced, so a plain
+// secret referencing a clipboard-only one is delivered as clipboard-only.
+func policyLookup(d *policy.Defaults) refs.Lookup {
+	return func(key string) (string, error) {
+		secret, err := vaultDB.PeekSecret(key)
+		if err != nil {
+			return "", err
+		}
+		tighten(d, resolvePolicy(secret.Key, secret.Tags))
+		return secret.Value, nil
+	}
+}
+
+// tighten makes the delivery settings of d as strict as those of other: the
+// shorter clear delay, and clipboard-only or sensitive if either is
+func tighten(d *policy.Defaults, other policy.Defaults) {
+	strict := policy.Strictest(*d, other)
+	d.ClearAfter, d.ClipboardOnly, d.Sensitive = strict.ClearAfter, strict.ClipboardOnly, strict.Sensitive
+}
+
+// checkedLookup is secretLookup for values handed to a program rather than
+// the vault's owner: each referenced secret must be one client may get
+// under az (skipped when az is nil) and must not be clipboard-only, or the
+// whole value is refused. Otherwise a secret the program may read could
+// reference, and so reveal, one it may not.
+func checkedLookup(az *authz.Authorizer, client string) refs.Lookup {
+	return func(key string) (string, error) {
+		if az != nil {
+			if err := az.Check(client, authz.OpGet, key); err != nil {
+				return "", err
+			}
+		}
+		secret, err := vaultDB.PeekSecret(key)
+		if err != nil {
+			return "", err
+		}
+		if resolvePolicy(secret.Key, secret.Tags).ClipboardOnly {
+			return "", policy.ErrClipboardOnly
+		}
+		return secret.Value, nil
+	}
+}
+
+// checkReferences warns about references in a new value that cannot be
+// resolved, without refusing to store it
+func checkReferences(key, value string) {
+	if len(refs.References(value)) == 0 {
+		return
+	}
+	if _, err := refs.Resolve(key, value, secretLookup(vaultDB.PeekSecret)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// auditSecretAccess records a secret retrieval in the vault's audit log
+func auditSecretAccess(key string) {
+	if err := sessionMgr.Audit(database.AuditEventGet, key); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record audit event: %v\n", err)
+	}
 }
 
 // printSecretsList prints secrets in a simple list format
 func printSecretsList(secrets []database.SearchResult) {
 	for _, secret := range secrets {
-		fmt.Printf("%-30s (accessed %d times, created %s)\n",
+		fmt.Printf("%-30s (accessed %d times, created %s)%s\n",
 			secret.Key,
 			secret.AccessCount,
-			secret.CreatedAt.Format("2006-01-02"))
+			secret.CreatedAt.Format("2006-01-02"),
+			formatTags(secret.Tags))
+	}
+}
+
+// formatTags renders a tag list as a " [a, b]" suffix, or nothing
+func formatTags(tags *string) string {
+	parsed := policy.ParseTags(tags)
+	if len(parsed) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(parsed, ", ") + "]"
+}
+
+// printRawKeys prints one key per line with no decoration, for scripts
+func printRawKeys(secrets []database.SearchResult) {
+	for _, secret := range secrets {
+		fmt.Println(secret.Key)
 	}
 }
 
@@ -568,7 +1702,10 @@ func printSecretsJSON(secrets []database.SearchResult) {
 		fmt.Printf("    \"key\": \"%s\",\n", secret.Key)
 		fmt.Printf("    \"created_at\": \"%s\",\n", secret.CreatedAt.Format(time.RFC3339))
 		fmt.Printf("    \"last_accessed\": \"%s\",\n", secret.LastAccessed.Format(time.RFC3339))
-		fmt.Printf("    \"access_count\": %d\n", secret.AccessCount)
+		fmt.Printf("    \"access_count\": %d,\n", secret.AccessCount)
+		tags, _ := json.Marshal(policy.ParseTags(secret.Tags))
+		fmt.Printf("    \"tags\": %s,\n", tags)
+		fmt.Printf("    \"revision\": %d\n", secret.Revision)
 		if i == len(secrets)-1 {
 			fmt.Printf("  }\n")
 		} else {
@@ -611,30 +1748,18 @@ func getCommit() string {
 	return versionInfo.commit
 }
 
-// generateSecret generates a cryptographically secure random secret
-func generateSecret(length int) (string, error) {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*()-_=+[]{}|;:,.<>?"
+// defaultSecretLength is the length of generated secrets unless a flag or
+// policy says otherwise
+const defaultSecretLength = 24
 
-	if length < 8 {
-		return "", fmt.Errorf("secret length must be at least 8 characters")
-	}
-	if length > 256 {
-		return "", fmt.Errorf("secret length must not exceed 256 characters")
-	}
-
-	secret := make([]byte, length)
-	charsetLen := len(charset)
+// secretCharset is the alphabet used for generated secrets
+const secretCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*()-_=+[]{}|;:,.<>?"
 
-	for i := range secret {
-		// Generate a random byte
-		randomBytes := make([]byte, 1)
-		if _, err := rand.Read(randomBytes); err != nil {
-			return "", fmt.Errorf("failed to generate random bytes: %w", err)
-		}
-
-		// Map the random byte to a character in the charset
-		secret[i] = charset[int(randomBytes[0])%charsetLen]
-	}
+// alphanumericCharset is the alphabet used when symbols are turned off
+const alphanumericCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
-	return string(secret), nil
+// generateSecret generates a cryptographically secure random secret drawn
+// uniformly from charset
+func generateSecret(length int, charset string) (string, error) {
+	return generator.Random(length, charset)
 }
@@ -0,0 +1,327 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/config"
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/keymap"
+	"github.com/lockr/go/internal/refs"
+)
+
+// Settings from the config file that are applied once the globals exist;
+// zero values keep the defaults
+var (
+	sessionTimeout   time.Duration
+	clipboardTimeout time.Duration
+	keyringEnabled   = true
+	vaultDurability  = database.DefaultDurability
+	storageEngine    string
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and edit the configuration file",
+	Long: `Inspect and edit the configuration file (--config, default config.yml in the
+config directory shown by 'lockr status').
+
+Settings:
+  vault_path: ~/work.lockr   vault used unless --vault or a workspace names one
+  session_timeout: 30m       how long an unlocked vault stays unlocked unused
+                             (default 15m; also the agent's timeout)
+  clipboard_timeout: 30s     how long copied secrets stay on the clipboard
+                             (default 60s)
+  keyring_enabled: false     turn off keyring integration
+  secret_length: 32          length of generated secrets, like --length
+  list_format: table         output of 'lockr list', like --format
+  durability: full           wait for every write to reach the disk and keep
+                             the vault one complete file between commands,
+                             for vaults in a file-sync folder (default normal)
+  engine: sqlcipher          storage engine of the vaults (default sqlcipher)
+  vaults:                    tell vaults apart in prompts, status and the
+    ~/work.lockr:            picker (color: a name, 0-255 or #rrggbb)
+      label: work
+      color: red
+      engine: sqlcipher      storage engine of this vault, overriding engine
+  no_clipboard: true   never use the clipboard, like --no-clipboard
+  limits:
+    max_secrets: 5000      warn in 'set' and 'status' above this many secrets
+    max_vault_size: 50MB   ... or when the vault file grows beyond this size
+  picker:
+    results: 10            results shown by 'lockr get', like --results
+    density: detailed      compact or detailed rows, like --density
+    program: fzf           builtin, fzf or sk, like --picker
+    reveal_for: 5s         how long the reveal key shows a value (default 10s)
+  clipboard:
+    confirm_size: 1MB      ask before copying values this large (default
+                           256KB; 0 never asks)
+    backend: osc52         clipboard to use, like --clipboard-backend
+  keys:                    remap keys of the interactive screens
+    picker:                (picker, generator or merge; press ? in a
+      up: [up, ctrl+k]     screen to see its actions and current keys)
+
+Flags and LOCKR_* variables override every setting.
+
+Limits are soft: writes still succeed, but the warning makes runaway
+automation noticeable before the vault becomes unwieldy.
+
+Sensitive settings such as webhook URLs with tokens or sync credentials can
+live in the vault instead of the file. Write the value as a reference to a
+secret and it is read from the vault when the setting is used:
+
+  webhook_url: !lockr ops/webhook
+  sync:
+    password: "!lockr:sync/password"
+
+The file then holds no secrets and is safe to back up or commit.`,
+}
+
+var configCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Verify that every vault reference in the config resolves",
+	Long: `List the settings in the configuration file that refer to secrets and check
+that each secret exists. Values are never printed.
+
+Examples:
+  lockr config check
+  lockr config check -c ./ci-config.yml`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		f, err := config.Load(configPath)
+		if err != nil {
+			handleError(err, "Failed to read config")
+			return
+		}
+
+		references := f.References()
+		if len(references) == 0 {
+			fmt.Printf("No vault references in %s\n", configPath)
+			return
+		}
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		failed := 0
+		for _, ref := range references {
+			status := "ok"
+			if _, err := configLookup(ref.Key); err != nil {
+				status = err.Error()
+				failed++
+			}
+			fmt.Printf("  %-24s %s %-24s %s\n", ref.Path, config.RefTag, ref.Key, status)
+		}
+
+		if failed > 0 {
+			fmt.Fprintf(os.Stderr, "%d of %d references do not resolve\n", failed, len(references))
+			os.Exit(1)
+		}
+		fmt.Printf("All %d references resolve\n", len(references))
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <setting>",
+	Short: "Print a setting from the configuration file",
+	Long: `Print a setting as written in the configuration file. Nested settings are
+named with dots; a reference prints as "!lockr <key>", never as the secret.
+Exits with status 1 when the setting is not in the file.
+
+Examples:
+  lockr config get session_timeout
+  lockr config get picker.results`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		f, err := config.Load(configPath)
+		if err != nil {
+			handleError(err, "Failed to read config")
+			return
+		}
+
+		value, err := f.Get(args[0])
+		if errors.Is(err, config.ErrNotSet) {
+			fmt.Fprintf(os.Stderr, "%s is not set in %s\n", args[0], configPath)
+			os.Exit(1)
+		}
+		if err != nil {
+			handleError(err, "Failed to read setting")
+			return
+		}
+		fmt.Println(value)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <setting> <value>",
+	Short: "Change a setting in the configuration file",
+	Long: `Change a setting in the configuration file, creating the file if needed.
+Comments and other settings are kept. The change is only saved if the
+resulting file is valid. Write "!lockr <key>" to make the setting a
+reference to a secret.
+
+Examples:
+  lockr config set session_timeout 30m
+  lockr config set picker.density compact
+  lockr config set webhook_url '!lockr ops/webhook'`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		f, err := config.Load(configPath)
+		if err != nil {
+			handleError(err, "Failed to read config")
+			return
+		}
+
+		if err := f.Set(args[0], args[1]); err != nil {
+			handleError(err, "Failed to change setting")
+			return
+		}
+		if _, err := f.Settings(); err != nil {
+			handleError(err, "Not saved")
+			return
+		}
+		if err := f.Save(); err != nil {
+			handleError(err, "Failed to write config")
+			return
+		}
+		printInfo("✓ Set %s in %s", args[0], configPath)
+	},
+}
+
+// applyConfigSettings applies the config file's options to flags that were
+// not set on the command line or in the environment
+func applyConfigSettings(cmd *cobra.Command) {
+	f, err := config.Load(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring config: %v\n", err)
+		return
+	}
+	settings, err := f.Settings()
+	if err == nil {
+		err = checkEngines(settings)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring config %s: %v\n", configPath, err)
+		return
+	}
+
+	if !cmd.Flags().Changed("no-clipboard") && settings.NoClipboard {
+		noClipboard = true
+	}
+	if path, err := settings.Vault(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring vault_path: %v\n", err)
+	} else if path != "" {
+		setFlagDefault(cmd, "vault", path)
+	}
+	if settings.Clipboard.Backend != "" {
+		setFlagDefault(cmd, "clipboard-backend", settings.Clipboard.Backend)
+	}
+	if settings.SecretLength > 0 {
+		setFlagDefault(cmd, "length", strconv.Itoa(settings.SecretLength))
+	}
+	if settings.ListFormat != "" && cmd == listCmd {
+		setFlagDefault(cmd, "format", settings.ListFormat)
+	}
+	sessionTimeout = settings.SessionTimeout
+	clipboardTimeout = settings.ClipboardTimeout
+	keyringEnabled = settings.KeyringEnabled == nil || *settings.KeyringEnabled
+	// Settings has already validated the mode
+	vaultDurability, _ = database.ParseDurability(settings.Durability)
+	storageEngine = settings.Engine
+	vaultLimits = settings.Limits
+	// Settings has already validated the size
+	confirmCopySize, _ = settings.Clipboard.ConfirmBytes()
+	pickerSettings = settings.Picker
+	vaultThemes = settings.Vaults
+	// Settings has already validated the overrides
+	keymaps, _ = keymap.Load(settings.Keys)
+}
+
+// checkEngines checks that every storage engine the config names is one
+// this build of lockr has
+func checkEngines(settings config.Settings) error {
+	known := database.Engines()
+	check := func(setting, engine string) error {
+		if engine != "" && !slices.Contains(known, engine) {
+			return fmt.Errorf("invalid config: %s %q is not a storage engine (use %s)", setting, engine, strings.Join(known, ", "))
+		}
+		return nil
+	}
+	if err := check("engine", settings.Engine); err != nil {
+		return err
+	}
+	for path, vault := range settings.Vaults {
+		if err := check("vaults."+path+".engine", vault.Engine); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vaultEngine returns the storage engine of the vault at path: the one its
+// entry under vaults names, else the engine setting, else the default
+func vaultEngine(path string) string {
+	if engine := vaultThemes.For(path).Engine; engine != "" {
+		return engine
+	}
+	return storageEngine
+}
+
+// setFlagDefault sets flag name of cmd to a value from the config file
+// unless the command line or environment gave one. The flag still counts as
+// unset, so workspaces and policies can override it as they would the
+// built-in default.
+func setFlagDefault(cmd *cobra.Command, name, value string) {
+	f := cmd.Flags().Lookup(name)
+	if f == nil || f.Changed {
+		return
+	}
+	if err := f.Value.Set(value); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring config value %q for --%s: %v\n", value, name, err)
+	}
+}
+
+// loadConfig reads the configuration file, resolving vault references
+// when resolve is set. Resolving unlocks the vault if the file has any.
+func loadConfig(resolve bool) (*config.File, error) {
+	f, err := config.Load(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if !resolve || f.Resolved() {
+		return f, nil
+	}
+
+	if err := ensureAuthenticated(); err != nil {
+		return nil, err
+	}
+	if err := f.Resolve(configLookup); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// configLookup reads a secret referenced from the config, expanding any
+// ${ref:key} references in it. Reads are not tracked as accesses.
+func configLookup(key string) (string, error) {
+	secret, err := vaultDB.PeekSecret(key)
+	if err != nil {
+		return "", err
+	}
+	return refs.Resolve(secret.Key, secret.Value, secretLookup(vaultDB.PeekSecret))
+}
+
+func init() {
+	configCmd.AddCommand(configCheckCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+}
@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lockr/go/internal/config"
+	"github.com/lockr/go/internal/database"
+)
+
+func TestVaultEngine(t *testing.T) {
+	defer func() { vaultThemes, storageEngine = nil, "" }()
+
+	vaultThemes = config.Themes{"/vaults/work.lockr": {Label: "work", Engine: "bolt"}}
+	assert.Equal(t, "bolt", vaultEngine("/vaults/work.lockr"))
+	assert.Equal(t, "", vaultEngine("/vaults/home.lockr"))
+
+	storageEngine = database.DefaultEngine
+	assert.Equal(t, database.DefaultEngine, vaultEngine("/vaults/home.lockr"))
+}
+
+func TestCheckEngines(t *testing.T) {
+	assert.NoError(t, checkEngines(config.Settings{}))
+	assert.NoError(t, checkEngines(config.Settings{Engine: database.DefaultEngine}))
+	assert.ErrorContains(t, checkEngines(config.Settings{Engine: "bolt"}), `engine "bolt"`)
+	assert.ErrorContains(t, checkEngines(config.Settings{
+		Vaults: config.Themes{"~/work.lockr": {Engine: "bolt"}},
+	}), "vaults.~/work.lockr.engine")
+}
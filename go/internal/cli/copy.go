@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/policy"
+	"github.com/lockr/go/internal/refs"
+)
+
+var copyCmd = &cobra.Command{
+	Use:   "copy <key>",
+	Short: "Copy a secret to the clipboard, never printing it",
+	Long: `Copy a secret's value to the clipboard. Unlike 'lockr get', copy never falls
+back to printing the value: without a clipboard it fails and points to
+'lockr show'. The clipboard is cleared as configured (clipboard_timeout, or
+the secret's policy).
+
+Examples:
+  lockr copy github-token`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if clipboardMgr == nil {
+			handleError(errors.New("no clipboard is available; print the value with 'lockr show'"), "")
+			return
+		}
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		key := activeWorkspace.QualifyKey(args[0])
+		value, defaults, err := readSecret(key)
+		if err != nil {
+			handleError(err, fmt.Sprintf("Failed to get secret '%s'", key))
+			return
+		}
+		applyClipboardPolicy(defaults)
+		if err := confirmLargeCopy(value); err != nil {
+			handleError(err, "")
+			return
+		}
+		if err := copySecret(value); err != nil {
+			handleError(err, "Failed to copy to clipboard")
+			return
+		}
+	},
+}
+
+var showCmd = &cobra.Command{
+	Use:   "show <key>",
+	Short: "Print a secret to stdout, never touching the clipboard",
+	Long: `Print a secret's value to stdout, for headless machines and pipes. The
+clipboard is never used.
+
+With --reveal N, the value is shown on the terminal for N seconds and then
+erased from the screen, so it does not linger in view or in a shared
+screen session. Ctrl-C erases it early. Clipboard-only secrets are never
+printed.
+
+Examples:
+  lockr show db/password | psql-connect
+  lockr show --reveal 10 wifi/home   # Read it off the screen, then it is gone`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		reveal, _ := cmd.Flags().GetInt("reveal")
+		if reveal < 0 {
+			handleError(errors.New("--reveal must be a number of seconds"), "")
+			return
+		}
+		if reveal > 0 && !term.IsTerminal(int(os.Stdout.Fd())) {
+			handleError(errors.New("--reveal needs a terminal to erase the value from"), "")
+			return
+		}
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		key := activeWorkspace.QualifyKey(args[0])
+		value, defaults, err := readSecret(key)
+		if err != nil {
+			handleError(err, fmt.Sprintf("Failed to get secret '%s'", key))
+			return
+		}
+		if defaults.ClipboardOnly {
+			handleError(fmt.Errorf("%w; use 'lockr copy'", policy.ErrClipboardOnly), "")
+			return
+		}
+
+		if reveal == 0 {
+			fmt.Println(value)
+			return
+		}
+		revealOnTerminal(value, time.Duration(reveal)*time.Second)
+	},
+}
+
+// readSecret returns the value of key with references resolved, and its
+// policy defaults made as strict as those of the secrets it references. The
+// retrieval is counted and audited like get.
+func readSecret(key string) (string, policy.Defaults, error) {
+	var referenced policy.Defaults
+	value, defaults, err := readSecretWith(key, policyLookup(&referenced))
+	tighten(&defaults, referenced)
+	return value, defaults, err
+}
+
+// readSecretWith is readSecret resolving references through lookup
+func readSecretWith(key string, lookup refs.Lookup) (string, policy.Defaults, error) {
+	secret, err := vaultDB.GetSecret(key)
+	if err == database.ErrKeyNotFound {
+		if hint := archivedHint(key); hint != "" {
+			fmt.Fprintln(os.Stderr, hint)
+		}
+	}
+	if err != nil {
+		return "", policy.Defaults{}, err
+	}
+
+	auditSecretAccess(key)
+	defaults := resolvePolicy(key, secret.Tags)
+	value, err := refs.Resolve(secret.Key, secret.Value, lookup)
+	if err != nil {
+		return "", defaults, err
+	}
+	warnExpired(secret)
+	return value, defaults, nil
+}
+
+// revealOnTerminal shows value on the terminal for d, or until interrupted,
+// then erases every screen row it took
+func revealOnTerminal(value string, d time.Duration) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	shown := value
+	fmt.Print(value)
+	if !quiet {
+		hint := fmt.Sprintf("  (hidden in %s)", d)
+		fmt.Fprint(os.Stderr, hint)
+		shown += hint
+	}
+
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+
+	rows := screenRows(shown)
+	fmt.Print("\r\033[2K")
+	for range rows - 1 {
+		fmt.Print("\033[1A\033[2K")
+	}
+}
+
+// screenRows returns how many terminal rows text takes, counting lines
+// that wrap at the terminal's width
+func screenRows(text string) int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		width = 80
+	}
+	rows := 0
+	for _, line := range strings.Split(text, "\n") {
+		rows += max(1, (utf8.RuneCountInString(line)+width-1)/width)
+	}
+	return rows
+}
+
+func init() {
+	showCmd.Flags().Int("reveal", 0, "Show the value for this many seconds, then erase it from the terminal")
+}
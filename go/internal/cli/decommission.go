@@ -0,0 +1,332 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/keyring"
+	"github.com/lockr/go/internal/paths"
+)
+
+// artifact is something lockr left on this machine
+type artifact struct {
+	// what describes it in the checklist
+	what string
+
+	// remove deletes it; nil means it has to be removed by hand
+	remove func() error
+}
+
+// shellFiles are the shell startup files checked for lockr integration,
+// relative to the home directory
+var shellFiles = []string{".bashrc", ".bash_profile", ".profile", ".zshrc", ".zprofile", ".config/fish/config.fish"}
+
+// lockrWord finds lockr mentioned in a shell startup file
+var lockrWord = regexp.MustCompile(`\blockr\b`)
+
+var decommissionCmd = &cobra.Command{
+	Use:   "decommission",
+	Short: "Remove everything lockr left on this machine",
+	Long: `Remove what lockr keeps on this machine before it is retired: the running
+agent and the keys it holds, keyring entries (vault password, derived and
+cache keys), the value cache, session files and sockets, the config file,
+the password hint and the workspace trust list. The vault itself is only
+deleted with --vault-too, after typing its file name; without it nothing
+that holds the vault is touched, even where the vault shares a directory
+with lockr's other files, as on macOS and Windows. --vault-too deletes the
+vault in use and its side files only; a ~/.lockr directory left by an
+earlier version, which may hold other vaults, is listed for removal by hand.
+
+The vault password is not needed, so a machine can be cleaned up even when
+the vault is locked for good.
+
+A checklist of what was removed follows, along with what has to be removed
+by hand: lines in shell startup files mentioning lockr, and backups made
+with clone, export or 'emergency setup', which live wherever they were
+written. Exits with status 1 if anything could not be removed.
+
+Examples:
+  lockr decommission --dry-run      # Only list what would be removed
+  lockr decommission                # Keep the vault file
+  lockr decommission --vault-too    # Delete the vault as well`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		vaultToo, _ := cmd.Flags().GetBool("vault-too")
+
+		artifacts := findArtifacts(vaultToo)
+		var removable, manual []artifact
+		for _, a := range artifacts {
+			if a.remove == nil {
+				manual = append(manual, a)
+			} else {
+				removable = append(removable, a)
+			}
+		}
+
+		if len(removable) == 0 {
+			fmt.Println("Nothing of lockr's is left on this machine")
+		} else {
+			fmt.Println("Found on this machine:")
+			for _, a := range removable {
+				fmt.Printf("  - %s\n", a.what)
+			}
+		}
+		if !vaultToo {
+			fmt.Printf("The vault %s is kept; pass --vault-too to delete it.\n", vaultPath)
+		}
+		if dryRun || len(removable) == 0 {
+			printManual(manual)
+			return
+		}
+
+		if !force {
+			fmt.Print("\nRemove all of the above? (y/N): ")
+			var response string
+			fmt.Scanln(&response)
+			if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
+				fmt.Println("Cancelled")
+				return
+			}
+		}
+		if vaultToo {
+			if err := confirmVaultDeletion(); err != nil {
+				handleError(err, "")
+				return
+			}
+		}
+
+		failed := 0
+		fmt.Println("\nDecommission report:")
+		for _, a := range removable {
+			if err := a.remove(); err != nil {
+				fmt.Printf("  ✗ %s: %v\n", a.what, err)
+				failed++
+				continue
+			}
+			fmt.Printf("  ✓ %s\n", a.what)
+		}
+		printManual(manual)
+
+		if failed > 0 {
+			fmt.Fprintf(os.Stderr, "%d of %d items could not be removed\n", failed, len(removable))
+			os.Exit(1)
+		}
+	},
+}
+
+// findArtifacts lists what lockr left on this machine. The vault and
+// anything that may contain it are only included with vaultToo.
+func findArtifacts(vaultToo bool) []artifact {
+	var found []artifact
+
+	for _, client := range allAgentClients() {
+		if !client.Running() {
+			continue
+		}
+		found = append(found, artifact{"Agent on " + client.Path() + " and the keys it holds", func() error {
+			_, err := client.Stop()
+			return err
+		}})
+	}
+
+	found = append(found, keyringArtifacts()...)
+
+	// Listed as description, path pairs. Only lockr's own files are
+	// listed, never the base directories: on macOS and Windows those hold
+	// the vault as well.
+	var files []string
+	for _, path := range dirs.OwnFiles() {
+		what := "Socket"
+		switch path {
+		case dirs.ValueCacheDir():
+			what = "Value cache"
+		case filepath.Dir(dirs.SessionFile("x")):
+			what = "Session files"
+		}
+		files = append(files, what, path)
+	}
+	files = append(files,
+		"Config file", configPath,
+		"Password hint", hintPath(),
+		"Workspace trust list", dirs.TrustFile(),
+	)
+	if vaultToo {
+		// Only the vault in use: other vaults, such as those left in the
+		// legacy directory, were never chosen
+		files = append(files,
+			"Vault", vaultPath,
+			"Vault journal", vaultPath+"-journal",
+			"Vault write-ahead log", vaultPath+"-wal",
+			"Vault shared memory file", vaultPath+"-shm")
+	}
+	seen := map[string]bool{}
+	for i := 0; i < len(files); i += 2 {
+		what, path := files[i], files[i+1]
+		if path == "" || seen[path] {
+			continue
+		}
+		seen[path] = true
+		if _, err := os.Lstat(path); err != nil {
+			continue
+		}
+		if !vaultToo && enclosesKept(path) {
+			continue
+		}
+		found = append(found, artifact{what + " " + path, func() error { return os.RemoveAll(path) }})
+	}
+	if !vaultToo {
+		// Emptied base directories go too; a directory still holding
+		// anything, such as the vault, stays
+		for _, dir := range []string{dirs.Runtime, dirs.State, dirs.Cache} {
+			if seen[dir] {
+				continue
+			}
+			seen[dir] = true
+			if _, err := os.Stat(dir); err == nil && !enclosesKept(dir) {
+				found = append(found, artifact{"Directory " + dir + " if empty", func() error { removeEmptyDir(dir); return nil }})
+			}
+		}
+	}
+	if dirs.Legacy != "" {
+		if _, err := os.Stat(dirs.Legacy); err == nil {
+			found = append(found, artifact{what: dirs.Legacy + " is left from an earlier version and may hold a vault"})
+		}
+	}
+
+	found = append(found, shellArtifacts()...)
+	found = append(found, artifact{what: "Backups made with clone, export or 'emergency setup' are wherever you wrote them"})
+	return found
+}
+
+// enclosesKept reports whether removing path would remove something kept
+// without --vault-too: the vault, the data directory or the trust list
+// (removed on its own, but never with its directory)
+func enclosesKept(path string) bool {
+	for _, kept := range []string{vaultPath, dirs.Data} {
+		if kept != "" && paths.Encloses(path, kept) {
+			return true
+		}
+	}
+	return path != dirs.TrustFile() && paths.Encloses(path, dirs.TrustFile())
+}
+
+// removeEmptyDir removes dir if nothing is left in it
+func removeEmptyDir(dir string) {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) == 0 {
+		os.Remove(dir)
+	}
+}
+
+// keyringArtifacts lists the keyring entries of every vault known on this
+// machine: the active one and those with sessions or value caches
+func keyringArtifacts() []artifact {
+	km := sessionMgr.GetKeyringManager()
+	// Entries may exist even if keyring use is turned off now
+	km.Enable()
+
+	var found []artifact
+	if km.HasPassword() {
+		found = append(found, artifact{"Vault password in the keyring", km.DeletePassword})
+	}
+
+	for _, id := range knownVaultIDs() {
+		if km.HasDerivedKey(id) {
+			found = append(found, artifact{"Derived key of vault " + id + " in the keyring", func() error { return km.DeleteDerivedKey(id) }})
+		}
+		if km.HasCacheKey(id) {
+			found = append(found, artifact{"Value cache key of vault " + id + " in the keyring", func() error { return km.DeleteCacheKey(id) }})
+		}
+	}
+	return found
+}
+
+// knownVaultIDs returns the IDs of the active vault and of every vault that
+// left a session file or value cache behind
+func knownVaultIDs() []string {
+	ids := map[string]bool{keyring.VaultID(vaultPath): true}
+	if entries, err := os.ReadDir(filepath.Dir(dirs.SessionFile("x"))); err == nil {
+		for _, e := range entries {
+			ids[e.Name()] = true
+		}
+	}
+	if entries, err := os.ReadDir(valueCacheDir()); err == nil {
+		for _, e := range entries {
+			if id, ok := strings.CutSuffix(e.Name(), ".cache"); ok {
+				ids[id] = true
+			}
+		}
+	}
+
+	var sorted []string
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// shellArtifacts lists lines of shell startup files that mention lockr.
+// lockr never writes to them, so they are left for the user to edit.
+func shellArtifacts() []artifact {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+
+	var found []artifact
+	for _, name := range shellFiles {
+		path := filepath.Join(home, name)
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for line := 1; scanner.Scan(); line++ {
+			if text := strings.TrimSpace(scanner.Text()); lockrWord.MatchString(text) {
+				found = append(found, artifact{what: fmt.Sprintf("%s:%d mentions lockr: %s", path, line, text)})
+			}
+		}
+		f.Close()
+	}
+	return found
+}
+
+// printManual lists what has to be removed by hand
+func printManual(manual []artifact) {
+	if len(manual) == 0 {
+		return
+	}
+	fmt.Println("\nRemove by hand:")
+	for _, a := range manual {
+		fmt.Printf("  ! %s\n", a.what)
+	}
+}
+
+// confirmVaultDeletion makes the user type the vault's file name before the
+// vault is deleted
+func confirmVaultDeletion() error {
+	name := filepath.Base(vaultPath)
+	fmt.Printf("Deleting the vault destroys every secret in it. Type %s to confirm: ", name)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	if strings.TrimSpace(line) != name {
+		return errors.New("the name did not match; nothing was removed")
+	}
+	return nil
+}
+
+func init() {
+	decommissionCmd.Flags().Bool("dry-run", false, "Only list what would be removed")
+	decommissionCmd.Flags().Bool("vault-too", false, "Delete the vault as well, after typing its file name")
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/merge"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <other-vault>",
+	Short: "Compare this vault with another vault file",
+	Long: `List the keys that differ between this vault and another vault file, such
+as a backup, a travel copy or the result of a sync, without showing values.
+
+Keys are reported as added (only in the other vault), removed (only in this
+vault) or changed, with what changed: the value (compared by hash), tags or
+notes. Nothing is written to either vault.
+
+With --exit-code the command exits with status 1 when there are differences,
+for checking backups from scripts.
+
+Examples:
+  lockr diff backup.lockr
+  lockr diff travel.lockr --all                 # Also list identical keys
+  lockr diff backup.lockr --exit-code --quiet   # Only the exit status`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		other := args[0]
+		showAll, _ := cmd.Flags().GetBool("all")
+		exitCode, _ := cmd.Flags().GetBool("exit-code")
+
+		if absOther, err := filepath.Abs(other); err == nil {
+			if absVault, err := filepath.Abs(vaultPath); err == nil && absVault == absOther {
+				handleError(errors.New("cannot compare a vault with itself"), "")
+				return
+			}
+		}
+		if _, err := os.Stat(other); err != nil {
+			handleError(fmt.Errorf("vault not found: %s", other), "")
+			return
+		}
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		theirs, err := readOtherVault(other)
+		if err != nil {
+			handleError(err, fmt.Sprintf("Failed to read %s", other))
+			return
+		}
+		ours, err := vaultDB.ExportSecrets("")
+		if err != nil {
+			handleError(err, "Failed to read vault")
+			return
+		}
+
+		diffs := merge.Diff(ours, theirs)
+		counts := make(map[string]int)
+		if !quiet {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, d := range diffs {
+				if d.Kind == merge.Same && !showAll {
+					continue
+				}
+				if len(d.Fields) > 0 {
+					fmt.Fprintf(w, "  %s\t%s\t(%s)\n", d.Kind, d.Key, strings.Join(d.Fields, ", "))
+					continue
+				}
+				fmt.Fprintf(w, "  %s\t%s\n", d.Kind, d.Key)
+			}
+			w.Flush()
+		}
+		for _, d := range diffs {
+			counts[d.Kind]++
+		}
+
+		printInfo("\n%d added, %d removed, %d changed, %d identical",
+			counts[merge.OnlyTheirs], counts[merge.OnlyOurs], counts[merge.Different], counts[merge.Same])
+		if exitCode && len(diffs) > counts[merge.Same] {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	diffCmd.Flags().Bool("all", false, "Also list identical keys")
+	diffCmd.Flags().Bool("exit-code", false, "Exit with status 1 when the vaults differ")
+}
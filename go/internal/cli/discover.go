@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/discover"
+	"github.com/lockr/go/internal/vaultio"
+)
+
+var discoverCmd = &cobra.Command{
+	Use:   "discover aws|gcp",
+	Short: "List a cloud secrets manager's secrets and scaffold vault keys for them",
+	Long: `List the secrets in AWS Secrets Manager or Google Secret Manager by name and
+show the vault key each maps to, to mirror a cloud account in the vault or
+migrate away from it. Values are never read: only the listing permission is
+needed, and nothing secret leaves the provider.
+
+The provider's own CLI does the listing, with its usual credentials: aws
+with --profile and --region, or gcloud with --profile (a gcloud
+configuration) and --project. Keys go under aws/ or gcp/ unless --prefix
+says otherwise; AWS names with slashes keep them as namespaces.
+
+With --scaffold, the keys that are not in the vault yet are created, empty,
+tagged "discovered" and the provider, with the console link as their URL and
+the secret's identifier, description and tags in the notes. Fill each one in
+with 'lockr update <key>'; until then 'lockr lint' lists it. Keys already in
+the vault are never changed.
+
+Examples:
+  lockr discover aws --profile work --region eu-west-1
+  lockr discover aws --profile work --prefix work/ --scaffold
+  lockr discover gcp --project shop-prod --scaffold`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{discover.AWS, discover.GCP},
+	Run: func(cmd *cobra.Command, args []string) {
+		provider := args[0]
+		profile, _ := cmd.Flags().GetString("profile")
+		region, _ := cmd.Flags().GetString("region")
+		project, _ := cmd.Flags().GetString("project")
+		scaffold, _ := cmd.Flags().GetBool("scaffold")
+		prefix := provider + "/"
+		if cmd.Flags().Changed("prefix") {
+			prefix, _ = cmd.Flags().GetString("prefix")
+		}
+
+		location := region
+		switch {
+		case provider == discover.AWS && project != "":
+			handleError(errors.New("--project is for gcp; AWS takes --region"), "")
+			return
+		case provider == discover.GCP && region != "":
+			handleError(errors.New("--region is for aws; gcp takes --project"), "")
+			return
+		case provider == discover.GCP:
+			location = project
+		}
+		argv, err := discover.Command(provider, profile, location)
+		if err != nil {
+			handleError(err, "")
+			return
+		}
+		if err := requireNetwork("discover"); err != nil {
+			handleError(err, "")
+			return
+		}
+
+		output, err := runProviderCLI(argv)
+		if err != nil {
+			handleError(err, "Failed to list secrets")
+			return
+		}
+		secrets, err := discover.Parse(provider, output)
+		if err != nil {
+			handleError(err, "")
+			return
+		}
+		if len(secrets) == 0 {
+			printInfo("No secrets found")
+			return
+		}
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		var missing []vaultio.Record
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, s := range secrets {
+			key := activeWorkspace.QualifyKey(s.Key(prefix))
+			status := "exists"
+			if _, err := vaultDB.PeekSecret(key); err != nil {
+				status = "new"
+				tags := provider + "," + discover.Tag
+				notes, link := s.Notes(), s.ConsoleURL()
+				missing = append(missing, vaultio.Record{Key: key, Tags: &tags, Notes: &notes, URL: &link})
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", status, key, s.ID)
+		}
+		if !quiet {
+			w.Flush()
+		}
+
+		if !scaffold {
+			printInfo("\n%d secrets, %d not in the vault; --scaffold creates keys for them", len(secrets), len(missing))
+			return
+		}
+		if len(missing) == 0 {
+			printInfo("\nEvery secret already has a key")
+			return
+		}
+		if _, err := vaultDB.ImportSecrets(vaultio.ToSecrets(missing)); err != nil {
+			handleError(err, "Failed to scaffold keys")
+			return
+		}
+		printInfo("\n✓ Scaffolded %d empty keys; fill them in with 'lockr update <key>'", len(missing))
+	},
+}
+
+// runProviderCLI runs a cloud provider's CLI and returns its output, with
+// its error message if it fails
+func runProviderCLI(argv []string) ([]byte, error) {
+	if _, err := exec.LookPath(argv[0]); err != nil {
+		return nil, fmt.Errorf("%s is not installed; discovery uses the provider's CLI and its credentials", argv[0])
+	}
+	var stderr bytes.Buffer
+	c := exec.Command(argv[0], argv[1:]...)
+	c.Stderr = &stderr
+	out, err := c.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", argv[0], msg)
+		}
+		return nil, fmt.Errorf("%s: %w", argv[0], err)
+	}
+	return out, nil
+}
+
+func init() {
+	discoverCmd.Flags().String("profile", "", "AWS profile, or gcloud configuration, to list with")
+	discoverCmd.Flags().String("region", "", "AWS region (default: the profile's)")
+	discoverCmd.Flags().String("project", "", "GCP project (default: the configuration's)")
+	discoverCmd.Flags().String("prefix", "", "Namespace for the vault keys (default aws/ or gcp/)")
+	discoverCmd.Flags().Bool("scaffold", false, "Create empty keys for secrets not in the vault yet")
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/dsn"
+	"github.com/lockr/go/internal/policy"
+)
+
+// asDSN is the --as format of get that assembles a connection string
+const asDSN = "dsn"
+
+// connectionString assembles the connection string for driver from the
+// structured secret at key. It returns the strictest policy of the secrets
+// read.
+func connectionString(key, driver string) (string, policy.Defaults, error) {
+	fields, defaults, err := structuredFields(key)
+	if err != nil {
+		return "", defaults, err
+	}
+	defer clear(fields)
+
+	value, err := dsn.Build(driver, fields)
+	if err != nil {
+		return "", defaults, fmt.Errorf("cannot build a connection string: %w", err)
+	}
+	return value, defaults, nil
+}
+
+// structuredFields reads the fields of the structured secret at key: a JSON
+// object stored under key itself, or the secrets directly under it
+// (key/host, key/port, key/user, key/password, ...). Each secret read is
+// counted and audited like a get.
+func structuredFields(key string) (dsn.Fields, policy.Defaults, error) {
+	value, defaults, err := readSecret(key)
+	if err == nil {
+		fields, err := dsn.ParseJSON(value)
+		if err != nil {
+			return nil, defaults, fmt.Errorf("'%s' is not a structured secret (%w); store its fields as a JSON object or under %s/", key, err, key)
+		}
+		return fields, defaults, nil
+	}
+	if !errors.Is(err, database.ErrKeyNotFound) {
+		return nil, defaults, err
+	}
+
+	children, err := vaultDB.ExportSecrets(key + "/*")
+	if err != nil {
+		return nil, defaults, err
+	}
+	prefix := key + "/"
+	fields := dsn.Fields{}
+	var all []policy.Defaults
+	for _, child := range children {
+		name := child.Key[len(prefix):]
+		if strings.Contains(name, "/") {
+			continue
+		}
+		value, d, err := readSecret(child.Key)
+		if err != nil {
+			return nil, policy.Strictest(all...), fmt.Errorf("%s: %w", child.Key, err)
+		}
+		fields.Set(name, value)
+		all = append(all, d)
+	}
+	if len(fields) == 0 {
+		return nil, defaults, fmt.Errorf("%w: no secret '%s' and none under %s", database.ErrKeyNotFound, key, prefix)
+	}
+	return fields, policy.Strictest(all...), nil
+}
@@ -0,0 +1,251 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/bulkedit"
+	"github.com/lockr/go/internal/database"
+)
+
+var editCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit secrets matching a pattern in $EDITOR",
+	Long: `Open the secrets matching a pattern in your editor as KEY = VALUE lines and
+apply the changes when the editor exits, for fast bulk corrections.
+
+Values are masked. Replace a mask with a new value to change a secret,
+replace it with ?reveal to see the current value (the editor opens again),
+delete a line to delete a secret or add a line to create one. All changes
+are applied in one transaction after confirmation; if any secret was changed
+elsewhere in the meantime, nothing is applied.
+
+The file is written to a private directory in $XDG_RUNTIME_DIR or /dev/shm
+when they are memory-backed, and is overwritten and removed when lockr is
+done, along with any swap or backup files the editor left beside it.
+Clipboard-only secrets are left out.
+
+The editor is taken from $VISUAL, then $EDITOR.
+
+Examples:
+  lockr edit --pattern 'myapp/*'
+  EDITOR=nano lockr edit --pattern 'staging/*'`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		pattern, _ := cmd.Flags().GetString("pattern")
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		secrets, err := vaultDB.ExportSecrets(pattern)
+		if err != nil {
+			handleError(err, "Failed to read secrets")
+			return
+		}
+		var entries []bulkedit.Entry
+		for _, secret := range secrets {
+			if resolvePolicy(secret.Key, secret.Tags).ClipboardOnly {
+				fmt.Fprintf(os.Stderr, "Skipping clipboard-only secret '%s'\n", secret.Key)
+				continue
+			}
+			entries = append(entries, bulkedit.Entry{Key: secret.Key, Value: secret.Value, Revision: secret.Revision})
+		}
+
+		dir, err := secureTempDir()
+		if err != nil {
+			handleError(err, "Failed to create temporary file")
+			return
+		}
+		defer shredDir(dir)
+		// handleError exits without running deferred calls
+		fail := func(err error, message string) {
+			shredDir(dir)
+			handleError(err, message)
+		}
+		path := filepath.Join(dir, "secrets.txt")
+
+		title := pattern
+		if title == "" {
+			title = "all secrets"
+		}
+		items := bulkedit.Masked(entries)
+		var result *bulkedit.Result
+		for {
+			var buf bytes.Buffer
+			bulkedit.Render(&buf, title, items)
+			original := buf.String()
+			if err := writePrivateFile(path, buf.Bytes()); err != nil {
+				fail(err, "Failed to write temporary file")
+				return
+			}
+
+			if err := runEditor(path); err != nil {
+				fail(err, "Editor failed")
+				return
+			}
+
+			edited, err := os.ReadFile(path)
+			if err != nil {
+				fail(err, "Failed to read temporary file")
+				return
+			}
+			if string(edited) == original {
+				fmt.Println("No changes")
+				return
+			}
+
+			lines, err := bulkedit.Parse(bytes.NewReader(edited))
+			if err == nil {
+				result, err = bulkedit.Compare(entries, lines)
+			}
+			if err != nil {
+				fail(err, "Invalid file")
+				return
+			}
+			if len(result.Reveal) == 0 {
+				break
+			}
+			items = result.Items
+		}
+
+		if len(result.Changes) == 0 {
+			fmt.Println("No changes")
+			return
+		}
+
+		var creates, updates, deletes int
+		for _, c := range result.Changes {
+			switch c.Op {
+			case database.ChangeCreate:
+				creates++
+				fmt.Printf("  + %s\n", c.Key)
+			case database.ChangeUpdate:
+				updates++
+				fmt.Printf("  ~ %s\n", c.Key)
+			case database.ChangeDelete:
+				deletes++
+				fmt.Printf("  - %s\n", c.Key)
+			}
+		}
+
+		if !force {
+			fmt.Print(vaultPrompt(fmt.Sprintf("Create %d, update %d and delete %d secrets? (y/N): ", creates, updates, deletes)))
+			var response string
+			fmt.Scanln(&response)
+			if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
+				fmt.Println("Cancelled")
+				return
+			}
+		}
+
+		// The editor may have been open longer than the session lasts;
+		// revisions still guard against changes made in the meantime
+		if err := ensureAuthenticated(); err != nil {
+			fail(err, "Authentication failed; nothing was applied")
+			return
+		}
+		if err := vaultDB.ApplyChanges(result.Changes); err != nil {
+			if errors.Is(err, database.ErrRevisionMismatch) {
+				fail(err, "A secret was changed while you were editing; nothing was applied")
+				return
+			}
+			fail(err, "Failed to apply changes")
+			return
+		}
+		for _, c := range result.Changes {
+			invalidateCachedSecret(c.Key)
+		}
+
+		printInfo("✓ Created %d, updated %d and deleted %d secrets", creates, updates, deletes)
+	},
+}
+
+// secureTempDir creates a directory only the current user can enter,
+// preferring memory-backed locations so edited values never reach a disk
+func secureTempDir() (string, error) {
+	var candidates []string
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		candidates = append(candidates, runtimeDir)
+	}
+	candidates = append(candidates, "/dev/shm")
+
+	for _, base := range candidates {
+		if memoryBacked, known := isMemoryBacked(filepath.Join(base, "x")); !known || !memoryBacked {
+			continue
+		}
+		if dir, err := os.MkdirTemp(base, "lockr-edit-"); err == nil {
+			return dir, privateDir(dir)
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "lockr-edit-")
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(os.Stderr, "Warning: no memory-backed directory available; %s is on disk\n", dir)
+	return dir, privateDir(dir)
+}
+
+// privateDir restricts dir to the current user, removing it if that fails
+func privateDir(dir string) error {
+	if err := os.Chmod(dir, 0700); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	return nil
+}
+
+// shredDir overwrites every file in dir with zeros, then removes dir
+func shredDir(dir string) {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+		f.Write(make([]byte, info.Size()))
+		f.Sync()
+		return nil
+	})
+	if err := os.RemoveAll(dir); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to delete %s: %v\n", dir, err)
+	}
+}
+
+// runEditor opens path in the user's editor and waits for it to exit
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	fields := strings.Fields(editor)
+	c := exec.Command(fields[0], append(fields[1:], path)...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return c.Run()
+}
+
+func init() {
+	editCmd.Flags().String("pattern", "", "Edit only keys matching this glob (e.g. 'myapp/*')")
+}
@@ -0,0 +1,491 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/crypto"
+	"github.com/lockr/go/internal/vaultio"
+)
+
+// Vault settings used by emergency access
+const (
+	emergencyPublicKeySetting  = "emergency.public_key"
+	emergencyContactSetting    = "emergency.contact"
+	emergencyInactivitySetting = "emergency.inactivity"
+	emergencyRefreshSetting    = "emergency.refresh"
+	emergencyOutputSetting     = "emergency.output"
+	emergencyLastBundleSetting = "emergency.last_bundle"
+)
+
+// Files written to the emergency output directory
+const (
+	emergencyBundleFile       = "recovery.bundle"
+	emergencyHeartbeatFile    = "heartbeat"
+	emergencyInstructionsFile = "RELEASE-INSTRUCTIONS.txt"
+)
+
+var emergencyCmd = &cobra.Command{
+	Use:   "emergency",
+	Short: "Set up emergency access for a trusted contact",
+	Long: `Emergency access keeps an encrypted recovery bundle of the vault that only a
+designated contact can open, for estate-planning style access.
+
+The contact generates a key pair with 'lockr emergency keygen' and shares the
+public key. Once set up, lockr refreshes the bundle periodically and records a
+heartbeat whenever the vault is unlocked. Both are written to an output
+directory together with release instructions. An external relay (or a person
+following the instructions) hands the bundle to the contact only after the
+heartbeat is older than the configured inactivity period.
+
+Examples:
+  lockr emergency keygen --out contact.key           # Run by the contact
+  lockr emergency setup --contact-key lockr-pub-... --contact "Sam" \
+      --inactivity 90d --output ~/Dropbox/lockr-emergency
+  lockr emergency status                             # Show configuration
+  lockr emergency bundle                             # Refresh the bundle now
+  lockr emergency open --key contact.key recovery.bundle --out vault.lockrx
+  lockr emergency disable                            # Stop producing bundles`,
+}
+
+var emergencyKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Generate a key pair for an emergency contact",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		out, _ := cmd.Flags().GetString("out")
+		if _, err := os.Stat(out); err == nil && !force {
+			fmt.Printf("Key file already exists at %s\nUse --force to overwrite\n", out)
+			return
+		}
+
+		pub, priv, err := crypto.GenerateRecipientKey()
+		if err != nil {
+			handleError(err, "Failed to generate key")
+			return
+		}
+
+		if err := os.WriteFile(out, []byte(priv.Encode()+"\n"), 0600); err != nil {
+			handleError(err, "Failed to write private key")
+			return
+		}
+
+		fmt.Printf("Private key written to %s (keep it safe and offline)\n", out)
+		fmt.Printf("Public key: %s\n", pub)
+	},
+}
+
+var emergencySetupCmd = &cobra.Command{
+	Use:   "setup",
+	Short: "Enable emergency access for a contact's public key",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		keyText, _ := cmd.Flags().GetString("contact-key")
+		contact, _ := cmd.Flags().GetString("contact")
+		inactivityText, _ := cmd.Flags().GetString("inactivity")
+		refreshText, _ := cmd.Flags().GetString("refresh")
+		output, _ := cmd.Flags().GetString("output")
+
+		if keyText == "" || output == "" {
+			handleError(errors.New("--contact-key and --output are required"), "")
+			return
+		}
+		if _, err := crypto.ParseRecipientPublicKey(keyText); err != nil {
+			handleError(err, "")
+			return
+		}
+		if _, err := parseDays(inactivityText); err != nil {
+			handleError(err, "Invalid --inactivity")
+			return
+		}
+		if _, err := parseDays(refreshText); err != nil {
+			handleError(err, "Invalid --refresh")
+			return
+		}
+
+		absOutput, err := filepath.Abs(output)
+		if err != nil {
+			handleError(err, "Invalid output directory")
+			return
+		}
+
+		if _, err := authenticateWithMasterPassword(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		settings := map[string]string{
+			emergencyPublicKeySetting:  strings.TrimSpace(keyText),
+			emergencyContactSetting:    contact,
+			emergencyInactivitySetting: inactivityText,
+			emergencyRefreshSetting:    refreshText,
+			emergencyOutputSetting:     absOutput,
+		}
+		for name, value := range settings {
+			if err := vaultDB.SetSetting(name, value); err != nil {
+				handleError(err, "Failed to save emergency access settings")
+				return
+			}
+		}
+
+		count, err := writeEmergencyBundle()
+		if err != nil {
+			handleError(err, "Failed to write recovery bundle")
+			return
+		}
+
+		fmt.Printf("Emergency access enabled: %d secrets sealed to %s\n", count, filepath.Join(absOutput, emergencyBundleFile))
+		fmt.Printf("Release instructions: %s\n", filepath.Join(absOutput, emergencyInstructionsFile))
+	},
+}
+
+var emergencyBundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Regenerate the recovery bundle now",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		if enabled, _ := travelEnabled(); enabled {
+			handleError(errors.New("travel mode is on; the bundle would be incomplete"), "")
+			return
+		}
+
+		count, err := writeEmergencyBundle()
+		if err != nil {
+			handleError(err, "Failed to write recovery bundle")
+			return
+		}
+		fmt.Printf("Recovery bundle refreshed with %d secrets\n", count)
+	},
+}
+
+var emergencyStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show emergency access configuration",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		settings, err := emergencySettings()
+		if err != nil {
+			handleError(err, "Failed to read emergency access settings")
+			return
+		}
+		if settings[emergencyPublicKeySetting] == "" {
+			fmt.Println("Emergency access: off")
+			return
+		}
+
+		fmt.Println("Emergency access: on")
+		if contact := settings[emergencyContactSetting]; contact != "" {
+			fmt.Printf("  Contact: %s\n", contact)
+		}
+		fmt.Printf("  Public key: %s\n", settings[emergencyPublicKeySetting])
+		fmt.Printf("  Release after: %s of inactivity\n", settings[emergencyInactivitySetting])
+		fmt.Printf("  Refresh every: %s\n", settings[emergencyRefreshSetting])
+		fmt.Printf("  Output: %s\n", settings[emergencyOutputSetting])
+		if last, err := time.Parse(time.RFC3339, settings[emergencyLastBundleSetting]); err == nil {
+			fmt.Printf("  Last bundle: %s (%s)\n", last.Local().Format("2006-01-02 15:04:05"), formatAge(last))
+		}
+	},
+}
+
+var emergencyDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Disable emergency access and remove the recovery bundle",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := authenticateWithMasterPassword(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		output, _, err := vaultDB.GetSetting(emergencyOutputSetting)
+		if err != nil {
+			handleError(err, "Failed to read emergency access settings")
+			return
+		}
+
+		for _, name := range []string{
+			emergencyPublicKeySetting,
+			emergencyContactSetting,
+			emergencyInactivitySetting,
+			emergencyRefreshSetting,
+			emergencyOutputSetting,
+			emergencyLastBundleSetting,
+		} {
+			if err := vaultDB.DeleteSetting(name); err != nil {
+				handleError(err, "Failed to remove emergency access settings")
+				return
+			}
+		}
+
+		if output != "" {
+			bundle := filepath.Join(output, emergencyBundleFile)
+			if err := os.Remove(bundle); err != nil && !os.IsNotExist(err) {
+				fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", bundle, err)
+			}
+		}
+
+		fmt.Println("Emergency access disabled")
+	},
+}
+
+var emergencyOpenCmd = &cobra.Command{
+	Use:   "open <bundle>",
+	Short: "Decrypt a recovery bundle with the contact's private key",
+	Long: `Decrypt a recovery bundle into a lockrx export, which can be turned into a
+new vault with 'lockr init --from'. Does not require an existing vault.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		keyPath, _ := cmd.Flags().GetString("key")
+		out, _ := cmd.Flags().GetString("out")
+
+		keyText, err := os.ReadFile(keyPath)
+		if err != nil {
+			handleError(err, "Failed to read private key")
+			return
+		}
+		priv, err := crypto.ParseRecipientPrivateKey(string(keyText))
+		if err != nil {
+			handleError(err, "")
+			return
+		}
+
+		f, err := os.Open(args[0])
+		if err != nil {
+			handleError(err, "Failed to open bundle")
+			return
+		}
+		defer f.Close()
+
+		records, err := vaultio.ReadRecoveryBundle(f, priv)
+		if err != nil {
+			handleError(err, "Failed to decrypt bundle")
+			return
+		}
+
+		if out == "" {
+			if err := vaultio.WriteLockrx(os.Stdout, records); err != nil {
+				handleError(err, "Failed to write export")
+			}
+			return
+		}
+
+		file, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			handleError(err, "Failed to create export file")
+			return
+		}
+		defer file.Close()
+
+		if err := vaultio.WriteLockrx(file, records); err != nil {
+			handleError(err, "Failed to write export")
+			return
+		}
+		fmt.Printf("Recovered %d secrets to %s\n", len(records), out)
+		fmt.Printf("Create a vault from it with: lockr init --from %s\n", out)
+	},
+}
+
+// emergencySettings returns every emergency access setting, empty when unset
+func emergencySettings() (map[string]string, error) {
+	settings := make(map[string]string)
+	for _, name := range []string{
+		emergencyPublicKeySetting,
+		emergencyContactSetting,
+		emergencyInactivitySetting,
+		emergencyRefreshSetting,
+		emergencyOutputSetting,
+		emergencyLastBundleSetting,
+	} {
+		value, _, err := vaultDB.GetSetting(name)
+		if err != nil {
+			return nil, err
+		}
+		settings[name] = value
+	}
+	return settings, nil
+}
+
+// writeEmergencyBundle seals the vault to the contact's key and writes the
+// bundle, heartbeat and release instructions to the output directory
+func writeEmergencyBundle() (int, error) {
+	settings, err := emergencySettings()
+	if err != nil {
+		return 0, err
+	}
+	if settings[emergencyPublicKeySetting] == "" {
+		return 0, errors.New("emergency access is not set up; run 'lockr emergency setup'")
+	}
+
+	pub, err := crypto.ParseRecipientPublicKey(settings[emergencyPublicKeySetting])
+	if err != nil {
+		return 0, err
+	}
+
+	output := settings[emergencyOutputSetting]
+	if err := os.MkdirAll(output, 0700); err != nil {
+		return 0, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	secrets, err := vaultDB.ExportSecrets("")
+	if err != nil {
+		return 0, err
+	}
+	hidden, err := vaultDB.HiddenSecrets()
+	if err != nil {
+		return 0, err
+	}
+	secrets = append(secrets, hidden...)
+
+	records := make([]vaultio.Record, len(secrets))
+	for i, s := range secrets {
+		records[i] = vaultio.Record{Key: s.Key, Value: s.Value, CreatedAt: s.CreatedAt, Tags: s.Tags, Notes: s.Notes}
+	}
+
+	// Write to a temporary file first so a failure never leaves a truncated bundle
+	bundlePath := filepath.Join(output, emergencyBundleFile)
+	tmp, err := os.CreateTemp(output, ".recovery-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := vaultio.WriteRecoveryBundle(tmp, pub, records); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), bundlePath); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	if err := os.WriteFile(filepath.Join(output, emergencyInstructionsFile), []byte(emergencyInstructions(settings, now)), 0644); err != nil {
+		return 0, err
+	}
+	if err := writeHeartbeat(output, now); err != nil {
+		return 0, err
+	}
+
+	if err := vaultDB.SetSetting(emergencyLastBundleSetting, now.UTC().Format(time.RFC3339)); err != nil {
+		return 0, err
+	}
+
+	return len(records), nil
+}
+
+// emergencyCheckIn records a heartbeat and refreshes the recovery bundle when
+// it is older than the configured refresh interval. It runs after every
+// successful unlock and never fails the calling command.
+func emergencyCheckIn() {
+	settings, err := emergencySettings()
+	if err != nil || settings[emergencyPublicKeySetting] == "" {
+		return
+	}
+
+	now := time.Now()
+	if err := writeHeartbeat(settings[emergencyOutputSetting], now); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record emergency access heartbeat: %v\n", err)
+	}
+
+	refresh, err := parseDays(settings[emergencyRefreshSetting])
+	if err != nil {
+		return
+	}
+	last, err := time.Parse(time.RFC3339, settings[emergencyLastBundleSetting])
+	if err == nil && now.Sub(last) < refresh {
+		return
+	}
+
+	// Don't seal a partial vault while secrets are hidden for travel
+	if enabled, _ := travelEnabled(); enabled {
+		return
+	}
+
+	if _, err := writeEmergencyBundle(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to refresh emergency recovery bundle: %v\n", err)
+		return
+	}
+	printVerbose("Refreshed emergency recovery bundle")
+}
+
+// writeHeartbeat records the time of the last vault activity
+func writeHeartbeat(output string, now time.Time) error {
+	return os.WriteFile(filepath.Join(output, emergencyHeartbeatFile), []byte(now.UTC().Format(time.RFC3339)+"\n"), 0644)
+}
+
+// emergencyInstructions renders the release instructions for the output directory
+func emergencyInstructions(settings map[string]string, now time.Time) string {
+	contact := settings[emergencyContactSetting]
+	if contact == "" {
+		contact = "the designated contact"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "LOCKR EMERGENCY ACCESS - RELEASE INSTRUCTIONS\n\n")
+	fmt.Fprintf(&b, "This directory contains an encrypted recovery bundle (%s) that only\n", emergencyBundleFile)
+	fmt.Fprintf(&b, "%s can open. It was last refreshed %s.\n\n", contact, now.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "The file %q holds the time the vault owner last unlocked the vault.\n", emergencyHeartbeatFile)
+	fmt.Fprintf(&b, "Release %s to %s ONLY if that time is more than\n", emergencyBundleFile, contact)
+	fmt.Fprintf(&b, "%s in the past.\n\n", settings[emergencyInactivitySetting])
+	fmt.Fprintf(&b, "To recover the secrets, %s runs:\n\n", contact)
+	fmt.Fprintf(&b, "  lockr emergency open --key contact.key %s --out vault.lockrx\n", emergencyBundleFile)
+	fmt.Fprintf(&b, "  lockr init --from vault.lockrx\n\n")
+	fmt.Fprintf(&b, "Recipient key: %s\n", settings[emergencyPublicKeySetting])
+	return b.String()
+}
+
+// parseDays parses a duration that may use a day suffix ("90d") in addition
+// to the units understood by time.ParseDuration
+func parseDays(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+func init() {
+	emergencyKeygenCmd.Flags().String("out", "contact.key", "Where to write the private key")
+	emergencySetupCmd.Flags().String("contact-key", "", "The contact's public key (lockr-pub-...)")
+	emergencySetupCmd.Flags().String("contact", "", "Name of the contact, used in the instructions")
+	emergencySetupCmd.Flags().String("inactivity", "90d", "Inactivity period before the bundle may be released")
+	emergencySetupCmd.Flags().String("refresh", "7d", "How often to refresh the bundle")
+	emergencySetupCmd.Flags().String("output", "", "Directory for the bundle, heartbeat and instructions")
+	emergencyOpenCmd.Flags().String("key", "contact.key", "Path to the contact's private key")
+	emergencyOpenCmd.Flags().String("out", "", "Write the recovered export to this file instead of stdout")
+
+	emergencyCmd.AddCommand(emergencyKeygenCmd)
+	emergencyCmd.AddCommand(emergencySetupCmd)
+	emergencyCmd.AddCommand(emergencyBundleCmd)
+	emergencyCmd.AddCommand(emergencyStatusCmd)
+	emergencyCmd.AddCommand(emergencyDisableCmd)
+	emergencyCmd.AddCommand(emergencyOpenCmd)
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/entrypoint"
+	"github.com/lockr/go/internal/policy"
+	"github.com/lockr/go/internal/refs"
+)
+
+// passwordFileEnv names the file holding the vault password, typically a
+// Docker or Kubernetes secret mount
+const passwordFileEnv = "LOCKR_PASSWORD_FILE"
+
+var entrypointCmd = &cobra.Command{
+	Use:   "entrypoint --map NAME=key... -- command [args...]",
+	Short: "Run a container process with secrets injected into its environment",
+	Long: `Open the vault read-only, export the mapped secrets as environment variables
+and run the given command in place of lockr's own process.
+
+Designed to be a container's ENTRYPOINT: the vault may live on a read-only
+mount, the password is read from the file named by --password-file or
+$LOCKR_PASSWORD_FILE (for example a Docker secret), and no session, audit
+entry or access statistics are written. Signals are forwarded to the command
+and, when running as PID 1, orphaned processes are reaped. lockr exits with
+the command's exit code.
+
+The password file variable is removed from the command's environment.
+Clipboard-only secrets, and secrets referencing one, are refused.
+
+Examples:
+  lockr entrypoint --map APP_DB_PASSWORD=db/prod -- ./server
+  lockr entrypoint -v /vault/vault.lockr --password-file /run/secrets/lockr \
+    --map API_TOKEN=api/token --map DB_PASSWORD=db/prod -- ./server --port 8080
+
+  # Dockerfile
+  ENV LOCKR_PASSWORD_FILE=/run/secrets/lockr_password
+  ENTRYPOINT ["lockr", "entrypoint", "-v", "/vault/vault.lockr", "--map", "APP_DB_PASSWORD=db/prod", "--"]
+  CMD ["./server"]`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		specs, _ := cmd.Flags().GetStringArray("map")
+		passwordFile, _ := cmd.Flags().GetString("password-file")
+
+		mappings := make([]entrypoint.Mapping, 0, len(specs))
+		for _, spec := range specs {
+			m, err := entrypoint.ParseMapping(spec)
+			if err != nil {
+				handleError(err, "Invalid --map")
+			}
+			mappings = append(mappings, m)
+		}
+
+		values, err := readMappedSecrets(passwordFile, mappings)
+		if err != nil {
+			handleError(err, "Failed to read secrets")
+		}
+
+		env := entrypoint.MergeEnv(os.Environ(), values, passwordFileEnv)
+		code, err := entrypoint.Run(args, env)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to run %s: %v\n", args[0], err)
+		}
+		os.Exit(code)
+	},
+}
+
+// readMappedSecrets opens the vault read-only with the password from
+// passwordFile and returns the mapped values keyed by variable name
+func readMappedSecrets(passwordFile string, mappings []entrypoint.Mapping) (map[string]string, error) {
+	if passwordFile == "" {
+		passwordFile = os.Getenv(passwordFileEnv)
+	}
+	if passwordFile == "" {
+		return nil, fmt.Errorf("no password file: set --password-file or $%s", passwordFileEnv)
+	}
+
+	data, err := os.ReadFile(passwordFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password file: %w", err)
+	}
+	password := strings.TrimRight(string(data), "\r\n")
+
+	if _, err := os.Stat(vaultPath); err != nil {
+		return nil, fmt.Errorf("vault not found: %s", vaultPath)
+	}
+
+	vd := database.NewReadOnlyVaultDatabase(vaultPath)
+	if err := vd.Connect(password); err != nil {
+		return nil, err
+	}
+	defer vd.Close()
+
+	if err := vd.IntegrityError(); err != nil {
+		return nil, err
+	}
+
+	encoded, _, err := vd.GetSetting(policyRulesSetting)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := policy.Decode(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	// Clipboard-only secrets never reach the program, directly or through
+	// a reference
+	lookup := func(key string) (string, error) {
+		secret, err := vd.PeekSecret(key)
+		if err != nil {
+			return "", err
+		}
+		if policy.Resolve(rules, secret.Key, policy.ParseTags(secret.Tags)).ClipboardOnly {
+			return "", fmt.Errorf("'%s': %w", secret.Key, policy.ErrClipboardOnly)
+		}
+		return secret.Value, nil
+	}
+
+	values := make(map[string]string, len(mappings))
+	for _, m := range mappings {
+		secret, err := vd.PeekSecret(m.Key)
+		if err != nil {
+			if err == database.ErrKeyNotFound {
+				fmt.Fprintf(os.Stderr, "Secret '%s' (for %s) not found\n", m.Key, m.Name)
+			}
+			return nil, err
+		}
+		if policy.Resolve(rules, secret.Key, policy.ParseTags(secret.Tags)).ClipboardOnly {
+			return nil, fmt.Errorf("'%s' (for %s): %w", m.Key, m.Name, policy.ErrClipboardOnly)
+		}
+		value, err := refs.Resolve(secret.Key, secret.Value, lookup)
+		if err != nil {
+			return nil, err
+		}
+		values[m.Name] = value
+		printVerbose("Mapped %s from '%s'", m.Name, m.Key)
+	}
+	return values, nil
+}
+
+func init() {
+	entrypointCmd.Flags().SetInterspersed(false)
+	entrypointCmd.Flags().StringArray("map", nil, "Export secret as an environment variable, as NAME=key (repeatable)")
+	entrypointCmd.Flags().String("password-file", "", "File containing the vault password (default $"+passwordFileEnv+")")
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// envPrefix starts the environment variables that stand in for flags
+const envPrefix = "LOCKR_"
+
+// keyringDisabledEnv turns off keyring integration when set to a true value
+const keyringDisabledEnv = "LOCKR_KEYRING_DISABLED"
+
+// envAliases maps variable names from earlier documentation onto flags
+var envAliases = map[string]string{
+	"LOCKR_VAULT_PATH": "vault",
+}
+
+// ownEnv are variables lockr reads itself; they never stand in for a flag
+// of the same name, such as discover's --profile
+var ownEnv = map[string]bool{
+	profileEnv: true,
+}
+
+// applyEnvOverrides sets every flag that was not given on the command line
+// from the environment. Each flag can be set by LOCKR_<FLAG>, or for one
+// command only by LOCKR_<COMMAND>_<FLAG>, which wins; dashes become
+// underscores. Precedence is flag > environment > config > default.
+func applyEnvOverrides(cmd *cobra.Command) error {
+	local := cmd.LocalNonPersistentFlags()
+	var err error
+
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || f.Name == "help" {
+			return
+		}
+
+		names := []string{envPrefix + envName(f.Name)}
+		if local.Lookup(f.Name) != nil {
+			names = append([]string{envPrefix + envName(commandPath(cmd)) + "_" + envName(f.Name)}, names...)
+		}
+		for alias, flag := range envAliases {
+			if flag == f.Name {
+				names = append(names, alias)
+			}
+		}
+
+		for _, name := range names {
+			value, ok := os.LookupEnv(name)
+			if !ok || value == "" || ownEnv[name] {
+				continue
+			}
+			if setErr := cmd.Flags().Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("invalid value %q in $%s: %w", value, name, setErr)
+			}
+			return
+		}
+	})
+	return err
+}
+
+// commandPath is cmd's path below the root command, e.g. "policy add"
+func commandPath(cmd *cobra.Command) string {
+	return strings.TrimPrefix(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()), " ")
+}
+
+// envName turns a flag name or command path into the form used in
+// variable names
+func envName(s string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", " ", "_").Replace(s))
+}
+
+// envEnabled reports whether the variable name is set to a true value
+func envEnabled(name string) bool {
+	enabled, err := strconv.ParseBool(os.Getenv(name))
+	return err == nil && enabled
+}
@@ -0,0 +1,329 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/refs"
+	"github.com/lockr/go/internal/vaultio"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export secrets as an env file, document or encrypted backup",
+	Long: `Write secrets matching a pattern as environment variables, for tools that
+only read env files, as a document with their metadata, or as an encrypted
+archive for offsite backups. References are expanded in env files and kept
+as written elsewhere. Clipboard-only secrets are never exported.
+
+Variable names are derived from keys after removing --strip-prefix: runs of
+characters other than letters and digits become "_" and letters are upper
+cased, so myapp/db-host becomes DB_HOST with --strip-prefix myapp/.
+
+Formats:
+  dotenv   .env syntax, quoting values where needed
+  compose  docker-compose env_file syntax, which has no quoting (values
+           are taken literally and may not span lines)
+  lockrx   lockr's JSON interchange format, keeping keys, tags, notes, URLs,
+           expiry and creation times; 'lockr init --from' reads it back
+  json     the same records as a plain JSON array
+  csv      the same records as CSV with a header row
+  lockr    a lockrx document encrypted with AES-256-GCM under a key derived
+           from a password you choose (PBKDF2-SHA256); restore it with
+           'lockr import --archive'
+
+Output is canonical: entries are sorted by key, timestamps are in UTC to the
+second and JSON fields are in a fixed order, so the same secrets always
+export to the same bytes. --hash prints the SHA-256 digest of the output
+instead of the output itself, to check that two machines hold identical
+secrets without comparing values. Encrypted archives use a fresh salt and
+nonce every time, so they are never canonical and cannot be hashed.
+
+The archive password is prompted for twice, or read from the first line of
+--password-file for unattended backups. json, csv and lockrx are plaintext.
+
+The output goes to stdout unless --output is given; files are created with
+mode 0600. With --delete-after lockr waits, then removes the file; point
+--output at a memory-backed directory such as /dev/shm or /run/user/$UID
+so the values never reach a disk. Encrypted archives are meant to be
+stored, so they can go anywhere.
+
+Examples:
+  lockr export --pattern 'myapp/*' --strip-prefix myapp/ > .env
+  lockr export --format compose --pattern 'myapp/*' --strip-prefix myapp/ \
+    --output /dev/shm/myapp.env --delete-after 60s &
+  docker compose --env-file /dev/shm/myapp.env up -d
+  lockr export --format lockrx --hash           # Compare with another machine
+  lockr export --format lockr -o vault-backup.lockr
+  lockr export --format csv --pattern 'work/*' > work.csv`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		pattern, _ := cmd.Flags().GetString("pattern")
+		stripPrefix, _ := cmd.Flags().GetString("strip-prefix")
+		output, _ := cmd.Flags().GetString("output")
+		deleteAfter, _ := cmd.Flags().GetDuration("delete-after")
+		hash, _ := cmd.Flags().GetBool("hash")
+
+		switch format {
+		case "dotenv", "compose", vaultio.FormatLockrx, vaultio.FormatJSON, vaultio.FormatCSV, vaultio.FormatArchive:
+		default:
+			handleError(fmt.Errorf("unknown format %q (use dotenv, compose, lockrx, json, csv or lockr)", format), "")
+			return
+		}
+		if deleteAfter > 0 && output == "" {
+			handleError(fmt.Errorf("--delete-after needs --output"), "")
+			return
+		}
+		if hash && output != "" {
+			handleError(fmt.Errorf("--hash cannot be combined with --output"), "")
+			return
+		}
+		if format == vaultio.FormatArchive {
+			if hash {
+				handleError(fmt.Errorf("--hash is not available for encrypted archives, which differ on every export"), "")
+				return
+			}
+			if output == "" && term.IsTerminal(int(os.Stdout.Fd())) {
+				handleError(fmt.Errorf("the archive is binary; pass --output or redirect stdout"), "")
+				return
+			}
+		}
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		var password string
+		if format == vaultio.FormatArchive {
+			var err error
+			if password, err = archivePassword(cmd, true); err != nil {
+				handleError(err, "")
+				return
+			}
+		}
+
+		var buf bytes.Buffer
+		count, err := exportAs(&buf, format, pattern, stripPrefix, password)
+		if err != nil {
+			handleError(err, "Failed to export secrets")
+			return
+		}
+
+		if hash {
+			fmt.Printf("sha256:%x\n", sha256.Sum256(buf.Bytes()))
+			return
+		}
+
+		if output == "" {
+			os.Stdout.Write(buf.Bytes())
+			return
+		}
+
+		if memoryBacked, known := isMemoryBacked(output); known && !memoryBacked && format != vaultio.FormatArchive {
+			fmt.Fprintf(os.Stderr, "Warning: %s is not on a memory-backed filesystem; values will reach the disk\n", output)
+		}
+		if err := writePrivateFile(output, buf.Bytes()); err != nil {
+			handleError(err, fmt.Sprintf("Failed to write %s", output))
+			return
+		}
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "Exported %d secrets to %s\n", count, output)
+		}
+
+		if deleteAfter > 0 {
+			waitAndRemove(output, deleteAfter)
+		}
+	},
+}
+
+// exportAs writes the secrets matching pattern to buf in format and
+// returns how many were written. password encrypts the lockr format.
+func exportAs(buf *bytes.Buffer, format, pattern, stripPrefix, password string) (int, error) {
+	switch format {
+	case vaultio.FormatLockrx, vaultio.FormatJSON, vaultio.FormatCSV, vaultio.FormatArchive:
+		records, err := exportRecords(pattern, stripPrefix)
+		if err != nil {
+			return 0, err
+		}
+		switch format {
+		case vaultio.FormatJSON:
+			err = vaultio.WriteJSON(buf, records)
+		case vaultio.FormatCSV:
+			err = vaultio.WriteCSV(buf, records)
+		case vaultio.FormatArchive:
+			err = vaultio.WriteArchive(buf, password, records)
+		default:
+			err = vaultio.WriteCanonicalLockrx(buf, records)
+		}
+		return len(records), err
+	}
+
+	vars, err := exportVars(pattern, stripPrefix)
+	if err != nil {
+		return 0, err
+	}
+	if format == "compose" {
+		return len(vars), vaultio.WriteComposeEnv(buf, vars)
+	}
+	return len(vars), vaultio.WriteDotenv(buf, vars)
+}
+
+// exportableSecrets reads the secrets matching pattern, sorted by key,
+// leaving out clipboard-only ones
+func exportableSecrets(pattern string) ([]database.Secret, error) {
+	secrets, err := vaultDB.ExportSecrets(pattern)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].Key < secrets[j].Key })
+
+	exportable := secrets[:0]
+	for _, secret := range secrets {
+		if resolvePolicy(secret.Key, secret.Tags).ClipboardOnly {
+			fmt.Fprintf(os.Stderr, "Skipping clipboard-only secret '%s'\n", secret.Key)
+			continue
+		}
+		exportable = append(exportable, secret)
+	}
+	return exportable, nil
+}
+
+// stripKeyPrefix removes prefix from key, ignoring case
+func stripKeyPrefix(key, prefix string) string {
+	if prefix != "" && len(key) >= len(prefix) && strings.EqualFold(key[:len(prefix)], prefix) {
+		return key[len(prefix):]
+	}
+	return key
+}
+
+// exportRecords reads the secrets matching pattern as lockrx records
+func exportRecords(pattern, stripPrefix string) ([]vaultio.Record, error) {
+	secrets, err := exportableSecrets(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]vaultio.Record, len(secrets))
+	for i, secret := range secrets {
+		records[i] = vaultio.Record{
+			Key:       stripKeyPrefix(secret.Key, stripPrefix),
+			Value:     secret.Value,
+			CreatedAt: secret.CreatedAt,
+			UpdatedAt: secret.UpdatedAt,
+			Tags:      secret.Tags,
+			Notes:     secret.Notes,
+			URL:       secret.URL,
+			ExpiresAt: secret.ExpiresAt,
+		}
+	}
+	return records, nil
+}
+
+// exportVars reads the secrets matching pattern and names them as
+// environment variables
+func exportVars(pattern, stripPrefix string) ([]vaultio.EnvVar, error) {
+	secrets, err := exportableSecrets(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	values := make(map[string]string, len(secrets))
+	for _, secret := range secrets {
+		value, err := refs.Resolve(secret.Key, secret.Value, checkedLookup(nil, ""))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve references in '%s': %w", secret.Key, err)
+		}
+
+		name := stripKeyPrefix(secret.Key, stripPrefix)
+		keys = append(keys, name)
+		values[name] = value
+	}
+
+	vars := make([]vaultio.EnvVar, 0, len(keys))
+	for _, e := range shellNames(keys, "", '_', true) {
+		vars = append(vars, vaultio.EnvVar{Name: e.name, Value: values[e.key]})
+	}
+	return vars, nil
+}
+
+// archivePassword reads the password of an encrypted archive from
+// --password-file, or prompts for it (twice when creating an archive)
+func archivePassword(cmd *cobra.Command, create bool) (string, error) {
+	if path, _ := cmd.Flags().GetString("password-file"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password file: %w", err)
+		}
+		password, _, _ := strings.Cut(string(data), "\n")
+		password = strings.TrimSuffix(password, "\r")
+		if password == "" {
+			return "", fmt.Errorf("password file %s is empty", path)
+		}
+		return password, nil
+	}
+
+	if create {
+		return promptNewPassword("Archive password: ")
+	}
+	return promptPassword("Archive password: ")
+}
+
+// writePrivateFile writes data to path readable only by the current user,
+// replacing any existing file
+func writePrivateFile(path string, data []byte) error {
+	os.Remove(path)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// waitAndRemove deletes path after d, or earlier if interrupted
+func waitAndRemove(path string, d time.Duration) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	select {
+	case <-time.After(d):
+	case <-sig:
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "Warning: failed to delete %s: %v\n", path, err)
+		return
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Deleted %s\n", path)
+	}
+}
+
+func init() {
+	exportCmd.Flags().String("format", "dotenv", "Output format: dotenv, compose, lockrx, json, csv, lockr (encrypted)")
+	exportCmd.Flags().String("pattern", "", "Export only keys matching this glob (e.g. 'myapp/*')")
+	exportCmd.Flags().String("strip-prefix", "", "Remove this prefix from keys before naming variables")
+	exportCmd.Flags().StringP("output", "o", "", "Write to this file (mode 0600) instead of stdout")
+	exportCmd.Flags().Duration("delete-after", 0, "Delete the --output file after this long (e.g. 60s)")
+	exportCmd.Flags().Bool("hash", false, "Print the SHA-256 digest of the output instead of the output")
+	exportCmd.Flags().String("password-file", "", "Read the archive password from the first line of this file")
+}
@@ -0,0 +1,22 @@
+package cli
+
+import (
+	"path/filepath"
+	"syscall"
+)
+
+// Filesystem types reported by statfs for memory-backed mounts
+const (
+	tmpfsMagic = 0x01021994
+	ramfsMagic = 0x858458f6
+)
+
+// isMemoryBacked reports whether path's directory is on tmpfs or ramfs
+func isMemoryBacked(path string) (memory bool, known bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(filepath.Dir(path), &st); err != nil {
+		return false, false
+	}
+	fsType := uint32(st.Type)
+	return fsType == tmpfsMagic || fsType == ramfsMagic, true
+}
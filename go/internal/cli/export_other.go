@@ -0,0 +1,9 @@
+//go:build !linux
+
+package cli
+
+// isMemoryBacked cannot tell memory-backed filesystems apart on this
+// platform
+func isMemoryBacked(path string) (memory bool, known bool) {
+	return false, false
+}
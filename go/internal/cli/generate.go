@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/lockr/go/internal/diceware"
+	"github.com/lockr/go/internal/generator"
+	"github.com/lockr/go/internal/strength"
+)
+
+// maxGenerateCount bounds how many candidates one generate prints
+const maxGenerateCount = 100
+
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a secret without storing it",
+	Long: `Generate a random secret, passphrase or patterned value without touching the
+vault, e.g. for a sign-up form or another tool's config.
+
+A single value is copied to the clipboard without being displayed, unless
+--show is given or no clipboard is available. With --count, the candidates are
+printed numbered and you choose which one to copy.
+
+Pattern placeholders:
+  ` + strings.ReplaceAll(generator.PatternHelp, "\n", "\n  ") + `
+
+Examples:
+  lockr generate                        # 24 characters, copied to the clipboard
+  lockr generate -l 40 --no-symbols
+  lockr generate --digits-only -l 12
+  lockr generate --words 5              # Diceware passphrase
+  lockr generate --pattern 'AAA-9999-aaa'
+  lockr generate --count 5              # Pick one of five candidates
+  lockr generate --show -q              # Print the value alone, for scripts`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		count, _ := cmd.Flags().GetInt("count")
+		if count < 1 || count > maxGenerateCount {
+			handleError(fmt.Errorf("--count must be between 1 and %d", maxGenerateCount), "")
+			return
+		}
+
+		generate, entropyBits, err := generatorFromFlags(cmd)
+		if err != nil {
+			handleError(err, "")
+			return
+		}
+		candidates := make([]string, count)
+		for i := range candidates {
+			if candidates[i], err = generate(); err != nil {
+				handleError(err, "Failed to generate secret")
+				return
+			}
+		}
+
+		show, _ := cmd.Flags().GetBool("show")
+		canCopy := clipboardMgr != nil && !noClipboard
+		if count == 1 && canCopy && !show {
+			if err := copySecret(candidates[0]); err != nil {
+				handleError(err, "Failed to copy generated secret to clipboard")
+				return
+			}
+			printInfo("✓ Generated secret copied to clipboard (%.0f bits)", entropyBits)
+			return
+		}
+
+		// Bare values when there is nobody to choose one
+		interactive := canCopy && count > 1 && term.IsTerminal(int(os.Stdin.Fd()))
+		if quiet || !interactive {
+			for _, c := range candidates {
+				fmt.Println(c)
+			}
+			return
+		}
+
+		for i, c := range candidates {
+			fmt.Printf("%3d) %s\n", i+1, c)
+		}
+		printInfo("Each has %.0f bits of strength", entropyBits)
+
+		fmt.Printf("Copy which? (1-%d, Enter for none): ", count)
+		var response string
+		fmt.Scanln(&response)
+		if response == "" {
+			return
+		}
+		choice, err := strconv.Atoi(response)
+		if err != nil || choice < 1 || choice > count {
+			handleError(fmt.Errorf("no candidate %q", response), "")
+			return
+		}
+		if err := copySecret(candidates[choice-1]); err != nil {
+			handleError(err, "Failed to copy generated secret to clipboard")
+			return
+		}
+		printInfo("✓ Candidate %d copied to clipboard", choice)
+	},
+}
+
+// generatorFromFlags returns a function producing values as generate's
+// flags describe, and the strength in bits of each value
+func generatorFromFlags(cmd *cobra.Command) (func() (string, error), float64, error) {
+	flags := cmd.Flags()
+	exclusive := 0
+	for _, name := range []string{"words", "pattern", "digits-only"} {
+		if flags.Changed(name) {
+			exclusive++
+		}
+	}
+	if exclusive > 1 {
+		return nil, 0, errors.New("use only one of --words, --pattern and --digits-only")
+	}
+	if (flags.Changed("words") || flags.Changed("pattern")) && (flags.Changed("length") || flags.Changed("no-symbols")) {
+		return nil, 0, errors.New("--length and --no-symbols do not apply to --words or --pattern")
+	}
+
+	switch {
+	case flags.Changed("words"):
+		words, _ := flags.GetInt("words")
+		separator, _ := flags.GetString("separator")
+		if words < diceware.MinWords || words > diceware.MaxWords {
+			return nil, 0, fmt.Errorf("passphrases must have between %d and %d words", diceware.MinWords, diceware.MaxWords)
+		}
+		return func() (string, error) {
+			return diceware.Generate(words, separator)
+		}, strength.ForGenerated(words, diceware.WordCount), nil
+
+	case flags.Changed("pattern"):
+		text, _ := flags.GetString("pattern")
+		pattern, err := generator.ParsePattern(text)
+		if err != nil {
+			return nil, 0, err
+		}
+		return pattern.Generate, pattern.Entropy(), nil
+	}
+
+	length, _ := flags.GetInt("length")
+	if length < generator.MinLength || length > generator.MaxLength {
+		return nil, 0, fmt.Errorf("length must be between %d and %d", generator.MinLength, generator.MaxLength)
+	}
+	charset := secretCharset
+	if noSymbols, _ := flags.GetBool("no-symbols"); noSymbols {
+		charset = alphanumericCharset
+	}
+	if digitsOnly, _ := flags.GetBool("digits-only"); digitsOnly {
+		charset = generator.Digits
+	}
+	return func() (string, error) {
+		return generateSecret(length, charset)
+	}, strength.ForGenerated(length, len(charset)), nil
+}
+
+func init() {
+	generateCmd.Flags().IntP("length", "l", defaultSecretLength, "Length of generated secret")
+	generateCmd.Flags().Bool("no-symbols", false, "Generate letters and digits only")
+	generateCmd.Flags().Bool("digits-only", false, "Generate digits only")
+	generateCmd.Flags().Int("words", diceware.DefaultWords, "Generate a diceware passphrase with this many words")
+	generateCmd.Flags().String("separator", "-", "Separator between passphrase words")
+	generateCmd.Flags().String("pattern", "", "Generate a value of this shape, e.g. 'AAA-9999' (see help)")
+	generateCmd.Flags().IntP("count", "n", 1, "Print this many candidates and choose one to copy")
+	generateCmd.Flags().Bool("show", false, "Print the value instead of copying it to the clipboard")
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/lockr/go/internal/diceware"
+	"github.com/lockr/go/internal/generator"
+	"github.com/lockr/go/internal/keymap"
+	"github.com/lockr/go/internal/policy"
+)
+
+// interactiveGenerate opens the generator panel with set's flags and the
+// key's policy defaults as starting options, returning the chosen value and
+// its strength in bits
+func interactiveGenerate(cmd *cobra.Command, defaults policy.Defaults) (string, float64, error) {
+	if !generator.InteractiveAvailable || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", 0, errors.New("the interactive generator needs a terminal; use -g or --words")
+	}
+
+	length, _ := cmd.Flags().GetInt("length")
+	if !cmd.Flags().Changed("length") && defaults.Length > 0 {
+		length = defaults.Length
+	}
+	noSymbols, _ := cmd.Flags().GetBool("no-symbols")
+	if !cmd.Flags().Changed("no-symbols") && defaults.Symbols != nil {
+		noSymbols = !*defaults.Symbols
+	}
+	words, _ := cmd.Flags().GetInt("words")
+	if !cmd.Flags().Changed("words") && defaults.Words > 0 {
+		words = defaults.Words
+	}
+	separator, _ := cmd.Flags().GetString("separator")
+
+	opts := generator.Options{
+		Length:     length,
+		Lower:      true,
+		Upper:      true,
+		Digits:     true,
+		Symbols:    !noSymbols,
+		Passphrase: cmd.Flags().Changed("words") || (!cmd.Flags().Changed("length") && defaults.Words > 0),
+		Words:      words,
+		Separator:  separator,
+	}
+
+	value, chosen, err := generator.Run(opts, keymaps[keymap.ScreenGenerator], generateWithOptions)
+	if err != nil {
+		return "", 0, err
+	}
+	return value, chosen.Entropy(), nil
+}
+
+// generateWithOptions generates a secret or passphrase as the panel's
+// options describe
+func generateWithOptions(o generator.Options) (string, error) {
+	if o.Passphrase {
+		return diceware.Generate(o.Words, o.Separator)
+	}
+	return generateSecret(o.Length, o.Charset())
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/agent"
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/policy"
+	"github.com/lockr/go/internal/refs"
+)
+
+// grantTokenEnv passes a grant token to 'lockr redeem' without putting it on
+// the command line
+const grantTokenEnv = "LOCKR_GRANT"
+
+// defaultGrantTTL is how long a grant waits to be redeemed
+const defaultGrantTTL = 5 * time.Minute
+
+var grantCmd = &cobra.Command{
+	Use:   "grant <key>",
+	Short: "Hand one secret to another process through the agent",
+	Long: `Give a secret to a script you would rather not trust with the whole vault.
+
+grant hands the secret's value to the running agent and prints a token.
+Whoever presents the token to the agent before --ttl passes gets the value,
+without unlocking the vault; by default only the first of them does. The
+agent keeps grants in memory only and drops them when locked or stopped.
+
+The script redeems the token with 'lockr redeem', which reads it from
+LOCKR_GRANT when not given as an argument, or by sending
+{"op":"redeem","token":"..."} as one line to the agent socket.
+
+Examples:
+  LOCKR_GRANT=$(lockr grant db/prod) ./migrate.sh    # migrate.sh runs 'lockr redeem'
+  lockr grant --ttl 30s deploy/token
+  lockr grant --once=false --ttl 1h ci/token         # Any number of times for an hour`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ttl, _ := cmd.Flags().GetDuration("ttl")
+		once, _ := cmd.Flags().GetBool("once")
+		if ttl <= 0 {
+			handleError(errors.New("--ttl must be positive"), "")
+			return
+		}
+
+		client := agentClient()
+		if !client.Running() {
+			handleError(fmt.Errorf("%w; start it with 'lockr agent start'", agent.ErrNotRunning), "")
+			return
+		}
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		key := activeWorkspace.QualifyKey(args[0])
+		secret, err := vaultDB.GetSecret(key)
+		if err != nil {
+			handleError(err, fmt.Sprintf("Failed to get secret '%s'", key))
+			return
+		}
+		if resolvePolicy(key, secret.Tags).ClipboardOnly {
+			handleError(fmt.Errorf("%w; it cannot be granted", policy.ErrClipboardOnly), "")
+			return
+		}
+		// The grant leaves lockr, so clipboard-only secrets it references
+		// are refused as the secret itself would be
+		value, err := refs.Resolve(secret.Key, secret.Value, checkedLookup(nil, ""))
+		if err != nil {
+			handleError(err, fmt.Sprintf("Failed to resolve references in '%s'", key))
+			return
+		}
+
+		token, err := client.Grant([]byte(value), ttl, once)
+		if err != nil {
+			handleError(err, "Failed to register grant")
+			return
+		}
+		if err := sessionMgr.Audit(database.AuditEventGrant, key); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record audit event: %v\n", err)
+		}
+
+		// Only the token goes to stdout, so it can be captured
+		uses := "once"
+		if !once {
+			uses = "any number of times"
+		}
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "✓ '%s' can be redeemed %s in the next %s\n", key, uses, ttl)
+		}
+		fmt.Println(token)
+	},
+}
+
+var redeemCmd = &cobra.Command{
+	Use:   "redeem [token]",
+	Short: "Print the secret behind a grant token",
+	Long: `Print the value granted with 'lockr grant'. The token is read from
+LOCKR_GRANT when not given, which keeps it out of the process list. No vault
+password is needed; the running agent hands out the value.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		token := os.Getenv(grantTokenEnv)
+		if len(args) == 1 {
+			token = args[0]
+		}
+		if token == "" {
+			handleError(fmt.Errorf("no grant token given and %s is not set", grantTokenEnv), "")
+			return
+		}
+
+		value, err := agentClient().Redeem(token)
+		if err != nil {
+			handleError(err, "Failed to redeem grant")
+			return
+		}
+		fmt.Println(string(value))
+	},
+}
+
+func init() {
+	grantCmd.Flags().Duration("ttl", defaultGrantTTL, "How long the grant can be redeemed")
+	grantCmd.Flags().Bool("once", true, "Allow a single redemption; --once=false allows any number until --ttl passes")
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// hintAfterFailures is how many wrong passwords are entered before the
+// vault's password hint is shown
+const hintAfterFailures = 2
+
+// maxHintLength keeps hints to a reminder rather than a password
+const maxHintLength = 200
+
+// hintCmd manages the vault's password hint
+var hintCmd = &cobra.Command{
+	Use:   "hint",
+	Short: "Set or clear the vault password hint",
+	Long: `Keep a reminder of the vault password that is shown after the second wrong
+password in a row.
+
+The hint is stored UNENCRYPTED in a file next to the vault (vault.db.hint),
+because it has to be readable before the vault is unlocked. Anyone who can
+read the vault file can read the hint, so write something only you will
+understand, never the password or part of it.
+
+Examples:
+  lockr hint set                # Prompt for the hint
+  lockr hint set "the usual + the year we met"
+  lockr hint clear`,
+}
+
+// hintSetCmd stores a password hint
+var hintSetCmd = &cobra.Command{
+	Use:   "set [hint]",
+	Short: "Store a password hint (kept unencrypted)",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// Only someone who can unlock the vault may change its hint
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		fmt.Fprintln(os.Stderr, "Warning: the hint is stored unencrypted next to the vault; never include the password.")
+		var hint string
+		if len(args) == 1 {
+			hint = args[0]
+		} else {
+			fmt.Print("Hint: ")
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && line == "" {
+				handleError(err, "Failed to read hint")
+				return
+			}
+			hint = line
+		}
+
+		hint = strings.TrimSpace(hint)
+		if hint == "" {
+			handleError(errors.New("the hint is empty; use 'lockr hint clear' to remove it"), "")
+			return
+		}
+		if len(hint) > maxHintLength {
+			handleError(fmt.Errorf("the hint is longer than %d characters", maxHintLength), "")
+			return
+		}
+
+		if err := os.WriteFile(hintPath(), []byte(hint+"\n"), 0600); err != nil {
+			handleError(err, "Failed to store hint")
+			return
+		}
+		printInfo("✓ Password hint stored in %s", hintPath())
+	},
+}
+
+// hintClearCmd removes the password hint
+var hintClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove the password hint",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := os.Remove(hintPath())
+		if errors.Is(err, os.ErrNotExist) {
+			printInfo("No password hint is set")
+			return
+		}
+		if err != nil {
+			handleError(err, "Failed to remove hint")
+			return
+		}
+		printInfo("✓ Password hint removed")
+	},
+}
+
+// hintPath is the file holding the active vault's password hint
+func hintPath() string {
+	return vaultPath + ".hint"
+}
+
+// passwordHint returns the active vault's password hint, or "" when none
+// is set
+func passwordHint() string {
+	data, err := os.ReadFile(hintPath())
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func init() {
+	hintCmd.AddCommand(hintSetCmd)
+	hintCmd.AddCommand(hintClearCmd)
+}
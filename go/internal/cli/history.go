@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/policy"
+	"github.com/spf13/cobra"
+)
+
+// historyCmd lists the previous values of a secret
+var historyCmd = &cobra.Command{
+	Use:   "history <key>",
+	Short: "Show the previous values of a secret",
+	Long: fmt.Sprintf(`List the values a secret held before it was last changed or deleted, newest
+first. Values are hidden unless --show is given, which clipboard-only
+secrets refuse. Bring one back with 'lockr restore <key> --version N'.
+
+Every update, restore and delete saves the value it replaces; the last %d
+are kept per key. Deleting a secret keeps its history, so use --clear to
+forget old values for good, for example after a leaked credential has been
+rotated.
+
+Examples:
+  lockr history db/prod            # Versions, when they were set and replaced
+  lockr history --show db/prod     # Include the values
+  lockr history --clear db/prod    # Forget every previous value`, database.MaxSecretVersions),
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		key := activeWorkspace.QualifyKey(args[0])
+		if clear, _ := cmd.Flags().GetBool("clear"); clear {
+			removed, err := vaultDB.ClearSecretHistory(key)
+			if err != nil {
+				handleError(err, fmt.Sprintf("Failed to clear the history of '%s'", key))
+				return
+			}
+			printInfo("✓ Forgot %d previous values of '%s'", removed, key)
+			return
+		}
+
+		versions, err := vaultDB.SecretHistory(key)
+		if err != nil {
+			handleError(err, fmt.Sprintf("Failed to read the history of '%s'", key))
+			return
+		}
+		if len(versions) == 0 {
+			fmt.Printf("No previous values of '%s'\n", key)
+			return
+		}
+
+		show, _ := cmd.Flags().GetBool("show")
+		if show {
+			// A deleted secret has no tags left; its key may still match a policy
+			var tags *string
+			if secret, err := vaultDB.PeekSecret(key); err == nil {
+				tags = secret.Tags
+			}
+			if resolvePolicy(key, tags).ClipboardOnly {
+				handleError(fmt.Errorf("%w; restore a previous value with 'lockr restore' instead", policy.ErrClipboardOnly), "")
+				return
+			}
+			auditSecretAccess(key)
+		}
+		fmt.Printf("Previous values of '%s':\n", versions[0].Key)
+		for _, v := range versions {
+			fmt.Printf("  v%-3d revision %-3d set %s, %s %s  (%d chars)\n",
+				v.Version, v.Revision,
+				v.SetAt.Local().Format("2006-01-02 15:04"),
+				v.Reason, v.ReplacedAt.Local().Format("2006-01-02 15:04"),
+				utf8.RuneCountInString(v.Value))
+			if show {
+				fmt.Printf("       %s\n", v.Value)
+			}
+		}
+	},
+}
+
+// restoreCmd makes a previous value of a secret current again
+var restoreCmd = &cobra.Command{
+	Use:   "restore <key> --version N",
+	Short: "Roll a secret back to a previous value",
+	Long: `Make a value listed by 'lockr history' current again. The value being
+replaced is saved as a new version, so a restore can be undone the same way.
+A deleted secret is recreated.
+
+Examples:
+  lockr history db/prod
+  lockr restore db/prod --version 3`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		version, _ := cmd.Flags().GetInt("version")
+		if version < 1 {
+			handleError(fmt.Errorf("--version must be 1 or greater; see 'lockr history %s'", args[0]), "")
+			return
+		}
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		key := activeWorkspace.QualifyKey(args[0])
+		if err := vaultDB.RestoreSecretVersion(key, version); err != nil {
+			handleError(err, fmt.Sprintf("Failed to restore '%s'", key))
+			return
+		}
+		invalidateCachedSecret(key)
+		printInfo("✓ Restored version %d of '%s'", version, key)
+	},
+}
+
+func init() {
+	historyCmd.Flags().Bool("show", false, "Include the previous values")
+	historyCmd.Flags().Bool("clear", false, "Forget every previous value of the secret")
+	historyCmd.MarkFlagsMutuallyExclusive("show", "clear")
+
+	restoreCmd.Flags().Int("version", 0, "Version to restore, as listed by 'lockr history'")
+	restoreCmd.MarkFlagRequired("version")
+}
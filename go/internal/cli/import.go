@@ -0,0 +1,355 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/merge"
+	"github.com/lockr/go/internal/progress"
+	"github.com/lockr/go/internal/vaultio"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import secrets from a .env file, another password manager or a backup",
+	Long: `Store each variable of a .env file, or each environment variable with a
+given name prefix, as a secret. Use --prefix to put them under a namespace.
+--archive restores the secrets of an encrypted backup made with 'lockr
+export --format lockr', with their tags, notes, URLs and expiry.
+
+Exports of other password managers are read too:
+
+  --from-1password FILE  1Password CSV export
+  --from-bitwarden FILE  Bitwarden unencrypted JSON export
+  --from-lastpass FILE   LastPass CSV export
+  --from-keepass FILE    KeePass 2 XML export
+  --from-pass DIR        pass password store, decrypted with gpg
+
+Logins become secrets named folder/title holding the password, with the
+username in the notes; URLs and tags are kept. Secure notes hold their text.
+
+Secrets already in the vault with the same value are left alone. When a
+key holds a different value, --conflict (or --on-conflict) decides what
+happens:
+
+  skip         keep the vault's value (default, or --skip-existing)
+  overwrite    replace it with the imported value (or --overwrite)
+  rename       import it as key-imported, keeping both
+  merge-newer  overwrite only if the imported value was changed more
+               recently; sources without timestamps never are
+  ask          ask about each conflict
+  fail         import nothing
+
+A report listing what happened to each key, never values, is printed at the
+end. Empty variables are ignored.
+
+Examples:
+  lockr import --env .env --prefix myapp/                 # Import a project's .env
+  lockr import --env .env --prefix myapp/ --dry-run       # Show what would happen
+  lockr import --from-environment MYAPP_ --prefix myapp/  # MYAPP_TOKEN -> myapp/TOKEN
+  lockr import --env .env --conflict overwrite --report import.txt
+  lockr import --archive vault-backup.lockr               # Prompts for the archive password
+  lockr import --from-1password export.csv --prefix 1p/ --dry-run
+  lockr import --from-pass ~/.password-store --overwrite
+  lockr import --archive laptop.lockr --on-conflict merge-newer
+  lockr import --from-keepass keepass.xml --on-conflict ask`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		envPrefix, _ := cmd.Flags().GetString("from-environment")
+		archiveFile, _ := cmd.Flags().GetString("archive")
+		passDir, _ := cmd.Flags().GetString("from-pass")
+		prefix, _ := cmd.Flags().GetString("prefix")
+		conflict, _ := cmd.Flags().GetString("conflict")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		reportPath, _ := cmd.Flags().GetString("report")
+		if skip, _ := cmd.Flags().GetBool("skip-existing"); skip {
+			conflict = vaultio.ConflictSkip
+		}
+		if overwrite, _ := cmd.Flags().GetBool("overwrite"); overwrite {
+			conflict = vaultio.ConflictOverwrite
+		}
+
+		var records []vaultio.Record
+		var source string
+		var err error
+		if path, format := importFile(cmd); path != "" {
+			records, err = vaultio.ReadFile(path, format)
+			source = path
+		} else {
+			switch {
+			case cmd.Flags().Changed("from-environment"):
+				records = vaultio.FromEnvironment(os.Environ(), envPrefix)
+				source = fmt.Sprintf("environment (%s*)", envPrefix)
+			case archiveFile != "":
+				records, err = readArchive(cmd, archiveFile)
+				source = archiveFile
+			case passDir != "":
+				records, err = vaultio.ReadPassStore(passDir, gpgDecrypt)
+				source = passDir
+			default:
+				handleError(errors.New("pass --env FILE, --from-environment PREFIX, --archive FILE or one of the --from-* sources"), "")
+				return
+			}
+		}
+		if err != nil {
+			handleError(err, fmt.Sprintf("Failed to read %s", source))
+			return
+		}
+
+		var skippedEmpty int
+		kept := records[:0]
+		for _, r := range records {
+			if r.Value == "" {
+				skippedEmpty++
+				continue
+			}
+			r.Key = activeWorkspace.QualifyKey(prefix + r.Key)
+			kept = append(kept, r)
+		}
+		records = kept
+		if skippedEmpty > 0 {
+			printVerbose("Ignoring %d empty values", skippedEmpty)
+		}
+		if len(records) == 0 {
+			printInfo("Nothing to import")
+			return
+		}
+
+		if conflict == vaultio.ConflictAsk && !term.IsTerminal(int(os.Stdin.Fd())) {
+			handleError(errors.New("conflicts cannot be resolved interactively here; pass another --conflict strategy"), "")
+			return
+		}
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		current := func(key string) (vaultio.Existing, bool) {
+			secret, err := vaultDB.PeekSecret(key)
+			if err != nil {
+				return vaultio.Existing{}, false
+			}
+			return vaultio.Existing{Value: secret.Value, Changed: merge.LastChanged(*secret)}, true
+		}
+		plan, err := vaultio.PlanImport(source, records, current, conflict, askConflict())
+		if errors.Is(err, errImportAborted) {
+			fmt.Println("Import aborted; nothing was changed")
+			return
+		}
+		if err != nil {
+			handleError(err, "Import cancelled; nothing was changed")
+			return
+		}
+
+		if dryRun {
+			printInfo("Dry run; nothing was changed")
+		} else {
+			// Answering conflict prompts can outlast the session
+			if err := ensureAuthenticated(); err != nil {
+				handleError(err, "Authentication failed; nothing was changed")
+				return
+			}
+			rep := statusReporter("import")
+			applyImport(plan, rep)
+			rep.Done(fmt.Sprintf("%d failed", len(plan.Failed)))
+		}
+
+		var buf bytes.Buffer
+		plan.WriteReport(&buf)
+		fmt.Print(buf.String())
+		if reportPath != "" {
+			if err := os.WriteFile(reportPath, buf.Bytes(), 0600); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to write report: %v\n", err)
+			}
+		}
+		if len(plan.Failed) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// errImportAborted is returned when the user quits at a conflict prompt
+var errImportAborted = errors.New("aborted at a conflict prompt")
+
+// askConflict returns an AskFunc prompting on the terminal for each
+// conflict. A capital letter applies the choice to the remaining ones.
+func askConflict() vaultio.AskFunc {
+	var all string
+	stdin := bufio.NewReader(os.Stdin)
+	return func(r vaultio.Record, existing vaultio.Existing) (string, error) {
+		if all != "" {
+			return all, nil
+		}
+
+		imported := "unknown"
+		if changed := r.UpdatedAt; changed != nil {
+			imported = changed.Local().Format("2006-01-02 15:04")
+		} else if !r.CreatedAt.IsZero() {
+			imported = r.CreatedAt.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(os.Stderr, "'%s' holds a different value (vault changed %s, import %s)\n",
+			r.Key, existing.Changed.Local().Format("2006-01-02 15:04"), imported)
+
+		for {
+			fmt.Fprint(os.Stderr, "  [s]kip, [o]verwrite, [r]ename or [q]uit? (S, O or R for all remaining): ")
+			line, err := stdin.ReadString('\n')
+			if err != nil && line == "" {
+				return "", errImportAborted
+			}
+			response := strings.TrimSpace(line)
+
+			choices := map[string]string{"s": vaultio.ConflictSkip, "o": vaultio.ConflictOverwrite, "r": vaultio.ConflictRename}
+			if response == "q" || response == "Q" {
+				return "", errImportAborted
+			}
+			if strategy, ok := choices[strings.ToLower(response)]; ok {
+				if response != strings.ToLower(response) {
+					all = strategy
+				}
+				return strategy, nil
+			}
+		}
+	}
+}
+
+// importFiles are the file sources of import: flag and format
+var importFiles = []struct {
+	flag   string
+	format string
+}{
+	{"env", vaultio.FormatDotenv},
+	{"from-1password", vaultio.FormatOnePassword},
+	{"from-bitwarden", vaultio.FormatBitwarden},
+	{"from-lastpass", vaultio.FormatLastPass},
+	{"from-keepass", vaultio.FormatKeePass},
+}
+
+// importFile returns the file given to one of the file sources and its
+// format, or "" when none was given
+func importFile(cmd *cobra.Command) (path, format string) {
+	for _, source := range importFiles {
+		if path, _ := cmd.Flags().GetString(source.flag); path != "" {
+			return path, source.format
+		}
+	}
+	return "", ""
+}
+
+// gpgDecrypt decrypts a file of a pass store with gpg, which asks for the
+// key's passphrase through gpg-agent as pass does
+func gpgDecrypt(path string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("gpg", "--quiet", "--batch", "--decrypt", path)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// readArchive decrypts the records of an encrypted export
+func readArchive(cmd *cobra.Command, path string) ([]vaultio.Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	password, err := archivePassword(cmd, false)
+	if err != nil {
+		return nil, err
+	}
+	return vaultio.ReadArchive(f, password)
+}
+
+// applyImport writes the new and overwritten records of plan, recording
+// failures in it and reporting progress to rep
+func applyImport(plan *vaultio.ImportPlan, rep *progress.Reporter) {
+	added := append(plan.Records(vaultio.OutcomeAdded), plan.Records(vaultio.OutcomeRenamed)...)
+	overwritten := vaultio.ToSecrets(plan.Records(vaultio.OutcomeOverwritten))
+	rep.Start(len(added) + len(overwritten))
+
+	if len(added) > 0 {
+		if _, err := vaultDB.ImportSecrets(vaultio.ToSecrets(added)); err != nil {
+			for _, r := range added {
+				plan.Failed[r.Key] = err
+			}
+		}
+		rep.Step(len(added), "added new secrets")
+	}
+
+	for _, s := range overwritten {
+		err := vaultDB.UpdateSecret(s.Key, s.Value)
+		rep.Step(1, "overwrote "+s.Key)
+		if err != nil {
+			plan.Failed[s.Key] = err
+			continue
+		}
+		invalidateCachedSecret(s.Key)
+		if err := restoreMetadata(s); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to restore metadata of '%s': %v\n", s.Key, err)
+		}
+		if err := vaultDB.SetSecretStrength(s.Key, *s.EntropyBits, database.SourceImported); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record strength of '%s': %v\n", s.Key, err)
+		}
+	}
+}
+
+// restoreMetadata copies the tags, notes, URL and expiry an archive carried
+// for an overwritten secret; env imports carry none
+func restoreMetadata(s database.Secret) error {
+	if s.Tags == nil && s.Notes == nil && s.URL == nil && s.ExpiresAt == nil {
+		return nil
+	}
+	var tags []string
+	if s.Tags != nil {
+		for _, tag := range strings.Split(*s.Tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return annotateSecret(s.Key, tags, database.SecretMetadata{Notes: s.Notes, URL: s.URL, ExpiresAt: s.ExpiresAt})
+}
+
+func init() {
+	importCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "on-conflict" {
+			name = "conflict"
+		}
+		return pflag.NormalizedName(name)
+	})
+	importCmd.Flags().String("env", "", "Read variables from this .env file")
+	importCmd.Flags().String("from-environment", "", "Read environment variables starting with this prefix (removed from keys)")
+	importCmd.Flags().String("archive", "", "Restore secrets from an encrypted archive made with 'export --format lockr'")
+	importCmd.Flags().String("password-file", "", "Read the archive password from the first line of this file")
+	importCmd.Flags().String("from-1password", "", "Read a 1Password CSV export")
+	importCmd.Flags().String("from-bitwarden", "", "Read an unencrypted Bitwarden JSON export")
+	importCmd.Flags().String("from-lastpass", "", "Read a LastPass CSV export")
+	importCmd.Flags().String("from-keepass", "", "Read a KeePass 2 XML export")
+	importCmd.Flags().String("from-pass", "", "Read a pass password store directory (e.g. ~/.password-store)")
+	importCmd.MarkFlagsMutuallyExclusive("env", "from-environment", "archive", "from-1password", "from-bitwarden", "from-lastpass", "from-keepass", "from-pass")
+	importCmd.Flags().String("prefix", "", "Prepend this to every key (e.g. myapp/)")
+	importCmd.Flags().String("conflict", vaultio.ConflictSkip, "When a key holds a different value: skip, overwrite, rename, merge-newer, ask, fail")
+	importCmd.Flags().Bool("skip-existing", false, "Keep the vault's value when a key differs (same as --conflict skip)")
+	importCmd.Flags().Bool("overwrite", false, "Replace the vault's value when a key differs (same as --conflict overwrite)")
+	importCmd.MarkFlagsMutuallyExclusive("conflict", "skip-existing", "overwrite")
+	importCmd.Flags().Bool("dry-run", false, "Show what would be imported without changing the vault")
+	importCmd.Flags().String("report", "", "Also write the import report to this file")
+	importCmd.MarkFlagsMutuallyExclusive("env", "from-environment")
+}
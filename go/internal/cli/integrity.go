@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/database"
+)
+
+var integrityCmd = &cobra.Command{
+	Use:   "integrity",
+	Short: "Check the vault's tamper-evident checksum",
+	Long: `Lockr keeps an HMAC over every secret's key, value, tags and notes, whether
+it is hidden or archived, its URL and its expiry, keyed by your master
+password and updated in the same transaction as each write. The checksum is
+verified whenever the vault is opened; a mismatch means the database was
+modified outside of lockr.
+
+If you know why the contents changed (for example you edited the vault with
+the sqlcipher shell), review it and accept the current contents with --accept.
+
+Examples:
+  lockr integrity              # Report whether the vault is intact
+  lockr integrity --accept     # Accept the current contents as legitimate`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		checker, ok := vaultDB.(database.IntegrityChecker)
+		if !ok {
+			handleError(fmt.Errorf("storage engine does not maintain an integrity checksum"), "")
+			return
+		}
+
+		accept, _ := cmd.Flags().GetBool("accept")
+		if !accept {
+			if err := checker.IntegrityError(); err != nil {
+				handleError(err, "Integrity check failed")
+				return
+			}
+			fmt.Println("Vault integrity: ok")
+			return
+		}
+
+		if checker.IntegrityError() == nil {
+			fmt.Println("Vault integrity: ok (nothing to accept)")
+			return
+		}
+
+		if !force {
+			fmt.Print("Accept the current vault contents as legitimate? (y/N): ")
+			var response string
+			fmt.Scanln(&response)
+			if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
+				fmt.Println("Cancelled")
+				return
+			}
+		}
+
+		if err := checker.ResealIntegrity(); err != nil {
+			handleError(err, "Failed to update integrity checksum")
+			return
+		}
+		if err := sessionMgr.Audit(database.AuditEventIntegrityReset, ""); err != nil {
+			printVerbose("Failed to record audit event: %v", err)
+		}
+
+		printInfo("Integrity checksum updated")
+	},
+}
+
+func init() {
+	integrityCmd.Flags().Bool("accept", false, "Accept the current contents and update the checksum")
+}
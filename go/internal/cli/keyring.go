@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/keyring"
 )
 
 var keyringCmd = &cobra.Command{
@@ -24,6 +26,7 @@ var keyringStatusCmd = &cobra.Command{
 		fmt.Printf("  Username: %s\n", km.GetUsername())
 		fmt.Printf("  Enabled: %t\n", km.IsEnabled())
 		fmt.Printf("  Has Stored Password: %t\n", km.HasPassword())
+		fmt.Printf("  Derived Key Cached: %t\n", km.HasDerivedKey(keyring.VaultID(vaultPath)))
 	},
 }
 
@@ -69,7 +72,7 @@ var keyringSetCmd = &cobra.Command{
 			return
 		}
 
-		fmt.Println("Password saved to keyring successfully")
+		printInfo("Password saved to keyring successfully")
 	},
 }
 
@@ -100,7 +103,7 @@ var keyringClearCmd = &cobra.Command{
 			return
 		}
 
-		fmt.Println("Password removed from keyring successfully")
+		printInfo("Password removed from keyring successfully")
 	},
 }
 
@@ -127,10 +130,75 @@ var keyringDisableCmd = &cobra.Command{
 	},
 }
 
+var keyringCacheKeyCmd = &cobra.Command{
+	Use:   "cache-key",
+	Short: "Cache the derived vault key to speed up unlocking",
+	Long: `Derive this vault's raw encryption key from the password and store it in
+the system keyring. Later unlocks open the vault with the cached key and skip
+SQLCipher's deliberately slow key derivation, cutting unlock time to
+milliseconds.
+
+The cached key is specific to this vault file and is not the password, but
+anyone who can read it from the keyring can open the vault. While it is
+cached, the session file also keeps the vault key, sealed with another key
+from the keyring, so commands run within the session timeout carry on with
+one session. Remove both with 'lockr keyring forget-key'. The cached key is
+replaced automatically by 'lockr rekey'.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		km := sessionMgr.GetKeyringManager()
+		if !km.IsEnabled() {
+			fmt.Println("Keyring is disabled")
+			return
+		}
+
+		if !force {
+			fmt.Print("Store the derived vault key in the system keyring? (y/N): ")
+			var response string
+			fmt.Scanln(&response)
+			if response != "y" && response != "Y" {
+				fmt.Println("Cancelled")
+				return
+			}
+		}
+
+		password, err := authenticateWithMasterPassword()
+		if err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		if err := sessionMgr.CacheDerivedKey(keyring.VaultID(vaultPath), password); err != nil {
+			handleError(err, "Failed to cache derived key")
+			return
+		}
+
+		fmt.Println("Derived key cached; unlocking will skip key derivation")
+	},
+}
+
+var keyringForgetKeyCmd = &cobra.Command{
+	Use:   "forget-key",
+	Short: "Remove the cached derived vault key",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		km := sessionMgr.GetKeyringManager()
+		if err := km.DeleteDerivedKey(keyring.VaultID(vaultPath)); err != nil {
+			handleError(err, "Failed to remove derived key")
+			return
+		}
+		// The session file may keep the vault key too
+		sessionMgr.ForgetSessionFile()
+		printInfo("Derived key removed from keyring")
+	},
+}
+
 func init() {
 	keyringCmd.AddCommand(keyringStatusCmd)
 	keyringCmd.AddCommand(keyringSetCmd)
 	keyringCmd.AddCommand(keyringClearCmd)
 	keyringCmd.AddCommand(keyringEnableCmd)
 	keyringCmd.AddCommand(keyringDisableCmd)
+	keyringCmd.AddCommand(keyringCacheKeyCmd)
+	keyringCmd.AddCommand(keyringForgetKeyCmd)
 }
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+)
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Print key names for shells and scripts",
+	Long: `Print the names of all keys, never their values, in a form a shell can load.
+Useful for tab completion and scripting where dynamic completion is not
+available.
+
+Formats:
+  plain   one key per line
+  shell   POSIX variable assignments (bash, zsh, sh), LOCKR_KEY_<NAME>='key'
+  fish    abbreviations that expand lk-<name> to 'lockr get key'
+
+Key names are sanitized into valid identifiers; names that would collide get
+a numeric suffix. Values are quoted so any key name is safe to eval.
+
+Examples:
+  lockr keys                                  # One key per line
+  eval "$(lockr keys --format shell)"         # Then: lockr get $LOCKR_KEY_PROD_WEB_DB
+  lockr keys --format fish | source           # Then type lk-prod-web-db<space>
+  lockr keys --format shell --prefix DB_      # Custom variable prefix`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		prefix, _ := cmd.Flags().GetString("prefix")
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		secrets, err := vaultDB.ListSecrets()
+		if err != nil {
+			handleError(err, "Failed to list secrets")
+			return
+		}
+
+		keys := make([]string, len(secrets))
+		for i, secret := range secrets {
+			keys[i] = secret.Key
+		}
+		sort.Strings(keys)
+
+		switch format {
+		case "plain":
+			for _, key := range keys {
+				fmt.Println(key)
+			}
+		case "shell":
+			if !cmd.Flags().Changed("prefix") {
+				prefix = "LOCKR_KEY_"
+			}
+			for _, e := range shellNames(keys, prefix, '_', true) {
+				fmt.Printf("%s=%s\n", e.name, shellQuote(e.key))
+			}
+		case "fish":
+			if !cmd.Flags().Changed("prefix") {
+				prefix = "lk-"
+			}
+			for _, e := range shellNames(keys, prefix, '-', false) {
+				fmt.Printf("abbr --add %s %s\n", e.name, shellQuote("lockr get "+shellQuote(e.key)))
+			}
+		default:
+			handleError(fmt.Errorf("unknown format %q (use plain, shell or fish)", format), "")
+		}
+	},
+}
+
+// shellName is a key with the identifier it is exported under
+type shellName struct {
+	name string
+	key  string
+}
+
+// shellNames derives a unique identifier for every key: runs of characters
+// other than ASCII letters and digits become sep, and duplicates get a
+// numeric suffix
+func shellNames(keys []string, prefix string, sep rune, upper bool) []shellName {
+	names := make([]shellName, 0, len(keys))
+	used := make(map[string]bool, len(keys))
+
+	for _, key := range keys {
+		var b strings.Builder
+		pendingSep := false
+		for _, r := range key {
+			if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				if pendingSep && b.Len() > 0 {
+					b.WriteRune(sep)
+				}
+				pendingSep = false
+				if upper {
+					r = unicode.ToUpper(r)
+				} else {
+					r = unicode.ToLower(r)
+				}
+				b.WriteRune(r)
+			} else {
+				pendingSep = true
+			}
+		}
+
+		base := prefix + b.String()
+		if b.Len() == 0 {
+			base = prefix + "key"
+		}
+		if base[0] >= '0' && base[0] <= '9' {
+			base = "_" + base
+		}
+
+		name := base
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s%c%d", base, sep, n)
+		}
+		used[name] = true
+		names = append(names, shellName{name: name, key: key})
+	}
+	return names
+}
+
+// shellQuote single-quotes s for POSIX shells and fish
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func init() {
+	keysCmd.Flags().String("format", "plain", "Output format: plain, shell, fish")
+	keysCmd.Flags().String("prefix", "", "Prefix for generated names (default LOCKR_KEY_ for shell, lk- for fish)")
+}
@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/policy"
+	"github.com/lockr/go/internal/refs"
+)
+
+// Launcher output formats
+const (
+	launcherAlfred = "alfred"
+	launcherRofi   = "rofi"
+)
+
+var launcherCmd = &cobra.Command{
+	Use:   "launcher",
+	Short: "List keys for keyboard launchers such as Alfred and rofi",
+	Long: `Print every key in the format a keyboard launcher expects, most recently
+used first, so a launcher can offer them and hand the chosen key to
+'lockr launcher-copy'. Values are never printed.
+
+Formats:
+  alfred   Script Filter JSON
+  rofi     One key per line, for rofi -dmenu, dmenu, wofi or fuzzel
+
+Launchers run without a terminal, so the vault must be unlockable through the
+keyring or a cached derived key.
+
+Examples:
+  lockr launcher --format alfred                           # Alfred Script Filter
+  lockr launcher --format rofi | rofi -dmenu -p lockr | xargs -r lockr launcher-copy`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		if format != launcherAlfred && format != launcherRofi {
+			handleError(fmt.Errorf("unknown format %q (use alfred or rofi)", format), "")
+			return
+		}
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		secrets, err := vaultDB.ListSecrets()
+		if err != nil {
+			handleError(err, "Failed to list secrets")
+			return
+		}
+		sort.SliceStable(secrets, func(i, j int) bool {
+			return secrets[i].LastAccessed.After(secrets[j].LastAccessed)
+		})
+
+		if format == launcherRofi {
+			for _, s := range secrets {
+				fmt.Println(s.Key)
+			}
+			return
+		}
+		if err := writeAlfredItems(secrets); err != nil {
+			handleError(err, "Failed to write launcher items")
+		}
+	},
+}
+
+var launcherCopyCmd = &cobra.Command{
+	Use:   "launcher-copy <key>",
+	Short: "Copy a secret chosen in a launcher to the clipboard",
+	Long: `Copy the value of key to the clipboard and stay running until the clipboard
+is cleared. This is the action behind 'lockr launcher': unlike 'lockr get' it
+never prints the value, so a launcher can't show it by accident.
+
+Examples:
+  lockr launcher-copy github/token`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		key := args[0]
+		if noClipboard {
+			handleError(errors.New("the clipboard is disabled by --no-clipboard"), "")
+			return
+		}
+		if clipboardMgr == nil {
+			handleError(errors.New("no clipboard is available"), "")
+			return
+		}
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		secret, err := vaultDB.GetSecret(key)
+		if err != nil {
+			handleError(err, fmt.Sprintf("Failed to get secret '%s'", key))
+			return
+		}
+		auditSecretAccess(key)
+		defaults := resolvePolicy(key, secret.Tags)
+		value, err := refs.Resolve(secret.Key, secret.Value, policyLookup(&defaults))
+		if err != nil {
+			handleError(err, fmt.Sprintf("Failed to resolve references in '%s'", key))
+			return
+		}
+		applyClipboardPolicy(defaults)
+		if err := clipboardMgr.CopySecretWithNotification(value); err != nil {
+			handleError(err, "Failed to copy to clipboard")
+			return
+		}
+
+		// The clear timer only runs while this process does
+		if !clipboardMgr.WaitForClear(clipboardMgr.ClearDelay() + time.Second) {
+			fmt.Fprintln(os.Stderr, "Warning: clipboard was not cleared")
+		}
+	},
+}
+
+// alfredItem is one result of an Alfred Script Filter
+type alfredItem struct {
+	UID          string `json:"uid"`
+	Title        string `json:"title"`
+	Subtitle     string `json:"subtitle,omitempty"`
+	Arg          string `json:"arg"`
+	Autocomplete string `json:"autocomplete"`
+	Match        string `json:"match"`
+}
+
+// writeAlfredItems prints secrets as Alfred Script Filter JSON
+func writeAlfredItems(secrets []database.SearchResult) error {
+	items := make([]alfredItem, 0, len(secrets))
+	for _, s := range secrets {
+		var subtitle []string
+		if tags := policy.ParseTags(s.Tags); len(tags) > 0 {
+			subtitle = append(subtitle, strings.Join(tags, ", "))
+		}
+		subtitle = append(subtitle, fmt.Sprintf("used %d times", s.AccessCount))
+
+		items = append(items, alfredItem{
+			UID:          s.Key,
+			Title:        s.Key,
+			Subtitle:     strings.Join(subtitle, " · "),
+			Arg:          s.Key,
+			Autocomplete: s.Key,
+			// Alfred matches word starts, so split keys into words
+			Match: strings.NewReplacer("/", " ", "-", " ", "_", " ", ".", " ").Replace(s.Key),
+		})
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]interface{}{"items": items})
+}
+
+func init() {
+	launcherCmd.Flags().String("format", launcherAlfred, "Output format: alfred or rofi")
+}
@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lockr/go/internal/config"
+)
+
+// vaultLimits are the soft limits from the config file
+var vaultLimits config.Limits
+
+// limitWarnings checks the open vault against the soft limits
+func limitWarnings() []string {
+	if vaultLimits == (config.Limits{}) {
+		return nil
+	}
+
+	count, err := vaultDB.CountSecrets()
+	if err != nil {
+		return nil
+	}
+	var size int64
+	if info, err := os.Stat(vaultPath); err == nil {
+		size = info.Size()
+	}
+	return vaultLimits.Check(count, size)
+}
+
+// warnLimits prints a warning for every soft limit the vault exceeds
+func warnLimits() {
+	for _, w := range limitWarnings() {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/lint"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [pattern]",
+	Short: "Check secrets for expired, dangling or risky entries",
+	Long: `Check the vault's secrets, or those whose keys match a glob pattern, for
+problems: empty values, expiry dates that have passed or are less than 14
+days away, and references (${ref:key}) to secrets that do not exist.
+
+With --values, the values themselves are checked too. Known credential
+formats are recognized and flagged when something looks wrong:
+
+  AWS access keys   no secret access key stored in the same namespace;
+                    temporary (ASIA) keys, which expire within hours
+  GitHub tokens     classic personal access tokens (ghp_), whose scopes
+                    cover every repository; app installation tokens (ghs_)
+  JWTs              expired or soon-expiring tokens (exp); unsigned tokens
+                    (alg "none"); broad scopes such as "*", "admin" or "repo:*"
+
+Nothing leaves the machine: tokens are decoded locally, never sent to the
+service that issued them, and values are never printed. Reading values does
+not count as an access. Exits with status 1 when there are findings.
+
+Examples:
+  lockr lint
+  lockr lint --values
+  lockr lint --values 'prod/*'`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		values, _ := cmd.Flags().GetBool("values")
+		pattern := ""
+		if len(args) == 1 {
+			pattern = args[0]
+		}
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		secrets, err := vaultDB.ExportSecrets(pattern)
+		if err != nil {
+			handleError(err, "Failed to read vault")
+			return
+		}
+
+		now := time.Now()
+		findings := lint.Metadata(secrets, now)
+		if values {
+			findings = append(findings, lint.Values(secrets, now)...)
+		}
+
+		if len(findings) == 0 {
+			printInfo("✓ No problems found in %d secrets", len(secrets))
+			return
+		}
+
+		lint.Sort(findings)
+		if !quiet {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, f := range findings {
+				format := f.Format
+				if format == "" {
+					format = "-"
+				}
+				fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", f.Severity, f.Key, format, f.Message)
+			}
+			w.Flush()
+		}
+		printInfo("\n%d problems in %d secrets checked", len(findings), len(secrets))
+		os.Exit(1)
+	},
+}
+
+func init() {
+	lintCmd.Flags().Bool("values", false, "Also check values in known credential formats (AWS keys, GitHub tokens, JWTs)")
+}
@@ -0,0 +1,240 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/lockr/go/internal/authz"
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/editor"
+	"github.com/lockr/go/internal/keyring"
+	"github.com/lockr/go/internal/policy"
+	"github.com/lockr/go/internal/refs"
+	"github.com/lockr/go/internal/search"
+)
+
+// Ways reveal requests from an editor are approved
+const (
+	approvePrompt = "prompt"
+	approveRules  = "rules"
+)
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Serve secrets to editor plugins over stdin/stdout",
+	Long: `Run a JSON-RPC 2.0 server on stdin and stdout for editor plugins (VS Code,
+Neovim, ...). Messages use the Content-Length framing of the Language Server
+Protocol, so plugins can reuse their LSP client.
+
+Methods:
+  initialize     Server name, version and capabilities
+  lockr/search   {"query": "...", "limit": 50} -> keys, tags and a ${ref:key}
+                 reference to insert; never values
+  lockr/reveal   {"key": "..."} -> {"key": "...", "value": "..."}
+  shutdown, exit
+
+The plugin acts as the client named by --client, and access rules ('lockr acl')
+decide which keys it may search (list) and reveal (get); with no matching rule
+everything is denied. By default each reveal must also be approved on the
+terminal lockr was started from; --approve rules trusts the access rules
+alone. Clipboard-only secrets are never revealed.
+
+The vault is unlocked with a cached key or the keyring, or else by a password
+typed on the terminal, because stdin carries the protocol.
+
+Examples:
+  lockr acl add --client plugin:vscode --keys 'dev/*' --ops get,list
+  lockr lsp --client plugin:vscode
+  lockr lsp --client plugin:nvim --approve rules`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, _ := cmd.Flags().GetString("client")
+		approve, _ := cmd.Flags().GetString("approve")
+		if strings.TrimSpace(client) == "" {
+			handleError(errors.New("--client is required, e.g. --client plugin:vscode"), "")
+			return
+		}
+		if approve != approvePrompt && approve != approveRules {
+			handleError(fmt.Errorf("unknown --approve mode %q (use prompt or rules)", approve), "")
+			return
+		}
+
+		// Prompts go to the terminal; stdout belongs to the protocol
+		tty, _ := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+		if tty != nil {
+			defer tty.Close()
+		}
+
+		sessionMgr.SetClient(database.ClientEditor)
+		sessionMgr.SetIdentity(client)
+		if err := authenticateOnTerminal(tty); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		rules, err := loadACLRules()
+		if err != nil {
+			handleError(err, "Failed to read access rules")
+			return
+		}
+
+		backend := &editorBackend{
+			client:  client,
+			authz:   authz.NewAuthorizer(rules, sessionMgr.AuditDenial),
+			prompt:  approve == approvePrompt,
+			tty:     tty,
+			matcher: search.NewEngine(),
+		}
+		server := editor.NewServer(backend, editor.ServerInfo{Name: "lockr", Version: getVersion()})
+		if err := server.Serve(os.Stdin, os.Stdout); err != nil {
+			handleError(err, "Editor connection failed")
+		}
+	},
+}
+
+// authenticateOnTerminal unlocks the vault like ensureAuthenticated, but
+// reads a password from tty instead of stdin
+func authenticateOnTerminal(tty *os.File) error {
+	if unlockWithAgent() {
+		afterAuthentication()
+		return nil
+	}
+	if err := sessionMgr.TryAuthenticateWithCachedKey(keyring.VaultID(vaultPath)); err == nil {
+		afterAuthentication()
+		return nil
+	}
+	if err := sessionMgr.TryAuthenticateWithKeyring(); err == nil {
+		afterAuthentication()
+		return nil
+	}
+	if tty == nil {
+		return errors.New("no terminal to read the password from; cache the derived key or enable the keyring")
+	}
+
+	// Offering to save the password would read stdin and write stdout
+	sessionMgr.GetKeyringManager().Disable()
+
+	fmt.Fprint(tty, "Enter vault password: ")
+	password, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	if err != nil {
+		return fmt.Errorf("failed to read password: %w", err)
+	}
+	if err := sessionMgr.Authenticate(string(password)); err != nil {
+		return err
+	}
+	afterAuthentication()
+	return nil
+}
+
+// editorBackend answers editor requests from the vault
+type editorBackend struct {
+	client  string
+	authz   *authz.Authorizer
+	prompt  bool
+	tty     *os.File
+	answers *bufio.Reader
+	matcher *search.Engine
+}
+
+// Search ranks the keys the client may list against query
+func (b *editorBackend) Search(query string, limit int) ([]editor.Entry, error) {
+	if err := b.resume(); err != nil {
+		return nil, err
+	}
+	secrets, err := vaultDB.ListSecrets()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(secrets))
+	for i, s := range secrets {
+		keys[i] = s.Key
+	}
+	allowed := make(map[string]bool)
+	for _, key := range b.authz.Filter(b.client, authz.OpList, keys) {
+		allowed[key] = true
+	}
+	var visible []database.SearchResult
+	for _, s := range secrets {
+		if allowed[s.Key] {
+			visible = append(visible, s)
+		}
+	}
+
+	var entries []editor.Entry
+	for _, m := range b.matcher.SearchInteractive(query, visible, limit) {
+		entries = append(entries, editor.Entry{
+			Key:       m.Result.Key,
+			Reference: refs.Reference(m.Result.Key),
+			Tags:      policy.ParseTags(m.Result.Tags),
+			Revision:  m.Result.Revision,
+		})
+	}
+	return entries, nil
+}
+
+// Reveal returns a value the client may get, once approved
+func (b *editorBackend) Reveal(key string) (string, error) {
+	if err := b.authz.Check(b.client, authz.OpGet, key); err != nil {
+		return "", err
+	}
+	if err := b.resume(); err != nil {
+		return "", err
+	}
+
+	secret, err := vaultDB.PeekSecret(key)
+	if err != nil {
+		return "", err
+	}
+	if resolvePolicy(secret.Key, secret.Tags).ClipboardOnly {
+		return "", policy.ErrClipboardOnly
+	}
+	if b.prompt && !b.approve(secret.Key) {
+		return "", editor.ErrNotApproved
+	}
+
+	value, err := refs.Resolve(secret.Key, secret.Value, checkedLookup(b.authz, b.client))
+	if err != nil {
+		return "", err
+	}
+	auditSecretAccess(secret.Key)
+	return value, nil
+}
+
+// resume refreshes the session before a request, unlocking the vault again
+// when it timed out while the editor sat idle
+func (b *editorBackend) resume() error {
+	if sessionMgr.IsAuthenticated() {
+		return sessionMgr.RefreshSession()
+	}
+	return authenticateOnTerminal(b.tty)
+}
+
+// approve asks on the terminal whether the client may read key
+func (b *editorBackend) approve(key string) bool {
+	if b.tty == nil {
+		return false
+	}
+	if b.answers == nil {
+		b.answers = bufio.NewReader(b.tty)
+	}
+	fmt.Fprintf(b.tty, "%s wants the value of '%s'. Allow? (y/N): ", b.client, key)
+	answer, err := b.answers.ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func init() {
+	lspCmd.Flags().String("client", "", "Client identity checked against access rules, e.g. plugin:vscode")
+	lspCmd.Flags().String("approve", approvePrompt, "How reveals are approved: prompt (on the terminal) or rules (access rules alone)")
+}
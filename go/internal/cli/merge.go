@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/keymap"
+	"github.com/lockr/go/internal/merge"
+	"github.com/lockr/go/internal/progress"
+	"github.com/lockr/go/internal/strength"
+)
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge <other-vault>",
+	Short: "Merge secrets from another vault into this one",
+	Long: `Copy secrets from another vault file, for example a travel copy made with
+'lockr clone', back into this vault.
+
+Keys that only exist in the other vault are added and identical ones are
+skipped. Keys whose values differ are conflicts: by default a merge screen
+shows both versions' metadata side by side (revision, when each changed,
+length, strength, tags) and lets you keep ours, take theirs or type a new
+value for each. Values are only shown for an entry when you press r.
+
+Nothing is written until every conflict is decided. A merge report listing
+what happened to each key, never values, is printed at the end.
+
+Strategies:
+  ask     decide each conflict in the merge screen (default)
+  ours    keep this vault's values
+  theirs  take the other vault's values
+  newer   take whichever value was changed last
+
+Examples:
+  lockr merge travel.lockr                       # Resolve conflicts interactively
+  lockr merge travel.lockr --strategy newer      # No prompts
+  lockr merge travel.lockr --dry-run             # Show what would happen
+  lockr merge travel.lockr --report merge.txt    # Also save the report`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		other := args[0]
+		strategy, _ := cmd.Flags().GetString("strategy")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		reportPath, _ := cmd.Flags().GetString("report")
+
+		if absOther, err := filepath.Abs(other); err == nil {
+			if absVault, err := filepath.Abs(vaultPath); err == nil && absVault == absOther {
+				handleError(errors.New("cannot merge a vault with itself"), "")
+				return
+			}
+		}
+		if _, err := os.Stat(other); err != nil {
+			handleError(fmt.Errorf("vault not found: %s", other), "")
+			return
+		}
+		if strategy == "ask" && (!merge.InteractiveAvailable || !term.IsTerminal(int(os.Stdin.Fd()))) {
+			handleError(errors.New("conflicts cannot be resolved interactively here; pass --strategy ours, theirs or newer"), "")
+			return
+		}
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		theirs, err := readOtherVault(other)
+		if err != nil {
+			handleError(err, fmt.Sprintf("Failed to read %s", other))
+			return
+		}
+		ours, err := vaultDB.ExportSecrets("")
+		if err != nil {
+			handleError(err, "Failed to read vault")
+			return
+		}
+
+		plan := merge.NewPlan(ours, theirs)
+		printInfo("%d new, %d identical, %d conflicting", len(plan.Added), len(plan.Identical), len(plan.Conflicts))
+		if len(plan.Added) == 0 && len(plan.Conflicts) == 0 {
+			fmt.Println("Nothing to merge")
+			return
+		}
+
+		var decisions []merge.Decision
+		if strategy == "ask" {
+			decisions, err = merge.RunResolver(plan.Conflicts, keymaps[keymap.ScreenMerge])
+		} else {
+			decisions, err = merge.Resolve(plan.Conflicts, strategy)
+		}
+		if err == merge.ErrAborted {
+			fmt.Println("Merge aborted; nothing was changed")
+			return
+		}
+		if err != nil {
+			handleError(err, "Failed to resolve conflicts")
+			return
+		}
+
+		report := merge.NewReport(other, plan)
+		report.Decisions = decisions
+		if dryRun {
+			printInfo("Dry run; nothing was changed")
+		} else {
+			rep := statusReporter("merge")
+			applyMerge(plan, decisions, report, rep)
+			rep.Done(fmt.Sprintf("%d failed", len(report.Failed)))
+		}
+
+		var buf bytes.Buffer
+		report.Write(&buf)
+		fmt.Print("\n" + buf.String())
+		if reportPath != "" {
+			if err := os.WriteFile(reportPath, buf.Bytes(), 0600); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to write report: %v\n", err)
+			}
+		}
+		if len(report.Failed) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// readOtherVault prompts for the other vault's password and reads all of
+// its secrets
+func readOtherVault(path string) ([]database.Secret, error) {
+	password, err := promptPassword(fmt.Sprintf("Enter password for %s: ", path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password: %w", err)
+	}
+
+	store, err := database.OpenStore(vaultEngine(path), path)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Connect(password); err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	return store.ExportSecrets("")
+}
+
+// applyMerge writes the planned additions and conflict decisions, recording
+// failures in the report
+func applyMerge(plan *merge.Plan, decisions []merge.Decision, report *merge.Report, rep *progress.Reporter) {
+	changed := 0
+	for _, d := range decisions {
+		if d.Resolution != merge.KeepOurs {
+			changed++
+		}
+	}
+	rep.Start(len(plan.Added) + changed)
+
+	if len(plan.Added) > 0 {
+		if _, err := vaultDB.ImportSecrets(plan.Added); err != nil {
+			for _, s := range plan.Added {
+				report.Failed[s.Key] = err
+			}
+		}
+		rep.Step(len(plan.Added), "added new secrets")
+	}
+
+	for _, d := range decisions {
+		if d.Resolution == merge.KeepOurs {
+			continue
+		}
+		err := vaultDB.UpdateSecret(d.Key, d.Value)
+		rep.Step(1, "updated "+d.Key)
+		if err != nil {
+			report.Failed[d.Key] = err
+			continue
+		}
+		invalidateCachedSecret(d.Key)
+
+		source := database.SourceImported
+		if d.Resolution == merge.Edited {
+			source = database.SourceManual
+		}
+		if err := vaultDB.SetSecretStrength(d.Key, strength.Estimate(d.Value), source); err != nil {
+			printVerbose("Failed to record strength of '%s': %v", d.Key, err)
+		}
+	}
+}
+
+func init() {
+	mergeCmd.Flags().String("strategy", "ask", "How to resolve conflicts: ask, ours, theirs, newer")
+	mergeCmd.Flags().Bool("dry-run", false, "Show what would change without writing")
+	mergeCmd.Flags().String("report", "", "Also write the merge report to this file")
+}
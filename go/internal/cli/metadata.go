@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lockr/go/internal/database"
+	"github.com/spf13/cobra"
+)
+
+// metadataFlags reads the --note, --url and --expires flags of set into the
+// changes they describe; flags that were not given are left nil
+func metadataFlags(cmd *cobra.Command) (database.SecretMetadata, error) {
+	var meta database.SecretMetadata
+
+	if cmd.Flags().Changed("note") {
+		note, _ := cmd.Flags().GetString("note")
+		meta.Notes = &note
+	}
+
+	if cmd.Flags().Changed("url") {
+		link, _ := cmd.Flags().GetString("url")
+		if link != "" {
+			parsed, err := url.Parse(link)
+			if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+				return meta, fmt.Errorf("--url must be an absolute URL such as https://example.com")
+			}
+		}
+		meta.URL = &link
+	}
+
+	if cmd.Flags().Changed("expires") {
+		text, _ := cmd.Flags().GetString("expires")
+		expires, err := parseExpiry(text, time.Now())
+		if err != nil {
+			return meta, err
+		}
+		meta.ExpiresAt = &expires
+	}
+
+	return meta, nil
+}
+
+// parseExpiry parses an expiry given as a duration from now ("90d", "12h"),
+// a date (2025-01-31, local midnight) or "never", which returns the zero time
+func parseExpiry(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "never" {
+		return time.Time{}, nil
+	}
+	if date, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return date, nil
+	}
+	d, err := parseDays(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--expires must be a duration such as 90d, a date such as 2025-01-31, or never")
+	}
+	return now.Add(d).Truncate(time.Second), nil
+}
+
+// annotateSecret applies the tags and metadata given to set
+func annotateSecret(key string, tags []string, meta database.SecretMetadata) error {
+	if err := applyTags(key, tags, vaultDB.AddTag); err != nil {
+		return err
+	}
+	return vaultDB.SetSecretMetadata(key, meta)
+}
+
+// warnExpired tells the user on stderr that a retrieved secret has expired
+func warnExpired(secret *database.Secret) {
+	if secret.Expired(time.Now()) {
+		fmt.Fprintf(os.Stderr, "Warning: secret '%s' expired on %s\n",
+			secret.Key, secret.ExpiresAt.Local().Format("2006-01-02"))
+	}
+}
+
+// printNotes prints a secret's notes on stderr, keeping stdout for the value
+func printNotes(secret *database.Secret) {
+	if secret.Notes == nil {
+		fmt.Fprintf(os.Stderr, "No notes for '%s'\n", secret.Key)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Notes: %s\n", *secret.Notes)
+}
@@ -0,0 +1,364 @@
+package cli
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/lockr/go/internal/companion"
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/policy"
+	"github.com/lockr/go/internal/refs"
+)
+
+// Vault settings holding the companion sync configuration
+const (
+	companionKeySetting      = "companion.key"
+	companionVaultIDSetting  = "companion.vault_id"
+	companionLocationSetting = "companion.location"
+	companionStateSetting    = "companion.state"
+)
+
+var mobileCmd = &cobra.Command{
+	Use:   "mobile",
+	Short: "Sync secrets to mobile and other companion apps",
+	Long: `Write the vault to a sync location in the companion format, which mobile
+apps and other clients can read. The location is a directory inside a folder
+you already sync to the phone (Syncthing, iCloud Drive, Dropbox, ...). It
+holds a manifest and encrypted segments: a full snapshot followed by
+incremental changes, so each sync only uploads what changed.
+
+The segments are encrypted with a random sync key kept in the vault. Pairing
+shows a QR code with the location and the sync key wrapped by a one-time
+pairing code, which is printed next to it and typed on the device; the QR
+code alone is not enough to read the secrets.
+
+The format is documented in go/docs/COMPANION_SYNC.md in the lockr repository.
+
+Examples:
+  lockr mobile pair --location ~/Sync/lockr
+  lockr mobile pair --location ~/Dropbox/lockr --remote-location /lockr
+  lockr mobile sync                 # Write the changes since the last sync
+  lockr mobile sync --full          # Write a fresh snapshot
+  lockr mobile unpair               # Forget the sync key and remove the files`,
+}
+
+var mobilePairCmd = &cobra.Command{
+	Use:   "pair",
+	Short: "Set up a sync location and show a pairing QR code",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		location, _ := cmd.Flags().GetString("location")
+		remote, _ := cmd.Flags().GetString("remote-location")
+		showURI, _ := cmd.Flags().GetBool("uri")
+		newKey, _ := cmd.Flags().GetBool("new-key")
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		if location == "" {
+			location, _, _ = vaultDB.GetSetting(companionLocationSetting)
+			if location == "" {
+				handleError(errors.New("pass --location with a directory inside a synced folder"), "")
+				return
+			}
+		}
+		location, err := filepath.Abs(location)
+		if err != nil {
+			handleError(err, "Invalid location")
+			return
+		}
+		if remote == "" {
+			remote = location
+		}
+
+		key, vaultID, err := companionKey(newKey)
+		if err != nil {
+			handleError(err, "Failed to set up the sync key")
+			return
+		}
+		if err := vaultDB.SetSetting(companionLocationSetting, location); err != nil {
+			handleError(err, "Failed to save the sync location")
+			return
+		}
+
+		// Start every pairing from a full snapshot
+		count, err := syncCompanion(location, vaultID, key, true)
+		if err != nil {
+			handleError(err, "Failed to write the sync location")
+			return
+		}
+		printInfo("✓ Wrote %d secrets to %s", count, location)
+
+		code, err := companion.GenerateCode()
+		if err != nil {
+			handleError(err, "Failed to generate a pairing code")
+			return
+		}
+		pairing, err := companion.NewPairing(remote, vaultID, key, code)
+		if err != nil {
+			handleError(err, "Failed to create the pairing")
+			return
+		}
+
+		if showURI || !term.IsTerminal(int(os.Stdout.Fd())) {
+			fmt.Println(pairing.URI())
+		} else {
+			fmt.Println()
+			if err := companion.WriteQR(os.Stdout, pairing.URI()); err != nil {
+				handleError(err, "Failed to draw the QR code")
+				return
+			}
+		}
+		fmt.Printf("\nPairing code: %s\n", code)
+		printInfo("Scan the QR code with the companion app and enter the pairing code.")
+		printInfo("Run 'lockr mobile sync' after changes to update the device.")
+	},
+}
+
+var mobileSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Write changes since the last sync to the sync location",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		full, _ := cmd.Flags().GetBool("full")
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		location, _, err := vaultDB.GetSetting(companionLocationSetting)
+		if err != nil {
+			handleError(err, "Failed to read the sync location")
+			return
+		}
+		if location == "" {
+			handleError(errors.New("no device is paired; run 'lockr mobile pair' first"), "")
+			return
+		}
+		key, vaultID, err := companionKey(false)
+		if err != nil {
+			handleError(err, "Failed to read the sync key")
+			return
+		}
+
+		count, err := syncCompanion(location, vaultID, key, full)
+		if err != nil {
+			handleError(err, "Sync failed")
+			return
+		}
+		if count == 0 {
+			printInfo("Nothing changed since the last sync")
+			return
+		}
+		printInfo("✓ Synced %d changes to %s", count, location)
+	},
+}
+
+var mobileUnpairCmd = &cobra.Command{
+	Use:   "unpair",
+	Short: "Forget the sync key and remove the sync location's files",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		location, _, err := vaultDB.GetSetting(companionLocationSetting)
+		if err != nil {
+			handleError(err, "Failed to read the sync location")
+			return
+		}
+		if location == "" {
+			printInfo("No device is paired")
+			return
+		}
+
+		if !force {
+			fmt.Printf("Unpair all devices and remove the synced files in %s? (y/N): ", location)
+			var response string
+			fmt.Scanln(&response)
+			if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
+				fmt.Println("Cancelled")
+				return
+			}
+		}
+
+		if m, err := companion.ReadManifest(location); err == nil {
+			for _, s := range m.Segments {
+				os.Remove(filepath.Join(location, filepath.Base(s.File)))
+			}
+			os.Remove(filepath.Join(location, companion.ManifestFile))
+			os.Remove(location)
+		}
+		for _, name := range []string{companionKeySetting, companionVaultIDSetting, companionLocationSetting, companionStateSetting} {
+			if err := vaultDB.DeleteSetting(name); err != nil {
+				handleError(err, "Failed to remove the sync settings")
+				return
+			}
+		}
+		printInfo("✓ Unpaired; devices can no longer receive updates")
+	},
+}
+
+// companionKey returns the vault's sync key and companion vault ID,
+// creating them if needed or when renew is set
+func companionKey(renew bool) (*companion.Key, string, error) {
+	encoded, _, err := vaultDB.GetSetting(companionKeySetting)
+	if err != nil {
+		return nil, "", err
+	}
+	vaultID, _, err := vaultDB.GetSetting(companionVaultIDSetting)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if encoded != "" && vaultID != "" && !renew {
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(raw) != companion.KeySize {
+			return nil, "", errors.New("stored sync key is corrupted; pair again with --new-key")
+		}
+		var key companion.Key
+		copy(key[:], raw)
+		return &key, vaultID, nil
+	}
+
+	key, err := companion.GenerateKey()
+	if err != nil {
+		return nil, "", err
+	}
+	if vaultID == "" {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return nil, "", err
+		}
+		vaultID = hex.EncodeToString(id)
+		if err := vaultDB.SetSetting(companionVaultIDSetting, vaultID); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := vaultDB.SetSetting(companionKeySetting, base64.StdEncoding.EncodeToString(key[:])); err != nil {
+		return nil, "", err
+	}
+	// Segments written with the old key are unreadable, so start over
+	if err := vaultDB.DeleteSetting(companionStateSetting); err != nil {
+		return nil, "", err
+	}
+	return key, vaultID, nil
+}
+
+// syncCompanion writes the vault's changes since the last sync, or a full
+// snapshot, to location and returns the number of records written or
+// deleted
+func syncCompanion(location, vaultID string, key *companion.Key, full bool) (int, error) {
+	records, err := companionRecords()
+	if err != nil {
+		return 0, err
+	}
+
+	var prev companion.State
+	if !full {
+		data, _, err := vaultDB.GetSetting(companionStateSetting)
+		if err != nil {
+			return 0, err
+		}
+		if data == "" {
+			full = true
+		} else if err := json.Unmarshal([]byte(data), &prev); err != nil {
+			return 0, fmt.Errorf("invalid sync state: %w", err)
+		}
+		// A location emptied or replaced behind our back needs a snapshot
+		if m, err := companion.ReadManifest(location); err != nil || m.VaultID != vaultID || len(m.Segments) == 0 {
+			full = true
+		}
+	}
+
+	upserts, deletes, next := companion.Changes(prev, records)
+	seg := &companion.Segment{Full: full, Upserts: upserts, Deletes: deletes}
+	if full {
+		seg.Upserts, seg.Deletes = records, nil
+	}
+	if seg.Empty() {
+		return 0, nil
+	}
+	if err := companion.Write(location, vaultID, key, seg); err != nil {
+		return 0, err
+	}
+
+	state, err := json.Marshal(next)
+	if err != nil {
+		return 0, err
+	}
+	if err := vaultDB.SetSetting(companionStateSetting, string(state)); err != nil {
+		return 0, err
+	}
+	return len(seg.Upserts) + len(seg.Deletes), nil
+}
+
+// companionRecords reads every secret with references resolved
+func companionRecords() ([]companion.Record, error) {
+	secrets, err := vaultDB.ExportSecrets("")
+	if err != nil {
+		return nil, err
+	}
+	rules, err := loadPolicyRules()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]companion.Record, 0, len(secrets))
+	for _, s := range secrets {
+		defaults := policy.Resolve(rules, s.Key, policy.ParseTags(s.Tags))
+		value, err := refs.Resolve(s.Key, s.Value, policyLookup(&defaults))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve references in '%s': %w", s.Key, err)
+		}
+		r := companion.Record{
+			Key:           s.Key,
+			Value:         value,
+			Tags:          policy.ParseTags(s.Tags),
+			Revision:      s.Revision,
+			UpdatedAt:     secretUpdatedAt(s),
+			ClipboardOnly: defaults.ClipboardOnly,
+		}
+		if s.Notes != nil {
+			r.Notes = *s.Notes
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// secretUpdatedAt returns when a secret's value last changed
+func secretUpdatedAt(s database.Secret) time.Time {
+	if s.UpdatedAt != nil {
+		return s.UpdatedAt.UTC()
+	}
+	return s.CreatedAt.UTC()
+}
+
+func init() {
+	mobilePairCmd.Flags().String("location", "", "Directory inside a synced folder to write to (default: the current one)")
+	mobilePairCmd.Flags().String("remote-location", "", "The location as the device sees it, if different")
+	mobilePairCmd.Flags().Bool("uri", false, "Print the pairing URI instead of a QR code")
+	mobilePairCmd.Flags().Bool("new-key", false, "Replace the sync key, cutting off devices paired before")
+	mobileSyncCmd.Flags().Bool("full", false, "Write a full snapshot, compacting the location")
+
+	mobileCmd.AddCommand(mobilePairCmd)
+	mobileCmd.AddCommand(mobileSyncCmd)
+	mobileCmd.AddCommand(mobileUnpairCmd)
+}
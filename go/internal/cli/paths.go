@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/keyring"
+	"github.com/lockr/go/internal/paths"
+)
+
+// migrateLegacyDirectory moves files out of ~/.lockr into the platform's
+// directories the first time a newer lockr runs. Files that cannot be moved
+// keep being used where they are. The vault is left for migrateLegacyVault,
+// as the config file read next may name it.
+func migrateLegacyDirectory(cmd *cobra.Command) {
+	reportMoves(dirs.MigrateExceptVault())
+
+	if legacy, ok := dirs.Unmigrated(dirs.ConfigFile()); ok && !cmd.Flags().Changed("config") {
+		configPath = legacy
+	}
+}
+
+// migrateLegacyVault moves the vault out of ~/.lockr once every source of
+// the vault path (--vault, LOCKR_VAULT_PATH, the config file, the profile
+// and the workspace) has been applied. It stays where it is while the vault
+// in use or any profile names a file in ~/.lockr, which would otherwise be
+// left naming nothing and get a new, empty vault.
+func migrateLegacyVault() {
+	if dirs.InLegacy(vaultPath) {
+		return
+	}
+	for _, path := range vaultThemes.Profiles() {
+		if dirs.InLegacy(path) {
+			return
+		}
+	}
+	reportMoves(dirs.Migrate())
+
+	if legacy, ok := dirs.Unmigrated(dirs.VaultFile()); ok && vaultPath == dirs.VaultFile() {
+		vaultPath = legacy
+	}
+}
+
+// reportMoves tells the user about files moved out of ~/.lockr
+func reportMoves(moves []paths.Move, err error) {
+	for _, m := range moves {
+		fmt.Fprintf(os.Stderr, "Moved %s to %s\n", m.From, m.To)
+		if m.To == dirs.VaultFile() {
+			moveVaultKeyringEntries(m.From, m.To)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not move files out of %s: %v\n", dirs.Legacy, err)
+	}
+}
+
+// moveVaultKeyringEntries re-files a moved vault's cached derived key under
+// its new path. Its value cache key is dropped along with the old cache.
+func moveVaultKeyringEntries(from, to string) {
+	km := keyring.NewManager()
+	oldID, newID := keyring.VaultID(from), keyring.VaultID(to)
+
+	if key, err := km.GetDerivedKey(oldID); err == nil {
+		if err := km.SaveDerivedKey(newID, key); err == nil {
+			km.DeleteDerivedKey(oldID)
+		}
+	}
+	km.DeleteCacheKey(oldID)
+}
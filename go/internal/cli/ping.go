@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/keyring"
+)
+
+var pingCmd = &cobra.Command{
+	Use:   "ping",
+	Short: "Check whether the vault can be unlocked without prompting",
+	Long: `Check whether the vault can be opened using non-interactive credentials
+(a cached derived key or the password stored in the keyring). Never prompts,
+never creates a session and never reads secrets.
+
+Exits 0 when the vault can be unlocked and 1 otherwise, so it can be used in
+shell prompts and scripts.
+
+Examples:
+  lockr ping                       # Silent; check the exit code
+  lockr ping --print               # Also print "ok" or the reason
+  lockr ping && deploy.sh          # Only run when secrets are available`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		printResult, _ := cmd.Flags().GetBool("print")
+
+		err := pingVault()
+		if err != nil {
+			if printResult {
+				fmt.Println(err)
+			}
+			printVerbose("Ping failed: %v", err)
+			os.Exit(1)
+		}
+
+		if printResult {
+			fmt.Println("ok")
+		}
+	},
+}
+
+// pingVault opens and closes the vault with non-interactive credentials
+func pingVault() error {
+	if _, err := os.Stat(vaultPath); err != nil {
+		return fmt.Errorf("vault not found: %s", vaultPath)
+	}
+	return sessionMgr.Probe(keyring.VaultID(vaultPath))
+}
+
+func init() {
+	pingCmd.Flags().Bool("print", false, "Print \"ok\" or the reason the vault cannot be unlocked")
+}
@@ -0,0 +1,268 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/clipboard"
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/policy"
+)
+
+// policyRulesSetting is the vault setting holding the policy rules
+const policyRulesSetting = "policy.rules"
+
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Set generator and clipboard defaults per tag or namespace",
+	Long: `Policy rules give secrets with a tag, or under a key namespace, their own
+defaults for 'set -g' and for how long 'get' and 'set' leave values on the
+clipboard. Flags given on the command line always win over a policy, except
+--clipboard-only: such values are never printed, 'get --no-copy' is refused
+and they are cleared from the clipboard within 20 seconds. --sensitive makes
+the picker's reveal key ask for the vault password again first.
+
+Rules are stored encrypted in the vault and applied in order; when several
+match, later rules override the settings they share with earlier ones.
+
+Examples:
+  lockr policy add --tag banking --length 32 --symbols --clear 20s
+  lockr policy add --tag wifi --words 6
+  lockr policy add --namespace pin/ --length 8 --no-symbols
+  lockr policy add --tag banking --clipboard-only  # Never print these values
+  lockr policy add --namespace bank/ --sensitive   # Password to reveal
+  lockr policy list                 # Show rules with their numbers
+  lockr policy show bank/main       # Show the defaults that apply to a key
+  lockr policy remove 2             # Delete rule number 2`,
+}
+
+var policyAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a policy rule",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		var rule policy.Rule
+		rule.Tag, _ = cmd.Flags().GetString("tag")
+		rule.Namespace, _ = cmd.Flags().GetString("namespace")
+		rule.Length, _ = cmd.Flags().GetInt("length")
+		rule.Words, _ = cmd.Flags().GetInt("words")
+		clearAfter, _ := cmd.Flags().GetDuration("clear")
+		rule.ClearAfter = policy.Duration(clearAfter)
+		rule.ClipboardOnly, _ = cmd.Flags().GetBool("clipboard-only")
+		rule.Sensitive, _ = cmd.Flags().GetBool("sensitive")
+
+		symbols, _ := cmd.Flags().GetBool("symbols")
+		noSymbols, _ := cmd.Flags().GetBool("no-symbols")
+		if symbols && noSymbols {
+			handleError(fmt.Errorf("--symbols and --no-symbols cannot be used together"), "")
+			return
+		}
+		if symbols || noSymbols {
+			rule.Symbols = &symbols
+		}
+
+		if err := rule.Validate(); err != nil {
+			handleError(err, "Invalid rule")
+			return
+		}
+
+		rules, err := loadPolicyRules()
+		if err != nil {
+			handleError(err, "Failed to read policy rules")
+			return
+		}
+		rules = append(rules, rule)
+		if err := savePolicyRules(rules); err != nil {
+			handleError(err, "Failed to save policy rules")
+			return
+		}
+		clearValueCache(false)
+
+		printInfo("Added rule %d: %s -> %s", len(rules), rule.Selector(), rule.Summary())
+	},
+}
+
+var policyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List policy rules",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		rules, err := loadPolicyRules()
+		if err != nil {
+			handleError(err, "Failed to read policy rules")
+			return
+		}
+		if len(rules) == 0 {
+			fmt.Println("No policy rules; add one with 'lockr policy add'")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "#\tMATCH\tDEFAULTS")
+		for i, rule := range rules {
+			fmt.Fprintf(w, "%d\t%s\t%s\n", i+1, rule.Selector(), rule.Summary())
+		}
+		w.Flush()
+	},
+}
+
+var policyRemoveCmd = &cobra.Command{
+	Use:   "remove <number>",
+	Short: "Remove a policy rule",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		rules, err := loadPolicyRules()
+		if err != nil {
+			handleError(err, "Failed to read policy rules")
+			return
+		}
+
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > len(rules) {
+			handleError(fmt.Errorf("no rule %s; see 'lockr policy list'", args[0]), "")
+			return
+		}
+
+		removed := rules[n-1]
+		rules = append(rules[:n-1], rules[n:]...)
+		if err := savePolicyRules(rules); err != nil {
+			handleError(err, "Failed to save policy rules")
+			return
+		}
+		clearValueCache(false)
+
+		printInfo("Removed rule %d: %s -> %s", n, removed.Selector(), removed.Summary())
+	},
+}
+
+var policyShowCmd = &cobra.Command{
+	Use:   "show <key>",
+	Short: "Show the defaults that apply to a key",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		d := secretDefaults(args[0])
+		fmt.Printf("Defaults for '%s':\n", args[0])
+
+		switch {
+		case d.Words > 0:
+			fmt.Printf("  Generate:        passphrase of %d words\n", d.Words)
+		case d.Length > 0:
+			fmt.Printf("  Generate:        %d characters\n", d.Length)
+		default:
+			fmt.Printf("  Generate:        %d characters (default)\n", defaultSecretLength)
+		}
+		if d.Symbols != nil && !*d.Symbols {
+			fmt.Println("  Symbols:         off")
+		} else {
+			fmt.Println("  Symbols:         on")
+		}
+		if d.ClearAfter > 0 || d.ClipboardOnly {
+			fmt.Printf("  Clipboard clear: %v\n", d.ClearDelay(clipboard.DefaultClearDelay))
+		} else {
+			fmt.Printf("  Clipboard clear: %v (default)\n", clipboard.DefaultClearDelay)
+		}
+		if d.ClipboardOnly {
+			fmt.Println("  Clipboard only:  yes (never printed)")
+		}
+		if d.Sensitive {
+			fmt.Println("  Sensitive:       yes (password to reveal)")
+		}
+	},
+}
+
+// loadPolicyRules reads the vault's policy rules
+func loadPolicyRules() ([]policy.Rule, error) {
+	data, _, err := vaultDB.GetSetting(policyRulesSetting)
+	if err != nil {
+		return nil, err
+	}
+	return policy.Decode(data)
+}
+
+// savePolicyRules replaces the vault's policy rules
+func savePolicyRules(rules []policy.Rule) error {
+	if len(rules) == 0 {
+		return vaultDB.DeleteSetting(policyRulesSetting)
+	}
+	data, err := policy.Encode(rules)
+	if err != nil {
+		return err
+	}
+	return vaultDB.SetSetting(policyRulesSetting, data)
+}
+
+// secretDefaults resolves the policy defaults for key, using the tags of
+// the existing secret if there is one
+func secretDefaults(key string) policy.Defaults {
+	var tags *string
+	if secret, err := vaultDB.PeekSecret(key); err == nil {
+		tags = secret.Tags
+	} else if err != database.ErrKeyNotFound {
+		printVerbose("Could not read tags of '%s': %v", key, err)
+	}
+	return resolvePolicy(key, tags)
+}
+
+// resolvePolicy resolves the policy defaults for key with the given stored
+// tags. Broken rules are reported and ignored.
+func resolvePolicy(key string, tags *string) policy.Defaults {
+	rules, err := loadPolicyRules()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring policy rules: %v\n", err)
+		return policy.Defaults{}
+	}
+	return policy.Resolve(rules, key, policy.ParseTags(tags))
+}
+
+// applyClipboardPolicy uses the policy's clipboard clear delay, if any, for
+// the rest of this command
+func applyClipboardPolicy(d policy.Defaults) {
+	if clipboardMgr == nil {
+		return
+	}
+	if delay := d.ClearDelay(clipboardMgr.ClearDelay()); delay != clipboardMgr.ClearDelay() {
+		clipboardMgr.SetClearDelay(delay)
+		printVerbose("Clipboard clear delay set to %v by policy", delay)
+	}
+}
+
+func init() {
+	policyAddCmd.Flags().String("tag", "", "Apply to secrets with this tag")
+	policyAddCmd.Flags().String("namespace", "", "Apply to keys starting with this prefix (e.g. bank/)")
+	policyAddCmd.Flags().Int("length", 0, "Length of generated secrets")
+	policyAddCmd.Flags().Int("words", 0, "Generate diceware passphrases with this many words")
+	policyAddCmd.Flags().Bool("symbols", false, "Include symbols in generated secrets")
+	policyAddCmd.Flags().Bool("no-symbols", false, "Generate letters and digits only")
+	policyAddCmd.Flags().Duration("clear", 0, "Clear the clipboard after this long (e.g. 20s)")
+	policyAddCmd.Flags().Bool("clipboard-only", false, "Never print values; only copy them, clearing within 20s")
+	policyAddCmd.Flags().Bool("sensitive", false, "Ask for the vault password before the picker reveals values")
+
+	policyCmd.AddCommand(policyAddCmd)
+	policyCmd.AddCommand(policyListCmd)
+	policyCmd.AddCommand(policyRemoveCmd)
+	policyCmd.AddCommand(policyShowCmd)
+}
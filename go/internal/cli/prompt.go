@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/keyring"
+)
+
+var promptSegmentCmd = &cobra.Command{
+	Use:   "prompt-segment",
+	Short: "Print a compact vault status for shell prompts",
+	Long: `Print a short status string for shell prompts such as starship or
+powerlevel10k. Only cheap local state is read: the vault is never opened,
+no password is ever prompted for and the command always exits 0.
+
+The vault is reported as "unlocked" when it can be opened without a prompt
+(a cached derived key or a keyring password is available) and "locked"
+otherwise. Nothing is printed when the vault does not exist.
+
+The --format template supports these placeholders:
+  {state}   locked or unlocked
+  {vault}   vault file name without extension
+
+Examples:
+  lockr prompt-segment                          # "locked" or "unlocked"
+  lockr prompt-segment --format '[{vault}:{state}]'
+
+  # starship.toml
+  [custom.lockr]
+  command = "lockr prompt-segment"
+  when = true`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+
+		if _, err := os.Stat(vaultPath); err != nil {
+			return
+		}
+
+		state := "locked"
+		if hasNonInteractiveCredentials() {
+			state = "unlocked"
+		}
+
+		name := strings.TrimSuffix(filepath.Base(vaultPath), filepath.Ext(vaultPath))
+		segment := strings.NewReplacer("{state}", state, "{vault}", name).Replace(format)
+		fmt.Println(segment)
+	},
+}
+
+// hasNonInteractiveCredentials reports whether the keyring holds something
+// that unlocks the vault without a prompt. The vault itself is not opened.
+func hasNonInteractiveCredentials() bool {
+	km := sessionMgr.GetKeyringManager()
+	if km.HasDerivedKey(keyring.VaultID(vaultPath)) {
+		return true
+	}
+	return km.IsEnabled() && km.HasPassword()
+}
+
+func init() {
+	promptSegmentCmd.Flags().String("format", "{state}", "Output template")
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/refs"
+)
+
+// recentCmd lists the most recently accessed secrets
+var recentCmd = &cobra.Command{
+	Use:   "recent",
+	Short: "List the most recently accessed keys",
+	Long: `Show the keys of the secrets you retrieved most recently, newest first.
+
+Examples:
+  lockr recent             # Show the 10 most recently accessed keys
+  lockr recent -n 25       # Show the 25 most recently accessed keys`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		count, _ := cmd.Flags().GetInt("count")
+		secrets, err := vaultDB.RecentSecrets(count)
+		if err != nil {
+			handleError(err, "Failed to list recent secrets")
+			return
+		}
+
+		if len(secrets) == 0 {
+			fmt.Println("No secrets have been accessed yet")
+			return
+		}
+
+		for i, secret := range secrets {
+			fmt.Printf("%2d. %-30s (accessed %s, %d times)\n",
+				i+1,
+				secret.Key,
+				formatAge(secret.LastAccessed),
+				secret.AccessCount)
+		}
+	},
+}
+
+// lastCmd re-copies the most recently retrieved secret
+var lastCmd = &cobra.Command{
+	Use:   "last",
+	Short: "Copy the most recently retrieved secret again",
+	Long: `Retrieve the secret you accessed most recently and copy it to the clipboard
+again, without searching for it.
+
+Examples:
+  lockr last               # Copy the last retrieved secret again
+  lockr last --no-copy     # Print it instead of copying
+  lockr last --show        # Same as --no-copy`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		recent, err := vaultDB.RecentSecrets(1)
+		if err != nil {
+			handleError(err, "Failed to find last secret")
+			return
+		}
+
+		if len(recent) == 0 {
+			fmt.Println("No secrets have been accessed yet")
+			return
+		}
+
+		key := recent[0].Key
+		secret, err := vaultDB.GetSecret(key)
+		if err != nil {
+			handleError(err, fmt.Sprintf("Failed to get secret '%s'", key))
+			return
+		}
+
+		auditSecretAccess(key)
+		defaults := resolvePolicy(key, secret.Tags)
+		value, err := refs.Resolve(secret.Key, secret.Value, policyLookup(&defaults))
+		if err != nil {
+			handleError(err, fmt.Sprintf("Failed to resolve references in '%s'", key))
+			return
+		}
+		applyClipboardPolicy(defaults)
+
+		fmt.Printf("Last secret: %s\n", key)
+		noCopy, _ := cmd.Flags().GetBool("no-copy")
+		if show, _ := cmd.Flags().GetBool("show"); show {
+			noCopy = true
+		}
+		if err := deliverSecret(value, noCopy, defaults); err != nil {
+			handleError(err, fmt.Sprintf("Cannot deliver secret '%s'", key))
+			return
+		}
+
+		printVerbose("Retrieved secret for key '%s' (accessed %d times)", key, secret.AccessCount)
+	},
+}
+
+// formatAge renders a timestamp relative to now (e.g. "5m ago")
+func formatAge(t time.Time) string {
+	age := time.Since(t)
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(age.Hours()/24))
+	}
+}
+
+func init() {
+	recentCmd.Flags().IntP("count", "n", 10, "Number of keys to show")
+	lastCmd.Flags().Bool("no-copy", false, "Don't copy secret to clipboard")
+	lastCmd.Flags().Bool("show", false, "Print the secret instead of copying it (same as --no-copy)")
+}
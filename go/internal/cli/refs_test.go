@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lockr/go/internal/policy"
+	"github.com/lockr/go/internal/refs"
+	"github.com/lockr/go/pkg/lockrtest"
+)
+
+func TestPolicyLookup_ClipboardOnlyReference(t *testing.T) {
+	test := lockrtest.New(t, map[string]string{
+		"bank/pin": "1234",
+		"app/pin":  "${ref:bank/pin}",
+	})
+	vaultDB = test.VaultDatabase
+	defer func() { vaultDB = nil }()
+	require.NoError(t, savePolicyRules([]policy.Rule{{Namespace: "bank/", ClipboardOnly: true}}))
+
+	secret, err := vaultDB.PeekSecret("app/pin")
+	require.NoError(t, err)
+	defaults := resolvePolicy(secret.Key, secret.Tags)
+	require.False(t, defaults.ClipboardOnly)
+
+	value, err := refs.Resolve(secret.Key, secret.Value, policyLookup(&defaults))
+	require.NoError(t, err)
+	assert.True(t, defaults.ClipboardOnly)
+
+	// get --show delivers without copying, which a clipboard-only value refuses
+	assert.ErrorIs(t, deliverSecret(value, true, defaults), policy.ErrClipboardOnly)
+
+	// Programs are refused the value outright
+	_, err = refs.Resolve(secret.Key, secret.Value, checkedLookup(nil, ""))
+	assert.ErrorIs(t, err, policy.ErrClipboardOnly)
+}
@@ -9,19 +9,32 @@ import (
 	"golang.org/x/term"
 
 	"github.com/lockr/go/internal/clipboard"
+	"github.com/lockr/go/internal/crypto"
 	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/keyring"
+	"github.com/lockr/go/internal/paths"
 	"github.com/lockr/go/internal/session"
 )
 
 var (
 	// Global flags
-	vaultPath  string
-	configPath string
-	verbose    bool
-	force      bool
+	vaultPath   string
+	configPath  string
+	verbose     bool
+	force       bool
+	offline     bool
+	noClipboard bool
+	quiet       bool
+	statusFD    int
+
+	// clipboardBackend names the clipboard backend to use, or "auto"
+	clipboardBackend string
+
+	// Directories for lockr's files on this platform
+	dirs = paths.Default()
 
 	// Global instances
-	vaultDB      *database.VaultDatabase
+	vaultDB      database.VaultStore
 	sessionMgr   *session.Manager
 	clipboardMgr *clipboard.Manager
 )
@@ -48,16 +61,54 @@ Examples:
   lockr list                   # List all keys
   lockr list api               # Search for keys matching "api"
   lockr delete -f mykey        # Force delete without prompt
-  lockr status                 # Show session status`,
+  lockr status                 # Show session status
+
+Every flag can also be set with an environment variable: LOCKR_<FLAG> for all
+commands, or LOCKR_<COMMAND>_<FLAG> for one command (dashes become
+underscores), e.g. LOCKR_VAULT=~/work.lockr or LOCKR_LIST_FORMAT=json.
+Flags on the command line win over the environment, which wins over the
+config file. LOCKR_KEYRING_DISABLED=1 turns off keyring integration.
+
+Front-ends that wrap lockr can pass --status-fd N to receive progress events
+for long operations (import, merge, rekey) as JSON lines on descriptor N,
+e.g. lockr import --env .env --status-fd 3 3>progress.jsonl
+
+--timings prints on stderr how long the command spent opening the vault,
+unlocking it (key derivation), in vault reads and writes, searching and
+copying to the clipboard. Nothing is sent anywhere.`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		startTimings()
+
+		// Fill in flags from LOCKR_* variables before anything reads them
+		if err := applyEnvOverrides(cmd); err != nil {
+			handleError(err, "")
+			return
+		}
+
+		// Move files left in ~/.lockr by earlier versions
+		migrateLegacyDirectory(cmd)
+
+		// Options from the config file fill in flags not given otherwise
+		applyConfigSettings(cmd)
+
+		// A profile chosen with lockr use picks the default vault
+		selectProfile(cmd)
+
+		// Pick up a project vault before anything opens the vault
+		selectWorkspace(cmd)
+
+		// Only now is it known whether the legacy vault is still named
+		migrateLegacyVault()
+
 		// Initialize global components
 		initializeGlobals()
 	},
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
 		// Cleanup
 		if sessionMgr != nil {
-			sessionMgr.Logout()
+			sessionMgr.Detach()
 		}
+		printTimings()
 	},
 	CompletionOptions: cobra.CompletionOptions{
 		DisableDefaultCmd: true,
@@ -85,6 +136,12 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", getDefaultConfigPath(), "Path to configuration file")
 	rootCmd.PersistentFlags().BoolVarP(&force, "force", "f", false, "Force operation without confirmation")
 	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Print only requested data and errors")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Disable every feature that uses the network")
+	rootCmd.PersistentFlags().BoolVar(&noClipboard, "no-clipboard", false, "Never use the clipboard; get prints values only with --show")
+	rootCmd.PersistentFlags().StringVar(&clipboardBackend, "clipboard-backend", clipboard.Auto, "Clipboard to use: auto, or one of wl-copy, xclip, xsel, pbcopy, powershell, osc52")
+	rootCmd.PersistentFlags().BoolVar(&showTimings, "timings", false, "Print how long each step of the command took")
+	rootCmd.PersistentFlags().IntVar(&statusFD, "status-fd", 0, "Write JSON progress events for import, merge and rekey to this file descriptor")
 
 	// Define command groups
 	rootCmd.AddGroup(&cobra.Group{ID: "management", Title: "Management Commands:"})
@@ -92,92 +149,304 @@ func init() {
 
 	// Secret operations
 	getCmd.GroupID = "secret"
+	copyCmd.GroupID = "secret"
+	showCmd.GroupID = "secret"
 	setCmd.GroupID = "secret"
 	deleteCmd.GroupID = "secret"
+	lastCmd.GroupID = "secret"
 
 	// Management commands
 	initCmd.GroupID = "management"
 	listCmd.GroupID = "management"
+	recentCmd.GroupID = "management"
 	statusCmd.GroupID = "management"
 	versionCmd.GroupID = "management"
 	keyringCmd.GroupID = "management"
 	rekeyCmd.GroupID = "management"
+	agentCmd.GroupID = "management"
+	grantCmd.GroupID = "secret"
+	generateCmd.GroupID = "secret"
+	redeemCmd.GroupID = "secret"
+	decommissionCmd.GroupID = "management"
+	sessionsCmd.GroupID = "management"
+	authLogCmd.GroupID = "management"
+	logCmd.GroupID = "management"
+	cloneCmd.GroupID = "management"
+	travelCmd.GroupID = "management"
+	emergencyCmd.GroupID = "management"
+	pingCmd.GroupID = "management"
+	promptSegmentCmd.GroupID = "management"
+	statsCmd.GroupID = "management"
+	integrityCmd.GroupID = "management"
+	selfUpdateCmd.GroupID = "management"
+	entrypointCmd.GroupID = "management"
+	policyCmd.GroupID = "management"
+	keysCmd.GroupID = "management"
+	watchCmd.GroupID = "management"
+	workspaceCmd.GroupID = "management"
+	aclCmd.GroupID = "management"
+	mergeCmd.GroupID = "management"
+	importCmd.GroupID = "management"
+	exportCmd.GroupID = "management"
+	configCmd.GroupID = "management"
+	lspCmd.GroupID = "management"
+	launcherCmd.GroupID = "management"
+	launcherCopyCmd.GroupID = "secret"
+	mobileCmd.GroupID = "management"
+	snapshotCmd.GroupID = "management"
+	editCmd.GroupID = "secret"
+	diffCmd.GroupID = "management"
+	lintCmd.GroupID = "management"
+	discoverCmd.GroupID = "management"
+	useCmd.GroupID = "management"
+	serveCmd.GroupID = "management"
+	tagCmd.GroupID = "secret"
+	totpCmd.GroupID = "secret"
+	historyCmd.GroupID = "secret"
+	hintCmd.GroupID = "management"
+	restoreCmd.GroupID = "secret"
 
 	// Add subcommands
 	rootCmd.AddCommand(getCmd)
+	rootCmd.AddCommand(copyCmd)
+	rootCmd.AddCommand(showCmd)
 	rootCmd.AddCommand(setCmd)
+	rootCmd.AddCommand(updateCmd)
 	rootCmd.AddCommand(deleteCmd)
 	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(recentCmd)
+	rootCmd.AddCommand(lastCmd)
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(keyringCmd)
 	rootCmd.AddCommand(rekeyCmd)
+	rootCmd.AddCommand(agentCmd)
+	rootCmd.AddCommand(grantCmd)
+	rootCmd.AddCommand(generateCmd)
+	rootCmd.AddCommand(redeemCmd)
+	rootCmd.AddCommand(decommissionCmd)
+	rootCmd.AddCommand(sessionsCmd)
+	rootCmd.AddCommand(authLogCmd)
+	rootCmd.AddCommand(logCmd)
+	rootCmd.AddCommand(cloneCmd)
+	rootCmd.AddCommand(travelCmd)
+	rootCmd.AddCommand(emergencyCmd)
+	rootCmd.AddCommand(pingCmd)
+	rootCmd.AddCommand(promptSegmentCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(integrityCmd)
+	rootCmd.AddCommand(selfUpdateCmd)
+	rootCmd.AddCommand(entrypointCmd)
+	rootCmd.AddCommand(policyCmd)
+	rootCmd.AddCommand(keysCmd)
+	rootCmd.AddCommand(watchCmd)
+	rootCmd.AddCommand(aclCmd)
+	rootCmd.AddCommand(tokenCmd)
+	rootCmd.AddCommand(mergeCmd)
+	rootCmd.AddCommand(workspaceCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(lspCmd)
+	rootCmd.AddCommand(launcherCmd)
+	rootCmd.AddCommand(launcherCopyCmd)
+	rootCmd.AddCommand(mobileCmd)
+	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(editCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(lintCmd)
+	rootCmd.AddCommand(discoverCmd)
+	rootCmd.AddCommand(useCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(archiveCmd)
+	rootCmd.AddCommand(unarchiveCmd)
+	rootCmd.AddCommand(tagCmd)
+	rootCmd.AddCommand(totpCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(hintCmd)
+	rootCmd.AddCommand(restoreCmd)
 }
 
 // initializeGlobals initializes the global components
 func initializeGlobals() {
 	// Initialize database
-	vaultDB = database.NewVaultDatabase(vaultPath)
+	stopOpen := timePhase("open")
+	store, err := database.OpenStore(vaultEngine(vaultPath), vaultPath)
+	stopOpen()
+	if err != nil {
+		handleError(err, "Failed to open vault")
+		return
+	}
+	if durable, ok := store.(database.DurableStore); ok {
+		durable.SetDurability(vaultDurability)
+	}
+	vaultDB = store
+	if showTimings {
+		vaultDB = timedStore{store}
+	}
 
 	// Initialize session manager
 	sessionMgr = session.NewManager(vaultDB)
+	sessionMgr.SetSessionFile(dirs.SessionFile(keyring.VaultID(vaultPath)))
+	// The vault key kept in the session file is sealed like the value
+	// cache, and only kept once the user chose to cache the vault's key
+	// with 'lockr keyring cache-key'
+	sessionMgr.SetSessionKey(func() (crypto.MasterKey, error) {
+		km := sessionMgr.GetKeyringManager()
+		vaultID := keyring.VaultID(vaultPath)
+		if !km.HasDerivedKey(vaultID) {
+			return nil, keyring.ErrDerivedKeyNotFound
+		}
+		return km.GetOrCreateCacheKey(vaultID)
+	})
+	if sessionTimeout > 0 {
+		sessionMgr.SetTimeout(sessionTimeout)
+	}
+	if envEnabled(keyringDisabledEnv) || !keyringEnabled {
+		sessionMgr.GetKeyringManager().Disable()
+	}
 
 	// Initialize clipboard manager
-	if clipboard.IsSupported() {
-		clipboardMgr = clipboard.NewManager()
+	if !noClipboard {
+		backend, err := clipboard.SelectBackend(clipboardBackend)
+		if err != nil {
+			handleError(err, "")
+			return
+		}
+		if backend != nil {
+			clipboardMgr = clipboard.NewManagerWithBackend(backend)
+			clipboardMgr.SetQuiet(quiet)
+			if clipboardTimeout > 0 {
+				clipboardMgr.SetClearDelay(clipboardTimeout)
+			}
+		}
 	}
 
 	if verbose {
 		fmt.Printf("Vault path: %s\n", vaultPath)
 		fmt.Printf("Config path: %s\n", configPath)
 		fmt.Printf("Clipboard enabled: %t\n", clipboardMgr != nil)
+		fmt.Printf("Offline mode: %t\n", offline)
 	}
 }
 
 // getDefaultVaultPath returns the default path for the vault database
 func getDefaultVaultPath() string {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "vault.lockr"
-	}
-	return filepath.Join(homeDir, ".lockr", "vault.lockr")
+	return dirs.VaultFile()
 }
 
 // getDefaultConfigPath returns the default path for the configuration file
 func getDefaultConfigPath() string {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "config.yml"
-	}
-	return filepath.Join(homeDir, ".lockr", "config.yml")
+	return dirs.ConfigFile()
 }
 
-// ensureAuthenticated ensures the user is authenticated, prompting if necessary
+// ensureAuthenticated ensures the user is authenticated, prompting if necessary.
+// Commands that wait on the user (an editor, a picker, a prompt) call it
+// again before touching the vault, so a session that timed out meanwhile is
+// unlocked again instead of failing the command.
 func ensureAuthenticated() error {
+	if sessionMgr.Expired() {
+		// A long operation outlived the session; unlock again and carry on
+		// rather than failing halfway through
+		fmt.Fprintln(os.Stderr, "Session expired; unlocking the vault again.")
+	}
 	if sessionMgr.IsAuthenticated() {
 		// Refresh session on each operation
-		return sessionMgr.RefreshSession()
+		err := sessionMgr.RefreshSession()
+		if err != database.ErrSessionExpired {
+			return err
+		}
+		// It expired between the two checks; unlock again below
 	}
 
-	// Try keyring authentication first
-	err := sessionMgr.TryAuthenticateWithKeyring()
+	// An earlier command's session carries on while it lasts
+	stopUnlock := timePhase("unlock")
+	err := sessionMgr.Resume()
+	stopUnlock()
+	if err == nil {
+		printVerbose("Resumed session")
+		afterAuthentication()
+		return nil
+	}
+
+	// A running agent holding the key skips unlocking altogether
+	if unlockWithAgent() {
+		printVerbose("Authenticated using the agent")
+		afterAuthentication()
+		return nil
+	}
+
+	// A cached derived key skips the slow key derivation entirely
+	stopUnlock = timePhase("unlock")
+	err = sessionMgr.TryAuthenticateWithCachedKey(keyring.VaultID(vaultPath))
+	stopUnlock()
+	if err == nil {
+		printVerbose("Authenticated using cached derived key")
+		shareWithAgent("")
+		afterAuthentication()
+		return nil
+	}
+
+	// Try keyring authentication next
+	stopUnlock = timePhase("unlock")
+	err = sessionMgr.TryAuthenticateWithKeyring()
+	stopUnlock()
 	if err == nil {
 		printVerbose("Authenticated using keyring")
+		shareWithAgent("")
+		afterAuthentication()
 		return nil
 	}
 
 	// If keyring auth failed (not available or wrong password), prompt for password
 	printVerbose("Keyring authentication failed: %v", err)
-	password, err := promptPassword("Enter vault password: ")
-	if err != nil {
-		return fmt.Errorf("failed to read password: %w", err)
-	}
+	for failures := 0; ; failures++ {
+		password, err := promptPassword(vaultPrompt("Enter vault password: "))
+		if err != nil {
+			return fmt.Errorf("failed to read password: %w", err)
+		}
 
-	if err := sessionMgr.Authenticate(password); err != nil {
-		return fmt.Errorf("authentication failed: %w", err)
+		stopUnlock = timePhase("unlock")
+		err = sessionMgr.Authenticate(password)
+		stopUnlock()
+		if err == nil {
+			shareWithAgent(password)
+			break
+		}
+		// Like sudo, a terminal gets three tries before giving up
+		if err != database.ErrAuthenticationFailed || failures == maxPasswordAttempts-1 || !term.IsTerminal(int(os.Stdin.Fd())) {
+			return fmt.Errorf("authentication failed: %w", err)
+		}
+
+		fmt.Fprintln(os.Stderr, "Wrong password, try again.")
+		if failures+1 == hintAfterFailures {
+			if hint := passwordHint(); hint != "" {
+				fmt.Fprintf(os.Stderr, "Hint: %s\n", hint)
+			}
+		}
 	}
 
+	afterAuthentication()
+	return nil
+}
+
+// maxPasswordAttempts is how many passwords ensureAuthenticated accepts on
+// a terminal before failing
+const maxPasswordAttempts = 3
+
+// afterAuthentication runs housekeeping that should happen whenever the
+// vault is unlocked
+func afterAuthentication() {
+	emergencyCheckIn()
+}
+
+// requireNetwork returns an error if feature may not use the network because
+// --offline is set. Every network-touching feature must call it first.
+func requireNetwork(feature string) error {
+	if offline {
+		return fmt.Errorf("%s needs network access and is disabled by --offline", feature)
+	}
 	return nil
 }
 
@@ -207,6 +476,7 @@ func handleError(err error, message string) {
 	} else {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 	}
+	printTimings()
 
 	// Specific handling for common errors
 	switch err {
@@ -218,11 +488,21 @@ func handleError(err error, message string) {
 		os.Exit(4)
 	case database.ErrSessionExpired:
 		os.Exit(5)
+	case database.ErrRevisionMismatch:
+		os.Exit(6)
 	default:
 		os.Exit(1)
 	}
 }
 
+// printInfo prints an informational message, such as a confirmation that
+// something was stored, unless quiet mode is enabled
+func printInfo(format string, args ...interface{}) {
+	if !quiet {
+		fmt.Printf(format+"\n", args...)
+	}
+}
+
 // printVerbose prints verbose output if verbose mode is enabled
 func printVerbose(format string, args ...interface{}) {
 	if verbose {
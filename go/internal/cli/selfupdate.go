@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/update"
+)
+
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Update lockr to the latest release",
+	Long: `Check the GitHub releases feed for a newer lockr and install it.
+
+The release's checksums.txt must carry a valid minisign signature from the
+release key built into this binary, and the downloaded archive must match its
+checksum. The running executable is then replaced atomically.
+
+Examples:
+  lockr self-update                  # Install the latest stable release
+  lockr self-update --check          # Only report whether an update exists
+  lockr self-update --channel beta   # Include pre-releases`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := requireNetwork("self-update"); err != nil {
+			handleError(err, "")
+			return
+		}
+
+		channel, _ := cmd.Flags().GetString("channel")
+		checkOnly, _ := cmd.Flags().GetBool("check")
+
+		client, err := update.NewClient()
+		if err != nil {
+			handleError(err, "Self-update unavailable")
+			return
+		}
+
+		release, err := client.Latest(channel)
+		if err != nil {
+			handleError(err, "Failed to check for updates")
+			return
+		}
+
+		current := getVersion()
+		if update.CompareVersions(release.Version(), current) <= 0 && !force {
+			fmt.Printf("lockr %s is up to date (%s channel)\n", current, channel)
+			return
+		}
+
+		fmt.Printf("Update available: %s -> %s\n", current, release.Version())
+		if checkOnly {
+			return
+		}
+
+		if !force {
+			fmt.Print("Install it now? (y/N): ")
+			var response string
+			fmt.Scanln(&response)
+			if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
+				fmt.Println("Cancelled")
+				return
+			}
+		}
+
+		binary, err := client.Download(release, runtime.GOOS, runtime.GOARCH)
+		if err != nil {
+			handleError(err, "Failed to download update")
+			return
+		}
+		printVerbose("Verified signature and checksum of %s", update.AssetName(release.Version(), runtime.GOOS, runtime.GOARCH))
+
+		executable, err := os.Executable()
+		if err != nil {
+			handleError(err, "Failed to locate the running executable")
+			return
+		}
+
+		if err := update.ReplaceExecutable(executable, binary); err != nil {
+			handleError(err, "Failed to install update")
+			return
+		}
+
+		printInfo("Updated lockr to %s", release.Version())
+	},
+}
+
+func init() {
+	selfUpdateCmd.Flags().String("channel", update.ChannelStable, "Release channel: stable, beta")
+	selfUpdateCmd.Flags().Bool("check", false, "Only check whether an update is available")
+}
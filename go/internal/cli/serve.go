@@ -0,0 +1,430 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/api"
+	"github.com/lockr/go/internal/authz"
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/policy"
+	"github.com/lockr/go/internal/strength"
+)
+
+// apiTokensSetting is the vault setting holding the API tokens' hashes
+const apiTokensSetting = "api.tokens"
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the vault to local programs over a REST API on a unix socket",
+	Long: `Serve the vault as a JSON REST API on a unix socket, so scripts, editors and
+GUI front-ends can read and write secrets without running lockr for each
+one. The socket is only reachable by you.
+
+Endpoints:
+  GET    /v1/secrets?pattern=glob   Keys, tags and revisions; never values
+  GET    /v1/secrets/<key>          {"key", "value", "revision"}
+  PUT    /v1/secrets/<key>          Body {"value": "..."}; 201 when created
+  DELETE /v1/secrets/<key>
+
+Every request needs an Authorization: Bearer header with a token from
+'lockr serve token create <name>'. A token named ci acts as the client
+rest:ci, and access rules ('lockr acl') decide which keys it may list, get,
+set and delete; with no matching rule everything is denied. Repeated wrong
+tokens lock the process sending them out for a few minutes; other processes
+using the socket are not affected.
+
+--print-openapi prints an OpenAPI 3 description of these endpoints, for
+generating typed clients, and exits without opening the vault.
+
+The vault is unlocked when the server starts. Each request checks the
+session first: one revoked with 'lockr sessions revoke' stops the server
+from answering, and one that timed out is unlocked again only through the
+agent, a cached key or the keyring. Reads, writes and denials are recorded
+in the audit log under the client's name ('lockr token usage ci').
+
+Examples:
+  lockr serve token create ci
+  lockr acl add --client rest:ci --keys 'ci/*' --ops get,list
+  lockr serve
+  curl --unix-socket "$XDG_RUNTIME_DIR/lockr/api.sock" \
+       -H "Authorization: Bearer $TOKEN" http://lockr/v1/secrets/ci/db`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if printOpenAPI, _ := cmd.Flags().GetBool("print-openapi"); printOpenAPI {
+			doc, err := api.OpenAPI(getVersion())
+			if err != nil {
+				handleError(err, "Failed to describe the API")
+				return
+			}
+			fmt.Println(string(doc))
+			return
+		}
+
+		socket, _ := cmd.Flags().GetString("socket")
+		if socket == "" {
+			socket = apiSocket()
+		}
+
+		sessionMgr.SetClient(database.ClientREST)
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+		if tokens, err := loadAPITokens(); err != nil {
+			handleError(err, "Failed to read API tokens")
+			return
+		} else if len(tokens) == 0 {
+			fmt.Fprintln(os.Stderr, "Warning: no API tokens yet; create one with 'lockr serve token create <name>'")
+		}
+
+		l, err := api.Listen(socket)
+		if err != nil {
+			handleError(err, "Failed to listen")
+			return
+		}
+		server := &http.Server{
+			Handler:           api.NewHandler(&apiBackend{}),
+			ConnContext:       api.ConnContext,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdown)
+		}()
+
+		printInfo("Serving %s on %s; stop with Ctrl-C", vaultPath, socket)
+		if err := server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			handleError(err, "Server failed")
+			return
+		}
+		os.Remove(socket)
+		printInfo("Stopped")
+	},
+}
+
+var serveTokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage the tokens clients of lockr serve authenticate with",
+}
+
+var serveTokenCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create an API token and print it once",
+	Long: `Create a token for the client rest:<name> and print it. Only a hash is
+stored, so the token cannot be shown again; revoke it and create another if
+it is lost. Grant it keys with 'lockr acl add --client rest:<name>'.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+		tokens, err := loadAPITokens()
+		if err != nil {
+			handleError(err, "Failed to read API tokens")
+			return
+		}
+		if _, exists := tokens.Find(args[0]); exists {
+			handleError(fmt.Errorf("a token named %q exists; revoke it first to replace it", args[0]), "")
+			return
+		}
+
+		token, secret, err := api.NewToken(args[0])
+		if err != nil {
+			handleError(err, "")
+			return
+		}
+		if err := saveAPITokens(append(tokens, token)); err != nil {
+			handleError(err, "Failed to save API token")
+			return
+		}
+
+		fmt.Println(secret)
+		printInfo("✓ Token for %s created; store it now, it is not shown again", token.Client())
+		printInfo("  Allow it keys with: lockr acl add --client %s --keys '<glob>' --ops get,list", token.Client())
+	},
+}
+
+var serveTokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List API tokens",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+		tokens, err := loadAPITokens()
+		if err != nil {
+			handleError(err, "Failed to read API tokens")
+			return
+		}
+		if len(tokens) == 0 {
+			printInfo("No API tokens")
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, t := range tokens {
+			fmt.Fprintf(w, "  %s\t%s\tcreated %s\n", t.Name, t.Client(), t.CreatedAt.Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+	},
+}
+
+var serveTokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <name>",
+	Short: "Revoke an API token; a running server refuses it from the next request",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+		tokens, err := loadAPITokens()
+		if err != nil {
+			handleError(err, "Failed to read API tokens")
+			return
+		}
+		if _, ok := tokens.Find(args[0]); !ok {
+			handleError(fmt.Errorf("no token named %q", args[0]), "")
+			return
+		}
+		if err := saveAPITokens(tokens.Without(args[0])); err != nil {
+			handleError(err, "Failed to save API tokens")
+			return
+		}
+		printInfo("✓ Token %s revoked", args[0])
+	},
+}
+
+// apiBackend answers API requests from the vault. Requests are served one
+// at a time, since the vault connection and session are shared.
+type apiBackend struct {
+	mu sync.Mutex
+	// revoked is set once the server's session was revoked; the server
+	// then stays locked rather than unlocking itself again
+	revoked bool
+}
+
+// Authenticate looks the token up among the vault's tokens, read afresh so
+// a revoked token stops working at once
+func (b *apiBackend) Authenticate(token string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.resume(); err != nil {
+		return "", err
+	}
+	tokens, err := loadAPITokens()
+	if err != nil {
+		return "", err
+	}
+	t, ok := tokens.Match(token)
+	if !ok {
+		return "", api.ErrUnauthorized
+	}
+	return t.Client(), nil
+}
+
+// List returns the secrets matching pattern that client may list
+func (b *apiBackend) List(client, pattern string) ([]api.Entry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	az, err := b.begin(client)
+	if err != nil {
+		return nil, err
+	}
+	if pattern == "" {
+		pattern = "*"
+	}
+	secrets, _, err := vaultDB.SearchSecretsGlob(pattern, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []api.Entry
+	for _, s := range secrets {
+		if !az.Allowed(client, authz.OpList, s.Key) {
+			continue
+		}
+		entries = append(entries, api.Entry{Key: s.Key, Tags: policy.ParseTags(s.Tags), Revision: s.Revision, CreatedAt: s.CreatedAt})
+	}
+	return entries, nil
+}
+
+// Get returns the value of key with references resolved. Clipboard-only
+// secrets are never served, nor are values referencing one or referencing a
+// secret the client may not get.
+func (b *apiBackend) Get(client, key string) (api.Secret, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	az, err := b.begin(client)
+	if err != nil {
+		return api.Secret{}, err
+	}
+	if err := az.Check(client, authz.OpGet, key); err != nil {
+		return api.Secret{}, err
+	}
+
+	secret, err := vaultDB.PeekSecret(key)
+	if err != nil {
+		return api.Secret{}, err
+	}
+	if resolvePolicy(secret.Key, secret.Tags).ClipboardOnly {
+		return api.Secret{}, policy.ErrClipboardOnly
+	}
+	value, _, err := readSecretWith(key, checkedLookup(az, client))
+	if err != nil {
+		return api.Secret{}, err
+	}
+	return api.Secret{Key: secret.Key, Value: value, Revision: secret.Revision}, nil
+}
+
+// Set stores value under key, creating the secret if needed
+func (b *apiBackend) Set(client, key, value string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	az, err := b.begin(client)
+	if err != nil {
+		return false, err
+	}
+	if err := az.Check(client, authz.OpSet, key); err != nil {
+		return false, err
+	}
+
+	err = vaultDB.CreateSecret(key, value)
+	created := err == nil
+	if errors.Is(err, database.ErrDuplicateKey) {
+		err = vaultDB.UpdateSecret(key, value)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	invalidateCachedSecret(key)
+	if err := vaultDB.SetSecretStrength(key, strength.Estimate(value), database.SourceManual); err != nil {
+		printVerbose("Failed to record secret strength: %v", err)
+	}
+	b.audit(database.AuditEventSet, key)
+	return created, nil
+}
+
+// Delete deletes key
+func (b *apiBackend) Delete(client, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	az, err := b.begin(client)
+	if err != nil {
+		return err
+	}
+	if err := az.Check(client, authz.OpDelete, key); err != nil {
+		return err
+	}
+	if err := vaultDB.DeleteSecret(key); err != nil {
+		return err
+	}
+	invalidateCachedSecret(key)
+	b.audit(database.AuditEventDelete, key)
+	return nil
+}
+
+// begin validates the session for a request by client and returns the
+// access rules in force, read afresh so changes apply at once. The caller
+// must hold b.mu.
+func (b *apiBackend) begin(client string) (*authz.Authorizer, error) {
+	if err := b.resume(); err != nil {
+		return nil, err
+	}
+	sessionMgr.SetIdentity(client)
+	rules, err := loadACLRules()
+	if err != nil {
+		return nil, err
+	}
+	return authz.NewAuthorizer(rules, sessionMgr.AuditDenial), nil
+}
+
+// resume checks that the server's session is still valid and extends it.
+// A session that timed out is unlocked again only without prompting; one
+// revoked from elsewhere locks the server for good. The caller must hold
+// b.mu.
+func (b *apiBackend) resume() error {
+	if b.revoked {
+		return fmt.Errorf("%w: its session was revoked; restart lockr serve", api.ErrLocked)
+	}
+	if sessionMgr.IsAuthenticated() {
+		err := sessionMgr.RefreshSession()
+		if !errors.Is(err, database.ErrInvalidSession) {
+			return err
+		}
+		b.revoked = true
+		sessionMgr.Logout()
+		fmt.Fprintln(os.Stderr, "Session revoked; refusing requests until lockr serve is restarted")
+		return fmt.Errorf("%w: its session was revoked; restart lockr serve", api.ErrLocked)
+	}
+	if err := authenticateOnTerminal(nil); err != nil {
+		return fmt.Errorf("%w: the session timed out; unlock it with 'lockr agent start' or restart lockr serve", api.ErrLocked)
+	}
+	return nil
+}
+
+// audit records a change made through the API
+func (b *apiBackend) audit(event, key string) {
+	if err := sessionMgr.Audit(event, key); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record audit event: %v\n", err)
+	}
+}
+
+// apiSocket returns the default socket of lockr serve; the vault of a
+// profile has a socket of its own
+func apiSocket() string {
+	if profile := vaultProfile(); profile != "" {
+		return dirs.ProfileAPISocket(profile)
+	}
+	return dirs.APISocket()
+}
+
+// loadAPITokens returns the vault's API tokens
+func loadAPITokens() (api.Tokens, error) {
+	data, _, err := vaultDB.GetSetting(apiTokensSetting)
+	if err != nil {
+		return nil, err
+	}
+	return api.DecodeTokens(data)
+}
+
+// saveAPITokens replaces the vault's API tokens
+func saveAPITokens(tokens api.Tokens) error {
+	if len(tokens) == 0 {
+		return vaultDB.DeleteSetting(apiTokensSetting)
+	}
+	data, err := api.EncodeTokens(tokens)
+	if err != nil {
+		return err
+	}
+	return vaultDB.SetSetting(apiTokensSetting, data)
+}
+
+func init() {
+	serveCmd.Flags().String("socket", "", "Socket to listen on (default: api.sock in the runtime directory)")
+	serveCmd.Flags().Bool("print-openapi", false, "Print an OpenAPI 3 description of the API and exit")
+
+	serveTokenCmd.AddCommand(serveTokenCreateCmd)
+	serveTokenCmd.AddCommand(serveTokenListCmd)
+	serveTokenCmd.AddCommand(serveTokenRevokeCmd)
+	serveCmd.AddCommand(serveTokenCmd)
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/database"
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "Manage vault sessions",
+	Long: `List and revoke sessions recorded in the vault by the CLI, the agent, or other clients.
+
+Commands run within 15 minutes of each other share one CLI session, which
+each of them extends; revoking it makes the next command start a new one.`,
+}
+
+var sessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recorded sessions",
+	Long:  `Display every session stored in the vault, including those created by other clients.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		sessions, err := sessionMgr.ListSessions()
+		if err != nil {
+			handleError(err, "Failed to list sessions")
+			return
+		}
+
+		current := sessionMgr.GetCurrentSession()
+
+		fmt.Printf("  %-14s %-20s %-20s %-10s\n", "SESSION", "CREATED", "LAST ACTIVITY", "EXPIRES")
+		for _, s := range sessions {
+			marker := " "
+			if current != nil && s.SessionID == current.SessionID {
+				marker = "*"
+			}
+			fmt.Printf("%s %-14s %-20s %-20s %-10s\n",
+				marker,
+				shortSessionID(s.SessionID),
+				s.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+				s.LastActivity.Local().Format("2006-01-02 15:04:05"),
+				formatExpiry(s.ExpiresAt))
+		}
+
+		fmt.Printf("\nTotal: %d sessions (* = current session)\n", len(sessions))
+	},
+}
+
+var sessionsRevokeCmd = &cobra.Command{
+	Use:   "revoke [session-id]",
+	Short: "Revoke one or all sessions",
+	Long: `Revoke a session by its ID (or a unique prefix of it), or every session
+other than the current one with --all.
+
+Examples:
+  lockr sessions revoke 3fa9c1e2       # Revoke a single session
+  lockr sessions revoke --all          # Revoke all other sessions`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		if all == (len(args) == 1) {
+			handleError(fmt.Errorf("specify either a session ID or --all"), "")
+			return
+		}
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		sessions, err := sessionMgr.ListSessions()
+		if err != nil {
+			handleError(err, "Failed to list sessions")
+			return
+		}
+
+		current := sessionMgr.GetCurrentSession()
+		var targets []database.Session
+
+		if all {
+			for _, s := range sessions {
+				if current == nil || s.SessionID != current.SessionID {
+					targets = append(targets, s)
+				}
+			}
+		} else {
+			prefix := strings.ToLower(args[0])
+			for _, s := range sessions {
+				if strings.HasPrefix(s.SessionID, prefix) {
+					targets = append(targets, s)
+				}
+			}
+			if len(targets) == 0 {
+				handleError(database.ErrInvalidSession, fmt.Sprintf("No session matches '%s'", args[0]))
+				return
+			}
+			if len(targets) > 1 {
+				handleError(fmt.Errorf("session ID prefix '%s' is ambiguous (%d matches)", args[0], len(targets)), "")
+				return
+			}
+		}
+
+		for _, s := range targets {
+			if err := sessionMgr.RevokeSession(s.SessionID); err != nil {
+				handleError(err, fmt.Sprintf("Failed to revoke session %s", shortSessionID(s.SessionID)))
+				return
+			}
+			fmt.Printf("Revoked session %s\n", shortSessionID(s.SessionID))
+		}
+		if len(targets) > 0 {
+			clearValueCache(false)
+		}
+
+		if len(targets) == 0 {
+			fmt.Println("No other sessions to revoke")
+		}
+	},
+}
+
+// shortSessionID returns an abbreviated session ID for display
+func shortSessionID(id string) string {
+	if len(id) <= 12 {
+		return id
+	}
+	return id[:12]
+}
+
+// formatExpiry describes when a session expires relative to now
+func formatExpiry(expiresAt time.Time) string {
+	remaining := time.Until(expiresAt)
+	if remaining <= 0 {
+		return "expired"
+	}
+	return remaining.Round(time.Second).String()
+}
+
+func init() {
+	sessionsRevokeCmd.Flags().Bool("all", false, "Revoke all sessions except the current one")
+
+	sessionsCmd.AddCommand(sessionsListCmd)
+	sessionsCmd.AddCommand(sessionsRevokeCmd)
+}
@@ -0,0 +1,329 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/snapshot"
+	"github.com/lockr/go/internal/strength"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Freeze, compare and restore namespaces",
+	Long: `Snapshots freeze the values of every secret in a namespace (a key prefix such
+as staging/) under a name, for promote-style workflows when the vault backs
+application config: snapshot staging, compare it with production, then
+restore it into production.
+
+Snapshots store keys relative to their namespace, so they can be compared
+with and restored into any namespace. Wherever a snapshot name is expected
+by diff, a namespace can be given instead to use its current values.
+
+Examples:
+  lockr snapshot create staging                    # Freeze staging/ as "staging"
+  lockr snapshot create rc-42 --namespace staging  # ... under another name
+  lockr snapshot list
+  lockr snapshot diff rc-42 production             # Snapshot vs live production/
+  lockr snapshot restore rc-42 --to production --dry-run
+  lockr snapshot restore rc-42 --to production --prune
+  lockr snapshot delete rc-42`,
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Freeze the current values of a namespace",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		namespace, _ := cmd.Flags().GetString("namespace")
+		if namespace == "" {
+			namespace = name
+		}
+		namespace = snapshot.NormalizeNamespace(namespace)
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		snap, err := vaultDB.CreateSnapshot(name, namespace)
+		if err != nil {
+			handleError(err, "Failed to create snapshot")
+			return
+		}
+		if snap.Count == 0 {
+			fmt.Fprintf(os.Stderr, "Warning: no secrets in %s; the snapshot is empty\n", namespace)
+		}
+		printInfo("✓ Snapshot '%s' holds %d secrets from %s", snap.Name, snap.Count, snap.Namespace)
+	},
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List snapshots",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		snapshots, err := vaultDB.ListSnapshots()
+		if err != nil {
+			handleError(err, "Failed to list snapshots")
+			return
+		}
+		if len(snapshots) == 0 {
+			fmt.Println("No snapshots")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tNAMESPACE\tSECRETS\tCREATED")
+		for _, s := range snapshots {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", s.Name, s.Namespace, s.Count, s.CreatedAt.Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+	},
+}
+
+var snapshotDiffCmd = &cobra.Command{
+	Use:   "diff <from> <to>",
+	Short: "Compare two snapshots or namespaces",
+	Long: `Show which keys were added, removed or changed going from one snapshot or
+namespace to another. Each side is a snapshot if one has that name, and
+otherwise the current contents of the namespace. Values are never shown.
+
+With --exit-code the command exits with status 1 when there are differences.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		showAll, _ := cmd.Flags().GetBool("all")
+		exitCode, _ := cmd.Flags().GetBool("exit-code")
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		from, fromDesc, err := snapshotSide(args[0])
+		if err != nil {
+			handleError(err, fmt.Sprintf("Failed to read '%s'", args[0]))
+			return
+		}
+		to, toDesc, err := snapshotSide(args[1])
+		if err != nil {
+			handleError(err, fmt.Sprintf("Failed to read '%s'", args[1]))
+			return
+		}
+
+		changes := snapshot.Diff(from, to)
+		printInfo("Comparing %s with %s", fromDesc, toDesc)
+		printChanges(changes, showAll)
+
+		counts := snapshot.Count(changes)
+		printInfo("\n%d added, %d removed, %d changed, %d unchanged",
+			counts[snapshot.Added], counts[snapshot.Removed], counts[snapshot.Changed], counts[snapshot.Unchanged])
+		if exitCode && len(changes) > counts[snapshot.Unchanged] {
+			os.Exit(1)
+		}
+	},
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <name>",
+	Short: "Write a snapshot's values back into a namespace",
+	Long: `Create and update secrets so that a namespace holds the values in a
+snapshot, by default the namespace the snapshot was taken from. Secrets in
+the namespace that are not in the snapshot are kept unless --prune is given.
+New secrets get the tags and notes recorded in the snapshot.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		target, _ := cmd.Flags().GetString("to")
+		prune, _ := cmd.Flags().GetBool("prune")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		snap, err := vaultDB.GetSnapshot(args[0])
+		if err != nil {
+			handleError(err, "Failed to read snapshot")
+			return
+		}
+		if target == "" {
+			target = snap.Namespace
+		}
+		target = snapshot.NormalizeNamespace(target)
+
+		current, err := namespaceEntries(target)
+		if err != nil {
+			handleError(err, "Failed to read vault")
+			return
+		}
+
+		var changes []snapshot.Change
+		for _, c := range snapshot.Diff(current, snap.Entries) {
+			if c.Kind == snapshot.Unchanged || (c.Kind == snapshot.Removed && !prune) {
+				continue
+			}
+			changes = append(changes, c)
+		}
+		if len(changes) == 0 {
+			printInfo("%s already matches snapshot '%s'", target, snap.Name)
+			return
+		}
+
+		printInfo("Restoring snapshot '%s' into %s", snap.Name, target)
+		printChanges(changes, false)
+		if dryRun {
+			printInfo("\nDry run; nothing was changed")
+			return
+		}
+
+		if !force {
+			fmt.Printf("\nApply %d changes to %s? (y/N): ", len(changes), target)
+			var response string
+			fmt.Scanln(&response)
+			if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
+				fmt.Println("Cancelled")
+				return
+			}
+		}
+
+		if failed := applyRestore(target, changes); failed > 0 {
+			handleError(fmt.Errorf("%d of %d changes failed", failed, len(changes)), "Restore incomplete")
+			return
+		}
+		printInfo("✓ Restored %d changes into %s", len(changes), target)
+	},
+}
+
+var snapshotDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a snapshot",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+		if err := vaultDB.DeleteSnapshot(args[0]); err != nil {
+			handleError(err, "Failed to delete snapshot")
+			return
+		}
+		printInfo("✓ Deleted snapshot '%s'", args[0])
+	},
+}
+
+// snapshotSide reads the entries of a snapshot, or of the namespace with
+// that name when there is no such snapshot, and describes which it was
+func snapshotSide(name string) ([]database.SnapshotEntry, string, error) {
+	snap, err := vaultDB.GetSnapshot(name)
+	if err == nil {
+		return snap.Entries, fmt.Sprintf("snapshot '%s' (%s, %s)", snap.Name, snap.Namespace, snap.CreatedAt.Local().Format("2006-01-02 15:04")), nil
+	}
+	if !errors.Is(err, database.ErrSnapshotNotFound) {
+		return nil, "", err
+	}
+
+	namespace := snapshot.NormalizeNamespace(name)
+	entries, err := namespaceEntries(namespace)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(entries) == 0 {
+		return nil, "", fmt.Errorf("no snapshot or namespace called '%s'", name)
+	}
+	return entries, "namespace " + namespace, nil
+}
+
+// namespaceEntries returns the current secrets under namespace as snapshot
+// entries
+func namespaceEntries(namespace string) ([]database.SnapshotEntry, error) {
+	secrets, err := vaultDB.ExportSecrets("")
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.FromSecrets(namespace, secrets), nil
+}
+
+// printChanges lists changes by kind, leaving out unchanged keys unless all
+// is set
+func printChanges(changes []snapshot.Change, all bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, c := range changes {
+		if c.Kind == snapshot.Unchanged && !all {
+			continue
+		}
+		fmt.Fprintf(w, "  %s\t%s\n", c.Kind, c.Key)
+	}
+	w.Flush()
+}
+
+// applyRestore writes changes into namespace and returns how many failed
+func applyRestore(namespace string, changes []snapshot.Change) int {
+	failed := 0
+	var added []database.Secret
+	for _, c := range changes {
+		switch c.Kind {
+		case snapshot.Added:
+			added = append(added, database.Secret{
+				Key:   namespace + c.To.Key,
+				Value: c.To.Value,
+				Tags:  c.To.Tags,
+				Notes: c.To.Notes,
+			})
+		case snapshot.Changed:
+			key := namespace + c.From.Key
+			if err := vaultDB.UpdateSecret(key, c.To.Value); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to update '%s': %v\n", key, err)
+				failed++
+				continue
+			}
+			invalidateCachedSecret(key)
+			if err := vaultDB.SetSecretStrength(key, strength.Estimate(c.To.Value), database.SourceImported); err != nil {
+				printVerbose("Failed to record strength of '%s': %v", key, err)
+			}
+		case snapshot.Removed:
+			key := namespace + c.From.Key
+			if err := vaultDB.DeleteSecret(key); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to delete '%s': %v\n", key, err)
+				failed++
+				continue
+			}
+			invalidateCachedSecret(key)
+		}
+	}
+
+	if len(added) > 0 {
+		if _, err := vaultDB.ImportSecrets(added); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create %d secrets: %v\n", len(added), err)
+			failed += len(added)
+		}
+	}
+	return failed
+}
+
+func init() {
+	snapshotCreateCmd.Flags().String("namespace", "", "Namespace to freeze (default: the snapshot name)")
+	snapshotDiffCmd.Flags().Bool("all", false, "Also list unchanged keys")
+	snapshotDiffCmd.Flags().Bool("exit-code", false, "Exit with status 1 when there are differences")
+	snapshotRestoreCmd.Flags().String("to", "", "Namespace to restore into (default: the snapshot's own)")
+	snapshotRestoreCmd.Flags().Bool("prune", false, "Delete secrets in the namespace that are not in the snapshot")
+	snapshotRestoreCmd.Flags().Bool("dry-run", false, "Show what would change without writing")
+
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotDiffCmd)
+	snapshotCmd.AddCommand(snapshotRestoreCmd)
+	snapshotCmd.AddCommand(snapshotDeleteCmd)
+}
@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/database"
+)
+
+// heatmapLevels are the cells used for increasing usage, from none to most
+var heatmapLevels = []string{"·", "░", "▒", "▓", "█"}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show how often secrets are used",
+	Long: `Show how often secrets have been retrieved, based on daily usage rollups
+recorded by 'get' and 'last'. Secrets are listed least used first, which
+makes credentials that may be ready for decommissioning easy to spot.
+
+With --heatmap, show a calendar heatmap of one secret's retrievals instead.
+
+Examples:
+  lockr stats                      # Usage of every secret over 90 days
+  lockr stats --days 365 --unused  # Secrets not retrieved in a year
+  lockr stats --heatmap prod/db    # Weekly heatmap for one secret
+  lockr stats --heatmap prod/db --weeks 52`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		heatmapKey, _ := cmd.Flags().GetString("heatmap")
+		if heatmapKey != "" {
+			weeks, _ := cmd.Flags().GetInt("weeks")
+			printUsageHeatmap(heatmapKey, weeks)
+			return
+		}
+
+		days, _ := cmd.Flags().GetInt("days")
+		unused, _ := cmd.Flags().GetBool("unused")
+		if days <= 0 {
+			handleError(fmt.Errorf("--days must be positive"), "")
+			return
+		}
+
+		totals, err := vaultDB.UsageTotals(time.Now().AddDate(0, 0, -days+1))
+		if err != nil {
+			handleError(err, "Failed to read usage")
+			return
+		}
+
+		shown := 0
+		fmt.Printf("%-40s %6s  %s\n", "KEY", "USES", "LAST USED")
+		for _, total := range totals {
+			if unused && total.Count > 0 {
+				continue
+			}
+			lastUsed := "-"
+			if total.LastUsed != nil {
+				lastUsed = total.LastUsed.Format("2006-01-02")
+			}
+			fmt.Printf("%-40s %6d  %s\n", truncateString(total.Key, 40), total.Count, lastUsed)
+			shown++
+		}
+
+		fmt.Printf("\n%d secrets, last %d days\n", shown, days)
+	},
+}
+
+// printUsageHeatmap prints the daily usage of key as a weekday-by-week grid
+func printUsageHeatmap(key string, weeks int) {
+	if weeks <= 0 {
+		handleError(fmt.Errorf("--weeks must be positive"), "")
+		return
+	}
+
+	// Make sure the key exists so a typo isn't shown as "never used"
+	secret, err := vaultDB.PeekSecret(key)
+	if err != nil {
+		handleError(err, fmt.Sprintf("Failed to find secret '%s'", key))
+		return
+	}
+
+	now := time.Now()
+	start := heatmapStart(now, weeks)
+	days, err := vaultDB.UsageByDay(secret.Key, start)
+	if err != nil {
+		handleError(err, "Failed to read usage")
+		return
+	}
+
+	fmt.Printf("Usage of '%s' over the last %d weeks\n\n", secret.Key, weeks)
+	fmt.Print(renderHeatmap(days, start, weeks, now))
+
+	var total int64
+	for _, day := range days {
+		total += day.Count
+	}
+	fmt.Printf("\n%d retrievals on %d days", total, len(days))
+	if len(days) > 0 {
+		fmt.Printf(", last on %s", days[len(days)-1].Day.Format("2006-01-02"))
+	}
+	fmt.Println()
+}
+
+// heatmapStart returns the Monday that begins the first of weeks columns
+// ending with the week containing now
+func heatmapStart(now time.Time, weeks int) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	sinceMonday := (int(today.Weekday()) + 6) % 7
+	return today.AddDate(0, 0, -sinceMonday-7*(weeks-1))
+}
+
+// renderHeatmap draws one row per weekday and one column per week starting
+// at start, shading each day relative to the busiest day
+func renderHeatmap(days []database.UsageDay, start time.Time, weeks int, now time.Time) string {
+	counts := make(map[string]int64, len(days))
+	var max int64
+	for _, day := range days {
+		counts[day.Day.Format("2006-01-02")] = day.Count
+		if day.Count > max {
+			max = day.Count
+		}
+	}
+
+	var b strings.Builder
+
+	// Month labels above the first week of each month
+	header := []rune(strings.Repeat(" ", weeks*2))
+	lastMonth := time.Month(0)
+	for week := 0; week < weeks; week++ {
+		monday := start.AddDate(0, 0, 7*week)
+		if monday.Month() == lastMonth {
+			continue
+		}
+		lastMonth = monday.Month()
+		label := []rune(monday.Format("Jan"))
+		if week*2+len(label) <= len(header) && (week == 0 || header[week*2-1] == ' ') {
+			copy(header[week*2:], label)
+		}
+	}
+	fmt.Fprintf(&b, "    %s\n", strings.TrimRight(string(header), " "))
+
+	weekdays := []string{"Mon", "", "Wed", "", "Fri", "", "Sun"}
+	for row, label := range weekdays {
+		fmt.Fprintf(&b, "%-3s ", label)
+		for week := 0; week < weeks; week++ {
+			day := start.AddDate(0, 0, 7*week+row)
+			if day.After(now) {
+				break
+			}
+			fmt.Fprintf(&b, "%s ", heatmapLevels[heatmapLevel(counts[day.Format("2006-01-02")], max)])
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "\n    less %s more\n", strings.Join(heatmapLevels, " "))
+	return b.String()
+}
+
+// heatmapLevel maps count to an index into heatmapLevels
+func heatmapLevel(count, max int64) int {
+	if count <= 0 || max <= 0 {
+		return 0
+	}
+	steps := int64(len(heatmapLevels) - 1)
+	return int((count*steps + max - 1) / max)
+}
+
+func init() {
+	statsCmd.Flags().String("heatmap", "", "Show a usage heatmap for this key")
+	statsCmd.Flags().Int("weeks", 26, "Number of weeks shown in the heatmap")
+	statsCmd.Flags().Int("days", 90, "Number of days to summarise")
+	statsCmd.Flags().Bool("unused", false, "Only show secrets with no uses in the period")
+}
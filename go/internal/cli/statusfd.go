@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lockr/go/internal/progress"
+)
+
+// statusOut receives progress events when --status-fd is set; it is opened
+// on first use and shared by every reporter in the process
+var statusOut io.Writer
+
+// statusReporter returns a reporter for op that writes to the --status-fd
+// descriptor, or one that discards events when the flag isn't set
+func statusReporter(op string) *progress.Reporter {
+	if statusFD <= 0 {
+		return nil
+	}
+	if statusOut == nil {
+		f := os.NewFile(uintptr(statusFD), "status-fd")
+		if f == nil {
+			fmt.Fprintf(os.Stderr, "Warning: --status-fd %d is not a valid descriptor\n", statusFD)
+			statusFD = 0
+			return nil
+		}
+		statusOut = f
+	}
+	return progress.New(statusOut, op)
+}
@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/policy"
+	"github.com/spf13/cobra"
+)
+
+// tagCmd groups the commands that change a secret's tags
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Add or remove secret tags",
+	Long: `Organize secrets with tags. Tags are case-insensitive labels such as "api" or
+"prod"; a secret can carry any number of them.
+
+Examples:
+  lockr tag add api/github api personal
+  lockr tag remove api/github personal
+  lockr set --tag api --tag prod api/stripe
+  lockr list --tag api              # Secrets tagged api
+  lockr list --tag api --tag prod   # Secrets tagged both api and prod`,
+}
+
+// tagAddCmd adds tags to a secret
+var tagAddCmd = &cobra.Command{
+	Use:   "add <key> <tag>...",
+	Short: "Add tags to a secret",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		changeTags(args[0], args[1:], vaultDB.AddTag, "added to")
+	},
+}
+
+// tagRemoveCmd removes tags from a secret
+var tagRemoveCmd = &cobra.Command{
+	Use:     "remove <key> <tag>...",
+	Aliases: []string{"rm"},
+	Short:   "Remove tags from a secret",
+	Args:    cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		changeTags(args[0], args[1:], vaultDB.RemoveTag, "removed from")
+	},
+}
+
+// changeTags applies change to each tag of the secret named by arg
+func changeTags(arg string, tags []string, change func(key, tag string) error, verb string) {
+	if err := ensureAuthenticated(); err != nil {
+		handleError(err, "Authentication failed")
+		return
+	}
+
+	key := activeWorkspace.QualifyKey(arg)
+	if err := applyTags(key, tags, change); err != nil {
+		handleError(err, fmt.Sprintf("Failed to update tags of '%s'", key))
+		return
+	}
+	printInfo("Tags %s '%s': %s", verb, key, strings.Join(tags, ", "))
+}
+
+// applyTags applies change to every tag of key, stopping at the first error
+func applyTags(key string, tags []string, change func(key, tag string) error) error {
+	for _, tag := range tags {
+		if err := change(key, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listTagged returns the secrets carrying every one of tags
+func listTagged(tags []string) ([]database.SearchResult, error) {
+	secrets, err := vaultDB.ListByTag(tags[0])
+	if err != nil {
+		return nil, err
+	}
+	return filterTagged(secrets, tags[1:]), nil
+}
+
+// filterTagged keeps the secrets carrying every one of tags
+func filterTagged(secrets []database.SearchResult, tags []string) []database.SearchResult {
+	if len(tags) == 0 {
+		return secrets
+	}
+	var kept []database.SearchResult
+	for _, secret := range secrets {
+		if hasTags(policy.ParseTags(secret.Tags), tags) {
+			kept = append(kept, secret)
+		}
+	}
+	return kept
+}
+
+// hasTags reports whether have contains every tag in want, ignoring case
+func hasTags(have, want []string) bool {
+	for _, w := range want {
+		found := false
+		for _, h := range have {
+			if strings.EqualFold(h, strings.TrimSpace(w)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// pageOf returns the page of secrets selected by limit and offset
+func pageOf(secrets []database.SearchResult, limit, offset int) []database.SearchResult {
+	if offset >= len(secrets) {
+		return nil
+	}
+	secrets = secrets[offset:]
+	if limit > 0 && limit < len(secrets) {
+		secrets = secrets[:limit]
+	}
+	return secrets
+}
+
+// tagFlag reads a repeatable --tag flag, rejecting empty tags
+func tagFlag(cmd *cobra.Command) ([]string, error) {
+	tags, _ := cmd.Flags().GetStringSlice("tag")
+	for i, tag := range tags {
+		tags[i] = strings.TrimSpace(tag)
+		if tags[i] == "" {
+			return nil, fmt.Errorf("--tag must not be empty")
+		}
+	}
+	return tags, nil
+}
+
+func init() {
+	tagCmd.AddCommand(tagAddCmd)
+	tagCmd.AddCommand(tagRemoveCmd)
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/lockr/go/internal/config"
+)
+
+// vaultThemes label and color vaults, from the config file's vaults section
+var vaultThemes config.Themes
+
+// activeTheme returns the theme of the vault in use
+func activeTheme() config.Theme {
+	return vaultThemes.For(vaultPath)
+}
+
+// vaultPrompt prefixes prompt with the active vault's label, so a prompt
+// about to write to or read from a vault says which one
+func vaultPrompt(prompt string) string {
+	theme := activeTheme()
+	if theme.Label == "" {
+		return prompt
+	}
+	return colorize("["+theme.Label+"]", theme) + " " + prompt
+}
+
+// colorize renders s in the theme's color when stdout is a terminal and
+// NO_COLOR is not set
+func colorize(s string, theme config.Theme) string {
+	code, err := theme.ColorCode()
+	if err != nil || code == "" || os.Getenv("NO_COLOR") != "" || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return s
+	}
+	return ansiColor(code) + s + "\x1b[0m"
+}
+
+// ansiColor returns the escape sequence selecting an ANSI color number or
+// #rrggbb as the foreground color
+func ansiColor(code string) string {
+	if hex, ok := strings.CutPrefix(code, "#"); ok {
+		rgb, _ := strconv.ParseUint(hex, 16, 32)
+		return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", rgb>>16, rgb>>8&0xff, rgb&0xff)
+	}
+	return "\x1b[38;5;" + code + "m"
+}
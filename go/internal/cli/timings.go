@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lockr/go/internal/database"
+)
+
+// showTimings is set by --timings
+var showTimings bool
+
+// phaseTiming is the accumulated time spent in one phase of a command
+type phaseTiming struct {
+	name  string
+	total time.Duration
+	calls int
+}
+
+// commandTimings records where a command spent its time. Nothing leaves the
+// process: the report is printed on stderr when the command finishes.
+var commandTimings struct {
+	start  time.Time
+	phases []*phaseTiming
+}
+
+// startTimings marks the start of the command
+func startTimings() {
+	commandTimings.start = time.Now()
+}
+
+// timePhase starts timing a phase; call the returned function when it ends.
+// Phases with the same name are summed.
+func timePhase(name string) func() {
+	if !showTimings {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		elapsed := time.Since(start)
+		for _, p := range commandTimings.phases {
+			if p.name == name {
+				p.total += elapsed
+				p.calls++
+				return
+			}
+		}
+		commandTimings.phases = append(commandTimings.phases, &phaseTiming{name: name, total: elapsed, calls: 1})
+	}
+}
+
+// printTimings prints the timing report if --timings was given. Time not
+// covered by a phase (prompts, output, startup) is reported as "other".
+func printTimings() {
+	if !showTimings || commandTimings.start.IsZero() {
+		return
+	}
+	total := time.Since(commandTimings.start)
+
+	fmt.Fprintln(os.Stderr, "Timings:")
+	var measured time.Duration
+	for _, p := range commandTimings.phases {
+		measured += p.total
+		calls := ""
+		if p.calls > 1 {
+			calls = fmt.Sprintf(" (%d calls)", p.calls)
+		}
+		fmt.Fprintf(os.Stderr, "  %-10s %10s%s\n", p.name, formatElapsed(p.total), calls)
+	}
+	if other := total - measured; other > 0 {
+		fmt.Fprintf(os.Stderr, "  %-10s %10s\n", "other", formatElapsed(other))
+	}
+	fmt.Fprintf(os.Stderr, "  %-10s %10s\n", "total", formatElapsed(total))
+
+	// Print once, even if an error path reaches here after the command
+	commandTimings.start = time.Time{}
+}
+
+// formatElapsed rounds a duration for the timing report
+func formatElapsed(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(100 * time.Microsecond).String()
+}
+
+// timedStore times the secret reads and writes commands make, reporting
+// them as the "db" phase. Other calls pass through untimed.
+type timedStore struct {
+	database.VaultStore
+}
+
+func (s timedStore) GetSecret(key string) (*database.Secret, error) {
+	defer timePhase("db")()
+	return s.VaultStore.GetSecret(key)
+}
+
+func (s timedStore) PeekSecret(key string) (*database.Secret, error) {
+	defer timePhase("db")()
+	return s.VaultStore.PeekSecret(key)
+}
+
+func (s timedStore) CreateSecret(key, value string) error {
+	defer timePhase("db")()
+	return s.VaultStore.CreateSecret(key, value)
+}
+
+func (s timedStore) UpdateSecret(key, value string) error {
+	defer timePhase("db")()
+	return s.VaultStore.UpdateSecret(key, value)
+}
+
+func (s timedStore) UpdateSecretIfRevision(key, value string, revision int64) error {
+	defer timePhase("db")()
+	return s.VaultStore.UpdateSecretIfRevision(key, value, revision)
+}
+
+func (s timedStore) DeleteSecret(key string) error {
+	defer timePhase("db")()
+	return s.VaultStore.DeleteSecret(key)
+}
+
+func (s timedStore) ListSecrets() ([]database.SearchResult, error) {
+	defer timePhase("db")()
+	return s.VaultStore.ListSecrets()
+}
+
+func (s timedStore) ListSecretsPage(limit, offset int) ([]database.SearchResult, error) {
+	defer timePhase("db")()
+	return s.VaultStore.ListSecretsPage(limit, offset)
+}
+
+func (s timedStore) CountSecrets() (int, error) {
+	defer timePhase("db")()
+	return s.VaultStore.CountSecrets()
+}
+
+func (s timedStore) SearchSecretsGlob(pattern string, limit, offset int) ([]database.SearchResult, int, error) {
+	defer timePhase("db")()
+	return s.VaultStore.SearchSecretsGlob(pattern, limit, offset)
+}
+
+func (s timedStore) SearchSecretsRegex(pattern string, limit, offset int) ([]database.SearchResult, int, error) {
+	defer timePhase("db")()
+	return s.VaultStore.SearchSecretsRegex(pattern, limit, offset)
+}
+
+func (s timedStore) RecentSecrets(limit int) ([]database.SearchResult, error) {
+	defer timePhase("db")()
+	return s.VaultStore.RecentSecrets(limit)
+}
+
+func (s timedStore) ListByTag(tag string) ([]database.SearchResult, error) {
+	defer timePhase("db")()
+	return s.VaultStore.ListByTag(tag)
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/api"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Investigate what automation tokens did with the vault",
+	Long: `Commands about the credentials programs use to reach the vault: API tokens
+of 'lockr serve' (create them with 'lockr serve token create') and the
+identities of editor plugins and programs embedding lockr.`,
+}
+
+var tokenUsageCmd = &cobra.Command{
+	Use:   "usage <id>",
+	Short: "Show which secrets a token retrieved",
+	Long: `List the audit events recorded for an automation token, newest first: the
+secrets it retrieved, what it stored or deleted and the requests access rules
+denied. <id> is the name of an API token, such as ci for rest:ci (revoked
+tokens too), or a client identity such as plugin:vscode or app:deployer.
+
+When a token may have leaked, rotate the secrets it retrieved, then revoke it
+with 'lockr serve token revoke' or remove the access rules that let it in.
+
+Examples:
+  lockr token usage ci
+  lockr token usage plugin:vscode --limit 0`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		// A bare name is an API token, revoked ones included
+		identity := args[0]
+		if !strings.Contains(identity, ":") {
+			identity = api.ClientPrefix + identity
+		}
+
+		limit, _ := cmd.Flags().GetInt("limit")
+		printIdentityUsage(identity, limit)
+	},
+}
+
+func init() {
+	tokenUsageCmd.Flags().Int("limit", 100, "Maximum number of events to show (0 for all)")
+
+	tokenCmd.AddCommand(tokenUsageCmd)
+}
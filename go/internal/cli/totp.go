@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/totp"
+	"github.com/spf13/cobra"
+)
+
+// totpTag marks secrets holding a TOTP seed
+const totpTag = "totp"
+
+// totpCmd generates two-factor codes from stored TOTP seeds
+var totpCmd = &cobra.Command{
+	Use:   "totp <key>",
+	Short: "Generate a two-factor code from a stored TOTP seed",
+	Long: `Generate the current RFC 6238 code of a two-factor seed stored in the vault
+and copy it to the clipboard, showing how long it stays valid.
+
+Store a seed with --add: paste the otpauth:// URI behind the QR code a site
+shows, or the base32 "setup key" it offers instead. The seed is stored as an
+ordinary secret tagged totp, so it is synced, exported and audited like any
+other; 'lockr get' on it returns the seed, not a code.
+
+Examples:
+  lockr totp --add github/2fa      # Prompt for the otpauth URI or setup key
+  lockr totp github/2fa            # Copy the current code
+  lockr totp --show github/2fa     # Print the code instead`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		key := activeWorkspace.QualifyKey(args[0])
+		if add, _ := cmd.Flags().GetBool("add"); add {
+			addTOTP(key)
+			return
+		}
+
+		secret, err := vaultDB.GetSecret(key)
+		if err != nil {
+			handleError(err, fmt.Sprintf("Failed to get secret '%s'", key))
+			return
+		}
+		auditSecretAccess(key)
+
+		otp, err := totp.Parse(secret.Value)
+		if err != nil {
+			handleError(err, fmt.Sprintf("Secret '%s' does not hold a TOTP seed", key))
+			return
+		}
+
+		now := time.Now()
+		code := otp.Code(now)
+		remaining := otp.Remaining(now)
+		if label := otp.Label(); label != "" {
+			printVerbose("Code for %s", label)
+		}
+
+		show, _ := cmd.Flags().GetBool("show")
+		if !show && !noClipboard && clipboardMgr != nil {
+			err := copySecret(code)
+			if err == nil {
+				printInfo("✓ Code copied to clipboard (valid for %ds)", int(remaining.Seconds()))
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Warning: failed to copy to clipboard: %v\n", err)
+		}
+
+		if quiet {
+			fmt.Println(code)
+			return
+		}
+		fmt.Printf("%s (valid for %ds)\n", code, int(remaining.Seconds()))
+	},
+}
+
+// addTOTP prompts for a TOTP seed, checks that it generates codes and
+// stores it under key
+func addTOTP(key string) {
+	value, err := promptPassword("otpauth URI or base32 setup key: ")
+	if err != nil {
+		handleError(err, "Failed to read seed")
+		return
+	}
+	otp, err := totp.Parse(value)
+	if err != nil {
+		handleError(err, "Invalid TOTP seed")
+		return
+	}
+
+	err = vaultDB.CreateSecret(key, value)
+	if errors.Is(err, database.ErrDuplicateKey) {
+		if !force {
+			handleError(err, fmt.Sprintf("Secret '%s' already exists; pass --force to replace it", key))
+			return
+		}
+		err = vaultDB.UpdateSecret(key, value)
+		invalidateCachedSecret(key)
+	}
+	if err != nil {
+		handleError(err, fmt.Sprintf("Failed to store secret '%s'", key))
+		return
+	}
+	if err := vaultDB.AddTag(key, totpTag); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to tag '%s': %v\n", key, err)
+	}
+
+	printInfo("✓ TOTP seed stored in '%s'", key)
+	if label := otp.Label(); label != "" {
+		printVerbose("Seed is for %s", label)
+	}
+}
+
+func init() {
+	totpCmd.Flags().Bool("add", false, "Store a TOTP seed under the key instead of generating a code")
+	totpCmd.Flags().Bool("show", false, "Print the code instead of copying it to the clipboard")
+}
@@ -0,0 +1,397 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/database"
+)
+
+// Vault settings used by travel mode
+const (
+	travelEnabledSetting  = "travel.enabled"
+	travelPatternsSetting = "travel.patterns"
+	travelTagsSetting     = "travel.tags"
+	travelDetachedSetting = "travel.detached"
+)
+
+var travelCmd = &cobra.Command{
+	Use:   "travel",
+	Short: "Hide sensitive secrets while travelling",
+	Long: `Travel mode hides secrets matching configured key patterns or tags.
+While it is on, hidden secrets are excluded from get, list, search and every
+other command as if they did not exist.
+
+With --detach, hidden secrets are moved out of the vault into a separate
+encrypted file instead, so they are not present in the vault at all. That
+file can be left at home and is merged back when travel mode is turned off.
+
+Turning travel mode on or off always requires the master password.
+
+Examples:
+  lockr travel hide 'work/*' 'bank/*'           # Choose what to hide
+  lockr travel hide --tag corp                   # Hide secrets tagged "corp"
+  lockr travel on                                # Hide them
+  lockr travel on --detach ~/safe/hidden.lockr   # Move them out of the vault
+  lockr travel off                               # Restore everything
+  lockr travel status                            # Show rules and state`,
+}
+
+var travelOnCmd = &cobra.Command{
+	Use:   "on",
+	Short: "Enable travel mode",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		password, err := authenticateWithMasterPassword()
+		if err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		if enabled, _ := travelEnabled(); enabled {
+			fmt.Println("Travel mode is already on")
+			return
+		}
+
+		patterns, tags, err := travelRules()
+		if err != nil {
+			handleError(err, "Failed to read travel rules")
+			return
+		}
+		if len(patterns) == 0 && len(tags) == 0 {
+			handleError(errors.New("no travel rules configured; add some with 'lockr travel hide'"), "")
+			return
+		}
+
+		hidden, err := vaultDB.HideSecrets(patterns, tags)
+		if err != nil {
+			handleError(err, "Failed to hide secrets")
+			return
+		}
+		clearValueCache(false)
+
+		detach, _ := cmd.Flags().GetString("detach")
+		if detach != "" {
+			if err := detachHiddenSecrets(detach, password); err != nil {
+				vaultDB.UnhideSecrets()
+				handleError(err, "Failed to detach hidden secrets")
+				return
+			}
+			if err := vaultDB.SetSetting(travelDetachedSetting, detach); err != nil {
+				handleError(err, "Failed to save travel state")
+				return
+			}
+		}
+
+		if err := vaultDB.SetSetting(travelEnabledSetting, "true"); err != nil {
+			handleError(err, "Failed to save travel state")
+			return
+		}
+
+		if detach != "" {
+			fmt.Printf("Travel mode on: %d secrets moved to %s\n", hidden, detach)
+		} else {
+			fmt.Printf("Travel mode on: %d secrets hidden\n", hidden)
+		}
+	},
+}
+
+var travelOffCmd = &cobra.Command{
+	Use:   "off",
+	Short: "Disable travel mode and restore hidden secrets",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		password, err := authenticateWithMasterPassword()
+		if err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		if enabled, _ := travelEnabled(); !enabled {
+			fmt.Println("Travel mode is not on")
+			return
+		}
+
+		restored := int64(0)
+		detached, ok, err := vaultDB.GetSetting(travelDetachedSetting)
+		if err != nil {
+			handleError(err, "Failed to read travel state")
+			return
+		}
+		if ok {
+			count, err := reattachSecrets(detached, password)
+			if err != nil {
+				handleError(err, fmt.Sprintf("Failed to restore secrets from %s", detached))
+				return
+			}
+			restored += int64(count)
+			if err := vaultDB.DeleteSetting(travelDetachedSetting); err != nil {
+				handleError(err, "Failed to save travel state")
+				return
+			}
+		}
+
+		unhidden, err := vaultDB.UnhideSecrets()
+		if err != nil {
+			handleError(err, "Failed to restore hidden secrets")
+			return
+		}
+		restored += unhidden
+
+		if err := vaultDB.DeleteSetting(travelEnabledSetting); err != nil {
+			handleError(err, "Failed to save travel state")
+			return
+		}
+
+		printInfo("Travel mode off: %d secrets restored", restored)
+	},
+}
+
+var travelHideCmd = &cobra.Command{
+	Use:   "hide [pattern...]",
+	Short: "Add key patterns or tags to hide in travel mode",
+	Run: func(cmd *cobra.Command, args []string) {
+		updateTravelRules(cmd, args, true)
+	},
+}
+
+var travelUnhideCmd = &cobra.Command{
+	Use:   "unhide [pattern...]",
+	Short: "Remove key patterns or tags from the travel rules",
+	Run: func(cmd *cobra.Command, args []string) {
+		updateTravelRules(cmd, args, false)
+	},
+}
+
+var travelStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show travel mode state and rules",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		enabled, err := travelEnabled()
+		if err != nil {
+			handleError(err, "Failed to read travel state")
+			return
+		}
+		patterns, tags, err := travelRules()
+		if err != nil {
+			handleError(err, "Failed to read travel rules")
+			return
+		}
+
+		if enabled {
+			fmt.Println("Travel mode: on")
+			if detached, ok, _ := vaultDB.GetSetting(travelDetachedSetting); ok {
+				fmt.Printf("Detached to: %s\n", detached)
+			}
+		} else {
+			fmt.Println("Travel mode: off")
+		}
+
+		if len(patterns) == 0 && len(tags) == 0 {
+			fmt.Println("No rules configured")
+			return
+		}
+		for _, p := range patterns {
+			fmt.Printf("  hide key  %s\n", p)
+		}
+		for _, t := range tags {
+			fmt.Printf("  hide tag  %s\n", t)
+		}
+	},
+}
+
+// updateTravelRules adds or removes patterns and --tag values from the rules
+func updateTravelRules(cmd *cobra.Command, patterns []string, add bool) {
+	tags, _ := cmd.Flags().GetStringSlice("tag")
+	if len(patterns) == 0 && len(tags) == 0 {
+		handleError(errors.New("specify at least one pattern or --tag"), "")
+		return
+	}
+
+	if err := ensureAuthenticated(); err != nil {
+		handleError(err, "Authentication failed")
+		return
+	}
+
+	if enabled, _ := travelEnabled(); enabled {
+		handleError(errors.New("travel mode is on; turn it off before changing rules"), "")
+		return
+	}
+
+	currentPatterns, currentTags, err := travelRules()
+	if err != nil {
+		handleError(err, "Failed to read travel rules")
+		return
+	}
+
+	if add {
+		currentPatterns = addUnique(currentPatterns, patterns)
+		currentTags = addUnique(currentTags, tags)
+	} else {
+		currentPatterns = removeAll(currentPatterns, patterns)
+		currentTags = removeAll(currentTags, tags)
+	}
+
+	if err := vaultDB.SetSetting(travelPatternsSetting, strings.Join(currentPatterns, "\n")); err != nil {
+		handleError(err, "Failed to save travel rules")
+		return
+	}
+	if err := vaultDB.SetSetting(travelTagsSetting, strings.Join(currentTags, "\n")); err != nil {
+		handleError(err, "Failed to save travel rules")
+		return
+	}
+
+	fmt.Printf("Travel rules: %d key patterns, %d tags\n", len(currentPatterns), len(currentTags))
+}
+
+// travelEnabled reports whether travel mode is on
+func travelEnabled() (bool, error) {
+	value, ok, err := vaultDB.GetSetting(travelEnabledSetting)
+	return ok && value == "true", err
+}
+
+// travelRules returns the configured key patterns and tags
+func travelRules() ([]string, []string, error) {
+	patterns, _, err := vaultDB.GetSetting(travelPatternsSetting)
+	if err != nil {
+		return nil, nil, err
+	}
+	tags, _, err := vaultDB.GetSetting(travelTagsSetting)
+	if err != nil {
+		return nil, nil, err
+	}
+	return splitLines(patterns), splitLines(tags), nil
+}
+
+// detachHiddenSecrets moves every hidden secret into a separate vault file
+// encrypted with password
+func detachHiddenSecrets(path, password string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+
+	secrets, err := vaultDB.HiddenSecrets()
+	if err != nil {
+		return err
+	}
+
+	if _, err := cloneVault(path, password, secrets); err != nil {
+		return err
+	}
+
+	if _, err := vaultDB.DeleteHiddenSecrets(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// reattachSecrets imports the secrets from a detached travel file and removes it
+func reattachSecrets(path, password string) (int, error) {
+	store, err := database.OpenStore(vaultEngine(path), path)
+	if err != nil {
+		return 0, err
+	}
+	if err := store.Connect(password); err != nil {
+		return 0, err
+	}
+
+	secrets, err := store.ExportSecrets("")
+	store.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := vaultDB.ImportSecrets(secrets)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.Remove(path); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", path, err)
+	}
+	return count, nil
+}
+
+// authenticateWithMasterPassword always prompts for the master password,
+// bypassing the keyring, and returns it
+func authenticateWithMasterPassword() (string, error) {
+	password, err := promptPassword("Enter vault password: ")
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+
+	if err := sessionMgr.Authenticate(password); err != nil {
+		return "", fmt.Errorf("authentication failed: %w", err)
+	}
+
+	afterAuthentication()
+	return password, nil
+}
+
+// splitLines splits a newline-separated list, dropping empty entries
+func splitLines(s string) []string {
+	var out []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// addUnique appends values not already present in list
+func addUnique(list, values []string) []string {
+	for _, v := range values {
+		found := false
+		for _, existing := range list {
+			if existing == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// removeAll returns list without any of values
+func removeAll(list, values []string) []string {
+	var out []string
+	for _, existing := range list {
+		keep := true
+		for _, v := range values {
+			if existing == v {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			out = append(out, existing)
+		}
+	}
+	return out
+}
+
+func init() {
+	travelOnCmd.Flags().String("detach", "", "Move hidden secrets into this separate vault file")
+	travelHideCmd.Flags().StringSlice("tag", nil, "Hide secrets with this tag (repeatable)")
+	travelUnhideCmd.Flags().StringSlice("tag", nil, "Tag to remove from the rules (repeatable)")
+
+	travelCmd.AddCommand(travelOnCmd)
+	travelCmd.AddCommand(travelOffCmd)
+	travelCmd.AddCommand(travelHideCmd)
+	travelCmd.AddCommand(travelUnhideCmd)
+	travelCmd.AddCommand(travelStatusCmd)
+}
@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// profileEnv selects a profile for the shell it is set in. lockr reads it
+// itself; it is not discover's --profile.
+const profileEnv = "LOCKR_PROFILE"
+
+// activeProfile is the profile selected by LOCKR_PROFILE, if any
+var activeProfile string
+
+var useCmd = &cobra.Command{
+	Use:   "use [profile]",
+	Short: "Switch this shell to another vault by its label",
+	Long: `Switch the current shell to a profile: a vault given a label in the vaults
+section of config.yml. lockr use prints the variable to set, for the shell
+to evaluate, so only that shell and the commands it starts switch:
+
+  eval "$(lockr use work)"              # bash, zsh
+  lockr use work --format fish | source
+
+Commands then use the profile's vault unless --vault, LOCKR_VAULT or a
+project workspace names another. Without a profile, lockr use lists the
+profiles and marks the one in use; --off switches back to the default vault.
+
+Every profile's vault is unlocked in an agent of its own, so unlocking the
+personal vault never unlocks the work vault, and 'lockr agent lock' in one
+profile leaves the others alone. Setting LOCKR_AGENT_SOCK makes every vault
+share that one agent again.
+
+Examples:
+  lockr use                 # List profiles
+  eval "$(lockr use work)"  # Switch this shell to the vault labelled work
+  eval "$(lockr use --off)"`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		off, _ := cmd.Flags().GetBool("off")
+		if format != "shell" && format != "fish" {
+			handleError(fmt.Errorf("unknown format %q (use shell or fish)", format), "")
+			return
+		}
+		profiles := vaultThemes.Profiles()
+
+		switch {
+		case off && len(args) > 0:
+			handleError(errors.New("--off takes no profile"), "")
+		case off:
+			if format == "fish" {
+				fmt.Printf("set -e %s\n", profileEnv)
+			} else {
+				fmt.Printf("unset %s\n", profileEnv)
+			}
+			evalHint(cmd, "--off")
+		case len(args) == 0:
+			listProfiles(profiles)
+		default:
+			name := args[0]
+			if _, ok := profiles[name]; !ok {
+				handleError(fmt.Errorf("unknown profile %q (%s)", name, profileNames(profiles)), "")
+				return
+			}
+			if format == "fish" {
+				fmt.Printf("set -gx %s %s\n", profileEnv, shellQuote(name))
+			} else {
+				fmt.Printf("export %s=%s\n", profileEnv, shellQuote(name))
+			}
+			evalHint(cmd, name)
+		}
+	},
+}
+
+// listProfiles prints every profile with its vault, marking the active one
+func listProfiles(profiles map[string]string) {
+	if len(profiles) == 0 {
+		fmt.Println("No profiles; give vaults a label in the vaults section of config.yml")
+		return
+	}
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		marker := " "
+		if name == activeProfile {
+			marker = "*"
+		}
+		fmt.Fprintf(w, "%s %s\t%s\n", marker, name, profiles[name])
+	}
+	w.Flush()
+}
+
+// profileNames lists the profiles for an error message
+func profileNames(profiles map[string]string) string {
+	if len(profiles) == 0 {
+		return "no vault has a label in config.yml"
+	}
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "profiles: " + strings.Join(names, ", ")
+}
+
+// evalHint tells someone who ran lockr use at a terminal, rather than
+// through eval, that the shell did not switch
+func evalHint(cmd *cobra.Command, arg string) {
+	if quiet || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return
+	}
+	format, _ := cmd.Flags().GetString("format")
+	if format == "fish" {
+		fmt.Fprintf(os.Stderr, "To switch this shell, run: lockr use %s --format fish | source\n", arg)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "To switch this shell, run: eval \"$(lockr use %s)\"\n", arg)
+}
+
+// selectProfile makes the vault of the profile in LOCKR_PROFILE the
+// default. An unknown profile is an error rather than a fallback to the
+// default vault, which may be the one the profile was meant to keep apart.
+func selectProfile(cmd *cobra.Command) {
+	name := os.Getenv(profileEnv)
+	if name == "" {
+		return
+	}
+	path, ok := vaultThemes.Profiles()[name]
+	if !ok {
+		if cmd == useCmd {
+			return // Let lockr use switch away from it
+		}
+		handleError(fmt.Errorf("unknown profile %q in $%s (%s); switch with 'lockr use'", name, profileEnv, profileNames(vaultThemes.Profiles())), "")
+		return
+	}
+	activeProfile = name
+	setFlagDefault(cmd, "vault", path)
+}
+
+// vaultProfile returns the profile whose vault is in use, or "" if the
+// vault has no label
+func vaultProfile() string {
+	path, err := filepath.Abs(vaultPath)
+	if err != nil {
+		return ""
+	}
+	for name, profile := range vaultThemes.Profiles() {
+		if profile == path {
+			return name
+		}
+	}
+	return ""
+}
+
+func init() {
+	useCmd.Flags().String("format", "shell", "Output format: shell (bash, zsh, sh) or fish")
+	useCmd.Flags().Bool("off", false, "Switch back to the default vault")
+}
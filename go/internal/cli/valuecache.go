@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/keyring"
+	"github.com/lockr/go/internal/policy"
+	"github.com/lockr/go/internal/valuecache"
+)
+
+// valueCacheRetention bounds how long any value stays in the cache file,
+// whatever --max-age later readers ask for
+const valueCacheRetention = 24 * time.Hour
+
+// parseMaxAge parses --max-age, which is either whole seconds ("300") or a
+// Go duration ("5m")
+func parseMaxAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if seconds, err := strconv.Atoi(s); err == nil {
+		if seconds < 0 {
+			return 0, fmt.Errorf("invalid max age %q", s)
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid max age %q", s)
+	}
+	if d > valueCacheRetention {
+		d = valueCacheRetention
+	}
+	return d, nil
+}
+
+// valueCacheDir holds one encrypted cache file per vault
+func valueCacheDir() string {
+	return dirs.ValueCacheDir()
+}
+
+// openValueCache returns the current vault's value cache, or nil if the
+// keyring cannot hold its key
+func openValueCache() *valuecache.Cache {
+	vaultID := keyring.VaultID(vaultPath)
+	key, err := sessionMgr.GetKeyringManager().GetOrCreateCacheKey(vaultID)
+	if err != nil {
+		printVerbose("Value cache disabled: %v", err)
+		return nil
+	}
+	return valuecache.New(valueCacheDir(), vaultID, key)
+}
+
+// cachedSecret returns a cached value for key that is at most maxAge old
+func cachedSecret(key string, maxAge time.Duration) (*valuecache.Entry, bool) {
+	// Don't create a cache key just to find there is nothing cached
+	if _, err := os.Stat(valuecache.Path(valueCacheDir(), keyring.VaultID(vaultPath))); err != nil {
+		return nil, false
+	}
+	cache := openValueCache()
+	if cache == nil {
+		return nil, false
+	}
+	return cache.Get(key, maxAge, time.Now())
+}
+
+// servableFromCache unlocks the vault and checks that entry, the cached
+// value of key, may still be served: the secret is neither hidden nor
+// archived and is still at the cached revision. It returns the policy
+// defaults to deliver the value under: the secret's current ones, made as
+// strict as those of the secrets it referenced when cached. The read is
+// audited like any other; an entry that can no longer be served is dropped
+// from the cache so the caller reads, and reports on, the vault itself.
+func servableFromCache(key string, entry *valuecache.Entry) (policy.Defaults, bool) {
+	if err := ensureAuthenticated(); err != nil {
+		handleError(err, "Authentication failed")
+		return policy.Defaults{}, false
+	}
+	secret, err := vaultDB.PeekSecret(key)
+	if err != nil || secret.ArchivedAt != nil || secret.Revision != entry.Revision {
+		invalidateCachedSecret(key)
+		return policy.Defaults{}, false
+	}
+	auditSecretAccess(key)
+
+	defaults := resolvePolicy(secret.Key, secret.Tags)
+	tighten(&defaults, policy.Defaults{ClipboardOnly: entry.ClipboardOnly, ClearAfter: entry.ClearAfter})
+	return defaults, true
+}
+
+// cacheSecret stores a freshly read value for later --max-age reads
+func cacheSecret(secret *database.Secret, value string, defaults policy.Defaults) {
+	cache := openValueCache()
+	if cache == nil {
+		return
+	}
+	entry := valuecache.Entry{
+		Value:         value,
+		Revision:      secret.Revision,
+		FetchedAt:     time.Now(),
+		ClipboardOnly: defaults.ClipboardOnly,
+		ClearAfter:    defaults.ClearAfter,
+	}
+	if err := cache.Put(secret.Key, entry, valueCacheRetention); err != nil {
+		printVerbose("Failed to update value cache: %v", err)
+	}
+}
+
+// invalidateCachedSecret drops key from the value cache after it changed.
+// Composed secrets that reference key may stay cached until they expire.
+func invalidateCachedSecret(key string) {
+	if _, err := os.Stat(valuecache.Path(valueCacheDir(), keyring.VaultID(vaultPath))); err != nil {
+		return
+	}
+	cache := openValueCache()
+	if cache == nil {
+		// Without the key the cache is unreadable anyway; remove it
+		valuecache.Clear(valueCacheDir(), keyring.VaultID(vaultPath))
+		return
+	}
+	if err := cache.Invalidate(key); err != nil {
+		printVerbose("Failed to update value cache: %v", err)
+	}
+}
+
+// clearValueCache removes the current vault's value cache, or with all the
+// cache of every vault, when secrets may no longer be served without
+// checking the vault: travel mode hid some, the policy rules the cached
+// values were delivered under changed, or the user locked up
+func clearValueCache(all bool) {
+	var err error
+	if all {
+		err = os.RemoveAll(valueCacheDir())
+	} else {
+		err = valuecache.Clear(valueCacheDir(), keyring.VaultID(vaultPath))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to clear the value cache: %v\n", err)
+	}
+}
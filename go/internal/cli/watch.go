@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lockr/go/internal/database"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Stream changes to the vault's secrets",
+	Long: `Print an event whenever a secret is created, updated or deleted, until
+interrupted. Events carry the key, the kind of change and the time it was
+seen, never the value, so other tools can react to changes without polling
+the vault themselves.
+
+Changes made by other lockr processes are noticed within --interval.
+
+Examples:
+  lockr watch                           # Human-readable events
+  lockr watch --json | my-reloader      # One JSON object per line
+  lockr watch --interval 500ms`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		interval, _ := cmd.Flags().GetDuration("interval")
+
+		if err := ensureAuthenticated(); err != nil {
+			handleError(err, "Authentication failed")
+			return
+		}
+
+		watcher, ok := vaultDB.(database.Watcher)
+		if !ok {
+			handleError(fmt.Errorf("this storage engine cannot stream changes"), "")
+			return
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		events, err := watcher.Subscribe(ctx, interval)
+		if err != nil {
+			handleError(err, "Failed to watch vault")
+			return
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		for event := range events {
+			if asJSON {
+				encoder.Encode(event)
+				continue
+			}
+			if event.Op == database.ChangeDeleted {
+				fmt.Printf("%s  %-8s %s\n", event.Timestamp.Format(time.RFC3339), event.Op, event.Key)
+			} else {
+				fmt.Printf("%s  %-8s %s (revision %d)\n", event.Timestamp.Format(time.RFC3339), event.Op, event.Key, event.Revision)
+			}
+		}
+	},
+}
+
+func init() {
+	watchCmd.Flags().Bool("json", false, "Print one JSON object per event")
+	watchCmd.Flags().Duration("interval", database.DefaultWatchInterval, "How often to check for changes by other processes")
+}
@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/lockr/go/internal/workspace"
+)
+
+// activeWorkspace is the trusted workspace file in effect, if any
+var activeWorkspace *workspace.Workspace
+
+var workspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Show or trust the project workspace file",
+	Long: `A project can keep its own vault, separate from your personal one, by
+adding a .lockr.toml file:
+
+  vault = "secrets/project.lockr"   # relative to the file
+  namespace = "myapp"               # optional default key namespace
+
+lockr looks for this file in the current directory and its parents. The
+first time it finds one, and again whenever the file changes, it asks
+whether to trust it; an untrusted file is ignored. An explicit --vault flag
+always wins.
+
+Inside a namespace, relative keys given to get, set and delete are prefixed
+with it ('lockr get db' reads myapp/db); start a key with / to address the
+whole vault ('lockr get /personal/email').
+
+Examples:
+  lockr workspace            # Show the workspace file in effect
+  lockr workspace trust      # Trust the current file without prompting
+  lockr workspace untrust    # Stop using it`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ws, err := findWorkspace()
+		if err != nil {
+			handleError(err, "Invalid workspace file")
+			return
+		}
+		if ws == nil {
+			fmt.Printf("No %s found in this directory or its parents\n", workspace.FileName)
+			return
+		}
+
+		trusted, err := workspaceTrust().IsTrusted(ws)
+		if err != nil {
+			handleError(err, "Failed to read trusted workspaces")
+			return
+		}
+
+		fmt.Printf("Workspace file: %s\n", ws.Path)
+		fmt.Printf("  Trusted:   %t\n", trusted)
+		if ws.Vault != "" {
+			fmt.Printf("  Vault:     %s\n", ws.Vault)
+		}
+		if ws.Namespace != "" {
+			fmt.Printf("  Namespace: %s\n", ws.Namespace)
+		}
+		if !trusted {
+			fmt.Println("Run 'lockr workspace trust' to use it")
+		}
+	},
+}
+
+var workspaceTrustCmd = &cobra.Command{
+	Use:   "trust",
+	Short: "Trust the workspace file as it is now",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ws, err := findWorkspace()
+		if err != nil {
+			handleError(err, "Invalid workspace file")
+			return
+		}
+		if ws == nil {
+			handleError(fmt.Errorf("no %s found in this directory or its parents", workspace.FileName), "")
+			return
+		}
+		if err := workspaceTrust().Trust(ws); err != nil {
+			handleError(err, "Failed to save trust")
+			return
+		}
+		printInfo("Trusted %s", ws.Path)
+	},
+}
+
+var workspaceUntrustCmd = &cobra.Command{
+	Use:   "untrust",
+	Short: "Stop using the workspace file",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ws, err := findWorkspace()
+		if err != nil {
+			handleError(err, "Invalid workspace file")
+			return
+		}
+		if ws == nil {
+			handleError(fmt.Errorf("no %s found in this directory or its parents", workspace.FileName), "")
+			return
+		}
+		if err := workspaceTrust().Revoke(ws.Path); err != nil {
+			handleError(err, "Failed to save trust")
+			return
+		}
+		fmt.Printf("No longer trusting %s\n", ws.Path)
+	},
+}
+
+// selectWorkspace switches to the project vault named by a trusted
+// workspace file, asking the user to trust new or changed files
+func selectWorkspace(cmd *cobra.Command) {
+	if cmd == workspaceCmd || cmd.Parent() == workspaceCmd {
+		return // These manage the file rather than use it
+	}
+	if cmd.Flags().Changed("vault") {
+		return
+	}
+
+	ws, err := findWorkspace()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring workspace file: %v\n", err)
+		return
+	}
+	if ws == nil {
+		return
+	}
+
+	trust := workspaceTrust()
+	trusted, err := trust.IsTrusted(ws)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to read trusted workspaces: %v\n", err)
+		return
+	}
+	if !trusted {
+		if !confirmWorkspaceTrust(ws) {
+			fmt.Fprintf(os.Stderr, "Ignoring untrusted %s; run 'lockr workspace trust' to use it\n", ws.Path)
+			return
+		}
+		if err := trust.Trust(ws); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to save trust: %v\n", err)
+		}
+	}
+
+	activeWorkspace = ws
+	if ws.Vault != "" {
+		vaultPath = ws.Vault
+	}
+	printVerbose("Using workspace %s", ws.Path)
+}
+
+// confirmWorkspaceTrust asks whether to trust a new or changed workspace
+// file. It never trusts anything without a terminal to ask on.
+func confirmWorkspaceTrust(ws *workspace.Workspace) bool {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false
+	}
+
+	var settings []string
+	if ws.Vault != "" {
+		settings = append(settings, "vault "+ws.Vault)
+	}
+	if ws.Namespace != "" {
+		settings = append(settings, "namespace "+ws.Namespace)
+	}
+	fmt.Fprintf(os.Stderr, "Found new or changed %s (%s).\nTrust it? (y/N): ", ws.Path, strings.Join(settings, ", "))
+
+	var response string
+	fmt.Scanln(&response)
+	response = strings.ToLower(response)
+	return response == "y" || response == "yes"
+}
+
+// findWorkspace looks for a workspace file from the current directory up
+func findWorkspace() (*workspace.Workspace, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return workspace.Find(cwd)
+}
+
+// workspaceTrust returns the store of trusted workspace files
+func workspaceTrust() *workspace.TrustStore {
+	return workspace.NewTrustStore(dirs.TrustFile())
+}
+
+func init() {
+	workspaceCmd.AddCommand(workspaceTrustCmd)
+	workspaceCmd.AddCommand(workspaceUntrustCmd)
+}
@@ -0,0 +1,236 @@
+package clipboard
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Backend is a mechanism for reading and writing the system clipboard
+type Backend interface {
+	// Name returns a short identifier for the backend (e.g. "xclip")
+	Name() string
+
+	// Available reports whether the backend can be used on this system
+	Available() bool
+
+	// Copy replaces the clipboard content with text
+	Copy(text string) error
+
+	// Paste returns the current clipboard content
+	Paste() (string, error)
+
+	// Clear empties the clipboard
+	Clear() error
+}
+
+// commandBackend drives an external clipboard tool that reads from stdin
+type commandBackend struct {
+	name     string
+	copyCmd  []string
+	pasteCmd []string
+	clearCmd []string // optional; without it Clear copies an empty string
+}
+
+// Name returns the tool name
+func (b *commandBackend) Name() string {
+	return b.name
+}
+
+// Available reports whether both the copy and paste tools are on PATH
+func (b *commandBackend) Available() bool {
+	return isCommandAvailable(b.copyCmd[0]) && isCommandAvailable(b.pasteCmd[0])
+}
+
+// Copy pipes text into the copy command
+func (b *commandBackend) Copy(text string) error {
+	return runWithStdin(b.copyCmd, text)
+}
+
+// Paste reads the output of the paste command
+func (b *commandBackend) Paste() (string, error) {
+	output, err := exec.Command(b.pasteCmd[0], b.pasteCmd[1:]...).Output()
+	if err != nil {
+		return "", err
+	}
+	return string(output), nil
+}
+
+// Clear runs the clear command, or copies an empty string
+func (b *commandBackend) Clear() error {
+	if len(b.clearCmd) > 0 {
+		if err := exec.Command(b.clearCmd[0], b.clearCmd[1:]...).Run(); err != nil {
+			return fmt.Errorf("%s: %w", b.clearCmd[0], err)
+		}
+		return nil
+	}
+	return b.Copy("")
+}
+
+// powershellBackend uses PowerShell's clipboard cmdlets on Windows
+type powershellBackend struct{}
+
+// Name returns the backend name
+func (powershellBackend) Name() string {
+	return "powershell"
+}
+
+// Available reports whether PowerShell is on PATH
+func (powershellBackend) Available() bool {
+	return isCommandAvailable("powershell")
+}
+
+// Copy sets the clipboard using Set-Clipboard
+func (powershellBackend) Copy(text string) error {
+	return exec.Command("powershell", "-command", "Set-Clipboard", "-Value", text).Run()
+}
+
+// Paste reads the clipboard using Get-Clipboard
+func (powershellBackend) Paste() (string, error) {
+	output, err := exec.Command("powershell", "-command", "Get-Clipboard").Output()
+	if err != nil {
+		return "", err
+	}
+	return string(output), nil
+}
+
+// Clear copies an empty string
+func (b powershellBackend) Clear() error {
+	return b.Copy("")
+}
+
+// MemoryBackend is an in-memory clipboard used for tests and headless systems
+type MemoryBackend struct {
+	mu      sync.Mutex
+	content string
+	history []string
+}
+
+// NewMemoryBackend creates an empty in-memory clipboard
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{}
+}
+
+// Name returns the backend name
+func (b *MemoryBackend) Name() string {
+	return "memory"
+}
+
+// Available always returns true
+func (b *MemoryBackend) Available() bool {
+	return true
+}
+
+// Copy stores text and records it in the copy history
+func (b *MemoryBackend) Copy(text string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.content = text
+	b.history = append(b.history, text)
+	return nil
+}
+
+// Paste returns the stored text
+func (b *MemoryBackend) Paste() (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.content, nil
+}
+
+// Clear empties the stored text
+func (b *MemoryBackend) Clear() error {
+	return b.Copy("")
+}
+
+// History returns every value copied so far, including clears
+func (b *MemoryBackend) History() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.history...)
+}
+
+// Auto is the backend name that picks one by detection
+const Auto = "auto"
+
+// DetectBackend returns the first available backend for this platform. In
+// an SSH session without a clipboard tool it falls back to OSC 52, which
+// reaches the clipboard of the terminal on the other end. It returns nil if
+// there is no clipboard to use.
+func DetectBackend() Backend {
+	for _, backend := range platformBackends() {
+		if backend.Available() {
+			return backend
+		}
+	}
+	if InSSH(os.Getenv) {
+		for _, backend := range terminalBackends() {
+			if backend.Available() {
+				return backend
+			}
+		}
+	}
+	return nil
+}
+
+// SelectBackend returns the backend called name, or the detected one for
+// Auto or "". Naming a backend that is not available is an error, while
+// detection finding none returns nil.
+func SelectBackend(name string) (Backend, error) {
+	if name == "" || name == Auto {
+		return DetectBackend(), nil
+	}
+	candidates := append(platformBackends(), terminalBackends()...)
+	names := []string{Auto}
+	for _, backend := range candidates {
+		if backend.Name() != name {
+			names = append(names, backend.Name())
+			continue
+		}
+		if !backend.Available() {
+			if name == OSC52 {
+				return nil, fmt.Errorf("clipboard backend %s needs a terminal", name)
+			}
+			return nil, fmt.Errorf("clipboard backend %s is not installed", name)
+		}
+		return backend, nil
+	}
+	return nil, fmt.Errorf("unknown clipboard backend %q (use %s)", name, strings.Join(names, ", "))
+}
+
+// Linux session types, as reported by Session
+const (
+	SessionWayland = "wayland"
+	SessionX11     = "x11"
+)
+
+// Session returns the kind of graphical session the environment describes:
+// SessionWayland, SessionX11, or "" for none (e.g. a console or SSH login)
+func Session(getenv func(string) string) string {
+	switch {
+	case getenv("WAYLAND_DISPLAY") != "" || getenv("XDG_SESSION_TYPE") == SessionWayland:
+		return SessionWayland
+	case getenv("DISPLAY") != "":
+		return SessionX11
+	}
+	return ""
+}
+
+// runWithStdin runs a command and writes input to its stdin. Output is not
+// captured because tools like xclip fork a process that keeps serving the
+// selection and would hold the pipes open.
+func runWithStdin(args []string, input string) error {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(input)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	return nil
+}
+
+// isCommandAvailable checks if a command is available in PATH
+func isCommandAvailable(command string) bool {
+	_, err := exec.LookPath(command)
+	return err == nil
+}
@@ -1,10 +1,11 @@
 package clipboard
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"runtime"
+	"sync"
 	"time"
 )
 
@@ -16,48 +17,96 @@ const (
 	MaxClipboardSize = 1024 * 1024 // 1MB
 )
 
+// ErrNoBackend is returned when no clipboard backend is available
+var ErrNoBackend = errors.New("no clipboard backend available")
+
 // Manager handles clipboard operations with auto-clear functionality
 type Manager struct {
+	mu         sync.Mutex
+	backend    Backend
 	clearDelay time.Duration
 	clearTimer *time.Timer
 	lastCopy   string
+	generation uint64 // incremented on every copy so stale timers can be ignored
+	quiet      bool
 }
 
-// NewManager creates a new clipboard manager with default settings
+// NewManager creates a new clipboard manager using the detected platform backend
 func NewManager() *Manager {
+	return NewManagerWithBackend(DetectBackend())
+}
+
+// NewManagerWithBackend creates a clipboard manager using a specific backend
+func NewManagerWithBackend(backend Backend) *Manager {
 	return &Manager{
+		backend:    backend,
 		clearDelay: DefaultClearDelay,
 	}
 }
 
+// Backend returns the backend used by the manager
+func (m *Manager) Backend() Backend {
+	return m.backend
+}
+
 // SetClearDelay configures how long to wait before auto-clearing clipboard
 func (m *Manager) SetClearDelay(delay time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.clearDelay = delay
 }
 
+// SetQuiet turns off the confirmation printed by CopySecretWithNotification
+func (m *Manager) SetQuiet(quiet bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quiet = quiet
+}
+
+// ClearDelay returns the configured auto-clear delay
+func (m *Manager) ClearDelay() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.clearDelay
+}
+
 // Copy copies the given text to the system clipboard with auto-clear
 func (m *Manager) Copy(text string) error {
+	return m.CopyWithCustomDelay(text, m.ClearDelay())
+}
+
+// CopyWithCustomDelay copies text and sets a custom clear delay for this operation
+func (m *Manager) CopyWithCustomDelay(text string, delay time.Duration) error {
 	if len(text) > MaxClipboardSize {
 		return fmt.Errorf("clipboard content too large: %d bytes (max %d)", len(text), MaxClipboardSize)
 	}
+	if m.backend == nil {
+		return ErrNoBackend
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	// Cancel any existing clear timer
 	if m.clearTimer != nil {
 		m.clearTimer.Stop()
+		m.clearTimer = nil
 	}
 
 	// Copy to system clipboard
-	if err := m.copyToSystem(text); err != nil {
+	if err := m.backend.Copy(text); err != nil {
 		return fmt.Errorf("failed to copy to clipboard: %w", err)
 	}
 
 	// Store the text we copied for verification during clear
 	m.lastCopy = text
+	m.generation++
+	generation := m.generation
 
 	// Set up auto-clear timer if delay is positive
-	if m.clearDelay > 0 {
-		m.clearTimer = time.AfterFunc(m.clearDelay, func() {
-			if err := m.clearIfUnchanged(); err != nil {
+	if delay > 0 {
+		m.clearTimer = time.AfterFunc(delay, func() {
+			if err := m.clearIfUnchanged(generation); err != nil {
 				// Log error but don't fail - this is a background operation
 				fmt.Fprintf(os.Stderr, "Warning: failed to auto-clear clipboard: %v\n", err)
 			}
@@ -67,17 +116,19 @@ func (m *Manager) Copy(text string) error {
 	return nil
 }
 
-// CopyWithCustomDelay copies text and sets a custom clear delay for this operation
-func (m *Manager) CopyWithCustomDelay(text string, delay time.Duration) error {
-	oldDelay := m.clearDelay
-	m.SetClearDelay(delay)
-	err := m.Copy(text)
-	m.SetClearDelay(oldDelay)
-	return err
-}
-
 // Clear immediately clears the clipboard
 func (m *Manager) Clear() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.clearLocked()
+}
+
+// clearLocked clears the clipboard; the caller must hold m.mu
+func (m *Manager) clearLocked() error {
+	if m.backend == nil {
+		return ErrNoBackend
+	}
+
 	// Cancel any pending auto-clear
 	if m.clearTimer != nil {
 		m.clearTimer.Stop()
@@ -85,7 +136,7 @@ func (m *Manager) Clear() error {
 	}
 
 	// Clear the system clipboard
-	if err := m.clearSystem(); err != nil {
+	if err := m.backend.Clear(); err != nil {
 		return fmt.Errorf("failed to clear clipboard: %w", err)
 	}
 
@@ -95,7 +146,10 @@ func (m *Manager) Clear() error {
 
 // GetContent retrieves the current clipboard content
 func (m *Manager) GetContent() (string, error) {
-	content, err := m.getFromSystem()
+	if m.backend == nil {
+		return "", ErrNoBackend
+	}
+	content, err := m.backend.Paste()
 	if err != nil {
 		return "", fmt.Errorf("failed to get clipboard content: %w", err)
 	}
@@ -103,13 +157,27 @@ func (m *Manager) GetContent() (string, error) {
 }
 
 // clearIfUnchanged clears the clipboard only if it still contains our last copied text
-func (m *Manager) clearIfUnchanged() error {
+func (m *Manager) clearIfUnchanged(generation uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// A newer copy replaced the timer that scheduled this clear
+	if generation != m.generation {
+		return nil
+	}
+
+	m.clearTimer = nil
 	if m.lastCopy == "" {
 		return nil // Nothing to clear
 	}
 
-	// Check current clipboard content
+	// Check current clipboard content. A backend that cannot read it back
+	// is cleared regardless: a value lingering is worse than losing one
+	// copied since.
 	current, err := m.GetContent()
+	if errors.Is(err, ErrPasteUnsupported) {
+		return m.clearLocked()
+	}
 	if err != nil {
 		// If we can't read clipboard, err on the side of caution and don't clear
 		return fmt.Errorf("cannot verify clipboard content: %w", err)
@@ -117,7 +185,7 @@ func (m *Manager) clearIfUnchanged() error {
 
 	// Only clear if the clipboard still contains what we put there
 	if current == m.lastCopy {
-		return m.Clear()
+		return m.clearLocked()
 	}
 
 	// Clipboard content has changed - user has copied something else
@@ -125,199 +193,24 @@ func (m *Manager) clearIfUnchanged() error {
 	return nil
 }
 
-// copyToSystem copies text to the system clipboard (platform-specific)
-func (m *Manager) copyToSystem(text string) error {
-	switch runtime.GOOS {
-	case "darwin":
-		return m.copyDarwin(text)
-	case "linux":
-		return m.copyLinux(text)
-	case "windows":
-		return m.copyWindows(text)
-	default:
-		return fmt.Errorf("unsupported platform: %s", runtime.GOOS)
-	}
-}
-
-// getFromSystem gets text from the system clipboard (platform-specific)
-func (m *Manager) getFromSystem() (string, error) {
-	switch runtime.GOOS {
-	case "darwin":
-		return m.getDarwin()
-	case "linux":
-		return m.getLinux()
-	case "windows":
-		return m.getWindows()
-	default:
-		return "", fmt.Errorf("unsupported platform: %s", runtime.GOOS)
-	}
-}
-
-// clearSystem clears the system clipboard (platform-specific)
-func (m *Manager) clearSystem() error {
-	switch runtime.GOOS {
-	case "darwin":
-		return m.clearDarwin()
-	case "linux":
-		return m.clearLinux()
-	case "windows":
-		return m.clearWindows()
-	default:
-		return fmt.Errorf("unsupported platform: %s", runtime.GOOS)
-	}
-}
-
-// macOS implementations using pbcopy/pbpaste
-func (m *Manager) copyDarwin(text string) error {
-	cmd := exec.Command("pbcopy")
-	cmd.Stdin = nil
-
-	// Use a pipe to send the text
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		return err
-	}
-
-	if err := cmd.Start(); err != nil {
-		stdin.Close()
-		return err
-	}
-
-	_, err = stdin.Write([]byte(text))
-	stdin.Close()
-
-	if err != nil {
-		cmd.Wait()
-		return err
-	}
-
-	return cmd.Wait()
-}
-
-func (m *Manager) getDarwin() (string, error) {
-	cmd := exec.Command("pbpaste")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", err
-	}
-	return string(output), nil
-}
-
-func (m *Manager) clearDarwin() error {
-	return m.copyDarwin("")
-}
-
-// Linux implementations using xclip
-func (m *Manager) copyLinux(text string) error {
-	// Try xclip first
-	cmd := exec.Command("xclip", "-selection", "clipboard")
-	cmd.Stdin = nil
-
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		// Fallback to xsel
-		return m.copyLinuxXsel(text)
-	}
-
-	if err := cmd.Start(); err != nil {
-		stdin.Close()
-		return m.copyLinuxXsel(text)
-	}
-
-	_, err = stdin.Write([]byte(text))
-	stdin.Close()
-
-	if err != nil {
-		cmd.Wait()
-		return m.copyLinuxXsel(text)
-	}
-
-	return cmd.Wait()
-}
-
-func (m *Manager) copyLinuxXsel(text string) error {
-	cmd := exec.Command("xsel", "--clipboard", "--input")
-	cmd.Stdin = nil
-
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		return err
-	}
-
-	if err := cmd.Start(); err != nil {
-		stdin.Close()
-		return err
-	}
-
-	_, err = stdin.Write([]byte(text))
-	stdin.Close()
-
-	if err != nil {
-		cmd.Wait()
-		return err
-	}
-
-	return cmd.Wait()
-}
-
-func (m *Manager) getLinux() (string, error) {
-	// Try xclip first
-	cmd := exec.Command("xclip", "-selection", "clipboard", "-output")
-	output, err := cmd.Output()
-	if err == nil {
-		return string(output), nil
-	}
-
-	// Fallback to xsel
-	cmd = exec.Command("xsel", "--clipboard", "--output")
-	output, err = cmd.Output()
-	if err != nil {
-		return "", err
-	}
-	return string(output), nil
-}
-
-func (m *Manager) clearLinux() error {
-	return m.copyLinux("")
-}
-
-// Windows implementations using PowerShell
-func (m *Manager) copyWindows(text string) error {
-	cmd := exec.Command("powershell", "-command", "Set-Clipboard", "-Value", text)
-	return cmd.Run()
-}
-
-func (m *Manager) getWindows() (string, error) {
-	cmd := exec.Command("powershell", "-command", "Get-Clipboard")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", err
+// WaitForClear blocks until any pending auto-clear has run or the timeout elapses
+func (m *Manager) WaitForClear(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		m.mu.Lock()
+		pending := m.clearTimer != nil
+		m.mu.Unlock()
+		if !pending {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
 	}
-	return string(output), nil
-}
-
-func (m *Manager) clearWindows() error {
-	return m.copyWindows("")
+	return false
 }
 
 // IsSupported returns true if clipboard operations are supported on this platform
 func IsSupported() bool {
-	switch runtime.GOOS {
-	case "darwin":
-		return isCommandAvailable("pbcopy") && isCommandAvailable("pbpaste")
-	case "linux":
-		return isCommandAvailable("xclip") || isCommandAvailable("xsel")
-	case "windows":
-		return isCommandAvailable("powershell")
-	default:
-		return false
-	}
-}
-
-// isCommandAvailable checks if a command is available in PATH
-func isCommandAvailable(command string) bool {
-	_, err := exec.LookPath(command)
-	return err == nil
+	return DetectBackend() != nil
 }
 
 // CopySecretWithNotification copies a secret to clipboard and shows user feedback
@@ -326,39 +219,54 @@ func (m *Manager) CopySecretWithNotification(secret string) error {
 		return err
 	}
 
+	m.mu.Lock()
+	quiet := m.quiet
+	m.mu.Unlock()
+
 	// Show user notification
-	fmt.Printf("Secret copied to clipboard (will auto-clear in %v)\n", m.clearDelay)
+	if !quiet {
+		fmt.Printf("Secret copied to clipboard (will auto-clear in %v)\n", m.ClearDelay())
+	}
 	return nil
 }
 
 // GetStatus returns information about the clipboard manager state
 func (m *Manager) GetStatus() map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	status := map[string]interface{}{
-		"supported":    IsSupported(),
+		"supported":    m.backend != nil,
 		"clear_delay":  m.clearDelay.String(),
 		"auto_clear":   m.clearDelay > 0,
 		"timer_active": m.clearTimer != nil,
 	}
 
+	if m.backend != nil {
+		status["backend"] = m.backend.Name()
+	}
+
 	// Add platform-specific information
 	switch runtime.GOOS {
 	case "darwin":
 		status["platform"] = "macOS"
-		status["commands"] = []string{"pbcopy", "pbpaste"}
 	case "linux":
 		status["platform"] = "Linux"
-		commands := []string{}
-		if isCommandAvailable("xclip") {
-			commands = append(commands, "xclip")
-		}
-		if isCommandAvailable("xsel") {
-			commands = append(commands, "xsel")
-		}
-		status["commands"] = commands
+		status["session"] = Session(os.Getenv)
 	case "windows":
 		status["platform"] = "Windows"
-		status["commands"] = []string{"powershell"}
+	default:
+		status["platform"] = runtime.GOOS
+	}
+
+	// Available tools in preference order; the first is the one detected
+	commands := []string{}
+	for _, backend := range platformBackends() {
+		if backend.Available() {
+			commands = append(commands, backend.Name())
+		}
 	}
+	status["commands"] = commands
 
 	return status
 }
@@ -0,0 +1,92 @@
+package clipboard
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_CopyAndClear(t *testing.T) {
+	backend := NewMemoryBackend()
+	m := NewManagerWithBackend(backend)
+	m.SetClearDelay(0)
+
+	require.NoError(t, m.Copy("secret"))
+	content, err := m.GetContent()
+	require.NoError(t, err)
+	assert.Equal(t, "secret", content)
+
+	require.NoError(t, m.Clear())
+	content, err = m.GetContent()
+	require.NoError(t, err)
+	assert.Equal(t, "", content)
+}
+
+func TestManager_AutoClear(t *testing.T) {
+	backend := NewMemoryBackend()
+	m := NewManagerWithBackend(backend)
+	m.SetClearDelay(20 * time.Millisecond)
+
+	require.NoError(t, m.Copy("secret"))
+	assert.True(t, m.WaitForClear(time.Second))
+
+	content, _ := backend.Paste()
+	assert.Equal(t, "", content)
+	assert.Equal(t, []string{"secret", ""}, backend.History())
+}
+
+func TestManager_AutoClearSkipsChangedContent(t *testing.T) {
+	backend := NewMemoryBackend()
+	m := NewManagerWithBackend(backend)
+	m.SetClearDelay(20 * time.Millisecond)
+
+	require.NoError(t, m.Copy("secret"))
+
+	// The user copies something else before the timer fires
+	require.NoError(t, backend.Copy("something else"))
+	assert.True(t, m.WaitForClear(time.Second))
+
+	content, _ := backend.Paste()
+	assert.Equal(t, "something else", content)
+}
+
+func TestManager_SequentialCopiesResetTimer(t *testing.T) {
+	backend := NewMemoryBackend()
+	m := NewManagerWithBackend(backend)
+
+	require.NoError(t, m.CopyWithCustomDelay("first", 20*time.Millisecond))
+	require.NoError(t, m.CopyWithCustomDelay("second", time.Hour))
+
+	// The first timer must not clear the second value
+	time.Sleep(60 * time.Millisecond)
+	content, _ := backend.Paste()
+	assert.Equal(t, "second", content)
+
+	require.NoError(t, m.Clear())
+}
+
+func TestManager_SizeLimit(t *testing.T) {
+	m := NewManagerWithBackend(NewMemoryBackend())
+
+	err := m.Copy(strings.Repeat("x", MaxClipboardSize+1))
+	assert.Error(t, err)
+}
+
+func TestManager_NoBackend(t *testing.T) {
+	m := NewManagerWithBackend(nil)
+
+	assert.ErrorIs(t, m.Copy("secret"), ErrNoBackend)
+	assert.ErrorIs(t, m.Clear(), ErrNoBackend)
+	assert.Equal(t, false, m.GetStatus()["supported"])
+}
+
+func TestManager_Status(t *testing.T) {
+	m := NewManagerWithBackend(NewMemoryBackend())
+
+	status := m.GetStatus()
+	assert.Equal(t, "memory", status["backend"])
+	assert.Equal(t, true, status["auto_clear"])
+}
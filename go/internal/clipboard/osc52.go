@@ -0,0 +1,96 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// OSC52 is the name of the terminal escape sequence backend
+const OSC52 = "osc52"
+
+// ErrPasteUnsupported is returned by backends that can write the clipboard
+// but not read it back
+var ErrPasteUnsupported = errors.New("reading the clipboard is not supported")
+
+// osc52Backend sets the clipboard of the terminal lockr runs in with the
+// OSC 52 escape sequence. It works over SSH, where the clipboard belongs to
+// the machine the terminal runs on, as long as the terminal emulator allows
+// it. Terminals rarely let programs read the clipboard, so Paste is not
+// supported.
+type osc52Backend struct {
+	// open returns where escape sequences are written, normally /dev/tty so
+	// redirecting stdout doesn't swallow them
+	open   func() (io.WriteCloser, error)
+	getenv func(string) string
+}
+
+// NewOSC52Backend returns a backend that writes OSC 52 sequences to the
+// controlling terminal
+func NewOSC52Backend() Backend {
+	return &osc52Backend{open: openTTY, getenv: os.Getenv}
+}
+
+func openTTY() (io.WriteCloser, error) {
+	return os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+}
+
+// Name returns the backend name
+func (b *osc52Backend) Name() string {
+	return OSC52
+}
+
+// Available reports whether there is a terminal to write to
+func (b *osc52Backend) Available() bool {
+	w, err := b.open()
+	if err != nil {
+		return false
+	}
+	w.Close()
+	return true
+}
+
+// Copy writes text to the terminal's clipboard
+func (b *osc52Backend) Copy(text string) error {
+	w, err := b.open()
+	if err != nil {
+		return fmt.Errorf("osc52: no terminal: %w", err)
+	}
+	defer w.Close()
+	_, err = io.WriteString(w, osc52Sequence(text, b.getenv))
+	return err
+}
+
+// Paste is not supported
+func (b *osc52Backend) Paste() (string, error) {
+	return "", ErrPasteUnsupported
+}
+
+// Clear copies an empty string
+func (b *osc52Backend) Clear() error {
+	return b.Copy("")
+}
+
+// osc52Sequence returns the escape sequence that sets the clipboard to
+// text. tmux and screen swallow unknown sequences, so inside them it is
+// wrapped to be passed through to the outer terminal.
+func osc52Sequence(text string, getenv func(string) string) string {
+	seq := "\033]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	switch {
+	case getenv("TMUX") != "":
+		// Escape characters inside the passthrough are doubled
+		return "\033Ptmux;" + strings.ReplaceAll(seq, "\033", "\033\033") + "\033\\"
+	case strings.HasPrefix(getenv("TERM"), "screen"):
+		return "\033P" + seq + "\033\\"
+	}
+	return seq
+}
+
+// InSSH reports whether the environment is that of an interactive SSH
+// login, which has a terminal for OSC 52
+func InSSH(getenv func(string) string) bool {
+	return getenv("SSH_TTY") != ""
+}
@@ -0,0 +1,59 @@
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func env(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+func TestOSC52Sequence(t *testing.T) {
+	assert.Equal(t, "\033]52;c;c2VjcmV0\a", osc52Sequence("secret", env(nil)))
+	assert.Equal(t, "\033Ptmux;\033\033]52;c;c2VjcmV0\a\033\\",
+		osc52Sequence("secret", env(map[string]string{"TMUX": "/tmp/tmux-1000/default,1,0", "TERM": "screen"})))
+	assert.Equal(t, "\033P\033]52;c;c2VjcmV0\a\033\\", osc52Sequence("secret", env(map[string]string{"TERM": "screen.xterm-256color"})))
+	assert.Equal(t, "\033]52;c;\a", osc52Sequence("", env(nil)))
+}
+
+func TestOSC52Backend(t *testing.T) {
+	var out bytes.Buffer
+	b := &osc52Backend{open: func() (io.WriteCloser, error) { return nopCloser{&out}, nil }, getenv: env(nil)}
+
+	assert.True(t, b.Available())
+	require.NoError(t, b.Copy("secret"))
+	assert.Equal(t, "\033]52;c;c2VjcmV0\a", out.String())
+	_, err := b.Paste()
+	assert.ErrorIs(t, err, ErrPasteUnsupported)
+
+	noTTY := &osc52Backend{open: func() (io.WriteCloser, error) { return nil, errors.New("no tty") }, getenv: env(nil)}
+	assert.False(t, noTTY.Available())
+	assert.Error(t, noTTY.Copy("secret"))
+}
+
+func TestManager_AutoClearWithoutPaste(t *testing.T) {
+	var out bytes.Buffer
+	b := &osc52Backend{open: func() (io.WriteCloser, error) { return nopCloser{&out}, nil }, getenv: env(nil)}
+	m := NewManagerWithBackend(b)
+	m.SetClearDelay(20 * time.Millisecond)
+
+	require.NoError(t, m.Copy("secret"))
+	assert.True(t, m.WaitForClear(time.Second))
+	assert.Equal(t, "\033]52;c;c2VjcmV0\a\033]52;c;\a", out.String())
+}
+
+func TestInSSH(t *testing.T) {
+	assert.True(t, InSSH(env(map[string]string{"SSH_TTY": "/dev/pts/3"})))
+	assert.False(t, InSSH(env(map[string]string{"SSH_CONNECTION": "10.0.0.2 50000 10.0.0.1 22"})))
+}
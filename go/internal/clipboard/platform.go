@@ -0,0 +1,50 @@
+//go:build !minimal
+
+package clipboard
+
+import (
+	"os"
+	"runtime"
+)
+
+// platformBackends returns the candidate backends for the current OS in
+// preference order
+func platformBackends() []Backend {
+	switch runtime.GOOS {
+	case "darwin":
+		return []Backend{
+			&commandBackend{name: "pbcopy", copyCmd: []string{"pbcopy"}, pasteCmd: []string{"pbpaste"}},
+		}
+	case "linux":
+		return linuxBackends(Session(os.Getenv))
+	case "windows":
+		return []Backend{powershellBackend{}}
+	default:
+		return nil
+	}
+}
+
+// terminalBackends returns the backends that go through the terminal
+// rather than a clipboard tool, for sessions without one such as SSH logins
+func terminalBackends() []Backend {
+	return []Backend{NewOSC52Backend()}
+}
+
+// linuxBackends orders the Linux clipboard tools for the session. A Wayland
+// session prefers wl-clipboard and falls back to the X11 tools, which reach
+// the clipboard through XWayland where it runs; any other session prefers
+// the X11 tools.
+func linuxBackends(session string) []Backend {
+	wayland := []Backend{
+		// wl-paste appends a newline unless told not to
+		&commandBackend{name: "wl-copy", copyCmd: []string{"wl-copy"}, pasteCmd: []string{"wl-paste", "--no-newline"}, clearCmd: []string{"wl-copy", "--clear"}},
+	}
+	x11 := []Backend{
+		&commandBackend{name: "xclip", copyCmd: []string{"xclip", "-selection", "clipboard"}, pasteCmd: []string{"xclip", "-selection", "clipboard", "-output"}},
+		&commandBackend{name: "xsel", copyCmd: []string{"xsel", "--clipboard", "--input"}, pasteCmd: []string{"xsel", "--clipboard", "--output"}},
+	}
+	if session == SessionWayland {
+		return append(wayland, x11...)
+	}
+	return append(x11, wayland...)
+}
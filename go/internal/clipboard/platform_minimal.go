@@ -0,0 +1,14 @@
+//go:build minimal
+
+package clipboard
+
+// platformBackends returns no backends: builds made with the minimal tag
+// leave out system clipboard support and secrets are printed instead
+func platformBackends() []Backend {
+	return nil
+}
+
+// terminalBackends returns no backends either
+func terminalBackends() []Backend {
+	return nil
+}
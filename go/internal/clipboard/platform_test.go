@@ -0,0 +1,36 @@
+//go:build !minimal
+
+package clipboard
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func backendNames(backends []Backend) []string {
+	var names []string
+	for _, b := range backends {
+		names = append(names, b.Name())
+	}
+	return names
+}
+
+func TestLinuxBackends_Order(t *testing.T) {
+	assert.Equal(t, []string{"wl-copy", "xclip", "xsel"}, backendNames(linuxBackends(SessionWayland)))
+	assert.Equal(t, []string{"xclip", "xsel", "wl-copy"}, backendNames(linuxBackends(SessionX11)))
+	assert.Equal(t, []string{"xclip", "xsel", "wl-copy"}, backendNames(linuxBackends("")))
+}
+
+func TestSession(t *testing.T) {
+	assert.Equal(t, SessionWayland, Session(env(map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"})))
+	assert.Equal(t, SessionWayland, Session(env(map[string]string{"XDG_SESSION_TYPE": "wayland"})))
+	assert.Equal(t, SessionX11, Session(env(map[string]string{"DISPLAY": ":0"})))
+	assert.Equal(t, "", Session(env(nil)))
+}
+
+func TestSelectBackend(t *testing.T) {
+	_, err := SelectBackend("clippy")
+	assert.ErrorContains(t, err, "unknown clipboard backend")
+	assert.ErrorContains(t, err, "osc52")
+}
@@ -0,0 +1,146 @@
+package companion
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(t *testing.T) *Key {
+	t.Helper()
+	key, err := GenerateKey()
+	require.NoError(t, err)
+	return key
+}
+
+func TestSegment_SealOpen(t *testing.T) {
+	key := testKey(t)
+	seg := &Segment{Seq: 3, Full: true, Upserts: []Record{{Key: "a", Value: "1", Revision: 2}}}
+
+	data, err := seg.Seal(key)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), `"value"`)
+
+	opened, err := OpenSegment(data, key)
+	require.NoError(t, err)
+	assert.Equal(t, seg.Upserts, opened.Upserts)
+	assert.Equal(t, uint64(3), opened.Seq)
+
+	_, err = OpenSegment(data, testKey(t))
+	assert.ErrorIs(t, err, ErrWrongKey)
+	_, err = OpenSegment([]byte("nope"), key)
+	assert.Error(t, err)
+}
+
+func TestChanges(t *testing.T) {
+	records := []Record{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}}
+	upserts, deletes, state := Changes(nil, records)
+	assert.Len(t, upserts, 2)
+	assert.Empty(t, deletes)
+
+	records = []Record{{Key: "a", Value: "1"}, {Key: "c", Value: "3"}}
+	records[0].Tags = []string{"new"}
+	upserts, deletes, state = Changes(state, records)
+	assert.Equal(t, []string{"a", "c"}, []string{upserts[0].Key, upserts[1].Key})
+	assert.Equal(t, []string{"b"}, deletes)
+
+	upserts, deletes, _ = Changes(state, records)
+	assert.Empty(t, upserts)
+	assert.Empty(t, deletes)
+}
+
+func TestLocation_WriteRead(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sync")
+	key := testKey(t)
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	err := Write(dir, "vault1", key, &Segment{Upserts: []Record{{Key: "a"}}})
+	assert.Error(t, err, "first segment must be full")
+
+	require.NoError(t, Write(dir, "vault1", key, &Segment{Full: true, CreatedAt: now, Upserts: []Record{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}}}))
+	require.NoError(t, Write(dir, "vault1", key, &Segment{Upserts: []Record{{Key: "a", Value: "changed"}}, Deletes: []string{"b"}}))
+
+	records, err := Read(dir, key)
+	require.NoError(t, err)
+	assert.Equal(t, []Record{{Key: "a", Value: "changed"}}, records)
+
+	m, err := ReadManifest(dir)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), m.LastSeq())
+	assert.Len(t, m.Segments, 2)
+
+	// A full segment compacts the chain
+	require.NoError(t, Write(dir, "vault1", key, &Segment{Full: true, Upserts: []Record{{Key: "z", Value: "26"}}}))
+	m, err = ReadManifest(dir)
+	require.NoError(t, err)
+	require.Len(t, m.Segments, 1)
+	assert.Equal(t, uint64(3), m.Segments[0].Seq)
+	_, err = os.Stat(filepath.Join(dir, "00000001.lkc"))
+	assert.True(t, os.IsNotExist(err))
+
+	records, err = Read(dir, key)
+	require.NoError(t, err)
+	assert.Equal(t, []Record{{Key: "z", Value: "26"}}, records)
+
+	err = Write(dir, "vault2", key, &Segment{Full: true})
+	assert.Error(t, err)
+}
+
+func TestLocation_Tampered(t *testing.T) {
+	dir := t.TempDir()
+	key := testKey(t)
+	require.NoError(t, Write(dir, "v", key, &Segment{Full: true, Upserts: []Record{{Key: "a"}}}))
+
+	path := filepath.Join(dir, "00000001.lkc")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data[len(data)-1] ^= 1
+	require.NoError(t, os.WriteFile(path, data, 0600))
+
+	_, err = Read(dir, key)
+	assert.Error(t, err)
+}
+
+func TestPairing(t *testing.T) {
+	key := testKey(t)
+	code, err := GenerateCode()
+	require.NoError(t, err)
+	assert.Len(t, code, 19)
+
+	p, err := NewPairing("/sync/lockr", "vault1", key, code)
+	require.NoError(t, err)
+	uri := p.URI()
+	assert.True(t, strings.HasPrefix(uri, "lockr-sync://pair?"))
+
+	parsed, err := ParsePairingURI(uri)
+	require.NoError(t, err)
+	assert.Equal(t, "/sync/lockr", parsed.Location)
+	assert.Equal(t, "vault1", parsed.VaultID)
+
+	unwrapped, err := parsed.Unwrap(strings.ToLower(strings.ReplaceAll(code, "-", "")))
+	require.NoError(t, err)
+	assert.Equal(t, key, unwrapped)
+
+	_, err = parsed.Unwrap("AAAA-AAAA-AAAA-AAAA")
+	assert.ErrorIs(t, err, ErrWrongCode)
+
+	_, err = ParsePairingURI("https://example.com/pair")
+	assert.Error(t, err)
+}
+
+func TestWriteQR(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteQR(&buf, "lockr-sync://pair?v=1"))
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	width := len([]rune(lines[0]))
+	assert.Greater(t, width, 20)
+	for _, l := range lines {
+		assert.Equal(t, width, len([]rune(l)))
+	}
+}
@@ -0,0 +1,143 @@
+// Package companion implements the sync format read by mobile and other
+// companion clients. A sync location is a directory, typically inside a
+// folder synced by Syncthing, iCloud Drive or Dropbox, holding a plaintext
+// manifest and a chain of encrypted segments: a full snapshot followed by
+// incremental changes. docs/COMPANION_SYNC.md describes the format for
+// implementers of other clients.
+package companion
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+const (
+	// FormatName identifies companion manifests
+	FormatName = "lockr-companion"
+	// Version is the current version of the format
+	Version = 1
+	// KeySize is the size of a sync key in bytes
+	KeySize = 32
+
+	segmentMagic = "LKC1"
+	nonceSize    = 24
+)
+
+// ErrWrongKey is returned when a segment cannot be decrypted
+var ErrWrongKey = errors.New("cannot decrypt segment: wrong sync key or corrupted file")
+
+// Key is a symmetric sync key
+type Key [KeySize]byte
+
+// GenerateKey returns a new random sync key
+func GenerateKey() (*Key, error) {
+	var k Key
+	if _, err := io.ReadFull(rand.Reader, k[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate sync key: %w", err)
+	}
+	return &k, nil
+}
+
+// Record is one secret as seen by a companion client
+type Record struct {
+	Key           string    `json:"key"`
+	Value         string    `json:"value"`
+	Tags          []string  `json:"tags,omitempty"`
+	Notes         string    `json:"notes,omitempty"`
+	Revision      int64     `json:"revision"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	ClipboardOnly bool      `json:"clipboard_only,omitempty"`
+}
+
+// Segment is one encrypted file in a sync location. A full segment replaces
+// everything before it; other segments apply upserts and then deletes to
+// the state built from the segments before them.
+type Segment struct {
+	Seq       uint64    `json:"seq"`
+	Full      bool      `json:"full"`
+	CreatedAt time.Time `json:"created_at"`
+	Upserts   []Record  `json:"upserts"`
+	Deletes   []string  `json:"deletes,omitempty"`
+}
+
+// Empty reports whether the segment changes nothing
+func (s *Segment) Empty() bool {
+	return !s.Full && len(s.Upserts) == 0 && len(s.Deletes) == 0
+}
+
+// Seal encrypts the segment with key. The output is the segment magic, a
+// random nonce and a NaCl secretbox of the segment's JSON.
+func (s *Segment) Seal(key *Key) ([]byte, error) {
+	plain, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	var nonce [nonceSize]byte
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	out := append([]byte(segmentMagic), nonce[:]...)
+	return secretbox.Seal(out, plain, &nonce, (*[KeySize]byte)(key)), nil
+}
+
+// OpenSegment decrypts a segment produced by Seal
+func OpenSegment(data []byte, key *Key) (*Segment, error) {
+	if len(data) < len(segmentMagic)+nonceSize || !bytes.HasPrefix(data, []byte(segmentMagic)) {
+		return nil, errors.New("not a companion segment")
+	}
+	var nonce [nonceSize]byte
+	copy(nonce[:], data[len(segmentMagic):])
+	plain, ok := secretbox.Open(nil, data[len(segmentMagic)+nonceSize:], &nonce, (*[KeySize]byte)(key))
+	if !ok {
+		return nil, ErrWrongKey
+	}
+	var s Segment
+	if err := json.Unmarshal(plain, &s); err != nil {
+		return nil, fmt.Errorf("invalid segment: %w", err)
+	}
+	return &s, nil
+}
+
+// State remembers a fingerprint of every record last written to a sync
+// location, so the next sync writes only what changed
+type State map[string]string
+
+// Fingerprint summarises everything a companion sees of a record
+func Fingerprint(r Record) string {
+	data, _ := json.Marshal(r)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Changes compares records with the state of the last sync and returns the
+// records to upsert, the keys to delete and the state after applying them
+func Changes(prev State, records []Record) ([]Record, []string, State) {
+	next := make(State, len(records))
+	var upserts []Record
+	for _, r := range records {
+		fp := Fingerprint(r)
+		next[r.Key] = fp
+		if prev[r.Key] != fp {
+			upserts = append(upserts, r)
+		}
+	}
+
+	var deletes []string
+	for key := range prev {
+		if _, ok := next[key]; !ok {
+			deletes = append(deletes, key)
+		}
+	}
+	sort.Strings(deletes)
+	return upserts, deletes, next
+}
@@ -0,0 +1,192 @@
+package companion
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ManifestFile is the name of the manifest in a sync location
+const ManifestFile = "manifest.json"
+
+// Manifest lists the segments a client needs, oldest first. The first
+// segment is always a full one. It holds no secrets.
+type Manifest struct {
+	Format   string        `json:"format"`
+	Version  int           `json:"version"`
+	VaultID  string        `json:"vault_id"`
+	Segments []SegmentInfo `json:"segments"`
+}
+
+// SegmentInfo describes one segment file
+type SegmentInfo struct {
+	Seq       uint64    `json:"seq"`
+	File      string    `json:"file"`
+	Full      bool      `json:"full"`
+	SHA256    string    `json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LastSeq returns the sequence number of the newest segment, or 0
+func (m *Manifest) LastSeq() uint64 {
+	if len(m.Segments) == 0 {
+		return 0
+	}
+	return m.Segments[len(m.Segments)-1].Seq
+}
+
+// ReadManifest reads the manifest of the sync location dir. A location
+// without one returns os.ErrNotExist.
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if m.Format != FormatName {
+		return nil, fmt.Errorf("not a %s manifest", FormatName)
+	}
+	if m.Version < 1 || m.Version > Version {
+		return nil, fmt.Errorf("unsupported %s version %d", FormatName, m.Version)
+	}
+	return &m, nil
+}
+
+// Write seals seg with key and adds it to the sync location dir, creating
+// the location if needed. The segment's sequence number is assigned here.
+// A full segment replaces the whole chain and the files of older segments
+// are removed; the first segment written to a location must be full.
+func Write(dir, vaultID string, key *Key, seg *Segment) error {
+	m, err := ReadManifest(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		m = &Manifest{Format: FormatName, Version: Version, VaultID: vaultID}
+	} else if err != nil {
+		return err
+	}
+	if m.VaultID != vaultID {
+		return fmt.Errorf("%s belongs to another vault", dir)
+	}
+	if !seg.Full && len(m.Segments) == 0 {
+		return errors.New("the first segment in a location must be full")
+	}
+
+	seg.Seq = m.LastSeq() + 1
+	if seg.CreatedAt.IsZero() {
+		seg.CreatedAt = time.Now().UTC()
+	}
+	data, err := seg.Seal(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%08d.lkc", seg.Seq)
+	if err := writeFileAtomic(filepath.Join(dir, name), data); err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	info := SegmentInfo{Seq: seg.Seq, File: name, Full: seg.Full, SHA256: hex.EncodeToString(sum[:]), CreatedAt: seg.CreatedAt}
+	var obsolete []SegmentInfo
+	if seg.Full {
+		obsolete = m.Segments
+		m.Segments = []SegmentInfo{info}
+	} else {
+		m.Segments = append(m.Segments, info)
+	}
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(dir, ManifestFile), manifest); err != nil {
+		return err
+	}
+
+	// Clients reading the old manifest may briefly miss these files, which
+	// they treat like an interrupted sync
+	for _, s := range obsolete {
+		os.Remove(filepath.Join(dir, s.File))
+	}
+	return nil
+}
+
+// Read rebuilds the records in the sync location dir, as a client would.
+// It checks every segment's hash and sequence number.
+func Read(dir string, key *Key) ([]Record, error) {
+	m, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Segments) == 0 || !m.Segments[0].Full {
+		return nil, errors.New("manifest does not start with a full segment")
+	}
+
+	records := make(map[string]Record)
+	for _, info := range m.Segments {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.Base(info.File)))
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != info.SHA256 {
+			return nil, fmt.Errorf("segment %d does not match the manifest", info.Seq)
+		}
+		seg, err := OpenSegment(data, key)
+		if err != nil {
+			return nil, err
+		}
+		if seg.Seq != info.Seq || seg.Full != info.Full {
+			return nil, fmt.Errorf("segment %d does not match the manifest", info.Seq)
+		}
+
+		if seg.Full {
+			records = make(map[string]Record)
+		}
+		for _, r := range seg.Upserts {
+			records[r.Key] = r
+		}
+		for _, k := range seg.Deletes {
+			delete(records, k)
+		}
+	}
+
+	out := make([]Record, 0, len(records))
+	for _, r := range records {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, nil
+}
+
+// writeFileAtomic replaces path with data via a temporary file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,154 @@
+package companion
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// PairScheme is the URI scheme of pairing codes
+const PairScheme = "lockr-sync"
+
+// Argon2id parameters for wrapping the sync key with a pairing code. They
+// are written into the pairing URI so they can be raised later.
+const (
+	pairTime    = 3
+	pairMemory  = 64 * 1024
+	pairThreads = 4
+	pairSalt    = 16
+)
+
+// codeAlphabet leaves out characters that are easily confused
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// ErrWrongCode is returned when a pairing code does not unwrap the key
+var ErrWrongCode = errors.New("wrong pairing code")
+
+// Pairing is what a companion client needs to find and open a sync
+// location. The sync key inside is wrapped with a pairing code that is
+// shown next to the pairing URI but never stored in it.
+type Pairing struct {
+	Location   string
+	VaultID    string
+	Salt       []byte
+	Time       uint32
+	Memory     uint32
+	Threads    uint8
+	WrappedKey []byte
+}
+
+// GenerateCode returns a random pairing code such as "K7QD-M2XH-9TPA-RW4C"
+func GenerateCode() (string, error) {
+	var b strings.Builder
+	for i := 0; i < 16; i++ {
+		if i > 0 && i%4 == 0 {
+			b.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(codeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(codeAlphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+// NewPairing wraps key with code for the given location
+func NewPairing(location, vaultID string, key *Key, code string) (*Pairing, error) {
+	p := &Pairing{
+		Location: location,
+		VaultID:  vaultID,
+		Salt:     make([]byte, pairSalt),
+		Time:     pairTime,
+		Memory:   pairMemory,
+		Threads:  pairThreads,
+	}
+	if _, err := io.ReadFull(rand.Reader, p.Salt); err != nil {
+		return nil, err
+	}
+
+	var nonce [nonceSize]byte
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return nil, err
+	}
+	p.WrappedKey = secretbox.Seal(nonce[:], key[:], &nonce, p.wrappingKey(code))
+	return p, nil
+}
+
+// Unwrap recovers the sync key with the pairing code
+func (p *Pairing) Unwrap(code string) (*Key, error) {
+	if len(p.WrappedKey) < nonceSize {
+		return nil, errors.New("invalid wrapped key")
+	}
+	var nonce [nonceSize]byte
+	copy(nonce[:], p.WrappedKey)
+	raw, ok := secretbox.Open(nil, p.WrappedKey[nonceSize:], &nonce, p.wrappingKey(code))
+	if !ok || len(raw) != KeySize {
+		return nil, ErrWrongCode
+	}
+	var k Key
+	copy(k[:], raw)
+	return &k, nil
+}
+
+// wrappingKey derives the key-wrapping key from a pairing code. Case and
+// dashes are ignored so codes can be typed loosely.
+func (p *Pairing) wrappingKey(code string) *[KeySize]byte {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	var k [KeySize]byte
+	copy(k[:], argon2.IDKey([]byte(normalized), p.Salt, p.Time, p.Memory, p.Threads, KeySize))
+	return &k
+}
+
+// URI encodes the pairing, for example as a QR code
+func (p *Pairing) URI() string {
+	q := url.Values{}
+	q.Set("v", strconv.Itoa(Version))
+	q.Set("loc", p.Location)
+	q.Set("vault", p.VaultID)
+	q.Set("kdf", fmt.Sprintf("argon2id:%d:%d:%d", p.Time, p.Memory, p.Threads))
+	q.Set("salt", base64.RawURLEncoding.EncodeToString(p.Salt))
+	q.Set("key", base64.RawURLEncoding.EncodeToString(p.WrappedKey))
+	return PairScheme + "://pair?" + q.Encode()
+}
+
+// ParsePairingURI decodes a URI produced by Pairing.URI
+func ParsePairingURI(s string) (*Pairing, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != PairScheme || u.Host != "pair" {
+		return nil, fmt.Errorf("not a %s pairing URI", PairScheme)
+	}
+	q := u.Query()
+	if v, _ := strconv.Atoi(q.Get("v")); v < 1 || v > Version {
+		return nil, fmt.Errorf("unsupported pairing version %q", q.Get("v"))
+	}
+
+	p := &Pairing{Location: q.Get("loc"), VaultID: q.Get("vault")}
+	var t, m, threads uint64
+	if _, err := fmt.Sscanf(q.Get("kdf"), "argon2id:%d:%d:%d", &t, &m, &threads); err != nil || t == 0 || m == 0 || threads == 0 || threads > 255 {
+		return nil, fmt.Errorf("invalid kdf parameters %q", q.Get("kdf"))
+	}
+	p.Time, p.Memory, p.Threads = uint32(t), uint32(m), uint8(threads)
+	if p.Salt, err = base64.RawURLEncoding.DecodeString(q.Get("salt")); err != nil {
+		return nil, fmt.Errorf("invalid salt: %w", err)
+	}
+	if p.WrappedKey, err = base64.RawURLEncoding.DecodeString(q.Get("key")); err != nil {
+		return nil, fmt.Errorf("invalid wrapped key: %w", err)
+	}
+	if p.Location == "" || p.VaultID == "" {
+		return nil, errors.New("pairing URI is missing the location or vault")
+	}
+	return p, nil
+}
@@ -0,0 +1,41 @@
+package companion
+
+import (
+	"io"
+	"strings"
+
+	"rsc.io/qr"
+)
+
+// quietZone is the blank border scanners need around a QR code, in modules
+const quietZone = 2
+
+// WriteQR draws text as a QR code with Unicode half blocks, two rows of
+// modules per line of output. Dark modules are drawn as spaces on a light
+// background so the code scans in dark and light terminal themes alike.
+func WriteQR(w io.Writer, text string) error {
+	code, err := qr.Encode(text, qr.L)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	for y := -quietZone; y < code.Size+quietZone; y += 2 {
+		for x := -quietZone; x < code.Size+quietZone; x++ {
+			top, bottom := code.Black(x, y), code.Black(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteString(" ")
+			case top:
+				b.WriteString("▄")
+			case bottom:
+				b.WriteString("▀")
+			default:
+				b.WriteString("█")
+			}
+		}
+		b.WriteString("\n")
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
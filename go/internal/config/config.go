@@ -0,0 +1,207 @@
+// Package config reads lockr's YAML configuration file.
+//
+// Sensitive values such as webhook URLs with tokens or sync credentials can
+// be kept in the vault instead of the file. A value written as
+//
+//	webhook_url: !lockr ops/webhook
+//
+// or as the string "!lockr:ops/webhook" refers to the secret stored under
+// ops/webhook and is replaced by it when the file is resolved, so the file
+// itself is safe to back up or commit.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RefTag is the YAML tag marking a value as a vault reference
+const RefTag = "!lockr"
+
+// refPrefix introduces a vault reference written as a plain string
+const refPrefix = RefTag + ":"
+
+// Lookup returns the value of the secret stored under key
+type Lookup func(key string) (string, error)
+
+// Reference is a config value that refers to a secret
+type Reference struct {
+	// Path locates the value in the file, e.g. "sync.password" or "hooks[0].url"
+	Path string
+	// Key is the secret it refers to
+	Key string
+}
+
+// File is a parsed configuration file. References are left in place until
+// Resolve is called.
+type File struct {
+	Path     string
+	root     yaml.Node
+	resolved bool
+}
+
+// Load reads the configuration file at path. A missing file is an empty
+// configuration, since the file is optional.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &File{Path: path}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	f.Path = path
+	return f, nil
+}
+
+// Parse parses configuration from YAML
+func Parse(data []byte) (*File, error) {
+	f := &File{}
+	if err := yaml.Unmarshal(data, &f.root); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	for _, ref := range f.References() {
+		if ref.Key == "" {
+			return nil, fmt.Errorf("invalid config: %s: %s needs a secret key", ref.Path, RefTag)
+		}
+	}
+	return f, nil
+}
+
+// References lists the values that refer to secrets, in file order
+func (f *File) References() []Reference {
+	var refs []Reference
+	walk(&f.root, "", func(path string, node *yaml.Node) {
+		if key, ok := reference(node); ok {
+			refs = append(refs, Reference{Path: path, Key: key})
+		}
+	})
+	return refs
+}
+
+// Resolve replaces every reference with the secret it refers to. Nothing
+// is replaced if any reference cannot be resolved.
+func (f *File) Resolve(lookup Lookup) error {
+	type replacement struct {
+		node  *yaml.Node
+		value string
+	}
+	var replacements []replacement
+	var firstErr error
+
+	walk(&f.root, "", func(path string, node *yaml.Node) {
+		key, ok := reference(node)
+		if !ok || firstErr != nil {
+			return
+		}
+		value, err := lookup(key)
+		if err != nil {
+			firstErr = fmt.Errorf("config %s: secret '%s': %w", path, key, err)
+			return
+		}
+		replacements = append(replacements, replacement{node, value})
+	})
+	if firstErr != nil {
+		return firstErr
+	}
+
+	for _, r := range replacements {
+		r.node.Kind = yaml.ScalarNode
+		r.node.Tag = "!!str"
+		r.node.Style = 0
+		r.node.Value = r.value
+	}
+	f.resolved = true
+	return nil
+}
+
+// Resolved reports whether Resolve has replaced the references
+func (f *File) Resolved() bool {
+	return f.resolved || len(f.References()) == 0
+}
+
+// Decode stores the configuration in v, which is typically a pointer to a
+// struct with yaml tags. Unresolved references decode as empty values, so
+// settings that are not sensitive can be read without unlocking the vault.
+func (f *File) Decode(v any) error {
+	if f.root.Kind == 0 {
+		return nil
+	}
+
+	root := f.root
+	if !f.resolved {
+		root = *clearReferences(&f.root)
+	}
+	if err := root.Decode(v); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	return nil
+}
+
+// reference returns the secret key a node refers to, if it is a reference
+func reference(node *yaml.Node) (string, bool) {
+	if node.Kind != yaml.ScalarNode {
+		return "", false
+	}
+	switch {
+	case node.Tag == RefTag:
+		return strings.TrimSpace(node.Value), true
+	case strings.HasPrefix(node.Tag, refPrefix):
+		return strings.TrimPrefix(node.Tag, refPrefix), true
+	case node.Tag == "!!str" && strings.HasPrefix(node.Value, refPrefix):
+		return strings.TrimSpace(strings.TrimPrefix(node.Value, refPrefix)), true
+	}
+	return "", false
+}
+
+// walk calls fn for every scalar value with its dotted path
+func walk(node *yaml.Node, path string, fn func(path string, node *yaml.Node)) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			walk(child, path, fn)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			child := node.Content[i].Value
+			if path != "" {
+				child = path + "." + child
+			}
+			walk(node.Content[i+1], child, fn)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			walk(child, fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	case yaml.ScalarNode:
+		fn(path, node)
+	}
+}
+
+// clearReferences returns a copy of node with references replaced by
+// empty strings
+func clearReferences(node *yaml.Node) *yaml.Node {
+	clone := *node
+	if _, ok := reference(node); ok {
+		clone.Tag = "!!str"
+		clone.Style = 0
+		clone.Value = ""
+		return &clone
+	}
+	if len(node.Content) > 0 {
+		clone.Content = make([]*yaml.Node, len(node.Content))
+		for i, child := range node.Content {
+			clone.Content[i] = clearReferences(child)
+		}
+	}
+	return &clone
+}
@@ -0,0 +1,224 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sample = `
+clipboard_timeout: 60s
+webhook_url: !lockr ops/webhook
+sync:
+  user: alice
+  password: "!lockr:sync/password"
+hooks:
+  - url: !lockr:hooks/deploy
+`
+
+type sampleConfig struct {
+	ClipboardTimeout string `yaml:"clipboard_timeout"`
+	WebhookURL       string `yaml:"webhook_url"`
+	Sync             struct {
+		User     string `yaml:"user"`
+		Password string `yaml:"password"`
+	} `yaml:"sync"`
+	Hooks []struct {
+		URL string `yaml:"url"`
+	} `yaml:"hooks"`
+}
+
+func TestFile_References(t *testing.T) {
+	f, err := Parse([]byte(sample))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Reference{
+		{Path: "webhook_url", Key: "ops/webhook"},
+		{Path: "sync.password", Key: "sync/password"},
+		{Path: "hooks[0].url", Key: "hooks/deploy"},
+	}, f.References())
+	assert.False(t, f.Resolved())
+}
+
+func TestFile_DecodeUnresolved(t *testing.T) {
+	f, err := Parse([]byte(sample))
+	require.NoError(t, err)
+
+	var cfg sampleConfig
+	require.NoError(t, f.Decode(&cfg))
+	assert.Equal(t, "60s", cfg.ClipboardTimeout)
+	assert.Equal(t, "alice", cfg.Sync.User)
+	assert.Empty(t, cfg.WebhookURL)
+	assert.Empty(t, cfg.Sync.Password)
+	require.Len(t, cfg.Hooks, 1)
+	assert.Empty(t, cfg.Hooks[0].URL)
+}
+
+func TestFile_Resolve(t *testing.T) {
+	secrets := map[string]string{
+		"ops/webhook":   "https://hooks.example/T0KEN",
+		"sync/password": "s3cret",
+		"hooks/deploy":  "https://deploy.example/x",
+	}
+	lookup := func(key string) (string, error) {
+		if v, ok := secrets[key]; ok {
+			return v, nil
+		}
+		return "", errors.New("not found")
+	}
+
+	f, err := Parse([]byte(sample))
+	require.NoError(t, err)
+	require.NoError(t, f.Resolve(lookup))
+	assert.True(t, f.Resolved())
+	assert.Empty(t, f.References())
+
+	var cfg sampleConfig
+	require.NoError(t, f.Decode(&cfg))
+	assert.Equal(t, "https://hooks.example/T0KEN", cfg.WebhookURL)
+	assert.Equal(t, "s3cret", cfg.Sync.Password)
+	assert.Equal(t, "https://deploy.example/x", cfg.Hooks[0].URL)
+
+	// A missing secret leaves the file untouched
+	delete(secrets, "hooks/deploy")
+	f, err = Parse([]byte(sample))
+	require.NoError(t, err)
+	err = f.Resolve(lookup)
+	assert.ErrorContains(t, err, "hooks[0].url")
+	assert.Len(t, f.References(), 3)
+}
+
+func TestFile_Settings(t *testing.T) {
+	f, err := Parse([]byte("no_clipboard: true\nwebhook_url: !lockr ops/webhook\n"))
+	require.NoError(t, err)
+	s, err := f.Settings()
+	require.NoError(t, err)
+	assert.True(t, s.NoClipboard)
+
+	f, err = Parse([]byte("picker:\n  results: 12\n  density: detailed\n"))
+	require.NoError(t, err)
+	s, err = f.Settings()
+	require.NoError(t, err)
+	assert.Equal(t, Picker{Results: 12, Density: "detailed"}, s.Picker)
+
+	f, err = Parse([]byte("keys:\n  picker:\n    up: [ctrl+k]\n"))
+	require.NoError(t, err)
+	s, err = f.Settings()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ctrl+k"}, s.Keys["picker"]["up"])
+
+	f, err = Parse([]byte("keys:\n  picker:\n    jump: [j]\n"))
+	require.NoError(t, err)
+	_, err = f.Settings()
+	assert.ErrorContains(t, err, "unknown picker action")
+
+	f, err = Parse([]byte("no_clipboard: sometimes\n"))
+	require.NoError(t, err)
+	_, err = f.Settings()
+	assert.Error(t, err)
+
+	f, err = Parse([]byte("session_timeout: 30m\nclipboard_timeout: 10s\nkeyring_enabled: false\nsecret_length: 40\nvault_path: ~/v.lockr\ndurability: full\n"))
+	require.NoError(t, err)
+	s, err = f.Settings()
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, s.SessionTimeout)
+	assert.Equal(t, 10*time.Second, s.ClipboardTimeout)
+	require.NotNil(t, s.KeyringEnabled)
+	assert.False(t, *s.KeyringEnabled)
+	assert.Equal(t, 40, s.SecretLength)
+	assert.Equal(t, "full", s.Durability)
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	vault, err := s.Vault()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, "v.lockr"), vault)
+
+	f, err = Parse([]byte("durability: sometimes\n"))
+	require.NoError(t, err)
+	_, err = f.Settings()
+	assert.ErrorContains(t, err, "durability")
+
+	f, err = Parse([]byte("session_timeout: -1m\n"))
+	require.NoError(t, err)
+	_, err = f.Settings()
+	assert.Error(t, err)
+
+	s, err = (&File{}).Settings()
+	require.NoError(t, err)
+	assert.False(t, s.NoClipboard)
+	assert.Nil(t, s.KeyringEnabled)
+}
+
+func TestFile_GetSet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lockr", "config.yml")
+	f, err := Load(path)
+	require.NoError(t, err)
+	_, err = f.Get("picker.results")
+	assert.ErrorIs(t, err, ErrNotSet)
+
+	require.NoError(t, f.Set("picker.results", "12"))
+	require.NoError(t, f.Set("webhook_url", "!lockr ops/webhook"))
+	require.NoError(t, f.Save())
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	f, err = Load(path)
+	require.NoError(t, err)
+	value, err := f.Get("picker.results")
+	require.NoError(t, err)
+	assert.Equal(t, "12", value)
+	value, err = f.Get("webhook_url")
+	require.NoError(t, err)
+	assert.Equal(t, "!lockr ops/webhook", value)
+	value, err = f.Get("picker")
+	require.NoError(t, err)
+	assert.Equal(t, "results: 12", value)
+	s, err := f.Settings()
+	require.NoError(t, err)
+	assert.Equal(t, 12, s.Picker.Results)
+
+	// Comments and the order of existing settings survive an edit
+	f, err = Parse([]byte("# mine\nno_clipboard: true # for now\nlimits:\n  max_secrets: 10\n"))
+	require.NoError(t, err)
+	f.Path = path
+	require.NoError(t, f.Set("no_clipboard", "false"))
+	require.NoError(t, f.Set("limits.max_secrets", "20"))
+	assert.ErrorContains(t, f.Set("no_clipboard.x", "1"), "not a section")
+	assert.ErrorContains(t, f.Set("limits", "1"), "is a section")
+	assert.Error(t, f.Set("limits..x", "1"))
+	require.NoError(t, f.Save())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "# mine\nno_clipboard: false # for now\nlimits:\n  max_secrets: 20\n", string(data))
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := Parse([]byte("key: [unclosed"))
+	assert.Error(t, err)
+
+	_, err = Parse([]byte("token: !lockr\n"))
+	assert.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	f, err := Load(filepath.Join(dir, "missing.yml"))
+	require.NoError(t, err)
+	assert.Empty(t, f.References())
+	var cfg sampleConfig
+	require.NoError(t, f.Decode(&cfg))
+
+	path := filepath.Join(dir, "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(sample), 0600))
+	f, err = Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, path, f.Path)
+	assert.Len(t, f.References(), 3)
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNotSet is returned by Get for a setting missing from the file
+var ErrNotSet = errors.New("setting is not set")
+
+// Get returns the setting at path, a dotted list of keys such as
+// "picker.results", as written in the file. A reference is returned as
+// "!lockr <key>" rather than the secret; sections are returned as YAML.
+func (f *File) Get(path string) (string, error) {
+	node := f.lookup(path)
+	if node == nil {
+		return "", ErrNotSet
+	}
+
+	if key, ok := reference(node); ok {
+		return RefTag + " " + key, nil
+	}
+	if node.Kind == yaml.ScalarNode {
+		return node.Value, nil
+	}
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
+
+// Set sets the setting at path to value, creating sections along the way
+// and keeping comments and the order of the file. A value written as
+// "!lockr <key>" becomes a reference to that secret.
+func (f *File) Set(path, value string) error {
+	if f.resolved {
+		return errors.New("cannot edit a config whose references were resolved")
+	}
+	keys, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+
+	if f.root.Kind == 0 {
+		f.root = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	node := f.root.Content[0]
+	for i, key := range keys {
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s is not a section", strings.Join(keys[:i], "."))
+		}
+		child := mappingValue(node, key)
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, child)
+		}
+		node = child
+	}
+	if node.Kind == yaml.MappingNode && len(node.Content) > 0 {
+		return fmt.Errorf("%s is a section; set the settings in it instead", path)
+	}
+
+	// Leave the tag empty so YAML infers it, as if typed into the file
+	*node = yaml.Node{Kind: yaml.ScalarNode, Value: value, HeadComment: node.HeadComment, LineComment: node.LineComment}
+	if key, ok := strings.CutPrefix(value, RefTag+" "); ok {
+		node.Tag = RefTag
+		node.Value = strings.TrimSpace(key)
+	}
+	return nil
+}
+
+// Save writes the file back to its path, readable only by the user
+func (f *File) Save() error {
+	if f.resolved {
+		return errors.New("cannot save a config whose references were resolved")
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&f.root); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(f.Path, buf.Bytes(), 0600)
+}
+
+// lookup returns the node at path, or nil
+func (f *File) lookup(path string) *yaml.Node {
+	keys, err := splitPath(path)
+	if err != nil || f.root.Kind == 0 {
+		return nil
+	}
+	node := f.root.Content[0]
+	for _, key := range keys {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		if node = mappingValue(node, key); node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// splitPath splits a dotted setting path into keys
+func splitPath(path string) ([]string, error) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys {
+		if key == "" {
+			return nil, fmt.Errorf("invalid setting %q", path)
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Limits are soft limits on the size of the vault. Exceeding one never
+// blocks a write; lockr warns so that runaway automation is noticed before
+// the vault becomes unwieldy. Zero values mean no limit.
+type Limits struct {
+	// MaxSecrets is the number of secrets above which lockr warns
+	MaxSecrets int `yaml:"max_secrets"`
+
+	// MaxVaultSize is the vault file size above which lockr warns, in bytes
+	// or with a KB, MB or GB suffix (e.g. "50MB")
+	MaxVaultSize string `yaml:"max_vault_size"`
+}
+
+// sizeUnits are the suffixes accepted by MaxVaultSize, longest first
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// MaxVaultBytes returns MaxVaultSize in bytes, or 0 if it is not set
+func (l Limits) MaxVaultBytes() (int64, error) {
+	return parseSize("limits.max_vault_size", l.MaxVaultSize)
+}
+
+// parseSize parses a size in bytes or with a KB, MB or GB suffix. An empty
+// size is 0; name is the setting reported in errors.
+func parseSize(name, size string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	if s == "" {
+		return 0, nil
+	}
+
+	unit := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s, unit = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid config: %s: %q is not a size", name, size)
+	}
+	return n * unit, nil
+}
+
+// Check returns a warning for every limit that secrets and size exceed
+func (l Limits) Check(secrets int, size int64) []string {
+	var warnings []string
+	if l.MaxSecrets > 0 && secrets > l.MaxSecrets {
+		warnings = append(warnings, fmt.Sprintf("vault holds %d secrets, above the soft limit of %d", secrets, l.MaxSecrets))
+	}
+	if max, err := l.MaxVaultBytes(); err == nil && max > 0 && size > max {
+		warnings = append(warnings, fmt.Sprintf("vault file is %s, above the soft limit of %s", FormatSize(size), FormatSize(max)))
+	}
+	return warnings
+}
+
+// FormatSize renders a byte count with the largest unit that keeps it at
+// least 1, e.g. "12.5 MB"
+func FormatSize(n int64) string {
+	for _, u := range sizeUnits[:len(sizeUnits)-1] {
+		if n >= u.bytes {
+			return fmt.Sprintf("%.1f %s", float64(n)/float64(u.bytes), u.suffix)
+		}
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimits_MaxVaultBytes(t *testing.T) {
+	for in, want := range map[string]int64{
+		"":      0,
+		"4096":  4096,
+		"512 B": 512,
+		"64KB":  64 << 10,
+		"50mb":  50 << 20,
+		"2 GB":  2 << 30,
+	} {
+		got, err := Limits{MaxVaultSize: in}.MaxVaultBytes()
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	for _, in := range []string{"lots", "-1MB", "1.5GB"} {
+		_, err := Limits{MaxVaultSize: in}.MaxVaultBytes()
+		assert.Error(t, err, in)
+	}
+}
+
+func TestClipboard_ConfirmBytes(t *testing.T) {
+	got, err := Clipboard{}.ConfirmBytes()
+	require.NoError(t, err)
+	assert.Equal(t, int64(DefaultConfirmCopySize), got)
+
+	got, err = Clipboard{ConfirmSize: "0"}.ConfirmBytes()
+	require.NoError(t, err)
+	assert.Zero(t, got)
+
+	got, err = Clipboard{ConfirmSize: "64KB"}.ConfirmBytes()
+	require.NoError(t, err)
+	assert.Equal(t, int64(64<<10), got)
+
+	_, err = Clipboard{ConfirmSize: "big"}.ConfirmBytes()
+	assert.ErrorContains(t, err, "clipboard.confirm_size")
+}
+
+func TestLimits_Check(t *testing.T) {
+	l := Limits{MaxSecrets: 100, MaxVaultSize: "1MB"}
+	assert.Empty(t, l.Check(100, 1<<20))
+
+	warnings := l.Check(101, 3<<20)
+	require.Len(t, warnings, 2)
+	assert.Contains(t, warnings[0], "101 secrets")
+	assert.Contains(t, warnings[1], "3.0 MB")
+
+	assert.Empty(t, Limits{}.Check(1e6, 1<<40))
+}
+
+func TestFile_SettingsLimits(t *testing.T) {
+	f, err := Parse([]byte("limits:\n  max_secrets: 500\n  max_vault_size: 20MB\n"))
+	require.NoError(t, err)
+	s, err := f.Settings()
+	require.NoError(t, err)
+	assert.Equal(t, 500, s.Limits.MaxSecrets)
+
+	f, err = Parse([]byte("limits:\n  max_vault_size: huge\n"))
+	require.NoError(t, err)
+	_, err = f.Settings()
+	assert.Error(t, err)
+}
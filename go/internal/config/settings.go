@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lockr/go/internal/keymap"
+)
+
+// Settings are the options lockr reads from the config file. Command-line
+// flags and LOCKR_* variables take precedence over them.
+type Settings struct {
+	// VaultPath is the vault used unless --vault or a workspace names
+	// another; a leading ~/ is the home directory
+	VaultPath string `yaml:"vault_path"`
+
+	// SessionTimeout is how long an unlocked vault stays unlocked without
+	// use, for sessions and 'lockr agent'. Zero keeps the default.
+	SessionTimeout time.Duration `yaml:"session_timeout"`
+
+	// ClipboardTimeout is how long copied secrets stay on the clipboard.
+	// Zero keeps the default.
+	ClipboardTimeout time.Duration `yaml:"clipboard_timeout"`
+
+	// KeyringEnabled set to false turns off keyring integration, like
+	// LOCKR_KEYRING_DISABLED
+	KeyringEnabled *bool `yaml:"keyring_enabled"`
+
+	// SecretLength is the length of generated secrets, like --length.
+	// Policies naming a length still take precedence.
+	SecretLength int `yaml:"secret_length"`
+
+	// ListFormat is the output format of 'lockr list', like --format
+	ListFormat string `yaml:"list_format"`
+
+	// NoClipboard disables the clipboard everywhere, like --no-clipboard
+	NoClipboard bool `yaml:"no_clipboard"`
+
+	// Durability is "normal" or "full": full waits for every write to reach
+	// the disk and leaves the vault a single complete file between
+	// commands, for vaults kept in a file-sync folder
+	Durability string `yaml:"durability"`
+
+	// Engine names the storage engine of vaults without one of their own
+	// under Vaults. Empty means the default, sqlcipher.
+	Engine string `yaml:"engine"`
+
+	// Limits are soft limits on the vault's growth
+	Limits Limits `yaml:"limits"`
+
+	// Picker configures the interactive picker of 'lockr get'
+	Picker Picker `yaml:"picker"`
+
+	// Clipboard configures copying values to the clipboard
+	Clipboard Clipboard `yaml:"clipboard"`
+
+	// Vaults label and color vaults by path, so they are told apart, and
+	// can choose their storage engine
+	Vaults Themes `yaml:"vaults"`
+
+	// Keys remap the interactive screens' keys: screen → action → keys
+	Keys map[string]map[string][]string `yaml:"keys"`
+}
+
+// Picker configures the interactive picker. Zero values keep the defaults.
+type Picker struct {
+	// Results is the number of results shown at once, like --results
+	Results int `yaml:"results"`
+
+	// Density is "compact" or "detailed", like --density
+	Density string `yaml:"density"`
+
+	// Program is "builtin", "fzf" or "sk", like --picker
+	Program string `yaml:"program"`
+
+	// RevealFor is how long the reveal key shows a value before masking it
+	// again
+	RevealFor time.Duration `yaml:"reveal_for"`
+}
+
+// DefaultConfirmCopySize is the value size from which copying to the
+// clipboard asks first. Some clipboard managers freeze on values this large,
+// such as pasted certificate chains.
+const DefaultConfirmCopySize = 256 << 10
+
+// Clipboard configures copying values to the clipboard
+type Clipboard struct {
+	// ConfirmSize is the value size from which copying asks first, in bytes
+	// or with a KB, MB or GB suffix. Empty means DefaultConfirmCopySize and
+	// 0 never asks.
+	ConfirmSize string `yaml:"confirm_size"`
+
+	// Backend names the clipboard backend to use, like --clipboard-backend
+	Backend string `yaml:"backend"`
+}
+
+// ConfirmBytes returns ConfirmSize in bytes, or 0 if copying never asks
+func (c Clipboard) ConfirmBytes() (int64, error) {
+	if strings.TrimSpace(c.ConfirmSize) == "" {
+		return DefaultConfirmCopySize, nil
+	}
+	return parseSize("clipboard.confirm_size", c.ConfirmSize)
+}
+
+// Vault returns VaultPath with a leading ~/ expanded, or "" if it is unset
+func (s Settings) Vault() (string, error) {
+	return expandHome(s.VaultPath)
+}
+
+// Settings decodes the options from the file. They never need the vault,
+// so references do not have to be resolved first.
+func (f *File) Settings() (Settings, error) {
+	var s Settings
+	if err := f.Decode(&s); err != nil {
+		return s, err
+	}
+	if s.SessionTimeout < 0 || s.ClipboardTimeout < 0 || s.Picker.RevealFor < 0 {
+		return s, fmt.Errorf("invalid config: timeouts cannot be negative")
+	}
+	if s.SecretLength < 0 {
+		return s, fmt.Errorf("invalid config: secret_length cannot be negative")
+	}
+	switch s.Durability {
+	case "", "normal", "full":
+	default:
+		return s, fmt.Errorf("invalid config: durability %q must be normal or full", s.Durability)
+	}
+	if _, err := s.Limits.MaxVaultBytes(); err != nil {
+		return s, err
+	}
+	if _, err := s.Clipboard.ConfirmBytes(); err != nil {
+		return s, err
+	}
+	if err := s.Vaults.validate(); err != nil {
+		return s, fmt.Errorf("invalid config: %w", err)
+	}
+	if _, err := keymap.Load(s.Keys); err != nil {
+		return s, err
+	}
+	return s, nil
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Theme marks a vault visibly in prompts, status output and the picker, so
+// secrets are not stored in or fetched from the wrong vault
+type Theme struct {
+	// Label names the vault, e.g. "work"
+	Label string `yaml:"label"`
+
+	// Color is a color name such as red, an ANSI color number (0-255) or
+	// #rrggbb
+	Color string `yaml:"color"`
+
+	// Engine names the storage engine holding the vault, overriding the
+	// top-level engine setting
+	Engine string `yaml:"engine"`
+}
+
+// Themes maps vault paths to their themes; a leading ~/ is the home
+// directory
+type Themes map[string]Theme
+
+// colorNames maps color names to ANSI color numbers
+var colorNames = map[string]string{
+	"black":   "0",
+	"red":     "1",
+	"green":   "2",
+	"yellow":  "3",
+	"blue":    "4",
+	"magenta": "5",
+	"cyan":    "6",
+	"white":   "7",
+	"gray":    "8",
+	"grey":    "8",
+	"orange":  "208",
+	"purple":  "93",
+	"pink":    "205",
+}
+
+// hexColor matches #rrggbb
+var hexColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// ColorCode returns Color as an ANSI color number or #rrggbb, or "" if no
+// color is set
+func (t Theme) ColorCode() (string, error) {
+	color := strings.ToLower(strings.TrimSpace(t.Color))
+	if color == "" {
+		return "", nil
+	}
+	if code, ok := colorNames[color]; ok {
+		return code, nil
+	}
+	if n, err := strconv.Atoi(color); err == nil && n >= 0 && n <= 255 {
+		return color, nil
+	}
+	if hexColor.MatchString(color) {
+		return color, nil
+	}
+	return "", fmt.Errorf("invalid color %q: use a name such as red, a number from 0 to 255 or #rrggbb", t.Color)
+}
+
+// For returns the theme of the vault at vaultPath. A theme with a color but
+// no label is labelled with the vault's file name.
+func (themes Themes) For(vaultPath string) Theme {
+	path, err := filepath.Abs(vaultPath)
+	if err != nil {
+		return Theme{}
+	}
+	for key, theme := range themes {
+		candidate, err := expandHome(key)
+		if err != nil {
+			continue
+		}
+		if candidate, err = filepath.Abs(candidate); err != nil || candidate != path {
+			continue
+		}
+		if theme.Label == "" && theme.Color != "" {
+			theme.Label = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		return theme
+	}
+	return Theme{}
+}
+
+// Profiles maps the labels given to vaults to the vaults' absolute paths,
+// so a vault can be picked by name with 'lockr use'. Only explicit labels
+// count, and a label given to several vaults names none of them.
+func (themes Themes) Profiles() map[string]string {
+	profiles := make(map[string]string)
+	taken := make(map[string]bool)
+	for key, theme := range themes {
+		if theme.Label == "" {
+			continue
+		}
+		path, err := expandHome(key)
+		if err == nil {
+			path, err = filepath.Abs(path)
+		}
+		if err != nil {
+			continue
+		}
+		if other, ok := profiles[theme.Label]; ok && other != path || taken[theme.Label] {
+			delete(profiles, theme.Label)
+			taken[theme.Label] = true
+			continue
+		}
+		profiles[theme.Label] = path
+	}
+	return profiles
+}
+
+// validate checks every theme's color
+func (themes Themes) validate() error {
+	for path, theme := range themes {
+		if _, err := theme.ColorCode(); err != nil {
+			return fmt.Errorf("vaults.%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// expandHome expands a leading ~/ in path to the home directory
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
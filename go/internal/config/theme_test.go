@@ -0,0 +1,57 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTheme_ColorCode(t *testing.T) {
+	for color, want := range map[string]string{"": "", "Red": "1", "208": "208", "#FF8800": "#ff8800"} {
+		code, err := Theme{Color: color}.ColorCode()
+		require.NoError(t, err, color)
+		assert.Equal(t, want, code, color)
+	}
+	for _, color := range []string{"redish", "256", "-1", "#ff88"} {
+		_, err := Theme{Color: color}.ColorCode()
+		assert.Error(t, err, color)
+	}
+}
+
+func TestThemes_For(t *testing.T) {
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	themes := Themes{
+		"~/work.lockr":       {Label: "work", Color: "red"},
+		"/vaults/home.lockr": {Color: "green"},
+	}
+	assert.Equal(t, Theme{Label: "work", Color: "red"}, themes.For(filepath.Join(home, "work.lockr")))
+	assert.Equal(t, Theme{Label: "home", Color: "green"}, themes.For("/vaults/../vaults/home.lockr"))
+	assert.Equal(t, Theme{}, themes.For("/vaults/other.lockr"))
+
+	f, err := Parse([]byte("vaults:\n  ~/work.lockr:\n    color: reddish\n"))
+	require.NoError(t, err)
+	_, err = f.Settings()
+	assert.ErrorContains(t, err, "vaults.~/work.lockr")
+}
+
+func TestThemes_Profiles(t *testing.T) {
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	themes := Themes{
+		"~/work.lockr":       {Label: "work", Color: "red"},
+		"/vaults/home.lockr": {Color: "green"},
+		"/vaults/a.lockr":    {Label: "shared"},
+		"/vaults/b.lockr":    {Label: "shared"},
+		"/vaults/../p.lockr": {Label: "personal"},
+	}
+	assert.Equal(t, map[string]string{
+		"work":     filepath.Join(home, "work.lockr"),
+		"personal": "/p.lockr",
+	}, themes.Profiles())
+}
@@ -132,6 +132,52 @@ func (mk MasterKey) DecryptPassword(encryptedPassword string) (string, error) {
 	return string(plaintext), nil
 }
 
+// Seal encrypts data with AES-GCM under the key directly, without a key
+// derivation step, binding it to additionalData. For random keys only; use
+// EncryptPassword for anything derived from a password.
+// Returns: nonce + ciphertext
+func (mk MasterKey) Seal(data, additionalData []byte) ([]byte, error) {
+	gcm, err := mk.gcm()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, NonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, data, additionalData), nil
+}
+
+// Open decrypts data produced by Seal with the same additionalData
+func (mk MasterKey) Open(sealed, additionalData []byte) ([]byte, error) {
+	gcm, err := mk.gcm()
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < NonceSize+gcm.Overhead() {
+		return nil, fmt.Errorf("sealed data too short: %d bytes", len(sealed))
+	}
+
+	plaintext, err := gcm.Open(nil, sealed[:NonceSize], sealed[NonceSize:], additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+// gcm returns an AES-GCM cipher keyed directly with the master key
+func (mk MasterKey) gcm() (cipher.AEAD, error) {
+	if len(mk) != KeySize {
+		return nil, fmt.Errorf("invalid master key size: expected %d, got %d", KeySize, len(mk))
+	}
+	block, err := aes.NewCipher(mk)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
 // String returns a safe string representation (not the actual key)
 func (mk MasterKey) String() string {
 	return fmt.Sprintf("MasterKey[%d bytes]", len(mk))
@@ -245,3 +245,28 @@ func BenchmarkDecryptPassword(b *testing.B) {
 		key.DecryptPassword(encrypted)
 	}
 }
+
+func TestSealOpen(t *testing.T) {
+	mk, err := GenerateMasterKey()
+	require.NoError(t, err)
+
+	sealed, err := mk.Seal([]byte("cached value"), []byte("vault-a"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(sealed), "cached value")
+
+	opened, err := mk.Open(sealed, []byte("vault-a"))
+	require.NoError(t, err)
+	assert.Equal(t, "cached value", string(opened))
+
+	// Bound to the additional data and the key
+	_, err = mk.Open(sealed, []byte("vault-b"))
+	assert.Error(t, err)
+
+	other, err := GenerateMasterKey()
+	require.NoError(t, err)
+	_, err = other.Open(sealed, []byte("vault-a"))
+	assert.Error(t, err)
+
+	_, err = mk.Open(sealed[:5], []byte("vault-a"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,18 @@
+package crypto
+
+import (
+	"crypto/sha256"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// PasswordIterations is the PBKDF2 work factor for keys derived from
+// passwords people type, such as archive passwords
+const PasswordIterations = 600000
+
+// KeyFromPassword derives an AES-256 key from a password with
+// PBKDF2-SHA256. The same password, salt and iteration count always give
+// the same key.
+func KeyFromPassword(password string, salt []byte, iterations int) MasterKey {
+	return MasterKey(pbkdf2.Key([]byte(password), salt, iterations, KeySize, sha256.New))
+}
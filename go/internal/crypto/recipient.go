@@ -0,0 +1,118 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+)
+
+const (
+	// RecipientKeySize is the size of recipient public and private keys in bytes
+	RecipientKeySize = 32
+
+	publicKeyPrefix  = "lockr-pub-"
+	privateKeyPrefix = "lockr-priv-"
+)
+
+// RecipientPublicKey is an X25519 public key that data can be sealed to
+type RecipientPublicKey [RecipientKeySize]byte
+
+// RecipientPrivateKey is the X25519 private key that opens sealed data
+type RecipientPrivateKey [RecipientKeySize]byte
+
+// GenerateRecipientKey generates a new recipient key pair
+func GenerateRecipientKey() (*RecipientPublicKey, *RecipientPrivateKey, error) {
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate recipient key: %w", err)
+	}
+	return (*RecipientPublicKey)(pub), (*RecipientPrivateKey)(priv), nil
+}
+
+// SealForRecipient encrypts data so that only the holder of the matching
+// private key can read it. The sender is anonymous.
+func SealForRecipient(pub *RecipientPublicKey, data []byte) ([]byte, error) {
+	sealed, err := box.SealAnonymous(nil, data, (*[RecipientKeySize]byte)(pub), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to seal data: %w", err)
+	}
+	return sealed, nil
+}
+
+// OpenSealed decrypts data produced by SealForRecipient
+func OpenSealed(pub *RecipientPublicKey, priv *RecipientPrivateKey, sealed []byte) ([]byte, error) {
+	data, ok := box.OpenAnonymous(nil, sealed, (*[RecipientKeySize]byte)(pub), (*[RecipientKeySize]byte)(priv))
+	if !ok {
+		return nil, errors.New("failed to open sealed data: wrong key or corrupted data")
+	}
+	return data, nil
+}
+
+// PublicKey derives the public key for a private key
+func (k *RecipientPrivateKey) PublicKey() (*RecipientPublicKey, error) {
+	raw, err := curve25519.X25519(k[:], curve25519.Basepoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive public key: %w", err)
+	}
+
+	var pub RecipientPublicKey
+	copy(pub[:], raw)
+	return &pub, nil
+}
+
+// String encodes the public key in its shareable text form
+func (k *RecipientPublicKey) String() string {
+	return publicKeyPrefix + base64.RawURLEncoding.EncodeToString(k[:])
+}
+
+// Encode encodes the private key in its text form
+func (k *RecipientPrivateKey) Encode() string {
+	return privateKeyPrefix + base64.RawURLEncoding.EncodeToString(k[:])
+}
+
+// String returns a safe string representation (not the actual key)
+func (k *RecipientPrivateKey) String() string {
+	return "RecipientPrivateKey[32 bytes]"
+}
+
+// ParseRecipientPublicKey decodes a public key produced by RecipientPublicKey.String
+func ParseRecipientPublicKey(s string) (*RecipientPublicKey, error) {
+	var key RecipientPublicKey
+	if err := decodeRecipientKey(s, publicKeyPrefix, key[:]); err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	return &key, nil
+}
+
+// ParseRecipientPrivateKey decodes a private key produced by RecipientPrivateKey.Encode
+func ParseRecipientPrivateKey(s string) (*RecipientPrivateKey, error) {
+	var key RecipientPrivateKey
+	if err := decodeRecipientKey(s, privateKeyPrefix, key[:]); err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	return &key, nil
+}
+
+// decodeRecipientKey strips prefix from s and decodes the key bytes into out
+func decodeRecipientKey(s, prefix string, out []byte) error {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, prefix) {
+		return fmt.Errorf("missing %q prefix", prefix)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(s, prefix))
+	if err != nil {
+		return err
+	}
+	if len(raw) != len(out) {
+		return fmt.Errorf("expected %d bytes, got %d", len(out), len(raw))
+	}
+
+	copy(out, raw)
+	return nil
+}
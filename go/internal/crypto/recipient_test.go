@@ -0,0 +1,49 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealForRecipient(t *testing.T) {
+	pub, priv, err := GenerateRecipientKey()
+	require.NoError(t, err)
+
+	sealed, err := SealForRecipient(pub, []byte("recovery data"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(sealed), "recovery data")
+
+	opened, err := OpenSealed(pub, priv, sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "recovery data", string(opened))
+
+	// A different key cannot open it
+	otherPub, otherPriv, err := GenerateRecipientKey()
+	require.NoError(t, err)
+	_, err = OpenSealed(otherPub, otherPriv, sealed)
+	assert.Error(t, err)
+}
+
+func TestRecipientKeyEncoding(t *testing.T) {
+	pub, priv, err := GenerateRecipientKey()
+	require.NoError(t, err)
+
+	parsedPub, err := ParseRecipientPublicKey(pub.String())
+	require.NoError(t, err)
+	assert.Equal(t, pub, parsedPub)
+
+	parsedPriv, err := ParseRecipientPrivateKey(priv.Encode() + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, priv, parsedPriv)
+
+	derived, err := parsedPriv.PublicKey()
+	require.NoError(t, err)
+	assert.Equal(t, pub, derived)
+
+	// Keys are not interchangeable and the private key never prints itself
+	_, err = ParseRecipientPublicKey(priv.Encode())
+	assert.Error(t, err)
+	assert.NotContains(t, priv.String(), priv.Encode())
+}
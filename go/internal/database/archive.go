@@ -0,0 +1,92 @@
+package database
+
+import (
+	"database/sql"
+)
+
+// ArchiveSecret marks a secret as archived. Archived secrets keep their
+// value and metadata but are left out of GetSecret, listings, searches and
+// counts until UnarchiveSecret restores them.
+func (vd *VaultDatabase) ArchiveSecret(key string) error {
+	return vd.setArchived("archive_secret", `
+		UPDATE secrets SET archived_at = CURRENT_TIMESTAMP
+		WHERE key = ? COLLATE NOCASE AND hidden = 0 AND archived_at IS NULL
+	`, key)
+}
+
+// UnarchiveSecret returns an archived secret to the working set
+func (vd *VaultDatabase) UnarchiveSecret(key string) error {
+	return vd.setArchived("unarchive_secret", `
+		UPDATE secrets SET archived_at = NULL
+		WHERE key = ? COLLATE NOCASE AND hidden = 0 AND archived_at IS NOT NULL
+	`, key)
+}
+
+// setArchived runs an archive state change for key, returning ErrKeyNotFound
+// if no secret was in the state the query expects
+func (vd *VaultDatabase) setArchived(op, query, key string) error {
+	if err := vd.ensureConnected(); err != nil {
+		return err
+	}
+
+	return vd.write(op, func(tx *sql.Tx) error {
+		result, err := tx.Exec(query, key)
+		if err != nil {
+			return NewDatabaseError(op, err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return NewDatabaseError(op+"_check", err)
+		}
+		if rowsAffected == 0 {
+			return ErrKeyNotFound
+		}
+		return nil
+	})
+}
+
+// ArchivedSecrets returns every archived secret (without values), most
+// recently archived first
+func (vd *VaultDatabase) ArchivedSecrets() ([]SearchResult, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT key, created_at, last_accessed, access_count, tags, revision, archived_at
+		FROM secrets
+		WHERE hidden = 0 AND archived_at IS NOT NULL
+		ORDER BY archived_at DESC, key ASC
+	`
+
+	rows, err := vd.connection.Query(query)
+	if err != nil {
+		return nil, NewDatabaseError("archived_secrets", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var result SearchResult
+		err := rows.Scan(
+			&result.Key,
+			&result.CreatedAt,
+			&result.LastAccessed,
+			&result.AccessCount,
+			&result.Tags,
+			&result.Revision,
+			&result.ArchivedAt,
+		)
+		if err != nil {
+			return nil, NewDatabaseError("scan_archived_secrets", err)
+		}
+		results = append(results, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, NewDatabaseError("archived_secrets_iteration", err)
+	}
+
+	return results, nil
+}
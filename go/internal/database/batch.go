@@ -0,0 +1,108 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Operations in a batch applied by ApplyChanges
+const (
+	ChangeCreate = "create"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+)
+
+// SecretChange is one write in a batch. Updates and deletes with a non-zero
+// Revision only apply while the secret is still at that revision.
+type SecretChange struct {
+	Op       string
+	Key      string
+	Value    string
+	Revision int64
+}
+
+// ApplyChanges applies every change in a single transaction. If any change
+// fails, including a revision mismatch, none of them are applied.
+func (vd *VaultDatabase) ApplyChanges(changes []SecretChange) error {
+	if err := vd.ensureConnected(); err != nil {
+		return err
+	}
+
+	for _, c := range changes {
+		if err := validateKey(c.Key); err != nil {
+			return fmt.Errorf("%w: %q", err, c.Key)
+		}
+	}
+
+	return vd.write("apply_changes", func(tx *sql.Tx) error {
+		for _, c := range changes {
+			if err := applyChange(tx, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// applyChange performs one change inside tx
+func applyChange(tx *sql.Tx, c SecretChange) error {
+	var result sql.Result
+	var err error
+
+	switch c.Op {
+	case ChangeCreate:
+		_, err = tx.Exec(`
+			INSERT INTO secrets (key, value, created_at, last_accessed, updated_at, access_count)
+			VALUES (?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, 0)
+		`, c.Key, c.Value)
+		if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return fmt.Errorf("%w: %q", ErrDuplicateKey, c.Key)
+		}
+		if err != nil {
+			return NewDatabaseError("apply_create", err)
+		}
+		return nil
+
+	case ChangeUpdate:
+		if err := saveVersion(tx, c.Key, VersionReplaced); err != nil {
+			return err
+		}
+		result, err = tx.Exec(`
+			UPDATE secrets
+			SET value = ?, last_accessed = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP,
+				entropy_bits = NULL, value_source = NULL, revision = revision + 1
+			WHERE key = ? COLLATE NOCASE AND hidden = 0 AND (? = 0 OR revision = ?)
+		`, c.Value, c.Key, c.Revision, c.Revision)
+
+	case ChangeDelete:
+		if err := saveVersion(tx, c.Key, VersionDeleted); err != nil {
+			return err
+		}
+		result, err = tx.Exec(`
+			DELETE FROM secrets
+			WHERE key = ? COLLATE NOCASE AND hidden = 0 AND (? = 0 OR revision = ?)
+		`, c.Key, c.Revision, c.Revision)
+		if err == nil {
+			_, err = tx.Exec(`DELETE FROM secret_usage WHERE key = ? COLLATE NOCASE`, c.Key)
+		}
+
+	default:
+		return fmt.Errorf("unknown change %q for %q", c.Op, c.Key)
+	}
+
+	if err != nil {
+		return NewDatabaseError("apply_"+c.Op, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return NewDatabaseError("apply_"+c.Op+"_check", err)
+	}
+	if n == 0 {
+		if c.Revision == 0 {
+			return fmt.Errorf("%w: %q", ErrKeyNotFound, c.Key)
+		}
+		return fmt.Errorf("%w: %q", ErrRevisionMismatch, c.Key)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CipherSettings are the SQLCipher parameters in effect for an open vault
+type CipherSettings struct {
+	Version       string `json:"cipher_version"`
+	KDFIterations int    `json:"kdf_iter"`
+	KDFAlgorithm  string `json:"kdf_algorithm"`
+	HMACAlgorithm string `json:"hmac_algorithm"`
+	UseHMAC       bool   `json:"use_hmac"`
+	PageSize      int    `json:"page_size"`
+}
+
+// CipherPolicy is the minimum acceptable set of cipher parameters
+type CipherPolicy struct {
+	MinMajorVersion  int
+	MinKDFIterations int
+	KDFAlgorithms    []string
+	HMACAlgorithms   []string
+	RequireHMAC      bool
+}
+
+// DefaultCipherPolicy matches the parameters lockr creates vaults with
+var DefaultCipherPolicy = CipherPolicy{
+	MinMajorVersion:  4,
+	MinKDFIterations: kdfIterations,
+	KDFAlgorithms:    []string{"PBKDF2_HMAC_SHA512"},
+	HMACAlgorithms:   []string{"HMAC_SHA512"},
+	RequireHMAC:      true,
+}
+
+// CipherSettings reads back the cipher parameters of the connected vault
+func (vd *VaultDatabase) CipherSettings() (*CipherSettings, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	pragmas := map[string]string{}
+	for _, name := range []string{"cipher_version", "kdf_iter", "cipher_kdf_algorithm", "cipher_hmac_algorithm", "cipher_use_hmac", "cipher_page_size"} {
+		var value string
+		if err := vd.connection.QueryRow("PRAGMA " + name).Scan(&value); err != nil {
+			return nil, NewDatabaseError("cipher_settings", fmt.Errorf("%s: %w", name, err))
+		}
+		pragmas[name] = value
+	}
+
+	settings := &CipherSettings{
+		Version:       pragmas["cipher_version"],
+		KDFAlgorithm:  pragmas["cipher_kdf_algorithm"],
+		HMACAlgorithm: pragmas["cipher_hmac_algorithm"],
+		UseHMAC:       pragmas["cipher_use_hmac"] == "1",
+	}
+	settings.KDFIterations, _ = strconv.Atoi(pragmas["kdf_iter"])
+	settings.PageSize, _ = strconv.Atoi(pragmas["cipher_page_size"])
+
+	return settings, nil
+}
+
+// Violations lists every way the settings fall short of policy. An empty
+// result means the settings are acceptable.
+func (s *CipherSettings) Violations(policy CipherPolicy) []string {
+	var problems []string
+
+	if major := majorVersion(s.Version); major < policy.MinMajorVersion {
+		problems = append(problems, fmt.Sprintf("cipher version %q is older than %d.x", s.Version, policy.MinMajorVersion))
+	}
+	if s.KDFIterations < policy.MinKDFIterations {
+		problems = append(problems, fmt.Sprintf("kdf_iter %d is below the minimum of %d", s.KDFIterations, policy.MinKDFIterations))
+	}
+	if len(policy.KDFAlgorithms) > 0 && !containsFold(policy.KDFAlgorithms, s.KDFAlgorithm) {
+		problems = append(problems, fmt.Sprintf("KDF algorithm %s is not allowed", s.KDFAlgorithm))
+	}
+	if policy.RequireHMAC && !s.UseHMAC {
+		problems = append(problems, "page HMAC is disabled")
+	}
+	if s.UseHMAC && len(policy.HMACAlgorithms) > 0 && !containsFold(policy.HMACAlgorithms, s.HMACAlgorithm) {
+		problems = append(problems, fmt.Sprintf("HMAC algorithm %s is not allowed", s.HMACAlgorithm))
+	}
+
+	return problems
+}
+
+// majorVersion extracts the major version from a string like "4.4.2 community"
+func majorVersion(version string) int {
+	major, _ := strconv.Atoi(strings.SplitN(strings.TrimSpace(version), ".", 2)[0])
+	return major
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Durability is how hard the vault tries to reach the disk intact
+type Durability string
+
+const (
+	// DurabilityNormal is SQLite's synchronous=NORMAL: commits are atomic
+	// and consistent, but the last ones may be lost if the machine loses
+	// power
+	DurabilityNormal Durability = "normal"
+
+	// DurabilityFull waits for every commit to reach the disk
+	// (synchronous=FULL), keeps the vault in rollback-journal mode and
+	// checkpoints on close, so between commands the vault is one complete
+	// file. Meant for vaults that file-sync tools copy while lockr runs.
+	DurabilityFull Durability = "full"
+)
+
+// DefaultDurability is used unless the config file chooses another mode
+const DefaultDurability = DurabilityNormal
+
+// ParseDurability returns the mode named s
+func ParseDurability(s string) (Durability, error) {
+	switch d := Durability(strings.ToLower(strings.TrimSpace(s))); d {
+	case "":
+		return DefaultDurability, nil
+	case DurabilityNormal, DurabilityFull:
+		return d, nil
+	}
+	return "", fmt.Errorf("unknown durability %q (use normal or full)", s)
+}
+
+// DurabilitySettings are the storage settings in effect for an open vault
+type DurabilitySettings struct {
+	Mode        Durability `json:"mode"`
+	Synchronous string     `json:"synchronous"`
+	JournalMode string     `json:"journal_mode"`
+}
+
+// synchronousModes names the values of PRAGMA synchronous
+var synchronousModes = map[string]string{"0": "OFF", "1": "NORMAL", "2": "FULL", "3": "EXTRA"}
+
+// SetDurability chooses the mode used by the next Connect
+func (vd *VaultDatabase) SetDurability(d Durability) {
+	vd.durability = d
+}
+
+// Durability reads back the storage settings of the connected vault
+func (vd *VaultDatabase) Durability() (*DurabilitySettings, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	settings := &DurabilitySettings{Mode: vd.durabilityMode()}
+	var synchronous string
+	if err := vd.connection.QueryRow(`PRAGMA synchronous`).Scan(&synchronous); err != nil {
+		return nil, NewDatabaseError("durability", err)
+	}
+	if err := vd.connection.QueryRow(`PRAGMA journal_mode`).Scan(&settings.JournalMode); err != nil {
+		return nil, NewDatabaseError("durability", err)
+	}
+	settings.Synchronous = synchronousModes[synchronous]
+	if settings.Synchronous == "" {
+		settings.Synchronous = synchronous
+	}
+	return settings, nil
+}
+
+// durabilityMode is the mode in use, DefaultDurability if none was chosen
+func (vd *VaultDatabase) durabilityMode() Durability {
+	if vd.durability == "" {
+		return DefaultDurability
+	}
+	return vd.durability
+}
+
+// durabilityParams returns the connection string parameters for the mode.
+// The driver applies them to every connection it opens.
+func (vd *VaultDatabase) durabilityParams() string {
+	if vd.durabilityMode() != DurabilityFull {
+		return ""
+	}
+	if vd.readOnly {
+		// Leaving write-ahead logging needs write access
+		return "&_sync=FULL"
+	}
+	return "&_sync=FULL&_journal_mode=DELETE"
+}
+
+// checkpoint folds any write-ahead log into the vault file before it is
+// closed, so nothing the vault needs is left in files beside it. A vault in
+// rollback-journal mode has nothing to checkpoint.
+func (vd *VaultDatabase) checkpoint() error {
+	if vd.durabilityMode() != DurabilityFull || vd.readOnly {
+		return nil
+	}
+	if _, err := vd.connection.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		return NewDatabaseError("checkpoint", err)
+	}
+	return nil
+}
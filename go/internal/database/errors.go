@@ -15,12 +15,36 @@ var (
 	// ErrKeyNotFound indicates the requested key does not exist
 	ErrKeyNotFound = errors.New("key not found")
 
+	// ErrSnapshotNotFound indicates the requested snapshot does not exist
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+
+	// ErrVersionNotFound indicates the requested version of a secret does
+	// not exist
+	ErrVersionNotFound = errors.New("version not found")
+
+	// ErrSnapshotExists indicates a snapshot with that name already exists
+	ErrSnapshotExists = errors.New("snapshot already exists")
+
 	// ErrDuplicateKey indicates the key already exists
 	ErrDuplicateKey = errors.New("key already exists")
 
+	// ErrRevisionMismatch indicates a conditional update lost a race with
+	// another writer
+	ErrRevisionMismatch = errors.New("secret was modified concurrently (revision mismatch)")
+
+	// ErrIntegrityMismatch indicates the vault's contents no longer match the
+	// checksum lockr maintains, i.e. it was modified outside of lockr
+	ErrIntegrityMismatch = errors.New("vault contents changed outside of lockr (integrity check failed)")
+
 	// ErrInvalidKey indicates the key format is invalid
 	ErrInvalidKey = errors.New("invalid key format")
 
+	// ErrInvalidTag indicates a tag is empty or contains a comma
+	ErrInvalidTag = errors.New("invalid tag (must be non-empty and contain no commas)")
+
+	// ErrInvalidPattern indicates a search pattern could not be parsed
+	ErrInvalidPattern = errors.New("invalid search pattern")
+
 	// ErrDatabaseNotConnected indicates no active database connection
 	ErrDatabaseNotConnected = errors.New("database not connected")
 
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"container/list"
+	"database/sql"
+	"regexp"
+	"sync"
+
+	sqlcipher "github.com/mutecomm/go-sqlcipher/v4"
+)
+
+// driverName is the SQLCipher driver registered with lockr's custom SQL functions
+const driverName = "sqlcipher_lockr"
+
+// regexCacheSize bounds the patterns kept compiled. Under 'lockr serve' and
+// the agent, patterns come from clients, so an unbounded cache would grow
+// forever.
+const regexCacheSize = 64
+
+// regexCache holds compiled patterns so REGEXP does not recompile per row
+var regexCache = newPatternCache(regexCacheSize)
+
+func init() {
+	sql.Register(driverName, &sqlcipher.SQLiteDriver{
+		ConnectHook: func(conn *sqlcipher.SQLiteConn) error {
+			return conn.RegisterFunc("regexp", sqlRegexp, true)
+		},
+	})
+}
+
+// sqlRegexp implements the SQLite REGEXP operator (`value REGEXP pattern`)
+func sqlRegexp(pattern, value string) (bool, error) {
+	re, err := regexCache.compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(value), nil
+}
+
+// patternCache keeps the most recently used compiled patterns, up to size
+type patternCache struct {
+	mu       sync.Mutex
+	size     int
+	order    *list.List // of *regexp.Regexp, most recently used first
+	patterns map[string]*list.Element
+}
+
+func newPatternCache(size int) *patternCache {
+	return &patternCache{
+		size:     size,
+		order:    list.New(),
+		patterns: make(map[string]*list.Element),
+	}
+}
+
+// compile returns pattern compiled, from the cache if it is there
+func (c *patternCache) compile(pattern string) (*regexp.Regexp, error) {
+	c.mu.Lock()
+	if e, ok := c.patterns[pattern]; ok {
+		c.order.MoveToFront(e)
+		c.mu.Unlock()
+		return e.Value.(*regexp.Regexp), nil
+	}
+	c.mu.Unlock()
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.patterns[pattern]; !ok {
+		c.patterns[pattern] = c.order.PushFront(re)
+		if c.order.Len() > c.size {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			delete(c.patterns, oldest.Value.(*regexp.Regexp).String())
+		}
+	}
+	return re, nil
+}
+
+// len is the number of patterns cached
+func (c *patternCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// HideSecrets marks secrets whose keys match any of the glob patterns, or
+// that carry any of the tags, as hidden. Hidden secrets are excluded from
+// every lookup, listing and search until UnhideSecrets is called.
+func (vd *VaultDatabase) HideSecrets(patterns, tags []string) (int64, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return 0, err
+	}
+
+	var conditions []string
+	var args []interface{}
+	for _, pattern := range patterns {
+		conditions = append(conditions, "lower(key) GLOB lower(?)")
+		args = append(args, pattern)
+	}
+	for _, tag := range tags {
+		// tags is a comma-separated list; compare whole entries only
+		conditions = append(conditions, "instr(',' || lower(replace(coalesce(tags, ''), ' ', '')) || ',', ?) > 0")
+		args = append(args, ","+strings.ToLower(strings.TrimSpace(tag))+",")
+	}
+
+	if len(conditions) == 0 {
+		return 0, nil
+	}
+
+	query := `UPDATE secrets SET hidden = 1 WHERE hidden = 0 AND (` + strings.Join(conditions, " OR ") + `)`
+
+	var hidden int64
+	err := vd.write("hide_secrets", func(tx *sql.Tx) error {
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return NewDatabaseError("hide_secrets", err)
+		}
+		hidden, err = result.RowsAffected()
+		return err
+	})
+	return hidden, err
+}
+
+// UnhideSecrets makes every hidden secret visible again
+func (vd *VaultDatabase) UnhideSecrets() (int64, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return 0, err
+	}
+
+	var restored int64
+	err := vd.write("unhide_secrets", func(tx *sql.Tx) error {
+		result, err := tx.Exec(`UPDATE secrets SET hidden = 0 WHERE hidden = 1`)
+		if err != nil {
+			return NewDatabaseError("unhide_secrets", err)
+		}
+		restored, err = result.RowsAffected()
+		return err
+	})
+	return restored, err
+}
+
+// HiddenSecrets returns every hidden secret, including values, ordered by key
+func (vd *VaultDatabase) HiddenSecrets() ([]Secret, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + secretColumns + `
+		FROM secrets
+		WHERE hidden = 1
+		ORDER BY key ASC
+	`
+
+	rows, err := vd.connection.Query(query)
+	if err != nil {
+		return nil, NewDatabaseError("hidden_secrets", err)
+	}
+	defer rows.Close()
+
+	var secrets []Secret
+	for rows.Next() {
+		var secret Secret
+		err := scanSecret(rows, &secret)
+		if err != nil {
+			return nil, NewDatabaseError("scan_hidden_secrets", err)
+		}
+		secrets = append(secrets, secret)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, NewDatabaseError("hidden_secrets_iteration", err)
+	}
+
+	return secrets, nil
+}
+
+// DeleteHiddenSecrets permanently removes every hidden secret from the vault,
+// along with its previous values
+func (vd *VaultDatabase) DeleteHiddenSecrets() (int64, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	err := vd.write("delete_hidden_secrets", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			DELETE FROM secret_versions WHERE key IN (SELECT key FROM secrets WHERE hidden = 1)
+		`)
+		if err != nil {
+			return NewDatabaseError("delete_hidden_versions", err)
+		}
+		result, err := tx.Exec(`DELETE FROM secrets WHERE hidden = 1`)
+		if err != nil {
+			return NewDatabaseError("delete_hidden_secrets", err)
+		}
+		deleted, err = result.RowsAffected()
+		return err
+	})
+	return deleted, err
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// MaxSecretVersions is how many previous values are kept per key; older
+// ones are pruned as new versions are saved
+const MaxSecretVersions = 20
+
+// Why a version was saved
+const (
+	VersionReplaced = "replaced"
+	VersionDeleted  = "deleted"
+)
+
+// SecretVersion is a previous value of a secret. Versions are numbered per
+// key from 1 and keep their numbers when older ones are pruned.
+type SecretVersion struct {
+	Key        string
+	Version    int
+	Revision   int64
+	Value      string
+	SetAt      time.Time
+	ReplacedAt time.Time
+	Reason     string
+}
+
+// saveVersion copies the current value of key into its history inside tx,
+// before an update or delete replaces it. Missing keys are ignored; the
+// caller's own statement reports them.
+func saveVersion(tx *sql.Tx, key, reason string) error {
+	_, err := tx.Exec(`
+		INSERT INTO secret_versions (key, version, revision, value, set_at, reason)
+		SELECT s.key,
+			COALESCE((SELECT MAX(version) FROM secret_versions WHERE key = s.key COLLATE NOCASE), 0) + 1,
+			s.revision, s.value, COALESCE(s.updated_at, s.created_at), ?
+		FROM secrets s
+		WHERE s.key = ? COLLATE NOCASE AND s.hidden = 0
+	`, reason, key)
+	if err != nil {
+		return NewDatabaseError("save_version", err)
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM secret_versions
+		WHERE key = ? COLLATE NOCASE AND version <= (
+			SELECT MAX(version) FROM secret_versions WHERE key = ? COLLATE NOCASE
+		) - ?
+	`, key, key, MaxSecretVersions)
+	if err != nil {
+		return NewDatabaseError("prune_versions", err)
+	}
+	return nil
+}
+
+// SecretHistory returns the previous values of key, newest first. Deleted
+// secrets keep their history, so it can be read after a delete; hidden ones
+// have none while they are hidden.
+func (vd *VaultDatabase) SecretHistory(key string) ([]SecretVersion, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	rows, err := vd.connection.Query(`
+		SELECT key, version, revision, value, set_at, replaced_at, reason
+		FROM secret_versions v
+		WHERE key = ? COLLATE NOCASE AND NOT EXISTS (
+			SELECT 1 FROM secrets s WHERE s.key = v.key COLLATE NOCASE AND s.hidden = 1
+		)
+		ORDER BY version DESC
+	`, key)
+	if err != nil {
+		return nil, NewDatabaseError("secret_history", err)
+	}
+	defer rows.Close()
+
+	var versions []SecretVersion
+	for rows.Next() {
+		var v SecretVersion
+		if err := rows.Scan(&v.Key, &v.Version, &v.Revision, &v.Value, &v.SetAt, &v.ReplacedAt, &v.Reason); err != nil {
+			return nil, NewDatabaseError("secret_history_scan", err)
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, NewDatabaseError("secret_history_rows", err)
+	}
+	return versions, nil
+}
+
+// RestoreSecretVersion makes a previous value current again. The value it
+// replaces is saved as a new version, so a restore can itself be undone. A
+// deleted secret is recreated.
+func (vd *VaultDatabase) RestoreSecretVersion(key string, version int) error {
+	if err := vd.ensureConnected(); err != nil {
+		return err
+	}
+
+	return vd.write("restore_secret_version", func(tx *sql.Tx) error {
+		var storedKey, value string
+		err := tx.QueryRow(`
+			SELECT key, value FROM secret_versions WHERE key = ? COLLATE NOCASE AND version = ?
+		`, key, version).Scan(&storedKey, &value)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: version %d of %q", ErrVersionNotFound, version, key)
+		}
+		if err != nil {
+			return NewDatabaseError("restore_secret_version", err)
+		}
+
+		if err := saveVersion(tx, key, VersionReplaced); err != nil {
+			return err
+		}
+		result, err := tx.Exec(`
+			UPDATE secrets
+			SET value = ?, last_accessed = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP,
+				entropy_bits = NULL, value_source = NULL, revision = revision + 1
+			WHERE key = ? COLLATE NOCASE AND hidden = 0
+		`, value, key)
+		if err != nil {
+			return NewDatabaseError("restore_secret_version", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return NewDatabaseError("restore_secret_version_check", err)
+		}
+		if rowsAffected > 0 {
+			return nil
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO secrets (key, value, created_at, last_accessed, updated_at, access_count)
+			VALUES (?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, 0)
+		`, storedKey, value)
+		if err != nil {
+			// A hidden secret still holds the key
+			return NewDatabaseError("restore_secret_version_create", err)
+		}
+		return nil
+	})
+}
+
+// ClearSecretHistory forgets every previous value of key and returns how
+// many were removed
+func (vd *VaultDatabase) ClearSecretHistory(key string) (int64, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return 0, err
+	}
+
+	var removed int64
+	err := vd.write("clear_secret_history", func(tx *sql.Tx) error {
+		result, err := tx.Exec(`DELETE FROM secret_versions WHERE key = ? COLLATE NOCASE`, key)
+		if err != nil {
+			return NewDatabaseError("clear_secret_history", err)
+		}
+		removed, err = result.RowsAffected()
+		if err != nil {
+			return NewDatabaseError("clear_secret_history_check", err)
+		}
+		return nil
+	})
+	return removed, err
+}
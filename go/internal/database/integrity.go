@@ -0,0 +1,207 @@
+package database
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash"
+)
+
+// integrityContext separates the integrity key from the database key it is
+// derived from
+const integrityContext = "lockr vault integrity v1"
+
+// Checksum formats. A vault sealed in an older format is checked in that
+// format once and then resealed in the current one.
+const (
+	// integrityFormatContent covers key, value, tags and notes
+	integrityFormatContent = 1
+
+	// integrityFormatState also covers hidden, archived_at, url and
+	// expires_at, so secrets cannot be hidden, archived, repointed or
+	// given a new expiry outside of lockr unnoticed
+	integrityFormatState = 2
+
+	// integrityFormat is the format new checksums are sealed in
+	integrityFormat = integrityFormatState
+)
+
+// querier is satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// deriveIntegrityKey derives the HMAC key for the integrity checksum from the
+// vault's raw database key
+func deriveIntegrityKey(rawKey []byte) []byte {
+	mac := hmac.New(sha256.New, rawKey)
+	mac.Write([]byte(integrityContext))
+	return mac.Sum(nil)
+}
+
+// contentMAC computes the HMAC over a canonical serialization of every
+// secret's key, value, tags and notes and, in integrityFormatState, its
+// hidden and archived state, URL and expiry. Access tracking and other
+// metadata that change on reads are not covered. Timestamps are taken as
+// stored, so reading them back cannot change the checksum.
+func (vd *VaultDatabase) contentMAC(q querier, format int) (string, error) {
+	if vd.integrityKey == nil {
+		return "", NewDatabaseError("integrity", errors.New("integrity key not available"))
+	}
+
+	rows, err := q.Query(`
+		SELECT key, value, tags, notes,
+			CASE WHEN hidden THEN '1' ELSE '0' END, CAST(archived_at AS TEXT), url, CAST(expires_at AS TEXT)
+		FROM secrets ORDER BY key COLLATE NOCASE ASC
+	`)
+	if err != nil {
+		return "", NewDatabaseError("integrity_read", err)
+	}
+	defer rows.Close()
+
+	mac := hmac.New(sha256.New, vd.integrityKey)
+	for rows.Next() {
+		var key, value, hidden string
+		var tags, notes, archivedAt, url, expiresAt *string
+		if err := rows.Scan(&key, &value, &tags, &notes, &hidden, &archivedAt, &url, &expiresAt); err != nil {
+			return "", NewDatabaseError("integrity_scan", err)
+		}
+		writeField(mac, &key)
+		writeField(mac, &value)
+		writeField(mac, tags)
+		writeField(mac, notes)
+		if format >= integrityFormatState {
+			writeField(mac, &hidden)
+			writeField(mac, archivedAt)
+			writeField(mac, url)
+			writeField(mac, expiresAt)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return "", NewDatabaseError("integrity_iteration", err)
+	}
+
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// writeField appends a length-prefixed field to h; NULL is encoded with a
+// length that no real value can have
+func writeField(h hash.Hash, s *string) {
+	var length [4]byte
+	if s == nil {
+		binary.BigEndian.PutUint32(length[:], 0xFFFFFFFF)
+		h.Write(length[:])
+		return
+	}
+	binary.BigEndian.PutUint32(length[:], uint32(len(*s)))
+	h.Write(length[:])
+	h.Write([]byte(*s))
+}
+
+// sealIntegrity stores the current content MAC
+func (vd *VaultDatabase) sealIntegrity(q querier) error {
+	mac, err := vd.contentMAC(q, integrityFormat)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO vault_integrity (id, mac, format, updated_at)
+		VALUES (1, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(id) DO UPDATE SET mac = excluded.mac, format = excluded.format, updated_at = excluded.updated_at
+	`
+	if _, err := q.Exec(query, mac, integrityFormat); err != nil {
+		return NewDatabaseError("integrity_seal", err)
+	}
+	return nil
+}
+
+// write runs fn in a transaction and updates the integrity checksum in the
+// same transaction, so the two can never disagree after a lockr write
+func (vd *VaultDatabase) write(op string, fn func(tx *sql.Tx) error) error {
+	return vd.writeContext(context.Background(), op, fn)
+}
+
+// writeContext is write with a context. The transaction is rolled back if
+// ctx is done before it commits.
+func (vd *VaultDatabase) writeContext(ctx context.Context, op string, fn func(tx *sql.Tx) error) error {
+	tx, err := vd.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return NewDatabaseError(op+"_begin", err)
+	}
+	defer tx.Rollback() // no-op after a successful commit
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := vd.sealIntegrity(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return NewDatabaseError(op+"_commit", err)
+	}
+	vd.watch.notify()
+	return nil
+}
+
+// verifyIntegrity compares the stored checksum with the vault's contents. A
+// vault without a checksum (created by an older version) is sealed as is,
+// and one whose checksum is intact but in an older format is resealed in
+// the current one.
+func (vd *VaultDatabase) verifyIntegrity() error {
+	var stored string
+	var format int
+	err := vd.connection.QueryRow(`SELECT mac, format FROM vault_integrity WHERE id = 1`).Scan(&stored, &format)
+	if err == sql.ErrNoRows {
+		if vd.readOnly {
+			return nil // Nothing to compare with and no way to store a checksum
+		}
+		return vd.sealIntegrity(vd.connection)
+	}
+	if err != nil {
+		return NewDatabaseError("integrity_load", err)
+	}
+
+	current, err := vd.contentMAC(vd.connection, format)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal([]byte(stored), []byte(current)) {
+		return ErrIntegrityMismatch
+	}
+	if format < integrityFormat && !vd.readOnly {
+		return vd.sealIntegrity(vd.connection)
+	}
+	return nil
+}
+
+// IntegrityError returns ErrIntegrityMismatch if the vault's contents did not
+// match its checksum when it was opened, meaning it was modified outside of
+// lockr. It returns nil for an intact vault.
+func (vd *VaultDatabase) IntegrityError() error {
+	return vd.integrityErr
+}
+
+// ResealIntegrity accepts the vault's current contents as legitimate and
+// replaces the stored checksum
+func (vd *VaultDatabase) ResealIntegrity() error {
+	if err := vd.ensureConnected(); err != nil {
+		return err
+	}
+
+	if err := vd.sealIntegrity(vd.connection); err != nil {
+		return err
+	}
+	vd.integrityErr = nil
+	return nil
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// SQLCipher 4 key derivation parameters used by every vault
+const (
+	kdfIterations = 256000
+	kdfKeySize    = 32
+	kdfSaltSize   = 16
+)
+
+// DeriveKey runs SQLCipher's key derivation for password against this
+// vault's salt and returns the raw key accepted by ConnectWithKey. The salt
+// is stored unencrypted in the first bytes of the file, so the vault must
+// already exist.
+func (vd *VaultDatabase) DeriveKey(password string) ([]byte, error) {
+	f, err := os.Open(vd.dbPath)
+	if err != nil {
+		return nil, NewDatabaseError("derive_key", err)
+	}
+	defer f.Close()
+
+	salt := make([]byte, kdfSaltSize)
+	if _, err := io.ReadFull(f, salt); err != nil {
+		return nil, NewDatabaseError("derive_key", fmt.Errorf("failed to read salt: %w", err))
+	}
+
+	return deriveKey(password, salt), nil
+}
+
+// newVaultKey picks the salt for a vault that does not exist yet and
+// derives password's raw key against it
+func newVaultKey(password string) (key, salt []byte, err error) {
+	salt = make([]byte, kdfSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil, NewDatabaseError("derive_key", fmt.Errorf("failed to generate salt: %w", err))
+	}
+	return deriveKey(password, salt), salt, nil
+}
+
+// deriveKey runs SQLCipher's key derivation for password and salt
+func deriveKey(password string, salt []byte) []byte {
+	return pbkdf2.Key([]byte(password), salt, kdfIterations, kdfKeySize, sha512.New)
+}
+
+// rawKey formats a raw key, optionally followed by the salt of a new vault,
+// in SQLCipher's x'...' syntax. Being hex it can be passed in the connection
+// string and in PRAGMA statements without any escaping, unlike a password.
+func rawKey(key, salt []byte) string {
+	return fmt.Sprintf("x'%x%x'", key, salt)
+}
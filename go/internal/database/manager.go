@@ -1,14 +1,14 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
-
-	_ "github.com/mutecomm/go-sqlcipher/v4" // SQLCipher driver
 )
 
 const (
@@ -19,14 +19,19 @@ const (
 	MaxKeyLength = 256
 
 	// SchemaVersion defines the current database schema version
-	SchemaVersion = 1
+	SchemaVersion = 14
 )
 
 // VaultDatabase manages the encrypted SQLCipher database
 type VaultDatabase struct {
-	dbPath     string
-	connection *sql.DB
-	isOpen     bool
+	dbPath       string
+	connection   *sql.DB
+	isOpen       bool
+	integrityKey []byte
+	integrityErr error
+	readOnly     bool
+	durability   Durability
+	watch        watchList
 }
 
 // NewVaultDatabase creates a new VaultDatabase instance
@@ -37,17 +42,84 @@ func NewVaultDatabase(dbPath string) *VaultDatabase {
 	}
 }
 
+// NewReadOnlyVaultDatabase creates a VaultDatabase that never writes to the
+// vault, for vaults on read-only mounts. The vault must already exist and be
+// at the current schema version; every write fails.
+func NewReadOnlyVaultDatabase(dbPath string) *VaultDatabase {
+	return &VaultDatabase{
+		dbPath:   dbPath,
+		readOnly: true,
+	}
+}
+
 // Connect establishes a connection to the encrypted database with the given password
 func (vd *VaultDatabase) Connect(password string) error {
+	// Deriving the raw key here rather than inside SQLCipher means the one
+	// slow derivation also yields the integrity key
+	if key, err := vd.DeriveKey(password); err == nil {
+		return vd.ConnectWithKey(key)
+	}
+
+	if vd.readOnly {
+		return NewDatabaseError("connect", fmt.Errorf("cannot open %s read-only: %w", vd.dbPath, os.ErrNotExist))
+	}
+
+	// A new vault has no salt yet: pick one and hand SQLCipher the raw key
+	// with it, so the password itself never reaches the connection string
+	key, salt, err := newVaultKey(password)
+	if err != nil {
+		return err
+	}
+	if err := vd.open(rawKey(key, salt)); err != nil {
+		return err
+	}
+	return vd.initIntegrity(key)
+}
+
+// ConnectWithKey establishes a connection using a raw key previously obtained
+// from DeriveKey, skipping SQLCipher's key derivation
+func (vd *VaultDatabase) ConnectWithKey(key []byte) error {
+	if len(key) != kdfKeySize {
+		return ErrAuthenticationFailed
+	}
+	if err := vd.open(rawKey(key, nil)); err != nil {
+		return err
+	}
+	return vd.initIntegrity(key)
+}
+
+// initIntegrity derives the integrity key from the raw database key and
+// checks the vault's contents against the stored checksum. A mismatch does
+// not prevent opening the vault; it is reported by IntegrityError.
+func (vd *VaultDatabase) initIntegrity(rawKey []byte) error {
+	vd.integrityKey = deriveIntegrityKey(rawKey)
+	vd.integrityErr = nil
+
+	err := vd.verifyIntegrity()
+	if err == ErrIntegrityMismatch {
+		vd.integrityErr = err
+		return nil
+	}
+	if err != nil {
+		vd.Close()
+		return err
+	}
+	return nil
+}
+
+// open connects using pragmaKey, a raw key from rawKey. Passwords are never
+// passed here: the driver splices the key into the DSN and a PRAGMA
+// unescaped, which breaks on characters such as &, % and quotes.
+func (vd *VaultDatabase) open(pragmaKey string) error {
 	if vd.isOpen {
 		return nil // Already connected
 	}
 
 	// Build connection string with SQLCipher parameters
-	connStr := fmt.Sprintf("%s?_pragma_key=%s&_pragma_cipher_page_size=4096&_pragma_cipher_hmac_algorithm=HMAC_SHA512&_pragma_cipher_kdf_algorithm=PBKDF2_HMAC_SHA512&_pragma_cipher_kdf_iter=256000",
-		vd.dbPath, password)
+	connStr := fmt.Sprintf("%s?_pragma_key=%s&_pragma_cipher_page_size=4096&_pragma_cipher_hmac_algorithm=HMAC_SHA512&_pragma_cipher_kdf_algorithm=PBKDF2_HMAC_SHA512&_pragma_cipher_kdf_iter=%d%s",
+		vd.dbPath, pragmaKey, kdfIterations, vd.durabilityParams())
 
-	db, err := sql.Open("sqlite3", connStr)
+	db, err := sql.Open(driverName, connStr)
 	if err != nil {
 		return NewDatabaseError("connect", err)
 	}
@@ -61,8 +133,40 @@ func (vd *VaultDatabase) Connect(password string) error {
 	vd.connection = db
 	vd.isOpen = true
 
+	if vd.readOnly {
+		return vd.enterReadOnly()
+	}
+
 	// Initialize schema if needed
-	return vd.initializeSchema()
+	if err := vd.initializeSchema(); err != nil {
+		return err
+	}
+
+	// Bring vaults created by older versions up to date
+	return vd.migrate()
+}
+
+// enterReadOnly stops the connection from writing and makes sure the vault
+// does not need migrations, which would require writing
+func (vd *VaultDatabase) enterReadOnly() error {
+	// query_only is per connection, so keep a single one
+	vd.connection.SetMaxOpenConns(1)
+	if _, err := vd.connection.Exec(`PRAGMA query_only = 1`); err != nil {
+		vd.Close()
+		return NewDatabaseError("connect_read_only", err)
+	}
+
+	version, err := vd.schemaVersion()
+	if err != nil {
+		vd.Close()
+		return err
+	}
+	if version < SchemaVersion {
+		vd.Close()
+		return NewDatabaseError("connect_read_only",
+			fmt.Errorf("vault schema version %d is older than %d; open it once with write access to upgrade it", version, SchemaVersion))
+	}
+	return nil
 }
 
 // testConnection verifies the database connection and password
@@ -175,9 +279,20 @@ func (vd *VaultDatabase) Rekey(oldPassword, newPassword string) error {
 		return fmt.Errorf("failed to verify old password: %w", err)
 	}
 
-	// Execute PRAGMA rekey to change the password
-	// SQLCipher will re-encrypt the entire database with the new password
-	_, err := vd.connection.Exec(fmt.Sprintf("PRAGMA rekey = '%s'", newPassword))
+	// The checksum is keyed by the password, so it has to be resealed
+	// afterwards; only do that if it was intact to begin with
+	intact := vd.integrityErr == nil
+
+	// Execute PRAGMA rekey to change the password. The vault keeps its
+	// salt, so the new raw key derived against it is what the new password
+	// will derive on the next unlock; as hex it needs no escaping.
+	// SQLCipher will re-encrypt the entire database with the new key
+	key, err := vd.DeriveKey(newPassword)
+	if err != nil {
+		vd.Close()
+		return err
+	}
+	_, err = vd.connection.Exec(fmt.Sprintf(`PRAGMA rekey = "%s"`, rawKey(key, nil)))
 	if err != nil {
 		vd.Close()
 		return NewDatabaseError("rekey", err)
@@ -193,6 +308,9 @@ func (vd *VaultDatabase) Rekey(oldPassword, newPassword string) error {
 		return fmt.Errorf("failed to verify new password after rekey: %w", err)
 	}
 
+	if intact {
+		return vd.ResealIntegrity()
+	}
 	return nil
 }
 
@@ -202,15 +320,18 @@ func (vd *VaultDatabase) Close() error {
 		return nil
 	}
 
+	checkpointErr := vd.checkpoint()
 	err := vd.connection.Close()
 	vd.connection = nil
 	vd.isOpen = false
+	vd.integrityKey = nil
+	vd.integrityErr = nil
 
 	if err != nil {
 		return NewDatabaseError("close", err)
 	}
 
-	return nil
+	return checkpointErr
 }
 
 // IsConnected returns true if the database connection is active
@@ -228,6 +349,12 @@ func (vd *VaultDatabase) ensureConnected() error {
 
 // CreateSecret adds a new secret to the vault
 func (vd *VaultDatabase) CreateSecret(key, value string) error {
+	return vd.CreateSecretContext(context.Background(), key, value)
+}
+
+// CreateSecretContext is CreateSecret with a context. Nothing is written if
+// ctx is done before the secret is committed.
+func (vd *VaultDatabase) CreateSecretContext(ctx context.Context, key, value string) error {
 	if err := vd.ensureConnected(); err != nil {
 		return err
 	}
@@ -237,45 +364,182 @@ func (vd *VaultDatabase) CreateSecret(key, value string) error {
 	}
 
 	query := `
-		INSERT INTO secrets (key, value, created_at, last_accessed, access_count)
-		VALUES (?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, 0)
+		INSERT INTO secrets (key, value, created_at, last_accessed, updated_at, access_count)
+		VALUES (?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, 0)
+	`
+
+	return vd.writeContext(ctx, "create_secret", func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, query, key, value)
+		if err != nil {
+			if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+				return ErrDuplicateKey
+			}
+			return NewDatabaseError("create_secret", err)
+		}
+		return nil
+	})
+}
+
+// ImportSecrets inserts secrets in a single transaction. Any error, including
+// a duplicate key or a row count that does not add up afterwards, rolls back
+// the whole import. It returns the number of secrets imported.
+func (vd *VaultDatabase) ImportSecrets(secrets []Secret) (int, error) {
+	return vd.ImportSecretsContext(context.Background(), secrets)
+}
+
+// ImportSecretsContext is ImportSecrets with a context. Cancelling ctx rolls
+// the import back.
+func (vd *VaultDatabase) ImportSecretsContext(ctx context.Context, secrets []Secret) (int, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return 0, err
+	}
+
+	for _, secret := range secrets {
+		if err := validateKey(secret.Key); err != nil {
+			return 0, fmt.Errorf("%w: %q", err, secret.Key)
+		}
+	}
+
+	tx, err := vd.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, NewDatabaseError("import_begin", err)
+	}
+	defer tx.Rollback() // no-op after a successful commit
+
+	var before int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM secrets`).Scan(&before); err != nil {
+		return 0, NewDatabaseError("import_count", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO secrets (key, value, created_at, last_accessed, updated_at, access_count, tags, notes, entropy_bits, value_source, url, expires_at)
+		VALUES (?, ?, ?, ?, ?, 0, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return 0, NewDatabaseError("import_prepare", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+	for _, secret := range secrets {
+		createdAt := secret.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = now
+		}
+
+		updatedAt := createdAt
+		if secret.UpdatedAt != nil {
+			updatedAt = *secret.UpdatedAt
+		}
+
+		source := secret.ValueSource
+		if source == nil {
+			imported := SourceImported
+			source = &imported
+		}
+
+		var expiresAt interface{}
+		if secret.ExpiresAt != nil {
+			expiresAt = secret.ExpiresAt.UTC()
+		}
+
+		_, err := stmt.ExecContext(ctx, secret.Key, secret.Value, createdAt.UTC(), now, updatedAt.UTC(), secret.Tags, secret.Notes, secret.EntropyBits, source, secret.URL, expiresAt)
+		if err != nil {
+			if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+				return 0, fmt.Errorf("%w: %q", ErrDuplicateKey, secret.Key)
+			}
+			return 0, NewDatabaseError("import_secret", err)
+		}
+	}
+
+	var after int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM secrets`).Scan(&after); err != nil {
+		return 0, NewDatabaseError("import_count", err)
+	}
+	if after-before != len(secrets) {
+		return 0, NewDatabaseError("import_verify",
+			fmt.Errorf("expected %d new secrets, found %d", len(secrets), after-before))
+	}
+
+	if err := vd.sealIntegrity(tx); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, NewDatabaseError("import_commit", err)
+	}
+	vd.watch.notify()
+
+	return len(secrets), nil
+}
+
+// ExportSecrets returns full secrets, including values, whose keys match the
+// glob pattern (case-insensitive). An empty pattern matches every visible
+// secret. Unlike GetSecret it does not update access tracking.
+func (vd *VaultDatabase) ExportSecrets(pattern string) ([]Secret, error) {
+	return vd.ExportSecretsContext(context.Background(), pattern)
+}
+
+// ExportSecretsContext is ExportSecrets with a context
+func (vd *VaultDatabase) ExportSecretsContext(ctx context.Context, pattern string) ([]Secret, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	query := `
+		SELECT ` + secretColumns + `
+		FROM secrets
+		WHERE hidden = 0 AND lower(key) GLOB lower(?)
+		ORDER BY key ASC
 	`
 
-	_, err := vd.connection.Exec(query, key, value)
+	rows, err := vd.connection.QueryContext(ctx, query, pattern)
 	if err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
-			return ErrDuplicateKey
+		return nil, NewDatabaseError("export_secrets", err)
+	}
+	defer rows.Close()
+
+	var secrets []Secret
+	for rows.Next() {
+		var secret Secret
+		err := scanSecret(rows, &secret)
+		if err != nil {
+			return nil, NewDatabaseError("scan_export_secrets", err)
 		}
-		return NewDatabaseError("create_secret", err)
+		secrets = append(secrets, secret)
 	}
 
-	return nil
+	if err = rows.Err(); err != nil {
+		return nil, NewDatabaseError("export_secrets_iteration", err)
+	}
+
+	return secrets, nil
 }
 
 // GetSecret retrieves a secret by key and updates access tracking
 func (vd *VaultDatabase) GetSecret(key string) (*Secret, error) {
+	return vd.GetSecretContext(context.Background(), key)
+}
+
+// GetSecretContext is GetSecret with a context
+func (vd *VaultDatabase) GetSecretContext(ctx context.Context, key string) (*Secret, error) {
 	if err := vd.ensureConnected(); err != nil {
 		return nil, err
 	}
 
 	// First, get the secret
 	query := `
-		SELECT id, key, value, created_at, last_accessed, access_count, tags, notes
+		SELECT ` + secretColumns + `
 		FROM secrets
-		WHERE key = ? COLLATE NOCASE
+		WHERE key = ? COLLATE NOCASE AND hidden = 0 AND archived_at IS NULL
 	`
 
 	var secret Secret
-	err := vd.connection.QueryRow(query, key).Scan(
-		&secret.ID,
-		&secret.Key,
-		&secret.Value,
-		&secret.CreatedAt,
-		&secret.LastAccessed,
-		&secret.AccessCount,
-		&secret.Tags,
-		&secret.Notes,
-	)
+	err := scanSecret(vd.connection.QueryRowContext(ctx, query, key), &secret)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -291,7 +555,7 @@ func (vd *VaultDatabase) GetSecret(key string) (*Secret, error) {
 		WHERE key = ? COLLATE NOCASE
 	`
 
-	_, err = vd.connection.Exec(updateQuery, key)
+	_, err = vd.connection.ExecContext(ctx, updateQuery, key)
 	if err != nil {
 		// Non-fatal error - return the secret but log the tracking failure
 		return &secret, NewDatabaseError("update_access_tracking", err)
@@ -300,29 +564,34 @@ func (vd *VaultDatabase) GetSecret(key string) (*Secret, error) {
 	// Increment the access count in the returned secret to match database state
 	secret.AccessCount++
 
+	if err := vd.recordUsage(ctx, secret.Key, time.Now()); err != nil {
+		return &secret, err
+	}
+
 	return &secret, nil
 }
 
-// UpdateSecret updates an existing secret's value
-func (vd *VaultDatabase) UpdateSecret(key, value string) error {
+// SetSecretStrength records the entropy estimate and origin of a secret's
+// current value so audits need not re-analyze values
+func (vd *VaultDatabase) SetSecretStrength(key string, entropyBits float64, source string) error {
 	if err := vd.ensureConnected(); err != nil {
 		return err
 	}
 
 	query := `
 		UPDATE secrets
-		SET value = ?, last_accessed = CURRENT_TIMESTAMP
-		WHERE key = ? COLLATE NOCASE
+		SET entropy_bits = ?, value_source = ?
+		WHERE key = ? COLLATE NOCASE AND hidden = 0
 	`
 
-	result, err := vd.connection.Exec(query, value, key)
+	result, err := vd.connection.Exec(query, entropyBits, source, key)
 	if err != nil {
-		return NewDatabaseError("update_secret", err)
+		return NewDatabaseError("set_secret_strength", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return NewDatabaseError("update_secret_check", err)
+		return NewDatabaseError("set_secret_strength_check", err)
 	}
 
 	if rowsAffected == 0 {
@@ -332,44 +601,178 @@ func (vd *VaultDatabase) UpdateSecret(key, value string) error {
 	return nil
 }
 
-// DeleteSecret removes a secret from the vault
-func (vd *VaultDatabase) DeleteSecret(key string) error {
+// PeekSecret retrieves a secret by key without recording an access
+func (vd *VaultDatabase) PeekSecret(key string) (*Secret, error) {
+	return vd.PeekSecretContext(context.Background(), key)
+}
+
+// PeekSecretContext is PeekSecret with a context
+func (vd *VaultDatabase) PeekSecretContext(ctx context.Context, key string) (*Secret, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + secretColumns + `
+		FROM secrets
+		WHERE key = ? COLLATE NOCASE AND hidden = 0
+	`
+
+	var secret Secret
+	if err := scanSecret(vd.connection.QueryRowContext(ctx, query, key), &secret); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrKeyNotFound
+		}
+		return nil, NewDatabaseError("peek_secret", err)
+	}
+
+	return &secret, nil
+}
+
+// UpdateSecret updates an existing secret's value
+func (vd *VaultDatabase) UpdateSecret(key, value string) error {
+	return vd.UpdateSecretContext(context.Background(), key, value)
+}
+
+// UpdateSecretContext is UpdateSecret with a context. Nothing is written if
+// ctx is done before the change is committed.
+func (vd *VaultDatabase) UpdateSecretContext(ctx context.Context, key, value string) error {
 	if err := vd.ensureConnected(); err != nil {
 		return err
 	}
 
-	query := `DELETE FROM secrets WHERE key = ? COLLATE NOCASE`
+	query := `
+		UPDATE secrets
+		SET value = ?, last_accessed = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP,
+			entropy_bits = NULL, value_source = NULL, revision = revision + 1
+		WHERE key = ? COLLATE NOCASE AND hidden = 0
+	`
 
-	result, err := vd.connection.Exec(query, key)
-	if err != nil {
-		return NewDatabaseError("delete_secret", err)
+	return vd.writeContext(ctx, "update_secret", func(tx *sql.Tx) error {
+		if err := saveVersion(tx, key, VersionReplaced); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, query, value, key)
+		if err != nil {
+			return NewDatabaseError("update_secret", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return NewDatabaseError("update_secret_check", err)
+		}
+
+		if rowsAffected == 0 {
+			return ErrKeyNotFound
+		}
+		return nil
+	})
+}
+
+// UpdateSecretIfRevision updates a secret's value only if its revision still
+// equals revision. It returns ErrRevisionMismatch when the secret has been
+// changed since that revision was read.
+func (vd *VaultDatabase) UpdateSecretIfRevision(key, value string, revision int64) error {
+	if err := vd.ensureConnected(); err != nil {
+		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return NewDatabaseError("delete_secret_check", err)
+	query := `
+		UPDATE secrets
+		SET value = ?, last_accessed = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP,
+			entropy_bits = NULL, value_source = NULL, revision = revision + 1
+		WHERE key = ? COLLATE NOCASE AND hidden = 0 AND revision = ?
+	`
+
+	err := vd.write("update_secret_if_revision", func(tx *sql.Tx) error {
+		// Rolled back with the update on a revision mismatch
+		if err := saveVersion(tx, key, VersionReplaced); err != nil {
+			return err
+		}
+		result, err := tx.Exec(query, value, key, revision)
+		if err != nil {
+			return NewDatabaseError("update_secret_if_revision", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return NewDatabaseError("update_secret_if_revision_check", err)
+		}
+
+		if rowsAffected == 0 {
+			return ErrRevisionMismatch
+		}
+		return nil
+	})
+
+	if err == ErrRevisionMismatch {
+		// Distinguish a missing key from a concurrent change
+		if _, peekErr := vd.PeekSecret(key); peekErr != nil {
+			return peekErr
+		}
 	}
+	return err
+}
 
-	if rowsAffected == 0 {
-		return ErrKeyNotFound
+// DeleteSecret removes a secret from the vault
+func (vd *VaultDatabase) DeleteSecret(key string) error {
+	return vd.DeleteSecretContext(context.Background(), key)
+}
+
+// DeleteSecretContext is DeleteSecret with a context. Nothing is deleted if
+// ctx is done before the deletion is committed.
+func (vd *VaultDatabase) DeleteSecretContext(ctx context.Context, key string) error {
+	if err := vd.ensureConnected(); err != nil {
+		return err
 	}
 
-	return nil
+	query := `DELETE FROM secrets WHERE key = ? COLLATE NOCASE AND hidden = 0`
+
+	return vd.writeContext(ctx, "delete_secret", func(tx *sql.Tx) error {
+		if err := saveVersion(tx, key, VersionDeleted); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, query, key)
+		if err != nil {
+			return NewDatabaseError("delete_secret", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return NewDatabaseError("delete_secret_check", err)
+		}
+
+		if rowsAffected == 0 {
+			return ErrKeyNotFound
+		}
+
+		// Usage history is only useful while the secret exists
+		if _, err := tx.ExecContext(ctx, `DELETE FROM secret_usage WHERE key = ? COLLATE NOCASE`, key); err != nil {
+			return NewDatabaseError("delete_secret_usage", err)
+		}
+		return nil
+	})
 }
 
 // ListSecrets returns all secrets for search and display (without values for security)
 func (vd *VaultDatabase) ListSecrets() ([]SearchResult, error) {
+	return vd.ListSecretsContext(context.Background())
+}
+
+// ListSecretsContext is ListSecrets with a context
+func (vd *VaultDatabase) ListSecretsContext(ctx context.Context) ([]SearchResult, error) {
 	if err := vd.ensureConnected(); err != nil {
 		return nil, err
 	}
 
 	query := `
-		SELECT key, created_at, last_accessed, access_count, tags
+		SELECT key, created_at, last_accessed, access_count, tags, revision
 		FROM secrets
+		WHERE hidden = 0 AND archived_at IS NULL
 		ORDER BY last_accessed DESC, key ASC
 	`
 
-	rows, err := vd.connection.Query(query)
+	rows, err := vd.connection.QueryContext(ctx, query)
 	if err != nil {
 		return nil, NewDatabaseError("list_secrets", err)
 	}
@@ -384,6 +787,7 @@ func (vd *VaultDatabase) ListSecrets() ([]SearchResult, error) {
 			&result.LastAccessed,
 			&result.AccessCount,
 			&result.Tags,
+			&result.Revision,
 		)
 		if err != nil {
 			return nil, NewDatabaseError("scan_secret_list", err)
@@ -398,34 +802,31 @@ func (vd *VaultDatabase) ListSecrets() ([]SearchResult, error) {
 	return results, nil
 }
 
-// SearchSecrets performs fuzzy search on secret keys
-func (vd *VaultDatabase) SearchSecrets(pattern string) ([]SearchResult, error) {
+// ListSecretsPage returns a single page of secrets using SQL LIMIT/OFFSET.
+// A limit of zero or less returns all secrets starting at offset.
+func (vd *VaultDatabase) ListSecretsPage(limit, offset int) ([]SearchResult, error) {
 	if err := vd.ensureConnected(); err != nil {
 		return nil, err
 	}
 
-	// Use LIKE for basic pattern matching (fuzzy search logic will be in search package)
+	if limit <= 0 {
+		limit = -1 // SQLite treats a negative LIMIT as unbounded
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
 	query := `
-		SELECT key, created_at, last_accessed, access_count, tags
+		SELECT key, created_at, last_accessed, access_count, tags, revision
 		FROM secrets
-		WHERE key LIKE ? COLLATE NOCASE
-		ORDER BY
-			CASE
-				WHEN key = ? COLLATE NOCASE THEN 1
-				WHEN key LIKE ? || '%' COLLATE NOCASE THEN 2
-				ELSE 3
-			END,
-			last_accessed DESC,
-			key ASC
-		LIMIT 100
+		WHERE hidden = 0 AND archived_at IS NULL
+		ORDER BY last_accessed DESC, key ASC
+		LIMIT ? OFFSET ?
 	`
 
-	likePattern := "%" + pattern + "%"
-	prefixPattern := pattern
-
-	rows, err := vd.connection.Query(query, likePattern, pattern, prefixPattern)
+	rows, err := vd.connection.Query(query, limit, offset)
 	if err != nil {
-		return nil, NewDatabaseError("search_secrets", err)
+		return nil, NewDatabaseError("list_secrets_page", err)
 	}
 	defer rows.Close()
 
@@ -438,37 +839,616 @@ func (vd *VaultDatabase) SearchSecrets(pattern string) ([]SearchResult, error) {
 			&result.LastAccessed,
 			&result.AccessCount,
 			&result.Tags,
+			&result.Revision,
 		)
 		if err != nil {
-			return nil, NewDatabaseError("scan_search_results", err)
+			return nil, NewDatabaseError("scan_secret_page", err)
 		}
 		results = append(results, result)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, NewDatabaseError("search_secrets_iteration", err)
+		return nil, NewDatabaseError("list_secrets_page_iteration", err)
 	}
 
 	return results, nil
 }
 
-// LogAuthAttempt records an authentication attempt
-func (vd *VaultDatabase) LogAuthAttempt(username string, success bool, ipAddress *string, sessionID *string) error {
+// CountSecrets returns the number of active secrets stored in the vault
+func (vd *VaultDatabase) CountSecrets() (int, error) {
+	return vd.CountSecretsContext(context.Background())
+}
+
+// CountSecretsContext is CountSecrets with a context
+func (vd *VaultDatabase) CountSecretsContext(ctx context.Context) (int, error) {
 	if err := vd.ensureConnected(); err != nil {
-		return err
+		return 0, err
 	}
 
-	query := `
-		INSERT INTO auth_attempts (timestamp, username, success, ip_address, session_id)
-		VALUES (CURRENT_TIMESTAMP, ?, ?, ?, ?)
-	`
-
-	_, err := vd.connection.Exec(query, username, success, ipAddress, sessionID)
-	if err != nil {
-		return NewDatabaseError("log_auth_attempt", err)
+	var count int
+	if err := vd.connection.QueryRowContext(ctx, `SELECT COUNT(*) FROM secrets WHERE hidden = 0 AND archived_at IS NULL`).Scan(&count); err != nil {
+		return 0, NewDatabaseError("count_secrets", err)
 	}
 
-	return nil
+	return count, nil
+}
+
+// RecentSecrets returns up to limit secrets that have been retrieved at least
+// once, most recently accessed first
+func (vd *VaultDatabase) RecentSecrets(limit int) ([]SearchResult, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = -1 // SQLite treats a negative LIMIT as unbounded
+	}
+
+	query := `
+		SELECT key, created_at, last_accessed, access_count, tags, revision
+		FROM secrets
+		WHERE access_count > 0 AND hidden = 0 AND archived_at IS NULL
+		ORDER BY last_accessed DESC, id DESC
+		LIMIT ?
+	`
+
+	rows, err := vd.connection.Query(query, limit)
+	if err != nil {
+		return nil, NewDatabaseError("recent_secrets", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var result SearchResult
+		err := rows.Scan(
+			&result.Key,
+			&result.CreatedAt,
+			&result.LastAccessed,
+			&result.AccessCount,
+			&result.Tags,
+			&result.Revision,
+		)
+		if err != nil {
+			return nil, NewDatabaseError("scan_recent_secrets", err)
+		}
+		results = append(results, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, NewDatabaseError("recent_secrets_iteration", err)
+	}
+
+	return results, nil
+}
+
+// SearchSecrets performs fuzzy search on secret keys
+func (vd *VaultDatabase) SearchSecrets(pattern string) ([]SearchResult, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	// Use LIKE for basic pattern matching (fuzzy search logic will be in search package)
+	query := `
+		SELECT key, created_at, last_accessed, access_count, tags, revision
+		FROM secrets
+		WHERE key LIKE ? COLLATE NOCASE AND hidden = 0 AND archived_at IS NULL
+		ORDER BY
+			CASE
+				WHEN key = ? COLLATE NOCASE THEN 1
+				WHEN key LIKE ? || '%' COLLATE NOCASE THEN 2
+				ELSE 3
+			END,
+			last_accessed DESC,
+			key ASC
+		LIMIT 100
+	`
+
+	likePattern := "%" + pattern + "%"
+	prefixPattern := pattern
+
+	rows, err := vd.connection.Query(query, likePattern, pattern, prefixPattern)
+	if err != nil {
+		return nil, NewDatabaseError("search_secrets", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var result SearchResult
+		err := rows.Scan(
+			&result.Key,
+			&result.CreatedAt,
+			&result.LastAccessed,
+			&result.AccessCount,
+			&result.Tags,
+			&result.Revision,
+		)
+		if err != nil {
+			return nil, NewDatabaseError("scan_search_results", err)
+		}
+		results = append(results, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, NewDatabaseError("search_secrets_iteration", err)
+	}
+
+	return results, nil
+}
+
+// SearchSecretsGlob returns a page of secrets whose keys match a shell-style
+// glob pattern (e.g. "prod/*/db") along with the total number of matches.
+// Matching is case-insensitive and evaluated in SQL.
+func (vd *VaultDatabase) SearchSecretsGlob(pattern string, limit, offset int) ([]SearchResult, int, error) {
+	return vd.SearchSecretsGlobContext(context.Background(), pattern, limit, offset)
+}
+
+// SearchSecretsGlobContext is SearchSecretsGlob with a context
+func (vd *VaultDatabase) SearchSecretsGlobContext(ctx context.Context, pattern string, limit, offset int) ([]SearchResult, int, error) {
+	return vd.searchSecretsWhere(ctx, "lower(key) GLOB lower(?)", pattern, limit, offset, "search_secrets_glob")
+}
+
+// SearchSecretsRegex returns a page of secrets whose keys match a regular
+// expression along with the total number of matches. Matching is
+// case-insensitive and evaluated in SQL.
+func (vd *VaultDatabase) SearchSecretsRegex(pattern string, limit, offset int) ([]SearchResult, int, error) {
+	return vd.SearchSecretsRegexContext(context.Background(), pattern, limit, offset)
+}
+
+// SearchSecretsRegexContext is SearchSecretsRegex with a context. Matching
+// runs row by row in SQL, so an expensive pattern over a large vault is
+// interrupted when ctx is done.
+func (vd *VaultDatabase) SearchSecretsRegexContext(ctx context.Context, pattern string, limit, offset int) ([]SearchResult, int, error) {
+	if _, err := regexp.Compile(pattern); err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrInvalidPattern, err)
+	}
+	return vd.searchSecretsWhere(ctx, "key REGEXP ?", "(?i)"+pattern, limit, offset, "search_secrets_regex")
+}
+
+// searchSecretsWhere runs a paginated secrets query filtered by a single-argument WHERE clause
+func (vd *VaultDatabase) searchSecretsWhere(ctx context.Context, where string, arg interface{}, limit, offset int, operation string) ([]SearchResult, int, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, 0, err
+	}
+
+	if limit <= 0 {
+		limit = -1 // SQLite treats a negative LIMIT as unbounded
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	var total int
+	countQuery := "SELECT COUNT(*) FROM secrets WHERE hidden = 0 AND archived_at IS NULL AND " + where
+	if err := vd.connection.QueryRowContext(ctx, countQuery, arg).Scan(&total); err != nil {
+		return nil, 0, NewDatabaseError(operation+"_count", err)
+	}
+
+	query := `
+		SELECT key, created_at, last_accessed, access_count, tags, revision
+		FROM secrets
+		WHERE hidden = 0 AND archived_at IS NULL AND ` + where + `
+		ORDER BY key ASC
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := vd.connection.QueryContext(ctx, query, arg, limit, offset)
+	if err != nil {
+		return nil, 0, NewDatabaseError(operation, err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var result SearchResult
+		err := rows.Scan(
+			&result.Key,
+			&result.CreatedAt,
+			&result.LastAccessed,
+			&result.AccessCount,
+			&result.Tags,
+			&result.Revision,
+		)
+		if err != nil {
+			return nil, 0, NewDatabaseError(operation+"_scan", err)
+		}
+		results = append(results, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, NewDatabaseError(operation+"_iteration", err)
+	}
+
+	return results, total, nil
+}
+
+// LogAuthAttempt records an authentication attempt along with the local
+// context (terminal, hostname, client) it originated from
+func (vd *VaultDatabase) LogAuthAttempt(info ClientInfo, success bool, sessionID *string) error {
+	if err := vd.ensureConnected(); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO auth_attempts (timestamp, username, success, session_id, terminal, hostname, client)
+		VALUES (CURRENT_TIMESTAMP, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := vd.connection.Exec(query, info.Username, success, sessionID,
+		nullIfEmpty(info.Terminal), nullIfEmpty(info.Hostname), nullIfEmpty(info.Client))
+	if err != nil {
+		return NewDatabaseError("log_auth_attempt", err)
+	}
+
+	return nil
+}
+
+// ListAuthAttempts returns up to limit authentication attempts, newest first
+func (vd *VaultDatabase) ListAuthAttempts(limit int) ([]AuthAttempt, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = -1 // SQLite treats a negative LIMIT as unbounded
+	}
+
+	query := `
+		SELECT id, timestamp, username, success, session_id, terminal, hostname, client
+		FROM auth_attempts
+		ORDER BY timestamp DESC, id DESC
+		LIMIT ?
+	`
+
+	rows, err := vd.connection.Query(query, limit)
+	if err != nil {
+		return nil, NewDatabaseError("list_auth_attempts", err)
+	}
+	defer rows.Close()
+
+	var attempts []AuthAttempt
+	for rows.Next() {
+		var attempt AuthAttempt
+		err := rows.Scan(
+			&attempt.ID,
+			&attempt.Timestamp,
+			&attempt.Username,
+			&attempt.Success,
+			&attempt.SessionID,
+			&attempt.Terminal,
+			&attempt.Hostname,
+			&attempt.Client,
+		)
+		if err != nil {
+			return nil, NewDatabaseError("scan_auth_attempts", err)
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, NewDatabaseError("list_auth_attempts_iteration", err)
+	}
+
+	return attempts, nil
+}
+
+// LogAuditEvent records a security-relevant operation such as a secret retrieval
+func (vd *VaultDatabase) LogAuditEvent(event *AuditEvent) error {
+	if err := vd.ensureConnected(); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO audit_events (timestamp, event, key, username, terminal, hostname, client, identity, session_id, details)
+		VALUES (CURRENT_TIMESTAMP, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := vd.connection.Exec(query,
+		event.Event,
+		event.Key,
+		nullIfEmpty(event.Client.Username),
+		nullIfEmpty(event.Client.Terminal),
+		nullIfEmpty(event.Client.Hostname),
+		nullIfEmpty(event.Client.Client),
+		nullIfEmpty(event.Client.Identity),
+		event.SessionID,
+		event.Details,
+	)
+	if err != nil {
+		return NewDatabaseError("log_audit_event", err)
+	}
+
+	if id, err := result.LastInsertId(); err == nil {
+		event.ID = id
+	}
+
+	return nil
+}
+
+// ListAuditEvents returns up to limit audit events, newest first
+func (vd *VaultDatabase) ListAuditEvents(limit int) ([]AuditEvent, error) {
+	return vd.listAuditEvents("", "", limit)
+}
+
+// ListAuditEventsByIdentity returns up to limit audit events recorded for
+// the client identity (a plugin or token ID), newest first
+func (vd *VaultDatabase) ListAuditEventsByIdentity(identity string, limit int) ([]AuditEvent, error) {
+	return vd.listAuditEvents("WHERE identity = ? COLLATE NOCASE", identity, limit)
+}
+
+// listAuditEvents returns up to limit audit events matching the optional
+// where clause, which takes arg as its only parameter
+func (vd *VaultDatabase) listAuditEvents(where, arg string, limit int) ([]AuditEvent, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = -1 // SQLite treats a negative LIMIT as unbounded
+	}
+
+	query := `
+		SELECT id, timestamp, event, key, username, terminal, hostname, client, identity, session_id, details
+		FROM audit_events
+		` + where + `
+		ORDER BY timestamp DESC, id DESC
+		LIMIT ?
+	`
+	args := []interface{}{limit}
+	if where != "" {
+		args = []interface{}{arg, limit}
+	}
+
+	rows, err := vd.connection.Query(query, args...)
+	if err != nil {
+		return nil, NewDatabaseError("list_audit_events", err)
+	}
+	defer rows.Close()
+
+	var events []AuditEvent
+	for rows.Next() {
+		var event AuditEvent
+		var username, terminal, hostname, client, identity sql.NullString
+		err := rows.Scan(
+			&event.ID,
+			&event.Timestamp,
+			&event.Event,
+			&event.Key,
+			&username,
+			&terminal,
+			&hostname,
+			&client,
+			&identity,
+			&event.SessionID,
+			&event.Details,
+		)
+		if err != nil {
+			return nil, NewDatabaseError("scan_audit_events", err)
+		}
+		event.Client = ClientInfo{
+			Username: username.String,
+			Terminal: terminal.String,
+			Hostname: hostname.String,
+			Client:   client.String,
+			Identity: identity.String,
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, NewDatabaseError("list_audit_events_iteration", err)
+	}
+
+	return events, nil
+}
+
+// nullIfEmpty converts an empty string into a SQL NULL
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// CreateSession stores a new session record
+func (vd *VaultDatabase) CreateSession(session *Session) error {
+	if err := vd.ensureConnected(); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO sessions (session_id, created_at, expires_at, last_activity)
+		VALUES (?, ?, ?, ?)
+	`
+
+	result, err := vd.connection.Exec(query,
+		session.SessionID,
+		session.CreatedAt.UTC(),
+		session.ExpiresAt.UTC(),
+		session.LastActivity.UTC(),
+	)
+	if err != nil {
+		return NewDatabaseError("create_session", err)
+	}
+
+	if id, err := result.LastInsertId(); err == nil {
+		session.ID = id
+	}
+
+	return nil
+}
+
+// UpdateSession updates the expiry and last activity of an existing session
+func (vd *VaultDatabase) UpdateSession(session *Session) error {
+	if err := vd.ensureConnected(); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE sessions
+		SET expires_at = ?, last_activity = ?
+		WHERE session_id = ?
+	`
+
+	result, err := vd.connection.Exec(query, session.ExpiresAt.UTC(), session.LastActivity.UTC(), session.SessionID)
+	if err != nil {
+		return NewDatabaseError("update_session", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return NewDatabaseError("update_session_check", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrInvalidSession
+	}
+
+	return nil
+}
+
+// GetSession retrieves a session by its identifier
+func (vd *VaultDatabase) GetSession(sessionID string) (*Session, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, session_id, created_at, expires_at, last_activity
+		FROM sessions
+		WHERE session_id = ?
+	`
+
+	var session Session
+	err := vd.connection.QueryRow(query, sessionID).Scan(
+		&session.ID,
+		&session.SessionID,
+		&session.CreatedAt,
+		&session.ExpiresAt,
+		&session.LastActivity,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidSession
+		}
+		return nil, NewDatabaseError("get_session", err)
+	}
+
+	return &session, nil
+}
+
+// DeleteSession removes a session record
+func (vd *VaultDatabase) DeleteSession(sessionID string) error {
+	if err := vd.ensureConnected(); err != nil {
+		return err
+	}
+
+	result, err := vd.connection.Exec(`DELETE FROM sessions WHERE session_id = ?`, sessionID)
+	if err != nil {
+		return NewDatabaseError("delete_session", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return NewDatabaseError("delete_session_check", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrInvalidSession
+	}
+
+	return nil
+}
+
+// ListSessions returns all stored sessions, most recently active first
+func (vd *VaultDatabase) ListSessions() ([]Session, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, session_id, created_at, expires_at, last_activity
+		FROM sessions
+		ORDER BY last_activity DESC
+	`
+
+	rows, err := vd.connection.Query(query)
+	if err != nil {
+		return nil, NewDatabaseError("list_sessions", err)
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		var session Session
+		err := rows.Scan(
+			&session.ID,
+			&session.SessionID,
+			&session.CreatedAt,
+			&session.ExpiresAt,
+			&session.LastActivity,
+		)
+		if err != nil {
+			return nil, NewDatabaseError("scan_session_list", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, NewDatabaseError("list_sessions_iteration", err)
+	}
+
+	return sessions, nil
+}
+
+// DeleteExpiredSessions removes sessions that expired before now and returns
+// the number of rows deleted
+func (vd *VaultDatabase) DeleteExpiredSessions(now time.Time) (int64, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return 0, err
+	}
+
+	result, err := vd.connection.Exec(`DELETE FROM sessions WHERE expires_at < ?`, now.UTC())
+	if err != nil {
+		return 0, NewDatabaseError("delete_expired_sessions", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, NewDatabaseError("delete_expired_sessions_check", err)
+	}
+
+	return deleted, nil
+}
+
+// secretColumns lists the secrets columns read by scanSecret, in order
+const secretColumns = `id, key, value, created_at, last_accessed, access_count, tags, notes, entropy_bits, value_source, updated_at, revision, archived_at, url, expires_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSecret scans a row selected with secretColumns into secret
+func scanSecret(row rowScanner, secret *Secret) error {
+	return row.Scan(
+		&secret.ID,
+		&secret.Key,
+		&secret.Value,
+		&secret.CreatedAt,
+		&secret.LastAccessed,
+		&secret.AccessCount,
+		&secret.Tags,
+		&secret.Notes,
+		&secret.EntropyBits,
+		&secret.ValueSource,
+		&secret.UpdatedAt,
+		&secret.Revision,
+		&secret.ArchivedAt,
+		&secret.URL,
+		&secret.ExpiresAt,
+	)
 }
 
 // validateKey validates a secret key according to the application rules
@@ -483,7 +1463,7 @@ func validateKey(key string) error {
 
 	// Check for valid characters (alphanumeric + common punctuation)
 	for _, r := range key {
-		if !isValidKeyChar(r) {
+		if !IsValidKeyChar(r) {
 			return ErrInvalidKey
 		}
 	}
@@ -491,8 +1471,8 @@ func validateKey(key string) error {
 	return nil
 }
 
-// isValidKeyChar checks if a character is valid for use in a key
-func isValidKeyChar(r rune) bool {
+// IsValidKeyChar checks if a character is valid for use in a key
+func IsValidKeyChar(r rune) bool {
 	// Allow alphanumeric characters
 	if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
 		return true
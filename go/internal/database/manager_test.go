@@ -1,9 +1,13 @@
 package database
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -176,3 +180,1337 @@ func TestVaultDatabase_Search(t *testing.T) {
 	require.NoError(t, err)
 	assert.Len(t, results, 0)
 }
+
+func TestVaultDatabase_ListSecretsPage(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := filepath.Join(tmpDir, "test.db")
+	vd := NewVaultDatabase(dbPath)
+	err = vd.Connect("test_password")
+	require.NoError(t, err)
+	defer vd.Close()
+
+	for i := 0; i < 7; i++ {
+		require.NoError(t, vd.CreateSecret(fmt.Sprintf("key_%d", i), "value"))
+	}
+
+	count, err := vd.CountSecrets()
+	require.NoError(t, err)
+	assert.Equal(t, 7, count)
+
+	page, err := vd.ListSecretsPage(3, 0)
+	require.NoError(t, err)
+	assert.Len(t, page, 3)
+
+	page, err = vd.ListSecretsPage(3, 6)
+	require.NoError(t, err)
+	assert.Len(t, page, 1)
+
+	page, err = vd.ListSecretsPage(0, 2)
+	require.NoError(t, err)
+	assert.Len(t, page, 5)
+}
+
+func TestVaultDatabase_GlobAndRegexSearch(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := filepath.Join(tmpDir, "test.db")
+	vd := NewVaultDatabase(dbPath)
+	err = vd.Connect("test_password")
+	require.NoError(t, err)
+	defer vd.Close()
+
+	for _, key := range []string{"prod/web/db", "prod/api/db", "Prod/api/cache", "staging/web/db"} {
+		require.NoError(t, vd.CreateSecret(key, "value"))
+	}
+
+	results, total, err := vd.SearchSecretsGlob("prod/*/db", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Len(t, results, 2)
+
+	// Glob matching is case-insensitive like the rest of the key handling
+	_, total, err = vd.SearchSecretsGlob("prod/api/*", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+
+	results, total, err = vd.SearchSecretsRegex(`^(prod|staging)/web/`, 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Len(t, results, 1)
+
+	_, _, err = vd.SearchSecretsRegex(`([`, 0, 0)
+	assert.ErrorIs(t, err, ErrInvalidPattern)
+}
+
+func TestVaultDatabase_RecentSecrets(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := filepath.Join(tmpDir, "test.db")
+	vd := NewVaultDatabase(dbPath)
+	err = vd.Connect("test_password")
+	require.NoError(t, err)
+	defer vd.Close()
+
+	for _, key := range []string{"first", "second", "never_used"} {
+		require.NoError(t, vd.CreateSecret(key, "value"))
+	}
+
+	// Nothing has been retrieved yet
+	recent, err := vd.RecentSecrets(10)
+	require.NoError(t, err)
+	assert.Len(t, recent, 0)
+
+	_, err = vd.GetSecret("first")
+	require.NoError(t, err)
+	_, err = vd.GetSecret("second")
+	require.NoError(t, err)
+
+	recent, err = vd.RecentSecrets(10)
+	require.NoError(t, err)
+	require.Len(t, recent, 2)
+
+	recent, err = vd.RecentSecrets(1)
+	require.NoError(t, err)
+	assert.Len(t, recent, 1)
+}
+
+func TestVaultDatabase_Sessions(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := filepath.Join(tmpDir, "test.db")
+	vd := NewVaultDatabase(dbPath)
+	err = vd.Connect("test_password")
+	require.NoError(t, err)
+	defer vd.Close()
+
+	now := time.Now()
+	active := &Session{SessionID: "active", CreatedAt: now, ExpiresAt: now.Add(time.Hour), LastActivity: now}
+	expired := &Session{SessionID: "expired", CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour), LastActivity: now.Add(-2 * time.Hour)}
+
+	require.NoError(t, vd.CreateSession(active))
+	require.NoError(t, vd.CreateSession(expired))
+	assert.NotZero(t, active.ID)
+
+	sessions, err := vd.ListSessions()
+	require.NoError(t, err)
+	assert.Len(t, sessions, 2)
+
+	// Update extends the session
+	active.ExpiresAt = now.Add(2 * time.Hour)
+	require.NoError(t, vd.UpdateSession(active))
+	stored, err := vd.GetSession("active")
+	require.NoError(t, err)
+	assert.WithinDuration(t, active.ExpiresAt, stored.ExpiresAt, time.Second)
+
+	deleted, err := vd.DeleteExpiredSessions(now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	require.NoError(t, vd.DeleteSession("active"))
+	assert.Equal(t, ErrInvalidSession, vd.DeleteSession("active"))
+	assert.Equal(t, ErrInvalidSession, vd.UpdateSession(active))
+
+	_, err = vd.GetSession("active")
+	assert.Equal(t, ErrInvalidSession, err)
+}
+
+func TestVaultDatabase_Migrations(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	vd := NewVaultDatabase(dbPath)
+	require.NoError(t, vd.Connect("test_password"))
+
+	version, err := vd.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, SchemaVersion, version)
+	require.NoError(t, vd.Close())
+
+	// Reconnecting must not re-apply migrations
+	vd = NewVaultDatabase(dbPath)
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+
+	version, err = vd.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, SchemaVersion, version)
+}
+
+func TestVaultDatabase_AuditLog(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	vd := NewVaultDatabase(filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+
+	info := ClientInfo{Username: "alice", Terminal: "pts/3", Hostname: "laptop", Client: ClientCLI}
+	require.NoError(t, vd.LogAuthAttempt(info, true, nil))
+	require.NoError(t, vd.LogAuthAttempt(ClientInfo{Username: "alice", Client: ClientAgent}, false, nil))
+
+	attempts, err := vd.ListAuthAttempts(10)
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+
+	assert.False(t, attempts[0].Success)
+	assert.Nil(t, attempts[0].Terminal)
+	require.NotNil(t, attempts[0].Client)
+	assert.Equal(t, ClientAgent, *attempts[0].Client)
+
+	assert.True(t, attempts[1].Success)
+	require.NotNil(t, attempts[1].Terminal)
+	assert.Equal(t, "pts/3", *attempts[1].Terminal)
+	assert.Equal(t, "laptop", *attempts[1].Hostname)
+
+	limited, err := vd.ListAuthAttempts(1)
+	require.NoError(t, err)
+	assert.Len(t, limited, 1)
+
+	key := "prod/db"
+	event := &AuditEvent{Event: AuditEventGet, Key: &key, Client: info}
+	require.NoError(t, vd.LogAuditEvent(event))
+	assert.NotZero(t, event.ID)
+
+	events, err := vd.ListAuditEvents(0)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, AuditEventGet, events[0].Event)
+	require.NotNil(t, events[0].Key)
+	assert.Equal(t, key, *events[0].Key)
+	assert.Equal(t, info, events[0].Client)
+
+	// Events of a client identity can be picked out for review
+	plugin := ClientInfo{Username: "alice", Client: ClientEditor, Identity: "plugin:vscode"}
+	require.NoError(t, vd.LogAuditEvent(&AuditEvent{Event: AuditEventGet, Key: &key, Client: plugin}))
+	require.NoError(t, vd.LogAuditEvent(&AuditEvent{Event: AuditEventAccessDenied, Key: &key, Client: plugin}))
+
+	byIdentity, err := vd.ListAuditEventsByIdentity("PLUGIN:VSCode", 0)
+	require.NoError(t, err)
+	require.Len(t, byIdentity, 2)
+	assert.Equal(t, AuditEventAccessDenied, byIdentity[0].Event)
+	assert.Equal(t, plugin, byIdentity[1].Client)
+
+	byIdentity, err = vd.ListAuditEventsByIdentity("plugin:vim", 0)
+	require.NoError(t, err)
+	assert.Empty(t, byIdentity)
+}
+
+func TestVaultDatabase_ImportSecrets(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	vd := NewVaultDatabase(filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+
+	notes := "imported"
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	n, err := vd.ImportSecrets([]Secret{
+		{Key: "one", Value: "1", CreatedAt: created},
+		{Key: "two", Value: "2", Notes: &notes},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	secret, err := vd.GetSecret("one")
+	require.NoError(t, err)
+	assert.True(t, created.Equal(secret.CreatedAt))
+
+	// A duplicate anywhere in the batch rolls back every insert
+	_, err = vd.ImportSecrets([]Secret{
+		{Key: "three", Value: "3"},
+		{Key: "ONE", Value: "dup"},
+	})
+	assert.ErrorIs(t, err, ErrDuplicateKey)
+
+	_, err = vd.GetSecret("three")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// Invalid keys are rejected before anything is written
+	_, err = vd.ImportSecrets([]Secret{{Key: "bad key!", Value: "x"}})
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	count, err := vd.CountSecrets()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestVaultDatabase_ExportSecrets(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	vd := NewVaultDatabase(filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+
+	for _, key := range []string{"prod/db", "prod/api", "dev/db"} {
+		require.NoError(t, vd.CreateSecret(key, "value_"+key))
+	}
+
+	all, err := vd.ExportSecrets("")
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, "dev/db", all[0].Key)
+	assert.Equal(t, "value_dev/db", all[0].Value)
+
+	prod, err := vd.ExportSecrets("PROD/*")
+	require.NoError(t, err)
+	require.Len(t, prod, 2)
+
+	// Exporting must not count as an access
+	for _, secret := range prod {
+		assert.Equal(t, int64(0), secret.AccessCount)
+	}
+	recent, err := vd.RecentSecrets(10)
+	require.NoError(t, err)
+	assert.Empty(t, recent)
+}
+
+func TestVaultDatabase_HiddenSecrets(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	vd := NewVaultDatabase(filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+
+	tags := "corp, finance"
+	require.NoError(t, vd.CreateSecret("work/vpn", "v"))
+	require.NoError(t, vd.CreateSecret("personal/mail", "m"))
+	n, err := vd.ImportSecrets([]Secret{{Key: "bank", Value: "b", Tags: &tags}})
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	hidden, err := vd.HideSecrets([]string{"WORK/*"}, []string{"finance"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), hidden)
+
+	// Hidden secrets are invisible to lookups, listings and searches
+	_, err = vd.GetSecret("work/vpn")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.ErrorIs(t, vd.DeleteSecret("bank"), ErrKeyNotFound)
+
+	count, err := vd.CountSecrets()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	results, total, err := vd.SearchSecretsGlob("*", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, "personal/mail", results[0].Key)
+
+	secrets, err := vd.HiddenSecrets()
+	require.NoError(t, err)
+	require.Len(t, secrets, 2)
+	assert.Equal(t, "bank", secrets[0].Key)
+
+	restored, err := vd.UnhideSecrets()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), restored)
+
+	_, err = vd.GetSecret("work/vpn")
+	assert.NoError(t, err)
+}
+
+func TestVaultDatabase_ArchivedSecrets(t *testing.T) {
+	vd := NewVaultDatabase(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+
+	require.NoError(t, vd.CreateSecret("old/token", "t"))
+	require.NoError(t, vd.CreateSecret("new/token", "n"))
+
+	require.NoError(t, vd.ArchiveSecret("OLD/token"))
+	assert.ErrorIs(t, vd.ArchiveSecret("old/token"), ErrKeyNotFound)
+	assert.ErrorIs(t, vd.ArchiveSecret("missing"), ErrKeyNotFound)
+
+	// Archived secrets leave the working set but keep their value
+	_, err := vd.GetSecret("old/token")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	count, err := vd.CountSecrets()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	list, err := vd.ListSecrets()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "new/token", list[0].Key)
+
+	peeked, err := vd.PeekSecret("old/token")
+	require.NoError(t, err)
+	assert.Equal(t, "t", peeked.Value)
+	assert.NotNil(t, peeked.ArchivedAt)
+
+	archived, err := vd.ArchivedSecrets()
+	require.NoError(t, err)
+	require.Len(t, archived, 1)
+	assert.Equal(t, "old/token", archived[0].Key)
+	assert.NotNil(t, archived[0].ArchivedAt)
+
+	require.NoError(t, vd.UnarchiveSecret("old/token"))
+	assert.ErrorIs(t, vd.UnarchiveSecret("old/token"), ErrKeyNotFound)
+	secret, err := vd.GetSecret("old/token")
+	require.NoError(t, err)
+	assert.Nil(t, secret.ArchivedAt)
+}
+
+func TestVaultDatabase_SecretMetadata(t *testing.T) {
+	vd := NewVaultDatabase(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+
+	require.NoError(t, vd.CreateSecret("db/prod", "pw"))
+
+	notes, url := "prod RDS", "https://console.example.com/rds"
+	expires := time.Now().Add(90 * 24 * time.Hour).Truncate(time.Second)
+	require.NoError(t, vd.SetSecretMetadata("db/prod", SecretMetadata{Notes: &notes, URL: &url, ExpiresAt: &expires}))
+
+	secret, err := vd.GetSecret("db/prod")
+	require.NoError(t, err)
+	require.NotNil(t, secret.Notes)
+	require.NotNil(t, secret.URL)
+	require.NotNil(t, secret.ExpiresAt)
+	assert.Equal(t, notes, *secret.Notes)
+	assert.Equal(t, url, *secret.URL)
+	assert.True(t, expires.Equal(*secret.ExpiresAt))
+	assert.False(t, secret.Expired(time.Now()))
+	assert.True(t, secret.Expired(expires))
+
+	// Nil fields are untouched; empty values clear
+	empty := ""
+	require.NoError(t, vd.SetSecretMetadata("db/prod", SecretMetadata{URL: &empty, ExpiresAt: &time.Time{}}))
+	secret, err = vd.PeekSecret("db/prod")
+	require.NoError(t, err)
+	require.NotNil(t, secret.Notes)
+	assert.Nil(t, secret.URL)
+	assert.Nil(t, secret.ExpiresAt)
+
+	assert.ErrorIs(t, vd.SetSecretMetadata("missing", SecretMetadata{Notes: &notes}), ErrKeyNotFound)
+	assert.NoError(t, vd.IntegrityError())
+}
+
+func TestVaultDatabase_Tags(t *testing.T) {
+	vd := NewVaultDatabase(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+
+	require.NoError(t, vd.CreateSecret("api/github", "g"))
+	require.NoError(t, vd.CreateSecret("api/stripe", "s"))
+	require.NoError(t, vd.CreateSecret("db/main", "d"))
+
+	require.NoError(t, vd.AddTag("api/github", "api"))
+	require.NoError(t, vd.AddTag("api/github", "Personal"))
+	require.NoError(t, vd.AddTag("api/github", "API"))
+	require.NoError(t, vd.AddTag("api/stripe", "api"))
+	assert.ErrorIs(t, vd.AddTag("missing", "api"), ErrKeyNotFound)
+	assert.ErrorIs(t, vd.AddTag("db/main", "a,b"), ErrInvalidTag)
+	assert.ErrorIs(t, vd.AddTag("db/main", " "), ErrInvalidTag)
+
+	secret, err := vd.PeekSecret("api/github")
+	require.NoError(t, err)
+	require.NotNil(t, secret.Tags)
+	assert.Equal(t, "api, Personal", *secret.Tags)
+
+	tagged, err := vd.ListByTag("API")
+	require.NoError(t, err)
+	require.Len(t, tagged, 2)
+	assert.Equal(t, "api/github", tagged[0].Key)
+
+	require.NoError(t, vd.RemoveTag("api/github", "personal"))
+	require.NoError(t, vd.RemoveTag("api/github", "api"))
+	require.NoError(t, vd.RemoveTag("api/github", "api"))
+	secret, err = vd.PeekSecret("api/github")
+	require.NoError(t, err)
+	assert.Nil(t, secret.Tags)
+
+	tagged, err = vd.ListByTag("api")
+	require.NoError(t, err)
+	require.Len(t, tagged, 1)
+	assert.Equal(t, "api/stripe", tagged[0].Key)
+
+	// Tag changes keep the integrity checksum current
+	assert.NoError(t, vd.IntegrityError())
+}
+
+func TestVaultDatabase_Settings(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	vd := NewVaultDatabase(filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+
+	_, ok, err := vd.GetSetting("travel.enabled")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, vd.SetSetting("travel.enabled", "true"))
+	require.NoError(t, vd.SetSetting("travel.enabled", "false"))
+
+	value, ok, err := vd.GetSetting("travel.enabled")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "false", value)
+
+	require.NoError(t, vd.DeleteSetting("travel.enabled"))
+	_, ok, err = vd.GetSetting("travel.enabled")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestVaultDatabase_SecretStrength(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	vd := NewVaultDatabase(filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+
+	require.NoError(t, vd.CreateSecret("api", "value"))
+	require.NoError(t, vd.SetSecretStrength("api", 142.5, SourceGenerated))
+
+	secret, err := vd.GetSecret("api")
+	require.NoError(t, err)
+	require.NotNil(t, secret.EntropyBits)
+	assert.Equal(t, 142.5, *secret.EntropyBits)
+	require.NotNil(t, secret.ValueSource)
+	assert.Equal(t, SourceGenerated, *secret.ValueSource)
+
+	// Changing the value invalidates the old estimate
+	require.NoError(t, vd.UpdateSecret("api", "other"))
+	secret, err = vd.GetSecret("api")
+	require.NoError(t, err)
+	assert.Nil(t, secret.EntropyBits)
+	assert.Nil(t, secret.ValueSource)
+
+	assert.ErrorIs(t, vd.SetSecretStrength("missing", 1, SourceManual), ErrKeyNotFound)
+
+	// Imports are marked as such unless the source is already known
+	_, err = vd.ImportSecrets([]Secret{{Key: "imported", Value: "x"}})
+	require.NoError(t, err)
+	secret, err = vd.GetSecret("imported")
+	require.NoError(t, err)
+	require.NotNil(t, secret.ValueSource)
+	assert.Equal(t, SourceImported, *secret.ValueSource)
+}
+
+func TestVaultDatabase_ConnectWithKey(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	vd := NewVaultDatabase(dbPath)
+	require.NoError(t, vd.Connect("test_password"))
+	require.NoError(t, vd.CreateSecret("k", "v"))
+	require.NoError(t, vd.Close())
+
+	key, err := vd.DeriveKey("test_password")
+	require.NoError(t, err)
+	assert.Len(t, key, 32)
+
+	// The derived key opens the vault without the password
+	vd = NewVaultDatabase(dbPath)
+	require.NoError(t, vd.ConnectWithKey(key))
+	secret, err := vd.GetSecret("k")
+	require.NoError(t, err)
+	assert.Equal(t, "v", secret.Value)
+	require.NoError(t, vd.Close())
+
+	// A key derived from the wrong password is rejected
+	wrong, err := vd.DeriveKey("wrong_password")
+	require.NoError(t, err)
+	assert.ErrorIs(t, NewVaultDatabase(dbPath).ConnectWithKey(wrong), ErrAuthenticationFailed)
+
+	_, err = NewVaultDatabase(filepath.Join(tmpDir, "missing.db")).DeriveKey("x")
+	assert.Error(t, err)
+}
+
+func TestVaultDatabase_SpecialCharacterPasswords(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	passwords := []string{
+		`a&b=c`,
+		`100%25 sure`,
+		`plus+space here`,
+		`it's "quoted"`,
+		`semi;colon -- PRAGMA`,
+		`x'00'`,
+		`ünïcødé ✓`,
+	}
+	for i, password := range passwords {
+		dbPath := filepath.Join(tmpDir, fmt.Sprintf("vault%d.db", i))
+
+		vd := NewVaultDatabase(dbPath)
+		require.NoError(t, vd.Connect(password), password)
+		require.NoError(t, vd.CreateSecret("k", "v"))
+		require.NoError(t, vd.Close())
+
+		require.NoError(t, vd.Connect(password), password)
+		secret, err := vd.GetSecret("k")
+		require.NoError(t, err)
+		assert.Equal(t, "v", secret.Value)
+		require.NoError(t, vd.Close())
+
+		// A similar password does not open it
+		assert.ErrorIs(t, NewVaultDatabase(dbPath).Connect(password+"!"), ErrAuthenticationFailed)
+
+		// Rekeying to the next password works just as well
+		next := passwords[(i+1)%len(passwords)]
+		require.NoError(t, vd.Rekey(password, next), password)
+		require.NoError(t, vd.Close())
+		require.NoError(t, vd.Connect(next), next)
+		require.NoError(t, vd.Close())
+	}
+}
+
+func TestVaultDatabase_PasswordKeyCompatible(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	// A vault created from a raw key opens with the password through
+	// SQLCipher's own key derivation, as other implementations open it
+	dbPath := filepath.Join(tmpDir, "test.db")
+	vd := NewVaultDatabase(dbPath)
+	require.NoError(t, vd.Connect("test_password"))
+	require.NoError(t, vd.CreateSecret("k", "v"))
+	require.NoError(t, vd.Close())
+
+	db, err := sql.Open(driverName, fmt.Sprintf("%s?_pragma_key=test_password&_pragma_cipher_page_size=4096&_pragma_cipher_hmac_algorithm=HMAC_SHA512&_pragma_cipher_kdf_algorithm=PBKDF2_HMAC_SHA512&_pragma_cipher_kdf_iter=%d", dbPath, kdfIterations))
+	require.NoError(t, err)
+	defer db.Close()
+	var value string
+	require.NoError(t, db.QueryRow(`SELECT value FROM secrets WHERE key = 'k'`).Scan(&value))
+	assert.Equal(t, "v", value)
+}
+
+func TestVaultDatabase_PeekSecret(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	vd := NewVaultDatabase(filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+
+	require.NoError(t, vd.CreateSecret("api", "value"))
+
+	// Peeking does not count as an access
+	secret, err := vd.PeekSecret("api")
+	require.NoError(t, err)
+	assert.Equal(t, "value", secret.Value)
+	assert.Equal(t, int64(0), secret.AccessCount)
+	require.NotNil(t, secret.UpdatedAt)
+	created := *secret.UpdatedAt
+
+	secret, err = vd.PeekSecret("api")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), secret.AccessCount)
+
+	// Updating the value moves the modification time forward
+	time.Sleep(1100 * time.Millisecond)
+	require.NoError(t, vd.UpdateSecret("api", "other"))
+	secret, err = vd.PeekSecret("api")
+	require.NoError(t, err)
+	require.NotNil(t, secret.UpdatedAt)
+	assert.True(t, secret.UpdatedAt.After(created))
+
+	_, err = vd.PeekSecret("missing")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestVaultDatabase_UpdateSecretIfRevision(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	vd := NewVaultDatabase(filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+
+	require.NoError(t, vd.CreateSecret("api", "v1"))
+	secret, err := vd.PeekSecret("api")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), secret.Revision)
+
+	// Every update bumps the revision
+	require.NoError(t, vd.UpdateSecret("api", "v2"))
+	require.NoError(t, vd.UpdateSecretIfRevision("api", "v3", 2))
+	secret, err = vd.PeekSecret("api")
+	require.NoError(t, err)
+	assert.Equal(t, "v3", secret.Value)
+	assert.Equal(t, int64(3), secret.Revision)
+
+	// A stale revision is rejected and leaves the value alone
+	assert.ErrorIs(t, vd.UpdateSecretIfRevision("api", "stale", 2), ErrRevisionMismatch)
+	secret, err = vd.PeekSecret("api")
+	require.NoError(t, err)
+	assert.Equal(t, "v3", secret.Value)
+
+	assert.ErrorIs(t, vd.UpdateSecretIfRevision("missing", "x", 1), ErrKeyNotFound)
+
+	results, err := vd.ListSecrets()
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, int64(3), results[0].Revision)
+}
+
+func TestVaultDatabase_Usage(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	vd := NewVaultDatabase(filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+
+	require.NoError(t, vd.CreateSecret("used", "v"))
+	require.NoError(t, vd.CreateSecret("unused", "v"))
+
+	for i := 0; i < 3; i++ {
+		_, err := vd.GetSecret("USED")
+		require.NoError(t, err)
+	}
+
+	// Peeking is not a retrieval
+	_, err = vd.PeekSecret("unused")
+	require.NoError(t, err)
+
+	since := time.Now().AddDate(0, 0, -7)
+	days, err := vd.UsageByDay("used", since)
+	require.NoError(t, err)
+	require.Len(t, days, 1)
+	assert.Equal(t, int64(3), days[0].Count)
+	assert.Equal(t, time.Now().Format("2006-01-02"), days[0].Day.Format("2006-01-02"))
+
+	totals, err := vd.UsageTotals(since)
+	require.NoError(t, err)
+	require.Len(t, totals, 2)
+	assert.Equal(t, "unused", totals[0].Key)
+	assert.Equal(t, int64(0), totals[0].Count)
+	assert.Nil(t, totals[0].LastUsed)
+	assert.Equal(t, "used", totals[1].Key)
+	assert.Equal(t, int64(3), totals[1].Count)
+	assert.NotNil(t, totals[1].LastUsed)
+
+	// Usage is dropped together with the secret
+	require.NoError(t, vd.DeleteSecret("used"))
+	days, err = vd.UsageByDay("used", since)
+	require.NoError(t, err)
+	assert.Empty(t, days)
+}
+
+func TestVaultDatabase_CipherSettings(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	vd := NewVaultDatabase(filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+
+	settings, err := vd.CipherSettings()
+	require.NoError(t, err)
+	assert.Equal(t, kdfIterations, settings.KDFIterations)
+	assert.True(t, settings.UseHMAC)
+	assert.Empty(t, settings.Violations(DefaultCipherPolicy))
+
+	// Weaker parameters are reported individually
+	weak := &CipherSettings{
+		Version:       "3.4.2 community",
+		KDFIterations: 64000,
+		KDFAlgorithm:  "PBKDF2_HMAC_SHA1",
+		HMACAlgorithm: "HMAC_SHA1",
+		UseHMAC:       true,
+	}
+	assert.Len(t, weak.Violations(DefaultCipherPolicy), 4)
+
+	weak.UseHMAC = false
+	problems := weak.Violations(DefaultCipherPolicy)
+	assert.Contains(t, problems, "page HMAC is disabled")
+}
+
+func TestVaultDatabase_Integrity(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := filepath.Join(tmpDir, "test.db")
+	vd := NewVaultDatabase(dbPath)
+	require.NoError(t, vd.Connect("test_password"))
+	require.NoError(t, vd.CreateSecret("api", "v1"))
+	require.NoError(t, vd.UpdateSecret("api", "v2"))
+	require.NoError(t, vd.CreateSecret("db", "v"))
+	require.NoError(t, vd.DeleteSecret("db"))
+	_, err = vd.ImportSecrets([]Secret{{Key: "imported", Value: "x"}})
+	require.NoError(t, err)
+	require.NoError(t, vd.Close())
+
+	// Writes through lockr keep the checksum current
+	require.NoError(t, vd.Connect("test_password"))
+	assert.NoError(t, vd.IntegrityError())
+
+	// A change made directly in the database is detected on the next open
+	_, err = vd.connection.Exec(`UPDATE secrets SET value = 'tampered' WHERE key = 'api'`)
+	require.NoError(t, err)
+	require.NoError(t, vd.Close())
+
+	require.NoError(t, vd.Connect("test_password"))
+	assert.ErrorIs(t, vd.IntegrityError(), ErrIntegrityMismatch)
+
+	// Resealing accepts the current contents
+	require.NoError(t, vd.ResealIntegrity())
+	assert.NoError(t, vd.IntegrityError())
+	require.NoError(t, vd.Close())
+
+	// Hiding, archiving, repointing or changing the expiry of a secret
+	// directly is detected too
+	for _, tamper := range []string{
+		`UPDATE secrets SET hidden = 1 WHERE key = 'api'`,
+		`UPDATE secrets SET archived_at = CURRENT_TIMESTAMP WHERE key = 'api'`,
+		`UPDATE secrets SET url = 'https://evil.example' WHERE key = 'api'`,
+		`UPDATE secrets SET expires_at = '2099-01-01 00:00:00' WHERE key = 'api'`,
+	} {
+		require.NoError(t, vd.Connect("test_password"))
+		_, err = vd.connection.Exec(tamper)
+		require.NoError(t, err)
+		require.NoError(t, vd.Close())
+
+		require.NoError(t, vd.Connect("test_password"))
+		assert.ErrorIs(t, vd.IntegrityError(), ErrIntegrityMismatch, tamper)
+		require.NoError(t, vd.ResealIntegrity())
+		require.NoError(t, vd.Close())
+	}
+
+	// Writes through lockr to those fields keep the checksum current
+	require.NoError(t, vd.Connect("test_password"))
+	_, err = vd.UnhideSecrets()
+	require.NoError(t, err)
+	require.NoError(t, vd.UnarchiveSecret("api"))
+	url := "https://example.com"
+	require.NoError(t, vd.SetSecretMetadata("api", SecretMetadata{URL: &url}))
+	require.NoError(t, vd.ArchiveSecret("imported"))
+	_, err = vd.HideSecrets([]string{"api"}, nil)
+	require.NoError(t, err)
+	require.NoError(t, vd.Close())
+	require.NoError(t, vd.Connect("test_password"))
+	assert.NoError(t, vd.IntegrityError())
+	require.NoError(t, vd.Close())
+
+	// The checksum survives a password change
+	require.NoError(t, vd.Rekey("test_password", "new_password"))
+	require.NoError(t, vd.Close())
+	require.NoError(t, vd.Connect("new_password"))
+	assert.NoError(t, vd.IntegrityError())
+	require.NoError(t, vd.Close())
+}
+
+func TestVaultDatabase_IntegrityFormatUpgrade(t *testing.T) {
+	vd := NewVaultDatabase(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, vd.Connect("test_password"))
+	require.NoError(t, vd.CreateSecret("api", "v"))
+
+	// A checksum sealed by an earlier version covers only the content
+	old, err := vd.contentMAC(vd.connection, integrityFormatContent)
+	require.NoError(t, err)
+	_, err = vd.connection.Exec(`UPDATE vault_integrity SET mac = ?, format = ?`, old, integrityFormatContent)
+	require.NoError(t, err)
+	require.NoError(t, vd.Close())
+
+	// It is verified in its own format and resealed in the current one
+	require.NoError(t, vd.Connect("test_password"))
+	assert.NoError(t, vd.IntegrityError())
+	var format int
+	require.NoError(t, vd.connection.QueryRow(`SELECT format FROM vault_integrity`).Scan(&format))
+	assert.Equal(t, integrityFormat, format)
+
+	// Tampering before the upgrade is still caught
+	_, err = vd.connection.Exec(`UPDATE vault_integrity SET mac = ?, format = ?`, old, integrityFormatContent)
+	require.NoError(t, err)
+	_, err = vd.connection.Exec(`UPDATE secrets SET value = 'tampered'`)
+	require.NoError(t, err)
+	require.NoError(t, vd.Close())
+
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+	assert.ErrorIs(t, vd.IntegrityError(), ErrIntegrityMismatch)
+	require.NoError(t, vd.connection.QueryRow(`SELECT format FROM vault_integrity`).Scan(&format))
+	assert.Equal(t, integrityFormatContent, format)
+}
+
+func TestVaultDatabase_ReadOnly(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := filepath.Join(tmpDir, "test.db")
+	vd := NewVaultDatabase(dbPath)
+	require.NoError(t, vd.Connect("test_password"))
+	require.NoError(t, vd.CreateSecret("db/prod", "hunter2"))
+	require.NoError(t, vd.Close())
+
+	// Simulate a read-only mount
+	require.NoError(t, os.Chmod(dbPath, 0o400))
+
+	ro := NewReadOnlyVaultDatabase(dbPath)
+	require.NoError(t, ro.Connect("test_password"))
+	defer ro.Close()
+
+	secret, err := ro.PeekSecret("db/prod")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", secret.Value)
+	assert.NoError(t, ro.IntegrityError())
+
+	assert.Error(t, ro.CreateSecret("new", "value"))
+
+	// A read-only store never creates a vault
+	_, err = os.Stat(filepath.Join(tmpDir, "missing.db"))
+	require.True(t, os.IsNotExist(err))
+	assert.Error(t, NewReadOnlyVaultDatabase(filepath.Join(tmpDir, "missing.db")).Connect("test_password"))
+	_, err = os.Stat(filepath.Join(tmpDir, "missing.db"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestVaultDatabase_Subscribe(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := filepath.Join(tmpDir, "test.db")
+	vd := NewVaultDatabase(dbPath)
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+
+	require.NoError(t, vd.CreateSecret("existing", "value"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := vd.Subscribe(ctx, time.Hour)
+	require.NoError(t, err)
+
+	next := func() ChangeEvent {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for change event")
+			return ChangeEvent{}
+		}
+	}
+
+	// Local writes are reported without waiting for the interval
+	require.NoError(t, vd.CreateSecret("new", "value"))
+	event := next()
+	assert.Equal(t, "new", event.Key)
+	assert.Equal(t, ChangeCreated, event.Op)
+
+	require.NoError(t, vd.UpdateSecret("existing", "changed"))
+	event = next()
+	assert.Equal(t, "existing", event.Key)
+	assert.Equal(t, ChangeUpdated, event.Op)
+	assert.Equal(t, int64(2), event.Revision)
+
+	require.NoError(t, vd.DeleteSecret("new"))
+	event = next()
+	assert.Equal(t, "new", event.Key)
+	assert.Equal(t, ChangeDeleted, event.Op)
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestDiffRevisions(t *testing.T) {
+	now := time.Now()
+	events := diffRevisions(
+		map[string]int64{"same": 1, "changed": 1, "gone": 3},
+		map[string]int64{"same": 1, "changed": 2, "added": 1},
+		now,
+	)
+	assert.ElementsMatch(t, []ChangeEvent{
+		{Key: "changed", Op: ChangeUpdated, Revision: 2, Timestamp: now},
+		{Key: "added", Op: ChangeCreated, Revision: 1, Timestamp: now},
+		{Key: "gone", Op: ChangeDeleted, Timestamp: now},
+	}, events)
+}
+
+func TestVaultDatabase_Snapshots(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	vd := NewVaultDatabase(filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+
+	tags := "db"
+	_, err = vd.ImportSecrets([]Secret{
+		{Key: "staging/db/password", Value: "s1", Tags: &tags},
+		{Key: "Staging/api/token", Value: "t1"},
+		{Key: "stagingx/other", Value: "x"},
+		{Key: "production/db/password", Value: "p1"},
+	})
+	require.NoError(t, err)
+
+	snap, err := vd.CreateSnapshot("staging-1", "staging/")
+	require.NoError(t, err)
+	assert.Equal(t, 2, snap.Count)
+
+	_, err = vd.CreateSnapshot("STAGING-1", "staging/")
+	assert.ErrorIs(t, err, ErrSnapshotExists)
+
+	// Later changes don't affect the snapshot
+	require.NoError(t, vd.UpdateSecret("staging/db/password", "s2"))
+
+	got, err := vd.GetSnapshot("staging-1")
+	require.NoError(t, err)
+	assert.Equal(t, "staging/", got.Namespace)
+	require.Len(t, got.Entries, 2)
+	assert.Equal(t, "api/token", got.Entries[0].Key)
+	assert.Equal(t, "db/password", got.Entries[1].Key)
+	assert.Equal(t, "s1", got.Entries[1].Value)
+	require.NotNil(t, got.Entries[1].Tags)
+	assert.Equal(t, "db", *got.Entries[1].Tags)
+
+	_, err = vd.CreateSnapshot("empty", "nothing/")
+	require.NoError(t, err)
+	list, err := vd.ListSnapshots()
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "staging-1", list[0].Name)
+	assert.Equal(t, 2, list[0].Count)
+	assert.Equal(t, 0, list[1].Count)
+
+	require.NoError(t, vd.DeleteSnapshot("staging-1"))
+	_, err = vd.GetSnapshot("staging-1")
+	assert.ErrorIs(t, err, ErrSnapshotNotFound)
+	assert.ErrorIs(t, vd.DeleteSnapshot("staging-1"), ErrSnapshotNotFound)
+}
+
+func TestVaultDatabase_ApplyChanges(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	vd := NewVaultDatabase(filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+
+	require.NoError(t, vd.CreateSecret("app/a", "1"))
+	require.NoError(t, vd.CreateSecret("app/b", "2"))
+
+	err = vd.ApplyChanges([]SecretChange{
+		{Op: ChangeUpdate, Key: "app/a", Value: "one", Revision: 1},
+		{Op: ChangeDelete, Key: "app/b", Revision: 1},
+		{Op: ChangeCreate, Key: "app/c", Value: "3"},
+	})
+	require.NoError(t, err)
+
+	a, err := vd.PeekSecret("app/a")
+	require.NoError(t, err)
+	assert.Equal(t, "one", a.Value)
+	assert.Equal(t, int64(2), a.Revision)
+	_, err = vd.PeekSecret("app/b")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	_, err = vd.PeekSecret("app/c")
+	assert.NoError(t, err)
+
+	// A stale revision rolls back the whole batch
+	err = vd.ApplyChanges([]SecretChange{
+		{Op: ChangeCreate, Key: "app/d", Value: "4"},
+		{Op: ChangeUpdate, Key: "app/a", Value: "stale", Revision: 1},
+	})
+	assert.ErrorIs(t, err, ErrRevisionMismatch)
+	_, err = vd.PeekSecret("app/d")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	err = vd.ApplyChanges([]SecretChange{{Op: ChangeCreate, Key: "app/c", Value: "dup"}})
+	assert.ErrorIs(t, err, ErrDuplicateKey)
+	err = vd.ApplyChanges([]SecretChange{{Op: ChangeDelete, Key: "missing"}})
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.NoError(t, vd.IntegrityError())
+}
+
+func TestVaultDatabase_History(t *testing.T) {
+	vd := NewVaultDatabase(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+
+	require.NoError(t, vd.CreateSecret("db/prod", "one"))
+	require.NoError(t, vd.UpdateSecret("db/prod", "two"))
+	require.NoError(t, vd.UpdateSecret("DB/prod", "three"))
+	assert.ErrorIs(t, vd.UpdateSecretIfRevision("db/prod", "lost", 1), ErrRevisionMismatch)
+
+	versions, err := vd.SecretHistory("db/prod")
+	require.NoError(t, err)
+	require.Len(t, versions, 2, "a failed conditional update saves nothing")
+	assert.Equal(t, 2, versions[0].Version)
+	assert.Equal(t, "two", versions[0].Value)
+	assert.Equal(t, int64(2), versions[0].Revision)
+	assert.Equal(t, "one", versions[1].Value)
+	assert.Equal(t, VersionReplaced, versions[1].Reason)
+
+	// Restoring saves the current value as a new version
+	require.NoError(t, vd.RestoreSecretVersion("db/prod", 1))
+	secret, err := vd.PeekSecret("db/prod")
+	require.NoError(t, err)
+	assert.Equal(t, "one", secret.Value)
+	versions, err = vd.SecretHistory("db/prod")
+	require.NoError(t, err)
+	assert.Equal(t, "three", versions[0].Value)
+	assert.ErrorIs(t, vd.RestoreSecretVersion("db/prod", 9), ErrVersionNotFound)
+
+	// Deleted secrets keep their history and can be restored
+	require.NoError(t, vd.DeleteSecret("db/prod"))
+	versions, err = vd.SecretHistory("db/prod")
+	require.NoError(t, err)
+	assert.Equal(t, VersionDeleted, versions[0].Reason)
+	require.NoError(t, vd.RestoreSecretVersion("db/prod", versions[0].Version))
+	secret, err = vd.PeekSecret("db/prod")
+	require.NoError(t, err)
+	assert.Equal(t, "one", secret.Value)
+
+	// Batched changes are versioned too
+	require.NoError(t, vd.ApplyChanges([]SecretChange{{Op: ChangeUpdate, Key: "db/prod", Value: "batched"}}))
+	versions, err = vd.SecretHistory("db/prod")
+	require.NoError(t, err)
+	assert.Equal(t, "one", versions[0].Value)
+
+	removed, err := vd.ClearSecretHistory("db/prod")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(versions)), removed)
+	versions, err = vd.SecretHistory("db/prod")
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+}
+
+func TestVaultDatabase_HiddenHistory(t *testing.T) {
+	vd := NewVaultDatabase(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+
+	require.NoError(t, vd.CreateSecret("work/vpn", "old"))
+	require.NoError(t, vd.UpdateSecret("work/vpn", "new"))
+	require.NoError(t, vd.CreateSecret("personal/mail", "old"))
+	require.NoError(t, vd.UpdateSecret("personal/mail", "new"))
+
+	_, err := vd.HideSecrets([]string{"work/*"}, nil)
+	require.NoError(t, err)
+
+	// Previous values of hidden secrets are hidden with them
+	versions, err := vd.SecretHistory("work/vpn")
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+
+	// Deleting hidden secrets takes their history along
+	deleted, err := vd.DeleteHiddenSecrets()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	versions, err = vd.SecretHistory("work/vpn")
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+	assert.ErrorIs(t, vd.RestoreSecretVersion("work/vpn", 1), ErrVersionNotFound)
+
+	versions, err = vd.SecretHistory("personal/mail")
+	require.NoError(t, err)
+	assert.Len(t, versions, 1)
+}
+
+func TestVaultDatabase_HistoryPruned(t *testing.T) {
+	vd := NewVaultDatabase(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+
+	require.NoError(t, vd.CreateSecret("token", "v0"))
+	for i := 1; i <= MaxSecretVersions+5; i++ {
+		require.NoError(t, vd.UpdateSecret("token", fmt.Sprintf("v%d", i)))
+	}
+
+	versions, err := vd.SecretHistory("token")
+	require.NoError(t, err)
+	require.Len(t, versions, MaxSecretVersions)
+	assert.Equal(t, MaxSecretVersions+5, versions[0].Version)
+	assert.Equal(t, 6, versions[len(versions)-1].Version)
+}
+
+func TestVaultDatabase_Durability(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := filepath.Join(tmpDir, "test.db")
+	vd := NewVaultDatabase(dbPath)
+	require.NoError(t, vd.Connect("test_password"))
+	settings, err := vd.Durability()
+	require.NoError(t, err)
+	assert.Equal(t, DurabilityNormal, settings.Mode)
+	assert.Equal(t, "NORMAL", settings.Synchronous)
+
+	// Leave the vault in write-ahead logging, as other SQLite tools may
+	_, err = vd.connection.Exec(`PRAGMA journal_mode = WAL`)
+	require.NoError(t, err)
+	require.NoError(t, vd.CreateSecret("api", "v1"))
+	require.NoError(t, vd.Close())
+
+	// Full durability puts the vault back in rollback-journal mode
+	vd.SetDurability(DurabilityFull)
+	require.NoError(t, vd.Connect("test_password"))
+	settings, err = vd.Durability()
+	require.NoError(t, err)
+	assert.Equal(t, &DurabilitySettings{Mode: DurabilityFull, Synchronous: "FULL", JournalMode: "delete"}, settings)
+	require.NoError(t, vd.UpdateSecret("api", "v2"))
+	require.NoError(t, vd.Close())
+
+	// Nothing the vault needs is left beside it
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		_, err := os.Stat(dbPath + suffix)
+		assert.True(t, os.IsNotExist(err), suffix)
+	}
+
+	_, err = ParseDurability("paranoid")
+	assert.Error(t, err)
+	d, err := ParseDurability("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultDurability, d)
+}
+
+// copyVault copies the vault and its journal, as a crash or a file-sync tool
+// would leave them, and returns the copy's path
+func copyVault(t *testing.T, dbPath string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, suffix := range []string{"", "-journal"} {
+		data, err := os.ReadFile(dbPath + suffix)
+		if os.IsNotExist(err) {
+			continue
+		}
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "test.db"+suffix), data, 0600))
+	}
+	return filepath.Join(dir, "test.db")
+}
+
+func TestVaultDatabase_CrashRecovery(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := filepath.Join(tmpDir, "test.db")
+	vd := NewVaultDatabase(dbPath)
+	vd.SetDurability(DurabilityFull)
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+	require.NoError(t, vd.CreateSecret("api", "v1"))
+
+	// A commit is complete in the vault file without closing the vault
+	committed := NewVaultDatabase(copyVault(t, dbPath))
+	require.NoError(t, committed.Connect("test_password"))
+	secret, err := committed.PeekSecret("api")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", secret.Value)
+	assert.NoError(t, committed.IntegrityError())
+	require.NoError(t, committed.Close())
+
+	// Stop halfway through a transaction large enough to spill pages into
+	// the vault file
+	ctx := context.Background()
+	conn, err := vd.connection.Conn(ctx)
+	require.NoError(t, err)
+	_, err = conn.ExecContext(ctx, `PRAGMA cache_size = 1`)
+	require.NoError(t, err)
+	_, err = conn.ExecContext(ctx, `BEGIN`)
+	require.NoError(t, err)
+	_, err = conn.ExecContext(ctx, `UPDATE secrets SET value = 'torn' WHERE key = 'api'`)
+	require.NoError(t, err)
+	for i := 0; i < 200; i++ {
+		_, err = conn.ExecContext(ctx, `INSERT INTO vault_settings (name, value) VALUES (?, ?)`,
+			fmt.Sprintf("filler.%d", i), string(make([]byte, 4096)))
+		require.NoError(t, err)
+	}
+	_, err = os.Stat(dbPath + "-journal")
+	require.NoError(t, err, "the transaction should have a hot journal")
+	crashed := copyVault(t, dbPath)
+	_, err = conn.ExecContext(ctx, `ROLLBACK`)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	// Opening the copy rolls the unfinished transaction back
+	recovered := NewVaultDatabase(crashed)
+	require.NoError(t, recovered.Connect("test_password"))
+	defer recovered.Close()
+	var check string
+	require.NoError(t, recovered.connection.QueryRow(`PRAGMA integrity_check`).Scan(&check))
+	assert.Equal(t, "ok", check)
+	secret, err = recovered.PeekSecret("api")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", secret.Value)
+	_, ok, err := recovered.GetSetting("filler.0")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, recovered.IntegrityError())
+}
+
+func TestVaultDatabase_Context(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	vd := NewVaultDatabase(filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, vd.Connect("test_password"))
+	defer vd.Close()
+	require.NoError(t, vd.CreateSecret("api", "v1"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Writes under a cancelled context leave the vault as it was
+	assert.ErrorIs(t, vd.CreateSecretContext(ctx, "new", "v"), context.Canceled)
+	assert.ErrorIs(t, vd.UpdateSecretContext(ctx, "api", "v2"), context.Canceled)
+	assert.ErrorIs(t, vd.DeleteSecretContext(ctx, "api"), context.Canceled)
+	_, err = vd.ImportSecretsContext(ctx, []Secret{{Key: "imported", Value: "x"}})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	count, err := vd.CountSecrets()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	secret, err := vd.PeekSecret("api")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", secret.Value)
+	assert.NoError(t, vd.IntegrityError())
+
+	_, err = vd.GetSecretContext(ctx, "api")
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = vd.ListSecretsContext(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	expired, cancelExpired := context.WithTimeout(context.Background(), -time.Second)
+	defer cancelExpired()
+	_, _, err = vd.SearchSecretsRegexContext(expired, "a.*", 0, 0)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// A live context behaves like the plain methods
+	require.NoError(t, vd.UpdateSecretContext(context.Background(), "api", "v2"))
+	secret, err = vd.GetSecretContext(context.Background(), "api")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", secret.Value)
+}
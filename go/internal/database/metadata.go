@@ -0,0 +1,64 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// SecretMetadata describes changes to the descriptive fields of a secret.
+// Nil fields are left unchanged; an empty Notes or URL, or a zero ExpiresAt,
+// clears the field.
+type SecretMetadata struct {
+	Notes     *string
+	URL       *string
+	ExpiresAt *time.Time
+}
+
+// SetSecretMetadata updates the notes, URL and expiry of an existing secret
+func (vd *VaultDatabase) SetSecretMetadata(key string, meta SecretMetadata) error {
+	if err := vd.ensureConnected(); err != nil {
+		return err
+	}
+
+	var sets []string
+	var args []interface{}
+	if meta.Notes != nil {
+		sets = append(sets, "notes = ?")
+		args = append(args, nullIfEmpty(strings.TrimSpace(*meta.Notes)))
+	}
+	if meta.URL != nil {
+		sets = append(sets, "url = ?")
+		args = append(args, nullIfEmpty(strings.TrimSpace(*meta.URL)))
+	}
+	if meta.ExpiresAt != nil {
+		sets = append(sets, "expires_at = ?")
+		if meta.ExpiresAt.IsZero() {
+			args = append(args, nil)
+		} else {
+			args = append(args, meta.ExpiresAt.UTC())
+		}
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+
+	query := `UPDATE secrets SET ` + strings.Join(sets, ", ") + ` WHERE key = ? COLLATE NOCASE AND hidden = 0`
+	args = append(args, key)
+
+	return vd.write("set_secret_metadata", func(tx *sql.Tx) error {
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return NewDatabaseError("set_secret_metadata", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return NewDatabaseError("set_secret_metadata_check", err)
+		}
+		if rowsAffected == 0 {
+			return ErrKeyNotFound
+		}
+		return nil
+	})
+}
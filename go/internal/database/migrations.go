@@ -0,0 +1,225 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// migration describes an incremental schema change applied on top of the
+// shared base schema (version 1)
+type migration struct {
+	version     int
+	description string
+	statements  []string
+}
+
+// migrations lists every schema change in order. New entries must use the
+// next version number and SchemaVersion must be bumped to match.
+var migrations = []migration{
+	{
+		version:     2,
+		description: "client context on auth attempts and audit events",
+		statements: []string{
+			`ALTER TABLE auth_attempts ADD COLUMN terminal TEXT`,
+			`ALTER TABLE auth_attempts ADD COLUMN hostname TEXT`,
+			`ALTER TABLE auth_attempts ADD COLUMN client TEXT`,
+			`CREATE TABLE IF NOT EXISTS audit_events (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				event TEXT NOT NULL,
+				key TEXT,
+				username TEXT,
+				terminal TEXT,
+				hostname TEXT,
+				client TEXT,
+				session_id TEXT,
+				details TEXT
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_audit_timestamp ON audit_events(timestamp)`,
+			`CREATE INDEX IF NOT EXISTS idx_audit_key ON audit_events(key COLLATE NOCASE)`,
+		},
+	},
+	{
+		version:     3,
+		description: "hidden secrets and vault settings",
+		statements: []string{
+			`ALTER TABLE secrets ADD COLUMN hidden BOOLEAN NOT NULL DEFAULT 0`,
+			`CREATE TABLE IF NOT EXISTS vault_settings (
+				name TEXT PRIMARY KEY,
+				value TEXT NOT NULL,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)`,
+		},
+	},
+	{
+		version:     4,
+		description: "entropy estimate and origin of secret values",
+		statements: []string{
+			`ALTER TABLE secrets ADD COLUMN entropy_bits REAL`,
+			`ALTER TABLE secrets ADD COLUMN value_source TEXT`,
+		},
+	},
+	{
+		version:     5,
+		description: "last modification time of secret values",
+		statements: []string{
+			`ALTER TABLE secrets ADD COLUMN updated_at TIMESTAMP`,
+			`UPDATE secrets SET updated_at = created_at`,
+		},
+	},
+	{
+		version:     6,
+		description: "revision counter for optimistic concurrency",
+		statements: []string{
+			`ALTER TABLE secrets ADD COLUMN revision INTEGER NOT NULL DEFAULT 1`,
+		},
+	},
+	{
+		version:     7,
+		description: "daily usage rollups",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS secret_usage (
+				key TEXT NOT NULL COLLATE NOCASE,
+				day TEXT NOT NULL,
+				count INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (key, day)
+			)`,
+		},
+	},
+	{
+		version:     8,
+		description: "integrity checksum over vault contents",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS vault_integrity (
+				id INTEGER PRIMARY KEY CHECK (id = 1),
+				mac TEXT NOT NULL,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)`,
+		},
+	},
+	{
+		version:     9,
+		description: "named snapshots of namespaces",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS snapshots (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+				namespace TEXT NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE TABLE IF NOT EXISTS snapshot_entries (
+				snapshot_id INTEGER NOT NULL,
+				key TEXT NOT NULL COLLATE NOCASE,
+				value TEXT NOT NULL,
+				tags TEXT,
+				notes TEXT,
+				PRIMARY KEY (snapshot_id, key)
+			)`,
+		},
+	},
+	{
+		version:     10,
+		description: "archived secrets",
+		statements: []string{
+			`ALTER TABLE secrets ADD COLUMN archived_at TIMESTAMP`,
+		},
+	},
+	{
+		version:     11,
+		description: "secret URL and expiry",
+		statements: []string{
+			`ALTER TABLE secrets ADD COLUMN url TEXT`,
+			`ALTER TABLE secrets ADD COLUMN expires_at TIMESTAMP`,
+		},
+	},
+	{
+		version:     12,
+		description: "previous values of secrets",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS secret_versions (
+				key TEXT NOT NULL COLLATE NOCASE,
+				version INTEGER NOT NULL,
+				revision INTEGER NOT NULL,
+				value TEXT NOT NULL,
+				set_at TIMESTAMP NOT NULL,
+				replaced_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				reason TEXT NOT NULL,
+				PRIMARY KEY (key, version)
+			)`,
+		},
+	},
+	{
+		version:     13,
+		description: "client identities in the audit log",
+		statements: []string{
+			`ALTER TABLE audit_events ADD COLUMN identity TEXT`,
+			`CREATE INDEX IF NOT EXISTS idx_audit_identity ON audit_events(identity COLLATE NOCASE)`,
+		},
+	},
+	{
+		// Existing checksums keep format 1 and are verified, then resealed
+		// in the current format, on the next unlock
+		version:     14,
+		description: "checksum format covering hidden, archived, URL and expiry",
+		statements: []string{
+			`ALTER TABLE vault_integrity ADD COLUMN format INTEGER NOT NULL DEFAULT 1`,
+		},
+	},
+}
+
+// migrate applies any migrations newer than the vault's recorded schema version
+func (vd *VaultDatabase) migrate() error {
+	current, err := vd.schemaVersion()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		tx, err := vd.connection.Begin()
+		if err != nil {
+			return NewDatabaseError("migrate_begin", err)
+		}
+
+		for _, stmt := range m.statements {
+			if _, err := tx.Exec(stmt); err != nil {
+				tx.Rollback()
+				return NewDatabaseError(fmt.Sprintf("migrate_v%d", m.version), fmt.Errorf("%s: %w", m.description, err))
+			}
+		}
+
+		if _, err := tx.Exec(`INSERT INTO schema_version (version) VALUES (?)`, m.version); err != nil {
+			tx.Rollback()
+			return NewDatabaseError("migrate_record_version", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return NewDatabaseError("migrate_commit", err)
+		}
+	}
+
+	return nil
+}
+
+// schemaVersion returns the highest schema version applied to the vault
+func (vd *VaultDatabase) schemaVersion() (int, error) {
+	var version sql.NullInt64
+	if err := vd.connection.QueryRow(`SELECT MAX(version) FROM schema_version`).Scan(&version); err != nil {
+		return 0, NewDatabaseError("schema_version", err)
+	}
+	if !version.Valid {
+		return 1, nil
+	}
+	return int(version.Int64), nil
+}
+
+// SchemaVersion returns the schema version of the connected vault
+func (vd *VaultDatabase) SchemaVersion() (int, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return 0, err
+	}
+	return vd.schemaVersion()
+}
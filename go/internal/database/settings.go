@@ -0,0 +1,55 @@
+package database
+
+import (
+	"database/sql"
+)
+
+// GetSetting returns the value of a vault setting and whether it is set
+func (vd *VaultDatabase) GetSetting(name string) (string, bool, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return "", false, err
+	}
+
+	var value string
+	err := vd.connection.QueryRow(`SELECT value FROM vault_settings WHERE name = ?`, name).Scan(&value)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", false, nil
+		}
+		return "", false, NewDatabaseError("get_setting", err)
+	}
+
+	return value, true, nil
+}
+
+// SetSetting stores a vault setting, replacing any previous value
+func (vd *VaultDatabase) SetSetting(name, value string) error {
+	if err := vd.ensureConnected(); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO vault_settings (name, value, updated_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(name) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+	`
+
+	if _, err := vd.connection.Exec(query, name, value); err != nil {
+		return NewDatabaseError("set_setting", err)
+	}
+
+	return nil
+}
+
+// DeleteSetting removes a vault setting. Removing an unset setting is not an error.
+func (vd *VaultDatabase) DeleteSetting(name string) error {
+	if err := vd.ensureConnected(); err != nil {
+		return err
+	}
+
+	if _, err := vd.connection.Exec(`DELETE FROM vault_settings WHERE name = ?`, name); err != nil {
+		return NewDatabaseError("delete_setting", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CreateSnapshot copies the key, value, tags and notes of every visible
+// secret whose key starts with namespace into a new snapshot called name.
+// Keys are stored relative to the namespace, so a snapshot can be restored
+// into a different one.
+func (vd *VaultDatabase) CreateSnapshot(name, namespace string) (*Snapshot, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("snapshot name cannot be empty")
+	}
+
+	tx, err := vd.connection.Begin()
+	if err != nil {
+		return nil, NewDatabaseError("snapshot_begin", err)
+	}
+	defer tx.Rollback() // no-op after a successful commit
+
+	now := time.Now().UTC()
+	result, err := tx.Exec(`INSERT INTO snapshots (name, namespace, created_at) VALUES (?, ?, ?)`, name, namespace, now)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, fmt.Errorf("%w: %q", ErrSnapshotExists, name)
+		}
+		return nil, NewDatabaseError("create_snapshot", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, NewDatabaseError("create_snapshot", err)
+	}
+
+	copied, err := tx.Exec(`
+		INSERT INTO snapshot_entries (snapshot_id, key, value, tags, notes)
+		SELECT ?, substr(key, length(?2) + 1), value, tags, notes
+		FROM secrets
+		WHERE hidden = 0 AND lower(substr(key, 1, length(?2))) = lower(?2) AND length(key) > length(?2)
+	`, id, namespace)
+	if err != nil {
+		return nil, NewDatabaseError("copy_snapshot_entries", err)
+	}
+	count, err := copied.RowsAffected()
+	if err != nil {
+		return nil, NewDatabaseError("copy_snapshot_entries", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, NewDatabaseError("snapshot_commit", err)
+	}
+	return &Snapshot{Name: name, Namespace: namespace, CreatedAt: now, Count: int(count)}, nil
+}
+
+// GetSnapshot returns a snapshot with its entries ordered by key
+func (vd *VaultDatabase) GetSnapshot(name string) (*Snapshot, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	var id int64
+	var s Snapshot
+	err := vd.connection.QueryRow(`SELECT id, name, namespace, created_at FROM snapshots WHERE name = ? COLLATE NOCASE`, name).
+		Scan(&id, &s.Name, &s.Namespace, &s.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %q", ErrSnapshotNotFound, name)
+	}
+	if err != nil {
+		return nil, NewDatabaseError("get_snapshot", err)
+	}
+
+	rows, err := vd.connection.Query(`SELECT key, value, tags, notes FROM snapshot_entries WHERE snapshot_id = ? ORDER BY key ASC`, id)
+	if err != nil {
+		return nil, NewDatabaseError("get_snapshot_entries", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e SnapshotEntry
+		if err := rows.Scan(&e.Key, &e.Value, &e.Tags, &e.Notes); err != nil {
+			return nil, NewDatabaseError("scan_snapshot_entry", err)
+		}
+		s.Entries = append(s.Entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, NewDatabaseError("get_snapshot_entries_iteration", err)
+	}
+	s.Count = len(s.Entries)
+	return &s, nil
+}
+
+// ListSnapshots returns every snapshot without entries, oldest first
+func (vd *VaultDatabase) ListSnapshots() ([]Snapshot, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	rows, err := vd.connection.Query(`
+		SELECT s.name, s.namespace, s.created_at, COUNT(e.key)
+		FROM snapshots s LEFT JOIN snapshot_entries e ON e.snapshot_id = s.id
+		GROUP BY s.id
+		ORDER BY s.created_at ASC, s.id ASC
+	`)
+	if err != nil {
+		return nil, NewDatabaseError("list_snapshots", err)
+	}
+	defer rows.Close()
+
+	var snapshots []Snapshot
+	for rows.Next() {
+		var s Snapshot
+		if err := rows.Scan(&s.Name, &s.Namespace, &s.CreatedAt, &s.Count); err != nil {
+			return nil, NewDatabaseError("scan_snapshot", err)
+		}
+		snapshots = append(snapshots, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, NewDatabaseError("list_snapshots_iteration", err)
+	}
+	return snapshots, nil
+}
+
+// DeleteSnapshot removes a snapshot and its entries
+func (vd *VaultDatabase) DeleteSnapshot(name string) error {
+	if err := vd.ensureConnected(); err != nil {
+		return err
+	}
+
+	tx, err := vd.connection.Begin()
+	if err != nil {
+		return NewDatabaseError("delete_snapshot_begin", err)
+	}
+	defer tx.Rollback() // no-op after a successful commit
+
+	var id int64
+	err = tx.QueryRow(`SELECT id FROM snapshots WHERE name = ? COLLATE NOCASE`, name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: %q", ErrSnapshotNotFound, name)
+	}
+	if err != nil {
+		return NewDatabaseError("delete_snapshot", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM snapshot_entries WHERE snapshot_id = ?`, id); err != nil {
+		return NewDatabaseError("delete_snapshot_entries", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM snapshots WHERE id = ?`, id); err != nil {
+		return NewDatabaseError("delete_snapshot", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return NewDatabaseError("delete_snapshot_commit", err)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultEngine is the storage engine used when none is configured
+const DefaultEngine = "sqlcipher"
+
+// SecretStore provides CRUD operations on individual secrets
+type SecretStore interface {
+	CreateSecret(key, value string) error
+	GetSecret(key string) (*Secret, error)
+	PeekSecret(key string) (*Secret, error)
+	UpdateSecret(key, value string) error
+	UpdateSecretIfRevision(key, value string, revision int64) error
+	DeleteSecret(key string) error
+	ImportSecrets(secrets []Secret) (int, error)
+	ExportSecrets(pattern string) ([]Secret, error)
+	ApplyChanges(changes []SecretChange) error
+	SetSecretStrength(key string, entropyBits float64, source string) error
+	SetSecretMetadata(key string, meta SecretMetadata) error
+}
+
+// SearchStore provides listing and search over secret metadata
+type SearchStore interface {
+	ListSecrets() ([]SearchResult, error)
+	ListSecretsPage(limit, offset int) ([]SearchResult, error)
+	CountSecrets() (int, error)
+	SearchSecrets(pattern string) ([]SearchResult, error)
+	SearchSecretsGlob(pattern string, limit, offset int) ([]SearchResult, int, error)
+	SearchSecretsRegex(pattern string, limit, offset int) ([]SearchResult, int, error)
+	RecentSecrets(limit int) ([]SearchResult, error)
+}
+
+// UsageStore reports daily retrieval rollups recorded by GetSecret
+type UsageStore interface {
+	UsageByDay(key string, since time.Time) ([]UsageDay, error)
+	UsageTotals(since time.Time) ([]UsageTotal, error)
+}
+
+// SessionStore persists authentication sessions
+type SessionStore interface {
+	CreateSession(session *Session) error
+	UpdateSession(session *Session) error
+	GetSession(sessionID string) (*Session, error)
+	DeleteSession(sessionID string) error
+	ListSessions() ([]Session, error)
+	DeleteExpiredSessions(now time.Time) (int64, error)
+}
+
+// AuditStore records security-relevant events
+type AuditStore interface {
+	LogAuthAttempt(info ClientInfo, success bool, sessionID *string) error
+	ListAuthAttempts(limit int) ([]AuthAttempt, error)
+	LogAuditEvent(event *AuditEvent) error
+	ListAuditEvents(limit int) ([]AuditEvent, error)
+	ListAuditEventsByIdentity(identity string, limit int) ([]AuditEvent, error)
+}
+
+// HiddenStore hides secrets from every lookup (used by travel mode)
+type HiddenStore interface {
+	HideSecrets(patterns, tags []string) (int64, error)
+	UnhideSecrets() (int64, error)
+	HiddenSecrets() ([]Secret, error)
+	DeleteHiddenSecrets() (int64, error)
+}
+
+// SettingsStore persists named settings inside the encrypted vault
+type SettingsStore interface {
+	GetSetting(name string) (string, bool, error)
+	SetSetting(name, value string) error
+	DeleteSetting(name string) error
+}
+
+// SnapshotStore keeps named, frozen copies of the secrets in a namespace
+type SnapshotStore interface {
+	CreateSnapshot(name, namespace string) (*Snapshot, error)
+	GetSnapshot(name string) (*Snapshot, error)
+	ListSnapshots() ([]Snapshot, error)
+	DeleteSnapshot(name string) error
+}
+
+// ArchiveStore moves secrets out of the working set without deleting them
+type ArchiveStore interface {
+	ArchiveSecret(key string) error
+	UnarchiveSecret(key string) error
+	ArchivedSecrets() ([]SearchResult, error)
+}
+
+// TagStore organizes secrets with tags
+type TagStore interface {
+	AddTag(key, tag string) error
+	RemoveTag(key, tag string) error
+	ListByTag(tag string) ([]SearchResult, error)
+}
+
+// HistoryStore keeps the previous values of secrets
+type HistoryStore interface {
+	SecretHistory(key string) ([]SecretVersion, error)
+	RestoreSecretVersion(key string, version int) error
+	ClearSecretHistory(key string) (int64, error)
+}
+
+// VaultStore is the complete storage contract used by the higher layers.
+// VaultDatabase is the SQLCipher implementation; alternative engines can be
+// registered with RegisterEngine.
+type VaultStore interface {
+	Connect(password string) error
+	Close() error
+	IsConnected() bool
+	Rekey(oldPassword, newPassword string) error
+
+	SecretStore
+	SearchStore
+	UsageStore
+	SessionStore
+	AuditStore
+	HiddenStore
+	SettingsStore
+	SnapshotStore
+	ArchiveStore
+	TagStore
+	HistoryStore
+}
+
+// Ensure VaultDatabase satisfies the storage contract
+var _ VaultStore = (*VaultDatabase)(nil)
+
+// KeyedStore is implemented by engines that can unlock with a cached raw key
+// instead of running the password key derivation on every connection
+type KeyedStore interface {
+	DeriveKey(password string) ([]byte, error)
+	ConnectWithKey(key []byte) error
+}
+
+// Ensure VaultDatabase supports key caching
+var _ KeyedStore = (*VaultDatabase)(nil)
+
+// CipherInspector is implemented by engines that can report the encryption
+// parameters of an open vault
+type CipherInspector interface {
+	CipherSettings() (*CipherSettings, error)
+}
+
+// Ensure VaultDatabase can report its cipher parameters
+var _ CipherInspector = (*VaultDatabase)(nil)
+
+// IntegrityChecker is implemented by engines that keep a tamper-evident
+// checksum over the vault's contents
+type IntegrityChecker interface {
+	IntegrityError() error
+	ResealIntegrity() error
+}
+
+// Ensure VaultDatabase maintains an integrity checksum
+var _ IntegrityChecker = (*VaultDatabase)(nil)
+
+// ContextStore is implemented by engines whose reads and writes can be
+// cancelled, so long operations stop and servers can enforce deadlines
+type ContextStore interface {
+	CreateSecretContext(ctx context.Context, key, value string) error
+	ImportSecretsContext(ctx context.Context, secrets []Secret) (int, error)
+	ExportSecretsContext(ctx context.Context, pattern string) ([]Secret, error)
+	GetSecretContext(ctx context.Context, key string) (*Secret, error)
+	PeekSecretContext(ctx context.Context, key string) (*Secret, error)
+	UpdateSecretContext(ctx context.Context, key, value string) error
+	DeleteSecretContext(ctx context.Context, key string) error
+	ListSecretsContext(ctx context.Context) ([]SearchResult, error)
+	CountSecretsContext(ctx context.Context) (int, error)
+	SearchSecretsGlobContext(ctx context.Context, pattern string, limit, offset int) ([]SearchResult, int, error)
+	SearchSecretsRegexContext(ctx context.Context, pattern string, limit, offset int) ([]SearchResult, int, error)
+}
+
+// Ensure VaultDatabase supports cancellation
+var _ ContextStore = (*VaultDatabase)(nil)
+
+// DurableStore is implemented by engines whose trade-off between write
+// speed and surviving crashes can be chosen
+type DurableStore interface {
+	SetDurability(d Durability)
+	Durability() (*DurabilitySettings, error)
+}
+
+// Ensure VaultDatabase lets the durability be chosen
+var _ DurableStore = (*VaultDatabase)(nil)
+
+// Watcher is implemented by engines that can stream changes to the vault's
+// secrets
+type Watcher interface {
+	Subscribe(ctx context.Context, interval time.Duration) (<-chan ChangeEvent, error)
+}
+
+// Ensure VaultDatabase can stream changes
+var _ Watcher = (*VaultDatabase)(nil)
+
+// EngineFactory creates a store for the vault at path
+type EngineFactory func(path string) VaultStore
+
+var (
+	enginesMu sync.RWMutex
+	engines   = map[string]EngineFactory{
+		DefaultEngine: func(path string) VaultStore { return NewVaultDatabase(path) },
+	}
+)
+
+// RegisterEngine makes a storage engine available under name
+func RegisterEngine(name string, factory EngineFactory) {
+	enginesMu.Lock()
+	defer enginesMu.Unlock()
+	engines[name] = factory
+}
+
+// Engines returns the names of all registered storage engines
+func Engines() []string {
+	enginesMu.RLock()
+	defer enginesMu.RUnlock()
+
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OpenStore creates a store for path using the named engine. An empty name
+// selects DefaultEngine.
+func OpenStore(engine, path string) (VaultStore, error) {
+	if engine == "" {
+		engine = DefaultEngine
+	}
+
+	enginesMu.RLock()
+	factory, ok := engines[engine]
+	enginesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown storage engine %q", engine)
+	}
+	return factory(path), nil
+}
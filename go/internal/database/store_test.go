@@ -0,0 +1,54 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenStore(t *testing.T) {
+	store, err := OpenStore("", "vault.lockr")
+	require.NoError(t, err)
+	assert.IsType(t, &VaultDatabase{}, store)
+	assert.False(t, store.IsConnected())
+
+	_, err = OpenStore("bolt", "vault.lockr")
+	assert.Error(t, err)
+}
+
+func TestRegisterEngine(t *testing.T) {
+	var openedPath string
+	RegisterEngine("test-engine", func(path string) VaultStore {
+		openedPath = path
+		return NewVaultDatabase(path)
+	})
+
+	assert.Contains(t, Engines(), "test-engine")
+	assert.Contains(t, Engines(), DefaultEngine)
+
+	_, err := OpenStore("test-engine", "other.lockr")
+	require.NoError(t, err)
+	assert.Equal(t, "other.lockr", openedPath)
+}
+
+func TestPatternCache(t *testing.T) {
+	c := newPatternCache(2)
+
+	for _, pattern := range []string{"^a", "^b", "^a", "^c"} {
+		_, err := c.compile(pattern)
+		require.NoError(t, err)
+	}
+	// ^b was used least recently and made room for ^c
+	assert.Equal(t, 2, c.len())
+	assert.Contains(t, c.patterns, "^a")
+	assert.NotContains(t, c.patterns, "^b")
+
+	_, err := c.compile("(")
+	assert.Error(t, err)
+	assert.Equal(t, 2, c.len())
+
+	matched, err := sqlRegexp("^ci/", "ci/db")
+	require.NoError(t, err)
+	assert.True(t, matched)
+}
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// AddTag adds tag to a secret's comma-separated tag list. Tags compare
+// case-insensitively; adding a tag the secret already has does nothing.
+func (vd *VaultDatabase) AddTag(key, tag string) error {
+	return vd.changeTags("add_tag", key, tag, func(tags []string, i int) []string {
+		if i >= 0 {
+			return tags
+		}
+		return append(tags, strings.TrimSpace(tag))
+	})
+}
+
+// RemoveTag removes tag from a secret's tag list. Removing a tag the secret
+// does not have does nothing.
+func (vd *VaultDatabase) RemoveTag(key, tag string) error {
+	return vd.changeTags("remove_tag", key, tag, func(tags []string, i int) []string {
+		if i < 0 {
+			return tags
+		}
+		return append(tags[:i], tags[i+1:]...)
+	})
+}
+
+// changeTags validates tag, then rewrites the tags of key with the list
+// returned by edit, which receives the current tags and the index of tag
+// among them (-1 if absent)
+func (vd *VaultDatabase) changeTags(op, key, tag string, edit func(tags []string, i int) []string) error {
+	if err := vd.ensureConnected(); err != nil {
+		return err
+	}
+	if err := validateTag(tag); err != nil {
+		return err
+	}
+
+	return vd.write(op, func(tx *sql.Tx) error {
+		var current *string
+		err := tx.QueryRow(`SELECT tags FROM secrets WHERE key = ? COLLATE NOCASE AND hidden = 0`, key).Scan(&current)
+		if err == sql.ErrNoRows {
+			return ErrKeyNotFound
+		}
+		if err != nil {
+			return NewDatabaseError(op, err)
+		}
+
+		tags := splitTags(current)
+		index := -1
+		for i, t := range tags {
+			if strings.EqualFold(t, strings.TrimSpace(tag)) {
+				index = i
+				break
+			}
+		}
+		tags = edit(tags, index)
+
+		var joined *string
+		if len(tags) > 0 {
+			s := strings.Join(tags, ", ")
+			joined = &s
+		}
+		if _, err := tx.Exec(`UPDATE secrets SET tags = ? WHERE key = ? COLLATE NOCASE AND hidden = 0`, joined, key); err != nil {
+			return NewDatabaseError(op, err)
+		}
+		return nil
+	})
+}
+
+// ListByTag returns the active secrets carrying tag (without values),
+// ordered by key
+func (vd *VaultDatabase) ListByTag(tag string) ([]SearchResult, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, err
+	}
+	if err := validateTag(tag); err != nil {
+		return nil, err
+	}
+
+	// tags is a comma-separated list; compare whole entries only
+	query := `
+		SELECT key, created_at, last_accessed, access_count, tags, revision
+		FROM secrets
+		WHERE hidden = 0 AND archived_at IS NULL
+			AND instr(',' || lower(replace(coalesce(tags, ''), ' ', '')) || ',', ?) > 0
+		ORDER BY key ASC
+	`
+
+	rows, err := vd.connection.Query(query, ","+strings.ToLower(strings.ReplaceAll(tag, " ", ""))+",")
+	if err != nil {
+		return nil, NewDatabaseError("list_by_tag", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var result SearchResult
+		err := rows.Scan(
+			&result.Key,
+			&result.CreatedAt,
+			&result.LastAccessed,
+			&result.AccessCount,
+			&result.Tags,
+			&result.Revision,
+		)
+		if err != nil {
+			return nil, NewDatabaseError("scan_list_by_tag", err)
+		}
+		results = append(results, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, NewDatabaseError("list_by_tag_iteration", err)
+	}
+
+	return results, nil
+}
+
+// validateTag rejects tags that cannot be stored in a comma-separated list
+func validateTag(tag string) error {
+	tag = strings.TrimSpace(tag)
+	if tag == "" || strings.Contains(tag, ",") {
+		return fmt.Errorf("%w: %q", ErrInvalidTag, tag)
+	}
+	return nil
+}
+
+// splitTags splits a stored comma-separated tag list
+func splitTags(tags *string) []string {
+	if tags == nil {
+		return nil
+	}
+	var out []string
+	for _, tag := range strings.Split(*tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
@@ -6,14 +6,53 @@ import (
 
 // Secret represents a stored secret entry
 type Secret struct {
-	ID           int64     `json:"id"`
-	Key          string    `json:"key"`
-	Value        string    `json:"value"`
-	CreatedAt    time.Time `json:"created_at"`
-	LastAccessed time.Time `json:"last_accessed"`
-	AccessCount  int64     `json:"access_count"`
-	Tags         *string   `json:"tags,omitempty"`
-	Notes        *string   `json:"notes,omitempty"`
+	ID           int64      `json:"id"`
+	Key          string     `json:"key"`
+	Value        string     `json:"value"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastAccessed time.Time  `json:"last_accessed"`
+	AccessCount  int64      `json:"access_count"`
+	Tags         *string    `json:"tags,omitempty"`
+	Notes        *string    `json:"notes,omitempty"`
+	EntropyBits  *float64   `json:"entropy_bits,omitempty"`
+	ValueSource  *string    `json:"value_source,omitempty"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+	Revision     int64      `json:"revision"`
+	ArchivedAt   *time.Time `json:"archived_at,omitempty"`
+	URL          *string    `json:"url,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+}
+
+// Expired reports whether the secret has an expiry date that has passed
+func (s *Secret) Expired(now time.Time) bool {
+	return s.ExpiresAt != nil && !now.Before(*s.ExpiresAt)
+}
+
+// Origins of a secret's value, recorded alongside its entropy estimate
+const (
+	SourceGenerated = "generated"
+	SourceManual    = "manual"
+	SourceImported  = "imported"
+)
+
+// Client identifiers recorded with auth attempts and audit events
+const (
+	ClientCLI    = "cli"
+	ClientAgent  = "agent"
+	ClientREST   = "rest"
+	ClientEditor = "editor"
+	ClientSDK    = "sdk"
+)
+
+// ClientInfo describes the local context an operation originated from
+type ClientInfo struct {
+	Username string `json:"username"`
+	Terminal string `json:"terminal,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	Client   string `json:"client,omitempty"`
+	// Identity names the program acting through the client, such as
+	// plugin:vscode, as matched by access rules. Only audit events keep it.
+	Identity string `json:"identity,omitempty"`
 }
 
 // AuthAttempt represents an authentication attempt log entry
@@ -22,8 +61,33 @@ type AuthAttempt struct {
 	Timestamp time.Time `json:"timestamp"`
 	Username  string    `json:"username"`
 	Success   bool      `json:"success"`
-	IPAddress *string   `json:"ip_address,omitempty"`
 	SessionID *string   `json:"session_id,omitempty"`
+	Terminal  *string   `json:"terminal,omitempty"`
+	Hostname  *string   `json:"hostname,omitempty"`
+	Client    *string   `json:"client,omitempty"`
+}
+
+// Audit event types
+const (
+	AuditEventGet            = "get"
+	AuditEventCipherCheck    = "cipher_check"
+	AuditEventIntegrityCheck = "integrity_check"
+	AuditEventIntegrityReset = "integrity_reset"
+	AuditEventAccessDenied   = "access_denied"
+	AuditEventGrant          = "grant"
+	AuditEventSet            = "set"
+	AuditEventDelete         = "delete"
+)
+
+// AuditEvent represents an audited operation on the vault
+type AuditEvent struct {
+	ID        int64      `json:"id"`
+	Timestamp time.Time  `json:"timestamp"`
+	Event     string     `json:"event"`
+	Key       *string    `json:"key,omitempty"`
+	Client    ClientInfo `json:"client"`
+	SessionID *string    `json:"session_id,omitempty"`
+	Details   *string    `json:"details,omitempty"`
 }
 
 // Session represents an active session
@@ -35,11 +99,44 @@ type Session struct {
 	LastActivity time.Time `json:"last_activity"`
 }
 
+// UsageDay is the number of times a secret was retrieved on one local day
+type UsageDay struct {
+	Day   time.Time `json:"day"`
+	Count int64     `json:"count"`
+}
+
+// UsageTotal summarises how often a secret was retrieved over a period
+type UsageTotal struct {
+	Key      string     `json:"key"`
+	Count    int64      `json:"count"`
+	LastUsed *time.Time `json:"last_used,omitempty"`
+}
+
+// Snapshot is a named copy of the secrets in a namespace at one moment
+type Snapshot struct {
+	Name      string          `json:"name"`
+	Namespace string          `json:"namespace"`
+	CreatedAt time.Time       `json:"created_at"`
+	Count     int             `json:"count"`
+	Entries   []SnapshotEntry `json:"entries,omitempty"`
+}
+
+// SnapshotEntry is one secret in a snapshot. Key is relative to the
+// snapshot's namespace.
+type SnapshotEntry struct {
+	Key   string  `json:"key"`
+	Value string  `json:"value"`
+	Tags  *string `json:"tags,omitempty"`
+	Notes *string `json:"notes,omitempty"`
+}
+
 // SearchResult represents a secret entry for search operations
 type SearchResult struct {
-	Key          string    `json:"key"`
-	CreatedAt    time.Time `json:"created_at"`
-	LastAccessed time.Time `json:"last_accessed"`
-	AccessCount  int64     `json:"access_count"`
-	Tags         *string   `json:"tags,omitempty"`
+	Key          string     `json:"key"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastAccessed time.Time  `json:"last_accessed"`
+	AccessCount  int64      `json:"access_count"`
+	Tags         *string    `json:"tags,omitempty"`
+	Revision     int64      `json:"revision"`
+	ArchivedAt   *time.Time `json:"archived_at,omitempty"`
 }
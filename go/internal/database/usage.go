@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// usageDayLayout is the format of the day column in secret_usage
+const usageDayLayout = "2006-01-02"
+
+// recordUsage adds one retrieval of key to today's rollup
+func (vd *VaultDatabase) recordUsage(ctx context.Context, key string, now time.Time) error {
+	query := `
+		INSERT INTO secret_usage (key, day, count)
+		VALUES (?, ?, 1)
+		ON CONFLICT(key, day) DO UPDATE SET count = count + 1
+	`
+
+	if _, err := vd.connection.ExecContext(ctx, query, key, now.Format(usageDayLayout)); err != nil {
+		return NewDatabaseError("record_usage", err)
+	}
+	return nil
+}
+
+// UsageByDay returns the daily retrieval counts of key since the given time,
+// oldest first. Days without retrievals are omitted.
+func (vd *VaultDatabase) UsageByDay(key string, since time.Time) ([]UsageDay, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT day, count
+		FROM secret_usage
+		WHERE key = ? COLLATE NOCASE AND day >= ?
+		ORDER BY day ASC
+	`
+
+	rows, err := vd.connection.Query(query, key, since.Format(usageDayLayout))
+	if err != nil {
+		return nil, NewDatabaseError("usage_by_day", err)
+	}
+	defer rows.Close()
+
+	var days []UsageDay
+	for rows.Next() {
+		var day string
+		var usage UsageDay
+		if err := rows.Scan(&day, &usage.Count); err != nil {
+			return nil, NewDatabaseError("scan_usage_day", err)
+		}
+		usage.Day, err = time.ParseInLocation(usageDayLayout, day, time.Local)
+		if err != nil {
+			return nil, NewDatabaseError("parse_usage_day", err)
+		}
+		days = append(days, usage)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, NewDatabaseError("usage_by_day_iteration", err)
+	}
+
+	return days, nil
+}
+
+// UsageTotals returns the number of retrievals of every visible secret since
+// the given time, least used first. Secrets that were never retrieved in
+// that period are included with a zero count.
+func (vd *VaultDatabase) UsageTotals(since time.Time) ([]UsageTotal, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT s.key, COALESCE(SUM(u.count), 0), MAX(u.day)
+		FROM secrets s
+		LEFT JOIN secret_usage u ON u.key = s.key COLLATE NOCASE AND u.day >= ?
+		WHERE s.hidden = 0
+		GROUP BY s.key
+		ORDER BY 2 ASC, s.key ASC
+	`
+
+	rows, err := vd.connection.Query(query, since.Format(usageDayLayout))
+	if err != nil {
+		return nil, NewDatabaseError("usage_totals", err)
+	}
+	defer rows.Close()
+
+	var totals []UsageTotal
+	for rows.Next() {
+		var total UsageTotal
+		var lastDay *string
+		if err := rows.Scan(&total.Key, &total.Count, &lastDay); err != nil {
+			return nil, NewDatabaseError("scan_usage_total", err)
+		}
+		if lastDay != nil {
+			day, err := time.ParseInLocation(usageDayLayout, *lastDay, time.Local)
+			if err != nil {
+				return nil, NewDatabaseError("parse_usage_day", err)
+			}
+			total.LastUsed = &day
+		}
+		totals = append(totals, total)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, NewDatabaseError("usage_totals_iteration", err)
+	}
+
+	return totals, nil
+}
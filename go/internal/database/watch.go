@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// Kinds of change reported by Subscribe
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+)
+
+// DefaultWatchInterval is how often Subscribe checks for changes made by
+// other processes
+const DefaultWatchInterval = 2 * time.Second
+
+// ChangeEvent describes a change to one secret. Values are never included.
+type ChangeEvent struct {
+	Key       string    `json:"key"`
+	Op        string    `json:"op"`
+	Revision  int64     `json:"revision,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// watchList wakes subscribers when this handle writes to the vault
+type watchList struct {
+	mu    sync.Mutex
+	wakes []chan struct{}
+}
+
+func (w *watchList) add() chan struct{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	wake := make(chan struct{}, 1)
+	w.wakes = append(w.wakes, wake)
+	return wake
+}
+
+func (w *watchList) remove(wake chan struct{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i, c := range w.wakes {
+		if c == wake {
+			w.wakes = append(w.wakes[:i], w.wakes[i+1:]...)
+			return
+		}
+	}
+}
+
+func (w *watchList) notify() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, wake := range w.wakes {
+		select {
+		case wake <- struct{}{}:
+		default: // A wake-up is already pending
+		}
+	}
+}
+
+// Subscribe streams changes to visible secrets until ctx is cancelled or
+// the vault is closed, then closes the channel. Writes through this handle
+// are reported immediately; changes by other processes are picked up every
+// interval (DefaultWatchInterval if zero). Cancel ctx before closing the
+// vault to stop cleanly.
+func (vd *VaultDatabase) Subscribe(ctx context.Context, interval time.Duration) (<-chan ChangeEvent, error) {
+	if err := vd.ensureConnected(); err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	db := vd.connection
+	last, err := secretRevisions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan ChangeEvent, 16)
+	wake := vd.watch.add()
+
+	go func() {
+		defer close(events)
+		defer vd.watch.remove(wake)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-wake:
+			}
+
+			current, err := secretRevisions(db)
+			if err != nil {
+				return // Vault closed
+			}
+			for _, event := range diffRevisions(last, current, time.Now()) {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+			last = current
+		}
+	}()
+
+	return events, nil
+}
+
+// secretRevisions maps every visible key to its revision
+func secretRevisions(db *sql.DB) (map[string]int64, error) {
+	rows, err := db.Query(`SELECT key, revision FROM secrets WHERE hidden = 0`)
+	if err != nil {
+		return nil, NewDatabaseError("watch_snapshot", err)
+	}
+	defer rows.Close()
+
+	revisions := make(map[string]int64)
+	for rows.Next() {
+		var key string
+		var revision int64
+		if err := rows.Scan(&key, &revision); err != nil {
+			return nil, NewDatabaseError("watch_scan", err)
+		}
+		revisions[key] = revision
+	}
+	if err := rows.Err(); err != nil {
+		return nil, NewDatabaseError("watch_iterate", err)
+	}
+	return revisions, nil
+}
+
+// diffRevisions lists the changes between two snapshots, deletions last
+func diffRevisions(before, after map[string]int64, now time.Time) []ChangeEvent {
+	var events []ChangeEvent
+	for key, revision := range after {
+		old, existed := before[key]
+		switch {
+		case !existed:
+			events = append(events, ChangeEvent{Key: key, Op: ChangeCreated, Revision: revision, Timestamp: now})
+		case old != revision:
+			events = append(events, ChangeEvent{Key: key, Op: ChangeUpdated, Revision: revision, Timestamp: now})
+		}
+	}
+	for key := range before {
+		if _, exists := after[key]; !exists {
+			events = append(events, ChangeEvent{Key: key, Op: ChangeDeleted, Timestamp: now})
+		}
+	}
+	return events
+}
@@ -0,0 +1,70 @@
+// Package diceware generates passphrases from the EFF large word list. The
+// list is embedded in the binary so passphrase generation works on
+// air-gapped machines.
+//
+// Word list: https://www.eff.org/deeplinks/2016/07/new-wordlists-random-passphrases
+// (Electronic Frontier Foundation, CC BY 3.0 US)
+package diceware
+
+import (
+	"bufio"
+	"crypto/rand"
+	_ "embed"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+const (
+	// WordCount is the number of words in the list (6^5, one per roll of five dice)
+	WordCount = 7776
+
+	// MinWords is the fewest words accepted by Generate
+	MinWords = 3
+
+	// MaxWords is the most words accepted by Generate
+	MaxWords = 32
+
+	// DefaultWords gives a passphrase of roughly 77 bits
+	DefaultWords = 6
+)
+
+//go:embed eff_large_wordlist.txt
+var effLargeWordlist string
+
+// Words returns the embedded word list in dice-roll order
+var Words = sync.OnceValue(func() []string {
+	words := make([]string, 0, WordCount)
+	scanner := bufio.NewScanner(strings.NewReader(effLargeWordlist))
+	for scanner.Scan() {
+		// Each line is "<five dice digits>\t<word>"
+		_, word, ok := strings.Cut(scanner.Text(), "\t")
+		if ok {
+			words = append(words, word)
+		}
+	}
+	return words
+})
+
+// Generate returns a passphrase of n words chosen uniformly at random and
+// joined with separator
+func Generate(n int, separator string) (string, error) {
+	if n < MinWords || n > MaxWords {
+		return "", fmt.Errorf("passphrase must have between %d and %d words", MinWords, MaxWords)
+	}
+
+	words := Words()
+	limit := big.NewInt(int64(len(words)))
+
+	chosen := make([]string, n)
+	for i := range chosen {
+		index, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate random index: %w", err)
+		}
+		chosen[i] = words[index.Int64()]
+	}
+
+	return strings.Join(chosen, separator), nil
+}
@@ -0,0 +1,48 @@
+package diceware
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWords(t *testing.T) {
+	words := Words()
+	require.Len(t, words, WordCount)
+	assert.Equal(t, "abacus", words[0])
+	assert.Equal(t, "zoom", words[len(words)-1])
+
+	seen := make(map[string]bool, len(words))
+	for _, word := range words {
+		assert.NotEmpty(t, word)
+		assert.False(t, seen[word], "duplicate word %q", word)
+		seen[word] = true
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	passphrase, err := Generate(DefaultWords, " ")
+	require.NoError(t, err)
+
+	known := make(map[string]bool, WordCount)
+	for _, word := range Words() {
+		known[word] = true
+	}
+
+	parts := strings.Split(passphrase, " ")
+	require.Len(t, parts, DefaultWords)
+	for _, part := range parts {
+		assert.True(t, known[part], "unexpected word %q", part)
+	}
+
+	other, err := Generate(DefaultWords, " ")
+	require.NoError(t, err)
+	assert.NotEqual(t, passphrase, other)
+
+	_, err = Generate(MinWords-1, " ")
+	assert.Error(t, err)
+	_, err = Generate(MaxWords+1, " ")
+	assert.Error(t, err)
+}
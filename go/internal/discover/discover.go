@@ -0,0 +1,191 @@
+// Package discover lists the secrets held in a cloud secrets manager (AWS
+// Secrets Manager, Google Secret Manager) by name, never value, and maps
+// them to vault keys, so a vault can mirror a cloud account's layout before
+// the values are migrated. Listing goes through the provider's own CLI (aws,
+// gcloud), which brings its credentials and profiles along.
+package discover
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Providers
+const (
+	AWS = "aws"
+	GCP = "gcp"
+)
+
+// Tag marks vault keys scaffolded from a discovery
+const Tag = "discovered"
+
+// Secret is one secret of a cloud account, without its value
+type Secret struct {
+	Provider string
+	// Name is the secret's name in the provider
+	Name string
+	// ID is the provider's full identifier (an ARN, or a resource name)
+	ID string
+	// Location is the AWS region or the GCP project
+	Location    string
+	Description string
+	Labels      map[string]string
+	CreatedAt   time.Time
+}
+
+// ConsoleURL links to the secret in the provider's web console
+func (s Secret) ConsoleURL() string {
+	switch s.Provider {
+	case AWS:
+		return fmt.Sprintf("https://%s.console.aws.amazon.com/secretsmanager/secret?name=%s&region=%s",
+			s.Location, url.QueryEscape(s.Name), s.Location)
+	case GCP:
+		return fmt.Sprintf("https://console.cloud.google.com/security/secret-manager/secret/%s/versions?project=%s",
+			url.PathEscape(s.Name), url.QueryEscape(s.Location))
+	}
+	return ""
+}
+
+// Key returns the vault key for the secret under prefix. Names already use
+// slashes for hierarchy in AWS; leading and doubled slashes are dropped.
+func (s Secret) Key(prefix string) string {
+	var parts []string
+	for _, part := range strings.Split(s.Name, "/") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return prefix + strings.Join(parts, "/")
+}
+
+// Notes describes where the secret lives, for the scaffolded vault key
+func (s Secret) Notes() string {
+	var b strings.Builder
+	switch s.Provider {
+	case AWS:
+		fmt.Fprintf(&b, "AWS Secrets Manager: %s", s.ID)
+	case GCP:
+		fmt.Fprintf(&b, "Google Secret Manager: %s", s.ID)
+	}
+	if s.Description != "" {
+		fmt.Fprintf(&b, "\n%s", s.Description)
+	}
+	if len(s.Labels) > 0 {
+		names := make([]string, 0, len(s.Labels))
+		for name := range s.Labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(&b, "\n%s=%s", name, s.Labels[name])
+		}
+	}
+	return b.String()
+}
+
+// Command returns the provider CLI invocation that lists the account's
+// secrets as JSON. profile selects an AWS profile or a gcloud
+// configuration; location an AWS region or a GCP project. Either may be
+// empty for the CLI's default.
+func Command(provider, profile, location string) ([]string, error) {
+	switch provider {
+	case AWS:
+		args := []string{"aws", "secretsmanager", "list-secrets", "--output", "json"}
+		if profile != "" {
+			args = append(args, "--profile", profile)
+		}
+		if location != "" {
+			args = append(args, "--region", location)
+		}
+		return args, nil
+	case GCP:
+		args := []string{"gcloud", "secrets", "list", "--format", "json"}
+		if profile != "" {
+			args = append(args, "--configuration", profile)
+		}
+		if location != "" {
+			args = append(args, "--project", location)
+		}
+		return args, nil
+	}
+	return nil, fmt.Errorf("unknown provider %q (use %s or %s)", provider, AWS, GCP)
+}
+
+// Parse reads the output of the provider's list command
+func Parse(provider string, data []byte) ([]Secret, error) {
+	switch provider {
+	case AWS:
+		return parseAWS(data)
+	case GCP:
+		return parseGCP(data)
+	}
+	return nil, fmt.Errorf("unknown provider %q (use %s or %s)", provider, AWS, GCP)
+}
+
+// awsList is the output of 'aws secretsmanager list-secrets'
+type awsList struct {
+	SecretList []struct {
+		ARN         string `json:"ARN"`
+		Name        string `json:"Name"`
+		Description string `json:"Description"`
+		CreatedDate string `json:"CreatedDate"`
+		Tags        []struct {
+			Key   string `json:"Key"`
+			Value string `json:"Value"`
+		} `json:"Tags"`
+	} `json:"SecretList"`
+}
+
+func parseAWS(data []byte) ([]Secret, error) {
+	var list awsList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("unexpected output of aws secretsmanager list-secrets: %w", err)
+	}
+	secrets := make([]Secret, 0, len(list.SecretList))
+	for _, item := range list.SecretList {
+		s := Secret{Provider: AWS, Name: item.Name, ID: item.ARN, Description: item.Description}
+		// arn:aws:secretsmanager:<region>:<account>:secret:<name>-<suffix>
+		if parts := strings.SplitN(item.ARN, ":", 6); len(parts) == 6 {
+			s.Location = parts[3]
+		}
+		if len(item.Tags) > 0 {
+			s.Labels = make(map[string]string, len(item.Tags))
+			for _, tag := range item.Tags {
+				s.Labels[tag.Key] = tag.Value
+			}
+		}
+		s.CreatedAt, _ = time.Parse(time.RFC3339Nano, item.CreatedDate)
+		secrets = append(secrets, s)
+	}
+	return secrets, nil
+}
+
+// gcpSecret is one entry of 'gcloud secrets list --format json'
+type gcpSecret struct {
+	Name       string            `json:"name"`
+	CreateTime string            `json:"createTime"`
+	Labels     map[string]string `json:"labels"`
+}
+
+func parseGCP(data []byte) ([]Secret, error) {
+	var list []gcpSecret
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("unexpected output of gcloud secrets list: %w", err)
+	}
+	secrets := make([]Secret, 0, len(list))
+	for _, item := range list {
+		// projects/<project>/secrets/<name>
+		parts := strings.Split(item.Name, "/")
+		if len(parts) != 4 || parts[0] != "projects" || parts[2] != "secrets" {
+			return nil, fmt.Errorf("unexpected secret name %q in gcloud output", item.Name)
+		}
+		s := Secret{Provider: GCP, Name: parts[3], ID: item.Name, Location: parts[1], Labels: item.Labels}
+		s.CreatedAt, _ = time.Parse(time.RFC3339Nano, item.CreateTime)
+		secrets = append(secrets, s)
+	}
+	return secrets, nil
+}
@@ -0,0 +1,79 @@
+package discover
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const awsOutput = `{
+  "SecretList": [
+    {
+      "ARN": "arn:aws:secretsmanager:eu-west-1:123456789012:secret:prod/db/password-AbCdEf",
+      "Name": "prod/db/password",
+      "Description": "RDS master password",
+      "CreatedDate": "2025-03-04T10:15:00.123000+00:00",
+      "Tags": [{"Key": "team", "Value": "data"}, {"Key": "env", "Value": "prod"}]
+    },
+    {
+      "ARN": "arn:aws:secretsmanager:eu-west-1:123456789012:secret:/stripe//key-XyZ123",
+      "Name": "/stripe//key"
+    }
+  ]
+}`
+
+const gcpOutput = `[
+  {"name": "projects/shop-prod/secrets/stripe-key", "createTime": "2025-01-02T03:04:05.678Z", "labels": {"owner": "payments"}},
+  {"name": "projects/shop-prod/secrets/db_password"}
+]`
+
+func TestParse_AWS(t *testing.T) {
+	secrets, err := Parse(AWS, []byte(awsOutput))
+	require.NoError(t, err)
+	require.Len(t, secrets, 2)
+
+	s := secrets[0]
+	assert.Equal(t, "prod/db/password", s.Name)
+	assert.Equal(t, "eu-west-1", s.Location)
+	assert.Equal(t, time.Date(2025, 3, 4, 10, 15, 0, 123000000, time.UTC), s.CreatedAt.UTC())
+	assert.Equal(t, "aws/prod/db/password", s.Key("aws/"))
+	assert.Equal(t, "https://eu-west-1.console.aws.amazon.com/secretsmanager/secret?name=prod%2Fdb%2Fpassword&region=eu-west-1", s.ConsoleURL())
+	assert.Equal(t, "AWS Secrets Manager: arn:aws:secretsmanager:eu-west-1:123456789012:secret:prod/db/password-AbCdEf\nRDS master password\nenv=prod\nteam=data", s.Notes())
+
+	assert.Equal(t, "stripe/key", secrets[1].Key(""))
+
+	_, err = Parse(AWS, []byte("An error occurred"))
+	assert.Error(t, err)
+}
+
+func TestParse_GCP(t *testing.T) {
+	secrets, err := Parse(GCP, []byte(gcpOutput))
+	require.NoError(t, err)
+	require.Len(t, secrets, 2)
+
+	s := secrets[0]
+	assert.Equal(t, "stripe-key", s.Name)
+	assert.Equal(t, "shop-prod", s.Location)
+	assert.Equal(t, "gcp/stripe-key", s.Key("gcp/"))
+	assert.Equal(t, "https://console.cloud.google.com/security/secret-manager/secret/stripe-key/versions?project=shop-prod", s.ConsoleURL())
+	assert.Equal(t, "Google Secret Manager: projects/shop-prod/secrets/stripe-key\nowner=payments", s.Notes())
+	assert.True(t, secrets[1].CreatedAt.IsZero())
+
+	_, err = Parse(GCP, []byte(`[{"name": "stripe-key"}]`))
+	assert.Error(t, err)
+}
+
+func TestCommand(t *testing.T) {
+	args, err := Command(AWS, "work", "us-east-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aws", "secretsmanager", "list-secrets", "--output", "json", "--profile", "work", "--region", "us-east-1"}, args)
+
+	args, err = Command(GCP, "", "shop-prod")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gcloud", "secrets", "list", "--format", "json", "--project", "shop-prod"}, args)
+
+	_, err = Command("azure", "", "")
+	assert.ErrorContains(t, err, "unknown provider")
+}
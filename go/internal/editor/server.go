@@ -0,0 +1,282 @@
+// Package editor serves the vault to editor plugins over JSON-RPC 2.0 on a
+// pair of streams, using the same Content-Length framing as the Language
+// Server Protocol so plugins can reuse their LSP client libraries.
+//
+// Searching returns metadata only. A value is sent only in answer to an
+// explicit lockr/reveal request, which the Backend may refuse.
+package editor
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lockr/go/internal/authz"
+	"github.com/lockr/go/internal/database"
+)
+
+// Methods understood by the server
+const (
+	MethodInitialize = "initialize"
+	MethodSearch     = "lockr/search"
+	MethodReveal     = "lockr/reveal"
+	MethodShutdown   = "shutdown"
+	MethodExit       = "exit"
+)
+
+// JSON-RPC error codes. The first four are defined by JSON-RPC; the rest are
+// in the range reserved for implementations.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeDenied         = -32001
+	CodeNotFound       = -32002
+	CodeNotApproved    = -32003
+)
+
+// DefaultSearchLimit caps search results when the request sets no limit
+const DefaultSearchLimit = 50
+
+// ErrNotApproved is returned by a Backend when the user declines a reveal
+var ErrNotApproved = errors.New("request was not approved")
+
+// Entry describes a secret without its value
+type Entry struct {
+	Key       string     `json:"key"`
+	Reference string     `json:"reference"`
+	Tags      []string   `json:"tags,omitempty"`
+	Revision  int64      `json:"revision"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Backend answers the requests that touch the vault
+type Backend interface {
+	// Search returns up to limit entries matching query, best first
+	Search(query string, limit int) ([]Entry, error)
+	// Reveal returns the value of key once the request is authorized
+	Reveal(key string) (string, error)
+}
+
+// ServerInfo identifies the server in the initialize response
+type ServerInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// SearchParams are the parameters of lockr/search
+type SearchParams struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+// RevealParams are the parameters of lockr/reveal
+type RevealParams struct {
+	Key string `json:"key"`
+}
+
+// RevealResult is the result of lockr/reveal
+type RevealResult struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Error is a JSON-RPC error object
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+type request struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *Error           `json:"error,omitempty"`
+}
+
+// Server dispatches requests to a Backend
+type Server struct {
+	backend  Backend
+	info     ServerInfo
+	shutdown bool
+}
+
+// NewServer creates a server answering from backend
+func NewServer(backend Backend, info ServerInfo) *Server {
+	return &Server{backend: backend, info: info}
+}
+
+// Serve reads requests from r and writes responses to w until the client
+// sends exit or closes r
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	in := bufio.NewReader(r)
+	for {
+		body, err := readMessage(in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			null := json.RawMessage("null")
+			if err := writeMessage(w, response{JSONRPC: "2.0", ID: &null, Error: &Error{Code: CodeParseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == MethodExit {
+			return nil
+		}
+
+		result, rpcErr := s.handle(req)
+		if req.ID == nil {
+			// Notifications get no response
+			continue
+		}
+		if rpcErr == nil && result == nil {
+			result = json.RawMessage("null")
+		}
+		if err := writeMessage(w, response{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr}); err != nil {
+			return err
+		}
+	}
+}
+
+// handle runs one request
+func (s *Server) handle(req request) (interface{}, *Error) {
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return nil, &Error{Code: CodeInvalidRequest, Message: "not a JSON-RPC 2.0 request"}
+	}
+	if s.shutdown && req.Method != MethodShutdown {
+		return nil, &Error{Code: CodeInvalidRequest, Message: "server is shutting down"}
+	}
+
+	switch req.Method {
+	case MethodInitialize:
+		return map[string]interface{}{
+			"serverInfo": s.info,
+			"capabilities": map[string]bool{
+				"search": true,
+				"reveal": true,
+			},
+		}, nil
+
+	case MethodSearch:
+		var params SearchParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		limit := params.Limit
+		if limit <= 0 {
+			limit = DefaultSearchLimit
+		}
+		entries, err := s.backend.Search(params.Query, limit)
+		if err != nil {
+			return nil, toError(err)
+		}
+		if entries == nil {
+			entries = []Entry{}
+		}
+		return entries, nil
+
+	case MethodReveal:
+		var params RevealParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(params.Key) == "" {
+			return nil, &Error{Code: CodeInvalidParams, Message: "key is required"}
+		}
+		value, err := s.backend.Reveal(params.Key)
+		if err != nil {
+			return nil, toError(err)
+		}
+		return RevealResult{Key: params.Key, Value: value}, nil
+
+	case MethodShutdown:
+		s.shutdown = true
+		return nil, nil
+	}
+
+	return nil, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+}
+
+// decodeParams unmarshals request parameters into v
+func decodeParams(raw json.RawMessage, v interface{}) *Error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// toError maps a backend error to a JSON-RPC error
+func toError(err error) *Error {
+	code := CodeInternalError
+	switch {
+	case errors.Is(err, authz.ErrDenied):
+		code = CodeDenied
+	case errors.Is(err, database.ErrKeyNotFound):
+		code = CodeNotFound
+	case errors.Is(err, ErrNotApproved):
+		code = CodeNotApproved
+	}
+	return &Error{Code: code, Message: err.Error()}
+}
+
+// readMessage reads one Content-Length framed message
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	headers, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF && len(headers) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("invalid message header: %w", err)
+	}
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, errors.New("missing or invalid Content-Length header")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("truncated message: %w", err)
+	}
+	return body, nil
+}
+
+// writeMessage writes v as one Content-Length framed message
+func writeMessage(w io.Writer, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
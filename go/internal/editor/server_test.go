@@ -0,0 +1,153 @@
+package editor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lockr/go/internal/authz"
+	"github.com/lockr/go/internal/database"
+)
+
+type fakeBackend struct {
+	entries  []Entry
+	values   map[string]string
+	denied   map[string]bool
+	declined map[string]bool
+	limit    int
+}
+
+func (b *fakeBackend) Search(query string, limit int) ([]Entry, error) {
+	b.limit = limit
+	var out []Entry
+	for _, e := range b.entries {
+		if strings.Contains(e.Key, query) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (b *fakeBackend) Reveal(key string) (string, error) {
+	if b.denied[key] {
+		return "", fmt.Errorf("%w: nope", authz.ErrDenied)
+	}
+	if b.declined[key] {
+		return "", ErrNotApproved
+	}
+	v, ok := b.values[key]
+	if !ok {
+		return "", database.ErrKeyNotFound
+	}
+	return v, nil
+}
+
+func frame(t *testing.T, msgs ...string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, m := range msgs {
+		fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n%s", len(m), m)
+	}
+	return &buf
+}
+
+type reply struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+func serve(t *testing.T, b Backend, msgs ...string) []reply {
+	t.Helper()
+	var out bytes.Buffer
+	s := NewServer(b, ServerInfo{Name: "lockr", Version: "test"})
+	require.NoError(t, s.Serve(frame(t, msgs...), &out))
+
+	var replies []reply
+	r := bufio.NewReader(&out)
+	for {
+		body, err := readMessage(r)
+		if err != nil {
+			break
+		}
+		var rep reply
+		require.NoError(t, json.Unmarshal(body, &rep))
+		replies = append(replies, rep)
+	}
+	return replies
+}
+
+func TestServer_Search(t *testing.T) {
+	b := &fakeBackend{entries: []Entry{{Key: "dev/db", Reference: "${ref:dev/db}"}, {Key: "prod/db"}}}
+	replies := serve(t, b,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"lockr/search","params":{"query":"dev"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"lockr/search","params":{"query":"nothing","limit":5}}`,
+	)
+	require.Len(t, replies, 3)
+	assert.Contains(t, string(replies[0].Result), `"version":"test"`)
+
+	var entries []Entry
+	require.NoError(t, json.Unmarshal(replies[1].Result, &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, "dev/db", entries[0].Key)
+	assert.Equal(t, "[]", string(replies[2].Result))
+	assert.Equal(t, 5, b.limit)
+}
+
+func TestServer_SearchDefaultLimit(t *testing.T) {
+	b := &fakeBackend{}
+	serve(t, b, `{"jsonrpc":"2.0","id":1,"method":"lockr/search","params":{"query":""}}`)
+	assert.Equal(t, DefaultSearchLimit, b.limit)
+}
+
+func TestServer_Reveal(t *testing.T) {
+	b := &fakeBackend{
+		values:   map[string]string{"dev/db": "s3cret", "prod/db": "x", "other": "y"},
+		denied:   map[string]bool{"prod/db": true},
+		declined: map[string]bool{"other": true},
+	}
+	replies := serve(t, b,
+		`{"jsonrpc":"2.0","id":1,"method":"lockr/reveal","params":{"key":"dev/db"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"lockr/reveal","params":{"key":"prod/db"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"lockr/reveal","params":{"key":"other"}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"lockr/reveal","params":{"key":"missing"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"lockr/reveal","params":{}}`,
+	)
+	require.Len(t, replies, 5)
+	assert.JSONEq(t, `{"key":"dev/db","value":"s3cret"}`, string(replies[0].Result))
+	assert.Equal(t, CodeDenied, replies[1].Error.Code)
+	assert.Equal(t, CodeNotApproved, replies[2].Error.Code)
+	assert.Equal(t, CodeNotFound, replies[3].Error.Code)
+	assert.Equal(t, CodeInvalidParams, replies[4].Error.Code)
+}
+
+func TestServer_Protocol(t *testing.T) {
+	replies := serve(t, &fakeBackend{},
+		`not json`,
+		`{"jsonrpc":"2.0","id":1,"method":"nope"}`,
+		`{"jsonrpc":"2.0","method":"lockr/search"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"lockr/search"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"initialize"}`,
+	)
+	// The notification gets no reply and nothing after exit is read
+	require.Len(t, replies, 4)
+	assert.Equal(t, CodeParseError, replies[0].Error.Code)
+	assert.Equal(t, CodeMethodNotFound, replies[1].Error.Code)
+	assert.Equal(t, "null", string(replies[2].Result))
+	assert.Nil(t, replies[2].Error)
+	assert.Equal(t, CodeInvalidRequest, replies[3].Error.Code)
+}
+
+func TestReadMessage_BadHeader(t *testing.T) {
+	_, err := readMessage(bufio.NewReader(strings.NewReader("Content-Type: x\r\n\r\n{}")))
+	assert.Error(t, err)
+}
@@ -0,0 +1,72 @@
+// Package entrypoint runs a container's main process with secrets injected
+// into its environment, forwarding signals the way an init process should
+package entrypoint
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Mapping binds an environment variable to a vault key
+type Mapping struct {
+	Name string
+	Key  string
+}
+
+// ParseMapping parses a NAME=key mapping
+func ParseMapping(spec string) (Mapping, error) {
+	name, key, ok := strings.Cut(spec, "=")
+	if !ok || key == "" {
+		return Mapping{}, fmt.Errorf("invalid mapping %q: expected NAME=key", spec)
+	}
+	if !envNamePattern.MatchString(name) {
+		return Mapping{}, fmt.Errorf("invalid mapping %q: %q is not a valid environment variable name", spec, name)
+	}
+	return Mapping{Name: name, Key: key}, nil
+}
+
+// MergeEnv returns base with the given variables set, replacing any
+// existing values and dropping the variables named in unset
+func MergeEnv(base []string, set map[string]string, unset ...string) []string {
+	drop := make(map[string]bool, len(set)+len(unset))
+	for name := range set {
+		drop[name] = true
+	}
+	for _, name := range unset {
+		drop[name] = true
+	}
+
+	env := make([]string, 0, len(base)+len(set))
+	for _, kv := range base {
+		name, _, _ := strings.Cut(kv, "=")
+		if !drop[name] {
+			env = append(env, kv)
+		}
+	}
+	for name, value := range set {
+		env = append(env, name+"="+value)
+	}
+	return env
+}
+
+// Run starts argv with env and the current stdio, forwards signals to it
+// until it exits and returns its exit code. When running as PID 1 it also
+// reaps orphaned processes re-parented to it.
+func Run(argv []string, env []string) (int, error) {
+	if len(argv) == 0 {
+		return 0, errors.New("no command given")
+	}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return run(cmd)
+}
@@ -0,0 +1,62 @@
+//go:build !windows
+
+package entrypoint
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMapping(t *testing.T) {
+	m, err := ParseMapping("APP_DB_PASSWORD=db/prod")
+	require.NoError(t, err)
+	assert.Equal(t, Mapping{Name: "APP_DB_PASSWORD", Key: "db/prod"}, m)
+
+	m, err = ParseMapping("TOKEN=a=b")
+	require.NoError(t, err)
+	assert.Equal(t, "a=b", m.Key)
+
+	for _, spec := range []string{"", "NAME", "NAME=", "=key", "1NAME=key", "BAD-NAME=key"} {
+		_, err := ParseMapping(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestMergeEnv(t *testing.T) {
+	env := MergeEnv(
+		[]string{"PATH=/bin", "DB=old", "LOCKR_PASSWORD_FILE=/run/secrets/pw"},
+		map[string]string{"DB": "new"},
+		"LOCKR_PASSWORD_FILE",
+	)
+	assert.ElementsMatch(t, []string{"PATH=/bin", "DB=new"}, env)
+}
+
+func TestRun_ExitCodeAndEnv(t *testing.T) {
+	code, err := Run([]string{"sh", "-c", "exit 3"}, os.Environ())
+	require.NoError(t, err)
+	assert.Equal(t, 3, code)
+
+	env := MergeEnv(os.Environ(), map[string]string{"LOCKR_TEST_VALUE": "s3cret"})
+	code, err = Run([]string{"sh", "-c", `test "$LOCKR_TEST_VALUE" = s3cret`}, env)
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+
+	_, err = Run([]string{"/nonexistent/lockr-test-binary"}, nil)
+	assert.Error(t, err)
+}
+
+func TestRun_ForwardsSignals(t *testing.T) {
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	}()
+
+	code, err := Run([]string{"sh", "-c", `trap "exit 7" USR1; while :; do sleep 0.1; done`}, os.Environ())
+	require.NoError(t, err)
+	assert.Equal(t, 7, code)
+}
@@ -0,0 +1,82 @@
+//go:build !windows
+
+package entrypoint
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+func run(cmd *exec.Cmd) (int, error) {
+	// Subscribe before starting so no signal, including the child's own
+	// SIGCHLD, can slip through
+	sigs := make(chan os.Signal, 32)
+	signal.Notify(sigs)
+	defer signal.Reset()
+
+	if err := cmd.Start(); err != nil {
+		return 127, err
+	}
+	pid := cmd.Process.Pid
+	reap := os.Getpid() == 1
+
+	exited := make(chan syscall.WaitStatus, 1)
+	if !reap {
+		go func() {
+			cmd.Wait()
+			exited <- cmd.ProcessState.Sys().(syscall.WaitStatus)
+		}()
+	}
+
+	for {
+		select {
+		case status := <-exited:
+			return exitCode(status), nil
+		case sig := <-sigs:
+			switch sig {
+			case syscall.SIGCHLD:
+				if !reap {
+					continue
+				}
+				if status, done := reapChildren(pid); done {
+					return exitCode(status), nil
+				}
+			case syscall.SIGURG:
+				// Used internally by the Go runtime for preemption
+			default:
+				cmd.Process.Signal(sig)
+			}
+		}
+	}
+}
+
+// reapChildren collects every exited child, reporting the status of pid
+// once it is among them
+func reapChildren(pid int) (syscall.WaitStatus, bool) {
+	var childStatus syscall.WaitStatus
+	found := false
+	for {
+		var status syscall.WaitStatus
+		reaped, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || reaped <= 0 {
+			return childStatus, found
+		}
+		if reaped == pid {
+			childStatus, found = status, true
+		}
+	}
+}
+
+// exitCode follows the shell convention of 128+n for a child killed by
+// signal n
+func exitCode(status syscall.WaitStatus) int {
+	if status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return status.ExitStatus()
+}
@@ -0,0 +1,27 @@
+//go:build windows
+
+package entrypoint
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+)
+
+func run(cmd *exec.Cmd) (int, error) {
+	// Console control events reach the child directly; just stay alive
+	// until it has handled them
+	signal.Ignore(os.Interrupt)
+	defer signal.Reset(os.Interrupt)
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 127, err
+	}
+	return 0, nil
+}
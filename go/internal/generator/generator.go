@@ -0,0 +1,133 @@
+// Package generator describes the options secrets are generated with and
+// provides an interactive panel for tuning them while watching the result
+package generator
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lockr/go/internal/diceware"
+	"github.com/lockr/go/internal/strength"
+)
+
+// Character classes a generated secret can draw from
+const (
+	Lowercase = "abcdefghijklmnopqrstuvwxyz"
+	Uppercase = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	Digits    = "0123456789"
+	Symbols   = "!@#$%^&*()-_=+[]{}|;:,.<>?"
+)
+
+// Bounds on generated secrets
+const (
+	MinLength = 8
+	MaxLength = 256
+)
+
+// ErrAborted is returned when the user leaves the generator without
+// choosing a value
+var ErrAborted = errors.New("generator cancelled")
+
+// Options control how a secret is generated
+type Options struct {
+	Length     int
+	Lower      bool
+	Upper      bool
+	Digits     bool
+	Symbols    bool
+	Passphrase bool
+	Words      int
+	Separator  string
+}
+
+// GenerateFunc produces a value for the given options
+type GenerateFunc func(Options) (string, error)
+
+// Charset returns the characters a random secret is drawn from
+func (o Options) Charset() string {
+	var charset string
+	if o.Lower {
+		charset += Lowercase
+	}
+	if o.Upper {
+		charset += Uppercase
+	}
+	if o.Digits {
+		charset += Digits
+	}
+	if o.Symbols {
+		charset += Symbols
+	}
+	return charset
+}
+
+// Validate checks the options describe something that can be generated
+func (o Options) Validate() error {
+	if o.Passphrase {
+		if o.Words < diceware.MinWords || o.Words > diceware.MaxWords {
+			return fmt.Errorf("passphrases must have between %d and %d words", diceware.MinWords, diceware.MaxWords)
+		}
+		return nil
+	}
+	if o.Length < MinLength || o.Length > MaxLength {
+		return fmt.Errorf("length must be between %d and %d", MinLength, MaxLength)
+	}
+	if o.Charset() == "" {
+		return errors.New("at least one character class is required")
+	}
+	return nil
+}
+
+// Entropy returns the exact strength in bits of a value generated with
+// these options
+func (o Options) Entropy() float64 {
+	if o.Passphrase {
+		return strength.ForGenerated(o.Words, diceware.WordCount)
+	}
+	return strength.ForGenerated(o.Length, len(o.Charset()))
+}
+
+// Random returns length characters drawn uniformly at random from charset,
+// which must hold between 1 and 256 bytes
+func Random(length int, charset string) (string, error) {
+	return random(rand.Reader, length, charset)
+}
+
+// random draws from r using rejection sampling: bytes at or above the
+// largest multiple of len(charset) are discarded, so every character is
+// equally likely. Entropy is read in batches rather than a byte at a time.
+func random(r io.Reader, length int, charset string) (string, error) {
+	if length < MinLength {
+		return "", fmt.Errorf("secret length must be at least %d characters", MinLength)
+	}
+	if length > MaxLength {
+		return "", fmt.Errorf("secret length must not exceed %d characters", MaxLength)
+	}
+	n := len(charset)
+	if n == 0 || n > 256 {
+		return "", fmt.Errorf("charset must hold between 1 and 256 characters, not %d", n)
+	}
+	limit := 256 - 256%n
+
+	secret := make([]byte, 0, length)
+	// Enough for the expected number of rejections, plus some slack
+	buf := make([]byte, length*256/limit+8)
+	for len(secret) < length {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", fmt.Errorf("failed to generate random bytes: %w", err)
+		}
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+			secret = append(secret, charset[int(b)%n])
+			if len(secret) == length {
+				break
+			}
+		}
+	}
+	clear(buf)
+	return string(secret), nil
+}
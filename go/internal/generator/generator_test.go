@@ -0,0 +1,107 @@
+package generator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions_Charset(t *testing.T) {
+	opts := Options{Lower: true, Digits: true}
+	assert.Equal(t, Lowercase+Digits, opts.Charset())
+
+	opts = Options{Lower: true, Upper: true, Digits: true, Symbols: true}
+	assert.Len(t, opts.Charset(), 88)
+}
+
+func TestOptions_Validate(t *testing.T) {
+	assert.NoError(t, Options{Length: 24, Lower: true}.Validate())
+	assert.Error(t, Options{Length: 4, Lower: true}.Validate())
+	assert.Error(t, Options{Length: 300, Lower: true}.Validate())
+	assert.Error(t, Options{Length: 24}.Validate())
+
+	assert.NoError(t, Options{Passphrase: true, Words: 6}.Validate())
+	assert.Error(t, Options{Passphrase: true, Words: 1}.Validate())
+}
+
+func TestOptions_Entropy(t *testing.T) {
+	assert.InDelta(t, 20*4.7, Options{Length: 20, Lower: true}.Entropy(), 0.1)
+	assert.InDelta(t, 77.5, Options{Passphrase: true, Words: 6}.Entropy(), 0.1)
+}
+
+// cycleReader yields the byte values 0 to 255 in turn, forever
+type cycleReader struct{ next byte }
+
+func (r *cycleReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = r.next
+		r.next++
+	}
+	return len(p), nil
+}
+
+func TestRandom_NoModuloBias(t *testing.T) {
+	// Over whole cycles of byte values every character comes up equally
+	// often; mapping with % alone favours the first 256%88 characters
+	charset := Options{Lower: true, Upper: true, Digits: true, Symbols: true}.Charset()
+	limit := 256 - 256%len(charset)
+	value, err := random(&cycleReader{}, limit, charset)
+	require.NoError(t, err)
+	for _, c := range []byte(charset) {
+		assert.Equal(t, limit/len(charset), strings.Count(value, string(c)), "character %q", c)
+	}
+
+	// Rejected bytes are skipped, not mapped
+	value, err = random(bytes.NewReader(bytes.Repeat([]byte{255, 1}, 64)), 8, Digits)
+	require.NoError(t, err)
+	assert.Equal(t, "11111111", value)
+
+	_, err = random(bytes.NewReader(nil), 8, Digits)
+	assert.Error(t, err)
+}
+
+func TestRandom_Uniform(t *testing.T) {
+	charset := Options{Lower: true, Upper: true, Digits: true, Symbols: true}.Charset()
+	counts := make(map[rune]int)
+	const rounds = 4000
+	for range rounds {
+		value, err := Random(MaxLength, charset)
+		require.NoError(t, err)
+		require.Len(t, value, MaxLength)
+		for _, c := range value {
+			counts[c]++
+		}
+	}
+	require.Len(t, counts, len(charset))
+
+	// Pearson's chi-squared test with 87 degrees of freedom; 160 is beyond
+	// the 0.9999 quantile, so a fair generator fails about once in 10^5 runs
+	expected := float64(rounds*MaxLength) / float64(len(charset))
+	var chi2 float64
+	for _, n := range counts {
+		d := float64(n) - expected
+		chi2 += d * d / expected
+	}
+	assert.Less(t, chi2, 160.0)
+
+	// Rejection sampling also keeps awkward alphabet sizes fair
+	counts = make(map[rune]int)
+	for range rounds {
+		value, err := Random(MaxLength, "abc")
+		require.NoError(t, err)
+		for _, c := range value {
+			counts[c]++
+		}
+	}
+	expected = float64(rounds*MaxLength) / 3
+	chi2 = 0
+	for _, n := range counts {
+		d := float64(n) - expected
+		chi2 += d * d / expected
+	}
+	// 2 degrees of freedom: 0.9999 quantile is 18.4
+	assert.Less(t, chi2, 18.4)
+}
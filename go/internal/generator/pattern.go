@@ -0,0 +1,98 @@
+package generator
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Placeholders in a pattern and the characters each stands for. A backslash
+// makes the next character literal; any other character is kept as is.
+var patternClasses = map[rune]string{
+	'a': Lowercase,
+	'A': Uppercase,
+	'9': Digits,
+	'#': Symbols,
+	'*': Lowercase + Uppercase + Digits + Symbols,
+}
+
+// PatternHelp describes the pattern syntax for flag and command help
+const PatternHelp = `a = lowercase letter, A = uppercase letter, 9 = digit, # = symbol,
+* = any of those; \ makes the next character literal, others are kept as is`
+
+// Pattern is a template for secrets of a fixed shape, such as "AAA-9999"
+type Pattern struct {
+	slots []patternSlot
+}
+
+// patternSlot is one position of a pattern: a random character from
+// charset, or literal when charset is empty
+type patternSlot struct {
+	charset string
+	literal rune
+}
+
+// ParsePattern parses a pattern in the PatternHelp syntax
+func ParsePattern(s string) (Pattern, error) {
+	var p Pattern
+	escaped := false
+	random := 0
+	for _, r := range s {
+		switch {
+		case escaped:
+			p.slots = append(p.slots, patternSlot{literal: r})
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case patternClasses[r] != "":
+			p.slots = append(p.slots, patternSlot{charset: patternClasses[r]})
+			random++
+		default:
+			p.slots = append(p.slots, patternSlot{literal: r})
+		}
+	}
+
+	if escaped {
+		return Pattern{}, errors.New("pattern ends with an unfinished escape")
+	}
+	if random == 0 {
+		return Pattern{}, errors.New("pattern has no random characters")
+	}
+	if len(p.slots) > MaxLength {
+		return Pattern{}, fmt.Errorf("pattern must not be longer than %d characters", MaxLength)
+	}
+	return p, nil
+}
+
+// Generate returns a value of the pattern's shape, each placeholder filled
+// uniformly at random
+func (p Pattern) Generate() (string, error) {
+	var b strings.Builder
+	for _, slot := range p.slots {
+		if slot.charset == "" {
+			b.WriteRune(slot.literal)
+			continue
+		}
+		index, err := rand.Int(rand.Reader, big.NewInt(int64(len(slot.charset))))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate random index: %w", err)
+		}
+		b.WriteByte(slot.charset[index.Int64()])
+	}
+	return b.String(), nil
+}
+
+// Entropy returns the exact strength in bits of a value generated from the
+// pattern; literal characters add nothing
+func (p Pattern) Entropy() float64 {
+	var bits float64
+	for _, slot := range p.slots {
+		if slot.charset != "" {
+			bits += math.Log2(float64(len(slot.charset)))
+		}
+	}
+	return bits
+}
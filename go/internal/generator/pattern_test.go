@@ -0,0 +1,32 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPattern(t *testing.T) {
+	p, err := ParsePattern(`AAA-9999-a\9#`)
+	require.NoError(t, err)
+
+	for range 20 {
+		value, err := p.Generate()
+		require.NoError(t, err)
+		require.Len(t, value, 12)
+		assert.Regexp(t, `^[A-Z]{3}-[0-9]{4}-[a-z]9.$`, value)
+		assert.True(t, strings.ContainsRune(Symbols, rune(value[11])))
+	}
+
+	// Literal characters add no strength
+	assert.InDelta(t, 3*4.7+4*3.32+4.7+4.7, p.Entropy(), 0.1)
+
+	_, err = ParsePattern("----")
+	assert.Error(t, err)
+	_, err = ParsePattern(`99\`)
+	assert.Error(t, err)
+	_, err = ParsePattern(strings.Repeat("9", MaxLength+1))
+	assert.Error(t, err)
+}
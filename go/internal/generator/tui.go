@@ -0,0 +1,338 @@
+//go:build !minimal
+
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/lockr/go/internal/diceware"
+	"github.com/lockr/go/internal/keymap"
+	"github.com/lockr/go/internal/strength"
+)
+
+// InteractiveAvailable reports whether this build includes the generator UI
+const InteractiveAvailable = true
+
+// sliderWidth is the number of cells in the length slider
+const sliderWidth = 30
+
+// sliderMax is the length at which the slider is full; longer secrets can
+// still be chosen
+const sliderMax = 64
+
+// Rows of the panel, in display order
+const (
+	rowLength = iota
+	rowLower
+	rowUpper
+	rowDigits
+	rowSymbols
+	rowPassphrase
+	rowCount
+)
+
+// panelStyles defines the visual styling for the generator panel
+type panelStyles struct {
+	Title    lipgloss.Style
+	Label    lipgloss.Style
+	Focused  lipgloss.Style
+	Value    lipgloss.Style
+	Error    lipgloss.Style
+	Help     lipgloss.Style
+	Disabled lipgloss.Style
+	Overlay  lipgloss.Style
+	Ratings  map[strength.Rating]lipgloss.Style
+}
+
+func defaultPanelStyles() panelStyles {
+	return panelStyles{
+		Title: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("32")). // Green
+			Bold(true),
+		Label: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")), // Gray
+		Focused: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("14")). // Cyan
+			Bold(true),
+		Value: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("11")). // Bright yellow
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("238")).
+			Padding(0, 1),
+		Error: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")), // Red
+		Help: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("242")), // Dark gray
+		Disabled: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("238")),
+		Overlay: lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("238")).
+			Padding(0, 1),
+		Ratings: map[strength.Rating]lipgloss.Style{
+			strength.Weak:       lipgloss.NewStyle().Foreground(lipgloss.Color("196")),
+			strength.Fair:       lipgloss.NewStyle().Foreground(lipgloss.Color("214")),
+			strength.Strong:     lipgloss.NewStyle().Foreground(lipgloss.Color("34")),
+			strength.VeryStrong: lipgloss.NewStyle().Foreground(lipgloss.Color("46")),
+		},
+	}
+}
+
+// Model is the Bubble Tea model for the generator panel. Every change to
+// the options generates a new value immediately.
+type Model struct {
+	opts     Options
+	generate GenerateFunc
+	value    string
+	err      error
+	focus    int
+	done     bool
+	aborted  bool
+	help     bool
+	keys     keymap.Keymap
+	styles   panelStyles
+}
+
+// NewModel creates a generator panel starting from opts
+func NewModel(opts Options, generate GenerateFunc) Model {
+	m := Model{
+		opts:     opts,
+		generate: generate,
+		keys:     keymap.Generator(),
+		styles:   defaultPanelStyles(),
+	}
+	if m.opts.Words == 0 {
+		m.opts.Words = diceware.DefaultWords
+	}
+	m.regenerate()
+	return m
+}
+
+// Init initializes the model (required by Bubble Tea)
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles key presses
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	if key.String() == keymap.Quit {
+		m.aborted = true
+		return m, tea.Quit
+	}
+
+	action := m.keys.Action(key.String())
+
+	// The help overlay swallows keys until it is closed
+	if m.help {
+		if action == keymap.Help || action == keymap.Cancel {
+			m.help = false
+		}
+		return m, nil
+	}
+
+	switch action {
+	case keymap.Cancel:
+		m.aborted = true
+		return m, tea.Quit
+
+	case keymap.Up:
+		m.focus = (m.focus + rowCount - 1) % rowCount
+
+	case keymap.Down:
+		m.focus = (m.focus + 1) % rowCount
+
+	case keymap.Decrease:
+		m.adjust(-1)
+
+	case keymap.Increase:
+		m.adjust(1)
+
+	case keymap.Toggle:
+		m.toggle(m.focus)
+
+	case keymap.Passphrase:
+		m.toggle(rowPassphrase)
+
+	case keymap.Regenerate:
+		m.regenerate()
+
+	case keymap.Accept:
+		if m.err == nil && m.value != "" {
+			m.done = true
+			return m, tea.Quit
+		}
+
+	case keymap.Help:
+		m.help = true
+	}
+
+	return m, nil
+}
+
+// adjust changes the length, or the word count in passphrase mode
+func (m *Model) adjust(delta int) {
+	if m.focus != rowLength {
+		return
+	}
+	if m.opts.Passphrase {
+		if words := m.opts.Words + delta; words >= diceware.MinWords && words <= diceware.MaxWords {
+			m.opts.Words = words
+			m.regenerate()
+		}
+		return
+	}
+	if length := m.opts.Length + delta; length >= MinLength && length <= MaxLength {
+		m.opts.Length = length
+		m.regenerate()
+	}
+}
+
+// toggle flips a character class or passphrase mode. The last enabled
+// class cannot be turned off.
+func (m *Model) toggle(row int) {
+	next := m.opts
+	switch row {
+	case rowLower:
+		next.Lower = !next.Lower
+	case rowUpper:
+		next.Upper = !next.Upper
+	case rowDigits:
+		next.Digits = !next.Digits
+	case rowSymbols:
+		next.Symbols = !next.Symbols
+	case rowPassphrase:
+		next.Passphrase = !next.Passphrase
+	default:
+		return
+	}
+	if row != rowPassphrase && next.Charset() == "" {
+		return
+	}
+	m.opts = next
+	m.regenerate()
+}
+
+// regenerate produces a fresh value for the current options
+func (m *Model) regenerate() {
+	m.value = ""
+	if m.err = m.opts.Validate(); m.err != nil {
+		return
+	}
+	m.value, m.err = m.generate(m.opts)
+}
+
+// Value returns the generated value and the options it was made with
+func (m Model) Value() (string, Options) {
+	return m.value, m.opts
+}
+
+// View renders the panel
+func (m Model) View() string {
+	if m.done || m.aborted {
+		return ""
+	}
+
+	if m.help {
+		return m.styles.Overlay.Render(m.keys.Overlay()) + "\n"
+	}
+
+	var b strings.Builder
+	b.WriteString(m.styles.Title.Render("Generate a secret"))
+	b.WriteString("\n\n")
+
+	if m.opts.Passphrase {
+		b.WriteString(m.row(rowLength, "Words", fmt.Sprintf("%s %d", m.slider(m.opts.Words-diceware.MinWords, diceware.MaxWords-diceware.MinWords), m.opts.Words)))
+	} else {
+		b.WriteString(m.row(rowLength, "Length", fmt.Sprintf("%s %d", m.slider(m.opts.Length-MinLength, sliderMax-MinLength), m.opts.Length)))
+	}
+	b.WriteString(m.row(rowLower, "a-z", m.checkbox(m.opts.Lower)))
+	b.WriteString(m.row(rowUpper, "A-Z", m.checkbox(m.opts.Upper)))
+	b.WriteString(m.row(rowDigits, "0-9", m.checkbox(m.opts.Digits)))
+	b.WriteString(m.row(rowSymbols, "Symbols", m.checkbox(m.opts.Symbols)))
+	b.WriteString(m.row(rowPassphrase, "Passphrase", m.checkbox(m.opts.Passphrase)))
+	b.WriteString("\n")
+
+	if m.err != nil {
+		b.WriteString(m.styles.Error.Render(m.err.Error()))
+		b.WriteString("\n")
+	} else {
+		b.WriteString(m.styles.Value.Render(m.value))
+		b.WriteString("\n")
+		bits := m.opts.Entropy()
+		rating := strength.Rate(bits)
+		b.WriteString(m.styles.Label.Render(fmt.Sprintf("%-11s", "Strength")))
+		b.WriteString(m.styles.Ratings[rating].Render(fmt.Sprintf("%s %.0f bits (%s)", m.slider(int(bits), 128), bits, rating)))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	k := m.keys
+	b.WriteString(m.styles.Help.Render(fmt.Sprintf("%s/%s move • %s/%s adjust • %s toggle • %s passphrase • %s regenerate • %s use • %s cancel • %s help",
+		k.Label(keymap.Up), k.Label(keymap.Down), k.Label(keymap.Decrease), k.Label(keymap.Increase),
+		k.Label(keymap.Toggle), k.Label(keymap.Passphrase), k.Label(keymap.Regenerate),
+		k.Label(keymap.Accept), k.Label(keymap.Cancel), k.Label(keymap.Help))))
+	b.WriteString("\n")
+	return b.String()
+}
+
+// row renders one labelled option, highlighting the focused one
+func (m Model) row(row int, label, content string) string {
+	cursor := "  "
+	labelStyle := m.styles.Label
+	if row == m.focus {
+		cursor = "> "
+		labelStyle = m.styles.Focused
+	}
+	if m.opts.Passphrase && row >= rowLower && row <= rowSymbols {
+		return cursor + m.styles.Disabled.Render(fmt.Sprintf("%-9s %s", label, content)) + "\n"
+	}
+	return cursor + labelStyle.Render(fmt.Sprintf("%-9s", label)) + " " + content + "\n"
+}
+
+func (m Model) checkbox(on bool) string {
+	if on {
+		return "[x]"
+	}
+	return "[ ]"
+}
+
+// slider renders value out of total as a bar
+func (m Model) slider(value, total int) string {
+	filled := 0
+	if total > 0 {
+		filled = value * sliderWidth / total
+	}
+	filled = min(max(filled, 0), sliderWidth)
+	return strings.Repeat("■", filled) + strings.Repeat("─", sliderWidth-filled)
+}
+
+// Run shows the generator panel and returns the chosen value with the
+// options it was generated with, or ErrAborted if the user cancelled. A zero
+// keys uses keymap.Generator.
+func Run(opts Options, keys keymap.Keymap, generate GenerateFunc) (string, Options, error) {
+	m := NewModel(opts, generate)
+	if keys.Bindings != nil {
+		m.keys = keys
+	}
+	program := tea.NewProgram(m)
+	finalModel, err := program.Run()
+	if err != nil {
+		return "", opts, fmt.Errorf("error running generator: %w", err)
+	}
+
+	final := finalModel.(Model)
+	if final.aborted || !final.done {
+		return "", opts, ErrAborted
+	}
+	value, chosen := final.Value()
+	return value, chosen, nil
+}
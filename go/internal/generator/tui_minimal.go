@@ -0,0 +1,21 @@
+//go:build minimal
+
+package generator
+
+import (
+	"errors"
+
+	"github.com/lockr/go/internal/keymap"
+)
+
+// ErrInteractiveUnavailable is returned by Run in builds made with the
+// minimal tag, which leave out the terminal UI
+var ErrInteractiveUnavailable = errors.New("the interactive generator is not available in this build; use set -g")
+
+// InteractiveAvailable reports whether this build includes the generator UI
+const InteractiveAvailable = false
+
+// Run always fails in minimal builds
+func Run(opts Options, keys keymap.Keymap, generate GenerateFunc) (string, Options, error) {
+	return "", opts, ErrInteractiveUnavailable
+}
@@ -0,0 +1,117 @@
+//go:build !minimal
+
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func press(m Model, keys ...string) Model {
+	for _, k := range keys {
+		var msg tea.KeyMsg
+		switch k {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "down":
+			msg = tea.KeyMsg{Type: tea.KeyDown}
+		case "right":
+			msg = tea.KeyMsg{Type: tea.KeyRight}
+		case "left":
+			msg = tea.KeyMsg{Type: tea.KeyLeft}
+		case " ":
+			msg = tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+		next, _ := m.Update(msg)
+		m = next.(Model)
+	}
+	return m
+}
+
+// fakeGenerate describes the options instead of generating randomness
+func fakeGenerate(calls *int) GenerateFunc {
+	return func(o Options) (string, error) {
+		*calls++
+		if o.Passphrase {
+			return fmt.Sprintf("words-%d", o.Words), nil
+		}
+		return strings.Repeat("x", o.Length) + "|" + o.Charset(), nil
+	}
+}
+
+func TestModel(t *testing.T) {
+	calls := 0
+	m := NewModel(Options{Length: 10, Lower: true, Digits: true}, fakeGenerate(&calls))
+	assert.Equal(t, 1, calls)
+	assert.Contains(t, m.View(), "xxxxxxxxxx|"+Lowercase+Digits)
+	assert.Contains(t, m.View(), "bits")
+
+	// Length slider
+	m = press(m, "right", "right", "left")
+	value, opts := m.Value()
+	assert.Equal(t, 11, opts.Length)
+	assert.True(t, strings.HasPrefix(value, strings.Repeat("x", 11)+"|"))
+
+	// Regenerate without changing anything
+	before := calls
+	m = press(m, "r")
+	assert.Equal(t, before+1, calls)
+
+	// Toggle symbols (fifth row)
+	m = press(m, "down", "down", "down", "down", " ")
+	_, opts = m.Value()
+	assert.True(t, opts.Symbols)
+
+	// The last character class cannot be turned off
+	m = press(m, "up", " ", "up", "up", " ")
+	_, opts = m.Value()
+	assert.Equal(t, Symbols, opts.Charset())
+	m = press(m, "down", "down", "down", " ")
+	_, opts = m.Value()
+	assert.Equal(t, Symbols, opts.Charset())
+
+	// Passphrase mode uses the slider for words
+	m = press(m, "p", "k", "k", "k", "k", "right")
+	value, opts = m.Value()
+	assert.True(t, opts.Passphrase)
+	assert.Equal(t, "words-7", value)
+
+	m = press(m, "enter")
+	assert.True(t, m.done)
+}
+
+func TestModel_Abort(t *testing.T) {
+	calls := 0
+	m := NewModel(Options{Length: 24, Lower: true}, fakeGenerate(&calls))
+	m = press(m, "q")
+	assert.True(t, m.aborted)
+	assert.Equal(t, "", m.View())
+}
+
+func TestModel_GenerateError(t *testing.T) {
+	m := NewModel(Options{Length: 24, Lower: true}, func(Options) (string, error) {
+		return "", fmt.Errorf("no entropy")
+	})
+	assert.Contains(t, m.View(), "no entropy")
+
+	// Enter does nothing without a value
+	m = press(m, "enter")
+	assert.False(t, m.done)
+}
+
+func TestModel_Help(t *testing.T) {
+	calls := 0
+	m := NewModel(Options{Length: 24, Lower: true}, fakeGenerate(&calls))
+	m = press(m, "?")
+	assert.Contains(t, m.View(), "Generate another value")
+
+	m = press(m, "r", "?")
+	assert.Equal(t, 1, calls)
+	assert.Contains(t, m.View(), "Strength")
+}
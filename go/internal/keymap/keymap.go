@@ -0,0 +1,307 @@
+// Package keymap describes the key bindings of lockr's interactive screens.
+// Each screen has a default Keymap that the config file can remap; the
+// screens dispatch key presses through it and render it as a help overlay.
+package keymap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Screens with remappable keys, as named in the config file
+const (
+	ScreenPicker    = "picker"
+	ScreenGenerator = "generator"
+	ScreenMerge     = "merge"
+)
+
+// Actions shared by every screen
+const (
+	Up     = "up"
+	Down   = "down"
+	Accept = "accept"
+	Cancel = "cancel"
+	Help   = "help"
+)
+
+// Picker actions; the picker also uses Reveal
+const (
+	DeleteChar = "delete-char"
+)
+
+// Generator actions
+const (
+	Decrease   = "decrease"
+	Increase   = "increase"
+	Toggle     = "toggle"
+	Passphrase = "passphrase"
+	Regenerate = "regenerate"
+)
+
+// Merge actions
+const (
+	KeepOurs   = "ours"
+	TakeTheirs = "theirs"
+	Edit       = "edit"
+	Reveal     = "reveal"
+)
+
+// Groups order the help overlay
+const (
+	GroupNavigation = "Navigation"
+	GroupSelection  = "Selection"
+	GroupActions    = "Actions"
+	GroupGeneral    = "General"
+)
+
+var groupOrder = []string{GroupNavigation, GroupSelection, GroupActions, GroupGeneral}
+
+// Quit is the key that always leaves a screen, whatever the keymap says
+const Quit = "ctrl+c"
+
+// Binding ties an action to the keys that trigger it. Keys use Bubble Tea's
+// names: "up", "enter", "ctrl+p", "a", and " " for the space bar.
+type Binding struct {
+	Action string
+	Keys   []string
+	Help   string
+	Group  string
+}
+
+// Keymap is the set of bindings of one screen
+type Keymap struct {
+	Screen   string
+	Title    string
+	Bindings []Binding
+}
+
+// Picker returns the default keys of the interactive search picker
+func Picker() Keymap {
+	return Keymap{Screen: ScreenPicker, Title: "Search", Bindings: []Binding{
+		{Up, []string{"up", "ctrl+p"}, "Move to the previous result", GroupNavigation},
+		{Down, []string{"down", "ctrl+n"}, "Move to the next result", GroupNavigation},
+		{Accept, []string{"enter"}, "Retrieve the selected secret", GroupSelection},
+		{DeleteChar, []string{"backspace"}, "Delete the last character of the query", GroupActions},
+		{Reveal, []string{"ctrl+r"}, "Show the selected value for a few seconds", GroupActions},
+		{Cancel, []string{"esc"}, "Cancel", GroupGeneral},
+		{Help, []string{"?"}, "Show or hide this help", GroupGeneral},
+	}}
+}
+
+// Generator returns the default keys of the secret generator panel
+func Generator() Keymap {
+	return Keymap{Screen: ScreenGenerator, Title: "Generate a secret", Bindings: []Binding{
+		{Up, []string{"up", "k", "shift+tab"}, "Focus the previous option", GroupNavigation},
+		{Down, []string{"down", "j", "tab"}, "Focus the next option", GroupNavigation},
+		{Decrease, []string{"left", "h", "-"}, "Shorten the secret (or use fewer words)", GroupActions},
+		{Increase, []string{"right", "l", "+", "="}, "Lengthen the secret (or use more words)", GroupActions},
+		{Toggle, []string{" ", "x"}, "Toggle the focused option", GroupSelection},
+		{Passphrase, []string{"p"}, "Switch between characters and a passphrase", GroupSelection},
+		{Regenerate, []string{"r"}, "Generate another value", GroupActions},
+		{Accept, []string{"enter"}, "Use the value shown", GroupActions},
+		{Cancel, []string{"esc", "q"}, "Cancel", GroupGeneral},
+		{Help, []string{"?"}, "Show or hide this help", GroupGeneral},
+	}}
+}
+
+// Merge returns the default keys of the merge conflict resolver
+func Merge() Keymap {
+	return Keymap{Screen: ScreenMerge, Title: "Resolve merge conflicts", Bindings: []Binding{
+		{Up, []string{"up", "k", "shift+tab"}, "Previous conflict", GroupNavigation},
+		{Down, []string{"down", "j", "tab"}, "Next conflict", GroupNavigation},
+		{KeepOurs, []string{"o", "left", "h"}, "Keep this vault's value", GroupSelection},
+		{TakeTheirs, []string{"t", "right", "l"}, "Take the other vault's value", GroupSelection},
+		{Edit, []string{"e"}, "Type a new value", GroupActions},
+		{Reveal, []string{"r"}, "Reveal or hide both values", GroupActions},
+		{Accept, []string{"enter"}, "Apply once every conflict is decided", GroupActions},
+		{Cancel, []string{"esc", "q"}, "Abort the merge", GroupGeneral},
+		{Help, []string{"?"}, "Show or hide this help", GroupGeneral},
+	}}
+}
+
+// Defaults returns the default keymap of every screen, by screen name
+func Defaults() map[string]Keymap {
+	return map[string]Keymap{
+		ScreenPicker:    Picker(),
+		ScreenGenerator: Generator(),
+		ScreenMerge:     Merge(),
+	}
+}
+
+// Load applies overrides from the config file, screen → action → keys, to
+// the defaults. Unknown screens or actions, and keys bound to two actions
+// of one screen, are errors.
+func Load(overrides map[string]map[string][]string) (map[string]Keymap, error) {
+	keymaps := Defaults()
+	for screen, actions := range overrides {
+		k, ok := keymaps[screen]
+		if !ok {
+			return nil, fmt.Errorf("unknown screen %q in keys (want %s)", screen, strings.Join(screenNames(), ", "))
+		}
+		remapped, err := k.Remap(actions)
+		if err != nil {
+			return nil, err
+		}
+		keymaps[screen] = remapped
+	}
+	return keymaps, nil
+}
+
+// Remap returns a copy of k with the keys of the given actions replaced
+func (k Keymap) Remap(actions map[string][]string) (Keymap, error) {
+	bindings := make([]Binding, len(k.Bindings))
+	copy(bindings, k.Bindings)
+
+	for action, keys := range actions {
+		i := k.index(action)
+		if i < 0 {
+			return k, fmt.Errorf("unknown %s action %q (want %s)", k.Screen, action, strings.Join(k.actions(), ", "))
+		}
+		if len(keys) == 0 {
+			return k, fmt.Errorf("%s action %q needs at least one key", k.Screen, action)
+		}
+		normalized := make([]string, len(keys))
+		for j, key := range keys {
+			normalized[j] = normalizeKey(key)
+			if normalized[j] == "" {
+				return k, fmt.Errorf("%s action %q has an empty key", k.Screen, action)
+			}
+			if normalized[j] == Quit {
+				return k, fmt.Errorf("%s always quits and cannot be bound", Quit)
+			}
+		}
+		bindings[i].Keys = normalized
+	}
+
+	seen := map[string]string{}
+	for _, b := range bindings {
+		for _, key := range b.Keys {
+			if other, ok := seen[key]; ok {
+				return k, fmt.Errorf("%s key %q is bound to both %q and %q", k.Screen, DisplayKey(key), other, b.Action)
+			}
+			seen[key] = b.Action
+		}
+	}
+
+	k.Bindings = bindings
+	return k, nil
+}
+
+// Action returns the action bound to key, or "" if there is none
+func (k Keymap) Action(key string) string {
+	for _, b := range k.Bindings {
+		for _, bound := range b.Keys {
+			if bound == key {
+				return b.Action
+			}
+		}
+	}
+	return ""
+}
+
+// Label returns the first key of action for short hints such as "? help"
+func (k Keymap) Label(action string) string {
+	if i := k.index(action); i >= 0 && len(k.Bindings[i].Keys) > 0 {
+		return DisplayKey(k.Bindings[i].Keys[0])
+	}
+	return "?"
+}
+
+// Overlay renders the bindings as a help overlay, grouped and aligned
+func (k Keymap) Overlay() string {
+	width := len(Quit)
+	keys := make([]string, len(k.Bindings))
+	for i, b := range k.Bindings {
+		display := make([]string, len(b.Keys))
+		for j, key := range b.Keys {
+			display[j] = DisplayKey(key)
+		}
+		keys[i] = strings.Join(display, " ")
+		width = max(width, len([]rune(keys[i])))
+	}
+
+	var s strings.Builder
+	fmt.Fprintf(&s, "Keys: %s\n", k.Title)
+	for _, group := range groupOrder {
+		first := true
+		for i, b := range k.Bindings {
+			if b.Group != group {
+				continue
+			}
+			if first {
+				fmt.Fprintf(&s, "\n%s\n", group)
+				first = false
+			}
+			pad := width - len([]rune(keys[i]))
+			fmt.Fprintf(&s, "  %s%s  %s\n", keys[i], strings.Repeat(" ", pad), b.Help)
+		}
+	}
+	fmt.Fprintf(&s, "  %s%s  %s\n", Quit, strings.Repeat(" ", width-len(Quit)), "Quit immediately")
+	fmt.Fprintf(&s, "\nKeys can be remapped under keys.%s in the config file. Press %s to close.", k.Screen, k.Label(Help))
+	return s.String()
+}
+
+// DisplayKey renders a key name for people
+func DisplayKey(key string) string {
+	switch key {
+	case " ":
+		return "space"
+	case "up":
+		return "↑"
+	case "down":
+		return "↓"
+	case "left":
+		return "←"
+	case "right":
+		return "→"
+	}
+	return key
+}
+
+// normalizeKey turns a key as written in the config file into Bubble Tea's
+// name for it
+func normalizeKey(key string) string {
+	if key == " " {
+		return key
+	}
+	key = strings.TrimSpace(key)
+	if len([]rune(key)) > 1 {
+		key = strings.ToLower(key)
+	}
+	switch key {
+	case "space":
+		return " "
+	case "return":
+		return "enter"
+	case "escape":
+		return "esc"
+	}
+	return key
+}
+
+func (k Keymap) index(action string) int {
+	for i, b := range k.Bindings {
+		if b.Action == action {
+			return i
+		}
+	}
+	return -1
+}
+
+func (k Keymap) actions() []string {
+	names := make([]string, len(k.Bindings))
+	for i, b := range k.Bindings {
+		names[i] = b.Action
+	}
+	return names
+}
+
+func screenNames() []string {
+	var names []string
+	for name := range Defaults() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
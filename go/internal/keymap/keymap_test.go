@@ -0,0 +1,55 @@
+package keymap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeymap_Action(t *testing.T) {
+	k := Generator()
+	assert.Equal(t, Up, k.Action("k"))
+	assert.Equal(t, Toggle, k.Action(" "))
+	assert.Equal(t, Help, k.Action("?"))
+	assert.Equal(t, "", k.Action("z"))
+	assert.Equal(t, "space", k.Label(Toggle))
+	assert.Equal(t, "↑", k.Label(Up))
+}
+
+func TestLoad(t *testing.T) {
+	keymaps, err := Load(map[string]map[string][]string{
+		ScreenPicker:    {Up: {"ctrl+k"}, Help: {"F1"}},
+		ScreenGenerator: {Toggle: {"space"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Up, keymaps[ScreenPicker].Action("ctrl+k"))
+	assert.Equal(t, "", keymaps[ScreenPicker].Action("up"))
+	assert.Equal(t, Help, keymaps[ScreenPicker].Action("f1"))
+	assert.Equal(t, Toggle, keymaps[ScreenGenerator].Action(" "))
+	assert.Equal(t, "", keymaps[ScreenGenerator].Action("x"))
+
+	// Defaults are not modified by remapping
+	assert.Equal(t, Up, Picker().Action("up"))
+
+	for name, overrides := range map[string]map[string]map[string][]string{
+		"screen":    {"editor": {Up: {"k"}}},
+		"action":    {ScreenPicker: {"jump": {"j"}}},
+		"no keys":   {ScreenPicker: {Up: {}}},
+		"duplicate": {ScreenMerge: {Edit: {"o"}}},
+		"quit":      {ScreenPicker: {Cancel: {"ctrl+c"}}},
+	} {
+		_, err := Load(overrides)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestKeymap_Overlay(t *testing.T) {
+	overlay := Merge().Overlay()
+	assert.Contains(t, overlay, "Keys: Resolve merge conflicts")
+	assert.Contains(t, overlay, "Navigation")
+	assert.Contains(t, overlay, "o ← h")
+	assert.Contains(t, overlay, "Reveal or hide both values")
+	assert.Contains(t, overlay, "ctrl+c")
+	assert.Contains(t, overlay, "keys.merge")
+}
@@ -9,6 +9,9 @@ var (
 	// ErrPasswordNotFound is returned when the password is not found in the keyring
 	ErrPasswordNotFound = errors.New("password not found in keyring")
 
+	// ErrDerivedKeyNotFound is returned when no derived key is cached for a vault
+	ErrDerivedKeyNotFound = errors.New("derived key not found in keyring")
+
 	// ErrKeyringNotSupported is returned when keyring is not supported on the system
 	ErrKeyringNotSupported = errors.New("keyring is not supported on this system")
 )
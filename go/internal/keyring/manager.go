@@ -1,9 +1,12 @@
 package keyring
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/lockr/go/internal/crypto"
 	"github.com/zalando/go-keyring"
@@ -15,6 +18,12 @@ const (
 
 	// DefaultUsername is the default username for storing the master key
 	DefaultUsername = "masterkey"
+
+	// derivedKeyPrefix prefixes the keyring accounts holding derived vault keys
+	derivedKeyPrefix = "derivedkey-"
+
+	// cacheKeyPrefix prefixes the keyring accounts holding value cache keys
+	cacheKeyPrefix = "cachekey-"
 )
 
 // KeyringData stores the master key and encrypted password
@@ -296,6 +305,112 @@ func (m *Manager) PromptToSave(password string) error {
 	return nil
 }
 
+// VaultID returns a stable identifier for the vault at path, used to keep
+// per-vault keyring entries apart
+func VaultID(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	sum := sha256.Sum256([]byte(path))
+	return hex.EncodeToString(sum[:8])
+}
+
+// derivedKeyUsername returns the keyring account holding a vault's derived key
+func (m *Manager) derivedKeyUsername(vaultID string) string {
+	return derivedKeyPrefix + vaultID
+}
+
+// SaveDerivedKey stores a vault's derived raw database key in the keyring.
+// The key unlocks only that vault and, unlike the password, cannot be used
+// to open other vaults sharing the same password.
+func (m *Manager) SaveDerivedKey(vaultID string, key []byte) error {
+	if !m.enabled {
+		return ErrKeyringDisabled
+	}
+
+	if err := keyring.Set(m.serviceName, m.derivedKeyUsername(vaultID), hex.EncodeToString(key)); err != nil {
+		return fmt.Errorf("failed to save to keyring: %w", err)
+	}
+	return nil
+}
+
+// GetDerivedKey retrieves a vault's cached derived key
+func (m *Manager) GetDerivedKey(vaultID string) ([]byte, error) {
+	if !m.enabled {
+		return nil, ErrKeyringDisabled
+	}
+
+	encoded, err := keyring.Get(m.serviceName, m.derivedKeyUsername(vaultID))
+	if err != nil {
+		if err == keyring.ErrNotFound {
+			return nil, ErrDerivedKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve from keyring: %w", err)
+	}
+
+	key, err := hex.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode derived key: %w", err)
+	}
+	return key, nil
+}
+
+// HasDerivedKey reports whether a derived key is cached for the vault
+func (m *Manager) HasDerivedKey(vaultID string) bool {
+	_, err := m.GetDerivedKey(vaultID)
+	return err == nil
+}
+
+// DeleteDerivedKey removes a vault's cached derived key
+func (m *Manager) DeleteDerivedKey(vaultID string) error {
+	err := keyring.Delete(m.serviceName, m.derivedKeyUsername(vaultID))
+	if err != nil && err != keyring.ErrNotFound {
+		return fmt.Errorf("failed to delete from keyring: %w", err)
+	}
+	return nil
+}
+
+// GetOrCreateCacheKey returns the key encrypting a vault's local value
+// cache, generating and storing one on first use
+func (m *Manager) GetOrCreateCacheKey(vaultID string) (crypto.MasterKey, error) {
+	if !m.enabled {
+		return nil, ErrKeyringDisabled
+	}
+
+	encoded, err := keyring.Get(m.serviceName, cacheKeyPrefix+vaultID)
+	if err == nil {
+		return crypto.DecodeMasterKey(encoded)
+	}
+	if err != keyring.ErrNotFound {
+		return nil, fmt.Errorf("failed to retrieve from keyring: %w", err)
+	}
+
+	key, err := crypto.GenerateMasterKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := keyring.Set(m.serviceName, cacheKeyPrefix+vaultID, key.Encode()); err != nil {
+		return nil, fmt.Errorf("failed to save to keyring: %w", err)
+	}
+	return key, nil
+}
+
+// HasCacheKey reports whether a value cache key is stored for vaultID
+func (m *Manager) HasCacheKey(vaultID string) bool {
+	_, err := keyring.Get(m.serviceName, cacheKeyPrefix+vaultID)
+	return err == nil
+}
+
+// DeleteCacheKey removes a vault's value cache key, making any cached
+// values unreadable
+func (m *Manager) DeleteCacheKey(vaultID string) error {
+	err := keyring.Delete(m.serviceName, cacheKeyPrefix+vaultID)
+	if err != nil && err != keyring.ErrNotFound {
+		return fmt.Errorf("failed to delete from keyring: %w", err)
+	}
+	return nil
+}
+
 // ClearCache clears the cached master key from memory
 func (m *Manager) ClearCache() {
 	if m.masterKey != nil {
@@ -350,3 +350,54 @@ func TestMultiplePasswords(t *testing.T) {
 		assert.Equal(t, password, retrieved, "Failed for password: %s", password)
 	}
 }
+
+func TestDerivedKey(t *testing.T) {
+	m := NewManager()
+	m.SetServiceName("lockr-test-" + t.Name())
+
+	vaultID := VaultID("/tmp/lockr-test/vault.lockr")
+	defer m.DeleteDerivedKey(vaultID)
+
+	assert.False(t, m.HasDerivedKey(vaultID))
+	_, err := m.GetDerivedKey(vaultID)
+	assert.ErrorIs(t, err, ErrDerivedKeyNotFound)
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	require.NoError(t, m.SaveDerivedKey(vaultID, key))
+
+	retrieved, err := m.GetDerivedKey(vaultID)
+	require.NoError(t, err)
+	assert.Equal(t, key, retrieved)
+
+	// Keys are stored per vault
+	assert.False(t, m.HasDerivedKey(VaultID("/tmp/lockr-test/other.lockr")))
+
+	require.NoError(t, m.DeleteDerivedKey(vaultID))
+	assert.False(t, m.HasDerivedKey(vaultID))
+}
+
+func TestCacheKey(t *testing.T) {
+	m := NewManager()
+	m.SetServiceName("lockr-test-" + t.Name())
+
+	vaultID := VaultID("/tmp/lockr-test/vault.lockr")
+	defer m.DeleteCacheKey(vaultID)
+
+	key, err := m.GetOrCreateCacheKey(vaultID)
+	require.NoError(t, err)
+
+	again, err := m.GetOrCreateCacheKey(vaultID)
+	require.NoError(t, err)
+	assert.Equal(t, key, again)
+
+	require.NoError(t, m.DeleteCacheKey(vaultID))
+	replaced, err := m.GetOrCreateCacheKey(vaultID)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, replaced)
+}
+
+func TestVaultID(t *testing.T) {
+	assert.Equal(t, VaultID("/a/vault.lockr"), VaultID("/a/../a/vault.lockr"))
+	assert.NotEqual(t, VaultID("/a/vault.lockr"), VaultID("/b/vault.lockr"))
+	assert.Len(t, VaultID("/a/vault.lockr"), 16)
+}
@@ -0,0 +1,194 @@
+package lint
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lockr/go/internal/database"
+)
+
+// Format is a recognizable credential format
+type Format struct {
+	Name string
+
+	// match reports whether a whole value is in the format
+	match func(value string) bool
+
+	// check returns the format's findings for a value, without key or format
+	check func(value string, now time.Time) []Finding
+}
+
+// Check returns the findings for a value in the format, without the key
+// and format filled in
+func (f *Format) Check(value string, now time.Time) []Finding {
+	if f.check == nil {
+		return nil
+	}
+	return f.check(value, now)
+}
+
+var (
+	awsAccessKeyPattern = regexp.MustCompile(`^(AKIA|ASIA)[A-Z2-7]{16}$`)
+	awsSecretPattern    = regexp.MustCompile(`(^|[^A-Za-z0-9/+])[A-Za-z0-9/+]{40}([^A-Za-z0-9/+=]|$)`)
+	githubTokenPattern  = regexp.MustCompile(`^(ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}$|^github_pat_[A-Za-z0-9]{22}_[A-Za-z0-9]{59}$`)
+	jwtPattern          = regexp.MustCompile(`^[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*$`)
+)
+
+var awsAccessKey = &Format{
+	Name:  "AWS access key",
+	match: func(v string) bool { return awsAccessKeyPattern.MatchString(strings.TrimSpace(v)) },
+	check: func(v string, now time.Time) []Finding {
+		if strings.HasPrefix(strings.TrimSpace(v), "ASIA") {
+			return []Finding{{Severity: Warning,
+				Message: "temporary STS credentials: they expire within hours and need a session token"}}
+		}
+		return nil
+	},
+}
+
+var githubToken = &Format{
+	Name:  "GitHub token",
+	match: func(v string) bool { return githubTokenPattern.MatchString(strings.TrimSpace(v)) },
+	check: func(v string, now time.Time) []Finding {
+		switch strings.TrimSpace(v)[:4] {
+		case "ghp_":
+			return []Finding{{Severity: Warning,
+				Message: "classic personal access token: its scopes cover every repository you can reach; prefer a fine-grained token (github_pat_)"}}
+		case "ghs_":
+			return []Finding{{Severity: Warning,
+				Message: "app installation token: it expires an hour after it is issued, so a stored copy is likely dead"}}
+		}
+		return nil
+	},
+}
+
+var jwt = &Format{
+	Name:  "JWT",
+	match: func(v string) bool { _, _, err := parseJWT(v); return err == nil },
+	check: checkJWT,
+}
+
+// formats are tried in order; the first match names a value's format
+var formats = []*Format{awsAccessKey, githubToken, jwt}
+
+// Recognize returns the credential format of value, or nil
+func Recognize(value string) *Format {
+	for _, f := range formats {
+		if f.match(value) {
+			return f
+		}
+	}
+	return nil
+}
+
+// hasAWSSecret reports whether an AWS secret access key is stored next to
+// the access key in s, in another secret under the same namespace
+func hasAWSSecret(s database.Secret, secrets []database.Secret) bool {
+	ns := strings.ToLower(namespace(s.Key))
+	for _, other := range secrets {
+		if other.Key == s.Key || strings.ToLower(namespace(other.Key)) != ns {
+			continue
+		}
+		if awsSecretPattern.MatchString(other.Value) && !awsAccessKeyPattern.MatchString(strings.TrimSpace(other.Value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// jwtHeader and jwtClaims are the parts of a JWT that are checked
+type jwtHeader struct {
+	Alg string `json:"alg"`
+}
+
+type jwtClaims struct {
+	Exp    *json.Number    `json:"exp"`
+	Scope  json.RawMessage `json:"scope"`
+	Scp    json.RawMessage `json:"scp"`
+	Scopes json.RawMessage `json:"scopes"`
+}
+
+// parseJWT decodes the header and claims of a JWT without verifying it
+func parseJWT(value string) (jwtHeader, jwtClaims, error) {
+	var header jwtHeader
+	var claims jwtClaims
+	value = strings.TrimSpace(value)
+	if !jwtPattern.MatchString(value) {
+		return header, claims, fmt.Errorf("not a JWT")
+	}
+	parts := strings.Split(value, ".")
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg == "" {
+		return header, claims, fmt.Errorf("not a JWT")
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return header, claims, fmt.Errorf("not a JWT")
+	}
+	return header, claims, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return err
+	}
+	d := json.NewDecoder(strings.NewReader(string(data)))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+// broadScopes are scope values that grant everything
+var broadScopes = map[string]bool{"*": true, "admin": true, "root": true, "all": true, "superuser": true}
+
+func checkJWT(value string, now time.Time) []Finding {
+	header, claims, err := parseJWT(value)
+	if err != nil {
+		return nil
+	}
+
+	var findings []Finding
+	if strings.EqualFold(header.Alg, "none") {
+		findings = append(findings, Finding{Severity: Error,
+			Message: `unsigned token (alg "none"): anyone can forge one like it`})
+	}
+	if claims.Exp != nil {
+		if seconds, err := claims.Exp.Float64(); err == nil {
+			if msg, severity, ok := expiry(time.Unix(int64(seconds), 0), now); ok {
+				findings = append(findings, Finding{Severity: severity, Message: "token " + msg})
+			}
+		}
+	}
+
+	var broad []string
+	for _, raw := range []json.RawMessage{claims.Scope, claims.Scp, claims.Scopes} {
+		for _, scope := range scopeList(raw) {
+			if broadScopes[strings.ToLower(scope)] || strings.HasSuffix(scope, ":*") || strings.HasSuffix(scope, "/*") {
+				broad = append(broad, scope)
+			}
+		}
+	}
+	if len(broad) > 0 {
+		findings = append(findings, Finding{Severity: Warning,
+			Message: fmt.Sprintf("broad scopes encoded in the token: %s", strings.Join(broad, ", "))})
+	}
+	return findings
+}
+
+// scopeList reads a scope claim, either a space-separated string or a list
+func scopeList(raw json.RawMessage) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return strings.Fields(s)
+	}
+	var list []string
+	if json.Unmarshal(raw, &list) == nil {
+		return list
+	}
+	return nil
+}
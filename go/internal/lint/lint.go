@@ -0,0 +1,142 @@
+// Package lint checks stored secrets for problems: expired or dangling
+// entries, and values in known credential formats (AWS keys, GitHub tokens,
+// JWTs) that are expired, incomplete or broader than they need to be. Every
+// check runs offline on what is stored.
+package lint
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/refs"
+)
+
+// Severity ranks findings
+type Severity string
+
+const (
+	// Error findings are secrets that will not work as stored
+	Error Severity = "error"
+
+	// Warning findings work but deserve a look
+	Warning Severity = "warning"
+)
+
+// ExpiringWithin is how soon an expiry date or token expiry is reported
+const ExpiringWithin = 14 * 24 * time.Hour
+
+// Finding is one problem with one secret
+type Finding struct {
+	Key      string   `json:"key"`
+	Format   string   `json:"format,omitempty"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// Metadata checks the secrets' expiry dates and references to other
+// secrets, and finds empty ones, such as keys scaffolded by discovery
+func Metadata(secrets []database.Secret, now time.Time) []Finding {
+	keys := make(map[string]bool, len(secrets))
+	for _, s := range secrets {
+		keys[strings.ToLower(s.Key)] = true
+	}
+
+	var findings []Finding
+	for _, s := range secrets {
+		if s.Value == "" {
+			findings = append(findings, Finding{Key: s.Key, Severity: Error,
+				Message: "holds no value; fill it in with 'lockr update'"})
+		}
+		if s.ExpiresAt != nil {
+			if msg, severity, ok := expiry(*s.ExpiresAt, now); ok {
+				findings = append(findings, Finding{Key: s.Key, Severity: severity, Message: msg})
+			}
+		}
+		for _, ref := range refs.References(s.Value) {
+			if !keys[strings.ToLower(ref)] {
+				findings = append(findings, Finding{Key: s.Key, Severity: Error,
+					Message: fmt.Sprintf("refers to missing secret '%s'", ref)})
+			}
+		}
+	}
+	return findings
+}
+
+// Values recognizes credential formats in the secrets' values and checks
+// each credential on its own and against the secrets stored next to it
+func Values(secrets []database.Secret, now time.Time) []Finding {
+	var findings []Finding
+	for _, s := range secrets {
+		format := Recognize(s.Value)
+		if format == nil {
+			continue
+		}
+		for _, f := range format.Check(s.Value, now) {
+			f.Key, f.Format = s.Key, format.Name
+			findings = append(findings, f)
+		}
+		if format == awsAccessKey && !hasAWSSecret(s, secrets) {
+			findings = append(findings, Finding{Key: s.Key, Format: format.Name, Severity: Warning,
+				Message: fmt.Sprintf("no AWS secret access key stored next to it (under %s)", namespaceLabel(s.Key))})
+		}
+	}
+	return findings
+}
+
+// Sort orders findings by key, errors first within a key
+func Sort(findings []Finding) {
+	slices.SortStableFunc(findings, func(a, b Finding) int {
+		if c := strings.Compare(strings.ToLower(a.Key), strings.ToLower(b.Key)); c != 0 {
+			return c
+		}
+		if a.Severity != b.Severity {
+			if a.Severity == Error {
+				return -1
+			}
+			return 1
+		}
+		return 0
+	})
+}
+
+// expiry describes an expiry time that has passed or is near
+func expiry(at, now time.Time) (string, Severity, bool) {
+	switch {
+	case !now.Before(at):
+		return fmt.Sprintf("expired %s ago (%s)", formatAge(now.Sub(at)), at.Local().Format("2006-01-02")), Error, true
+	case at.Sub(now) < ExpiringWithin:
+		return fmt.Sprintf("expires in %s (%s)", formatAge(at.Sub(now)), at.Local().Format("2006-01-02")), Warning, true
+	}
+	return "", "", false
+}
+
+// formatAge renders a duration in the largest whole unit that fits
+func formatAge(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%d days", int(d/(24*time.Hour)))
+	case d >= 2*time.Hour:
+		return fmt.Sprintf("%d hours", int(d/time.Hour))
+	case d >= 2*time.Minute:
+		return fmt.Sprintf("%d minutes", int(d/time.Minute))
+	}
+	return d.Round(time.Second).String()
+}
+
+// namespace returns the part of key before its last slash, or ""
+func namespace(key string) string {
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		return key[:i+1]
+	}
+	return ""
+}
+
+func namespaceLabel(key string) string {
+	if ns := namespace(key); ns != "" {
+		return ns
+	}
+	return "the top level"
+}
@@ -0,0 +1,87 @@
+package merge
+
+import (
+	"crypto/sha256"
+	"sort"
+	"strings"
+
+	"github.com/lockr/go/internal/database"
+)
+
+// Kinds of difference reported by Diff, seen from this vault
+const (
+	OnlyTheirs = "added"
+	OnlyOurs   = "removed"
+	Different  = "changed"
+	Same       = "identical"
+)
+
+// Fields that Diff compares besides the key
+const (
+	FieldValue = "value"
+	FieldTags  = "tags"
+	FieldNotes = "notes"
+)
+
+// Difference describes how one key differs between two vaults. Fields
+// lists what differs for changed keys.
+type Difference struct {
+	Key    string
+	Kind   string
+	Fields []string
+}
+
+// Diff compares every key of this vault (ours) with another (theirs).
+// Values are compared by their SHA-256 hashes so they never need to be
+// held side by side; tags and notes are compared as stored. The result is
+// ordered by key.
+func Diff(ours, theirs []database.Secret) []Difference {
+	other := make(map[string]database.Secret, len(theirs))
+	for _, t := range theirs {
+		other[strings.ToLower(t.Key)] = t
+	}
+
+	var diffs []Difference
+	seen := make(map[string]bool, len(ours))
+	for _, o := range ours {
+		seen[strings.ToLower(o.Key)] = true
+		t, ok := other[strings.ToLower(o.Key)]
+		if !ok {
+			diffs = append(diffs, Difference{Key: o.Key, Kind: OnlyOurs})
+			continue
+		}
+
+		var fields []string
+		if sha256.Sum256([]byte(o.Value)) != sha256.Sum256([]byte(t.Value)) {
+			fields = append(fields, FieldValue)
+		}
+		if deref(o.Tags) != deref(t.Tags) {
+			fields = append(fields, FieldTags)
+		}
+		if deref(o.Notes) != deref(t.Notes) {
+			fields = append(fields, FieldNotes)
+		}
+		kind := Same
+		if len(fields) > 0 {
+			kind = Different
+		}
+		diffs = append(diffs, Difference{Key: o.Key, Kind: kind, Fields: fields})
+	}
+	for _, t := range theirs {
+		if !seen[strings.ToLower(t.Key)] {
+			diffs = append(diffs, Difference{Key: t.Key, Kind: OnlyTheirs})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return strings.ToLower(diffs[i].Key) < strings.ToLower(diffs[j].Key)
+	})
+	return diffs
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,33 @@
+package merge
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lockr/go/internal/database"
+)
+
+func TestDiff(t *testing.T) {
+	tags := "prod"
+	ours := []database.Secret{
+		{Key: "same", Value: "v"},
+		{Key: "Value", Value: "old"},
+		{Key: "tagged", Value: "t", Tags: &tags},
+		{Key: "only-ours", Value: "x"},
+	}
+	theirs := []database.Secret{
+		{Key: "same", Value: "v"},
+		{Key: "value", Value: "new"},
+		{Key: "tagged", Value: "t"},
+		{Key: "only-theirs", Value: "y"},
+	}
+
+	assert.Equal(t, []Difference{
+		{Key: "only-ours", Kind: OnlyOurs},
+		{Key: "only-theirs", Kind: OnlyTheirs},
+		{Key: "same", Kind: Same},
+		{Key: "tagged", Kind: Different, Fields: []string{FieldTags}},
+		{Key: "Value", Kind: Different, Fields: []string{FieldValue}},
+	}, Diff(ours, theirs))
+}
@@ -0,0 +1,184 @@
+// Package merge reconciles the secrets of another vault with this one:
+// new keys are copied, identical ones skipped and conflicting values
+// resolved by a strategy or interactively
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/strength"
+)
+
+// Ways a conflict can be resolved
+const (
+	KeepOurs   = "ours"
+	TakeTheirs = "theirs"
+	Edited     = "edited"
+)
+
+// Non-interactive strategies for resolving every conflict at once
+const (
+	StrategyOurs   = "ours"
+	StrategyTheirs = "theirs"
+	StrategyNewer  = "newer"
+)
+
+// ErrAborted is returned when the user abandons an interactive merge
+var ErrAborted = errors.New("merge aborted")
+
+// Conflict is a key whose value differs between the two vaults
+type Conflict struct {
+	Key    string
+	Ours   database.Secret
+	Theirs database.Secret
+}
+
+// Plan is the outcome of comparing two vaults
+type Plan struct {
+	Added     []database.Secret
+	Identical []string
+	Conflicts []Conflict
+}
+
+// NewPlan compares the secrets of this vault (ours) with another (theirs).
+// Keys match case-insensitively, as they do in the vault.
+func NewPlan(ours, theirs []database.Secret) *Plan {
+	existing := make(map[string]database.Secret, len(ours))
+	for _, s := range ours {
+		existing[strings.ToLower(s.Key)] = s
+	}
+
+	plan := &Plan{}
+	for _, t := range theirs {
+		o, ok := existing[strings.ToLower(t.Key)]
+		switch {
+		case !ok:
+			plan.Added = append(plan.Added, t)
+		case o.Value == t.Value:
+			plan.Identical = append(plan.Identical, o.Key)
+		default:
+			plan.Conflicts = append(plan.Conflicts, Conflict{Key: o.Key, Ours: o, Theirs: t})
+		}
+	}
+	return plan
+}
+
+// Decision records how one conflict was resolved. Value is the value to
+// store for TakeTheirs and Edited, and is never written to reports.
+type Decision struct {
+	Key        string
+	Resolution string
+	Value      string
+}
+
+// Resolve decides every conflict with a non-interactive strategy
+func Resolve(conflicts []Conflict, strategy string) ([]Decision, error) {
+	decisions := make([]Decision, 0, len(conflicts))
+	for _, c := range conflicts {
+		var theirs bool
+		switch strategy {
+		case StrategyOurs:
+		case StrategyTheirs:
+			theirs = true
+		case StrategyNewer:
+			theirs = LastChanged(c.Theirs).After(LastChanged(c.Ours))
+		default:
+			return nil, fmt.Errorf("unknown strategy %q (use %s, %s or %s)", strategy, StrategyOurs, StrategyTheirs, StrategyNewer)
+		}
+
+		if theirs {
+			decisions = append(decisions, Decision{Key: c.Key, Resolution: TakeTheirs, Value: c.Theirs.Value})
+		} else {
+			decisions = append(decisions, Decision{Key: c.Key, Resolution: KeepOurs})
+		}
+	}
+	return decisions, nil
+}
+
+// LastChanged is when a secret's value was last set
+func LastChanged(s database.Secret) time.Time {
+	if s.UpdatedAt != nil {
+		return *s.UpdatedAt
+	}
+	return s.CreatedAt
+}
+
+// Describe summarises a secret's metadata, without its value, as label and
+// value pairs
+func Describe(s database.Secret) [][2]string {
+	tags := "-"
+	if s.Tags != nil && *s.Tags != "" {
+		tags = *s.Tags
+	}
+	bits := strength.Estimate(s.Value)
+	return [][2]string{
+		{"Revision", fmt.Sprintf("%d", s.Revision)},
+		{"Changed", LastChanged(s).Local().Format("2006-01-02 15:04")},
+		{"Created", s.CreatedAt.Local().Format("2006-01-02 15:04")},
+		{"Length", fmt.Sprintf("%d", utf8.RuneCountInString(s.Value))},
+		{"Strength", fmt.Sprintf("%s (~%.0f bits)", strength.Rate(bits), bits)},
+		{"Tags", tags},
+		{"Accessed", fmt.Sprintf("%d times", s.AccessCount)},
+	}
+}
+
+// Report summarises a merge. It names keys but never contains values.
+type Report struct {
+	Source    string
+	Added     []string
+	Identical int
+	Decisions []Decision
+	Failed    map[string]error
+}
+
+// NewReport starts a report for merging from source
+func NewReport(source string, plan *Plan) *Report {
+	r := &Report{Source: source, Identical: len(plan.Identical), Failed: map[string]error{}}
+	for _, s := range plan.Added {
+		r.Added = append(r.Added, s.Key)
+	}
+	return r
+}
+
+// Write prints the report as text
+func (r *Report) Write(w io.Writer) {
+	counts := map[string]int{}
+	for _, d := range r.Decisions {
+		counts[d.Resolution]++
+	}
+
+	fmt.Fprintf(w, "Merge from %s\n", r.Source)
+	fmt.Fprintf(w, "  Added:     %d\n", len(r.Added))
+	fmt.Fprintf(w, "  Identical: %d\n", r.Identical)
+	fmt.Fprintf(w, "  Conflicts: %d (kept ours %d, took theirs %d, edited %d)\n",
+		len(r.Decisions), counts[KeepOurs], counts[TakeTheirs], counts[Edited])
+	if len(r.Failed) > 0 {
+		fmt.Fprintf(w, "  Failed:    %d\n", len(r.Failed))
+	}
+
+	if len(r.Added) > 0 {
+		fmt.Fprintln(w, "\nAdded:")
+		for _, key := range r.Added {
+			fmt.Fprintf(w, "  + %s%s\n", key, r.failure(key))
+		}
+	}
+	if len(r.Decisions) > 0 {
+		fmt.Fprintln(w, "\nConflicts:")
+		for _, d := range r.Decisions {
+			fmt.Fprintf(w, "  %-6s %s%s\n", d.Resolution, d.Key, r.failure(d.Key))
+		}
+	}
+}
+
+func (r *Report) failure(key string) string {
+	if err, ok := r.Failed[key]; ok {
+		return fmt.Sprintf("  (FAILED: %v)", err)
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package merge
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lockr/go/internal/database"
+)
+
+func TestNewPlan(t *testing.T) {
+	ours := []database.Secret{
+		{Key: "same", Value: "v"},
+		{Key: "Changed", Value: "old"},
+		{Key: "only-ours", Value: "x"},
+	}
+	theirs := []database.Secret{
+		{Key: "same", Value: "v"},
+		{Key: "changed", Value: "new"},
+		{Key: "only-theirs", Value: "y"},
+	}
+
+	plan := NewPlan(ours, theirs)
+	require.Len(t, plan.Added, 1)
+	assert.Equal(t, "only-theirs", plan.Added[0].Key)
+	assert.Equal(t, []string{"same"}, plan.Identical)
+	require.Len(t, plan.Conflicts, 1)
+	assert.Equal(t, "Changed", plan.Conflicts[0].Key)
+	assert.Equal(t, "new", plan.Conflicts[0].Theirs.Value)
+}
+
+func TestResolve(t *testing.T) {
+	older := time.Now().Add(-time.Hour)
+	newer := time.Now()
+	conflicts := []Conflict{
+		{Key: "a", Ours: database.Secret{Value: "o1", UpdatedAt: &newer}, Theirs: database.Secret{Value: "t1", UpdatedAt: &older}},
+		{Key: "b", Ours: database.Secret{Value: "o2", CreatedAt: older}, Theirs: database.Secret{Value: "t2", CreatedAt: newer}},
+	}
+
+	decisions, err := Resolve(conflicts, StrategyNewer)
+	require.NoError(t, err)
+	assert.Equal(t, []Decision{
+		{Key: "a", Resolution: KeepOurs},
+		{Key: "b", Resolution: TakeTheirs, Value: "t2"},
+	}, decisions)
+
+	decisions, err = Resolve(conflicts, StrategyTheirs)
+	require.NoError(t, err)
+	assert.Equal(t, TakeTheirs, decisions[0].Resolution)
+	assert.Equal(t, "t1", decisions[0].Value)
+
+	_, err = Resolve(conflicts, "coinflip")
+	assert.Error(t, err)
+}
+
+func TestReport_Write(t *testing.T) {
+	plan := &Plan{
+		Added:     []database.Secret{{Key: "new", Value: "secret-new"}},
+		Identical: []string{"same"},
+	}
+	report := NewReport("other.lockr", plan)
+	report.Decisions = []Decision{
+		{Key: "a", Resolution: KeepOurs},
+		{Key: "b", Resolution: Edited, Value: "secret-edited"},
+	}
+	report.Failed["b"] = errors.New("disk full")
+
+	var buf bytes.Buffer
+	report.Write(&buf)
+	out := buf.String()
+
+	assert.Contains(t, out, "Added:     1")
+	assert.Contains(t, out, "kept ours 1, took theirs 0, edited 1")
+	assert.Contains(t, out, "+ new")
+	assert.Contains(t, out, "FAILED: disk full")
+	assert.NotContains(t, out, "secret-")
+}
@@ -0,0 +1,321 @@
+//go:build !minimal
+
+package merge
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/lockr/go/internal/keymap"
+)
+
+// InteractiveAvailable reports whether this build includes the merge UI
+const InteractiveAvailable = true
+
+// resolverStyles defines the visual styling for the merge UI
+type resolverStyles struct {
+	Title    lipgloss.Style
+	Key      lipgloss.Style
+	Label    lipgloss.Style
+	Side     lipgloss.Style
+	Chosen   lipgloss.Style
+	Value    lipgloss.Style
+	Help     lipgloss.Style
+	Progress lipgloss.Style
+	Overlay  lipgloss.Style
+}
+
+func defaultResolverStyles() resolverStyles {
+	return resolverStyles{
+		Title: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("32")). // Green
+			Bold(true),
+		Key: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("220")). // Yellow
+			Bold(true),
+		Label: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")), // Gray
+		Side: lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("238")).
+			Padding(0, 1).
+			Width(38),
+		Chosen: lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("14")). // Cyan
+			Padding(0, 1).
+			Width(38),
+		Value: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("11")), // Bright yellow
+		Help: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("242")), // Dark gray
+		Progress: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")), // Gray
+		Overlay: lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("238")).
+			Padding(0, 1),
+	}
+}
+
+// ResolverModel is the Bubble Tea model for resolving conflicts one by one
+type ResolverModel struct {
+	conflicts []Conflict
+	decisions []*Decision
+	revealed  map[int]bool
+	current   int
+	editing   bool
+	input     string
+	done      bool
+	aborted   bool
+	help      bool
+	keys      keymap.Keymap
+	styles    resolverStyles
+}
+
+// NewResolverModel creates a merge UI for the given conflicts
+func NewResolverModel(conflicts []Conflict) ResolverModel {
+	return ResolverModel{
+		conflicts: conflicts,
+		decisions: make([]*Decision, len(conflicts)),
+		revealed:  map[int]bool{},
+		keys:      keymap.Merge(),
+		styles:    defaultResolverStyles(),
+	}
+}
+
+// Init initializes the model (required by Bubble Tea)
+func (m ResolverModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles key presses
+func (m ResolverModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	if key.String() == keymap.Quit {
+		m.aborted = true
+		return m, tea.Quit
+	}
+
+	if m.editing {
+		return m.updateEditing(key)
+	}
+
+	action := m.keys.Action(key.String())
+
+	// The help overlay swallows keys until it is closed
+	if m.help {
+		if action == keymap.Help || action == keymap.Cancel {
+			m.help = false
+		}
+		return m, nil
+	}
+
+	switch action {
+	case keymap.Cancel:
+		m.aborted = true
+		return m, tea.Quit
+
+	case keymap.Up:
+		m.move(-1)
+
+	case keymap.Down:
+		m.move(1)
+
+	case keymap.KeepOurs:
+		m.decide(Decision{Key: m.conflicts[m.current].Key, Resolution: KeepOurs})
+
+	case keymap.TakeTheirs:
+		c := m.conflicts[m.current]
+		m.decide(Decision{Key: c.Key, Resolution: TakeTheirs, Value: c.Theirs.Value})
+
+	case keymap.Edit:
+		m.editing = true
+		m.input = ""
+
+	case keymap.Reveal:
+		m.revealed[m.current] = !m.revealed[m.current]
+
+	case keymap.Accept:
+		if m.Undecided() == 0 {
+			m.done = true
+			return m, tea.Quit
+		}
+
+	case keymap.Help:
+		m.help = true
+	}
+
+	return m, nil
+}
+
+// updateEditing handles key presses while a replacement value is typed
+func (m ResolverModel) updateEditing(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch key.Type {
+	case tea.KeyEsc:
+		m.editing = false
+		m.input = ""
+	case tea.KeyEnter:
+		if m.input != "" {
+			m.editing = false
+			m.decide(Decision{Key: m.conflicts[m.current].Key, Resolution: Edited, Value: m.input})
+			m.input = ""
+		}
+	case tea.KeyBackspace:
+		if len(m.input) > 0 {
+			_, size := utf8.DecodeLastRuneInString(m.input)
+			m.input = m.input[:len(m.input)-size]
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		for _, r := range key.Runes {
+			if unicode.IsPrint(r) {
+				m.input += string(r)
+			}
+		}
+	}
+	return m, nil
+}
+
+// decide records a decision for the current conflict and moves on to the
+// next undecided one
+func (m *ResolverModel) decide(d Decision) {
+	m.decisions[m.current] = &d
+	m.revealed[m.current] = false
+	for i := 1; i <= len(m.conflicts); i++ {
+		next := (m.current + i) % len(m.conflicts)
+		if m.decisions[next] == nil {
+			m.current = next
+			return
+		}
+	}
+}
+
+func (m *ResolverModel) move(direction int) {
+	m.current = (m.current + direction + len(m.conflicts)) % len(m.conflicts)
+}
+
+// Undecided returns how many conflicts still need a decision
+func (m ResolverModel) Undecided() int {
+	n := 0
+	for _, d := range m.decisions {
+		if d == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Decisions returns the decisions made, in conflict order
+func (m ResolverModel) Decisions() []Decision {
+	var out []Decision
+	for _, d := range m.decisions {
+		if d != nil {
+			out = append(out, *d)
+		}
+	}
+	return out
+}
+
+// View renders the current conflict
+func (m ResolverModel) View() string {
+	if m.done || m.aborted {
+		return ""
+	}
+
+	if m.help {
+		return m.styles.Overlay.Render(m.keys.Overlay()) + "\n"
+	}
+
+	c := m.conflicts[m.current]
+	decision := m.decisions[m.current]
+
+	var b strings.Builder
+	b.WriteString(m.styles.Title.Render("Resolve merge conflicts"))
+	b.WriteString(m.styles.Progress.Render(fmt.Sprintf("  %d of %d, %d undecided", m.current+1, len(m.conflicts), m.Undecided())))
+	b.WriteString("\n\n")
+	b.WriteString(m.styles.Key.Render(c.Key))
+	if decision != nil {
+		b.WriteString(m.styles.Label.Render("  → " + decision.Resolution))
+	}
+	b.WriteString("\n")
+
+	ours := m.renderSide("This vault ("+m.keys.Label(keymap.KeepOurs)+")", c.Ours.Value, Describe(c.Ours), decision != nil && decision.Resolution == KeepOurs)
+	theirs := m.renderSide("Other vault ("+m.keys.Label(keymap.TakeTheirs)+")", c.Theirs.Value, Describe(c.Theirs), decision != nil && decision.Resolution == TakeTheirs)
+	b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, ours, " ", theirs))
+	b.WriteString("\n")
+
+	if m.editing {
+		b.WriteString(m.styles.Label.Render("New value: "))
+		b.WriteString(strings.Repeat("•", utf8.RuneCountInString(m.input)))
+		b.WriteString("\n")
+		b.WriteString(m.styles.Help.Render("enter save • esc cancel"))
+	} else {
+		k := m.keys
+		help := fmt.Sprintf("%s keep ours • %s take theirs • %s edit • %s reveal • %s/%s move • %s abort • %s help",
+			k.Label(keymap.KeepOurs), k.Label(keymap.TakeTheirs), k.Label(keymap.Edit), k.Label(keymap.Reveal),
+			k.Label(keymap.Up), k.Label(keymap.Down), k.Label(keymap.Cancel), k.Label(keymap.Help))
+		if m.Undecided() == 0 {
+			help = k.Label(keymap.Accept) + " apply • " + help
+		}
+		b.WriteString(m.styles.Help.Render(help))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// renderSide renders one version's metadata, and its value only when the
+// user asked to reveal this entry
+func (m ResolverModel) renderSide(title, value string, meta [][2]string, chosen bool) string {
+	var b strings.Builder
+	b.WriteString(m.styles.Title.Render(title))
+	for _, field := range meta {
+		b.WriteString("\n")
+		b.WriteString(m.styles.Label.Render(fmt.Sprintf("%-9s", field[0])))
+		b.WriteString(" " + field[1])
+	}
+	b.WriteString("\n")
+	b.WriteString(m.styles.Label.Render(fmt.Sprintf("%-9s", "Value")))
+	if m.revealed[m.current] {
+		b.WriteString(" " + m.styles.Value.Render(value))
+	} else {
+		b.WriteString(" (hidden, " + m.keys.Label(keymap.Reveal) + " to reveal)")
+	}
+
+	if chosen {
+		return m.styles.Chosen.Render(b.String())
+	}
+	return m.styles.Side.Render(b.String())
+}
+
+// RunResolver lets the user resolve each conflict and returns the
+// decisions, or ErrAborted if they quit. A zero keys uses keymap.Merge.
+func RunResolver(conflicts []Conflict, keys keymap.Keymap) ([]Decision, error) {
+	if len(conflicts) == 0 {
+		return nil, nil
+	}
+
+	m := NewResolverModel(conflicts)
+	if keys.Bindings != nil {
+		m.keys = keys
+	}
+	program := tea.NewProgram(m)
+	finalModel, err := program.Run()
+	if err != nil {
+		return nil, fmt.Errorf("error running merge UI: %w", err)
+	}
+
+	final := finalModel.(ResolverModel)
+	if final.aborted || !final.done {
+		return nil, ErrAborted
+	}
+	return final.Decisions(), nil
+}
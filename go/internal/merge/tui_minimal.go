@@ -0,0 +1,21 @@
+//go:build minimal
+
+package merge
+
+import (
+	"errors"
+
+	"github.com/lockr/go/internal/keymap"
+)
+
+// ErrInteractiveUnavailable is returned by RunResolver in builds made with
+// the minimal tag, which leave out the terminal UI
+var ErrInteractiveUnavailable = errors.New("interactive merge is not available in this build; use --strategy")
+
+// InteractiveAvailable reports whether this build includes the merge UI
+const InteractiveAvailable = false
+
+// RunResolver always fails in minimal builds
+func RunResolver(conflicts []Conflict, keys keymap.Keymap) ([]Decision, error) {
+	return nil, ErrInteractiveUnavailable
+}
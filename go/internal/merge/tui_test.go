@@ -0,0 +1,86 @@
+//go:build !minimal
+
+package merge
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lockr/go/internal/database"
+)
+
+func press(m ResolverModel, keys ...string) ResolverModel {
+	for _, k := range keys {
+		var msg tea.KeyMsg
+		switch k {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+		next, _ := m.Update(msg)
+		m = next.(ResolverModel)
+	}
+	return m
+}
+
+func TestResolverModel(t *testing.T) {
+	conflicts := []Conflict{
+		{Key: "a", Ours: database.Secret{Key: "a", Value: "ours-a"}, Theirs: database.Secret{Key: "a", Value: "theirs-a"}},
+		{Key: "b", Ours: database.Secret{Key: "b", Value: "ours-b"}, Theirs: database.Secret{Key: "b", Value: "theirs-b"}},
+		{Key: "c", Ours: database.Secret{Key: "c", Value: "ours-c"}, Theirs: database.Secret{Key: "c", Value: "theirs-c"}},
+	}
+	m := NewResolverModel(conflicts)
+
+	// Values stay hidden until revealed for the current entry
+	assert.NotContains(t, m.View(), "ours-a")
+	m = press(m, "r")
+	assert.Contains(t, m.View(), "ours-a")
+	assert.Contains(t, m.View(), "theirs-a")
+
+	m = press(m, "t") // a: theirs, moves to b
+	assert.NotContains(t, m.View(), "ours-b")
+	m = press(m, "o") // b: ours, moves to c
+
+	// Enter does nothing while conflicts are undecided
+	m = press(m, "enter")
+	assert.False(t, m.done)
+
+	m = press(m, "e", "n", "e", "w", "enter")
+	assert.Equal(t, 0, m.Undecided())
+	assert.NotContains(t, m.View(), "new")
+
+	m = press(m, "enter")
+	assert.True(t, m.done)
+	assert.Equal(t, []Decision{
+		{Key: "a", Resolution: TakeTheirs, Value: "theirs-a"},
+		{Key: "b", Resolution: KeepOurs},
+		{Key: "c", Resolution: Edited, Value: "new"},
+	}, m.Decisions())
+}
+
+func TestResolverModel_Abort(t *testing.T) {
+	m := NewResolverModel([]Conflict{{Key: "a"}})
+	m = press(m, "e", "x", "esc")
+	assert.False(t, m.editing)
+	assert.Equal(t, 1, m.Undecided())
+
+	m = press(m, "q")
+	assert.True(t, m.aborted)
+}
+
+func TestResolverModel_Help(t *testing.T) {
+	m := NewResolverModel([]Conflict{{Key: "a"}})
+	m = press(m, "?")
+	assert.Contains(t, m.View(), "Keep this vault's value")
+
+	// Keys do nothing while the overlay is open; esc closes it
+	m = press(m, "o", "esc")
+	assert.Equal(t, 1, m.Undecided())
+	assert.False(t, m.aborted)
+	assert.Contains(t, m.View(), "? help")
+}
@@ -0,0 +1,269 @@
+// Package paths decides where lockr keeps its files. It follows the XDG
+// base directory specification on Linux and other Unix systems and the
+// platform conventions on macOS and Windows, and moves files out of the
+// ~/.lockr directory used by earlier versions.
+package paths
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// appName names lockr's directory inside each base directory
+const appName = "lockr"
+
+// Dirs are the directories lockr keeps its files in
+type Dirs struct {
+	// Config holds config.yml
+	Config string
+	// Data holds the default vault and trusted workspaces
+	Data string
+	// State holds logs and other state worth keeping across runs
+	State string
+	// Cache holds files that are safe to delete, such as the value cache
+	Cache string
+	// Runtime holds sockets; it is $XDG_RUNTIME_DIR when set, else State
+	Runtime string
+	// Legacy is ~/.lockr, where everything lived before
+	Legacy string
+}
+
+// Default returns the directories for the current user and platform
+func Default() Dirs {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = ""
+	}
+	return Resolve(runtime.GOOS, home, os.Getenv)
+}
+
+// Resolve returns the directories for goos and the user's home directory.
+// Explicitly set XDG variables win on every platform; without them macOS
+// uses ~/Library and Windows uses %APPDATA% and %LOCALAPPDATA%. With no home
+// directory everything lives in the current directory.
+func Resolve(goos, home string, getenv func(string) string) Dirs {
+	if home == "" {
+		return Dirs{}
+	}
+
+	var config, data, state, cache string
+	switch goos {
+	case "darwin":
+		support := filepath.Join(home, "Library", "Application Support")
+		config, data, state = support, support, support
+		cache = filepath.Join(home, "Library", "Caches")
+	case "windows":
+		config = getenv("APPDATA")
+		if config == "" {
+			config = filepath.Join(home, "AppData", "Roaming")
+		}
+		data = getenv("LOCALAPPDATA")
+		if data == "" {
+			data = filepath.Join(home, "AppData", "Local")
+		}
+		state, cache = data, data
+	default:
+		config = filepath.Join(home, ".config")
+		data = filepath.Join(home, ".local", "share")
+		state = filepath.Join(home, ".local", "state")
+		cache = filepath.Join(home, ".cache")
+	}
+
+	state = xdg(getenv, "XDG_STATE_HOME", state)
+	return Dirs{
+		Config:  filepath.Join(xdg(getenv, "XDG_CONFIG_HOME", config), appName),
+		Data:    filepath.Join(xdg(getenv, "XDG_DATA_HOME", data), appName),
+		State:   filepath.Join(state, appName),
+		Cache:   filepath.Join(xdg(getenv, "XDG_CACHE_HOME", cache), appName),
+		Runtime: filepath.Join(xdg(getenv, "XDG_RUNTIME_DIR", state), appName),
+		Legacy:  filepath.Join(home, ".lockr"),
+	}
+}
+
+// xdg returns the value of an XDG variable, or fallback if it is unset or
+// not absolute, which the specification says to ignore
+func xdg(getenv func(string) string, name, fallback string) string {
+	if dir := getenv(name); dir != "" && filepath.IsAbs(dir) {
+		return dir
+	}
+	return fallback
+}
+
+// VaultFile is the default vault
+func (d Dirs) VaultFile() string {
+	return filepath.Join(d.Data, "vault.lockr")
+}
+
+// ConfigFile is the default configuration file
+func (d Dirs) ConfigFile() string {
+	return filepath.Join(d.Config, "config.yml")
+}
+
+// TrustFile lists the workspaces the user trusts
+func (d Dirs) TrustFile() string {
+	return filepath.Join(d.Data, "trusted-workspaces")
+}
+
+// ValueCacheDir holds the encrypted value caches
+func (d Dirs) ValueCacheDir() string {
+	return filepath.Join(d.Cache, "values")
+}
+
+// AgentSocket is where 'lockr agent' listens
+func (d Dirs) AgentSocket() string {
+	return filepath.Join(d.Runtime, "agent.sock")
+}
+
+// ProfileAgentSocket is where the agent of a profile listens, apart from
+// the agent of every other vault
+func (d Dirs) ProfileAgentSocket(profile string) string {
+	return filepath.Join(d.Runtime, "agent-"+url.PathEscape(profile)+".sock")
+}
+
+// APISocket is where 'lockr serve' listens by default
+func (d Dirs) APISocket() string {
+	return filepath.Join(d.Runtime, "api.sock")
+}
+
+// ProfileAPISocket is where 'lockr serve' listens by default for the vault
+// of a profile
+func (d Dirs) ProfileAPISocket(profile string) string {
+	return filepath.Join(d.Runtime, "api-"+url.PathEscape(profile)+".sock")
+}
+
+// SessionFile remembers the session of the vault identified by vaultID
+// between commands
+func (d Dirs) SessionFile(vaultID string) string {
+	return filepath.Join(d.State, "sessions", vaultID)
+}
+
+// OwnFiles lists the files and directories lockr creates for itself apart
+// from the vault, the config and the trust list: value caches, session
+// files and sockets. Only those that exist are listed. The base directories
+// are never listed, since on macOS and Windows they are shared with the
+// vault.
+func (d Dirs) OwnFiles() []string {
+	var found []string
+	for _, path := range []string{d.ValueCacheDir(), filepath.Dir(d.SessionFile("x"))} {
+		if _, err := os.Lstat(path); err == nil {
+			found = append(found, path)
+		}
+	}
+	for _, pattern := range []string{"agent*.sock", "api*.sock"} {
+		matches, _ := filepath.Glob(filepath.Join(d.Runtime, pattern))
+		found = append(found, matches...)
+	}
+	return found
+}
+
+// Encloses reports whether dir is path or one of the directories above it
+func Encloses(dir, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Move is a file moved out of the legacy directory
+type Move struct {
+	From string
+	To   string
+}
+
+// legacyMoves lists where each file of the legacy directory belongs
+func (d Dirs) legacyMoves() []Move {
+	vault := filepath.Join(d.Legacy, "vault.lockr")
+	moves := []Move{
+		{vault, d.VaultFile()},
+		{filepath.Join(d.Legacy, "config.yml"), d.ConfigFile()},
+		{filepath.Join(d.Legacy, "trusted-workspaces"), d.TrustFile()},
+	}
+	// SQLite side files must travel with the vault
+	for _, suffix := range []string{"-journal", "-wal", "-shm"} {
+		moves = append(moves, Move{vault + suffix, d.VaultFile() + suffix})
+	}
+	return moves
+}
+
+// Migrate moves files from the legacy directory to their new homes, once.
+// Files whose destination already exists are left where they are. The
+// legacy value cache is deleted, and the legacy directory too if nothing
+// else is left in it. It returns the moves made, which are complete up to
+// the first error.
+func (d Dirs) Migrate() ([]Move, error) {
+	return d.migrate(false)
+}
+
+// MigrateExceptVault is Migrate leaving the vault and its side files where
+// they are, for while the vault in use is not known yet or when something
+// still names the legacy vault. Migrate moves them later.
+func (d Dirs) MigrateExceptVault() ([]Move, error) {
+	return d.migrate(true)
+}
+
+func (d Dirs) migrate(keepVault bool) ([]Move, error) {
+	if d.Legacy == "" {
+		return nil, nil
+	}
+	if _, err := os.Stat(d.Legacy); err != nil {
+		return nil, nil
+	}
+
+	var done []Move
+	for _, m := range d.legacyMoves() {
+		if keepVault && strings.HasPrefix(m.To, d.VaultFile()) {
+			continue
+		}
+		if _, err := os.Stat(m.From); err != nil {
+			continue
+		}
+		if _, err := os.Stat(m.To); err == nil {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(m.To), 0700); err != nil {
+			return done, err
+		}
+		if err := os.Rename(m.From, m.To); err != nil {
+			return done, fmt.Errorf("failed to move %s: %w", m.From, err)
+		}
+		done = append(done, m)
+	}
+
+	if err := os.RemoveAll(filepath.Join(d.Legacy, "cache")); err != nil {
+		return done, err
+	}
+	os.Remove(d.Legacy) // Fails, harmlessly, if other files are left
+	return done, nil
+}
+
+// InLegacy reports whether path names a file in the legacy directory
+func (d Dirs) InLegacy(path string) bool {
+	if d.Legacy == "" || path == "" {
+		return false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(d.Legacy, abs)
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..")
+}
+
+// Unmigrated returns where the file that belongs at path still is, if
+// Migrate could not move it out of the legacy directory
+func (d Dirs) Unmigrated(path string) (string, bool) {
+	if _, err := os.Stat(path); err == nil {
+		return "", false
+	}
+	for _, m := range d.legacyMoves() {
+		if m.To != path {
+			continue
+		}
+		if _, err := os.Stat(m.From); err == nil {
+			return m.From, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,179 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+func TestResolve_Linux(t *testing.T) {
+	dirs := Resolve("linux", "/home/alice", env(nil))
+	assert.Equal(t, "/home/alice/.config/lockr", dirs.Config)
+	assert.Equal(t, "/home/alice/.local/share/lockr", dirs.Data)
+	assert.Equal(t, "/home/alice/.local/state/lockr", dirs.State)
+	assert.Equal(t, "/home/alice/.cache/lockr", dirs.Cache)
+	assert.Equal(t, "/home/alice/.lockr", dirs.Legacy)
+	assert.Equal(t, "/home/alice/.local/share/lockr/vault.lockr", dirs.VaultFile())
+	assert.Equal(t, "/home/alice/.config/lockr/config.yml", dirs.ConfigFile())
+	assert.Equal(t, "/home/alice/.local/state/lockr/agent.sock", dirs.AgentSocket())
+	assert.Equal(t, "/home/alice/.local/state/lockr/sessions/abc", dirs.SessionFile("abc"))
+
+	dirs = Resolve("linux", "/home/alice", env(map[string]string{
+		"XDG_CONFIG_HOME": "/cfg",
+		"XDG_DATA_HOME":   "/data",
+		"XDG_STATE_HOME":  "relative/is/ignored",
+		"XDG_CACHE_HOME":  "/tmp/cache",
+		"XDG_RUNTIME_DIR": "/run/user/1000",
+	}))
+	assert.Equal(t, "/cfg/lockr", dirs.Config)
+	assert.Equal(t, "/data/lockr", dirs.Data)
+	assert.Equal(t, "/home/alice/.local/state/lockr", dirs.State)
+	assert.Equal(t, "/tmp/cache/lockr", dirs.Cache)
+	assert.Equal(t, "/run/user/1000/lockr/agent.sock", dirs.AgentSocket())
+	assert.Equal(t, "/run/user/1000/lockr/agent-my%20work.sock", dirs.ProfileAgentSocket("my work"))
+}
+
+func TestResolve_Darwin(t *testing.T) {
+	dirs := Resolve("darwin", "/Users/alice", env(nil))
+	assert.Equal(t, "/Users/alice/Library/Application Support/lockr", dirs.Config)
+	assert.Equal(t, "/Users/alice/Library/Application Support/lockr", dirs.Data)
+	assert.Equal(t, "/Users/alice/Library/Caches/lockr", dirs.Cache)
+
+	dirs = Resolve("darwin", "/Users/alice", env(map[string]string{"XDG_CONFIG_HOME": "/Users/alice/.config"}))
+	assert.Equal(t, "/Users/alice/.config/lockr", dirs.Config)
+}
+
+func TestOwnFiles_Darwin(t *testing.T) {
+	// Data and State are the same directory on macOS
+	dirs := Resolve("darwin", t.TempDir(), env(nil))
+	require.Equal(t, dirs.Data, dirs.State)
+	require.NoError(t, os.MkdirAll(dirs.Data, 0700))
+	require.NoError(t, os.WriteFile(dirs.VaultFile(), []byte("vault"), 0600))
+	require.NoError(t, os.WriteFile(dirs.TrustFile(), []byte("trust"), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Dir(dirs.SessionFile("abc")), 0700))
+	require.NoError(t, os.WriteFile(dirs.SessionFile("abc"), []byte("session"), 0600))
+	require.NoError(t, os.MkdirAll(dirs.ValueCacheDir(), 0700))
+	require.NoError(t, os.WriteFile(dirs.AgentSocket(), nil, 0600))
+
+	own := dirs.OwnFiles()
+	assert.ElementsMatch(t, []string{dirs.ValueCacheDir(), filepath.Dir(dirs.SessionFile("abc")), dirs.AgentSocket()}, own)
+	for _, path := range own {
+		assert.False(t, Encloses(path, dirs.VaultFile()), path)
+		require.NoError(t, os.RemoveAll(path))
+	}
+
+	// The vault and the trust list survive
+	_, err := os.Stat(dirs.VaultFile())
+	assert.NoError(t, err)
+	_, err = os.Stat(dirs.TrustFile())
+	assert.NoError(t, err)
+	assert.True(t, Encloses(dirs.State, dirs.VaultFile()))
+	assert.False(t, Encloses(filepath.Join(dirs.Data, "vault"), dirs.VaultFile()))
+}
+
+func TestResolve_NoHome(t *testing.T) {
+	dirs := Resolve("linux", "", env(nil))
+	assert.Equal(t, "vault.lockr", dirs.VaultFile())
+	assert.Equal(t, "config.yml", dirs.ConfigFile())
+
+	moves, err := dirs.Migrate()
+	assert.NoError(t, err)
+	assert.Empty(t, moves)
+}
+
+func TestMigrate(t *testing.T) {
+	home := t.TempDir()
+	dirs := Resolve("linux", home, env(nil))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dirs.Legacy, "cache"), 0700))
+	for _, name := range []string{"vault.lockr", "vault.lockr-wal", "config.yml", "cache/x.cache"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dirs.Legacy, name), []byte(name), 0600))
+	}
+
+	legacyVault, ok := dirs.Unmigrated(dirs.VaultFile())
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(dirs.Legacy, "vault.lockr"), legacyVault)
+
+	moves, err := dirs.Migrate()
+	require.NoError(t, err)
+	assert.Len(t, moves, 3)
+
+	data, err := os.ReadFile(dirs.VaultFile())
+	require.NoError(t, err)
+	assert.Equal(t, "vault.lockr", string(data))
+	assert.FileExists(t, dirs.VaultFile()+"-wal")
+	assert.FileExists(t, dirs.ConfigFile())
+
+	// Nothing left, so the legacy directory is gone
+	assert.NoDirExists(t, dirs.Legacy)
+	_, ok = dirs.Unmigrated(dirs.VaultFile())
+	assert.False(t, ok)
+
+	// Running again does nothing
+	moves, err = dirs.Migrate()
+	require.NoError(t, err)
+	assert.Empty(t, moves)
+}
+
+func TestMigrate_KeepsExisting(t *testing.T) {
+	home := t.TempDir()
+	dirs := Resolve("linux", home, env(nil))
+
+	require.NoError(t, os.MkdirAll(dirs.Legacy, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dirs.Legacy, "vault.lockr"), []byte("old"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dirs.Legacy, "notes.txt"), []byte("mine"), 0600))
+	require.NoError(t, os.MkdirAll(dirs.Data, 0700))
+	require.NoError(t, os.WriteFile(dirs.VaultFile(), []byte("new"), 0600))
+
+	moves, err := dirs.Migrate()
+	require.NoError(t, err)
+	assert.Empty(t, moves)
+
+	data, err := os.ReadFile(dirs.VaultFile())
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+	assert.FileExists(t, filepath.Join(dirs.Legacy, "vault.lockr"))
+}
+
+func TestMigrateExceptVault(t *testing.T) {
+	home := t.TempDir()
+	dirs := Resolve("linux", home, env(nil))
+
+	require.NoError(t, os.MkdirAll(dirs.Legacy, 0700))
+	for _, name := range []string{"vault.lockr", "vault.lockr-wal", "config.yml"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dirs.Legacy, name), []byte(name), 0600))
+	}
+
+	// The vault was named explicitly, so it and its side files stay
+	moves, err := dirs.MigrateExceptVault()
+	require.NoError(t, err)
+	assert.Equal(t, []Move{{filepath.Join(dirs.Legacy, "config.yml"), dirs.ConfigFile()}}, moves)
+	assert.FileExists(t, filepath.Join(dirs.Legacy, "vault.lockr"))
+	assert.FileExists(t, filepath.Join(dirs.Legacy, "vault.lockr-wal"))
+	assert.NoFileExists(t, dirs.VaultFile())
+
+	// A later run without an explicit vault moves it
+	moves, err = dirs.Migrate()
+	require.NoError(t, err)
+	assert.Len(t, moves, 2)
+	assert.FileExists(t, dirs.VaultFile())
+	assert.NoDirExists(t, dirs.Legacy)
+}
+
+func TestInLegacy(t *testing.T) {
+	dirs := Resolve("linux", "/home/u", env(nil))
+
+	assert.True(t, dirs.InLegacy("/home/u/.lockr/vault.lockr"))
+	assert.True(t, dirs.InLegacy("/home/u/.lockr/../.lockr/work.lockr"))
+	assert.False(t, dirs.InLegacy("/home/u/.lockr"))
+	assert.False(t, dirs.InLegacy("/home/u/.lockr-old/vault.lockr"))
+	assert.False(t, dirs.InLegacy(dirs.VaultFile()))
+	assert.False(t, dirs.InLegacy(""))
+}
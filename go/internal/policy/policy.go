@@ -0,0 +1,238 @@
+// Package policy maps tags and key namespaces to per-secret behaviour
+// defaults, such as how values are generated and how long they stay on the
+// clipboard
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Rule sets defaults for secrets with a tag or under a key namespace. Zero
+// fields leave the corresponding default alone.
+type Rule struct {
+	Tag       string `json:"tag,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+
+	Length     int      `json:"length,omitempty"`
+	Symbols    *bool    `json:"symbols,omitempty"`
+	Words      int      `json:"words,omitempty"`
+	ClearAfter Duration `json:"clear_after,omitempty"`
+
+	// ClipboardOnly secrets are never printed, only copied to the clipboard
+	// for at most ClipboardOnlyMaxDelay
+	ClipboardOnly bool `json:"clipboard_only,omitempty"`
+
+	// Sensitive secrets need the vault password typed again before the
+	// picker reveals them
+	Sensitive bool `json:"sensitive,omitempty"`
+}
+
+// ClipboardOnlyMaxDelay is the longest a clipboard-only value may stay on
+// the clipboard
+const ClipboardOnlyMaxDelay = 20 * time.Second
+
+// ErrClipboardOnly is returned when a clipboard-only value would be printed
+var ErrClipboardOnly = errors.New("secret is clipboard-only and cannot be printed")
+
+// Duration is a time.Duration stored in its string form ("20s")
+type Duration time.Duration
+
+// MarshalJSON encodes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decodes a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Validate reports whether the rule selects something and sets something
+func (r Rule) Validate() error {
+	if r.Tag == "" && r.Namespace == "" {
+		return errors.New("rule needs a tag or a namespace")
+	}
+	if r.Tag != "" && r.Namespace != "" {
+		return errors.New("rule can match a tag or a namespace, not both")
+	}
+	if r.Length == 0 && r.Symbols == nil && r.Words == 0 && r.ClearAfter == 0 && !r.ClipboardOnly && !r.Sensitive {
+		return errors.New("rule sets no defaults")
+	}
+	if r.Length != 0 && r.Words != 0 {
+		return errors.New("rule cannot set both a length and a word count")
+	}
+	if r.Length < 0 || r.Words < 0 || r.ClearAfter < 0 {
+		return errors.New("rule values must be positive")
+	}
+	return nil
+}
+
+// Matches reports whether the rule applies to key with the given tags
+func (r Rule) Matches(key string, tags []string) bool {
+	if r.Namespace != "" {
+		return strings.HasPrefix(strings.ToLower(key), strings.ToLower(r.Namespace))
+	}
+	for _, tag := range tags {
+		if strings.EqualFold(tag, r.Tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Selector describes what the rule matches, for display
+func (r Rule) Selector() string {
+	if r.Namespace != "" {
+		return "namespace " + r.Namespace
+	}
+	return "tag " + r.Tag
+}
+
+// Summary describes the defaults the rule sets, for display
+func (r Rule) Summary() string {
+	var parts []string
+	if r.Length != 0 {
+		parts = append(parts, fmt.Sprintf("length %d", r.Length))
+	}
+	if r.Symbols != nil {
+		if *r.Symbols {
+			parts = append(parts, "symbols on")
+		} else {
+			parts = append(parts, "symbols off")
+		}
+	}
+	if r.Words != 0 {
+		parts = append(parts, fmt.Sprintf("passphrase of %d words", r.Words))
+	}
+	if r.ClearAfter != 0 {
+		parts = append(parts, fmt.Sprintf("clipboard clear %v", time.Duration(r.ClearAfter)))
+	}
+	if r.ClipboardOnly {
+		parts = append(parts, "clipboard only")
+	}
+	if r.Sensitive {
+		parts = append(parts, "password to reveal")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Defaults are the effective defaults for one secret. Zero fields mean no
+// rule applies and the command's own default is used.
+type Defaults struct {
+	Length        int
+	Symbols       *bool
+	Words         int
+	ClearAfter    time.Duration
+	ClipboardOnly bool
+	Sensitive     bool
+}
+
+// ClearDelay returns the clipboard clear delay to use instead of current:
+// the policy's own delay if it sets one, capped at ClipboardOnlyMaxDelay for
+// clipboard-only secrets
+func (d Defaults) ClearDelay(current time.Duration) time.Duration {
+	delay := current
+	if d.ClearAfter > 0 {
+		delay = d.ClearAfter
+	}
+	if d.ClipboardOnly && (delay <= 0 || delay > ClipboardOnlyMaxDelay) {
+		delay = ClipboardOnlyMaxDelay
+	}
+	return delay
+}
+
+// Strictest combines the defaults of several secrets delivered as one
+// value, such as the fields of a connection string: the shortest clear
+// delay, and clipboard-only or sensitive if any of them is. Generator
+// defaults are left out.
+func Strictest(all ...Defaults) Defaults {
+	var d Defaults
+	for _, other := range all {
+		if other.ClearAfter > 0 && (d.ClearAfter == 0 || other.ClearAfter < d.ClearAfter) {
+			d.ClearAfter = other.ClearAfter
+		}
+		d.ClipboardOnly = d.ClipboardOnly || other.ClipboardOnly
+		d.Sensitive = d.Sensitive || other.Sensitive
+	}
+	return d
+}
+
+// Resolve merges every rule matching key and tags, in order; a later rule
+// overrides the fields it sets. A length and a word count replace each
+// other, so the last generation mode wins. Clipboard-only and sensitive
+// cannot be undone by a later rule.
+func Resolve(rules []Rule, key string, tags []string) Defaults {
+	var d Defaults
+	for _, r := range rules {
+		if !r.Matches(key, tags) {
+			continue
+		}
+		if r.Length != 0 {
+			d.Length, d.Words = r.Length, 0
+		}
+		if r.Words != 0 {
+			d.Words, d.Length = r.Words, 0
+		}
+		if r.Symbols != nil {
+			d.Symbols = r.Symbols
+		}
+		if r.ClearAfter != 0 {
+			d.ClearAfter = time.Duration(r.ClearAfter)
+		}
+		if r.ClipboardOnly {
+			d.ClipboardOnly = true
+		}
+		if r.Sensitive {
+			d.Sensitive = true
+		}
+	}
+	return d
+}
+
+// ParseTags splits a stored comma-separated tag list
+func ParseTags(tags *string) []string {
+	if tags == nil {
+		return nil
+	}
+	var out []string
+	for _, tag := range strings.Split(*tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+// Decode parses rules stored with Encode
+func Decode(data string) ([]Rule, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var rules []Rule
+	if err := json.Unmarshal([]byte(data), &rules); err != nil {
+		return nil, fmt.Errorf("invalid policy rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Encode serialises rules for storage
+func Encode(rules []Rule) (string, error) {
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,99 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	on, off := true, false
+	rules := []Rule{
+		{Tag: "banking", Length: 32, Symbols: &on, ClearAfter: Duration(20 * time.Second)},
+		{Tag: "wifi", Words: 5},
+		{Namespace: "bank/legacy/", Symbols: &off},
+	}
+
+	d := Resolve(rules, "bank/main", []string{"Banking"})
+	assert.Equal(t, 32, d.Length)
+	require.NotNil(t, d.Symbols)
+	assert.True(t, *d.Symbols)
+	assert.Equal(t, 20*time.Second, d.ClearAfter)
+
+	// Later rules override the fields they set
+	d = Resolve(rules, "Bank/Legacy/pin", []string{"banking"})
+	assert.Equal(t, 32, d.Length)
+	assert.False(t, *d.Symbols)
+
+	// A word count replaces a length
+	d = Resolve(rules, "home", []string{"banking", "wifi"})
+	assert.Equal(t, 5, d.Words)
+	assert.Zero(t, d.Length)
+
+	assert.Equal(t, Defaults{}, Resolve(rules, "other", nil))
+}
+
+func TestDefaults_ClearDelay(t *testing.T) {
+	assert.Equal(t, time.Minute, Defaults{}.ClearDelay(time.Minute))
+	assert.Equal(t, 5*time.Second, Defaults{ClearAfter: 5 * time.Second}.ClearDelay(time.Minute))
+
+	// Clipboard-only values never stay longer than the cap, even when
+	// auto-clear is off
+	assert.Equal(t, ClipboardOnlyMaxDelay, Defaults{ClipboardOnly: true}.ClearDelay(time.Minute))
+	assert.Equal(t, ClipboardOnlyMaxDelay, Defaults{ClipboardOnly: true}.ClearDelay(0))
+	assert.Equal(t, 10*time.Second, Defaults{ClipboardOnly: true, ClearAfter: 10 * time.Second}.ClearDelay(time.Minute))
+	assert.Equal(t, ClipboardOnlyMaxDelay, Defaults{ClipboardOnly: true, ClearAfter: time.Hour}.ClearDelay(time.Minute))
+
+	rules := []Rule{{Tag: "banking", ClipboardOnly: true}, {Tag: "banking", ClearAfter: Duration(time.Hour)}}
+	assert.True(t, Resolve(rules, "bank", []string{"banking"}).ClipboardOnly)
+
+	rules = []Rule{{Namespace: "bank/", Sensitive: true}, {Tag: "banking", Length: 20}}
+	assert.True(t, Resolve(rules, "bank/main", []string{"banking"}).Sensitive)
+	assert.False(t, Resolve(rules, "home", []string{"banking"}).Sensitive)
+}
+
+func TestStrictest(t *testing.T) {
+	assert.Equal(t, Defaults{}, Strictest())
+	d := Strictest(
+		Defaults{ClearAfter: time.Minute, Length: 20},
+		Defaults{},
+		Defaults{ClearAfter: 10 * time.Second, ClipboardOnly: true},
+		Defaults{ClearAfter: 30 * time.Second, Sensitive: true},
+	)
+	assert.Equal(t, Defaults{ClearAfter: 10 * time.Second, ClipboardOnly: true, Sensitive: true}, d)
+}
+
+func TestRule_Validate(t *testing.T) {
+	assert.NoError(t, Rule{Namespace: "bank/", ClipboardOnly: true}.Validate())
+	assert.NoError(t, Rule{Tag: "wifi", Words: 6}.Validate())
+	assert.NoError(t, Rule{Tag: "banking", Sensitive: true}.Validate())
+	assert.Error(t, Rule{Words: 6}.Validate())
+	assert.Error(t, Rule{Tag: "a", Namespace: "b/", Words: 6}.Validate())
+	assert.Error(t, Rule{Tag: "wifi"}.Validate())
+	assert.Error(t, Rule{Tag: "wifi", Words: 6, Length: 20}.Validate())
+}
+
+func TestEncodeDecode(t *testing.T) {
+	on := true
+	rules := []Rule{{Tag: "banking", Length: 32, Symbols: &on, ClearAfter: Duration(20 * time.Second)}}
+
+	data, err := Encode(rules)
+	require.NoError(t, err)
+	assert.Contains(t, data, `"clear_after":"20s"`)
+
+	decoded, err := Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, rules, decoded)
+
+	decoded, err = Decode("")
+	require.NoError(t, err)
+	assert.Empty(t, decoded)
+}
+
+func TestParseTags(t *testing.T) {
+	tags := "banking, personal,,"
+	assert.Equal(t, []string{"banking", "personal"}, ParseTags(&tags))
+	assert.Nil(t, ParseTags(nil))
+}
@@ -0,0 +1,119 @@
+// Package progress writes machine-readable progress events for long
+// operations, one JSON object per line, so GUI front-ends and editors that
+// wrap lockr can show progress without scraping its human-readable output.
+//
+// Every operation produces a "start" event, any number of "progress"
+// events and then exactly one "done" or "error" event:
+//
+//	{"event":"start","op":"import","total":3,"time":"..."}
+//	{"event":"progress","op":"import","current":1,"total":3,"message":"...","time":"..."}
+//	{"event":"done","op":"import","current":3,"total":3,"time":"..."}
+//
+// Events never contain secret values.
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	EventStart    = "start"
+	EventProgress = "progress"
+	EventDone     = "done"
+	EventError    = "error"
+)
+
+// Event is one line of the protocol
+type Event struct {
+	Event   string    `json:"event"`
+	Op      string    `json:"op"`
+	Current int       `json:"current,omitempty"`
+	Total   int       `json:"total,omitempty"`
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Reporter emits the events of one operation. A nil Reporter, or one
+// without a writer, discards everything, so callers need no checks.
+type Reporter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	op      string
+	current int
+	total   int
+	now     func() time.Time
+}
+
+// New returns a reporter writing op's events to w
+func New(w io.Writer, op string) *Reporter {
+	return &Reporter{w: w, op: op, now: time.Now}
+}
+
+// Start announces the operation and how many steps it has, if known
+func (r *Reporter) Start(total int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.total = total
+	r.emit(EventStart, "")
+}
+
+// Step reports that n more steps are complete
+func (r *Reporter) Step(n int, message string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current += n
+	r.emit(EventProgress, message)
+}
+
+// Done reports that the operation finished
+func (r *Reporter) Done(message string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.emit(EventDone, message)
+	r.w = nil
+}
+
+// Fail reports that the operation stopped with err
+func (r *Reporter) Fail(err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.emit(EventError, err.Error())
+	r.w = nil
+}
+
+// emit writes an event; the reporter stops writing after a failed write
+func (r *Reporter) emit(event, message string) {
+	if r.w == nil {
+		return
+	}
+	line, err := json.Marshal(Event{
+		Event:   event,
+		Op:      r.op,
+		Current: r.current,
+		Total:   r.total,
+		Message: message,
+		Time:    r.now().UTC(),
+	})
+	if err != nil {
+		return
+	}
+	if _, err := r.w.Write(append(line, '\n')); err != nil {
+		r.w = nil
+	}
+}
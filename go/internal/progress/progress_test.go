@@ -0,0 +1,88 @@
+package progress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, buf *bytes.Buffer) []Event {
+	t.Helper()
+	var events []Event
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var e Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := New(&buf, "import")
+	r.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	r.Start(3)
+	r.Step(2, "added")
+	r.Step(1, "updated")
+	r.Done("")
+	r.Step(1, "ignored after done")
+
+	events := decode(t, &buf)
+	require.Len(t, events, 4)
+	assert.Equal(t, Event{Event: EventStart, Op: "import", Total: 3, Time: r.now()}, events[0])
+	assert.Equal(t, EventProgress, events[1].Event)
+	assert.Equal(t, 2, events[1].Current)
+	assert.Equal(t, "added", events[1].Message)
+	assert.Equal(t, 3, events[2].Current)
+	assert.Equal(t, EventDone, events[3].Event)
+}
+
+func TestReporter_Fail(t *testing.T) {
+	var buf bytes.Buffer
+	r := New(&buf, "rekey")
+	r.Start(0)
+	r.Fail(errors.New("wrong password"))
+	r.Done("")
+
+	events := decode(t, &buf)
+	require.Len(t, events, 2)
+	assert.Equal(t, EventError, events[1].Event)
+	assert.Equal(t, "wrong password", events[1].Message)
+}
+
+func TestReporter_Nil(t *testing.T) {
+	var r *Reporter
+	r.Start(1)
+	r.Step(1, "")
+	r.Done("")
+	r.Fail(errors.New("x"))
+
+	// A reporter without a writer discards events too
+	r = New(nil, "merge")
+	r.Start(1)
+	r.Done("")
+}
+
+type failingWriter struct{ writes int }
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, errors.New("broken pipe")
+}
+
+func TestReporter_StopsAfterWriteError(t *testing.T) {
+	w := &failingWriter{}
+	r := New(w, "import")
+	r.Start(2)
+	r.Step(1, "")
+	r.Done("")
+	assert.Equal(t, 1, w.writes)
+}
@@ -0,0 +1,92 @@
+// Package refs resolves references from one secret's value to others, so a
+// composed value such as a connection string can embed a password that is
+// rotated separately
+package refs
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxDepth bounds how deeply references may nest
+const MaxDepth = 16
+
+// ErrCycle is returned when references form a loop
+var ErrCycle = errors.New("reference cycle")
+
+// refPattern matches ${ref:key}, and $${ref:key} which escapes it
+var refPattern = regexp.MustCompile(`\$?\$\{ref:([^}]*)\}`)
+
+// Lookup returns the stored value of key
+type Lookup func(key string) (string, error)
+
+// Reference returns the text that refers to key from another value
+func Reference(key string) string {
+	return "${ref:" + key + "}"
+}
+
+// References lists the keys value refers to directly, in order of
+// appearance
+func References(value string) []string {
+	var keys []string
+	for _, m := range refPattern.FindAllStringSubmatch(value, -1) {
+		if !strings.HasPrefix(m[0], "$$") {
+			keys = append(keys, strings.TrimSpace(m[1]))
+		}
+	}
+	return keys
+}
+
+// Resolve expands every ${ref:key} in the value stored under key, following
+// references in the referenced values too. $${ref:key} yields a literal
+// ${ref:key}.
+func Resolve(key, value string, lookup Lookup) (string, error) {
+	return resolve(value, []string{key}, lookup)
+}
+
+func resolve(value string, chain []string, lookup Lookup) (string, error) {
+	if len(chain) > MaxDepth {
+		return "", fmt.Errorf("references nested deeper than %d: %s", MaxDepth, strings.Join(chain, " -> "))
+	}
+
+	var resolveErr error
+	out := refPattern.ReplaceAllStringFunc(value, func(match string) string {
+		if resolveErr != nil {
+			return match
+		}
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+
+		ref := strings.TrimSpace(refPattern.FindStringSubmatch(match)[1])
+		if ref == "" {
+			resolveErr = fmt.Errorf("empty reference in '%s'", chain[len(chain)-1])
+			return match
+		}
+		for _, seen := range chain {
+			if strings.EqualFold(seen, ref) {
+				resolveErr = fmt.Errorf("%w: %s -> %s", ErrCycle, strings.Join(chain, " -> "), ref)
+				return match
+			}
+		}
+
+		refValue, err := lookup(ref)
+		if err != nil {
+			resolveErr = fmt.Errorf("'%s' references '%s': %w", chain[len(chain)-1], ref, err)
+			return match
+		}
+
+		resolved, err := resolve(refValue, append(chain[:len(chain):len(chain)], ref), lookup)
+		if err != nil {
+			resolveErr = err
+			return match
+		}
+		return resolved
+	})
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+	return out, nil
+}
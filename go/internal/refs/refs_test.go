@@ -0,0 +1,81 @@
+package refs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errNotFound = errors.New("key not found")
+
+func lookupIn(values map[string]string) Lookup {
+	return func(key string) (string, error) {
+		value, ok := values[strings.ToLower(key)]
+		if !ok {
+			return "", errNotFound
+		}
+		return value, nil
+	}
+}
+
+func TestResolve(t *testing.T) {
+	lookup := lookupIn(map[string]string{
+		"db/password": "hunter2",
+		"db/user":     "app",
+		"db/creds":    "${ref:db/user}:${ref:db/password}",
+	})
+
+	value, err := Resolve("db/url", "postgres://${ref:db/creds}@db:5432/app", lookup)
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://app:hunter2@db:5432/app", value)
+
+	value, err = Resolve("plain", "no references here", lookup)
+	require.NoError(t, err)
+	assert.Equal(t, "no references here", value)
+
+	// Escaped references are left literal
+	value, err = Resolve("doc", "use $${ref:db/user} to embed", lookup)
+	require.NoError(t, err)
+	assert.Equal(t, "use ${ref:db/user} to embed", value)
+
+	_, err = Resolve("broken", "${ref:missing}", lookup)
+	assert.ErrorIs(t, err, errNotFound)
+	assert.Contains(t, err.Error(), "'broken' references 'missing'")
+}
+
+func TestReference(t *testing.T) {
+	ref := Reference("db/password")
+	assert.Equal(t, "${ref:db/password}", ref)
+	assert.Equal(t, []string{"db/password"}, References("x"+ref))
+}
+
+func TestResolve_Cycles(t *testing.T) {
+	lookup := lookupIn(map[string]string{
+		"a": "${ref:b}",
+		"b": "x${ref:A}",
+	})
+
+	_, err := Resolve("a", "${ref:b}", lookup)
+	assert.ErrorIs(t, err, ErrCycle)
+	assert.Contains(t, err.Error(), "a -> b -> A")
+
+	_, err = Resolve("self", "${ref:self}", lookup)
+	assert.ErrorIs(t, err, ErrCycle)
+}
+
+func TestResolve_Depth(t *testing.T) {
+	values := map[string]string{}
+	for i := 0; i <= MaxDepth; i++ {
+		values[string(rune('a'+i))] = "${ref:" + string(rune('a'+i+1)) + "}"
+	}
+	_, err := Resolve("start", "${ref:a}", lookupIn(values))
+	assert.Error(t, err)
+}
+
+func TestReferences(t *testing.T) {
+	assert.Equal(t, []string{"db/user", "db/password"}, References("${ref:db/user}:${ref: db/password }$${ref:literal}"))
+	assert.Empty(t, References("plain"))
+}
@@ -0,0 +1,104 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lockr/go/internal/keymap"
+)
+
+// MaxDisplayResults is the number of results the interactive picker shows
+// when neither a count nor the terminal height is known
+const MaxDisplayResults = 5
+
+// Density selects how much the interactive picker shows for each result
+type Density string
+
+const (
+	// DensityCompact shows one line per result: the key and its access count
+	DensityCompact Density = "compact"
+
+	// DensityDetailed adds a second line with the namespace and tags
+	DensityDetailed Density = "detailed"
+)
+
+// ParseDensity converts a user-supplied string into a Density
+func ParseDensity(density string) (Density, error) {
+	switch Density(strings.ToLower(density)) {
+	case DensityCompact, "":
+		return DensityCompact, nil
+	case DensityDetailed:
+		return DensityDetailed, nil
+	default:
+		return "", fmt.Errorf("unknown density %q (expected compact or detailed)", density)
+	}
+}
+
+// DisplayOptions configures the interactive picker
+type DisplayOptions struct {
+	// Results is the number of results shown at once. Zero fits as many as
+	// the terminal height allows.
+	Results int
+
+	// Density selects the row rendering
+	Density Density
+
+	// Keys are the picker's key bindings; the zero value uses keymap.Picker
+	Keys keymap.Keymap
+
+	// Program is an external picker such as fzf to hand the keys to
+	// instead; empty uses the built-in picker
+	Program string
+
+	// Label names the vault in the prompt, and Color (an ANSI color number
+	// or #rrggbb) frames the picker, so vaults are told apart
+	Label string
+	Color string
+
+	// Reveal, when set, lets the reveal key show the selected secret's
+	// value for RevealFor (DefaultRevealFor if zero). It is called with a
+	// nil password first and returns ErrPasswordRequired when the secret
+	// needs the vault password typed again.
+	Reveal    func(key string, password []byte) ([]byte, error)
+	RevealFor time.Duration
+}
+
+// DefaultRevealFor is how long a revealed value stays shown
+const DefaultRevealFor = 10 * time.Second
+
+// ErrPasswordRequired is returned by DisplayOptions.Reveal for secrets that
+// are only revealed after the vault password is typed again
+var ErrPasswordRequired = errors.New("the vault password is required to reveal this secret")
+
+// pickerChromeLines is the number of lines the picker uses besides result
+// rows: the query and a blank line, the "more results" line, and a blank
+// line and the help text
+const pickerChromeLines = 5
+
+// FitResults returns how many results fit in a terminal height lines tall.
+// It falls back to MaxDisplayResults when the height is unknown and always
+// shows at least one result.
+func FitResults(height int, density Density) int {
+	if height <= 0 {
+		return MaxDisplayResults
+	}
+
+	rowLines := 1
+	if density == DensityDetailed {
+		rowLines = 2
+	}
+	if n := (height - pickerChromeLines) / rowLines; n > 1 {
+		return n
+	}
+	return 1
+}
+
+// namespace returns the part of key before its last "/", or "" if it has none
+func namespace(key string) string {
+	if i := strings.LastIndex(key, "/"); i > 0 {
+		return key[:i]
+	}
+	return ""
+}
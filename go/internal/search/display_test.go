@@ -0,0 +1,33 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDensity(t *testing.T) {
+	d, err := ParseDensity("")
+	assert.NoError(t, err)
+	assert.Equal(t, DensityCompact, d)
+
+	d, err = ParseDensity("Detailed")
+	assert.NoError(t, err)
+	assert.Equal(t, DensityDetailed, d)
+
+	_, err = ParseDensity("cozy")
+	assert.Error(t, err)
+}
+
+func TestFitResults(t *testing.T) {
+	assert.Equal(t, MaxDisplayResults, FitResults(0, DensityCompact))
+	assert.Equal(t, 19, FitResults(24, DensityCompact))
+	assert.Equal(t, 9, FitResults(24, DensityDetailed))
+	assert.Equal(t, 1, FitResults(3, DensityDetailed))
+}
+
+func TestNamespace(t *testing.T) {
+	assert.Equal(t, "prod/db", namespace("prod/db/password"))
+	assert.Equal(t, "", namespace("token"))
+	assert.Equal(t, "", namespace("/token"))
+}
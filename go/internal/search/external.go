@@ -0,0 +1,80 @@
+package search
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/lockr/go/internal/database"
+)
+
+// Pickers 'lockr get' can select a secret with
+const (
+	// PickerBuiltin is lockr's own interactive picker
+	PickerBuiltin = "builtin"
+
+	// PickerFzf hands the key list to fzf
+	PickerFzf = "fzf"
+
+	// PickerSkim hands the key list to skim
+	PickerSkim = "sk"
+)
+
+// ParsePicker converts a user-supplied picker name, returning "" for the
+// built-in picker and the program to run otherwise
+func ParsePicker(picker string) (string, error) {
+	switch strings.ToLower(picker) {
+	case PickerBuiltin, "":
+		return "", nil
+	case PickerFzf:
+		return PickerFzf, nil
+	case PickerSkim, "skim":
+		return PickerSkim, nil
+	default:
+		return "", fmt.Errorf("unknown picker %q (expected builtin, fzf or sk)", picker)
+	}
+}
+
+// RunExternalPicker writes the keys of secrets, one per line, to program
+// and returns the key it prints. Only keys are written: values never reach
+// the external process. A cancelled or empty selection returns "".
+func RunExternalPicker(program string, secrets []database.SearchResult) (string, error) {
+	var keys bytes.Buffer
+	for _, s := range secrets {
+		keys.WriteString(s.Key)
+		keys.WriteByte('\n')
+	}
+
+	var out bytes.Buffer
+	cmd := exec.Command(program, "--prompt", "secret> ", "--no-multi")
+	cmd.Stdin = &keys
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		// fzf and skim exit with 1 when nothing matched and 130 when the
+		// user pressed Esc or ctrl+c
+		if errors.As(err, &exitErr) && (exitErr.ExitCode() == 1 || exitErr.ExitCode() == 130) {
+			return "", nil
+		}
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", fmt.Errorf("%s is not installed or not in PATH", program)
+		}
+		return "", fmt.Errorf("%s failed: %w", program, err)
+	}
+
+	key := strings.TrimRight(out.String(), "\r\n")
+	for _, s := range secrets {
+		if s.Key == key {
+			return key, nil
+		}
+	}
+	if key == "" {
+		return "", nil
+	}
+	return "", fmt.Errorf("%s returned %q, which is not a secret", program, key)
+}
@@ -0,0 +1,65 @@
+package search
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lockr/go/internal/database"
+)
+
+func TestParsePicker(t *testing.T) {
+	program, err := ParsePicker("")
+	assert.NoError(t, err)
+	assert.Equal(t, "", program)
+
+	program, err = ParsePicker("FZF")
+	assert.NoError(t, err)
+	assert.Equal(t, PickerFzf, program)
+
+	program, err = ParsePicker("skim")
+	assert.NoError(t, err)
+	assert.Equal(t, PickerSkim, program)
+
+	_, err = ParsePicker("dmenu")
+	assert.Error(t, err)
+}
+
+// fakePicker writes a script that saves its input to a file and exits as
+// given after printing output
+func fakePicker(t *testing.T, output string, exit int) (program, input string) {
+	dir := t.TempDir()
+	program = filepath.Join(dir, "picker")
+	input = filepath.Join(dir, "input")
+	script := fmt.Sprintf("#!/bin/sh\ncat > %s\nprintf '%s'\nexit %d\n", input, output, exit)
+	require.NoError(t, os.WriteFile(program, []byte(script), 0700))
+	return program, input
+}
+
+func TestRunExternalPicker(t *testing.T) {
+	secrets := []database.SearchResult{{Key: "api/github"}, {Key: "db/prod"}}
+
+	program, input := fakePicker(t, "db/prod\\n", 0)
+	key, err := RunExternalPicker(program, secrets)
+	require.NoError(t, err)
+	assert.Equal(t, "db/prod", key)
+	sent, err := os.ReadFile(input)
+	require.NoError(t, err)
+	assert.Equal(t, "api/github\ndb/prod\n", string(sent))
+
+	program, _ = fakePicker(t, "", 1)
+	key, err = RunExternalPicker(program, secrets)
+	assert.NoError(t, err, "no match is a cancelled selection")
+	assert.Equal(t, "", key)
+
+	program, _ = fakePicker(t, "db/other\\n", 0)
+	_, err = RunExternalPicker(program, secrets)
+	assert.Error(t, err)
+
+	_, err = RunExternalPicker(filepath.Join(t.TempDir(), "missing"), secrets)
+	assert.Error(t, err)
+}
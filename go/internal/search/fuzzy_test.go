@@ -223,3 +223,65 @@ func TestEngine_MatchQuality(t *testing.T) {
 	assert.Equal(t, SubstringMatch, engine.GetMatchQuality("test", "my_test_key"))
 	assert.Equal(t, NoMatch, engine.GetMatchQuality("xyz", "abc"))
 }
+
+func TestEngine_SearchPage(t *testing.T) {
+	engine := NewEngine()
+
+	secrets := make([]database.SearchResult, 25)
+	for i := 0; i < 25; i++ {
+		secrets[i] = database.SearchResult{
+			Key:       fmt.Sprintf("key_%02d", i),
+			CreatedAt: time.Now(),
+		}
+	}
+
+	page, total := engine.SearchPage("key", secrets, 0, 10)
+	assert.Equal(t, 25, total)
+	assert.Len(t, page, 10)
+
+	page, total = engine.SearchPage("key", secrets, 20, 10)
+	assert.Equal(t, 25, total)
+	assert.Len(t, page, 5)
+
+	page, total = engine.SearchPage("key", secrets, 30, 10)
+	assert.Equal(t, 25, total)
+	assert.Len(t, page, 0)
+
+	// Zero limit returns every remaining match
+	page, _ = engine.SearchPage("key", secrets, 5, 0)
+	assert.Len(t, page, 20)
+}
+
+func TestParseMatchMode(t *testing.T) {
+	mode, err := ParseMatchMode("glob")
+	require.NoError(t, err)
+	assert.Equal(t, MatchGlob, mode)
+
+	mode, err = ParseMatchMode("REGEX")
+	require.NoError(t, err)
+	assert.Equal(t, MatchRegex, mode)
+
+	mode, err = ParseMatchMode("")
+	require.NoError(t, err)
+	assert.Equal(t, MatchFuzzy, mode)
+
+	_, err = ParseMatchMode("soundex")
+	assert.Error(t, err)
+}
+
+func TestEngine_UnicodeHighlights(t *testing.T) {
+	engine := NewEngine()
+
+	secrets := []database.SearchResult{
+		{Key: "Ünïcode_Schlüssel", CreatedAt: time.Now()},
+	}
+
+	results := engine.Search("schlüssel", secrets)
+	require.Len(t, results, 1)
+
+	// Highlights index runes of the original key
+	runes := []rune(results[0].Result.Key)
+	require.Len(t, results[0].Highlights, 1)
+	h := results[0].Highlights[0]
+	assert.Equal(t, "Schlüssel", string(runes[h.Start:h.End]))
+}
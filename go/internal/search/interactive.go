@@ -1,29 +1,49 @@
+//go:build !minimal
+
 package search
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/keymap"
 )
 
-const (
-	// MaxDisplayResults is the maximum number of results to show in the interactive UI
-	MaxDisplayResults = 5
-)
+// InteractiveAvailable reports whether this build includes the interactive UI
+const InteractiveAvailable = true
 
 // InteractiveSearch provides a real-time fuzzy search interface
 type InteractiveSearch struct {
 	engine   *Engine
 	secrets  []database.SearchResult
 	results  []MatchResult
+	total    int
 	query    string
+	queryErr error
 	selected int
 	active   bool
 	styles   InteractiveStyles
+	options  DisplayOptions
+	visible  int
+	keys     keymap.Keymap
+	help     bool
+
+	// The value of revealKey while it is shown, the password being typed
+	// before a sensitive secret is revealed, and why revealing failed
+	revealed  []byte
+	revealKey string
+	revealGen int
+	prompting bool
+	password  []byte
+	revealErr error
 }
 
 // InteractiveStyles defines the visual styling for the interactive search
@@ -37,12 +57,27 @@ type InteractiveStyles struct {
 	Highlight      lipgloss.Style
 	MoreIndicator  lipgloss.Style
 	NoResults      lipgloss.Style
+	HelpOverlay    lipgloss.Style
+	VaultLabel     lipgloss.Style
+	Frame          lipgloss.Style
 }
 
-// NewInteractiveSearch creates a new interactive search instance
-func NewInteractiveSearch(secrets []database.SearchResult) *InteractiveSearch {
+// NewInteractiveSearch creates a new interactive search instance. Unless
+// options fix the number of results, it shows MaxDisplayResults until
+// SetHeight reports the terminal height.
+func NewInteractiveSearch(secrets []database.SearchResult, options DisplayOptions) *InteractiveSearch {
 	engine := NewEngine()
-	engine.SetMaxResults(MaxDisplayResults * 2) // Get more results for better filtering
+	engine.SetMaxResults(0) // Count every match for the "more results" line
+
+	visible := options.Results
+	if visible <= 0 {
+		visible = FitResults(0, options.Density)
+	}
+
+	keys := options.Keys
+	if keys.Bindings == nil {
+		keys = keymap.Picker()
+	}
 
 	return &InteractiveSearch{
 		engine:   engine,
@@ -51,7 +86,28 @@ func NewInteractiveSearch(secrets []database.SearchResult) *InteractiveSearch {
 		query:    "",
 		selected: 0,
 		active:   true,
-		styles:   defaultInteractiveStyles(),
+		styles:   vaultStyles(defaultInteractiveStyles(), options),
+		options:  options,
+		visible:  visible,
+		keys:     keys,
+	}
+}
+
+// SetHeight fits the number of results shown to a terminal height lines
+// tall, unless the options fix the count
+func (is *InteractiveSearch) SetHeight(height int) {
+	if is.options.Results > 0 {
+		return
+	}
+	if is.options.Color != "" {
+		height -= frameLines
+	}
+	if is.options.Reveal != nil {
+		height -= valueLines
+	}
+	is.visible = FitResults(height, is.options.Density)
+	if is.query != "" {
+		is.updateResults()
 	}
 }
 
@@ -82,7 +138,34 @@ func defaultInteractiveStyles() InteractiveStyles {
 		NoResults: lipgloss.NewStyle().
 			Foreground(lipgloss.Color("red")).
 			Italic(true),
+		HelpOverlay: lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("238")).
+			Padding(0, 1),
+	}
+}
+
+// frameLines is the number of lines the border around a colored picker uses
+const frameLines = 2
+
+// valueLines is the number of lines the selected value uses when the picker
+// can reveal it
+const valueLines = 1
+
+// vaultStyles colors the vault label and the picker's frame with the
+// vault's color
+func vaultStyles(styles InteractiveStyles, options DisplayOptions) InteractiveStyles {
+	styles.VaultLabel = styles.QueryPrompt
+	styles.Frame = lipgloss.NewStyle()
+	if options.Color != "" {
+		color := lipgloss.Color(options.Color)
+		styles.VaultLabel = styles.VaultLabel.Foreground(color)
+		styles.Frame = styles.Frame.
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(color).
+			Padding(0, 1)
 	}
+	return styles
 }
 
 // Model represents the state for the Bubble Tea model
@@ -93,9 +176,9 @@ type Model struct {
 }
 
 // NewModel creates a new Bubble Tea model for interactive search
-func NewModel(secrets []database.SearchResult) Model {
+func NewModel(secrets []database.SearchResult, options DisplayOptions) Model {
 	return Model{
-		search: NewInteractiveSearch(secrets),
+		search: NewInteractiveSearch(secrets, options),
 	}
 }
 
@@ -104,34 +187,75 @@ func (m Model) Init() tea.Cmd {
 	return nil
 }
 
+// hideMsg masks the value shown by reveal number gen once its time is up
+type hideMsg struct {
+	gen int
+}
+
 // Update handles messages and updates the model state
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.search.SetHeight(msg.Height)
+
+	case hideMsg:
+		if msg.gen == m.search.revealGen {
+			m.search.mask()
+		}
+
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+c", "esc":
+		if msg.String() == keymap.Quit {
+			m.search.Hide()
+			m.quitting = true
+			return m, tea.Quit
+		}
+
+		// The password prompt takes every key until submitted or cancelled
+		if m.search.prompting {
+			return m, m.search.typePassword(msg)
+		}
+
+		action := m.search.keys.Action(msg.String())
+		if action == keymap.Reveal {
+			return m, m.search.Reveal()
+		}
+		m.search.Hide()
+
+		// The help overlay swallows keys until it is closed
+		if m.search.help {
+			if action == keymap.Help || action == keymap.Cancel {
+				m.search.help = false
+			}
+			return m, nil
+		}
+
+		switch action {
+		case keymap.Cancel:
 			m.quitting = true
 			return m, tea.Quit
 
-		case "enter":
+		case keymap.Accept:
 			if len(m.search.results) > 0 && m.search.selected < len(m.search.results) {
 				m.selected = &m.search.results[m.search.selected]
 			}
 			return m, tea.Quit
 
-		case "up", "ctrl+p":
+		case keymap.Up:
 			m.search.MoveSelection(-1)
 
-		case "down", "ctrl+n":
+		case keymap.Down:
 			m.search.MoveSelection(1)
 
-		case "backspace":
+		case keymap.DeleteChar:
 			m.search.RemoveChar()
 
+		case keymap.Help:
+			m.search.help = true
+
 		default:
-			// Add character to query
-			if len(msg.String()) == 1 && msg.String()[0] >= 32 { // Printable characters
-				m.search.AddChar(msg.String()[0])
+			// Add typed characters (including multi-byte runes) to query
+			if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+				m.search.AddRunes(msg.Runes)
 			}
 		}
 	}
@@ -148,6 +272,94 @@ func (m Model) View() string {
 	return m.search.Render()
 }
 
+// Reveal shows the selected secret's value for the reveal duration, or
+// prompts for the vault password first when the secret needs it. It returns
+// the command that masks the value again.
+func (is *InteractiveSearch) Reveal() tea.Cmd {
+	result := is.GetSelectedResult()
+	if is.options.Reveal == nil || result == nil {
+		return nil
+	}
+	is.Hide()
+
+	value, err := is.options.Reveal(result.Result.Key, nil)
+	if errors.Is(err, ErrPasswordRequired) {
+		is.prompting = true
+		return nil
+	}
+	return is.show(result.Result.Key, value, err)
+}
+
+// typePassword handles a key pressed at the password prompt
+func (is *InteractiveSearch) typePassword(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEnter:
+		result := is.GetSelectedResult()
+		password := is.password
+		is.password, is.prompting = nil, false
+		defer clear(password)
+		if result == nil {
+			return nil
+		}
+		value, err := is.options.Reveal(result.Result.Key, password)
+		return is.show(result.Result.Key, value, err)
+
+	case tea.KeyEsc:
+		is.cancelPrompt()
+
+	case tea.KeyBackspace:
+		if len(is.password) > 0 {
+			_, size := utf8.DecodeLastRune(is.password)
+			clear(is.password[len(is.password)-size:])
+			is.password = is.password[:len(is.password)-size]
+		}
+
+	case tea.KeyRunes, tea.KeySpace:
+		for _, r := range msg.Runes {
+			is.password = utf8.AppendRune(is.password, r)
+		}
+	}
+	return nil
+}
+
+// show displays value as the value of key, or err if revealing failed
+func (is *InteractiveSearch) show(key string, value []byte, err error) tea.Cmd {
+	if err != nil {
+		is.revealErr = err
+		return nil
+	}
+	is.revealed, is.revealKey = value, key
+	is.revealGen++
+	gen := is.revealGen
+
+	revealFor := is.options.RevealFor
+	if revealFor <= 0 {
+		revealFor = DefaultRevealFor
+	}
+	return tea.Tick(revealFor, func(time.Time) tea.Msg { return hideMsg{gen} })
+}
+
+// Hide masks a revealed value, scrubbing it from memory, and drops any
+// password prompt or reveal error
+func (is *InteractiveSearch) Hide() {
+	is.mask()
+	is.cancelPrompt()
+	is.revealErr = nil
+}
+
+// mask scrubs the revealed value
+func (is *InteractiveSearch) mask() {
+	clear(is.revealed)
+	is.revealed, is.revealKey = nil, ""
+}
+
+// cancelPrompt leaves the password prompt, scrubbing what was typed
+func (is *InteractiveSearch) cancelPrompt() {
+	clear(is.password)
+	is.password = nil
+	is.prompting = false
+}
+
 // AddChar adds a character to the search query and updates results
 func (is *InteractiveSearch) AddChar(ch byte) {
 	is.query += string(ch)
@@ -155,10 +367,26 @@ func (is *InteractiveSearch) AddChar(ch byte) {
 	is.selected = 0 // Reset selection when query changes
 }
 
-// RemoveChar removes the last character from the search query
+// AddRunes appends printable runes to the search query and updates results
+func (is *InteractiveSearch) AddRunes(runes []rune) {
+	added := false
+	for _, r := range runes {
+		if unicode.IsPrint(r) {
+			is.query += string(r)
+			added = true
+		}
+	}
+	if added {
+		is.updateResults()
+		is.selected = 0 // Reset selection when query changes
+	}
+}
+
+// RemoveChar removes the last character (rune) from the search query
 func (is *InteractiveSearch) RemoveChar() {
 	if len(is.query) > 0 {
-		is.query = is.query[:len(is.query)-1]
+		_, size := utf8.DecodeLastRuneInString(is.query)
+		is.query = is.query[:len(is.query)-size]
 		is.updateResults()
 		is.selected = 0 // Reset selection when query changes
 	}
@@ -189,10 +417,17 @@ func (is *InteractiveSearch) GetSelectedResult() *MatchResult {
 
 // updateResults refreshes the search results based on the current query
 func (is *InteractiveSearch) updateResults() {
-	allResults := is.engine.Search(is.query, is.secrets)
+	// Qualifiers such as tag:prod narrow the secrets before fuzzy ranking
+	query, err := ParseQuery(is.query)
+	is.queryErr = err
+	var allResults []MatchResult
+	if err == nil {
+		allResults = is.engine.SearchQuery(query, is.secrets)
+	}
+	is.total = len(allResults)
 
 	// Limit to display results
-	displayCount := MaxDisplayResults
+	displayCount := is.visible
 	if len(allResults) < displayCount {
 		displayCount = len(allResults)
 	}
@@ -210,9 +445,17 @@ func (is *InteractiveSearch) updateResults() {
 
 // Render renders the interactive search interface
 func (is *InteractiveSearch) Render() string {
+	if is.help {
+		return is.styles.HelpOverlay.Render(is.keys.Overlay()) + "\n"
+	}
+
 	var b strings.Builder
 
-	// Render query prompt and input
+	// Render query prompt and input, naming the vault when it has a label
+	if is.options.Label != "" {
+		b.WriteString(is.styles.VaultLabel.Render("[" + is.options.Label + "]"))
+		b.WriteString(" ")
+	}
 	b.WriteString(is.styles.QueryPrompt.Render("Search: "))
 	b.WriteString(is.styles.QueryInput.Render(is.query))
 
@@ -222,10 +465,12 @@ func (is *InteractiveSearch) Render() string {
 
 	// Render results
 	if len(is.results) == 0 {
-		if len(is.query) > 0 {
+		if is.queryErr != nil {
+			b.WriteString(is.styles.NoResults.Render(is.queryErr.Error()))
+		} else if len(is.query) > 0 {
 			b.WriteString(is.styles.NoResults.Render("No matches found"))
 		} else {
-			b.WriteString(is.styles.ResultMeta.Render("Start typing to search..."))
+			b.WriteString(is.styles.ResultMeta.Render("Start typing to search (filters: tag:, ns:, created:, accessed:, count:)..."))
 		}
 	} else {
 		for i, result := range is.results {
@@ -235,22 +480,50 @@ func (is *InteractiveSearch) Render() string {
 		}
 
 		// Show "more results" indicator if there are additional matches
-		totalMatches := len(is.engine.Search(is.query, is.secrets))
-		if totalMatches > len(is.results) {
-			moreCount := totalMatches - len(is.results)
+		if is.total > len(is.results) {
+			moreCount := is.total - len(is.results)
 			moreText := fmt.Sprintf("... and %d more results", moreCount)
 			b.WriteString(is.styles.MoreIndicator.Render(moreText))
 			b.WriteString("\n")
 		}
 	}
 
+	if is.options.Reveal != nil {
+		b.WriteString(is.renderValue())
+		b.WriteString("\n")
+	}
+
 	// Add help text
 	b.WriteString("\n")
-	b.WriteString(is.styles.ResultMeta.Render("Use ↑/↓ to navigate, Enter to select, Esc to cancel"))
+	b.WriteString(is.styles.ResultMeta.Render(fmt.Sprintf("Use %s/%s to navigate, %s to select, %s to cancel, %s for help",
+		is.keys.Label(keymap.Up), is.keys.Label(keymap.Down), is.keys.Label(keymap.Accept),
+		is.keys.Label(keymap.Cancel), is.keys.Label(keymap.Help))))
 
+	if is.options.Color != "" {
+		return is.styles.Frame.Render(b.String())
+	}
 	return b.String()
 }
 
+// renderValue renders the selected secret's value line: masked, revealed,
+// or the password prompt for revealing it
+func (is *InteractiveSearch) renderValue() string {
+	result := is.GetSelectedResult()
+	switch {
+	case result == nil:
+		return ""
+	case is.prompting:
+		return is.styles.QueryPrompt.Render("Vault password to reveal "+result.Result.Key+": ") +
+			strings.Repeat("•", utf8.RuneCount(is.password)) + "█"
+	case is.revealErr != nil:
+		return is.styles.NoResults.Render(is.revealErr.Error())
+	case is.revealed != nil && is.revealKey == result.Result.Key:
+		value := strings.ReplaceAll(string(is.revealed), "\n", "⏎")
+		return "Value: " + is.styles.ResultKey.Render(value)
+	}
+	return "Value: ••••••••  " + is.styles.ResultMeta.Render(fmt.Sprintf("(%s to reveal)", is.keys.Label(keymap.Reveal)))
+}
+
 // renderResult renders a single search result
 func (is *InteractiveSearch) renderResult(result MatchResult, selected bool) string {
 	key := result.Result.Key
@@ -273,9 +546,29 @@ func (is *InteractiveSearch) renderResult(result MatchResult, selected bool) str
 		content = "  " + styledKey + " " + is.styles.ResultMeta.Render(meta)
 	}
 
+	if is.options.Density == DensityDetailed {
+		content += "\n    " + is.styles.ResultMeta.Render(resultDetails(result.Result))
+	}
+
 	return content
 }
 
+// resultDetails describes the namespace and tags of a result for the
+// detailed density
+func resultDetails(result database.SearchResult) string {
+	var parts []string
+	if ns := namespace(result.Key); ns != "" {
+		parts = append(parts, "namespace: "+ns)
+	}
+	if result.Tags != nil && strings.TrimSpace(*result.Tags) != "" {
+		parts = append(parts, "tags: "+strings.TrimSpace(*result.Tags))
+	}
+	if len(parts) == 0 {
+		return "no namespace or tags"
+	}
+	return strings.Join(parts, " · ")
+}
+
 // applyHighlights applies highlighting to matched portions of text
 func (is *InteractiveSearch) applyHighlights(text string, highlights []HighlightRange) string {
 	if len(highlights) == 0 {
@@ -287,17 +580,20 @@ func (is *InteractiveSearch) applyHighlights(text string, highlights []Highlight
 
 	pos := 0
 	for _, highlight := range highlights {
+		// Skip ranges that overlap what was already written or fall outside the text
+		if highlight.Start < pos || highlight.End > len(runes) || highlight.Start >= highlight.End {
+			continue
+		}
+
 		// Add text before highlight
 		if highlight.Start > pos {
 			result.WriteString(string(runes[pos:highlight.Start]))
 		}
 
-		// Add highlighted text
-		if highlight.End <= len(runes) {
-			highlightedText := string(runes[highlight.Start:highlight.End])
-			result.WriteString(is.styles.Highlight.Render(highlightedText))
-			pos = highlight.End
-		}
+		// Add highlighted text (ranges are rune indices into the original key)
+		highlightedText := string(runes[highlight.Start:highlight.End])
+		result.WriteString(is.styles.Highlight.Render(highlightedText))
+		pos = highlight.End
 	}
 
 	// Add remaining text
@@ -309,8 +605,8 @@ func (is *InteractiveSearch) applyHighlights(text string, highlights []Highlight
 }
 
 // RunInteractiveSearch runs the interactive search and returns the selected key
-func RunInteractiveSearch(secrets []database.SearchResult) (string, error) {
-	model := NewModel(secrets)
+func RunInteractiveSearch(secrets []database.SearchResult, options DisplayOptions) (string, error) {
+	model := NewModel(secrets, options)
 
 	program := tea.NewProgram(model)
 	finalModel, err := program.Run()
@@ -320,6 +616,7 @@ func RunInteractiveSearch(secrets []database.SearchResult) (string, error) {
 
 	// Get the result from the final model
 	final := finalModel.(Model)
+	final.search.Hide()
 	if final.selected != nil {
 		return final.selected.Result.Key, nil
 	}
@@ -0,0 +1,21 @@
+//go:build minimal
+
+package search
+
+import (
+	"errors"
+
+	"github.com/lockr/go/internal/database"
+)
+
+// ErrInteractiveUnavailable is returned by RunInteractiveSearch in builds
+// made with the minimal tag, which leave out the terminal UI
+var ErrInteractiveUnavailable = errors.New("interactive search is not available in this build; pass a key or use 'lockr list'")
+
+// InteractiveAvailable reports whether this build includes the interactive UI
+const InteractiveAvailable = false
+
+// RunInteractiveSearch always fails in minimal builds
+func RunInteractiveSearch(secrets []database.SearchResult, options DisplayOptions) (string, error) {
+	return "", ErrInteractiveUnavailable
+}
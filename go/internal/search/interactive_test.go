@@ -0,0 +1,160 @@
+//go:build !minimal
+
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/keymap"
+)
+
+func TestInteractiveSearch_UnicodeInput(t *testing.T) {
+	secrets := []database.SearchResult{
+		{Key: "Ünïcode_Schlüssel", CreatedAt: time.Now()},
+	}
+
+	is := NewInteractiveSearch(secrets, DisplayOptions{})
+	is.AddRunes([]rune("ü"))
+	assert.Equal(t, "ü", is.query)
+	is.RemoveChar()
+	assert.Equal(t, "", is.query)
+}
+
+func TestInteractiveSearch_VisibleResults(t *testing.T) {
+	var secrets []database.SearchResult
+	for _, key := range []string{"app/a", "app/b", "app/c", "app/d", "app/e", "app/f", "app/g"} {
+		secrets = append(secrets, database.SearchResult{Key: key, CreatedAt: time.Now()})
+	}
+
+	is := NewInteractiveSearch(secrets, DisplayOptions{})
+	is.AddRunes([]rune("app"))
+	assert.Len(t, is.results, MaxDisplayResults)
+	assert.Contains(t, is.Render(), "... and 2 more results")
+
+	// A tall terminal shows every match
+	is.SetHeight(40)
+	assert.Len(t, is.results, 7)
+
+	// A fixed count ignores the terminal height
+	is = NewInteractiveSearch(secrets, DisplayOptions{Results: 3, Density: DensityDetailed})
+	is.SetHeight(40)
+	is.AddRunes([]rune("app"))
+	assert.Len(t, is.results, 3)
+	assert.Contains(t, is.Render(), "namespace: app")
+}
+
+func TestInteractiveSearch_VaultTheme(t *testing.T) {
+	secrets := []database.SearchResult{{Key: "app/a", CreatedAt: time.Now()}}
+
+	is := NewInteractiveSearch(secrets, DisplayOptions{})
+	assert.NotContains(t, is.Render(), "╭")
+
+	is = NewInteractiveSearch(secrets, DisplayOptions{Label: "work", Color: "1"})
+	view := is.Render()
+	assert.Contains(t, view, "[work]")
+	assert.Contains(t, view, "╭")
+
+	// The frame takes two lines from the results
+	plain := NewInteractiveSearch(secrets, DisplayOptions{})
+	plain.SetHeight(20)
+	is.SetHeight(20)
+	assert.Equal(t, plain.visible-frameLines, is.visible)
+}
+
+func TestInteractiveSearch_Qualifiers(t *testing.T) {
+	prod := "prod"
+	secrets := []database.SearchResult{
+		{Key: "app/db", Tags: &prod, CreatedAt: time.Now()},
+		{Key: "app/dbx", CreatedAt: time.Now()},
+	}
+
+	is := NewInteractiveSearch(secrets, DisplayOptions{})
+	is.AddRunes([]rune("db tag:prod"))
+	assert.Len(t, is.results, 1)
+	assert.Equal(t, "app/db", is.results[0].Result.Key)
+
+	is.RemoveChar()
+	is.AddRunes([]rune("x"))
+	assert.Empty(t, is.results)
+
+	is = NewInteractiveSearch(secrets, DisplayOptions{})
+	is.AddRunes([]rune("count:>x"))
+	assert.Empty(t, is.results)
+	assert.Contains(t, is.Render(), "not a number")
+}
+
+func TestModel_Keys(t *testing.T) {
+	secrets := []database.SearchResult{
+		{Key: "api/github", CreatedAt: time.Now()},
+		{Key: "api/stripe", CreatedAt: time.Now()},
+	}
+	keys, err := keymap.Picker().Remap(map[string][]string{keymap.Down: {"ctrl+j"}})
+	require.NoError(t, err)
+
+	var m tea.Model = NewModel(secrets, DisplayOptions{Keys: keys})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("api")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlJ})
+	assert.Equal(t, 1, m.(Model).search.selected)
+
+	// ? opens the overlay, which swallows keys until closed
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("?")})
+	assert.Contains(t, m.View(), "ctrl+j")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, "api", m.(Model).search.query)
+	assert.Contains(t, m.View(), "? for help")
+}
+
+func TestModel_Reveal(t *testing.T) {
+	secrets := []database.SearchResult{
+		{Key: "api/github", CreatedAt: time.Now()},
+		{Key: "bank/main", CreatedAt: time.Now()},
+	}
+	var asked [][]byte
+	reveal := func(key string, password []byte) ([]byte, error) {
+		asked = append(asked, password)
+		if key == "bank/main" && string(password) != "pw" {
+			return nil, ErrPasswordRequired
+		}
+		return []byte("value of " + key), nil
+	}
+
+	var m tea.Model = NewModel(secrets, DisplayOptions{Reveal: reveal})
+	ctrlR := tea.KeyMsg{Type: tea.KeyCtrlR}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("api")})
+	assert.Contains(t, m.View(), "Value: ••••••••")
+
+	// The value shows until its timer fires, then is scrubbed
+	m, cmd := m.Update(ctrlR)
+	require.NotNil(t, cmd)
+	assert.Contains(t, m.View(), "value of api/github")
+	held := m.(Model).search.revealed
+	m, _ = m.Update(hideMsg{gen: m.(Model).search.revealGen})
+	assert.NotContains(t, m.View(), "value of api/github")
+	assert.Equal(t, make([]byte, len(held)), held)
+
+	// Any other key masks it at once
+	m, _ = m.Update(ctrlR)
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	assert.NotContains(t, m.View(), "value of api/github")
+
+	// Sensitive secrets prompt for the password first, then scrub it
+	asked = nil
+	var search tea.Model = NewModel(secrets, DisplayOptions{Reveal: reveal})
+	search, _ = search.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("bank")})
+	search, _ = search.Update(ctrlR)
+	assert.Contains(t, search.View(), "Vault password to reveal bank/main")
+	search, _ = search.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("pw")})
+	assert.Contains(t, search.View(), "••█")
+	assert.Equal(t, "bank", search.(Model).search.query, "the prompt takes typed keys")
+	search, _ = search.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Contains(t, search.View(), "value of bank/main")
+	require.Len(t, asked, 2)
+	assert.Equal(t, []byte{0, 0}, asked[1])
+}
@@ -0,0 +1,179 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lockr/go/internal/database"
+)
+
+// Query is a parsed search query: free text ranked by the fuzzy engine plus
+// metadata qualifiers that every result must satisfy. Qualifiers are words
+// of the form name:value:
+//
+//	tag:prod              carries the tag prod
+//	ns:work/              key is in the namespace work/
+//	created:>2024-01-01   created after that day (also <, >=, <=, =)
+//	accessed:<2024-06-01  last retrieved before that day
+//	count:>10             retrieved more than 10 times
+//
+// Words with any other prefix are part of the text, since keys may contain
+// colons.
+type Query struct {
+	// Text is what remains after removing the qualifiers, for fuzzy matching
+	Text string
+
+	filters []func(database.SearchResult) bool
+}
+
+// qualifiers maps each qualifier name to the parser of its value
+var qualifiers = map[string]func(value string) (func(database.SearchResult) bool, error){
+	"tag":      parseTagFilter,
+	"ns":       parseNamespaceFilter,
+	"created":  dateFilter(func(r database.SearchResult) time.Time { return r.CreatedAt }),
+	"accessed": dateFilter(func(r database.SearchResult) time.Time { return r.LastAccessed }),
+	"count":    parseCountFilter,
+}
+
+// ParseQuery splits a query into fuzzy text and qualifiers
+func ParseQuery(query string) (Query, error) {
+	var q Query
+	var text []string
+
+	for _, word := range strings.Fields(query) {
+		name, value, ok := strings.Cut(word, ":")
+		parse, known := qualifiers[strings.ToLower(name)]
+		if !ok || !known {
+			text = append(text, word)
+			continue
+		}
+		if value == "" {
+			return Query{}, fmt.Errorf("%s: needs a value", name)
+		}
+
+		filter, err := parse(value)
+		if err != nil {
+			return Query{}, fmt.Errorf("%s: %w", name, err)
+		}
+		q.filters = append(q.filters, filter)
+	}
+
+	q.Text = strings.Join(text, " ")
+	return q, nil
+}
+
+// HasQualifiers reports whether the query filters on metadata
+func (q Query) HasQualifiers() bool {
+	return len(q.filters) > 0
+}
+
+// Match reports whether result satisfies every qualifier
+func (q Query) Match(result database.SearchResult) bool {
+	for _, filter := range q.filters {
+		if !filter(result) {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter returns the secrets that satisfy every qualifier, in order
+func (q Query) Filter(secrets []database.SearchResult) []database.SearchResult {
+	if !q.HasQualifiers() {
+		return secrets
+	}
+
+	var matched []database.SearchResult
+	for _, secret := range secrets {
+		if q.Match(secret) {
+			matched = append(matched, secret)
+		}
+	}
+	return matched
+}
+
+// SearchQuery ranks the secrets that satisfy the query's qualifiers by its
+// text
+func (e *Engine) SearchQuery(q Query, secrets []database.SearchResult) []MatchResult {
+	return e.Search(q.Text, q.Filter(secrets))
+}
+
+func parseTagFilter(value string) (func(database.SearchResult) bool, error) {
+	want := strings.ToLower(value)
+	return func(r database.SearchResult) bool {
+		if r.Tags == nil {
+			return false
+		}
+		for _, tag := range strings.Split(*r.Tags, ",") {
+			if strings.ToLower(strings.TrimSpace(tag)) == want {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func parseNamespaceFilter(value string) (func(database.SearchResult) bool, error) {
+	prefix := strings.ToLower(value)
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return func(r database.SearchResult) bool {
+		return strings.HasPrefix(strings.ToLower(r.Key), prefix)
+	}, nil
+}
+
+func parseCountFilter(value string) (func(database.SearchResult) bool, error) {
+	op, operand := splitComparison(value)
+	n, err := strconv.ParseInt(operand, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a number", operand)
+	}
+	return func(r database.SearchResult) bool {
+		return compare(op, r.AccessCount, n)
+	}, nil
+}
+
+// dateFilter returns a parser comparing the day of field, in local time,
+// with a YYYY-MM-DD value
+func dateFilter(field func(database.SearchResult) time.Time) func(string) (func(database.SearchResult) bool, error) {
+	return func(value string) (func(database.SearchResult) bool, error) {
+		op, operand := splitComparison(value)
+		if _, err := time.Parse(time.DateOnly, operand); err != nil {
+			return nil, fmt.Errorf("%q is not a date (use YYYY-MM-DD)", operand)
+		}
+		return func(r database.SearchResult) bool {
+			// Dates in this layout order the same as strings
+			return compare(op, field(r).Local().Format(time.DateOnly), operand)
+		}, nil
+	}
+}
+
+// splitComparison separates a leading comparison operator from its operand;
+// a value without one compares for equality
+func splitComparison(value string) (string, string) {
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, op) {
+			return op, value[len(op):]
+		}
+	}
+	return "=", value
+}
+
+// compare applies a comparison operator from splitComparison
+func compare[T int64 | string](op string, a, b T) bool {
+	switch op {
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	default:
+		return a == b
+	}
+}
@@ -0,0 +1,68 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lockr/go/internal/database"
+)
+
+func querySecrets() []database.SearchResult {
+	prod, dev := "prod, db", "dev"
+	return []database.SearchResult{
+		{Key: "work/db/password", Tags: &prod, AccessCount: 12, CreatedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)},
+		{Key: "work/api/token", Tags: &dev, AccessCount: 3, CreatedAt: time.Date(2023, 6, 1, 12, 0, 0, 0, time.Local)},
+		{Key: "home/wifi", AccessCount: 40, CreatedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)},
+	}
+}
+
+func filteredKeys(t *testing.T, query string) []string {
+	q, err := ParseQuery(query)
+	require.NoError(t, err, query)
+	var keys []string
+	for _, r := range q.Filter(querySecrets()) {
+		keys = append(keys, r.Key)
+	}
+	return keys
+}
+
+func TestParseQuery_Qualifiers(t *testing.T) {
+	assert.Equal(t, []string{"work/db/password"}, filteredKeys(t, "tag:PROD"))
+	assert.Equal(t, []string{"work/db/password", "work/api/token"}, filteredKeys(t, "ns:work"))
+	assert.Equal(t, []string{"work/db/password", "work/api/token"}, filteredKeys(t, "ns:work/"))
+	assert.Equal(t, []string{"work/db/password"}, filteredKeys(t, "created:>2024-01-01"))
+	assert.Equal(t, []string{"work/db/password", "home/wifi"}, filteredKeys(t, "created:>=2024-01-01"))
+	assert.Equal(t, []string{"home/wifi"}, filteredKeys(t, "created:2024-01-01"))
+	assert.Equal(t, []string{"work/db/password", "home/wifi"}, filteredKeys(t, "count:>10"))
+	assert.Equal(t, []string{"work/db/password"}, filteredKeys(t, "count:>10 ns:work"))
+}
+
+func TestParseQuery_Text(t *testing.T) {
+	q, err := ParseQuery("db tag:prod url:x pass")
+	require.NoError(t, err)
+	assert.Equal(t, "db url:x pass", q.Text)
+	assert.True(t, q.HasQualifiers())
+
+	q, err = ParseQuery("plain text")
+	require.NoError(t, err)
+	assert.False(t, q.HasQualifiers())
+	assert.Len(t, q.Filter(querySecrets()), 3)
+}
+
+func TestParseQuery_Invalid(t *testing.T) {
+	for _, query := range []string{"tag:", "count:>many", "created:yesterday", "created:<2024-13-01"} {
+		_, err := ParseQuery(query)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestEngine_SearchQuery(t *testing.T) {
+	q, err := ParseQuery("ns:work token")
+	require.NoError(t, err)
+	matches := NewEngine().SearchQuery(q, querySecrets())
+	require.Len(t, matches, 1)
+	assert.Equal(t, "work/api/token", matches[0].Result.Key)
+}
@@ -0,0 +1,24 @@
+// Package security provides small primitives shared by authentication flows:
+// constant-time comparison of user-supplied secrets and rate limiting of
+// failed attempts.
+package security
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+)
+
+// Equal reports whether a and b are equal without leaking, through timing,
+// how many leading bytes match. Use it whenever a user-supplied token, PIN or
+// password is compared against a stored value.
+func Equal(a, b string) bool {
+	return EqualBytes([]byte(a), []byte(b))
+}
+
+// EqualBytes is Equal for byte slices. Both inputs are hashed first so the
+// comparison time does not depend on the length of the stored value either.
+func EqualBytes(a, b []byte) bool {
+	ha := sha256.Sum256(a)
+	hb := sha256.Sum256(b)
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
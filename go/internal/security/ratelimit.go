@@ -0,0 +1,145 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned (wrapped in a *RateLimitError) when a key has
+// too many recent failures
+var ErrRateLimited = errors.New("too many failed attempts")
+
+// RateLimitError reports how long the caller must wait before retrying
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v; retry in %v", ErrRateLimited, e.RetryAfter.Round(time.Second))
+}
+
+// Unwrap lets errors.Is match ErrRateLimited
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// Default limits for authentication endpoints
+const (
+	DefaultMaxFailures = 5
+	DefaultWindow      = time.Minute
+	DefaultLockout     = 5 * time.Minute
+)
+
+// Limiter tracks failed attempts per key (a client address, token ID, or
+// "pin") and locks a key out once it reaches maxFailures within window.
+// It is safe for concurrent use.
+type Limiter struct {
+	mu          sync.Mutex
+	maxFailures int
+	window      time.Duration
+	lockout     time.Duration
+	now         func() time.Time
+	entries     map[string]*limiterEntry
+}
+
+type limiterEntry struct {
+	failures    []time.Time
+	lockedUntil time.Time
+}
+
+// NewLimiter creates a limiter allowing maxFailures failures per window
+// before locking a key out for lockout
+func NewLimiter(maxFailures int, window, lockout time.Duration) *Limiter {
+	return &Limiter{
+		maxFailures: maxFailures,
+		window:      window,
+		lockout:     lockout,
+		now:         time.Now,
+		entries:     make(map[string]*limiterEntry),
+	}
+}
+
+// NewDefaultLimiter creates a limiter with the default authentication limits
+func NewDefaultLimiter() *Limiter {
+	return NewLimiter(DefaultMaxFailures, DefaultWindow, DefaultLockout)
+}
+
+// Check returns a *RateLimitError if key is currently locked out. Call it
+// before verifying credentials.
+func (l *Limiter) Check(key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.entries[key]
+	if !ok {
+		return nil
+	}
+
+	if remaining := entry.lockedUntil.Sub(l.now()); remaining > 0 {
+		return &RateLimitError{RetryAfter: remaining}
+	}
+	return nil
+}
+
+// Failure records a failed attempt for key, locking it out once the limit is
+// reached. It returns the resulting lockout error, if any.
+func (l *Limiter) Failure(key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	entry, ok := l.entries[key]
+	if !ok {
+		entry = &limiterEntry{}
+		l.entries[key] = entry
+	}
+
+	// Drop failures that have left the window
+	cutoff := now.Add(-l.window)
+	kept := entry.failures[:0]
+	for _, t := range entry.failures {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	entry.failures = append(kept, now)
+
+	if len(entry.failures) >= l.maxFailures {
+		entry.lockedUntil = now.Add(l.lockout)
+		entry.failures = nil
+		return &RateLimitError{RetryAfter: l.lockout}
+	}
+	return nil
+}
+
+// Success clears the failure history for key
+func (l *Limiter) Success(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, key)
+}
+
+// Backoff returns an exponentially growing delay to impose after the given
+// number of consecutive failures: nothing for the first two, then 1s, 2s,
+// 4s, ... capped at max
+func Backoff(failures int, max time.Duration) time.Duration {
+	const free = 2
+	if failures <= free {
+		return 0
+	}
+
+	delay := time.Second
+	for i := free + 1; i < failures; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
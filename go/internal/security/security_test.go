@@ -0,0 +1,68 @@
+package security
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEqual(t *testing.T) {
+	assert.True(t, Equal("1234", "1234"))
+	assert.False(t, Equal("1234", "1235"))
+	assert.False(t, Equal("1234", "12345"))
+	assert.True(t, Equal("", ""))
+}
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter(3, time.Minute, 5*time.Minute)
+	l.now = func() time.Time { return now }
+
+	require.NoError(t, l.Check("pin"))
+	require.NoError(t, l.Failure("pin"))
+	require.NoError(t, l.Failure("pin"))
+
+	err := l.Failure("pin")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrRateLimited))
+
+	var rateErr *RateLimitError
+	require.ErrorAs(t, l.Check("pin"), &rateErr)
+	assert.Equal(t, 5*time.Minute, rateErr.RetryAfter)
+
+	// Other keys are unaffected
+	assert.NoError(t, l.Check("token-1"))
+
+	// The lockout expires
+	now = now.Add(5*time.Minute + time.Second)
+	assert.NoError(t, l.Check("pin"))
+}
+
+func TestLimiter_WindowAndSuccess(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter(2, time.Minute, time.Minute)
+	l.now = func() time.Time { return now }
+
+	// Failures outside the window don't accumulate
+	require.NoError(t, l.Failure("a"))
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, l.Failure("a"))
+
+	// Success resets the count
+	l.Success("a")
+	require.NoError(t, l.Failure("a"))
+	assert.NoError(t, l.Check("a"))
+}
+
+func TestBackoff(t *testing.T) {
+	max := 30 * time.Second
+	assert.Equal(t, time.Duration(0), Backoff(0, max))
+	assert.Equal(t, time.Duration(0), Backoff(2, max))
+	assert.Equal(t, time.Second, Backoff(3, max))
+	assert.Equal(t, 2*time.Second, Backoff(4, max))
+	assert.Equal(t, 8*time.Second, Backoff(6, max))
+	assert.Equal(t, max, Backoff(20, max))
+}
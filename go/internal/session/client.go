@@ -0,0 +1,56 @@
+package session
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/lockr/go/internal/database"
+	"golang.org/x/term"
+)
+
+// DetectClientInfo gathers the local context recorded with auth attempts and
+// audit events. client identifies the caller (database.ClientCLI,
+// ClientAgent, ClientREST or ClientEditor).
+func DetectClientInfo(client string) database.ClientInfo {
+	info := database.ClientInfo{
+		Username: "unknown",
+		Client:   client,
+	}
+
+	if u, err := user.Current(); err == nil {
+		info.Username = u.Username
+	}
+
+	if hostname, err := os.Hostname(); err == nil {
+		info.Hostname = hostname
+	}
+
+	info.Terminal = detectTerminal()
+	return info
+}
+
+// detectTerminal returns the name of the controlling terminal (e.g.
+// "pts/3"), or an empty string when stdin is not a terminal
+func detectTerminal() string {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return ""
+	}
+
+	// Linux exposes the device behind each descriptor under /proc
+	if target, err := os.Readlink("/proc/self/fd/0"); err == nil && strings.HasPrefix(target, "/dev/") {
+		return strings.TrimPrefix(target, "/dev/")
+	}
+
+	// sshd exports the allocated tty path
+	if tty := os.Getenv("SSH_TTY"); tty != "" {
+		return strings.TrimPrefix(tty, "/dev/")
+	}
+
+	if tty := os.Getenv("TTY"); tty != "" {
+		return filepath.Base(tty)
+	}
+
+	return "tty"
+}
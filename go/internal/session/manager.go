@@ -2,18 +2,27 @@ package session
 
 import (
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
-	"os/user"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/lockr/go/internal/crypto"
 	"github.com/lockr/go/internal/database"
 	"github.com/lockr/go/internal/keyring"
 )
 
+// ErrKeyCacheUnsupported is returned when the storage engine cannot unlock
+// with a cached derived key
+var ErrKeyCacheUnsupported = errors.New("storage engine does not support derived key caching")
+
 const (
-	// SessionTimeout defines how long a session remains valid
+	// SessionTimeout defines how long a session remains valid unless
+	// SetTimeout says otherwise
 	SessionTimeout = 15 * time.Minute
 
 	// SessionIDLength defines the length of session IDs in bytes
@@ -22,56 +31,261 @@ const (
 
 // Manager handles authentication sessions and timeouts
 type Manager struct {
-	db             *database.VaultDatabase
+	db             database.VaultStore
 	currentSession *database.Session
 	keyringMgr     *keyring.Manager
+	client         string
+	identity       string
+	cipherPolicy   database.CipherPolicy
+	sessionFile    string
+	sessionKey     func() (crypto.MasterKey, error)
+	timeout        time.Duration
 }
 
 // NewManager creates a new session manager
-func NewManager(db *database.VaultDatabase) *Manager {
+func NewManager(db database.VaultStore) *Manager {
 	return &Manager{
-		db:         db,
-		keyringMgr: keyring.NewManager(),
+		db:           db,
+		keyringMgr:   keyring.NewManager(),
+		client:       database.ClientCLI,
+		cipherPolicy: database.DefaultCipherPolicy,
+		timeout:      SessionTimeout,
 	}
 }
 
 // NewManagerWithKeyring creates a new session manager with a custom keyring manager
-func NewManagerWithKeyring(db *database.VaultDatabase, kr *keyring.Manager) *Manager {
+func NewManagerWithKeyring(db database.VaultStore, kr *keyring.Manager) *Manager {
 	return &Manager{
-		db:         db,
-		keyringMgr: kr,
+		db:           db,
+		keyringMgr:   kr,
+		client:       database.ClientCLI,
+		cipherPolicy: database.DefaultCipherPolicy,
+		timeout:      SessionTimeout,
 	}
 }
 
+// SetClient sets the client identifier recorded with auth attempts and
+// audit events (defaults to database.ClientCLI)
+func (m *Manager) SetClient(client string) {
+	m.client = client
+}
+
+// SetCipherPolicy sets the minimum cipher parameters checked on every unlock
+func (m *Manager) SetCipherPolicy(policy database.CipherPolicy) {
+	m.cipherPolicy = policy
+}
+
+// SetIdentity sets the identity of the program acting through the client,
+// such as plugin:vscode, recorded with audit events so its use of secrets
+// can be reviewed with 'lockr token usage'
+func (m *Manager) SetIdentity(identity string) {
+	m.identity = identity
+}
+
+// SetTimeout sets how long a session stays valid without activity
+func (m *Manager) SetTimeout(timeout time.Duration) {
+	m.timeout = timeout
+}
+
+// SetSessionFile sets the file that remembers the current session between
+// processes. A later process unlocking the same vault resumes the session
+// named there until it expires, instead of starting a new one.
+func (m *Manager) SetSessionFile(path string) {
+	m.sessionFile = path
+}
+
+// SetSessionKey sets where the key sealing the session file comes from.
+// With one, the session file also keeps the vault's raw key, sealed, so a
+// later process can Resume the session without unlocking the vault again.
+// While key returns an error, as it should until the user has agreed to
+// cache the vault's key, only the session ID is kept.
+func (m *Manager) SetSessionKey(key func() (crypto.MasterKey, error)) {
+	m.sessionKey = key
+}
+
+// ClientInfo returns the local context of the current process
+func (m *Manager) ClientInfo() database.ClientInfo {
+	info := DetectClientInfo(m.client)
+	info.Identity = m.identity
+	return info
+}
+
 // Authenticate attempts to authenticate with the given password and creates a session
 func (m *Manager) Authenticate(password string) error {
-	// Get current user for logging
-	currentUser, err := user.Current()
+	var key []byte
+	open := func() error { return m.db.Connect(password) }
+	if keyed, ok := m.db.(database.KeyedStore); ok && m.sessionKey != nil {
+		// Connect derives the same key; doing it here lets the session file
+		// keep it. A vault that does not exist yet has no salt to derive with.
+		if derived, err := keyed.DeriveKey(password); err == nil {
+			key = derived
+			open = func() error { return keyed.ConnectWithKey(derived) }
+		}
+	}
+	if err := m.connect(open); err != nil {
+		return err
+	}
+
+	// Optionally save password to keyring
+	if m.keyringMgr.IsEnabled() && !m.keyringMgr.HasPassword() {
+		if err := m.keyringMgr.PromptToSave(password); err != nil {
+			// Log warning but continue - keyring is optional
+			fmt.Fprintf(os.Stderr, "Warning: keyring save failed: %v\n", err)
+		}
+	}
+
+	return m.startSession(key)
+}
+
+// AuthenticateWithKey authenticates with a raw key obtained from
+// database.KeyedStore.DeriveKey and creates a session
+func (m *Manager) AuthenticateWithKey(key []byte) error {
+	keyed, ok := m.db.(database.KeyedStore)
+	if !ok {
+		return ErrKeyCacheUnsupported
+	}
+
+	if err := m.connect(func() error { return keyed.ConnectWithKey(key) }); err != nil {
+		return err
+	}
+
+	return m.startSession(key)
+}
+
+// Resume unlocks the vault with the key kept in the session file and
+// carries on with the session named there. It fails with
+// database.ErrInvalidSession when there is nothing to resume: no session
+// file, no key in it, or a session that expired or was revoked.
+func (m *Manager) Resume() error {
+	id, key := m.readSessionFile()
+	if id == "" || key == nil {
+		return database.ErrInvalidSession
+	}
+	keyed, ok := m.db.(database.KeyedStore)
+	if !ok {
+		return ErrKeyCacheUnsupported
+	}
+
+	if err := m.connect(func() error { return keyed.ConnectWithKey(key) }); err != nil {
+		if err == database.ErrAuthenticationFailed {
+			// The vault was rekeyed since the key was saved
+			m.removeSessionFile()
+		}
+		return err
+	}
+	if !m.resumeSession() {
+		m.db.Close()
+		return database.ErrInvalidSession
+	}
+	m.checkCipher()
+	m.checkIntegrity()
+	return nil
+}
+
+// TryAuthenticateWithCachedKey authenticates using the derived key cached in
+// the keyring for vaultID. A cached key that no longer opens the vault (for
+// example after a rekey) is removed.
+func (m *Manager) TryAuthenticateWithCachedKey(vaultID string) error {
+	key, err := m.keyringMgr.GetDerivedKey(vaultID)
 	if err != nil {
-		currentUser = &user.User{Username: "unknown"}
+		return err
 	}
 
-	// Attempt database connection
-	err = m.db.Connect(password)
+	if err := m.AuthenticateWithKey(key); err != nil {
+		if err == database.ErrAuthenticationFailed {
+			m.keyringMgr.DeleteDerivedKey(vaultID)
+		}
+		return err
+	}
+	return nil
+}
+
+// CacheDerivedKey derives the vault's raw key from password and stores it in
+// the keyring so later unlocks can skip key derivation
+func (m *Manager) CacheDerivedKey(vaultID, password string) error {
+	keyed, ok := m.db.(database.KeyedStore)
+	if !ok {
+		return ErrKeyCacheUnsupported
+	}
+
+	key, err := keyed.DeriveKey(password)
+	if err != nil {
+		return err
+	}
+
+	return m.keyringMgr.SaveDerivedKey(vaultID, key)
+}
+
+// Probe reports whether the vault can be unlocked with non-interactive
+// credentials (a cached derived key or the keyring password). The connection
+// is closed again without creating a session or logging an auth attempt.
+func (m *Manager) Probe(vaultID string) error {
+	err := m.probeCachedKey(vaultID)
+	if err != nil {
+		err = m.probeKeyring()
+	}
+	if err != nil {
+		return err
+	}
+	return m.db.Close()
+}
+
+// probeCachedKey opens the vault with the derived key cached for vaultID
+func (m *Manager) probeCachedKey(vaultID string) error {
+	keyed, ok := m.db.(database.KeyedStore)
+	if !ok {
+		return ErrKeyCacheUnsupported
+	}
 
-	// Log the authentication attempt
+	key, err := m.keyringMgr.GetDerivedKey(vaultID)
+	if err != nil {
+		return err
+	}
+	return keyed.ConnectWithKey(key)
+}
+
+// probeKeyring opens the vault with the password stored in the keyring
+func (m *Manager) probeKeyring() error {
+	if !m.keyringMgr.IsEnabled() || !m.keyringMgr.HasPassword() {
+		return keyring.ErrPasswordNotFound
+	}
+
+	password, err := m.keyringMgr.GetPassword()
+	if err != nil {
+		return err
+	}
+	return m.db.Connect(password)
+}
+
+// connect opens the database using open and records the attempt
+func (m *Manager) connect(open func() error) error {
+	err := open()
+
+	// Log the authentication attempt with where it came from
 	success := err == nil
-	logErr := m.db.LogAuthAttempt(currentUser.Username, success, nil, nil)
+	logErr := m.db.LogAuthAttempt(m.ClientInfo(), success, nil)
 	if logErr != nil && success {
 		// If we successfully authenticated but failed to log, continue anyway
 		fmt.Fprintf(os.Stderr, "Warning: failed to log authentication attempt: %v\n", logErr)
 	}
 
-	if err != nil {
-		return err
+	return err
+}
+
+// startSession creates and stores a new session after a successful unlock
+// with key, the vault's raw key if known
+func (m *Manager) startSession(key []byte) error {
+	// Remove sessions left behind by processes that never logged out
+	if err := m.CleanExpiredSessions(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to clean expired sessions: %v\n", err)
 	}
 
-	// Optionally save password to keyring
-	if m.keyringMgr.IsEnabled() && !m.keyringMgr.HasPassword() {
-		if err := m.keyringMgr.PromptToSave(password); err != nil {
-			// Log warning but continue - keyring is optional
-			fmt.Fprintf(os.Stderr, "Warning: keyring save failed: %v\n", err)
-		}
+	// Carry on with the session of an earlier command while it lasts
+	if m.resumeSession() {
+		m.saveSessionFile(key)
+		m.checkCipher()
+		m.checkIntegrity()
+		return nil
 	}
 
 	// Create a new session
@@ -83,7 +297,7 @@ func (m *Manager) Authenticate(password string) error {
 	session := &database.Session{
 		SessionID:    sessionID,
 		CreatedAt:    time.Now(),
-		ExpiresAt:    time.Now().Add(SessionTimeout),
+		ExpiresAt:    time.Now().Add(m.timeout),
 		LastActivity: time.Now(),
 	}
 
@@ -93,9 +307,57 @@ func (m *Manager) Authenticate(password string) error {
 	}
 
 	m.currentSession = session
+	m.saveSessionFile(key)
+	m.checkCipher()
+	m.checkIntegrity()
 	return nil
 }
 
+// checkIntegrity warns and records an audit event when the vault's contents
+// did not match its checksum on open
+func (m *Manager) checkIntegrity() {
+	checker, ok := m.db.(database.IntegrityChecker)
+	if !ok {
+		return
+	}
+
+	details := "ok"
+	if err := checker.IntegrityError(); err != nil {
+		details = err.Error()
+		fmt.Fprintf(os.Stderr, "Warning: %v. Review the vault and run 'lockr integrity --accept' if the changes are expected.\n", err)
+	}
+
+	if err := m.audit(database.AuditEventIntegrityCheck, "", details); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record integrity check: %v\n", err)
+	}
+}
+
+// checkCipher compares the vault's cipher parameters with the configured
+// policy, warns about anything weaker (an old client or tampering may have
+// downgraded the vault) and records the result in the audit log
+func (m *Manager) checkCipher() {
+	inspector, ok := m.db.(database.CipherInspector)
+	if !ok {
+		return
+	}
+
+	settings, err := inspector.CipherSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to read cipher settings: %v\n", err)
+		return
+	}
+
+	details := "ok"
+	if problems := settings.Violations(m.cipherPolicy); len(problems) > 0 {
+		details = strings.Join(problems, "; ")
+		fmt.Fprintf(os.Stderr, "Warning: vault uses weaker encryption than required (possible downgrade): %s\n", details)
+	}
+
+	if err := m.audit(database.AuditEventCipherCheck, "", details); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record cipher check: %v\n", err)
+	}
+}
+
 // IsAuthenticated checks if there's an active, valid session
 func (m *Manager) IsAuthenticated() bool {
 	if m.currentSession == nil {
@@ -111,6 +373,13 @@ func (m *Manager) IsAuthenticated() bool {
 	return m.db.IsConnected()
 }
 
+// Expired reports whether the current session has timed out. Unlike
+// IsAuthenticated it leaves the session in place, so a timeout can be told
+// apart from never having unlocked.
+func (m *Manager) Expired() bool {
+	return m.currentSession != nil && time.Now().After(m.currentSession.ExpiresAt)
+}
+
 // RefreshSession updates the session's last activity time and extends expiration
 func (m *Manager) RefreshSession() error {
 	if m.currentSession == nil {
@@ -125,7 +394,7 @@ func (m *Manager) RefreshSession() error {
 
 	// Update session activity
 	m.currentSession.LastActivity = time.Now()
-	m.currentSession.ExpiresAt = time.Now().Add(SessionTimeout)
+	m.currentSession.ExpiresAt = time.Now().Add(m.timeout)
 
 	// Update in database
 	return m.updateSession(m.currentSession)
@@ -150,6 +419,25 @@ func (m *Manager) Logout() error {
 
 	// Clear local session
 	m.currentSession = nil
+	m.removeSessionFile()
+	return nil
+}
+
+// Detach closes the vault but keeps the current session, so the next
+// process to unlock the vault resumes it. Without a session file it is the
+// same as Logout.
+func (m *Manager) Detach() error {
+	if m.sessionFile == "" {
+		return m.Logout()
+	}
+	if m.currentSession == nil {
+		return nil
+	}
+
+	if err := m.db.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close database connection: %v\n", err)
+	}
+	m.currentSession = nil
 	return nil
 }
 
@@ -180,10 +468,67 @@ func (m *Manager) CleanExpiredSessions() error {
 		return database.ErrDatabaseNotConnected
 	}
 
-	// This is a simplified version - in the full implementation,
-	// we would need to access the underlying sql.DB connection
-	// For now, we'll skip this operation
-	return nil
+	_, err := m.db.DeleteExpiredSessions(time.Now())
+	return err
+}
+
+// ListSessions returns all sessions recorded in the vault
+func (m *Manager) ListSessions() ([]database.Session, error) {
+	if !m.db.IsConnected() {
+		return nil, database.ErrDatabaseNotConnected
+	}
+	return m.db.ListSessions()
+}
+
+// RevokeSession deletes a session from the vault. Revoking the current
+// session also logs out.
+func (m *Manager) RevokeSession(sessionID string) error {
+	if m.currentSession != nil && m.currentSession.SessionID == sessionID {
+		if err := m.db.DeleteSession(sessionID); err != nil {
+			return err
+		}
+		m.currentSession = nil
+		m.removeSessionFile()
+		return nil
+	}
+	return m.db.DeleteSession(sessionID)
+}
+
+// Audit records an operation on key in the vault's audit log
+func (m *Manager) Audit(event, key string) error {
+	return m.audit(event, key, "")
+}
+
+// AuditDenial records a request refused by access control. It matches
+// authz.DenyFunc so it can be handed to an Authorizer.
+func (m *Manager) AuditDenial(client, op, key string) {
+	details := fmt.Sprintf("client=%s op=%s", client, op)
+	if err := m.audit(database.AuditEventAccessDenied, key, details); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record access denial: %v\n", err)
+	}
+}
+
+// audit records an audit event with optional details
+func (m *Manager) audit(event, key, details string) error {
+	if !m.db.IsConnected() {
+		return database.ErrDatabaseNotConnected
+	}
+
+	entry := &database.AuditEvent{
+		Event:  event,
+		Client: m.ClientInfo(),
+	}
+	if key != "" {
+		entry.Key = &key
+	}
+	if details != "" {
+		entry.Details = &details
+	}
+	if m.currentSession != nil {
+		entry.SessionID = &m.currentSession.SessionID
+	}
+
+	return m.db.LogAuditEvent(entry)
 }
 
 // expireSession handles session expiration cleanup
@@ -193,6 +538,7 @@ func (m *Manager) expireSession() {
 		m.deleteSession(m.currentSession.SessionID)
 		m.currentSession = nil
 	}
+	m.removeSessionFile()
 
 	// Close database connection
 	m.db.Close()
@@ -200,24 +546,122 @@ func (m *Manager) expireSession() {
 
 // createSession stores a new session in the database
 func (m *Manager) createSession(session *database.Session) error {
-	// In a full implementation, we would need direct SQL access
-	// For now, we'll store the session locally only
-	// This is a limitation of the current database abstraction
-	return nil
+	return m.db.CreateSession(session)
 }
 
 // updateSession updates an existing session in the database
 func (m *Manager) updateSession(session *database.Session) error {
-	// In a full implementation, we would need direct SQL access
-	// For now, we'll update the local session only
-	return nil
+	return m.db.UpdateSession(session)
 }
 
 // deleteSession removes a session from the database
 func (m *Manager) deleteSession(sessionID string) error {
-	// In a full implementation, we would need direct SQL access
-	// For now, we'll just clear the local session
-	return nil
+	if !m.db.IsConnected() {
+		return nil
+	}
+	err := m.db.DeleteSession(sessionID)
+	if err == database.ErrInvalidSession {
+		return nil // Already removed (e.g. revoked by another client)
+	}
+	return err
+}
+
+// resumeSession picks up the unexpired session recorded in the session file
+// by an earlier process and extends it
+func (m *Manager) resumeSession() bool {
+	id, _ := m.readSessionFile()
+	if id == "" {
+		return false
+	}
+
+	// A missing row covers revoked sessions and expired ones cleaned out;
+	// RefreshSession turns away the rest
+	session, err := m.db.GetSession(id)
+	if err != nil {
+		m.removeSessionFile()
+		return false
+	}
+
+	m.currentSession = session
+	if err := m.RefreshSession(); err != nil {
+		m.currentSession = nil
+		return false
+	}
+	return true
+}
+
+// saveSessionFile records the current session for later processes: its ID
+// on the first line and, when there is a session key, the vault's raw key
+// sealed with it on the second
+func (m *Manager) saveSessionFile(key []byte) {
+	if m.sessionFile == "" {
+		return
+	}
+	id := m.currentSession.SessionID
+	data := id + "\n"
+	if key != nil && m.sessionKey != nil {
+		// Without the session key (no keyring) only the ID is kept
+		if sessionKey, err := m.sessionKey(); err == nil {
+			if sealed, err := sessionKey.Seal(key, []byte(id)); err == nil {
+				data += base64.StdEncoding.EncodeToString(sealed) + "\n"
+			}
+		}
+	}
+
+	err := os.MkdirAll(filepath.Dir(m.sessionFile), 0700)
+	if err == nil {
+		err = os.WriteFile(m.sessionFile, []byte(data), 0600)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save session: %v\n", err)
+	}
+}
+
+// readSessionFile returns the session ID recorded in the session file and
+// the vault key kept with it, nil if there is none or it cannot be opened
+func (m *Manager) readSessionFile() (string, []byte) {
+	if m.sessionFile == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(m.sessionFile)
+	if err != nil {
+		return "", nil
+	}
+	lines := strings.Fields(string(data))
+	if len(lines) == 0 {
+		return "", nil
+	}
+	id := lines[0]
+	if len(lines) < 2 || m.sessionKey == nil {
+		return id, nil
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		return id, nil
+	}
+	sessionKey, err := m.sessionKey()
+	if err != nil {
+		return id, nil
+	}
+	key, err := sessionKey.Open(sealed, []byte(id))
+	if err != nil {
+		return id, nil
+	}
+	return id, key
+}
+
+// ForgetSessionFile removes the session file, and any vault key kept in it,
+// so later processes unlock the vault again
+func (m *Manager) ForgetSessionFile() {
+	m.removeSessionFile()
+}
+
+// removeSessionFile forgets the session recorded for later processes
+func (m *Manager) removeSessionFile() {
+	if m.sessionFile != "" {
+		os.Remove(m.sessionFile)
+	}
 }
 
 // generateSessionID creates a cryptographically secure random session ID
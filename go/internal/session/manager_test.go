@@ -0,0 +1,117 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lockr/go/internal/crypto"
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/keyring"
+)
+
+// testSessionKey stands in for the session key the CLI keeps in the keyring
+var testSessionKey, _ = crypto.GenerateMasterKey()
+
+// newTestManager opens a manager on the vault at path, as a new process would
+func newTestManager(path, sessionFile string) *Manager {
+	kr := keyring.NewManager()
+	kr.Disable()
+	m := NewManagerWithKeyring(database.NewVaultDatabase(path), kr)
+	m.SetSessionFile(sessionFile)
+	m.SetSessionKey(func() (crypto.MasterKey, error) { return testSessionKey, nil })
+	return m
+}
+
+func TestManager_SessionFile(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lockr_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	vaultPath := filepath.Join(tmpDir, "test.db")
+	sessionFile := filepath.Join(tmpDir, "sessions", "vault")
+	password := "test_password_123"
+
+	// Create the vault; its key can only be kept once it has a salt
+	setup := newTestManager(vaultPath, "")
+	require.NoError(t, setup.Authenticate(password))
+	require.NoError(t, setup.Logout())
+
+	first := newTestManager(vaultPath, sessionFile)
+	require.NoError(t, first.Authenticate(password))
+	id := first.GetCurrentSession().SessionID
+	require.NoError(t, first.Detach())
+	assert.False(t, first.IsAuthenticated())
+
+	// The next process carries on with the same session, without the password
+	second := newTestManager(vaultPath, sessionFile)
+	assert.False(t, second.IsAuthenticated())
+	require.NoError(t, second.Resume())
+	assert.True(t, second.IsAuthenticated())
+	assert.Equal(t, id, second.GetCurrentSession().SessionID)
+	sessions, err := second.ListSessions()
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+
+	// Once expired, its row is deleted and a new session starts
+	session := second.GetCurrentSession()
+	session.ExpiresAt = time.Now().Add(-time.Minute)
+	require.NoError(t, second.db.UpdateSession(session))
+	require.NoError(t, second.Detach())
+
+	third := newTestManager(vaultPath, sessionFile)
+	assert.ErrorIs(t, third.Resume(), database.ErrInvalidSession)
+	assert.False(t, third.IsAuthenticated())
+	require.NoError(t, third.Authenticate(password))
+	assert.NotEqual(t, id, third.GetCurrentSession().SessionID)
+	sessions, err = third.ListSessions()
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+
+	// A revoked session cannot be resumed
+	revoked := third.GetCurrentSession().SessionID
+	require.NoError(t, third.Detach())
+	admin := newTestManager(vaultPath, "")
+	require.NoError(t, admin.Authenticate(password))
+	require.NoError(t, admin.RevokeSession(revoked))
+	require.NoError(t, admin.Logout())
+	fourth := newTestManager(vaultPath, sessionFile)
+	assert.ErrorIs(t, fourth.Resume(), database.ErrInvalidSession)
+
+	// Without the session key, resuming needs the password again
+	require.NoError(t, fourth.Authenticate(password))
+	require.NoError(t, fourth.Detach())
+	fifth := newTestManager(vaultPath, sessionFile)
+	fifth.SetSessionKey(nil)
+	assert.ErrorIs(t, fifth.Resume(), database.ErrInvalidSession)
+	require.NoError(t, fifth.Authenticate(password))
+	assert.True(t, fifth.IsAuthenticated())
+
+	// While the session key is unavailable, as before the user agrees to
+	// key caching, the vault key is not written
+	require.NoError(t, fifth.Detach())
+	sixth := newTestManager(vaultPath, sessionFile)
+	sixth.SetSessionKey(func() (crypto.MasterKey, error) { return nil, keyring.ErrDerivedKeyNotFound })
+	require.NoError(t, sixth.Authenticate(password))
+	data, err := os.ReadFile(sessionFile)
+	require.NoError(t, err)
+	assert.Equal(t, sixth.GetCurrentSession().SessionID+"\n", string(data))
+	require.NoError(t, sixth.Detach())
+	assert.ErrorIs(t, newTestManager(vaultPath, sessionFile).Resume(), database.ErrInvalidSession)
+
+	// Opting out removes the file
+	sixth.ForgetSessionFile()
+	_, err = os.Stat(sessionFile)
+	assert.True(t, os.IsNotExist(err))
+
+	// Logging out ends the session for good
+	require.NoError(t, fifth.Authenticate(password))
+	require.NoError(t, fifth.Logout())
+	_, err = os.Stat(sessionFile)
+	assert.True(t, os.IsNotExist(err))
+	assert.ErrorIs(t, newTestManager(vaultPath, sessionFile).Resume(), database.ErrInvalidSession)
+}
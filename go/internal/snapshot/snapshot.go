@@ -0,0 +1,99 @@
+// Package snapshot compares frozen copies of a namespace with each other
+// and with the live vault, for promote-style workflows such as checking
+// what differs between staging and production before copying one over the
+// other
+package snapshot
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/lockr/go/internal/database"
+)
+
+// Kinds of difference between two sets of entries
+const (
+	Added     = "added"
+	Removed   = "removed"
+	Changed   = "changed"
+	Unchanged = "unchanged"
+)
+
+// Change describes how one key differs between two sets of entries
+type Change struct {
+	Key  string
+	Kind string
+	// From and To are the entries on each side; nil when missing
+	From *database.SnapshotEntry
+	To   *database.SnapshotEntry
+}
+
+// Diff reports, for every key in either set, how to get from the entries in
+// from to those in to. Keys compare case-insensitively, as in the vault, and
+// only values count as changes. The result is ordered by key.
+func Diff(from, to []database.SnapshotEntry) []Change {
+	index := func(entries []database.SnapshotEntry) map[string]*database.SnapshotEntry {
+		m := make(map[string]*database.SnapshotEntry, len(entries))
+		for i := range entries {
+			m[strings.ToLower(entries[i].Key)] = &entries[i]
+		}
+		return m
+	}
+	a, b := index(from), index(to)
+
+	var changes []Change
+	for k, ea := range a {
+		eb, ok := b[k]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Key: ea.Key, Kind: Removed, From: ea})
+		case ea.Value != eb.Value:
+			changes = append(changes, Change{Key: eb.Key, Kind: Changed, From: ea, To: eb})
+		default:
+			changes = append(changes, Change{Key: eb.Key, Kind: Unchanged, From: ea, To: eb})
+		}
+	}
+	for k, eb := range b {
+		if _, ok := a[k]; !ok {
+			changes = append(changes, Change{Key: eb.Key, Kind: Added, To: eb})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return strings.ToLower(changes[i].Key) < strings.ToLower(changes[j].Key)
+	})
+	return changes
+}
+
+// Count returns how many changes are of each kind
+func Count(changes []Change) map[string]int {
+	counts := make(map[string]int)
+	for _, c := range changes {
+		counts[c.Kind]++
+	}
+	return counts
+}
+
+// FromSecrets turns live secrets under namespace into entries with keys
+// relative to it, as CreateSnapshot stores them. Secrets outside the
+// namespace are skipped.
+func FromSecrets(namespace string, secrets []database.Secret) []database.SnapshotEntry {
+	var entries []database.SnapshotEntry
+	for _, s := range secrets {
+		if len(s.Key) <= len(namespace) || !strings.EqualFold(s.Key[:len(namespace)], namespace) {
+			continue
+		}
+		entries = append(entries, database.SnapshotEntry{
+			Key:   s.Key[len(namespace):],
+			Value: s.Value,
+			Tags:  s.Tags,
+			Notes: s.Notes,
+		})
+	}
+	return entries
+}
+
+// NormalizeNamespace returns ns with exactly one trailing slash
+func NormalizeNamespace(ns string) string {
+	return strings.TrimRight(ns, "/") + "/"
+}
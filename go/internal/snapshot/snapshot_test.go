@@ -0,0 +1,56 @@
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lockr/go/internal/database"
+)
+
+func TestDiff(t *testing.T) {
+	from := []database.SnapshotEntry{
+		{Key: "db/password", Value: "a"},
+		{Key: "api/token", Value: "t"},
+		{Key: "old", Value: "o"},
+	}
+	to := []database.SnapshotEntry{
+		{Key: "DB/password", Value: "b"},
+		{Key: "api/token", Value: "t"},
+		{Key: "new", Value: "n"},
+	}
+
+	changes := Diff(from, to)
+	require.Len(t, changes, 4)
+	assert.Equal(t, Change{Key: "api/token", Kind: Unchanged, From: &from[1], To: &to[1]}, changes[0])
+	assert.Equal(t, "DB/password", changes[1].Key)
+	assert.Equal(t, Changed, changes[1].Kind)
+	assert.Equal(t, Added, changes[2].Kind)
+	assert.Nil(t, changes[2].From)
+	assert.Equal(t, Removed, changes[3].Kind)
+	assert.Nil(t, changes[3].To)
+
+	counts := Count(changes)
+	assert.Equal(t, 1, counts[Added])
+	assert.Equal(t, 1, counts[Removed])
+	assert.Equal(t, 1, counts[Changed])
+	assert.Equal(t, 1, counts[Unchanged])
+}
+
+func TestFromSecrets(t *testing.T) {
+	entries := FromSecrets("staging/", []database.Secret{
+		{Key: "Staging/db", Value: "1"},
+		{Key: "staging/", Value: "namespace itself"},
+		{Key: "stagingx/db", Value: "2"},
+		{Key: "production/db", Value: "3"},
+	})
+	require.Len(t, entries, 1)
+	assert.Equal(t, "db", entries[0].Key)
+}
+
+func TestNormalizeNamespace(t *testing.T) {
+	assert.Equal(t, "staging/", NormalizeNamespace("staging"))
+	assert.Equal(t, "staging/", NormalizeNamespace("staging//"))
+	assert.Equal(t, "a/b/", NormalizeNamespace("a/b"))
+}
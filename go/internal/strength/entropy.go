@@ -0,0 +1,136 @@
+// Package strength estimates the entropy of secret values
+package strength
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Rating is a coarse, human-readable strength category
+type Rating string
+
+// Strength ratings, from weakest to strongest
+const (
+	Weak       Rating = "weak"
+	Fair       Rating = "fair"
+	Strong     Rating = "strong"
+	VeryStrong Rating = "very strong"
+)
+
+// Character pool sizes used by the estimator
+const (
+	lowerPool   = 26
+	upperPool   = 26
+	digitPool   = 10
+	symbolPool  = 33
+	unicodePool = 100
+)
+
+// commonPasswords are values so frequently used that any guesser tries them first
+var commonPasswords = map[string]bool{
+	"password": true, "123456": true, "12345678": true, "123456789": true,
+	"qwerty": true, "abc123": true, "letmein": true, "welcome": true,
+	"admin": true, "iloveyou": true, "monkey": true, "dragon": true,
+	"111111": true, "password1": true, "changeme": true, "secret": true,
+}
+
+// Estimate returns a rough entropy estimate in bits for a human-chosen
+// value. It assumes an attacker knows which character classes are used and
+// discounts repeated and sequential characters. Dictionary words are not
+// modelled, so passphrases built from common words are overestimated.
+func Estimate(value string) float64 {
+	if value == "" {
+		return 0
+	}
+	if commonPasswords[strings.ToLower(value)] {
+		return 0
+	}
+
+	runes := []rune(value)
+	pool := poolSize(runes)
+	return effectiveLength(runes) * math.Log2(float64(pool))
+}
+
+// ForGenerated returns the exact entropy in bits of a value of length
+// characters drawn uniformly from a charset of charsetSize characters
+func ForGenerated(length, charsetSize int) float64 {
+	if length <= 0 || charsetSize <= 1 {
+		return 0
+	}
+	return float64(length) * math.Log2(float64(charsetSize))
+}
+
+// Rate maps an entropy estimate onto a Rating
+func Rate(bits float64) Rating {
+	switch {
+	case bits < 40:
+		return Weak
+	case bits < 60:
+		return Fair
+	case bits < 100:
+		return Strong
+	default:
+		return VeryStrong
+	}
+}
+
+// poolSize sums the sizes of the character classes present in runes
+func poolSize(runes []rune) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	pool := 0
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{
+		{lower, lowerPool},
+		{upper, upperPool},
+		{digit, digitPool},
+		{symbol, symbolPool},
+		{other, unicodePool},
+	} {
+		if class.present {
+			pool += class.size
+		}
+	}
+	if pool < 2 {
+		pool = 2
+	}
+	return pool
+}
+
+// effectiveLength counts characters, giving little weight to those that
+// repeat the previous character or continue an ascending/descending run
+// ("aaaa", "1234", "cba")
+func effectiveLength(runes []rune) float64 {
+	length := 0.0
+	for i, r := range runes {
+		if i == 0 {
+			length++
+			continue
+		}
+
+		delta := r - runes[i-1]
+		if delta == 0 || delta == 1 || delta == -1 {
+			length += 0.25
+			continue
+		}
+		length++
+	}
+	return length
+}
@@ -0,0 +1,34 @@
+package strength
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimate(t *testing.T) {
+	assert.Equal(t, 0.0, Estimate(""))
+	assert.Equal(t, 0.0, Estimate("Password"))
+
+	// Lowercase only: 8 * log2(26)
+	assert.InDelta(t, 37.6, Estimate("zqmvxkwp"), 0.1)
+
+	// Repeats and runs count for much less than random characters
+	assert.Less(t, Estimate("aaaaaaaa"), Estimate("zqmvxkwp"))
+	assert.Less(t, Estimate("abcdefgh"), Estimate("zqmvxkwp"))
+
+	// Adding character classes grows the pool
+	assert.Greater(t, Estimate("zqmV7kw!"), Estimate("zqmvxkwp"))
+}
+
+func TestForGenerated(t *testing.T) {
+	assert.InDelta(t, 128.0, ForGenerated(32, 16), 0.001)
+	assert.Equal(t, 0.0, ForGenerated(0, 90))
+}
+
+func TestRate(t *testing.T) {
+	assert.Equal(t, Weak, Rate(20))
+	assert.Equal(t, Fair, Rate(45))
+	assert.Equal(t, Strong, Rate(80))
+	assert.Equal(t, VeryStrong, Rate(150))
+}
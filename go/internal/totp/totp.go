@@ -0,0 +1,186 @@
+// Package totp generates RFC 6238 time-based one-time passwords from the
+// otpauth:// URIs or bare base32 seeds that sites hand out when two-factor
+// authentication is enabled
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults of RFC 6238 and of the otpauth URI format
+const (
+	DefaultDigits    = 6
+	DefaultPeriod    = 30 * time.Second
+	DefaultAlgorithm = "SHA1"
+)
+
+// MinSecretBytes is the shortest seed accepted. RFC 4226 asks for 128
+// bits, but many sites still hand out 80-bit seeds.
+const MinSecretBytes = 10
+
+// ErrInvalid is returned for values that are neither an otpauth URI nor a
+// base32 seed
+var ErrInvalid = errors.New("not an otpauth:// URI or base32 seed")
+
+// Key is a TOTP seed with its parameters
+type Key struct {
+	Secret    []byte
+	Algorithm string
+	Digits    int
+	Period    time.Duration
+
+	// Issuer and Account label the key; both may be empty
+	Issuer  string
+	Account string
+}
+
+// Parse reads an otpauth://totp/ URI or a base32 seed. Seeds may be in any
+// case and contain spaces or dashes, as sites often display them grouped.
+func Parse(value string) (*Key, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(strings.ToLower(value), "otpauth:") {
+		return parseURI(value)
+	}
+
+	secret, err := decodeSeed(value)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{Secret: secret, Algorithm: DefaultAlgorithm, Digits: DefaultDigits, Period: DefaultPeriod}, nil
+}
+
+func parseURI(value string) (*Key, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	switch strings.ToLower(u.Host) {
+	case "totp":
+	case "hotp":
+		return nil, errors.New("counter-based (HOTP) keys are not supported")
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalid, u.Host)
+	}
+
+	q := u.Query()
+	secret, err := decodeSeed(q.Get("secret"))
+	if err != nil {
+		return nil, err
+	}
+	key := &Key{Secret: secret, Algorithm: DefaultAlgorithm, Digits: DefaultDigits, Period: DefaultPeriod}
+
+	// The label is "issuer:account" or just "account"
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		key.Issuer, key.Account = strings.TrimSpace(issuer), strings.TrimSpace(account)
+	} else {
+		key.Account = label
+	}
+	if issuer := q.Get("issuer"); issuer != "" {
+		key.Issuer = issuer
+	}
+
+	if algorithm := q.Get("algorithm"); algorithm != "" {
+		key.Algorithm = strings.ToUpper(algorithm)
+		if newHash(key.Algorithm) == nil {
+			return nil, fmt.Errorf("unsupported algorithm %q (expected SHA1, SHA256 or SHA512)", algorithm)
+		}
+	}
+	if digits := q.Get("digits"); digits != "" {
+		n, err := strconv.Atoi(digits)
+		if err != nil || n < 6 || n > 10 {
+			return nil, fmt.Errorf("invalid digits %q (expected 6 to 10)", digits)
+		}
+		key.Digits = n
+	}
+	if period := q.Get("period"); period != "" {
+		n, err := strconv.Atoi(period)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid period %q", period)
+		}
+		key.Period = time.Duration(n) * time.Second
+	}
+	return key, nil
+}
+
+// decodeSeed decodes a base32 seed, tolerating case, grouping and missing
+// padding
+func decodeSeed(seed string) ([]byte, error) {
+	seed = strings.ToUpper(strings.NewReplacer(" ", "", "-", "", "=", "").Replace(seed))
+	if seed == "" {
+		return nil, ErrInvalid
+	}
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(seed)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	if len(secret) < MinSecretBytes {
+		return nil, fmt.Errorf("seed is too short (%d bits, need at least %d)", len(secret)*8, MinSecretBytes*8)
+	}
+	return secret, nil
+}
+
+// newHash returns the constructor for an otpauth algorithm name, or nil
+func newHash(algorithm string) func() hash.Hash {
+	switch algorithm {
+	case "SHA1":
+		return sha1.New
+	case "SHA256":
+		return sha256.New
+	case "SHA512":
+		return sha512.New
+	default:
+		return nil
+	}
+}
+
+// Code returns the code valid at t
+func (k *Key) Code(t time.Time) string {
+	counter := uint64(t.Unix() / int64(k.Period/time.Second))
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(newHash(k.Algorithm), k.Secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation (RFC 4226 section 5.3)
+	offset := sum[len(sum)-1] & 0x0f
+	binCode := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint64(1)
+	for i := 0; i < k.Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", k.Digits, uint64(binCode)%mod)
+}
+
+// Remaining returns how long the code valid at t stays valid
+func (k *Key) Remaining(t time.Time) time.Duration {
+	period := int64(k.Period / time.Second)
+	return time.Duration(period-t.Unix()%period) * time.Second
+}
+
+// Label describes the key as "issuer (account)", or "" when it has no label
+func (k *Key) Label() string {
+	switch {
+	case k.Issuer != "" && k.Account != "":
+		return fmt.Sprintf("%s (%s)", k.Issuer, k.Account)
+	case k.Issuer != "":
+		return k.Issuer
+	default:
+		return k.Account
+	}
+}
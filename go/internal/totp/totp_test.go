@@ -0,0 +1,86 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 6238 appendix B test vectors
+func TestCode_RFC6238(t *testing.T) {
+	seeds := map[string]string{
+		"SHA1":   "12345678901234567890",
+		"SHA256": "12345678901234567890123456789012",
+		"SHA512": "1234567890123456789012345678901234567890123456789012345678901234",
+	}
+	vectors := []struct {
+		unix      int64
+		algorithm string
+		code      string
+	}{
+		{59, "SHA1", "94287082"},
+		{59, "SHA256", "46119246"},
+		{59, "SHA512", "90693936"},
+		{1111111109, "SHA1", "07081804"},
+		{1111111109, "SHA256", "68084774"},
+		{1234567890, "SHA512", "93441116"},
+		{20000000000, "SHA1", "65353130"},
+	}
+
+	for _, v := range vectors {
+		key := &Key{Secret: []byte(seeds[v.algorithm]), Algorithm: v.algorithm, Digits: 8, Period: DefaultPeriod}
+		assert.Equal(t, v.code, key.Code(time.Unix(v.unix, 0)), "%s at %d", v.algorithm, v.unix)
+	}
+}
+
+func TestParse_Seed(t *testing.T) {
+	seed := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	key, err := Parse(seed)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultDigits, key.Digits)
+	assert.Equal(t, "287082", key.Code(time.Unix(59, 0)))
+
+	// Lower case, grouped and unpadded
+	key, err = Parse("gezd gnbv gy3t qojq gezd gnbv gy3t qojq")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("12345678901234567890"), key.Secret)
+
+	_, err = Parse("not base32!")
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = Parse("GEZDGNBV")
+	assert.Error(t, err, "seeds under 80 bits are rejected")
+}
+
+func TestParse_URI(t *testing.T) {
+	key, err := Parse("otpauth://totp/Example:alice@example.com?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&issuer=Example&digits=8&period=60&algorithm=sha256")
+	require.NoError(t, err)
+	assert.Equal(t, "Example", key.Issuer)
+	assert.Equal(t, "alice@example.com", key.Account)
+	assert.Equal(t, "Example (alice@example.com)", key.Label())
+	assert.Equal(t, 8, key.Digits)
+	assert.Equal(t, time.Minute, key.Period)
+	assert.Equal(t, "SHA256", key.Algorithm)
+
+	_, err = Parse("otpauth://hotp/Example?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=1")
+	assert.Error(t, err)
+
+	_, err = Parse("otpauth://totp/Example?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&digits=4")
+	assert.Error(t, err)
+
+	_, err = Parse("otpauth://totp/Example?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&algorithm=MD5")
+	assert.Error(t, err)
+
+	_, err = Parse("otpauth://totp/Example")
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestRemaining(t *testing.T) {
+	key := &Key{Period: DefaultPeriod}
+	assert.Equal(t, 30*time.Second, key.Remaining(time.Unix(60, 0)))
+	assert.Equal(t, time.Second, key.Remaining(time.Unix(89, 0)))
+}
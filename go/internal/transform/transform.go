@@ -0,0 +1,72 @@
+// Package transform encodes a secret's value for where it is going, such as
+// a basic-auth header, a Kubernetes Secret manifest or a URL, so plaintext
+// doesn't have to be piped through other tools first
+package transform
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Names of the transforms, in the order they are listed in help
+const (
+	Base64     = "base64"
+	Hex        = "hex"
+	URLEncode  = "urlencode"
+	JSONEscape = "json-escape"
+)
+
+// Names lists every transform
+var Names = []string{Base64, Hex, URLEncode, JSONEscape}
+
+// Check returns an error unless name is a known transform or empty
+func Check(name string) error {
+	if name == "" {
+		return nil
+	}
+	for _, n := range Names {
+		if n == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown transform %q (use %s)", name, strings.Join(Names, ", "))
+}
+
+// Apply encodes value with the named transform. An empty name returns value
+// unchanged.
+//
+//   - base64 is standard, padded base64 (as in Kubernetes Secret data and
+//     Authorization: Basic headers)
+//   - hex is lowercase hexadecimal
+//   - urlencode percent-encodes everything but unreserved characters, so the
+//     result is safe in a query, a path segment or the userinfo of a URL
+//   - json-escape produces the contents of a JSON string, without the
+//     surrounding quotes
+func Apply(name, value string) (string, error) {
+	switch name {
+	case "":
+		return value, nil
+	case Base64:
+		return base64.StdEncoding.EncodeToString([]byte(value)), nil
+	case Hex:
+		return hex.EncodeToString([]byte(value)), nil
+	case URLEncode:
+		// QueryEscape writes spaces as "+", which only means a space in queries
+		return strings.ReplaceAll(url.QueryEscape(value), "+", "%20"), nil
+	case JSONEscape:
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(value); err != nil {
+			return "", err
+		}
+		quoted := strings.TrimSuffix(buf.String(), "\n")
+		return quoted[1 : len(quoted)-1], nil
+	}
+	return "", Check(name)
+}
@@ -0,0 +1,38 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"", "p@ss word", "p@ss word"},
+		{Base64, "admin:p@ss", "YWRtaW46cEBzcw=="},
+		{Hex, "key\x00", "6b657900"},
+		{URLEncode, "p@ss word+/?&=~._-", "p%40ss%20word%2B%2F%3F%26%3D~._-"},
+		{JSONEscape, "a\"b\\c\nd\t<e>&é\x01", `a\"b\\c\nd\t<e>&é\u0001`},
+	}
+	for _, tt := range tests {
+		got, err := Apply(tt.name, tt.value)
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.want, got, tt.name)
+	}
+
+	_, err := Apply("rot13", "x")
+	assert.ErrorContains(t, err, "unknown transform")
+}
+
+func TestCheck(t *testing.T) {
+	assert.NoError(t, Check(""))
+	for _, name := range Names {
+		assert.NoError(t, Check(name))
+	}
+	assert.Error(t, Check("Base64"))
+}
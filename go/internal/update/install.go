@@ -0,0 +1,116 @@
+package update
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrChecksumMismatch is returned when a download does not match checksums.txt
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// maxBinarySize bounds how much is read from an archive entry
+const maxBinarySize = 200 << 20
+
+// VerifyChecksum checks data against the entry for name in a goreleaser
+// checksums.txt ("<sha256>  <file name>" per line)
+func VerifyChecksum(checksums []byte, name string, data []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[1] != name {
+			continue
+		}
+
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != strings.ToLower(fields[0]) {
+			return fmt.Errorf("%w for %s", ErrChecksumMismatch, name)
+		}
+		return nil
+	}
+	return fmt.Errorf("no checksum listed for %s", name)
+}
+
+// ExtractBinary returns the file called binary from a .tar.gz archive
+func ExtractBinary(archive []byte, binary string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in archive", binary)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || path.Base(header.Name) != binary {
+			continue
+		}
+
+		data, err := io.ReadAll(io.LimitReader(tr, maxBinarySize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", binary, err)
+		}
+		if len(data) > maxBinarySize {
+			return nil, fmt.Errorf("%s is larger than %d bytes", binary, maxBinarySize)
+		}
+		return data, nil
+	}
+}
+
+// ReplaceExecutable atomically replaces the file at target with binary. The
+// new file is written next to the target and renamed over it, so the
+// executable is never left half-written.
+func ReplaceExecutable(target string, binary []byte) error {
+	target, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return fmt.Errorf("failed to resolve executable: %w", err)
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		return fmt.Errorf("failed to stat executable: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".new-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op after a successful rename
+
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write new executable: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write new executable: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write new executable: %w", err)
+	}
+	if err := os.Chmod(tmpPath, info.Mode().Perm()|0o111); err != nil {
+		return fmt.Errorf("failed to make new executable runnable: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, target); err != nil {
+		return fmt.Errorf("failed to replace executable: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package update
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// ErrBadSignature is returned when a minisign signature does not verify
+var ErrBadSignature = errors.New("invalid minisign signature")
+
+// minisign algorithm identifiers
+var (
+	algEd25519       = []byte("Ed") // signature over the raw message
+	algEd25519Hashed = []byte("ED") // signature over BLAKE2b-512(message)
+)
+
+// PublicKey is a minisign Ed25519 public key
+type PublicKey struct {
+	KeyID [8]byte
+	Key   ed25519.PublicKey
+}
+
+// ParsePublicKey parses a minisign public key, either the bare base64 line
+// or the full two-line .pub file
+func ParsePublicKey(text string) (*PublicKey, error) {
+	line := lastLine(text)
+	raw, err := base64.StdEncoding.DecodeString(line)
+	if err != nil || len(raw) != 2+8+ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid minisign public key")
+	}
+	if !bytes.Equal(raw[:2], algEd25519) {
+		return nil, fmt.Errorf("unsupported minisign key algorithm %q", raw[:2])
+	}
+
+	pk := &PublicKey{Key: ed25519.PublicKey(raw[10:])}
+	copy(pk.KeyID[:], raw[2:10])
+	return pk, nil
+}
+
+// Verify checks a minisign signature file against message, including the
+// signature over the trusted comment
+func (pk *PublicKey) Verify(message, signatureFile []byte) error {
+	lines := strings.Split(strings.TrimSpace(string(signatureFile)), "\n")
+	if len(lines) != 4 {
+		return fmt.Errorf("%w: malformed signature file", ErrBadSignature)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(sig) != 2+8+ed25519.SignatureSize {
+		return fmt.Errorf("%w: malformed signature", ErrBadSignature)
+	}
+	if !bytes.Equal(sig[2:10], pk.KeyID[:]) {
+		return fmt.Errorf("%w: signed with a different key", ErrBadSignature)
+	}
+
+	signed := message
+	switch {
+	case bytes.Equal(sig[:2], algEd25519Hashed):
+		digest := blake2b.Sum512(message)
+		signed = digest[:]
+	case !bytes.Equal(sig[:2], algEd25519):
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrBadSignature, sig[:2])
+	}
+
+	if !ed25519.Verify(pk.Key, signed, sig[10:]) {
+		return ErrBadSignature
+	}
+
+	// The trusted comment is covered by a second, global signature
+	const trustedPrefix = "trusted comment: "
+	comment := strings.TrimSpace(lines[2])
+	if !strings.HasPrefix(comment, trustedPrefix) {
+		return fmt.Errorf("%w: missing trusted comment", ErrBadSignature)
+	}
+	globalSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: malformed global signature", ErrBadSignature)
+	}
+	globalMessage := append(append([]byte{}, sig[10:]...), strings.TrimPrefix(comment, trustedPrefix)...)
+	if !ed25519.Verify(pk.Key, globalMessage, globalSig) {
+		return fmt.Errorf("%w: trusted comment was modified", ErrBadSignature)
+	}
+
+	return nil
+}
+
+// lastLine returns the last non-empty line of text
+func lastLine(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,300 @@
+// Package update finds, verifies and installs new lockr releases published
+// by goreleaser on GitHub
+package update
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Release channels
+const (
+	ChannelStable = "stable"
+	ChannelBeta   = "beta"
+)
+
+const (
+	// DefaultReleasesURL is the GitHub releases feed of the lockr repository
+	DefaultReleasesURL = "https://api.github.com/repos/metabot/lockr/releases"
+
+	// ChecksumsFile is the goreleaser checksum manifest attached to every release
+	ChecksumsFile = "checksums.txt"
+
+	// SignatureFile is the minisign signature of ChecksumsFile
+	SignatureFile = ChecksumsFile + ".minisig"
+
+	// BinaryName is the executable inside each release archive
+	BinaryName = "lockr"
+
+	// maxDownloadSize bounds every download
+	maxDownloadSize = 200 << 20
+)
+
+// ReleaseKey is the minisign public key that release checksums are signed
+// with. It is set at build time (-X github.com/lockr/go/internal/update.ReleaseKey=...);
+// builds without it refuse to self-update.
+var ReleaseKey = ""
+
+// ErrNoPublicKey is returned when the binary was built without a release key
+var ErrNoPublicKey = errors.New("this build has no release signing key; download updates manually")
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Release is a GitHub release
+type Release struct {
+	Tag        string  `json:"tag_name"`
+	Draft      bool    `json:"draft"`
+	Prerelease bool    `json:"prerelease"`
+	Assets     []Asset `json:"assets"`
+}
+
+// Version returns the release version without the leading "v"
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.Tag, "v")
+}
+
+// Asset returns the attached file called name
+func (r *Release) Asset(name string) (*Asset, error) {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i], nil
+		}
+	}
+	return nil, fmt.Errorf("release %s has no asset %s", r.Tag, name)
+}
+
+// Client talks to the releases feed
+type Client struct {
+	ReleasesURL string
+	HTTP        *http.Client
+	Key         *PublicKey
+}
+
+// NewClient creates a client for the default releases feed using the
+// embedded release key
+func NewClient() (*Client, error) {
+	if ReleaseKey == "" {
+		return nil, ErrNoPublicKey
+	}
+	key, err := ParsePublicKey(ReleaseKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		ReleasesURL: DefaultReleasesURL,
+		HTTP:        &http.Client{Timeout: 5 * time.Minute},
+		Key:         key,
+	}, nil
+}
+
+// Latest returns the newest published release on channel. The stable
+// channel ignores pre-releases; beta includes them.
+func (c *Client) Latest(channel string) (*Release, error) {
+	if channel != ChannelStable && channel != ChannelBeta {
+		return nil, fmt.Errorf("unknown channel %q (use %s or %s)", channel, ChannelStable, ChannelBeta)
+	}
+
+	body, err := c.get(c.ReleasesURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []Release
+	if err := json.Unmarshal(body, &releases); err != nil {
+		return nil, fmt.Errorf("failed to parse releases feed: %w", err)
+	}
+
+	var latest *Release
+	for i := range releases {
+		release := &releases[i]
+		if release.Draft || (release.Prerelease && channel == ChannelStable) {
+			continue
+		}
+		if latest == nil || CompareVersions(release.Version(), latest.Version()) > 0 {
+			latest = release
+		}
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("no %s releases found", channel)
+	}
+	return latest, nil
+}
+
+// Download fetches the archive for goos/goarch from release, verifies the
+// signed checksum manifest and the archive's checksum, and returns the
+// extracted binary
+func (c *Client) Download(release *Release, goos, goarch string) ([]byte, error) {
+	checksums, err := c.getAsset(release, ChecksumsFile)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := c.getAsset(release, SignatureFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Key.Verify(checksums, signature); err != nil {
+		return nil, err
+	}
+
+	name := AssetName(release.Version(), goos, goarch)
+	archive, err := c.getAsset(release, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifyChecksum(checksums, name, archive); err != nil {
+		return nil, err
+	}
+
+	return ExtractBinary(archive, BinaryName)
+}
+
+// getAsset downloads the release file called name
+func (c *Client) getAsset(release *Release, name string) ([]byte, error) {
+	asset, err := release.Asset(name)
+	if err != nil {
+		return nil, err
+	}
+	return c.get(asset.URL)
+}
+
+// get fetches url, failing on non-200 responses and oversized bodies
+func (c *Client) get(url string) ([]byte, error) {
+	resp, err := c.HTTP.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	if len(body) > maxDownloadSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", url, maxDownloadSize)
+	}
+	return body, nil
+}
+
+// AssetName returns the goreleaser archive name for a version and platform
+// (see name_template in .goreleaser.yml)
+func AssetName(version, goos, goarch string) string {
+	arch := goarch
+	switch goarch {
+	case "amd64":
+		arch = "x86_64"
+	case "386":
+		arch = "i386"
+	}
+
+	osName := goos
+	if osName != "" {
+		osName = strings.ToUpper(osName[:1]) + osName[1:]
+	}
+
+	return fmt.Sprintf("%s_%s_%s_%s.tar.gz", BinaryName, version, osName, arch)
+}
+
+// CompareVersions compares two semantic versions ("1.2.3", "1.3.0-beta.1")
+// and returns -1, 0 or 1. A release sorts after its pre-releases.
+// Non-numeric versions such as "dev" sort before everything else.
+func CompareVersions(a, b string) int {
+	coreA, preA, _ := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	coreB, preB, _ := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+
+	partsA, okA := parseCore(coreA)
+	partsB, okB := parseCore(coreB)
+	switch {
+	case !okA && !okB:
+		return strings.Compare(a, b)
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+
+	for i := 0; i < 3; i++ {
+		if partsA[i] != partsB[i] {
+			if partsA[i] < partsB[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+	return comparePrerelease(preA, preB)
+}
+
+// parseCore parses "MAJOR.MINOR.PATCH"; missing parts count as zero
+func parseCore(core string) ([3]int, bool) {
+	var parts [3]int
+	fields := strings.Split(core, ".")
+	if len(fields) > 3 {
+		return parts, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// comparePrerelease compares dot-separated pre-release identifiers, numeric
+// identifiers numerically
+func comparePrerelease(a, b string) int {
+	idsA, idsB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(idsA) && i < len(idsB); i++ {
+		numA, errA := strconv.Atoi(idsA[i])
+		numB, errB := strconv.Atoi(idsB[i])
+		switch {
+		case errA == nil && errB == nil:
+			if numA != numB {
+				if numA < numB {
+					return -1
+				}
+				return 1
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(idsA[i], idsB[i]); c != 0 {
+				return c
+			}
+		}
+	}
+
+	switch {
+	case len(idsA) < len(idsB):
+		return -1
+	case len(idsA) > len(idsB):
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,216 @@
+package update
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2b"
+)
+
+// testSigner produces minisign keys and signatures for tests
+type testSigner struct {
+	keyID [8]byte
+	priv  ed25519.PrivateKey
+	pub   ed25519.PublicKey
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	s := &testSigner{priv: priv, pub: pub}
+	_, err = rand.Read(s.keyID[:])
+	require.NoError(t, err)
+	return s
+}
+
+func (s *testSigner) publicKey() string {
+	raw := append(append([]byte("Ed"), s.keyID[:]...), s.pub...)
+	return "untrusted comment: minisign public key\n" + base64.StdEncoding.EncodeToString(raw) + "\n"
+}
+
+func (s *testSigner) sign(message []byte, comment string) []byte {
+	digest := blake2b.Sum512(message)
+	sig := ed25519.Sign(s.priv, digest[:])
+	raw := append(append([]byte("ED"), s.keyID[:]...), sig...)
+	global := ed25519.Sign(s.priv, append(append([]byte{}, sig...), comment...))
+	return []byte(fmt.Sprintf("untrusted comment: signature\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(raw), comment, base64.StdEncoding.EncodeToString(global)))
+}
+
+func makeArchive(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, data := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(data)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestMinisignVerify(t *testing.T) {
+	signer := newTestSigner(t)
+	key, err := ParsePublicKey(signer.publicKey())
+	require.NoError(t, err)
+
+	message := []byte("checksums")
+	signature := signer.sign(message, "timestamp:1")
+	assert.NoError(t, key.Verify(message, signature))
+
+	assert.ErrorIs(t, key.Verify([]byte("tampered"), signature), ErrBadSignature)
+
+	// Editing the trusted comment breaks the global signature
+	edited := bytes.Replace(signature, []byte("timestamp:1"), []byte("timestamp:2"), 1)
+	assert.ErrorIs(t, key.Verify(message, edited), ErrBadSignature)
+
+	// A signature from another key is rejected
+	other := newTestSigner(t)
+	assert.ErrorIs(t, key.Verify(message, other.sign(message, "x")), ErrBadSignature)
+
+	_, err = ParsePublicKey("not a key")
+	assert.Error(t, err)
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2.4", "1.2.3", 1},
+		{"1.10.0", "1.9.0", 1},
+		{"2.0.0", "10.0.0", -1},
+		{"1.0.0-beta.1", "1.0.0", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.10", -1},
+		{"1.0.0-alpha", "1.0.0-beta", -1},
+		{"dev", "0.0.1", -1},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, CompareVersions(tt.a, tt.b), "%s vs %s", tt.a, tt.b)
+	}
+}
+
+func TestAssetName(t *testing.T) {
+	assert.Equal(t, "lockr_1.2.3_Darwin_x86_64.tar.gz", AssetName("1.2.3", "darwin", "amd64"))
+	assert.Equal(t, "lockr_1.2.3_Linux_arm64.tar.gz", AssetName("1.2.3", "linux", "arm64"))
+}
+
+func TestVerifyChecksumAndExtract(t *testing.T) {
+	archive := makeArchive(t, map[string][]byte{"README.md": []byte("readme"), "lockr": []byte("binary")})
+	sum := sha256.Sum256(archive)
+	checksums := []byte(hex.EncodeToString(sum[:]) + "  lockr_1.0.0_Linux_x86_64.tar.gz\n")
+
+	assert.NoError(t, VerifyChecksum(checksums, "lockr_1.0.0_Linux_x86_64.tar.gz", archive))
+	assert.ErrorIs(t, VerifyChecksum(checksums, "lockr_1.0.0_Linux_x86_64.tar.gz", []byte("other")), ErrChecksumMismatch)
+	assert.Error(t, VerifyChecksum(checksums, "missing.tar.gz", archive))
+
+	binary, err := ExtractBinary(archive, "lockr")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("binary"), binary)
+
+	_, err = ExtractBinary(archive, "missing")
+	assert.Error(t, err)
+}
+
+func TestReplaceExecutable(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "lockr")
+	require.NoError(t, os.WriteFile(target, []byte("old"), 0o755))
+
+	require.NoError(t, ReplaceExecutable(target, []byte("new")))
+
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("new"), data)
+
+	info, err := os.Stat(target)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode().Perm()&0o111)
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestClientLatestAndDownload(t *testing.T) {
+	signer := newTestSigner(t)
+	key, err := ParsePublicKey(signer.publicKey())
+	require.NoError(t, err)
+
+	assetName := AssetName("1.1.0", "linux", "amd64")
+	archive := makeArchive(t, map[string][]byte{"lockr": []byte("new binary")})
+	sum := sha256.Sum256(archive)
+	checksums := []byte(hex.EncodeToString(sum[:]) + "  " + assetName + "\n")
+
+	files := map[string][]byte{
+		ChecksumsFile: checksums,
+		SignatureFile: signer.sign(checksums, "lockr 1.1.0"),
+		assetName:     archive,
+	}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/releases" {
+			var stable []Asset
+			for name := range files {
+				stable = append(stable, Asset{Name: name, URL: server.URL + "/download/" + name})
+			}
+			json.NewEncoder(w).Encode([]Release{
+				{Tag: "v1.2.0-beta.1", Prerelease: true},
+				{Tag: "v1.1.0", Assets: stable},
+				{Tag: "v1.0.0"},
+				{Tag: "v9.9.9", Draft: true},
+			})
+			return
+		}
+		data, ok := files[filepath.Base(r.URL.Path)]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	client := &Client{ReleasesURL: server.URL + "/releases", HTTP: server.Client(), Key: key}
+
+	release, err := client.Latest(ChannelStable)
+	require.NoError(t, err)
+	assert.Equal(t, "1.1.0", release.Version())
+
+	beta, err := client.Latest(ChannelBeta)
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.0-beta.1", beta.Version())
+
+	_, err = client.Latest("nightly")
+	assert.Error(t, err)
+
+	binary, err := client.Download(release, "linux", "amd64")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("new binary"), binary)
+
+	// A manifest signed by someone else is refused before anything is installed
+	files[SignatureFile] = newTestSigner(t).sign(checksums, "lockr 1.1.0")
+	_, err = client.Download(release, "linux", "amd64")
+	assert.ErrorIs(t, err, ErrBadSignature)
+}
@@ -0,0 +1,154 @@
+// Package valuecache keeps recently fetched secret values in an encrypted
+// file so automation can read them again within a freshness limit without
+// unlocking the vault. The whole file, key names included, is sealed with a
+// random per-vault key that lives in the system keyring.
+package valuecache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lockr/go/internal/crypto"
+)
+
+// Entry is one cached value with what is needed to deliver it the way a
+// fresh read would
+type Entry struct {
+	Value         string        `json:"value"`
+	Revision      int64         `json:"revision"`
+	FetchedAt     time.Time     `json:"fetched_at"`
+	ClipboardOnly bool          `json:"clipboard_only,omitempty"`
+	ClearAfter    time.Duration `json:"clear_after,omitempty"`
+}
+
+// Cache is the value cache of one vault
+type Cache struct {
+	path    string
+	vaultID string
+	key     crypto.MasterKey
+}
+
+// New returns the cache for vaultID kept in dir, encrypted with key
+func New(dir, vaultID string, key crypto.MasterKey) *Cache {
+	return &Cache{
+		path:    Path(dir, vaultID),
+		vaultID: vaultID,
+		key:     key,
+	}
+}
+
+// Path is the cache file for vaultID in dir
+func Path(dir, vaultID string) string {
+	return filepath.Join(dir, vaultID+".cache")
+}
+
+// Get returns the entry for secretKey if it was fetched no longer than
+// maxAge ago. Unreadable caches count as empty.
+func (c *Cache) Get(secretKey string, maxAge time.Duration, now time.Time) (*Entry, bool) {
+	entries, err := c.load()
+	if err != nil {
+		return nil, false
+	}
+	entry, ok := entries[normalize(secretKey)]
+	if !ok || now.Sub(entry.FetchedAt) > maxAge || entry.FetchedAt.After(now) {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// Put stores an entry, dropping any that are older than keep
+func (c *Cache) Put(secretKey string, entry Entry, keep time.Duration) error {
+	entries, err := c.load()
+	if err != nil {
+		entries = map[string]Entry{} // Start over rather than fail
+	}
+	for k, e := range entries {
+		if entry.FetchedAt.Sub(e.FetchedAt) > keep {
+			delete(entries, k)
+		}
+	}
+	entries[normalize(secretKey)] = entry
+	return c.save(entries)
+}
+
+// Invalidate removes secretKey's entry
+func (c *Cache) Invalidate(secretKey string) error {
+	entries, err := c.load()
+	if err != nil {
+		return Clear(filepath.Dir(c.path), c.vaultID)
+	}
+	if _, ok := entries[normalize(secretKey)]; !ok {
+		return nil
+	}
+	delete(entries, normalize(secretKey))
+	return c.save(entries)
+}
+
+// Clear deletes the cache file for vaultID in dir
+func Clear(dir, vaultID string) error {
+	err := os.Remove(Path(dir, vaultID))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (c *Cache) load() (map[string]Entry, error) {
+	sealed, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := c.key.Open(sealed, []byte(c.vaultID))
+	if err != nil {
+		return nil, fmt.Errorf("unreadable value cache: %w", err)
+	}
+
+	var entries map[string]Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("corrupt value cache: %w", err)
+	}
+	return entries, nil
+}
+
+func (c *Cache) save(entries map[string]Entry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	sealed, err := c.key.Seal(data, []byte(c.vaultID))
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".cache-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(sealed); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// normalize matches keys case-insensitively, like the vault
+func normalize(key string) string {
+	return strings.ToLower(key)
+}
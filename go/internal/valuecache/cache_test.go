@@ -0,0 +1,90 @@
+package valuecache
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lockr/go/internal/crypto"
+)
+
+func newTestCache(t *testing.T) (*Cache, string) {
+	t.Helper()
+	key, err := crypto.GenerateMasterKey()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	return New(dir, "vault1", key), dir
+}
+
+func TestCache_GetPut(t *testing.T) {
+	c, dir := newTestCache(t)
+	now := time.Now()
+
+	_, ok := c.Get("db/password", time.Minute, now)
+	assert.False(t, ok)
+
+	require.NoError(t, c.Put("DB/Password", Entry{Value: "hunter2", Revision: 3, FetchedAt: now}, time.Hour))
+
+	entry, ok := c.Get("db/password", time.Minute, now.Add(30*time.Second))
+	require.True(t, ok)
+	assert.Equal(t, "hunter2", entry.Value)
+	assert.Equal(t, int64(3), entry.Revision)
+
+	// Stale entries are not served
+	_, ok = c.Get("db/password", time.Minute, now.Add(2*time.Minute))
+	assert.False(t, ok)
+
+	// Nothing readable on disk
+	data, err := os.ReadFile(Path(dir, "vault1"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "hunter2")
+	assert.NotContains(t, string(data), "password")
+}
+
+func TestCache_InvalidateAndClear(t *testing.T) {
+	c, dir := newTestCache(t)
+	now := time.Now()
+	require.NoError(t, c.Put("a", Entry{Value: "1", FetchedAt: now}, time.Hour))
+	require.NoError(t, c.Put("b", Entry{Value: "2", FetchedAt: now}, time.Hour))
+
+	require.NoError(t, c.Invalidate("A"))
+	_, ok := c.Get("a", time.Minute, now)
+	assert.False(t, ok)
+	_, ok = c.Get("b", time.Minute, now)
+	assert.True(t, ok)
+
+	require.NoError(t, Clear(dir, "vault1"))
+	_, ok = c.Get("b", time.Minute, now)
+	assert.False(t, ok)
+	require.NoError(t, Clear(dir, "vault1"))
+}
+
+func TestCache_WrongKey(t *testing.T) {
+	c, dir := newTestCache(t)
+	now := time.Now()
+	require.NoError(t, c.Put("a", Entry{Value: "1", FetchedAt: now}, time.Hour))
+
+	other, err := crypto.GenerateMasterKey()
+	require.NoError(t, err)
+	_, ok := New(dir, "vault1", other).Get("a", time.Minute, now)
+	assert.False(t, ok)
+
+	// A cache that cannot be read is replaced on the next write
+	require.NoError(t, New(dir, "vault1", other).Put("b", Entry{Value: "2", FetchedAt: now}, time.Hour))
+	_, ok = New(dir, "vault1", other).Get("b", time.Minute, now)
+	assert.True(t, ok)
+}
+
+func TestCache_PutPrunes(t *testing.T) {
+	c, _ := newTestCache(t)
+	old := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, c.Put("old", Entry{Value: "1", FetchedAt: old}, time.Hour))
+	require.NoError(t, c.Put("new", Entry{Value: "2", FetchedAt: time.Now()}, time.Hour))
+
+	entries, err := c.load()
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
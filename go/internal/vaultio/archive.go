@@ -0,0 +1,98 @@
+package vaultio
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lockr/go/internal/crypto"
+)
+
+// FormatArchive is a password-protected, AES-GCM encrypted lockrx document
+// for backups
+const FormatArchive = "lockr"
+
+// archiveMagic starts every archive
+var archiveMagic = []byte("LOCKRARC")
+
+// archiveVersion is the current archive layout
+const archiveVersion = 1
+
+// archiveHeaderSize is the size of magic, version, iterations and salt
+var archiveHeaderSize = len(archiveMagic) + 1 + 4 + crypto.SaltSize
+
+// ErrArchivePassword is returned when an archive cannot be decrypted
+var ErrArchivePassword = errors.New("wrong password or damaged archive")
+
+// WriteArchive writes records as an encrypted archive. The layout is the
+// magic, a version byte, the PBKDF2 iteration count (big endian uint32), the
+// salt, then the AES-GCM nonce and ciphertext of a lockrx document. The
+// header is authenticated along with the contents.
+func WriteArchive(w io.Writer, password string, records []Record) error {
+	if password == "" {
+		return errors.New("archive password cannot be empty")
+	}
+
+	var plain bytes.Buffer
+	if err := WriteLockrx(&plain, records); err != nil {
+		return err
+	}
+
+	salt := make([]byte, crypto.SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	header := make([]byte, 0, archiveHeaderSize)
+	header = append(header, archiveMagic...)
+	header = append(header, archiveVersion)
+	header = binary.BigEndian.AppendUint32(header, crypto.PasswordIterations)
+	header = append(header, salt...)
+
+	key := crypto.KeyFromPassword(password, salt, crypto.PasswordIterations)
+	defer key.Zeroize()
+	sealed, err := key.Seal(plain.Bytes(), header)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err = w.Write(sealed)
+	return err
+}
+
+// ReadArchive decrypts an archive written by WriteArchive
+func ReadArchive(r io.Reader, password string) ([]Record, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !IsArchive(data) || len(data) < archiveHeaderSize {
+		return nil, errors.New("not a lockr archive")
+	}
+
+	header := data[:archiveHeaderSize]
+	if version := header[len(archiveMagic)]; version != archiveVersion {
+		return nil, fmt.Errorf("unsupported archive version %d", version)
+	}
+	iterations := binary.BigEndian.Uint32(header[len(archiveMagic)+1:])
+	salt := header[len(archiveMagic)+5:]
+
+	key := crypto.KeyFromPassword(password, salt, int(iterations))
+	defer key.Zeroize()
+	plain, err := key.Open(data[archiveHeaderSize:], header)
+	if err != nil {
+		return nil, ErrArchivePassword
+	}
+	return ReadLockrx(bytes.NewReader(plain))
+}
+
+// IsArchive reports whether data starts like an encrypted archive
+func IsArchive(data []byte) bool {
+	return bytes.HasPrefix(data, archiveMagic)
+}
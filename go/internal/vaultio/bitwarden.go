@@ -0,0 +1,94 @@
+package vaultio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Bitwarden item types
+const (
+	bitwardenTypeLogin      = 1
+	bitwardenTypeSecureNote = 2
+)
+
+type bitwardenExport struct {
+	Encrypted bool              `json:"encrypted"`
+	Folders   []bitwardenFolder `json:"folders"`
+	Items     []bitwardenItem   `json:"items"`
+}
+
+type bitwardenFolder struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type bitwardenItem struct {
+	FolderID     *string         `json:"folderId"`
+	Type         int             `json:"type"`
+	Name         string          `json:"name"`
+	Notes        *string         `json:"notes"`
+	Login        *bitwardenLogin `json:"login"`
+	CreationDate *time.Time      `json:"creationDate"`
+	RevisionDate *time.Time      `json:"revisionDate"`
+}
+
+type bitwardenLogin struct {
+	Username *string `json:"username"`
+	Password *string `json:"password"`
+}
+
+// ReadBitwarden parses an unencrypted Bitwarden JSON export. Logins become
+// secrets holding the password; secure notes hold the note text. Keys are
+// "folder/name" with characters lockr does not accept replaced by "_".
+func ReadBitwarden(r io.Reader) ([]Record, error) {
+	var export bitwardenExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, fmt.Errorf("invalid Bitwarden export: %w", err)
+	}
+	if export.Encrypted {
+		return nil, errors.New("encrypted Bitwarden exports are not supported; export as unencrypted JSON")
+	}
+
+	folders := make(map[string]string, len(export.Folders))
+	for _, f := range export.Folders {
+		folders[f.ID] = f.Name
+	}
+
+	var records []Record
+	for _, item := range export.Items {
+		record := Record{Key: sanitizeKey(item.Name)}
+		if item.FolderID != nil {
+			if folder := folders[*item.FolderID]; folder != "" {
+				record.Key = sanitizeKey(folder) + "/" + record.Key
+			}
+		}
+		if item.CreationDate != nil {
+			record.CreatedAt = *item.CreationDate
+		}
+		record.UpdatedAt = item.RevisionDate
+
+		switch item.Type {
+		case bitwardenTypeLogin:
+			if item.Login == nil || item.Login.Password == nil || *item.Login.Password == "" {
+				continue
+			}
+			record.Value = *item.Login.Password
+			record.Notes = loginNotes(deref(item.Login.Username), deref(item.Notes))
+		case bitwardenTypeSecureNote:
+			if item.Notes == nil || *item.Notes == "" {
+				continue
+			}
+			record.Value = *item.Notes
+		default:
+			// Cards and identities have no single secret value
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
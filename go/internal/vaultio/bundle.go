@@ -0,0 +1,101 @@
+package vaultio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lockr/go/internal/crypto"
+)
+
+const (
+	bundleBegin     = "-----BEGIN LOCKR RECOVERY BUNDLE-----"
+	bundleEnd       = "-----END LOCKR RECOVERY BUNDLE-----"
+	bundleLineWidth = 64
+)
+
+// WriteRecoveryBundle seals records, as a lockrx document, to the recipient's
+// public key and writes them as an armored text bundle
+func WriteRecoveryBundle(w io.Writer, pub *crypto.RecipientPublicKey, records []Record) error {
+	var plain bytes.Buffer
+	if err := WriteLockrx(&plain, records); err != nil {
+		return err
+	}
+
+	sealed, err := crypto.SealForRecipient(pub, plain.Bytes())
+	if err != nil {
+		return err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(sealed)
+
+	var out strings.Builder
+	out.WriteString(bundleBegin + "\n")
+	out.WriteString("Recipient: " + pub.String() + "\n\n")
+	for len(encoded) > bundleLineWidth {
+		out.WriteString(encoded[:bundleLineWidth] + "\n")
+		encoded = encoded[bundleLineWidth:]
+	}
+	out.WriteString(encoded + "\n")
+	out.WriteString(bundleEnd + "\n")
+
+	_, err = io.WriteString(w, out.String())
+	return err
+}
+
+// ReadRecoveryBundle opens an armored recovery bundle with the recipient's
+// private key and returns the records it contains
+func ReadRecoveryBundle(r io.Reader, priv *crypto.RecipientPrivateKey) ([]Record, error) {
+	scanner := bufio.NewScanner(r)
+
+	var body strings.Builder
+	inBody, inHeaders, done := false, false, false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == bundleBegin:
+			inBody, inHeaders = true, true
+		case line == bundleEnd:
+			done = true
+		case !inBody || done:
+			// Text outside the armor is ignored
+		case inHeaders:
+			// Headers end at the first blank line
+			if line == "" {
+				inHeaders = false
+			}
+		default:
+			body.WriteString(line)
+		}
+		if done {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !done {
+		return nil, errors.New("invalid recovery bundle: missing armor")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(body.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid recovery bundle: %w", err)
+	}
+
+	pub, err := priv.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := crypto.OpenSealed(pub, priv, sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadLockrx(bytes.NewReader(plain))
+}
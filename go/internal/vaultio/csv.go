@@ -0,0 +1,85 @@
+package vaultio
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// csvHeader names the columns written by WriteCSV
+var csvHeader = []string{"key", "value", "created_at", "tags", "notes", "url", "expires_at"}
+
+// WriteCSV writes records as CSV with a header row, in canonical order.
+// Times are RFC 3339 in UTC; missing fields are empty.
+func WriteCSV(w io.Writer, records []Record) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, r := range canonicalRecords(records) {
+		expires := ""
+		if r.ExpiresAt != nil {
+			expires = r.ExpiresAt.Format(time.RFC3339)
+		}
+		row := []string{r.Key, r.Value, r.CreatedAt.Format(time.RFC3339), deref(r.Tags), deref(r.Notes), deref(r.URL), expires}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// deref returns the string s points to, or "" for nil
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// readCSVRows reads a CSV file with a header row, returning each row as a
+// map from the lowercased column name to the field. what names the format
+// in errors.
+func readCSVRows(r io.Reader, what string) ([]map[string]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s export: %w", what, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("invalid %s export: missing header row", what)
+	}
+
+	header := make([]string, len(rows[0]))
+	for i, name := range rows[0] {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+	}
+
+	fields := make([]map[string]string, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		m := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(row) {
+				m[name] = row[i]
+			}
+		}
+		fields = append(fields, m)
+	}
+	return fields, nil
+}
+
+// column returns the first non-empty field among names
+func column(row map[string]string, names ...string) string {
+	for _, name := range names {
+		if v := row[name]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,218 @@
+package vaultio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ReadDotenv parses a .env file into records keyed by variable name. It
+// accepts the common dialect: "export " prefixes, # comments, unquoted
+// values with trailing comments, 'single quoted' literals and "double
+// quoted" values with \n, \t, \", \\ escapes that may span lines. Later
+// assignments to the same name win.
+func ReadDotenv(r io.Reader) ([]Record, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	values := make(map[string]string)
+	var order []string
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		name, rest, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !isEnvName(name) {
+			return nil, fmt.Errorf("invalid .env file: line %d: expected NAME=value", lineNo)
+		}
+		rest = strings.TrimSpace(rest)
+
+		var value string
+		switch {
+		case strings.HasPrefix(rest, "'"):
+			end := strings.Index(rest[1:], "'")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid .env file: line %d: unterminated quote", lineNo)
+			}
+			value = rest[1 : end+1]
+		case strings.HasPrefix(rest, `"`):
+			// Double quoted values may continue over several lines
+			body := rest[1:]
+			start := lineNo
+			for {
+				if v, ok := unquoteDouble(body); ok {
+					value = v
+					break
+				}
+				if !scanner.Scan() {
+					return nil, fmt.Errorf("invalid .env file: line %d: unterminated quote", start)
+				}
+				lineNo++
+				body += "\n" + scanner.Text()
+			}
+		default:
+			if i := strings.Index(rest, " #"); i >= 0 {
+				rest = rest[:i]
+			}
+			value = strings.TrimSpace(rest)
+		}
+
+		if _, seen := values[name]; !seen {
+			order = append(order, name)
+		}
+		values[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("invalid .env file: %w", err)
+	}
+
+	records := make([]Record, 0, len(order))
+	for _, name := range order {
+		records = append(records, Record{Key: name, Value: values[name]})
+	}
+	return records, nil
+}
+
+// unquoteDouble decodes body up to its closing double quote, reporting
+// false if the quote is not closed yet
+func unquoteDouble(body string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case c == '"':
+			return b.String(), true
+		case c == '\\' && i+1 < len(body):
+			i++
+			switch body[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(body[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", false
+}
+
+// isEnvName reports whether name is a valid environment variable name
+func isEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// FromEnvironment returns the variables in environ (as from os.Environ)
+// whose names start with prefix, with the prefix removed from their keys
+func FromEnvironment(environ []string, prefix string) []Record {
+	var records []Record
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, prefix) || name == prefix {
+			continue
+		}
+		records = append(records, Record{Key: strings.TrimPrefix(name, prefix), Value: value})
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Key < records[j].Key })
+	return records
+}
+
+// EnvVar is a variable written to an env file
+type EnvVar struct {
+	Name  string
+	Value string
+}
+
+// WriteDotenv writes vars as a .env file that ReadDotenv and common dotenv
+// loaders read back unchanged. Values are quoted only when needed.
+func WriteDotenv(w io.Writer, vars []EnvVar) error {
+	for _, v := range vars {
+		if !isEnvName(v.Name) {
+			return fmt.Errorf("invalid variable name %q", v.Name)
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", v.Name, quoteDotenv(v.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteComposeEnv writes vars in the format of docker-compose env_file,
+// which takes everything after "=" literally: no quoting, no escapes and
+// no multi-line values
+func WriteComposeEnv(w io.Writer, vars []EnvVar) error {
+	for _, v := range vars {
+		if !isEnvName(v.Name) {
+			return fmt.Errorf("invalid variable name %q", v.Name)
+		}
+		if strings.ContainsAny(v.Value, "\r\n") {
+			return fmt.Errorf("%s: multi-line values cannot be written to an env_file", v.Name)
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", v.Name, v.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quoteDotenv quotes value if it would not survive unquoted, preferring
+// single quotes, which no loader expands $VARS in
+func quoteDotenv(value string) string {
+	plain := value != "" && strings.TrimSpace(value) == value
+	for _, r := range value {
+		if strings.ContainsRune("#\"'\\$` \t\r\n", r) {
+			plain = false
+			break
+		}
+	}
+	if plain || value == "" {
+		return value
+	}
+	if !strings.ContainsAny(value, "'\r\n") {
+		return "'" + value + "'"
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
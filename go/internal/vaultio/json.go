@@ -0,0 +1,15 @@
+package vaultio
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// WriteJSON writes records as a plain JSON array in canonical order, for
+// tools that do not know the lockrx envelope
+func WriteJSON(w io.Writer, records []Record) error {
+	canonical := canonicalRecords(records)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(canonical)
+}
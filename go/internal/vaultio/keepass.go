@@ -0,0 +1,123 @@
+package vaultio
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type keepassFile struct {
+	Meta struct {
+		RecycleBinUUID string `xml:"RecycleBinUUID"`
+	} `xml:"Meta"`
+	Root struct {
+		Groups []keepassGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type keepassGroup struct {
+	UUID    string         `xml:"UUID"`
+	Name    string         `xml:"Name"`
+	Entries []keepassEntry `xml:"Entry"`
+	Groups  []keepassGroup `xml:"Group"`
+}
+
+type keepassEntry struct {
+	Strings []keepassString `xml:"String"`
+	Tags    string          `xml:"Tags"`
+	Times   struct {
+		CreationTime         string `xml:"CreationTime"`
+		LastModificationTime string `xml:"LastModificationTime"`
+		Expires              string `xml:"Expires"`
+		ExpiryTime           string `xml:"ExpiryTime"`
+	} `xml:"Times"`
+}
+
+type keepassString struct {
+	Key   string `xml:"Key"`
+	Value struct {
+		Text      string `xml:",chardata"`
+		Protected string `xml:"Protected,attr"`
+	} `xml:"Value"`
+}
+
+// ReadKeePass parses a KeePass 2 XML export. Entries with a password
+// become secrets under their group path; the top-level group (named after
+// the database), the recycle bin and entry history are left out.
+func ReadKeePass(r io.Reader) ([]Record, error) {
+	var file keepassFile
+	if err := xml.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid KeePass export: %w", err)
+	}
+	if len(file.Root.Groups) == 0 {
+		return nil, errors.New("invalid KeePass export: no groups")
+	}
+
+	var records []Record
+	var walk func(g keepassGroup, path []string) error
+	walk = func(g keepassGroup, path []string) error {
+		if g.UUID != "" && g.UUID == file.Meta.RecycleBinUUID {
+			return nil
+		}
+		for _, e := range g.Entries {
+			record, ok, err := keepassRecord(e, path)
+			if err != nil {
+				return err
+			}
+			if ok {
+				records = append(records, record)
+			}
+		}
+		for _, sub := range g.Groups {
+			if err := walk(sub, append(path[:len(path):len(path)], sub.Name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, root := range file.Root.Groups {
+		if err := walk(root, nil); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// keepassRecord converts an entry, reporting false when it has no password
+func keepassRecord(e keepassEntry, path []string) (Record, bool, error) {
+	fields := make(map[string]string, len(e.Strings))
+	for _, s := range e.Strings {
+		// Protected values are only encrypted inside a .kdbx file; XML
+		// exports write them in the clear
+		if strings.EqualFold(s.Value.Protected, "true") {
+			return Record{}, false, errors.New("KeePass export holds encrypted values; export the database as KeePass XML (2.x) instead")
+		}
+		fields[s.Key] = s.Value.Text
+	}
+	if fields["Password"] == "" {
+		return Record{}, false, nil
+	}
+
+	record := Record{
+		Key:   folderKey(path, fields["Title"]),
+		Value: fields["Password"],
+		Notes: loginNotes(fields["UserName"], fields["Notes"]),
+		URL:   optional(fields["URL"]),
+		Tags:  splitTagList(e.Tags),
+	}
+	if created, err := time.Parse(time.RFC3339, e.Times.CreationTime); err == nil {
+		record.CreatedAt = created
+	}
+	if modified, err := time.Parse(time.RFC3339, e.Times.LastModificationTime); err == nil {
+		record.UpdatedAt = &modified
+	}
+	if strings.EqualFold(e.Times.Expires, "true") {
+		if expires, err := time.Parse(time.RFC3339, e.Times.ExpiryTime); err == nil {
+			record.ExpiresAt = &expires
+		}
+	}
+	return record, true, nil
+}
@@ -0,0 +1,42 @@
+package vaultio
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// lastpassNoteURL is the URL LastPass gives secure notes
+const lastpassNoteURL = "http://sn"
+
+// ReadLastPass parses a LastPass CSV export. Sites become secrets holding
+// the password, under their folder ("grouping", whose subfolders LastPass
+// separates with backslashes); secure notes hold the note text.
+func ReadLastPass(r io.Reader) ([]Record, error) {
+	rows, err := readCSVRows(r, "LastPass")
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > 0 {
+		if _, ok := rows[0]["grouping"]; !ok {
+			return nil, fmt.Errorf("invalid LastPass export: no grouping column")
+		}
+	}
+
+	var records []Record
+	for _, row := range rows {
+		record := Record{Key: folderKey(strings.Split(row["grouping"], `\`), row["name"])}
+		if row["url"] == lastpassNoteURL {
+			record.Value = row["extra"]
+		} else {
+			record.Value = row["password"]
+			record.Notes = loginNotes(row["username"], row["extra"])
+			record.URL = optional(row["url"])
+		}
+		if record.Value == "" {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
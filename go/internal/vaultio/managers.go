@@ -0,0 +1,79 @@
+package vaultio
+
+import (
+	"strings"
+
+	"github.com/lockr/go/internal/database"
+)
+
+// Helpers shared by the readers of other password managers' exports
+
+// loginNotes combines a login's username and notes into a secret's notes,
+// returning nil when both are empty
+func loginNotes(username, notes string) *string {
+	var parts []string
+	if username != "" {
+		parts = append(parts, "username: "+username)
+	}
+	if notes != "" {
+		parts = append(parts, notes)
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	joined := strings.Join(parts, "\n")
+	return &joined
+}
+
+// folderKey builds a key from folder names and an item name
+func folderKey(folders []string, name string) string {
+	var parts []string
+	for _, folder := range folders {
+		if strings.TrimSpace(folder) != "" {
+			parts = append(parts, sanitizeKey(folder))
+		}
+	}
+	return strings.Join(append(parts, sanitizeKey(name)), "/")
+}
+
+// optional returns a pointer to s, or nil when s is blank
+func optional(s string) *string {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil
+	}
+	return &s
+}
+
+// sanitizeKey replaces characters that are not valid in lockr keys
+func sanitizeKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(name) {
+		// "/" separates the folder from the item name
+		if database.IsValidKeyChar(r) && r != '/' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+
+	if b.Len() == 0 {
+		return "unnamed"
+	}
+	return b.String()
+}
+
+// splitTagList converts a comma or semicolon separated tag list into lockr's
+// stored form
+func splitTagList(field string) *string {
+	var tags []string
+	for _, tag := range strings.FieldsFunc(field, func(r rune) bool { return r == ',' || r == ';' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	joined := strings.Join(tags, ", ")
+	return &joined
+}
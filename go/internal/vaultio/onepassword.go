@@ -0,0 +1,39 @@
+package vaultio
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReadOnePassword parses a 1Password CSV export. Columns are matched by
+// name, so exports from 1Password 7 and 8 both work. Items without a
+// password are skipped, as are archived ones; the username is kept in the
+// notes.
+func ReadOnePassword(r io.Reader) ([]Record, error) {
+	rows, err := readCSVRows(r, "1Password")
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > 0 {
+		if _, ok := rows[0]["password"]; !ok {
+			return nil, fmt.Errorf("invalid 1Password export: no password column")
+		}
+	}
+
+	var records []Record
+	for _, row := range rows {
+		password := row["password"]
+		if password == "" || strings.EqualFold(row["archived"], "true") {
+			continue
+		}
+		records = append(records, Record{
+			Key:   folderKey([]string{row["vault"]}, column(row, "title", "name")),
+			Value: password,
+			Notes: loginNotes(row["username"], column(row, "notes", "notesplain")),
+			URL:   optional(column(row, "url", "website", "urls")),
+			Tags:  splitTagList(row["tags"]),
+		})
+	}
+	return records, nil
+}
@@ -0,0 +1,67 @@
+package vaultio
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReadPassStore reads a pass (password-store) directory. Every .gpg file
+// becomes a secret keyed by its path; the first line of the decrypted file
+// is the value and the rest become the notes, as pass itself treats them.
+// decrypt returns a file's plaintext, usually by running gpg.
+func ReadPassStore(dir string, decrypt func(path string) ([]byte, error)) ([]Record, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a password store directory", dir)
+	}
+
+	var records []Record
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// Skip .git and other hidden directories
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".gpg" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, strings.TrimSuffix(path, ".gpg"))
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+
+		plaintext, err := decrypt(path)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %w", rel, err)
+		}
+		value, notes, _ := strings.Cut(string(plaintext), "\n")
+		value = strings.TrimSuffix(value, "\r")
+		if value == "" {
+			return nil
+		}
+
+		records = append(records, Record{
+			Key:   folderKey(parts[:len(parts)-1], parts[len(parts)-1]),
+			Value: value,
+			Notes: optional(notes),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,206 @@
+package vaultio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// What happens to each imported record
+const (
+	OutcomeAdded       = "added"
+	OutcomeOverwritten = "overwritten"
+	OutcomeUnchanged   = "unchanged"
+	OutcomeSkipped     = "skipped"
+	OutcomeRenamed     = "renamed"
+)
+
+// How to treat a record whose key already holds a different value
+const (
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictRename    = "rename"
+	ConflictNewer     = "merge-newer"
+	ConflictFail      = "fail"
+	ConflictAsk       = "ask"
+)
+
+// renameSuffix is appended to the keys of renamed records
+const renameSuffix = "-imported"
+
+// ErrConflict is returned by PlanImport under ConflictFail when a key
+// already holds a different value
+var ErrConflict = errors.New("key already exists with a different value")
+
+// ImportItem is one record and what the import does with it.
+// RenamedFrom is the record's original key when it was renamed.
+type ImportItem struct {
+	Record      Record
+	Outcome     string
+	RenamedFrom string
+}
+
+// Existing is what PlanImport learns about a key already in the vault
+type Existing struct {
+	Value   string
+	Changed time.Time
+}
+
+// AskFunc chooses ConflictSkip, ConflictOverwrite or ConflictRename for
+// one conflicting record under ConflictAsk
+type AskFunc func(r Record, existing Existing) (string, error)
+
+// ImportPlan lists what importing a set of records into a vault would do.
+// It never holds on to the vault's existing values.
+type ImportPlan struct {
+	Source string
+	Items  []ImportItem
+	Failed map[string]error
+}
+
+// PlanImport decides the outcome of each record. current returns what is
+// stored under a key, if anything. Records repeating a key (ignoring case)
+// are collapsed, the last one winning. ask decides each conflict under
+// ConflictAsk and may be nil otherwise.
+func PlanImport(source string, records []Record, current func(key string) (Existing, bool), conflict string, ask AskFunc) (*ImportPlan, error) {
+	switch conflict {
+	case ConflictSkip, ConflictOverwrite, ConflictRename, ConflictNewer, ConflictFail, ConflictAsk:
+	default:
+		return nil, fmt.Errorf("unknown conflict strategy %q (use skip, overwrite, rename, merge-newer, fail or ask)", conflict)
+	}
+
+	index := make(map[string]int)
+	var unique []Record
+	for _, r := range records {
+		lower := strings.ToLower(r.Key)
+		if i, ok := index[lower]; ok {
+			unique[i] = r
+			continue
+		}
+		index[lower] = len(unique)
+		unique = append(unique, r)
+	}
+
+	// Renamed records must not land on a key in the vault or the import
+	reserved := make(map[string]bool, len(index))
+	for key := range index {
+		reserved[key] = true
+	}
+	taken := func(key string) bool {
+		if reserved[strings.ToLower(key)] {
+			return true
+		}
+		_, ok := current(key)
+		return ok
+	}
+
+	plan := &ImportPlan{Source: source, Failed: make(map[string]error)}
+	for _, r := range unique {
+		item := ImportItem{Record: r, Outcome: OutcomeAdded}
+		existing, ok := current(r.Key)
+		if ok && existing.Value == r.Value {
+			item.Outcome = OutcomeUnchanged
+		} else if ok {
+			strategy := conflict
+			if strategy == ConflictAsk {
+				var err error
+				if strategy, err = ask(r, existing); err != nil {
+					return nil, err
+				}
+			}
+			if strategy == ConflictNewer {
+				strategy = ConflictSkip
+				if changed := r.changed(); !changed.IsZero() && changed.After(existing.Changed) {
+					strategy = ConflictOverwrite
+				}
+			}
+
+			switch strategy {
+			case ConflictOverwrite:
+				item.Outcome = OutcomeOverwritten
+			case ConflictRename:
+				item.Outcome = OutcomeRenamed
+				item.RenamedFrom = r.Key
+				item.Record.Key = freeKey(r.Key, taken)
+				reserved[strings.ToLower(item.Record.Key)] = true
+			case ConflictFail:
+				return nil, fmt.Errorf("%w: %s", ErrConflict, r.Key)
+			default:
+				item.Outcome = OutcomeSkipped
+			}
+		}
+		plan.Items = append(plan.Items, item)
+	}
+	return plan, nil
+}
+
+// freeKey returns the first of key-imported, key-imported-2, ... that is
+// not taken
+func freeKey(key string, taken func(string) bool) string {
+	candidate := key + renameSuffix
+	for n := 2; taken(candidate); n++ {
+		candidate = fmt.Sprintf("%s%s-%d", key, renameSuffix, n)
+	}
+	return candidate
+}
+
+// changed is when the record's value was last set, or zero when the source
+// does not say
+func (r Record) changed() time.Time {
+	if r.UpdatedAt != nil {
+		return *r.UpdatedAt
+	}
+	return r.CreatedAt
+}
+
+// Records returns the records with the given outcome
+func (p *ImportPlan) Records(outcome string) []Record {
+	var records []Record
+	for _, item := range p.Items {
+		if item.Outcome == outcome {
+			records = append(records, item.Record)
+		}
+	}
+	return records
+}
+
+// WriteReport writes a summary and the outcome of every key. Values are
+// never included.
+func (p *ImportPlan) WriteReport(w io.Writer) {
+	counts := map[string]int{}
+	for _, item := range p.Items {
+		counts[item.Outcome]++
+	}
+
+	fmt.Fprintf(w, "Import from %s\n", p.Source)
+	fmt.Fprintf(w, "  Added:       %d\n", counts[OutcomeAdded])
+	fmt.Fprintf(w, "  Overwritten: %d\n", counts[OutcomeOverwritten])
+	fmt.Fprintf(w, "  Unchanged:   %d\n", counts[OutcomeUnchanged])
+	fmt.Fprintf(w, "  Skipped:     %d\n", counts[OutcomeSkipped])
+	if counts[OutcomeRenamed] > 0 {
+		fmt.Fprintf(w, "  Renamed:     %d\n", counts[OutcomeRenamed])
+	}
+	if len(p.Failed) > 0 {
+		fmt.Fprintf(w, "  Failed:      %d\n", len(p.Failed))
+	}
+
+	if len(p.Items) > 0 {
+		fmt.Fprintln(w)
+	}
+	for _, item := range p.Items {
+		key := item.Record.Key
+		if item.RenamedFrom != "" {
+			key = item.RenamedFrom + " -> " + key
+		}
+		fmt.Fprintf(w, "  %-11s %s%s\n", item.Outcome, key, p.failure(item.Record.Key))
+	}
+}
+
+func (p *ImportPlan) failure(key string) string {
+	if err, ok := p.Failed[key]; ok {
+		return fmt.Sprintf("  (FAILED: %v)", err)
+	}
+	return ""
+}
@@ -0,0 +1,181 @@
+// Package vaultio reads and writes vault contents in interchange formats
+package vaultio
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/strength"
+)
+
+// Supported formats
+const (
+	// FormatLockrx is lockr's own plaintext JSON interchange format
+	FormatLockrx = "lockrx"
+
+	// FormatBitwarden is Bitwarden's unencrypted JSON export
+	FormatBitwarden = "bitwarden"
+
+	// FormatOnePassword is 1Password's CSV export
+	FormatOnePassword = "1password"
+
+	// FormatLastPass is LastPass's CSV export
+	FormatLastPass = "lastpass"
+
+	// FormatKeePass is KeePass 2's XML export
+	FormatKeePass = "keepass"
+
+	// FormatDotenv is a .env file of NAME=value lines
+	FormatDotenv = "dotenv"
+
+	// FormatJSON is a plain JSON array of records, for other tools
+	FormatJSON = "json"
+
+	// FormatCSV is a CSV file with a header row, for spreadsheets
+	FormatCSV = "csv"
+)
+
+// LockrxVersion is the current version of the lockrx format
+const LockrxVersion = 1
+
+// Record is a single secret in an interchange file
+type Record struct {
+	Key       string     `json:"key"`
+	Value     string     `json:"value"`
+	CreatedAt time.Time  `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	Tags      *string    `json:"tags,omitempty"`
+	Notes     *string    `json:"notes,omitempty"`
+	URL       *string    `json:"url,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// lockrxDocument is the top-level structure of a lockrx file
+type lockrxDocument struct {
+	Format     string     `json:"format"`
+	Version    int        `json:"version"`
+	ExportedAt *time.Time `json:"exported_at,omitempty"`
+	Secrets    []Record   `json:"secrets"`
+}
+
+// ReadFile loads records from path in the given format
+func ReadFile(path, format string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch format {
+	case FormatLockrx:
+		return ReadLockrx(f)
+	case FormatBitwarden:
+		return ReadBitwarden(f)
+	case FormatOnePassword:
+		return ReadOnePassword(f)
+	case FormatLastPass:
+		return ReadLastPass(f)
+	case FormatKeePass:
+		return ReadKeePass(f)
+	case FormatDotenv:
+		return ReadDotenv(f)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// ReadLockrx parses a lockrx document
+func ReadLockrx(r io.Reader) ([]Record, error) {
+	var doc lockrxDocument
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid lockrx file: %w", err)
+	}
+
+	if doc.Format != FormatLockrx {
+		return nil, fmt.Errorf("invalid lockrx file: unexpected format %q", doc.Format)
+	}
+	if doc.Version < 1 || doc.Version > LockrxVersion {
+		return nil, fmt.Errorf("unsupported lockrx version %d", doc.Version)
+	}
+
+	return doc.Secrets, nil
+}
+
+// WriteLockrx writes records as a lockrx document
+func WriteLockrx(w io.Writer, records []Record) error {
+	now := time.Now().UTC()
+	doc := lockrxDocument{
+		Format:     FormatLockrx,
+		Version:    LockrxVersion,
+		ExportedAt: &now,
+		Secrets:    records,
+	}
+	return encodeLockrx(w, doc)
+}
+
+// WriteCanonicalLockrx writes records as a lockrx document in canonical
+// form: sorted by key, creation times in UTC to the second and no export
+// time. The same secrets always produce the same bytes, so digests of the
+// output can be compared between machines.
+func WriteCanonicalLockrx(w io.Writer, records []Record) error {
+	return encodeLockrx(w, lockrxDocument{
+		Format:  FormatLockrx,
+		Version: LockrxVersion,
+		Secrets: canonicalRecords(records),
+	})
+}
+
+// canonicalRecords returns a copy of records sorted by key, with times in
+// UTC to the second
+func canonicalRecords(records []Record) []Record {
+	canonical := make([]Record, len(records))
+	for i, r := range records {
+		r.CreatedAt = r.CreatedAt.UTC().Truncate(time.Second)
+		if r.UpdatedAt != nil {
+			updated := r.UpdatedAt.UTC().Truncate(time.Second)
+			r.UpdatedAt = &updated
+		}
+		if r.ExpiresAt != nil {
+			expires := r.ExpiresAt.UTC().Truncate(time.Second)
+			r.ExpiresAt = &expires
+		}
+		canonical[i] = r
+	}
+	sort.Slice(canonical, func(i, j int) bool { return canonical[i].Key < canonical[j].Key })
+	return canonical
+}
+
+func encodeLockrx(w io.Writer, doc lockrxDocument) error {
+	if doc.Secrets == nil {
+		doc.Secrets = []Record{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// ToSecrets converts records into database secrets ready for import,
+// estimating the strength of each value
+func ToSecrets(records []Record) []database.Secret {
+	secrets := make([]database.Secret, len(records))
+	for i, r := range records {
+		bits := strength.Estimate(r.Value)
+		secrets[i] = database.Secret{
+			Key:         r.Key,
+			Value:       r.Value,
+			CreatedAt:   r.CreatedAt,
+			Tags:        r.Tags,
+			Notes:       r.Notes,
+			URL:         r.URL,
+			ExpiresAt:   r.ExpiresAt,
+			EntropyBits: &bits,
+		}
+	}
+	return secrets
+}
@@ -0,0 +1,459 @@
+package vaultio
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lockr/go/internal/crypto"
+)
+
+func TestLockrx_RoundTrip(t *testing.T) {
+	notes := "rotated monthly"
+	records := []Record{
+		{Key: "prod/db", Value: "s3cret"},
+		{Key: "api_key", Value: "abc", Notes: &notes},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteLockrx(&buf, records))
+
+	loaded, err := ReadLockrx(&buf)
+	require.NoError(t, err)
+	require.Len(t, loaded, 2)
+	assert.Equal(t, "prod/db", loaded[0].Key)
+	assert.Equal(t, "s3cret", loaded[0].Value)
+	require.NotNil(t, loaded[1].Notes)
+	assert.Equal(t, notes, *loaded[1].Notes)
+}
+
+func TestWriteCanonicalLockrx(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 0, 0, 500, time.FixedZone("CET", 3600))
+	a := []Record{
+		{Key: "b", Value: "2", CreatedAt: created},
+		{Key: "a", Value: "1"},
+	}
+	b := []Record{
+		{Key: "a", Value: "1"},
+		{Key: "b", Value: "2", CreatedAt: created.UTC().Truncate(time.Second)},
+	}
+
+	var bufA, bufB bytes.Buffer
+	require.NoError(t, WriteCanonicalLockrx(&bufA, a))
+	require.NoError(t, WriteCanonicalLockrx(&bufB, b))
+	assert.Equal(t, bufA.String(), bufB.String())
+	assert.NotContains(t, bufA.String(), "exported_at")
+	assert.Contains(t, bufA.String(), `"2024-03-01T11:00:00Z"`)
+	assert.Equal(t, "b", a[0].Key, "input is not reordered")
+
+	loaded, err := ReadLockrx(&bufA)
+	require.NoError(t, err)
+	assert.Equal(t, "a", loaded[0].Key)
+}
+
+func TestLockrx_Invalid(t *testing.T) {
+	_, err := ReadLockrx(strings.NewReader(`{"format":"other","version":1}`))
+	assert.Error(t, err)
+
+	_, err = ReadLockrx(strings.NewReader(`{"format":"lockrx","version":99}`))
+	assert.Error(t, err)
+
+	_, err = ReadLockrx(strings.NewReader(`not json`))
+	assert.Error(t, err)
+}
+
+func TestReadBitwarden(t *testing.T) {
+	export := `{
+		"encrypted": false,
+		"folders": [{"id": "f1", "name": "Work Stuff"}],
+		"items": [
+			{"type": 1, "folderId": "f1", "name": "GitHub", "login": {"username": "alice", "password": "gh-pass"}},
+			{"type": 1, "folderId": null, "name": "No Password", "login": {"username": "bob"}},
+			{"type": 2, "folderId": null, "name": "Recovery codes", "notes": "1234 5678"},
+			{"type": 3, "folderId": null, "name": "Visa"}
+		]
+	}`
+
+	records, err := ReadBitwarden(strings.NewReader(export))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	assert.Equal(t, "Work_Stuff/GitHub", records[0].Key)
+	assert.Equal(t, "gh-pass", records[0].Value)
+	require.NotNil(t, records[0].Notes)
+	assert.Contains(t, *records[0].Notes, "username: alice")
+
+	assert.Equal(t, "Recovery_codes", records[1].Key)
+	assert.Equal(t, "1234 5678", records[1].Value)
+}
+
+func TestReadBitwarden_Encrypted(t *testing.T) {
+	_, err := ReadBitwarden(strings.NewReader(`{"encrypted": true, "items": []}`))
+	assert.Error(t, err)
+}
+
+func TestReadOnePassword(t *testing.T) {
+	export := "Title,Url,Username,Password,OTPAuth,Favorite,Archived,Tags,Notes\n" +
+		"GitHub,https://github.com,alice,gh-pass,,false,false,\"dev,work\",personal account\n" +
+		"Old,,bob,old-pass,,false,true,,\n" +
+		"Wifi,,,,,false,false,,no password\n"
+
+	records, err := ReadOnePassword(strings.NewReader(export))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "GitHub", records[0].Key)
+	assert.Equal(t, "gh-pass", records[0].Value)
+	assert.Equal(t, "https://github.com", *records[0].URL)
+	assert.Equal(t, "dev, work", *records[0].Tags)
+	assert.Equal(t, "username: alice\npersonal account", *records[0].Notes)
+
+	_, err = ReadOnePassword(strings.NewReader("a,b\n1,2\n"))
+	assert.Error(t, err)
+}
+
+func TestReadLastPass(t *testing.T) {
+	export := "url,username,password,totp,extra,name,grouping,fav\n" +
+		"https://aws.amazon.com,root,aws-pass,,,AWS,Work\\Cloud,0\n" +
+		"http://sn,,,,door code 1234,Office,Work,0\n"
+
+	records, err := ReadLastPass(strings.NewReader(export))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "Work/Cloud/AWS", records[0].Key)
+	assert.Equal(t, "aws-pass", records[0].Value)
+	assert.Equal(t, "https://aws.amazon.com", *records[0].URL)
+	assert.Equal(t, "Work/Office", records[1].Key)
+	assert.Equal(t, "door code 1234", records[1].Value)
+	assert.Nil(t, records[1].URL)
+}
+
+func TestReadKeePass(t *testing.T) {
+	export := `<?xml version="1.0" encoding="utf-8"?>
+<KeePassFile>
+	<Meta><RecycleBinUUID>bin</RecycleBinUUID></Meta>
+	<Root>
+		<Group>
+			<UUID>root</UUID><Name>Database</Name>
+			<Entry>
+				<Tags>prod;db</Tags>
+				<Times>
+					<CreationTime>2024-01-02T03:04:05Z</CreationTime>
+					<Expires>True</Expires><ExpiryTime>2030-01-01T00:00:00Z</ExpiryTime>
+				</Times>
+				<String><Key>Title</Key><Value>Postgres</Value></String>
+				<String><Key>UserName</Key><Value>app</Value></String>
+				<String><Key>Password</Key><Value ProtectInMemory="True">pg-pass</Value></String>
+				<History><Entry><String><Key>Password</Key><Value>older</Value></String></Entry></History>
+			</Entry>
+			<Group>
+				<UUID>g1</UUID><Name>Email</Name>
+				<Entry><String><Key>Title</Key><Value>Fastmail</Value></String><String><Key>Password</Key><Value>fm-pass</Value></String></Entry>
+			</Group>
+			<Group>
+				<UUID>bin</UUID><Name>Recycle Bin</Name>
+				<Entry><String><Key>Title</Key><Value>Deleted</Value></String><String><Key>Password</Key><Value>gone</Value></String></Entry>
+			</Group>
+		</Group>
+	</Root>
+</KeePassFile>`
+
+	records, err := ReadKeePass(strings.NewReader(export))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "Postgres", records[0].Key)
+	assert.Equal(t, "pg-pass", records[0].Value)
+	assert.Equal(t, "prod, db", *records[0].Tags)
+	assert.Equal(t, 2024, records[0].CreatedAt.Year())
+	require.NotNil(t, records[0].ExpiresAt)
+	assert.Equal(t, 2030, records[0].ExpiresAt.Year())
+	assert.Equal(t, "Email/Fastmail", records[1].Key)
+
+	_, err = ReadKeePass(strings.NewReader(`<KeePassFile><Root><Group><Entry><String><Key>Password</Key><Value Protected="True">c2VjcmV0</Value></String></Entry></Group></Root></KeePassFile>`))
+	assert.Error(t, err)
+}
+
+func TestReadPassStore(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"email/fastmail.gpg": "fm-pass\nuser: alice\n",
+		"github.gpg":         "gh-pass",
+		".git/config":        "ignored",
+		".gpg-id":            "ABCDEF",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+
+	// A fake decrypt that returns the file as is
+	records, err := ReadPassStore(dir, os.ReadFile)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "email/fastmail", records[0].Key)
+	assert.Equal(t, "fm-pass", records[0].Value)
+	assert.Equal(t, "user: alice", *records[0].Notes)
+	assert.Equal(t, "github", records[1].Key)
+	assert.Nil(t, records[1].Notes)
+
+	_, err = ReadPassStore(filepath.Join(dir, "github.gpg"), os.ReadFile)
+	assert.Error(t, err)
+}
+
+func TestReadDotenv(t *testing.T) {
+	env := `# database
+export DB_HOST=localhost
+DB_PASSWORD='p@ss # not a comment'
+API_KEY=abc123 # trailing comment
+GREETING="hello\tworld \"quoted\""
+CERT="line one
+line two"
+EMPTY=
+DB_HOST=db.internal
+`
+
+	records, err := ReadDotenv(strings.NewReader(env))
+	require.NoError(t, err)
+	require.Len(t, records, 6)
+
+	values := make(map[string]string)
+	for _, r := range records {
+		values[r.Key] = r.Value
+	}
+	assert.Equal(t, "DB_HOST", records[0].Key)
+	assert.Equal(t, "db.internal", values["DB_HOST"])
+	assert.Equal(t, "p@ss # not a comment", values["DB_PASSWORD"])
+	assert.Equal(t, "abc123", values["API_KEY"])
+	assert.Equal(t, "hello\tworld \"quoted\"", values["GREETING"])
+	assert.Equal(t, "line one\nline two", values["CERT"])
+	assert.Equal(t, "", values["EMPTY"])
+}
+
+func TestReadDotenv_Invalid(t *testing.T) {
+	_, err := ReadDotenv(strings.NewReader("NOT A VARIABLE\n"))
+	assert.Error(t, err)
+
+	_, err = ReadDotenv(strings.NewReader("KEY=\"unterminated\n"))
+	assert.Error(t, err)
+
+	_, err = ReadDotenv(strings.NewReader("1KEY=value\n"))
+	assert.Error(t, err)
+}
+
+func TestFromEnvironment(t *testing.T) {
+	records := FromEnvironment([]string{
+		"MYAPP_TOKEN=t0k",
+		"PATH=/usr/bin",
+		"MYAPP_DB_URL=postgres://x=y",
+		"MYAPP_=ignored",
+	}, "MYAPP_")
+
+	require.Len(t, records, 2)
+	assert.Equal(t, "DB_URL", records[0].Key)
+	assert.Equal(t, "postgres://x=y", records[0].Value)
+	assert.Equal(t, "TOKEN", records[1].Key)
+}
+
+func TestRecoveryBundle_RoundTrip(t *testing.T) {
+	pub, priv, err := crypto.GenerateRecipientKey()
+	require.NoError(t, err)
+
+	records := []Record{{Key: "bank/pin", Value: "1234"}}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteRecoveryBundle(&buf, pub, records))
+	assert.NotContains(t, buf.String(), "1234")
+
+	// Surrounding text such as an email body is ignored
+	armored := "Forwarded message:\n\n" + buf.String() + "\n-- \nsignature"
+
+	loaded, err := ReadRecoveryBundle(strings.NewReader(armored), priv)
+	require.NoError(t, err)
+	require.Len(t, loaded, 1)
+	assert.Equal(t, "bank/pin", loaded[0].Key)
+	assert.Equal(t, "1234", loaded[0].Value)
+
+	_, otherPriv, err := crypto.GenerateRecipientKey()
+	require.NoError(t, err)
+	_, err = ReadRecoveryBundle(bytes.NewReader(buf.Bytes()), otherPriv)
+	assert.Error(t, err)
+}
+
+func TestArchive_RoundTrip(t *testing.T) {
+	url := "https://console.example.com"
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	records := []Record{{Key: "db/prod", Value: "hunter2", URL: &url, ExpiresAt: &expires}}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteArchive(&buf, "correct horse", records))
+	assert.True(t, IsArchive(buf.Bytes()))
+	assert.NotContains(t, buf.String(), "hunter2")
+	assert.NotContains(t, buf.String(), "db/prod")
+
+	loaded, err := ReadArchive(bytes.NewReader(buf.Bytes()), "correct horse")
+	require.NoError(t, err)
+	require.Len(t, loaded, 1)
+	assert.Equal(t, "hunter2", loaded[0].Value)
+	require.NotNil(t, loaded[0].URL)
+	assert.Equal(t, url, *loaded[0].URL)
+	require.NotNil(t, loaded[0].ExpiresAt)
+	assert.True(t, expires.Equal(*loaded[0].ExpiresAt))
+
+	_, err = ReadArchive(bytes.NewReader(buf.Bytes()), "wrong")
+	assert.ErrorIs(t, err, ErrArchivePassword)
+
+	// The header is authenticated: a lowered work factor is detected
+	tampered := bytes.Clone(buf.Bytes())
+	tampered[len(archiveMagic)+4]--
+	_, err = ReadArchive(bytes.NewReader(tampered), "correct horse")
+	assert.ErrorIs(t, err, ErrArchivePassword)
+
+	_, err = ReadArchive(strings.NewReader(`{"format":"lockrx"}`), "correct horse")
+	assert.Error(t, err)
+	assert.Error(t, WriteArchive(&bytes.Buffer{}, "", records))
+}
+
+func TestWriteJSONAndCSV(t *testing.T) {
+	notes := "line one, \"quoted\""
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	records := []Record{
+		{Key: "b", Value: "2", CreatedAt: created, Notes: &notes},
+		{Key: "a", Value: "1", CreatedAt: created},
+	}
+
+	var j bytes.Buffer
+	require.NoError(t, WriteJSON(&j, records))
+	assert.True(t, strings.HasPrefix(j.String(), "[\n  {\n    \"key\": \"a\""))
+
+	var c bytes.Buffer
+	require.NoError(t, WriteCSV(&c, records))
+	assert.Equal(t, "key,value,created_at,tags,notes,url,expires_at\n"+
+		"a,1,2024-03-01T12:00:00Z,,,,\n"+
+		"b,2,2024-03-01T12:00:00Z,,\"line one, \"\"quoted\"\"\",,\n", c.String())
+}
+
+func TestPlanImport(t *testing.T) {
+	existing := map[string]string{"a": "1", "b": "2"}
+	current := func(key string) (Existing, bool) {
+		v, ok := existing[strings.ToLower(key)]
+		return Existing{Value: v}, ok
+	}
+	records := []Record{
+		{Key: "a", Value: "1"},
+		{Key: "b", Value: "s3cret"},
+		{Key: "c", Value: "first"},
+		{Key: "C", Value: "last"},
+	}
+
+	plan, err := PlanImport(".env", records, current, ConflictSkip, nil)
+	require.NoError(t, err)
+	require.Len(t, plan.Items, 3)
+	assert.Equal(t, OutcomeUnchanged, plan.Items[0].Outcome)
+	assert.Equal(t, OutcomeSkipped, plan.Items[1].Outcome)
+	assert.Equal(t, OutcomeAdded, plan.Items[2].Outcome)
+	assert.Equal(t, "last", plan.Items[2].Record.Value)
+
+	plan, err = PlanImport(".env", records, current, ConflictOverwrite, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []Record{{Key: "b", Value: "s3cret"}}, plan.Records(OutcomeOverwritten))
+
+	_, err = PlanImport(".env", records, current, ConflictFail, nil)
+	assert.ErrorIs(t, err, ErrConflict)
+
+	_, err = PlanImport(".env", records, current, "merge", nil)
+	assert.Error(t, err)
+
+	var buf bytes.Buffer
+	plan.WriteReport(&buf)
+	assert.Contains(t, buf.String(), "Overwritten: 1")
+	assert.NotContains(t, buf.String(), "s3cret")
+}
+
+func TestPlanImport_Strategies(t *testing.T) {
+	vaultChanged := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	existing := map[string]string{"a": "1", "a-imported": "x"}
+	current := func(key string) (Existing, bool) {
+		v, ok := existing[strings.ToLower(key)]
+		return Existing{Value: v, Changed: vaultChanged}, ok
+	}
+	older := vaultChanged.Add(-time.Hour)
+	newer := vaultChanged.Add(time.Hour)
+
+	plan, err := PlanImport("x", []Record{{Key: "a", Value: "2"}}, current, ConflictRename, nil)
+	require.NoError(t, err)
+	require.Len(t, plan.Items, 1)
+	assert.Equal(t, OutcomeRenamed, plan.Items[0].Outcome)
+	assert.Equal(t, "a", plan.Items[0].RenamedFrom)
+	assert.Equal(t, "a-imported-2", plan.Items[0].Record.Key)
+
+	plan, err = PlanImport("x", []Record{{Key: "a", Value: "2", CreatedAt: older}}, current, ConflictNewer, nil)
+	require.NoError(t, err)
+	assert.Equal(t, OutcomeSkipped, plan.Items[0].Outcome)
+
+	plan, err = PlanImport("x", []Record{{Key: "a", Value: "2", CreatedAt: older, UpdatedAt: &newer}}, current, ConflictNewer, nil)
+	require.NoError(t, err)
+	assert.Equal(t, OutcomeOverwritten, plan.Items[0].Outcome)
+
+	plan, err = PlanImport("x", []Record{{Key: "a", Value: "2"}}, current, ConflictNewer, nil)
+	require.NoError(t, err)
+	assert.Equal(t, OutcomeSkipped, plan.Items[0].Outcome, "records without times are never newer")
+
+	var asked []string
+	ask := func(r Record, e Existing) (string, error) {
+		asked = append(asked, r.Key)
+		return ConflictOverwrite, nil
+	}
+	plan, err = PlanImport("x", []Record{{Key: "a", Value: "2"}, {Key: "b", Value: "3"}}, current, ConflictAsk, ask)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, asked)
+	assert.Equal(t, OutcomeOverwritten, plan.Items[0].Outcome)
+	assert.Equal(t, OutcomeAdded, plan.Items[1].Outcome)
+
+	aborted := errors.New("aborted")
+	_, err = PlanImport("x", []Record{{Key: "a", Value: "2"}}, current, ConflictAsk, func(Record, Existing) (string, error) {
+		return "", aborted
+	})
+	assert.ErrorIs(t, err, aborted)
+}
+
+func TestWriteDotenv_RoundTrip(t *testing.T) {
+	vars := []EnvVar{
+		{Name: "PLAIN", Value: "abc123"},
+		{Name: "SPACES", Value: " padded value "},
+		{Name: "QUOTES", Value: `say "hi" it's \ok`},
+		{Name: "MULTI", Value: "line one\nline two"},
+		{Name: "HASH", Value: "p#ss"},
+		{Name: "DOLLAR", Value: "pa$HOME"},
+		{Name: "EMPTY", Value: ""},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteDotenv(&buf, vars))
+	assert.Contains(t, buf.String(), "PLAIN=abc123\n")
+	assert.Contains(t, buf.String(), "DOLLAR='pa$HOME'\n")
+
+	records, err := ReadDotenv(&buf)
+	require.NoError(t, err)
+	require.Len(t, records, len(vars))
+	for i, v := range vars {
+		assert.Equal(t, v.Name, records[i].Key)
+		assert.Equal(t, v.Value, records[i].Value)
+	}
+
+	assert.Error(t, WriteDotenv(&buf, []EnvVar{{Name: "not valid", Value: "x"}}))
+}
+
+func TestWriteComposeEnv(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteComposeEnv(&buf, []EnvVar{{Name: "URL", Value: `a "b" #c`}}))
+	assert.Equal(t, "URL=a \"b\" #c\n", buf.String())
+
+	assert.Error(t, WriteComposeEnv(&buf, []EnvVar{{Name: "CERT", Value: "a\nb"}}))
+}
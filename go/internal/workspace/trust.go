@@ -0,0 +1,88 @@
+package workspace
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TrustStore remembers which workspace files the user allowed, by path and
+// content digest, so an edited file has to be trusted again
+type TrustStore struct {
+	path string
+}
+
+// NewTrustStore creates a trust store kept in the file at path
+func NewTrustStore(path string) *TrustStore {
+	return &TrustStore{path: path}
+}
+
+// IsTrusted reports whether ws, with its current contents, was trusted
+func (t *TrustStore) IsTrusted(ws *Workspace) (bool, error) {
+	entries, err := t.load()
+	if err != nil {
+		return false, err
+	}
+	return entries[ws.Path] == ws.Digest, nil
+}
+
+// Trust records ws's current contents as trusted
+func (t *TrustStore) Trust(ws *Workspace) error {
+	entries, err := t.load()
+	if err != nil {
+		return err
+	}
+	entries[ws.Path] = ws.Digest
+	return t.save(entries)
+}
+
+// Revoke forgets any trust for the workspace file at path
+func (t *TrustStore) Revoke(path string) error {
+	entries, err := t.load()
+	if err != nil {
+		return err
+	}
+	delete(entries, path)
+	return t.save(entries)
+}
+
+// load reads "digest path" lines
+func (t *TrustStore) load() (map[string]string, error) {
+	entries := map[string]string{}
+	file, err := os.Open(t.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		digest, path, ok := strings.Cut(scanner.Text(), " ")
+		if ok && path != "" {
+			entries[path] = digest
+		}
+	}
+	return entries, scanner.Err()
+}
+
+func (t *TrustStore) save(entries map[string]string) error {
+	if err := os.MkdirAll(filepath.Dir(t.path), 0700); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	for path, digest := range entries {
+		b.WriteString(digest + " " + path + "\n")
+	}
+
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.path)
+}
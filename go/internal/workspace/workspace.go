@@ -0,0 +1,191 @@
+// Package workspace finds project-local vault settings: a .lockr.toml file
+// in the current directory or one of its parents can point lockr at a
+// project vault and a default key namespace. Like direnv, a workspace file
+// is only used once the user has trusted its current contents.
+package workspace
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileName is the name of the workspace file
+const FileName = ".lockr.toml"
+
+// Workspace is a parsed workspace file
+type Workspace struct {
+	// Path is the absolute path of the workspace file
+	Path string
+	// Vault is the absolute path of the project vault, if set
+	Vault string
+	// Namespace is prefixed to relative keys, e.g. "myapp/"
+	Namespace string
+	// Digest identifies the file's contents for trust decisions
+	Digest string
+}
+
+// Find looks for a workspace file in dir and its parents. It returns nil
+// without an error when there is none.
+func Find(dir string) (*Workspace, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		path := filepath.Join(dir, FileName)
+		info, err := os.Stat(path)
+		if err == nil && !info.IsDir() {
+			return Load(path)
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// Load reads and parses a workspace file
+func Load(path string) (*Workspace, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	sum := sha256.Sum256(append([]byte(path+"\x00"), data...))
+	ws := &Workspace{Path: path, Digest: hex.EncodeToString(sum[:])}
+	for key, value := range values {
+		switch key {
+		case "vault":
+			if value == "" {
+				return nil, fmt.Errorf("%s: vault must not be empty", path)
+			}
+			if !filepath.IsAbs(value) {
+				value = filepath.Join(filepath.Dir(path), value)
+			}
+			ws.Vault = filepath.Clean(value)
+		case "namespace":
+			ws.Namespace = normalizeNamespace(value)
+		default:
+			return nil, fmt.Errorf("%s: unknown setting %q (supported: vault, namespace)", path, key)
+		}
+	}
+	return ws, nil
+}
+
+// QualifyKey applies the workspace namespace to a key given on the command
+// line. Inside a namespace, a key starting with "/" is taken as absolute and
+// only loses the slash; keys already inside the namespace are left alone.
+func (ws *Workspace) QualifyKey(key string) string {
+	if ws == nil || ws.Namespace == "" {
+		return key
+	}
+	if strings.HasPrefix(key, "/") {
+		return strings.TrimPrefix(key, "/")
+	}
+	if strings.HasPrefix(strings.ToLower(key), strings.ToLower(ws.Namespace)) {
+		return key
+	}
+	return ws.Namespace + key
+}
+
+func normalizeNamespace(ns string) string {
+	ns = strings.Trim(ns, "/")
+	if ns == "" {
+		return ""
+	}
+	return ns + "/"
+}
+
+// parse reads the subset of TOML used by workspace files: top-level
+// key = "string" pairs, blank lines and # comments
+func parse(data []byte) (map[string]string, error) {
+	values := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, rest, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t\"'[]") {
+			return nil, fmt.Errorf("line %d: expected key = \"value\"", n)
+		}
+
+		value, err := parseString(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if _, dup := values[key]; dup {
+			return nil, fmt.Errorf("line %d: %s set twice", n, key)
+		}
+		values[key] = value
+	}
+	return values, scanner.Err()
+}
+
+// parseString parses a basic ("...") or literal ('...') TOML string with an
+// optional trailing comment
+func parseString(s string) (string, error) {
+	if s == "" {
+		return "", errors.New("missing value")
+	}
+
+	quote := s[0]
+	if quote != '"' && quote != '\'' {
+		return "", errors.New("value must be a quoted string")
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			if rest := strings.TrimSpace(s[i+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+				return "", fmt.Errorf("unexpected %q after value", rest)
+			}
+			return b.String(), nil
+		case c == '\\' && quote == '"':
+			i++
+			if i == len(s) {
+				return "", errors.New("unterminated string")
+			}
+			switch s[i] {
+			case '"', '\\':
+				b.WriteByte(s[i])
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				return "", fmt.Errorf("unsupported escape \\%c", s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", errors.New("unterminated string")
+}
@@ -0,0 +1,99 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeWorkspace(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, FileName)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "src", "pkg")
+	require.NoError(t, os.MkdirAll(nested, 0755))
+
+	ws, err := Find(nested)
+	require.NoError(t, err)
+	assert.Nil(t, ws)
+
+	path := writeWorkspace(t, root, `
+# Project vault
+vault = "secrets/project.lockr"
+namespace = 'myapp'   # default namespace
+`)
+
+	ws, err = Find(nested)
+	require.NoError(t, err)
+	require.NotNil(t, ws)
+	assert.Equal(t, path, ws.Path)
+	assert.Equal(t, filepath.Join(root, "secrets", "project.lockr"), ws.Vault)
+	assert.Equal(t, "myapp/", ws.Namespace)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	for _, content := range []string{
+		`vault = unquoted`,
+		`vault = "unterminated`,
+		`vault = "a" extra`,
+		`colour = "blue"`,
+		`[section]`,
+		"vault = \"a\"\nvault = \"b\"",
+		`vault = ""`,
+	} {
+		path := writeWorkspace(t, t.TempDir(), content)
+		_, err := Load(path)
+		assert.Error(t, err, content)
+	}
+}
+
+func TestQualifyKey(t *testing.T) {
+	ws := &Workspace{Namespace: "myapp/"}
+	assert.Equal(t, "myapp/db", ws.QualifyKey("db"))
+	assert.Equal(t, "MyApp/db", ws.QualifyKey("MyApp/db"))
+	assert.Equal(t, "personal/email", ws.QualifyKey("/personal/email"))
+
+	var none *Workspace
+	assert.Equal(t, "db", none.QualifyKey("db"))
+	assert.Equal(t, "/db", none.QualifyKey("/db"))
+}
+
+func TestTrustStore(t *testing.T) {
+	dir := t.TempDir()
+	path := writeWorkspace(t, dir, `vault = "a.lockr"`)
+	store := NewTrustStore(filepath.Join(dir, "state", "trusted"))
+
+	ws, err := Load(path)
+	require.NoError(t, err)
+
+	trusted, err := store.IsTrusted(ws)
+	require.NoError(t, err)
+	assert.False(t, trusted)
+
+	require.NoError(t, store.Trust(ws))
+	trusted, err = store.IsTrusted(ws)
+	require.NoError(t, err)
+	assert.True(t, trusted)
+
+	// Editing the file revokes trust
+	writeWorkspace(t, dir, `vault = "/elsewhere.lockr"`)
+	edited, err := Load(path)
+	require.NoError(t, err)
+	trusted, err = store.IsTrusted(edited)
+	require.NoError(t, err)
+	assert.False(t, trusted)
+
+	require.NoError(t, store.Trust(edited))
+	require.NoError(t, store.Revoke(path))
+	trusted, err = store.IsTrusted(edited)
+	require.NoError(t, err)
+	assert.False(t, trusted)
+}
@@ -0,0 +1,86 @@
+package fuzzy
+
+import "fmt"
+
+// String names the quality as shown in explanations
+func (q Quality) String() string {
+	switch q {
+	case ExactMatch:
+		return "exact"
+	case PrefixMatch:
+		return "prefix"
+	case SubstringMatch:
+		return "substring"
+	case FuzzyMatch:
+		return "fuzzy"
+	default:
+		return "none"
+	}
+}
+
+// Factor is one rule that contributed to a score
+type Factor struct {
+	Name   string  `json:"name"`
+	Value  float64 `json:"value"`
+	Detail string  `json:"detail,omitempty"`
+}
+
+// Explanation breaks the score of one target down into the rules that
+// produced it, for debugging rankings and tuning Options
+type Explanation struct {
+	Quality Quality  `json:"quality"`
+	Score   float64  `json:"score"`
+	Factors []Factor `json:"factors"`
+	Notes   []string `json:"notes,omitempty"`
+}
+
+// Explain scores target against query like Score and reports how the score
+// was reached. Matches with equal scores are ordered by Rank's tie-breakers:
+// targets starting with the query first, then alphabetically.
+func (m *Matcher) Explain(query, target string) Explanation {
+	queryNorm, _ := m.normalizeRunes(query)
+	targetNorm, _ := m.normalizeRunes(target)
+	score, _ := m.Score(query, target)
+	e := Explanation{Quality: NoMatch, Score: score}
+
+	switch {
+	case runesEqual(queryNorm, targetNorm):
+		e.Quality = ExactMatch
+		e.Factors = []Factor{{Name: "exact", Value: m.opts.ExactScore, Detail: "query equals the key"}}
+
+	case hasRunePrefix(targetNorm, queryNorm):
+		e.Quality = PrefixMatch
+		e.Factors = []Factor{{Name: "prefix", Value: m.opts.PrefixScore, Detail: "key starts with the query"}}
+
+	case indexRunes(targetNorm, queryNorm) >= 0:
+		e.Quality = SubstringMatch
+		idx := indexRunes(targetNorm, queryNorm)
+		e.Factors = []Factor{
+			{Name: "substring", Value: m.opts.SubstringScore, Detail: "key contains the query"},
+			{Name: "position", Value: -float64(idx) * m.opts.SubstringPositionPenalty, Detail: fmt.Sprintf("match starts at character %d", idx)},
+		}
+		if raw := m.opts.SubstringScore - float64(idx)*m.opts.SubstringPositionPenalty; raw < m.opts.SubstringMinScore {
+			e.Factors = append(e.Factors, Factor{Name: "floor", Value: m.opts.SubstringMinScore - raw, Detail: "raised to the substring minimum"})
+		}
+
+	default:
+		p, ok := m.fuzzyMatch(queryNorm, targetNorm)
+		if !ok {
+			return e
+		}
+		e.Quality = FuzzyMatch
+		scale := p.matchRatio * p.lengthRatio * m.opts.FuzzyScale
+		e.Factors = []Factor{
+			{Name: "characters", Value: p.chars * scale, Detail: fmt.Sprintf("%d matched in order", len(p.positions))},
+			{Name: "consecutive", Value: p.consecutive * scale, Detail: "bonus for adjacent matches"},
+			{Name: "boundary", Value: p.boundary * scale, Detail: fmt.Sprintf("%d matches at word starts", p.boundaries)},
+		}
+		if raw := p.score(m.opts); raw < m.opts.FuzzyMinScore {
+			e.Factors = append(e.Factors, Factor{Name: "floor", Value: m.opts.FuzzyMinScore - raw, Detail: "raised to the fuzzy minimum"})
+		}
+		e.Notes = append(e.Notes, fmt.Sprintf("fuzzy bonuses are scaled by %d/%d (query/key length) × %.0f",
+			len(queryNorm), len(targetNorm), m.opts.FuzzyScale))
+	}
+
+	return e
+}
@@ -0,0 +1,440 @@
+// Package fuzzy provides the ranked fuzzy matching used by every lockr
+// frontend. The CLI, interactive picker, agent and any embedding program share
+// this implementation so that results are ordered identically everywhere.
+package fuzzy
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Options configures scoring and normalization for a Matcher
+type Options struct {
+	// CaseSensitive disables lowercasing during normalization
+	CaseSensitive bool
+
+	// Normalize overrides the default normalization when set. It is applied
+	// rune by rune to both the query and the target before comparison, so
+	// highlight ranges can be mapped back onto the original target.
+	Normalize func(string) string
+
+	// ExactScore is awarded when the query equals the target
+	ExactScore float64
+
+	// PrefixScore is awarded when the target starts with the query
+	PrefixScore float64
+
+	// SubstringScore is the base score for a contiguous match
+	SubstringScore float64
+
+	// SubstringPositionPenalty is subtracted per character of offset
+	SubstringPositionPenalty float64
+
+	// SubstringMinScore is the floor for contiguous matches
+	SubstringMinScore float64
+
+	// CharScore is awarded for every query character in a fuzzy match
+	CharScore float64
+
+	// ConsecutiveBonus is added per run length for adjacent matched characters
+	ConsecutiveBonus float64
+
+	// BoundaryBonus is added when a fuzzy character matches at the start of
+	// a word (after '/', '_', '-', '.', ':' or a space)
+	BoundaryBonus float64
+
+	// FuzzyScale scales the raw fuzzy score into the reported range
+	FuzzyScale float64
+
+	// FuzzyMinScore is the floor for any successful fuzzy match
+	FuzzyMinScore float64
+
+	// MaxResults limits the number of ranked results (0 for no limit)
+	MaxResults int
+
+	// Highlight enables computation of highlight ranges
+	Highlight bool
+}
+
+// DefaultOptions returns the scoring configuration used by the lockr CLI
+func DefaultOptions() Options {
+	return Options{
+		ExactScore:               100.0,
+		PrefixScore:              90.0,
+		SubstringScore:           80.0,
+		SubstringPositionPenalty: 2.0,
+		SubstringMinScore:        50.0,
+		CharScore:                2.0,
+		ConsecutiveBonus:         0.5,
+		BoundaryBonus:            1.0,
+		FuzzyScale:               20.0,
+		FuzzyMinScore:            10.0,
+		MaxResults:               100,
+		Highlight:                true,
+	}
+}
+
+// Range represents a half-open range of rune indices to highlight in a match
+type Range struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Quality represents how a query matched a target
+type Quality int
+
+const (
+	ExactMatch Quality = iota
+	PrefixMatch
+	SubstringMatch
+	FuzzyMatch
+	NoMatch
+)
+
+// Match is a single ranked result returned by Rank
+type Match struct {
+	Index      int     `json:"index"`
+	Target     string  `json:"target"`
+	Score      float64 `json:"score"`
+	Highlights []Range `json:"highlights,omitempty"`
+}
+
+// Matcher scores and ranks strings against a query
+type Matcher struct {
+	opts Options
+}
+
+// New creates a Matcher with the given options
+func New(opts Options) *Matcher {
+	return &Matcher{opts: opts}
+}
+
+// Options returns the matcher's current configuration
+func (m *Matcher) Options() Options {
+	return m.opts
+}
+
+// SetOptions replaces the matcher's configuration
+func (m *Matcher) SetOptions(opts Options) {
+	m.opts = opts
+}
+
+// Normalize applies the configured normalization to s
+func (m *Matcher) Normalize(s string) string {
+	if m.opts.Normalize != nil {
+		return m.opts.Normalize(s)
+	}
+	if m.opts.CaseSensitive {
+		return s
+	}
+	return strings.ToLower(s)
+}
+
+// Rank scores every target against query and returns the matches ordered by
+// descending score. An empty query returns every target with a zero score.
+func (m *Matcher) Rank(query string, targets []string) []Match {
+	if len(query) == 0 {
+		matches := make([]Match, len(targets))
+		for i, target := range targets {
+			matches[i] = Match{Index: i, Target: target}
+		}
+		return m.limit(matches)
+	}
+
+	var matches []Match
+	for i, target := range targets {
+		if score, highlights := m.Score(query, target); score > 0 {
+			match := Match{Index: i, Target: target, Score: score}
+			if m.opts.Highlight {
+				match.Highlights = highlights
+			}
+			matches = append(matches, match)
+		}
+	}
+
+	queryNorm := m.Normalize(query)
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		// Tie-breaker: prefer targets that start with the query
+		iTarget := m.Normalize(matches[i].Target)
+		jTarget := m.Normalize(matches[j].Target)
+
+		iStartsWith := strings.HasPrefix(iTarget, queryNorm)
+		jStartsWith := strings.HasPrefix(jTarget, queryNorm)
+
+		if iStartsWith != jStartsWith {
+			return iStartsWith
+		}
+
+		// Final tie-breaker: alphabetical order
+		return iTarget < jTarget
+	})
+
+	return m.limit(matches)
+}
+
+// Score calculates the match score between query and target. A score of zero
+// means the target does not match. Highlight ranges are expressed as rune
+// indices into the original, un-normalized target.
+func (m *Matcher) Score(query, target string) (float64, []Range) {
+	queryNorm, _ := m.normalizeRunes(query)
+	targetNorm, origIndex := m.normalizeRunes(target)
+
+	// Exact match gets highest score
+	if runesEqual(queryNorm, targetNorm) {
+		return m.opts.ExactScore, []Range{{Start: 0, End: utf8.RuneCountInString(target)}}
+	}
+
+	// Check for prefix match
+	if hasRunePrefix(targetNorm, queryNorm) {
+		return m.opts.PrefixScore, []Range{mapRange(0, len(queryNorm), origIndex)}
+	}
+
+	// Check for substring match
+	if idx := indexRunes(targetNorm, queryNorm); idx >= 0 {
+		highlights := []Range{mapRange(idx, idx+len(queryNorm), origIndex)}
+		score := m.opts.SubstringScore - float64(idx)*m.opts.SubstringPositionPenalty // Prefer matches earlier in the string
+		if score < m.opts.SubstringMinScore {
+			score = m.opts.SubstringMinScore
+		}
+		return score, highlights
+	}
+
+	// Fuzzy matching using character-by-character scoring
+	return m.fuzzyScore(queryNorm, targetNorm, origIndex)
+}
+
+// Quality determines how query matched target
+func (m *Matcher) Quality(query, target string) Quality {
+	queryNorm, _ := m.normalizeRunes(query)
+	targetNorm, origIndex := m.normalizeRunes(target)
+
+	if runesEqual(queryNorm, targetNorm) {
+		return ExactMatch
+	}
+
+	if hasRunePrefix(targetNorm, queryNorm) {
+		return PrefixMatch
+	}
+
+	if indexRunes(targetNorm, queryNorm) >= 0 {
+		return SubstringMatch
+	}
+
+	if score, _ := m.fuzzyScore(queryNorm, targetNorm, origIndex); score > 0 {
+		return FuzzyMatch
+	}
+
+	return NoMatch
+}
+
+// normalizeRunes normalizes s and returns its runes together with the index
+// of the original rune each normalized rune came from. The index slice is nil
+// when normalization maps runes one-to-one.
+func (m *Matcher) normalizeRunes(s string) ([]rune, []int) {
+	if m.opts.Normalize == nil {
+		runes := []rune(s)
+		if !m.opts.CaseSensitive {
+			for i, r := range runes {
+				runes[i] = unicode.ToLower(r)
+			}
+		}
+		return runes, nil
+	}
+
+	// Custom normalization may change the number of runes, so apply it rune
+	// by rune and remember where each output rune originated
+	var runes []rune
+	var origIndex []int
+	i := 0
+	for _, r := range s {
+		for _, nr := range m.opts.Normalize(string(r)) {
+			runes = append(runes, nr)
+			origIndex = append(origIndex, i)
+		}
+		i++
+	}
+	return runes, origIndex
+}
+
+// mapRange converts a range over normalized runes into original rune indices
+func mapRange(start, end int, origIndex []int) Range {
+	if origIndex == nil || start >= end {
+		return Range{Start: start, End: end}
+	}
+	return Range{Start: origIndex[start], End: origIndex[end-1] + 1}
+}
+
+// fuzzyParts are the contributions to a fuzzy score, kept apart so that
+// Explain can report them
+type fuzzyParts struct {
+	chars       float64 // CharScore per matched character
+	consecutive float64 // ConsecutiveBonus for runs of adjacent matches
+	boundary    float64 // BoundaryBonus for matches at word starts
+	boundaries  int
+	matchRatio  float64
+	lengthRatio float64
+	positions   []int
+}
+
+// score combines the parts into the raw fuzzy score, before the floor
+func (p fuzzyParts) score(opts Options) float64 {
+	return (p.chars + p.consecutive + p.boundary) * p.matchRatio * p.lengthRatio * opts.FuzzyScale
+}
+
+// fuzzyMatch matches query characters in order against the target and
+// reports false if some query character could not be matched
+func (m *Matcher) fuzzyMatch(queryRunes, targetRunes []rune) (fuzzyParts, bool) {
+	var p fuzzyParts
+	if len(queryRunes) == 0 || len(targetRunes) == 0 {
+		return p, false
+	}
+
+	queryPos := 0
+	targetPos := 0
+	consecutiveMatches := 0
+
+	for queryPos < len(queryRunes) && targetPos < len(targetRunes) {
+		if queryRunes[queryPos] == targetRunes[targetPos] {
+			// Character match; track matched positions for highlighting
+			p.positions = append(p.positions, targetPos)
+
+			consecutiveMatches++
+			// Bonus for consecutive matches
+			p.chars += m.opts.CharScore
+			p.consecutive += float64(consecutiveMatches) * m.opts.ConsecutiveBonus
+			if isBoundary(targetRunes, targetPos) {
+				p.boundary += m.opts.BoundaryBonus
+				p.boundaries++
+			}
+
+			queryPos++
+			targetPos++
+		} else {
+			// No match - move to next target character
+			consecutiveMatches = 0
+			targetPos++
+		}
+	}
+
+	// Check if we matched all query characters
+	if queryPos < len(queryRunes) {
+		return p, false
+	}
+
+	p.matchRatio = float64(len(p.positions)) / float64(len(queryRunes))
+	p.lengthRatio = float64(len(queryRunes)) / float64(len(targetRunes))
+	return p, true
+}
+
+// fuzzyScore performs character-by-character fuzzy matching on normalized runes
+func (m *Matcher) fuzzyScore(queryRunes, targetRunes []rune, origIndex []int) (float64, []Range) {
+	p, ok := m.fuzzyMatch(queryRunes, targetRunes)
+	if !ok {
+		return 0.0, nil
+	}
+
+	// Calculate final score based on match ratio and penalties
+	finalScore := p.score(m.opts)
+
+	// Ensure minimum score threshold
+	if finalScore < m.opts.FuzzyMinScore {
+		finalScore = m.opts.FuzzyMinScore
+	}
+
+	matchedPositions := p.positions
+	if origIndex != nil {
+		for i, pos := range matchedPositions {
+			matchedPositions[i] = origIndex[pos]
+		}
+	}
+
+	return finalScore, rangesFromPositions(matchedPositions)
+}
+
+// runesEqual reports whether two rune slices are identical
+func runesEqual(a, b []rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// hasRunePrefix reports whether s begins with prefix
+func hasRunePrefix(s, prefix []rune) bool {
+	return len(s) >= len(prefix) && runesEqual(s[:len(prefix)], prefix)
+}
+
+// indexRunes returns the rune index of the first occurrence of sub in s, or -1
+func indexRunes(s, sub []rune) int {
+	if len(sub) == 0 {
+		return 0
+	}
+	for i := 0; i+len(sub) <= len(s); i++ {
+		if s[i] == sub[0] && runesEqual(s[i:i+len(sub)], sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isBoundary reports whether the rune at pos starts a word
+func isBoundary(runes []rune, pos int) bool {
+	if pos == 0 {
+		return true
+	}
+	switch runes[pos-1] {
+	case '/', '_', '-', '.', ':', ' ':
+		return true
+	}
+	return false
+}
+
+// rangesFromPositions converts matched positions into highlight ranges
+func rangesFromPositions(positions []int) []Range {
+	if len(positions) == 0 {
+		return nil
+	}
+
+	var ranges []Range
+	start := positions[0]
+	end := positions[0] + 1
+
+	for i := 1; i < len(positions); i++ {
+		if positions[i] == positions[i-1] {
+			// Several normalized runes can originate from the same rune
+			continue
+		}
+		if positions[i] == positions[i-1]+1 {
+			// Consecutive position - extend current range
+			end = positions[i] + 1
+		} else {
+			// Non-consecutive - finalize current range and start new one
+			ranges = append(ranges, Range{Start: start, End: end})
+			start = positions[i]
+			end = positions[i] + 1
+		}
+	}
+
+	// Add the final range
+	ranges = append(ranges, Range{Start: start, End: end})
+
+	return ranges
+}
+
+// limit truncates matches to the configured maximum
+func (m *Matcher) limit(matches []Match) []Match {
+	if m.opts.MaxResults <= 0 || len(matches) <= m.opts.MaxResults {
+		return matches
+	}
+	return matches[:m.opts.MaxResults]
+}
@@ -0,0 +1,186 @@
+package fuzzy
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcher_Rank(t *testing.T) {
+	m := New(DefaultOptions())
+
+	targets := []string{"database_password", "api_key_github", "github_token", "user_password"}
+
+	matches := m.Rank("github", targets)
+	require.Len(t, matches, 2)
+	assert.Equal(t, "github_token", matches[0].Target)
+	assert.Equal(t, 2, matches[0].Index)
+	assert.Equal(t, "api_key_github", matches[1].Target)
+}
+
+func TestMatcher_EmptyQuery(t *testing.T) {
+	m := New(DefaultOptions())
+
+	matches := m.Rank("", []string{"a", "b", "c"})
+	assert.Len(t, matches, 3)
+	for _, match := range matches {
+		assert.Equal(t, 0.0, match.Score)
+	}
+}
+
+func TestMatcher_CustomWeights(t *testing.T) {
+	opts := DefaultOptions()
+	opts.ExactScore = 10
+	opts.PrefixScore = 5
+	m := New(opts)
+
+	score, _ := m.Score("test", "test")
+	assert.Equal(t, 10.0, score)
+
+	score, _ = m.Score("test", "testing")
+	assert.Equal(t, 5.0, score)
+}
+
+func TestMatcher_BoundaryBonus(t *testing.T) {
+	opts := DefaultOptions()
+	opts.BoundaryBonus = 0
+	plain := New(opts)
+
+	opts.BoundaryBonus = 5
+	boosted := New(opts)
+
+	// "pdb" matches the start of each word in "prod/db"
+	plainScore, _ := plain.Score("pdb", "prod/db/backup")
+	boostedScore, _ := boosted.Score("pdb", "prod/db/backup")
+	assert.Greater(t, boostedScore, plainScore)
+}
+
+func TestMatcher_CustomNormalize(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Normalize = func(s string) string {
+		return strings.ReplaceAll(strings.ToLower(s), "-", "_")
+	}
+	m := New(opts)
+
+	assert.Equal(t, ExactMatch, m.Quality("api-key", "API_KEY"))
+}
+
+func TestMatcher_MaxResults(t *testing.T) {
+	opts := DefaultOptions()
+	opts.MaxResults = 3
+	m := New(opts)
+
+	targets := make([]string, 10)
+	for i := range targets {
+		targets[i] = fmt.Sprintf("key_%d", i)
+	}
+	assert.Len(t, m.Rank("key", targets), 3)
+
+	opts.MaxResults = 0
+	m.SetOptions(opts)
+	assert.Len(t, m.Rank("key", targets), 10)
+}
+
+func TestMatcher_Quality(t *testing.T) {
+	m := New(DefaultOptions())
+
+	assert.Equal(t, ExactMatch, m.Quality("test", "TEST"))
+	assert.Equal(t, PrefixMatch, m.Quality("test", "test_key"))
+	assert.Equal(t, SubstringMatch, m.Quality("test", "my_test_key"))
+	assert.Equal(t, FuzzyMatch, m.Quality("tk", "test_key"))
+	assert.Equal(t, NoMatch, m.Quality("xyz", "abc"))
+}
+
+func benchmarkTargets(n int) []string {
+	namespaces := []string{"prod", "staging", "dev", "personal", "work"}
+	services := []string{"db", "api", "cache", "queue", "auth", "github", "aws"}
+
+	targets := make([]string, n)
+	for i := range targets {
+		targets[i] = fmt.Sprintf("%s/%s/secret_%d",
+			namespaces[i%len(namespaces)], services[i%len(services)], i)
+	}
+	return targets
+}
+
+func BenchmarkRank10k(b *testing.B) {
+	m := New(DefaultOptions())
+	targets := benchmarkTargets(10000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Rank("prdgh", targets)
+	}
+}
+
+func BenchmarkRank10kSubstring(b *testing.B) {
+	m := New(DefaultOptions())
+	targets := benchmarkTargets(10000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Rank("github", targets)
+	}
+}
+
+func TestMatcher_UnicodeHighlights(t *testing.T) {
+	m := New(DefaultOptions())
+
+	// Substring match after multi-byte characters uses rune offsets
+	score, highlights := m.Score("κλειδί", "Ünïcode_ΚΛΕΙΔΊ")
+	require.Greater(t, score, 0.0)
+	require.Len(t, highlights, 1)
+	assert.Equal(t, Range{Start: 8, End: 14}, highlights[0])
+
+	// Exact match covers every rune, not every byte
+	_, highlights = m.Score("café", "CAFÉ")
+	assert.Equal(t, []Range{{Start: 0, End: 4}}, highlights)
+
+	// Fuzzy positions land on the original runes
+	_, highlights = m.Score("éa", "été_api")
+	assert.Equal(t, []Range{{Start: 0, End: 1}, {Start: 4, End: 5}}, highlights)
+}
+
+func TestMatcher_ExpandingNormalize(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Normalize = func(s string) string {
+		return strings.ReplaceAll(strings.ToLower(s), "ß", "ss")
+	}
+	m := New(opts)
+
+	// "ß" expands to two runes but highlights still index the original key
+	_, highlights := m.Score("strasse", "Die_Straße")
+	assert.Equal(t, []Range{{Start: 4, End: 10}}, highlights)
+}
+
+func TestMatcher_Explain(t *testing.T) {
+	m := New(DefaultOptions())
+
+	for _, tc := range []struct {
+		query, target string
+		quality       Quality
+	}{
+		{"db", "db", ExactMatch},
+		{"db", "db/password", PrefixMatch},
+		{"pass", "db/password", SubstringMatch},
+		{"dbpw", "db/password", FuzzyMatch},
+		{"zz", "db/password", NoMatch},
+	} {
+		e := m.Explain(tc.query, tc.target)
+		score, _ := m.Score(tc.query, tc.target)
+		assert.Equal(t, tc.quality, e.Quality, tc.target)
+		assert.Equal(t, score, e.Score, tc.target)
+
+		// The factors add up to the score
+		sum := 0.0
+		for _, f := range e.Factors {
+			sum += f.Value
+		}
+		assert.InDelta(t, score, sum, 1e-9, tc.target)
+	}
+
+	assert.Equal(t, "substring", SubstringMatch.String())
+}
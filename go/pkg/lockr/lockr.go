@@ -0,0 +1,270 @@
+// Package lockr lets Go programs read and write lockr vaults directly,
+// without running the lockr command.
+//
+// A Vault is unlocked once with the vault password and then used like the
+// CLI would: reads and writes are recorded in the vault's audit log under
+// the "sdk" client, and the vault locks itself after Options.SessionTimeout
+// without use.
+//
+//	vault, err := lockr.Open(ctx, path, password, nil)
+//	if err != nil {
+//		return err
+//	}
+//	defer vault.Close()
+//
+//	dsn, err := vault.Get(ctx, "db/prod/password")
+//
+// A Vault is safe for concurrent use; calls are serialised. Every method
+// takes a context: a query still running when it is done is interrupted, and
+// a write is rolled back.
+package lockr
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lockr/go/internal/database"
+	"github.com/lockr/go/internal/keyring"
+	"github.com/lockr/go/internal/policy"
+	"github.com/lockr/go/internal/search"
+	"github.com/lockr/go/internal/session"
+	"github.com/lockr/go/internal/strength"
+)
+
+var (
+	// ErrNotFound is returned for a key the vault does not hold
+	ErrNotFound = database.ErrKeyNotFound
+
+	// ErrWrongPassword is returned by Open when the password does not
+	// unlock the vault
+	ErrWrongPassword = database.ErrAuthenticationFailed
+
+	// ErrInvalidKey is returned for keys lockr does not accept, such as
+	// empty ones or ones with spaces
+	ErrInvalidKey = database.ErrInvalidKey
+
+	// ErrSessionExpired is returned once the vault locked itself after going
+	// unused for the session timeout. Open it again to continue.
+	ErrSessionExpired = database.ErrSessionExpired
+
+	// ErrClosed is returned by every method after Close
+	ErrClosed = errors.New("lockr: vault is closed")
+)
+
+// Options tune how a vault is opened. The zero value, or nil, keeps the
+// defaults.
+type Options struct {
+	// Identity names the program in the audit log, such as app:deployer,
+	// so its use of secrets can be reviewed with 'lockr token usage'
+	Identity string
+
+	// SessionTimeout is how long the vault stays unlocked without use.
+	// Zero means 15 minutes.
+	SessionTimeout time.Duration
+
+	// Durability is "normal" or "full", as in the durability setting of
+	// config.yml. Empty means normal.
+	Durability string
+}
+
+// Entry describes a secret without its value
+type Entry struct {
+	Key          string
+	Tags         []string
+	Revision     int64
+	CreatedAt    time.Time
+	LastAccessed time.Time
+	AccessCount  int64
+}
+
+// Vault is an unlocked vault
+type Vault struct {
+	mu       sync.Mutex
+	db       *database.VaultDatabase
+	sessions *session.Manager
+	closed   bool
+}
+
+// Open unlocks the vault at path with password, creating the vault if the
+// file does not exist. The key derivation takes about a second and is not
+// interrupted by ctx.
+func Open(ctx context.Context, path, password string, opts *Options) (*Vault, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &Options{}
+	}
+	durability, err := database.ParseDurability(opts.Durability)
+	if err != nil {
+		return nil, err
+	}
+
+	db := database.NewVaultDatabase(path)
+	db.SetDurability(durability)
+
+	// Programs keep their own credentials; the user's keyring is the CLI's
+	kr := keyring.NewManager()
+	kr.Disable()
+	sessions := session.NewManagerWithKeyring(db, kr)
+	sessions.SetClient(database.ClientSDK)
+	sessions.SetIdentity(opts.Identity)
+	if opts.SessionTimeout > 0 {
+		sessions.SetTimeout(opts.SessionTimeout)
+	}
+
+	if err := sessions.Authenticate(password); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Vault{db: db, sessions: sessions}, nil
+}
+
+// Close locks the vault and ends its session. Closing twice is harmless.
+func (v *Vault) Close() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.closed {
+		return nil
+	}
+	v.closed = true
+	v.sessions.Logout()
+	return v.db.Close()
+}
+
+// Get returns the value stored under key. Composed secrets are returned as
+// stored, with their references unresolved.
+func (v *Vault) Get(ctx context.Context, key string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.begin(ctx); err != nil {
+		return "", err
+	}
+
+	secret, err := v.db.GetSecretContext(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	v.sessions.Audit(database.AuditEventGet, key)
+	return secret.Value, nil
+}
+
+// Set stores value under key, creating the secret or replacing its value
+func (v *Vault) Set(ctx context.Context, key, value string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.begin(ctx); err != nil {
+		return err
+	}
+
+	err := v.db.CreateSecretContext(ctx, key, value)
+	if errors.Is(err, database.ErrDuplicateKey) {
+		err = v.db.UpdateSecretContext(ctx, key, value)
+	}
+	if err != nil {
+		return err
+	}
+
+	// The strength estimate only feeds 'lockr audit'; a failure is not the
+	// caller's problem
+	v.db.SetSecretStrength(key, strength.Estimate(value), database.SourceManual)
+	v.sessions.Audit(database.AuditEventSet, key)
+	return nil
+}
+
+// Delete removes key
+func (v *Vault) Delete(ctx context.Context, key string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.begin(ctx); err != nil {
+		return err
+	}
+
+	if err := v.db.DeleteSecretContext(ctx, key); err != nil {
+		return err
+	}
+	v.sessions.Audit(database.AuditEventDelete, key)
+	return nil
+}
+
+// List returns every secret except hidden and archived ones, most recently
+// read first
+func (v *Vault) List(ctx context.Context) ([]Entry, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.begin(ctx); err != nil {
+		return nil, err
+	}
+
+	secrets, err := v.db.ListSecretsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return entries(secrets), nil
+}
+
+// Search returns the secrets matching query, best match first, as 'lockr
+// list <query>' ranks them. Qualifiers such as tag:prod or ns:work/ narrow
+// the results before the remaining text is matched fuzzily against keys.
+// A limit of zero or less returns every match.
+func (v *Vault) Search(ctx context.Context, query string, limit int) ([]Entry, error) {
+	q, err := search.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.begin(ctx); err != nil {
+		return nil, err
+	}
+
+	secrets, err := v.db.ListSecretsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	matches, _ := search.NewEngine().SearchPage(q.Text, q.Filter(secrets), 0, limit)
+
+	found := make([]database.SearchResult, len(matches))
+	for i, match := range matches {
+		found[i] = match.Result
+	}
+	return entries(found), nil
+}
+
+// begin checks that a call may go ahead and keeps the session alive. The
+// caller must hold v.mu.
+func (v *Vault) begin(ctx context.Context) error {
+	if v.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// Once a session expires the manager forgets it, so later calls see no
+	// session at all
+	if err := v.sessions.RefreshSession(); errors.Is(err, database.ErrInvalidSession) {
+		return ErrSessionExpired
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// entries converts search results to Entries
+func entries(secrets []database.SearchResult) []Entry {
+	list := make([]Entry, len(secrets))
+	for i, s := range secrets {
+		list[i] = Entry{
+			Key:          s.Key,
+			Tags:         policy.ParseTags(s.Tags),
+			Revision:     s.Revision,
+			CreatedAt:    s.CreatedAt,
+			LastAccessed: s.LastAccessed,
+			AccessCount:  s.AccessCount,
+		}
+	}
+	return list
+}
@@ -0,0 +1,96 @@
+package lockr
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lockr/go/pkg/lockrtest"
+)
+
+func keys(entries []Entry) []string {
+	var list []string
+	for _, e := range entries {
+		list = append(list, e.Key)
+	}
+	return list
+}
+
+func TestVault(t *testing.T) {
+	ctx := context.Background()
+	test := lockrtest.New(t, map[string]string{
+		"db/prod/password": "hunter2",
+		"db/dev/password":  "dev",
+		"api/token":        "abc",
+	})
+
+	_, err := Open(ctx, test.Path, "wrong", nil)
+	assert.ErrorIs(t, err, ErrWrongPassword)
+
+	vault, err := Open(ctx, test.Path, test.Password, &Options{Identity: "app:test", Durability: "full"})
+	require.NoError(t, err)
+	defer vault.Close()
+
+	value, err := vault.Get(ctx, "db/prod/password")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", value)
+	_, err = vault.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, vault.Set(ctx, "api/token", "rotated"))
+	require.NoError(t, vault.Set(ctx, "api/new", "fresh"))
+	assert.Equal(t, "rotated", test.Value(t, "api/token"))
+	assert.ErrorIs(t, vault.Set(ctx, "bad key", "x"), ErrInvalidKey)
+
+	require.NoError(t, vault.Delete(ctx, "api/new"))
+	assert.ErrorIs(t, vault.Delete(ctx, "api/new"), ErrNotFound)
+
+	list, err := vault.List(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"db/prod/password", "db/dev/password", "api/token"}, keys(list))
+
+	found, err := vault.Search(ctx, "dbprod", 0)
+	require.NoError(t, err)
+	require.NotEmpty(t, found)
+	assert.Equal(t, "db/prod/password", found[0].Key)
+	found, err = vault.Search(ctx, "ns:db/", 1)
+	require.NoError(t, err)
+	assert.Len(t, found, 1)
+	_, err = vault.Search(ctx, "count:lots", 0)
+	assert.Error(t, err)
+
+	// Reads and writes are recorded against the program
+	events, err := test.ListAuditEventsByIdentity("app:test", 10)
+	require.NoError(t, err)
+	assert.NotEmpty(t, events)
+
+	require.NoError(t, vault.Close())
+	require.NoError(t, vault.Close())
+	_, err = vault.Get(ctx, "api/token")
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestVault_Context(t *testing.T) {
+	test := lockrtest.New(t, map[string]string{"api/token": "abc"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	vault, err := Open(ctx, test.Path, test.Password, &Options{SessionTimeout: 50 * time.Millisecond})
+	require.NoError(t, err)
+	defer vault.Close()
+
+	cancel()
+	_, err = vault.Get(ctx, "api/token")
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = Open(ctx, test.Path, test.Password, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// An unused vault locks itself
+	time.Sleep(100 * time.Millisecond)
+	_, err = vault.Get(context.Background(), "api/token")
+	assert.ErrorIs(t, err, ErrSessionExpired)
+	_, err = vault.List(context.Background())
+	assert.ErrorIs(t, err, ErrSessionExpired)
+}
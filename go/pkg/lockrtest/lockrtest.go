@@ -0,0 +1,81 @@
+// Package lockrtest creates throwaway vaults for tests. Programs that embed
+// lockr can use it to run integration tests against a real, encrypted vault
+// without writing the setup and cleanup themselves.
+package lockrtest
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/lockr/go/internal/database"
+)
+
+// DefaultPassword is the password of vaults created by New
+const DefaultPassword = "lockrtest-password"
+
+// Vault is a temporary vault, connected and ready to use. It is closed and
+// removed when the test that created it finishes.
+type Vault struct {
+	*database.VaultDatabase
+
+	// Path is the vault file, for code under test that opens vaults itself
+	Path string
+
+	// Password unlocks the vault at Path
+	Password string
+}
+
+// New creates a vault in a temporary directory, seeded with secrets (key to
+// value), and registers its cleanup with tb. Any failure stops the test.
+func New(tb testing.TB, secrets map[string]string) *Vault {
+	tb.Helper()
+
+	v := &Vault{
+		Path:     filepath.Join(tb.TempDir(), "vault.lockr"),
+		Password: DefaultPassword,
+	}
+	v.VaultDatabase = database.NewVaultDatabase(v.Path)
+	if err := v.Connect(v.Password); err != nil {
+		tb.Fatalf("lockrtest: create vault: %v", err)
+	}
+	tb.Cleanup(func() { v.Close() })
+
+	v.Seed(tb, secrets)
+	return v
+}
+
+// Seed adds secrets (key to value) to the vault in one transaction. Keys
+// that already exist fail the test.
+func (v *Vault) Seed(tb testing.TB, secrets map[string]string) {
+	tb.Helper()
+	if len(secrets) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(secrets))
+	for key := range secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	batch := make([]database.Secret, len(keys))
+	for i, key := range keys {
+		batch[i] = database.Secret{Key: key, Value: secrets[key]}
+	}
+	if _, err := v.ImportSecrets(batch); err != nil {
+		tb.Fatalf("lockrtest: seed vault: %v", err)
+	}
+}
+
+// Value returns the current value of key without recording an access, or
+// fails the test if the key does not exist
+func (v *Vault) Value(tb testing.TB, key string) string {
+	tb.Helper()
+
+	secret, err := v.PeekSecret(key)
+	if err != nil {
+		tb.Fatalf("lockrtest: read %q: %v", key, err)
+	}
+	return secret.Value
+}
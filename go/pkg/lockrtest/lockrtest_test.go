@@ -0,0 +1,33 @@
+package lockrtest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lockr/go/internal/database"
+)
+
+func TestNew(t *testing.T) {
+	v := New(t, map[string]string{
+		"db/password": "hunter2",
+		"api/token":   "abc",
+	})
+
+	assert.Equal(t, "hunter2", v.Value(t, "db/password"))
+	count, err := v.CountSecrets()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	v.Seed(t, map[string]string{"api/secret": "xyz"})
+	assert.Equal(t, "xyz", v.Value(t, "api/secret"))
+
+	// The file can be opened separately by the code under test
+	other := database.NewVaultDatabase(v.Path)
+	require.NoError(t, other.Connect(v.Password))
+	defer other.Close()
+	secret, err := other.PeekSecret("api/token")
+	require.NoError(t, err)
+	assert.Equal(t, "abc", secret.Value)
+}